@@ -24,6 +24,8 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -32,6 +34,8 @@ import (
 	"github.com/jmagar/nugs/cron/internal/api/middleware"
 	"github.com/jmagar/nugs/cron/internal/database"
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/services"
+	"github.com/jmagar/nugs/cron/internal/version"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -42,6 +46,16 @@ type Config struct {
 	Environment string
 	DatabaseURL string
 	JWTSecret   []byte
+	// WebhookConcurrency caps concurrent webhook deliveries across the server
+	WebhookConcurrency int
+	// WebhookQueueSize caps the deliveries waiting for a slot; more are dropped
+	WebhookQueueSize int
+	// TrustedProxies are the proxy IPs/CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are believed. Empty means the socket address is used.
+	TrustedProxies []string
+	// RequestTimeout bounds each protected request, canceling its database
+	// queries once it passes. Zero disables it.
+	RequestTimeout time.Duration
 }
 
 func main() {
@@ -57,6 +71,9 @@ func main() {
 	}
 	defer db.Close()
 
+	services.SetWebhookConcurrency(config.WebhookConcurrency)
+	services.SetWebhookQueueSize(config.WebhookQueueSize)
+
 	// Setup router with database connection
 	router := setupRouter(config, db)
 
@@ -82,6 +99,12 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 
 	router := gin.New()
 
+	// Only trust forwarding headers from configured proxies; otherwise any client
+	// could choose the IP used for rate limiting and audit logs
+	if err := router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Initialize job manager
 	jobManager := models.NewJobManager()
 
@@ -95,6 +118,23 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 	webhookHandler := handlers.NewWebhookHandler(db, jobManager)
 	adminHandler := handlers.NewAdminHandler(db, jobManager)
 	schedulerHandler := handlers.NewSchedulerHandler(db, jobManager)
+	apiKeyHandler := handlers.NewAPIKeyHandler(db)
+	apiKeyService := services.NewAPIKeyService(db)
+
+	// The dashboard summary reports on the scheduler the API controls
+	analyticsHandler.AnalyticsService.SetScheduler(schedulerHandler.SchedulerService)
+
+	// Alert system_alert webhooks when storage crosses its thresholds
+	adminHandler.AdminService.StartStorageAlerts(5 * time.Minute)
+
+	// Fail jobs left running by a goroutine that died
+	adminHandler.AdminService.StartJobReaper(5 * time.Minute)
+
+	// Deliver webhook digests once their window has passed
+	webhookHandler.WebhookService.StartDigests(time.Minute)
+
+	// Keep shows.json and downloads in step with tootie while sync_enabled is set
+	downloadHandler.SyncService.StartSync(time.Minute)
 
 	// Global middleware
 	router.Use(middleware.Logger())
@@ -116,7 +156,7 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 	{
 		v1.GET("/", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{
-				"message": "Nugs Collection API v1.0.0",
+				"message": "Nugs Collection API v" + version.Version,
 				"docs":    "/docs",
 			})
 		})
@@ -124,7 +164,7 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 		// Authentication routes (no auth required)
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", authHandler.Login)
+			auth.POST("/login", middleware.RateLimit(10), authHandler.Login)
 			auth.POST("/logout", authHandler.Logout)
 		}
 
@@ -152,22 +192,28 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 
 		// Protected routes
 		protected := v1.Group("/")
-		protected.Use(middleware.JWTAuth(string(config.JWTSecret)))
+		protected.Use(middleware.APIKeyOrJWTAuth(string(config.JWTSecret), apiKeyService.Authenticate))
+		protected.Use(middleware.Timeout(config.RequestTimeout))
 		{
 			// Auth verification
-			protected.GET("/auth/verify", authHandler.Verify)
+			protected.GET("/auth/verify", middleware.DenyAPIKey(), authHandler.Verify)
+
+			// Entity tags for the JSON read endpoints, so clients polling them
+			// get 304s while nothing changed
+			etag := middleware.ETag()
 
 			// Catalog endpoints
 			catalog := protected.Group("/catalog")
+			catalog.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeCatalog))
 			{
 				// Artists
-				catalog.GET("/artists", catalogHandler.GetArtists)
-				catalog.GET("/artists/:id", catalogHandler.GetArtist)
-				catalog.GET("/artists/:id/shows", catalogHandler.GetArtistShows)
+				catalog.GET("/artists", etag, catalogHandler.GetArtists)
+				catalog.GET("/artists/:id", etag, catalogHandler.GetArtist)
+				catalog.GET("/artists/:id/shows", etag, catalogHandler.GetArtistShows)
 
 				// Shows
-				catalog.GET("/shows/search", catalogHandler.SearchShows)
-				catalog.GET("/shows/:id", catalogHandler.GetShow)
+				catalog.GET("/shows/search", etag, catalogHandler.SearchShows)
+				catalog.GET("/shows/:id", etag, catalogHandler.GetShow)
 
 				// Refresh endpoints
 				catalog.POST("/refresh", refreshHandler.StartRefresh)
@@ -179,18 +225,24 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 
 			// Download endpoints
 			downloads := protected.Group("/downloads")
+			downloads.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeDownloads))
 			{
 				downloads.GET("/", downloadHandler.GetDownloads)
 				downloads.POST("/queue", downloadHandler.QueueDownload)
 				downloads.GET("/queue", downloadHandler.GetDownloadQueue)
 				downloads.POST("/queue/reorder", downloadHandler.ReorderQueue)
 				downloads.GET("/stats", downloadHandler.GetDownloadStats)
+				downloads.GET("/duplicates", downloadHandler.GetDuplicates)
+				downloads.POST("/duplicates/cleanup", downloadHandler.CleanupDuplicates)
+				downloads.POST("/scan", downloadHandler.ScanShows)
+				downloads.POST("/fetch-new", downloadHandler.FetchNewShows)
 				downloads.GET("/:id", downloadHandler.GetDownload)
 				downloads.DELETE("/:id", downloadHandler.CancelDownload)
 			}
 
 			// Monitoring endpoints
 			monitoring := protected.Group("/monitoring")
+			monitoring.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeMonitoring))
 			{
 				// Monitor management
 				monitoring.POST("/monitors", monitoringHandler.CreateMonitor)
@@ -214,31 +266,41 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 
 			// Analytics endpoints
 			analytics := protected.Group("/analytics")
+			analytics.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeAnalytics), analyticsHandler.Deadline())
 			{
 				// Report generation
 				analytics.POST("/reports", analyticsHandler.GenerateReport)
+				analytics.GET("/reports", analyticsHandler.ListReports)
+				analytics.GET("/reports/:id", analyticsHandler.GetReport)
 
 				// Core analytics
-				analytics.GET("/collection", analyticsHandler.GetCollectionStats)
-				analytics.GET("/artists", analyticsHandler.GetArtistAnalytics)
-				analytics.GET("/downloads", analyticsHandler.GetDownloadAnalytics)
+				analytics.GET("/collection", etag, analyticsHandler.GetCollectionStats)
+				analytics.GET("/artists", etag, analyticsHandler.GetArtistAnalytics)
+				analytics.GET("/artists/:id/timeline", etag, analyticsHandler.GetArtistTimeline)
+				analytics.GET("/completion/years", etag, analyticsHandler.GetCompletionMatrix)
+				analytics.GET("/downloads", etag, analyticsHandler.GetDownloadAnalytics)
 				analytics.GET("/system", analyticsHandler.GetSystemMetrics)
+				analytics.GET("/storage", etag, analyticsHandler.GetStorageBreakdown)
 				analytics.GET("/performance", analyticsHandler.GetPerformanceMetrics)
 
 				// Top lists
-				analytics.GET("/top/artists", analyticsHandler.GetTopArtists)
-				analytics.GET("/top/venues", analyticsHandler.GetTopVenues)
+				analytics.GET("/top/artists", etag, analyticsHandler.GetTopArtists)
+				analytics.GET("/top/venues", etag, analyticsHandler.GetTopVenues)
+
+				// Backfill recommendations
+				analytics.GET("/recommendations", etag, analyticsHandler.GetDownloadRecommendations)
 
 				// Trends and insights
-				analytics.GET("/trends/downloads", analyticsHandler.GetDownloadTrends)
+				analytics.GET("/trends/downloads", etag, analyticsHandler.GetDownloadTrends)
 
 				// Dashboard
-				analytics.GET("/summary", analyticsHandler.GetDashboardSummary)
-				analytics.GET("/health", analyticsHandler.GetHealthScore)
+				analytics.GET("/summary", etag, analyticsHandler.GetDashboardSummary)
+				analytics.GET("/health", etag, analyticsHandler.GetHealthScore)
 			}
 
 			// Webhook endpoints
 			webhooks := protected.Group("/webhooks")
+			webhooks.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeWebhooks))
 			{
 				// Webhook management
 				webhooks.POST("/", webhookHandler.CreateWebhook)
@@ -257,10 +319,12 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 				// Webhook information
 				webhooks.GET("/events", webhookHandler.GetAvailableEvents)
 				webhooks.GET("/stats", webhookHandler.GetWebhookStats)
+				webhooks.GET("/stats/trend", webhookHandler.GetDeliveryTrend)
 			}
 
 			// Admin endpoints (require admin role in production)
 			admin := protected.Group("/admin")
+			admin.Use(middleware.DenyAPIKey())
 			{
 				// User management
 				admin.POST("/users", adminHandler.CreateUser)
@@ -281,6 +345,8 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 
 				// Audit logs
 				admin.GET("/audit", adminHandler.GetAuditLogs)
+				admin.GET("/audit/export", adminHandler.ExportAuditLogs)
+				admin.GET("/audit/verify", adminHandler.VerifyAuditLogs)
 
 				// Job management
 				admin.GET("/jobs", adminHandler.GetJobs)
@@ -291,16 +357,27 @@ func setupRouter(config *Config, db *sql.DB) *gin.Engine {
 				admin.POST("/database/backup", adminHandler.CreateDatabaseBackup)
 				admin.POST("/database/optimize", adminHandler.OptimizeDatabase)
 				admin.GET("/database/stats", adminHandler.GetDatabaseStats)
+
+				// Full-system backup bundle
+				admin.GET("/backup/export", adminHandler.ExportBackup)
+				admin.POST("/backup/import", adminHandler.ImportBackup)
+
+				// API keys
+				admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+				admin.GET("/api-keys", apiKeyHandler.GetAPIKeys)
+				admin.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
 			}
 
 			// Scheduler endpoints (background job scheduling)
 			scheduler := protected.Group("/scheduler")
+			scheduler.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeScheduler))
 			{
 				// Scheduler control
 				scheduler.POST("/start", schedulerHandler.StartScheduler)
 				scheduler.POST("/stop", schedulerHandler.StopScheduler)
 				scheduler.GET("/status", schedulerHandler.GetSchedulerStatus)
 				scheduler.GET("/stats", schedulerHandler.GetSchedulerStats)
+				scheduler.GET("/upcoming", schedulerHandler.GetUpcomingRuns)
 
 				// Schedule management
 				scheduler.POST("/schedules", schedulerHandler.CreateSchedule)
@@ -330,6 +407,11 @@ func loadConfig() *Config {
 		Environment: "development",
 		DatabaseURL: "./data/nugs_api.db",
 		JWTSecret:   []byte("change-this-in-production"),
+
+		WebhookConcurrency: services.DefaultWebhookConcurrency,
+		WebhookQueueSize:   services.DefaultWebhookQueueSize,
+		// Responses after the server's 15s write timeout are never delivered
+		RequestTimeout: 15 * time.Second,
 	}
 
 	// Override with environment variables
@@ -349,5 +431,37 @@ func loadConfig() *Config {
 		config.JWTSecret = []byte(jwtSecret)
 	}
 
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				config.TrustedProxies = append(config.TrustedProxies, proxy)
+			}
+		}
+	}
+
+	if concurrency := os.Getenv("WEBHOOK_MAX_CONCURRENCY"); concurrency != "" {
+		if n, err := strconv.Atoi(concurrency); err == nil && n > 0 {
+			config.WebhookConcurrency = n
+		} else {
+			log.Printf("Ignoring invalid WEBHOOK_MAX_CONCURRENCY %q", concurrency)
+		}
+	}
+
+	if size := os.Getenv("WEBHOOK_MAX_QUEUE"); size != "" {
+		if n, err := strconv.Atoi(size); err == nil && n > 0 {
+			config.WebhookQueueSize = n
+		} else {
+			log.Printf("Ignoring invalid WEBHOOK_MAX_QUEUE %q", size)
+		}
+	}
+
+	if timeout := os.Getenv("REQUEST_TIMEOUT_SECONDS"); timeout != "" {
+		if n, err := strconv.Atoi(timeout); err == nil && n >= 0 {
+			config.RequestTimeout = time.Duration(n) * time.Second
+		} else {
+			log.Printf("Ignoring invalid REQUEST_TIMEOUT_SECONDS %q", timeout)
+		}
+	}
+
 	return config
 }
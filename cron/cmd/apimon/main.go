@@ -5,12 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/jmagar/nugs/cron/internal/api"
+	"github.com/jmagar/nugs/cron/internal/paths"
 )
 
 func main() {
@@ -30,6 +30,8 @@ func main() {
 		showRecentLogs()
 	case "errors":
 		showRecentErrors()
+	case "rollup":
+		rollupDay(os.Args[2:])
 	case "stop":
 		enableEmergencyStop()
 	case "start":
@@ -52,6 +54,7 @@ func printUsage() {
 	fmt.Println("  reset   - Reset API counters and circuit breaker")
 	fmt.Println("  logs    - Show recent API request logs")
 	fmt.Println("  errors  - Show recent API errors")
+	fmt.Println("  rollup  - Summarize a day's request log (default today): rollup [YYYY-MM-DD]")
 	fmt.Println("  stop    - Enable emergency stop (creates STOP_API file)")
 	fmt.Println("  start   - Disable emergency stop (removes STOP_API file)")
 	fmt.Println("  status  - Show overall system status")
@@ -60,31 +63,66 @@ func printUsage() {
 func showStats() {
 	client := api.NewSafeAPIClient()
 	stats := client.GetStats()
+	config := api.LoadAPIConfig()
 
 	fmt.Println("=== API Usage Statistics ===")
 	fmt.Printf("Date: %s\n", stats.CurrentDate)
 	fmt.Printf("Time: %02d:%02d\n", stats.CurrentHour, stats.CurrentMinute)
 	fmt.Println("")
 
-	fmt.Printf("Requests Today: %d / %d\n", stats.TotalRequestsToday, 5000)
-	fmt.Printf("Requests This Hour: %d / %d\n", stats.RequestsThisHour, 500)
-	fmt.Printf("Requests This Minute: %d / %d\n", stats.RequestsThisMinute, 30)
+	fmt.Printf("Requests Today: %d / %d\n", stats.TotalRequestsToday, config.MaxRequestsPerDay)
+	fmt.Printf("Requests This Hour: %d / %d\n", stats.RequestsThisHour, config.MaxRequestsPerHour)
+	fmt.Printf("Requests This Minute: %d / %d\n", stats.RequestsThisMinute, config.MaxRequestsPerMinute)
 	fmt.Printf("Last Request: %s\n", stats.LastRequestTime)
+	fmt.Printf("Deduplicated Requests: %d\n", stats.DedupedRequests)
 	fmt.Println("")
 
 	fmt.Printf("Circuit Breaker: %s\n", getBreakerStatus(stats.CircuitBreakerOpen))
-	fmt.Printf("Consecutive Errors: %d / %d\n", stats.ConsecutiveErrors, 5)
+	fmt.Printf("Consecutive Errors: %d / %d\n", stats.ConsecutiveErrors, config.MaxConsecutiveErrors)
 	fmt.Println("")
 
+	if len(stats.Accounts) > 0 {
+		fmt.Println("=== Per-Account Statistics ===")
+
+		emails := make([]string, 0, len(stats.Accounts))
+		for email := range stats.Accounts {
+			emails = append(emails, email)
+		}
+		sort.Strings(emails)
+
+		fmt.Printf("%-30s %8s %8s %8s %8s  %s\n", "Account", "Today", "Hour", "Minute", "Errors", "Breaker")
+		fmt.Println(strings.Repeat("-", 80))
+		for _, email := range emails {
+			account := stats.Accounts[email]
+			breaker := "closed"
+			if account.CircuitBreakerOpen {
+				breaker = "OPEN"
+			}
+			if !account.Authenticated {
+				breaker += " (not logged in)"
+			}
+			fmt.Printf("%-30s %8d %8d %8d %8d  %s\n", email, account.TotalRequestsToday,
+				account.RequestsThisHour, account.RequestsThisMinute, account.ConsecutiveErrors, breaker)
+		}
+		fmt.Println("")
+	}
+
+	// A rollup of today's log has latencies too, so it replaces the counters
+	if summary, err := api.LoadDailySummary(config.LogDirectory, stats.CurrentDate); err == nil {
+		printDailySummary(summary)
+		return
+	}
+
 	if len(stats.Endpoints) > 0 {
 		fmt.Println("=== Per-Endpoint Statistics ===")
 
 		// Sort endpoints by request count
 		type endpointStat struct {
-			Name     string
-			Count    int
-			Errors   int
-			ErrorPct float64
+			Name          string
+			Count         int
+			Errors        int
+			ErrorPct      float64
+			P50, P95, P99 int64
 		}
 
 		var endpoints []endpointStat
@@ -98,6 +136,9 @@ func showStats() {
 				Count:    stat.Count,
 				Errors:   stat.Errors,
 				ErrorPct: errorPct,
+				P50:      stat.Percentile(50),
+				P95:      stat.Percentile(95),
+				P99:      stat.Percentile(99),
 			})
 		}
 
@@ -105,14 +146,78 @@ func showStats() {
 			return endpoints[i].Count > endpoints[j].Count
 		})
 
-		fmt.Printf("%-30s %8s %8s %8s\n", "Endpoint", "Requests", "Errors", "Error %")
-		fmt.Println(strings.Repeat("-", 70))
+		// Latency percentiles cover each endpoint's most recent responses
+		fmt.Printf("%-30s %8s %8s %8s %8s %8s %8s\n", "Endpoint", "Requests", "Errors", "Error %", "p50 ms", "p95 ms", "p99 ms")
+		fmt.Println(strings.Repeat("-", 89))
 		for _, ep := range endpoints {
-			fmt.Printf("%-30s %8d %8d %7.1f%%\n", ep.Name, ep.Count, ep.Errors, ep.ErrorPct)
+			fmt.Printf("%-30s %8d %8d %7.1f%% %8d %8d %8d\n", ep.Name, ep.Count, ep.Errors, ep.ErrorPct, ep.P50, ep.P95, ep.P99)
 		}
 	}
 }
 
+// rollupDay writes the daily summary of the log for the date in args, or today
+func rollupDay(args []string) {
+	date := time.Now().Format("2006-01-02")
+	if len(args) > 0 {
+		if _, err := time.Parse("2006-01-02", args[0]); err != nil {
+			fmt.Printf("Invalid date %q, expected YYYY-MM-DD\n", args[0])
+			os.Exit(1)
+		}
+		date = args[0]
+	}
+
+	logDir := api.LoadAPIConfig().LogDirectory
+	summary, err := api.BuildDailySummary(logDir, date)
+	if os.IsNotExist(err) {
+		fmt.Printf("No log file found for %s.\n", date)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error reading log file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := api.SaveDailySummary(logDir, summary); err != nil {
+		fmt.Printf("Error writing summary: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote %s\n\n", api.SummaryFile(logDir, date))
+	printDailySummary(summary)
+}
+
+// printDailySummary prints a rollup's totals and per-endpoint breakdown
+func printDailySummary(summary *api.DailySummary) {
+	fmt.Printf("=== Daily Summary %s (rolled up %s) ===\n", summary.Date, summary.GeneratedAt)
+	fmt.Printf("Requests: %d  Errors: %d (%.1f%%)  p50: %dms  p95: %dms  p99: %dms\n",
+		summary.TotalRequests, summary.Errors, summary.ErrorRate, summary.P50Ms, summary.P95Ms, summary.P99Ms)
+
+	if len(summary.ErrorClasses) > 0 {
+		classes := make([]string, 0, len(summary.ErrorClasses))
+		for class, count := range summary.ErrorClasses {
+			classes = append(classes, fmt.Sprintf("%s=%d", class, count))
+		}
+		sort.Strings(classes)
+		fmt.Printf("Error classes: %s\n", strings.Join(classes, " "))
+	}
+	fmt.Println("")
+
+	names := make([]string, 0, len(summary.Endpoints))
+	for name := range summary.Endpoints {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return summary.Endpoints[names[i]].Count > summary.Endpoints[names[j]].Count
+	})
+
+	fmt.Printf("%-30s %8s %8s %8s %8s %8s %8s\n", "Endpoint", "Requests", "Errors", "Error %", "p50 ms", "p95 ms", "p99 ms")
+	fmt.Println(strings.Repeat("-", 89))
+	for _, name := range names {
+		ep := summary.Endpoints[name]
+		fmt.Printf("%-30s %8d %8d %7.1f%% %8d %8d %8d\n", name, ep.Count, ep.Errors, ep.ErrorRate, ep.P50Ms, ep.P95Ms, ep.P99Ms)
+	}
+}
+
 func resetStats() {
 	fmt.Print("Are you sure you want to reset all API statistics? (y/N): ")
 	reader := bufio.NewReader(os.Stdin)
@@ -129,9 +234,8 @@ func resetStats() {
 }
 
 func showRecentLogs() {
-	logDir := "logs/api_logs"
-	today := time.Now().Format("2006-01-02")
-	logFile := filepath.Join(logDir, fmt.Sprintf("api_requests_%s.log", today))
+	logDir := api.LoadAPIConfig().LogDirectory
+	logFile := api.RequestLogFile(logDir, time.Now().Format("2006-01-02"))
 
 	if _, err := os.Stat(logFile); os.IsNotExist(err) {
 		fmt.Println("No log file found for today.")
@@ -169,9 +273,8 @@ func showRecentLogs() {
 }
 
 func showRecentErrors() {
-	logDir := "logs/api_logs"
-	today := time.Now().Format("2006-01-02")
-	logFile := filepath.Join(logDir, fmt.Sprintf("api_requests_%s.log", today))
+	logDir := api.LoadAPIConfig().LogDirectory
+	logFile := api.RequestLogFile(logDir, time.Now().Format("2006-01-02"))
 
 	if _, err := os.Stat(logFile); os.IsNotExist(err) {
 		fmt.Println("No log file found for today.")
@@ -211,8 +314,8 @@ func showRecentErrors() {
 	}
 
 	fmt.Printf("=== Recent API Errors (last %d) ===\n", len(errors)-start)
-	fmt.Printf("%-20s %-25s %-4s %s\n", "Time", "Endpoint", "Code", "Error")
-	fmt.Println(strings.Repeat("-", 90))
+	fmt.Printf("%-20s %-25s %-4s %-12s %s\n", "Time", "Endpoint", "Code", "Class", "Error")
+	fmt.Println(strings.Repeat("-", 103))
 
 	for i := start; i < len(errors); i++ {
 		entry := errors[i]
@@ -224,12 +327,12 @@ func showRecentErrors() {
 			errorStr = errorStr[:47] + "..."
 		}
 
-		fmt.Printf("%-20s %-25s %-4d %s\n", timeStr, entry.Endpoint, entry.ResponseCode, errorStr)
+		fmt.Printf("%-20s %-25s %-4d %-12s %s\n", timeStr, entry.Endpoint, entry.ResponseCode, entry.Classification, errorStr)
 	}
 }
 
 func enableEmergencyStop() {
-	file, err := os.Create("configs/STOP_API")
+	file, err := os.Create(paths.Config("STOP_API"))
 	if err != nil {
 		fmt.Printf("Error creating emergency stop file: %v\n", err)
 		return
@@ -244,12 +347,12 @@ func enableEmergencyStop() {
 }
 
 func disableEmergencyStop() {
-	if _, err := os.Stat("configs/STOP_API"); os.IsNotExist(err) {
+	if _, err := os.Stat(paths.Config("STOP_API")); os.IsNotExist(err) {
 		fmt.Println("Emergency stop is not currently enabled.")
 		return
 	}
 
-	err := os.Remove("configs/STOP_API")
+	err := os.Remove(paths.Config("STOP_API"))
 	if err != nil {
 		fmt.Printf("Error removing emergency stop file: %v\n", err)
 		return
@@ -261,11 +364,12 @@ func disableEmergencyStop() {
 func showStatus() {
 	client := api.NewSafeAPIClient()
 	stats := client.GetStats()
+	config := api.LoadAPIConfig()
 
 	fmt.Println("=== System Status ===")
 
 	// Emergency stop status
-	if _, err := os.Stat("configs/STOP_API"); err == nil {
+	if _, err := os.Stat(paths.Config("STOP_API")); err == nil {
 		fmt.Println("Emergency Stop: ENABLED ⛔")
 	} else {
 		fmt.Println("Emergency Stop: DISABLED ✓")
@@ -276,14 +380,15 @@ func showStatus() {
 
 	// Rate limit status
 	rateLimitStatus := "✓ OK"
-	if stats.RequestsThisMinute >= 25 { // 25/30 = 83% threshold
+	if stats.RequestsThisMinute*6 >= config.MaxRequestsPerMinute*5 { // 83% threshold
 		rateLimitStatus = "⚠️ HIGH"
 	}
-	if stats.RequestsThisMinute >= 30 {
+	if stats.RequestsThisMinute >= config.MaxRequestsPerMinute {
 		rateLimitStatus = "⛔ LIMIT"
 	}
-	fmt.Printf("Rate Limits: %s (%d/30 per min, %d/500 per hour, %d/5000 per day)\n",
-		rateLimitStatus, stats.RequestsThisMinute, stats.RequestsThisHour, stats.TotalRequestsToday)
+	fmt.Printf("Rate Limits: %s (%d/%d per min, %d/%d per hour, %d/%d per day)\n",
+		rateLimitStatus, stats.RequestsThisMinute, config.MaxRequestsPerMinute,
+		stats.RequestsThisHour, config.MaxRequestsPerHour, stats.TotalRequestsToday, config.MaxRequestsPerDay)
 
 	// Error rate
 	errorRate := 0.0
@@ -307,9 +412,7 @@ func showStatus() {
 	fmt.Printf("Error Rate: %s (%.1f%%)\n", errorStatus, errorRate)
 
 	// Log file status
-	logDir := "logs/api_logs"
-	today := time.Now().Format("2006-01-02")
-	logFile := filepath.Join(logDir, fmt.Sprintf("api_requests_%s.log", today))
+	logFile := api.RequestLogFile(config.LogDirectory, time.Now().Format("2006-01-02"))
 
 	if stat, err := os.Stat(logFile); err == nil {
 		fmt.Printf("Log File: %s (%.1f KB)\n", logFile, float64(stat.Size())/1024)
@@ -318,9 +421,13 @@ func showStatus() {
 	}
 
 	// Configuration
-	config := api.LoadAPIConfig()
 	fmt.Printf("Config: %d/min, %d/hr, %d/day limits\n",
 		config.MaxRequestsPerMinute, config.MaxRequestsPerHour, config.MaxRequestsPerDay)
+	breaker := fmt.Sprintf("%d consecutive errors", config.MaxConsecutiveErrors)
+	if config.ErrorRateThreshold > 0 {
+		breaker += fmt.Sprintf(" or %.0f%% errors over %d requests", config.ErrorRateThreshold, config.ErrorRateWindow)
+	}
+	fmt.Printf("Breaker: opens after %s, cooldown %ds\n", breaker, config.BreakerCooldownSecs)
 }
 
 func getBreakerStatus(open bool) string {
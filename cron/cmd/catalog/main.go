@@ -1,7 +1,51 @@
 package main
 
-import "github.com/jmagar/nugs/cron/internal/catalog"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/fetch"
+	"github.com/jmagar/nugs/cron/internal/lockfile"
+	"github.com/jmagar/nugs/cron/internal/paths"
+)
 
 func main() {
-	catalog.RunCLI()
+	catalog.RunCLI(downloadShows)
+}
+
+// downloadShows fetches the shows found by search --download the way the
+// monitor does, syncing them to tootie and recording them in shows.json
+func downloadShows(shows []catalog.ShowContainer) error {
+	// Shares the monitor's lock so the two never download at once
+	lock, err := lockfile.Acquire(paths.Data("monitor.lock"))
+	if errors.Is(err, lockfile.ErrLocked) {
+		return fmt.Errorf("the monitor is already running: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("acquiring lock: %w", err)
+	}
+	defer lock.Release()
+
+	// On SIGINT/SIGTERM, abort any running nugs-dl and save progress
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, err := fetch.Shows(ctx, shows, fetch.Options{})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Downloaded %d of %d shows, %d already present", result.Downloaded, len(shows), result.AlreadyPresent)
+	if result.Interrupted {
+		return errors.New("interrupted, progress saved to shows.json")
+	}
+	if result.Errors > 0 {
+		return fmt.Errorf("%d shows could not be downloaded", result.Errors)
+	}
+	return nil
 }
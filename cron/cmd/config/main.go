@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jmagar/nugs/cron/internal/nugsconfig"
+	"github.com/jmagar/nugs/cron/internal/paths"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		return
+	}
+
+	filename := paths.Config("config.json")
+	if len(os.Args) > 2 {
+		filename = os.Args[2]
+	}
+
+	var err error
+	switch command := os.Args[1]; command {
+	case "encrypt":
+		err = encryptConfig(filename)
+	case "decrypt":
+		err = decryptConfig(filename)
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		printUsage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Println("Config Utility")
+	fmt.Println("")
+	fmt.Println("Usage: nugs_config <command> [file]")
+	fmt.Println("")
+	fmt.Println("Commands:")
+	fmt.Println("  encrypt - Encrypt config.json in place with the " + nugsconfig.KeyEnv + " passphrase")
+	fmt.Println("  decrypt - Decrypt config.json in place for editing")
+	fmt.Println("")
+	fmt.Println("The file defaults to configs/config.json under NUGS_HOME.")
+}
+
+func encryptConfig(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if nugsconfig.IsEncrypted(data) {
+		return fmt.Errorf("%s is already encrypted", filename)
+	}
+
+	sealed, err := nugsconfig.Encrypt(data, os.Getenv(nugsconfig.KeyEnv))
+	if err != nil {
+		return err
+	}
+
+	if err := writePrivate(filename, sealed); err != nil {
+		return err
+	}
+
+	fmt.Printf("Encrypted %s\n", filename)
+	return nil
+}
+
+func decryptConfig(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !nugsconfig.IsEncrypted(data) {
+		return fmt.Errorf("%s is not encrypted", filename)
+	}
+
+	passphrase := os.Getenv(nugsconfig.KeyEnv)
+	if passphrase == "" {
+		return nugsconfig.ErrNoKey
+	}
+
+	plaintext, err := nugsconfig.Decrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := writePrivate(filename, plaintext); err != nil {
+		return err
+	}
+
+	fmt.Printf("Decrypted %s\n", filename)
+	return nil
+}
+
+// writePrivate replaces the file through a temp file readable only by the owner
+func writePrivate(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -1,84 +1,111 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"flag"
 	"io/ioutil"
 	"log"
-	"os/exec"
-	"regexp"
+	"os"
+	"os/signal"
 	"sort"
-	"strings"
+	"syscall"
 	"time"
 
 	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/detection"
+	"github.com/jmagar/nugs/cron/internal/folders"
+	"github.com/jmagar/nugs/cron/internal/lockfile"
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
+	"github.com/jmagar/nugs/cron/internal/showsdata"
 )
 
 func main() {
+	allowEmptyShows := flag.Bool("allow-empty-shows", false, "Continue with empty shows data if data/shows.json is corrupt")
+	outputFormat := flag.String("output-format", "", "Also emit a summary of the results: json")
+	outputFile := flag.String("output", "", "Summary output file (default: stdout)")
+	flag.Parse()
+
+	if *outputFormat != "" && *outputFormat != "json" {
+		log.Fatalf("Unknown output format: %s", *outputFormat)
+	}
+
+	// Only one detector may run at a time; overlapping cron runs race on shows.json
+	lock, err := lockfile.Acquire(paths.Data("detector.lock"))
+	if errors.Is(err, lockfile.ErrLocked) {
+		log.Fatalf("Another detector is already running, exiting: %v", err)
+	}
+	if err != nil {
+		log.Fatal("Error acquiring lock:", err)
+	}
+	defer lock.Release()
+
 	log.Println("Starting missing shows detection...")
 
 	// Load monitor configuration
 	// Note: We no longer need the main config since we don't authenticate here
 
-	monitorConfig, err := loadMonitorConfig("configs/monitor_config.json")
+	monitorConfig, err := detection.LoadMonitorConfig(paths.Config("monitor_config.json"))
 	if err != nil {
 		log.Fatal("Error loading monitor config:", err)
 	}
+	if _, err := folders.NewMatcher(monitorConfig.FolderPatterns, ""); err != nil {
+		log.Fatal("Error in monitor config folder_patterns:", err)
+	}
+
+	// Load shows data, refusing to continue on a corrupt file unless told to
+	showsData, err := showsdata.Load(paths.Data("shows.json"), *allowEmptyShows)
+	if err != nil {
+		log.Fatal("Error loading shows data (rerun with -allow-empty-shows to start fresh):", err)
+	}
 
-	// Load shows data
-	showsData := loadShowsData()
+	// Create the catalog source config.json selects
+	catalogSource, err := catalog.LoadSource()
+	if err != nil {
+		log.Fatal("Error in config catalogSource:", err)
+	}
 
-	// Create catalog manager
-	catalogManager := catalog.NewCatalogManager()
+	// On SIGINT/SIGTERM, finish the current artist, save progress and exit non-zero
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	log.Println("Loading catalog...")
 
+	var processedArtists []string
+
 	// Process each monitored artist
 	for _, artist := range monitorConfig.Artists {
+		if ctx.Err() != nil {
+			break
+		}
 		if !artist.Monitor {
 			continue
 		}
 
 		log.Printf("\nProcessing %s (ID: %d)...", artist.Artist, artist.ID)
 
-		// Get available shows from cached catalog
-		availableShows, err := catalogManager.GetShowsForArtist(artist.Artist)
-		if err != nil {
-			log.Printf("Error getting shows for %s: %v", artist.Artist, err)
-			continue
+		// Match the artist's folders on tootie against the cached catalog
+		data, err := detection.Artist(catalogSource, artist, monitorConfig.FolderPatterns,
+			showsData.Artists[artist.Artist], detection.SSHLister)
+		if ctx.Err() != nil {
+			// The listing may have been cut short by the signal, so don't record it
+			break
 		}
-
-		// Get show IDs
-		availableIDs := make([]int, len(availableShows))
-		for i, show := range availableShows {
-			availableIDs[i] = show.ContainerID
-		}
-
-		// Get downloaded shows from tootie filesystem
-		downloadedIDs, err := getDownloadedShows(artist.ArtistFolder, artist.Artist)
 		if err != nil {
-			log.Printf("Error scanning downloaded shows for %s: %v", artist.Artist, err)
+			log.Printf("Error processing %s: %v", artist.Artist, err)
 			continue
 		}
 
-		// Calculate missing shows
-		missingIDs := findMissingShows(availableIDs, downloadedIDs)
-
-		// Update shows data
 		if showsData.Artists == nil {
 			showsData.Artists = make(map[string]models.ArtistShowData)
 		}
-
-		showsData.Artists[artist.Artist] = models.ArtistShowData{
-			ArtistID:   artist.ID,
-			Downloaded: downloadedIDs,
-			Available:  availableIDs,
-			Missing:    missingIDs,
-		}
+		showsData.Artists[artist.Artist] = data
+		processedArtists = append(processedArtists, artist.Artist)
 
 		// Update catalog metadata from catalog manager
-		if catalogStats, err := catalogManager.GetCatalogStats(); err == nil {
+		if catalogStats, err := catalogSource.GetCatalogStats(); err == nil {
 			showsData.LastCatalogUpdate = catalogStats.LastUpdate
 			showsData.CatalogTotalShows = catalogStats.TotalShows
 			showsData.CatalogTotalArtists = catalogStats.TotalArtists
@@ -86,188 +113,112 @@ func main() {
 		showsData.LastAnalysisTime = time.Now().Format(time.RFC3339)
 
 		// Report results
-		log.Printf("Available shows: %d", len(availableIDs))
-		log.Printf("Downloaded shows: %d", len(downloadedIDs))
-		log.Printf("Missing shows: %d", len(missingIDs))
-
-		if len(missingIDs) > 0 {
-			log.Printf("Missing show IDs: %v", missingIDs[:min(10, len(missingIDs))])
-			if len(missingIDs) > 10 {
-				log.Printf("... and %d more", len(missingIDs)-10)
+		log.Printf("Available shows: %d", len(data.Available))
+		log.Printf("Downloaded shows: %d", len(data.Downloaded))
+		log.Printf("Missing shows: %d", len(data.Missing))
+		if len(data.Failed) > 0 {
+			log.Printf("Failed shows (download attempts exhausted): %d", len(data.Failed))
+		}
+		if len(data.Unmatched) > 0 {
+			log.Printf("Unmatched folders (no catalog show): %d", len(data.Unmatched))
+			for _, folder := range data.Unmatched[:min(10, len(data.Unmatched))] {
+				log.Printf("  %s", folder)
+			}
+			if len(data.Unmatched) > 10 {
+				log.Printf("  ... and %d more", len(data.Unmatched)-10)
+			}
+		}
+
+		if len(data.Missing) > 0 {
+			log.Printf("Missing show IDs: %v", data.Missing[:min(10, len(data.Missing))])
+			if len(data.Missing) > 10 {
+				log.Printf("... and %d more", len(data.Missing)-10)
 			}
 		}
 	}
 
 	// Save updated shows data
-	err = saveShowsData(showsData)
+	err = showsdata.Save(paths.Data("shows.json"), showsData)
 	if err != nil {
 		log.Fatal("Error saving shows data:", err)
 	}
 
-	log.Println("\nMissing shows detection complete!")
-	log.Println("Check shows.json for detailed results.")
-}
-
-func loadConfig(filename string) (*models.Config, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	var config models.Config
-	err = json.Unmarshal(data, &config)
-	return &config, err
-}
-
-func loadMonitorConfig(filename string) (*models.MonitorConfig, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
+	if ctx.Err() != nil {
+		log.Println("\nInterrupted, progress saved to shows.json")
+		lock.Release()
+		os.Exit(130)
 	}
 
-	var config models.MonitorConfig
-	err = json.Unmarshal(data, &config)
-	return &config, err
-}
-
-func loadShowsData() *models.ShowsData {
-	data, err := ioutil.ReadFile("data/shows.json")
-	if err != nil {
-		return &models.ShowsData{
-			Artists: make(map[string]models.ArtistShowData),
+	if *outputFormat == "json" {
+		if err := writeSummary(buildSummary(showsData, processedArtists), *outputFile); err != nil {
+			log.Fatal("Error writing summary:", err)
 		}
 	}
 
-	var shows models.ShowsData
-	json.Unmarshal(data, &shows)
-	if shows.Artists == nil {
-		shows.Artists = make(map[string]models.ArtistShowData)
-	}
-
-	// Initialize metadata fields if they don't exist
-	if shows.LastCatalogUpdate == "" {
-		shows.LastCatalogUpdate = "unknown"
-	}
-	if shows.LastAnalysisTime == "" {
-		shows.LastAnalysisTime = "unknown"
-	}
-
-	return &shows
+	log.Println("\nMissing shows detection complete!")
+	log.Println("Check shows.json for detailed results.")
 }
 
-func saveShowsData(shows *models.ShowsData) error {
-	data, err := json.MarshalIndent(shows, "", "  ")
-	if err != nil {
-		return err
-	}
-	return ioutil.WriteFile("data/shows.json", data, 0644)
+// DetectionSummary is the machine-readable result of a detection run
+type DetectionSummary struct {
+	LastCatalogUpdate   string          `json:"last_catalog_update"`
+	CatalogTotalShows   int             `json:"catalog_total_shows"`
+	CatalogTotalArtists int             `json:"catalog_total_artists"`
+	LastAnalysisTime    string          `json:"last_analysis_time"`
+	Artists             []ArtistSummary `json:"artists"`
 }
 
-func getDownloadedShows(artistFolder, artistName string) ([]int, error) {
-	// Use SSH to list directories on tootie
-	cmd := exec.Command("ssh", "tootie", "ls", "-1", fmt.Sprintf("'%s'", artistFolder))
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// If directory doesn't exist or SSH fails, return empty list
-		return []int{}, nil
-	}
-
-	// Parse directory names to log found folders
-	folders := strings.Split(strings.TrimSpace(string(output)), "\n")
-
-	// Regular expressions to match different folder name patterns
-	// Pattern 1: MM_DD_YY format (newer shows)
-	datePattern1 := regexp.MustCompile(`^(\d{2})_(\d{2})_(\d{2})`)
-	// Pattern 2: Artist Name - MM_DD_YY format (older shows)
-	datePattern2 := regexp.MustCompile(`^` + regexp.QuoteMeta(artistName) + ` - (\d{2})_(\d{2})_(\d{2})`)
-
-	foundCount := 0
-	for _, folder := range folders {
-		if folder == "" || strings.HasPrefix(folder, ".") || strings.HasSuffix(folder, ".nfo") ||
-			strings.HasSuffix(folder, ".jpg") || strings.HasSuffix(folder, ".png") ||
-			strings.HasSuffix(folder, ".md") {
-			continue
-		}
-
-		if datePattern1.MatchString(folder) || datePattern2.MatchString(folder) {
-			foundCount++
-		}
-	}
-
-	if foundCount > 0 {
-		log.Printf("Found %d show folders for %s", foundCount, artistName)
-	}
-
-	// Parse folder names and match them to container IDs in the catalog
-	catalogManager := catalog.NewCatalogManager()
-	downloadedIDs := []int{}
-
-	// Get shows for this artist from catalog ONCE (not per folder)
-	shows, err := catalogManager.GetShowsForArtist(artistName)
-	if err != nil {
-		log.Printf("Error getting catalog shows for %s: %v", artistName, err)
-		return []int{}, nil
-	}
-
-	// Create a map of dates to container IDs for fast lookup
-	dateToContainerID := make(map[string]int)
-	for _, show := range shows {
-		dateToContainerID[show.PerformanceDateShort] = show.ContainerID
-	}
-
-	// Parse each folder name and try to match to a container ID
-	for _, folder := range folders {
-		if folder == "" || strings.HasPrefix(folder, ".") || strings.HasSuffix(folder, ".nfo") ||
-			strings.HasSuffix(folder, ".jpg") || strings.HasSuffix(folder, ".png") ||
-			strings.HasSuffix(folder, ".md") {
-			continue
-		}
-
-		var month, day, year string
-
-		// Try Pattern 1: MM_DD_YY format (newer shows)
-		if matches := datePattern1.FindStringSubmatch(folder); len(matches) == 4 {
-			month, day, year = matches[1], matches[2], matches[3]
-		} else if matches := datePattern2.FindStringSubmatch(folder); len(matches) == 4 {
-			// Pattern 2: Artist Name - MM_DD_YY format (older shows)
-			month, day, year = matches[1], matches[2], matches[3]
-		} else {
-			// Folder doesn't match expected patterns
-			continue
-		}
-
-		// Convert MM_DD_YY to MM/DD/YY format to match catalog
-		dateToMatch := fmt.Sprintf("%s/%s/%s", month, day, year)
-
-		// Fast lookup in map instead of looping through all shows
-		if containerID, exists := dateToContainerID[dateToMatch]; exists {
-			downloadedIDs = append(downloadedIDs, containerID)
-		}
-	}
+// ArtistSummary holds the show counts for one artist
+type ArtistSummary struct {
+	Artist     string `json:"artist"`
+	ArtistID   int    `json:"artist_id"`
+	Available  int    `json:"available"`
+	Downloaded int    `json:"downloaded"`
+	Missing    int    `json:"missing"`
+	Failed     int    `json:"failed"`
+	Unmatched  int    `json:"unmatched"`
+}
 
-	log.Printf("Successfully matched %d folders to container IDs for %s", len(downloadedIDs), artistName)
-	return downloadedIDs, nil
+// buildSummary collects the counts for the artists processed in this run, sorted by name
+func buildSummary(showsData *models.ShowsData, artists []string) DetectionSummary {
+	summary := DetectionSummary{
+		LastCatalogUpdate:   showsData.LastCatalogUpdate,
+		CatalogTotalShows:   showsData.CatalogTotalShows,
+		CatalogTotalArtists: showsData.CatalogTotalArtists,
+		LastAnalysisTime:    showsData.LastAnalysisTime,
+		Artists:             []ArtistSummary{},
+	}
+
+	sort.Strings(artists)
+	for _, name := range artists {
+		data := showsData.Artists[name]
+		summary.Artists = append(summary.Artists, ArtistSummary{
+			Artist:     name,
+			ArtistID:   data.ArtistID,
+			Available:  len(data.Available),
+			Downloaded: len(data.Downloaded),
+			Missing:    len(data.Missing),
+			Failed:     len(data.Failed),
+			Unmatched:  len(data.Unmatched),
+		})
+	}
+
+	return summary
 }
 
-func findMissingShows(available, downloaded []int) []int {
-	// Convert downloaded to map for faster lookup
-	downloadedMap := make(map[int]bool)
-	for _, id := range downloaded {
-		downloadedMap[id] = true
+// writeSummary writes the summary as JSON to outputFile, or stdout if empty
+func writeSummary(summary DetectionSummary, outputFile string) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
 	}
+	data = append(data, '\n')
 
-	// Find missing shows
-	var missing []int
-	for _, id := range available {
-		if !downloadedMap[id] {
-			missing = append(missing, id)
-		}
+	if outputFile == "" {
+		_, err = os.Stdout.Write(data)
+		return err
 	}
-
-	// Sort for consistent output
-	sort.Ints(missing)
-	return missing
+	return ioutil.WriteFile(outputFile, data, 0644)
 }
 
 func min(a, b int) int {
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"regexp"
+)
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+// dashboardAssets holds the vendored scripts, under assets/
+var dashboardAssets fs.FS = embeddedAssets
+
+// dashboardScript is a script the dashboard loads, from its CDN or inlined
+type dashboardScript struct {
+	Src    string
+	Inline template.JS
+}
+
+// dashboardScripts are the CDN scripts the dashboard needs, with the file
+// make gap_report_assets vendors each one to
+var dashboardScripts = []struct {
+	URL  string
+	File string
+}{
+	{"https://cdn.jsdelivr.net/npm/chart.js@4.4.0/dist/chart.umd.min.js", "chart.umd.min.js"},
+	{"https://cdnjs.cloudflare.com/ajax/libs/gsap/3.12.2/gsap.min.js", "gsap.min.js"},
+}
+
+// scriptEnd matches a closing script tag inside inlined code. JavaScript reads
+// <\/script the same way, but the HTML parser no longer ends the element there.
+var scriptEnd = regexp.MustCompile(`(?i)</(script)`)
+
+// loadScripts returns the dashboard's scripts, read from the vendored assets
+// when inline is set
+func loadScripts(inline bool) ([]dashboardScript, error) {
+	var scripts []dashboardScript
+	for _, script := range dashboardScripts {
+		if !inline {
+			scripts = append(scripts, dashboardScript{Src: script.URL})
+			continue
+		}
+
+		data, err := fs.ReadFile(dashboardAssets, "assets/"+script.File)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not vendored, run make gap_report_assets and rebuild: %v", script.File, err)
+		}
+		scripts = append(scripts, dashboardScript{
+			Inline: template.JS(scriptEnd.ReplaceAll(data, []byte(`<\/$1`))),
+		})
+	}
+	return scripts, nil
+}
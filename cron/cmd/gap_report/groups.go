@@ -0,0 +1,90 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// GroupBys are the accepted --group-by values
+var GroupBys = []string{"tag"}
+
+// untaggedGroup collects artists with no tags in monitor_config.json
+const untaggedGroup = "untagged"
+
+// GroupSummary rolls up the reports of every artist in a group
+type GroupSummary struct {
+	Group           string  `json:"group"`
+	Artists         int     `json:"artists"`
+	TotalShowsHave  int     `json:"total_shows_have"`
+	TotalShowsAvail int     `json:"total_shows_available"`
+	Completion      float64 `json:"completion"`
+	TotalMissing    int     `json:"total_missing"`
+	TotalFailed     int     `json:"total_failed"`
+	TotalUpgrades   int     `json:"total_upgrades"`
+}
+
+// validGroupBy reports whether groupBy is empty or a known grouping
+func validGroupBy(groupBy string) bool {
+	if groupBy == "" {
+		return true
+	}
+	for _, g := range GroupBys {
+		if g == groupBy {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByTag rolls reports up by their artists' tags. An artist with several
+// tags counts toward each of them, so group totals can add up to more than
+// the overall summary. Groups are sorted by name, with untagged artists last.
+func groupByTag(reports []GapReport) []GroupSummary {
+	groups := make(map[string]*GroupSummary)
+	var names []string
+	for _, report := range reports {
+		tags := report.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedGroup}
+		}
+
+		seen := make(map[string]bool)
+		for _, tag := range tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+
+			group, exists := groups[tag]
+			if !exists {
+				group = &GroupSummary{Group: tag}
+				groups[tag] = group
+				names = append(names, tag)
+			}
+			group.Artists++
+			group.TotalShowsHave += report.TotalDownloaded
+			group.TotalShowsAvail += report.TotalAvailable
+			group.TotalMissing += report.MissingCount
+			group.TotalFailed += report.FailedCount
+			group.TotalUpgrades += report.UpgradeCount
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == untaggedGroup) != (names[j] == untaggedGroup) {
+			return names[j] == untaggedGroup
+		}
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+
+	summaries := make([]GroupSummary, 0, len(names))
+	for _, name := range names {
+		group := groups[name]
+		if group.TotalShowsAvail > 0 {
+			group.Completion = float64(group.TotalShowsHave) / float64(group.TotalShowsAvail) * 100
+		}
+		summaries = append(summaries, *group)
+	}
+	return summaries
+}
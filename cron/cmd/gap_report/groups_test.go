@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupByTag(t *testing.T) {
+	reports := []GapReport{
+		{Artist: "Goose", TotalAvailable: 10, TotalDownloaded: 5, MissingCount: 5, Tags: []string{"jam bands"}},
+		{Artist: "Phish", TotalAvailable: 30, TotalDownloaded: 30, UpgradeCount: 2, Tags: []string{"jam bands", "Vermont", "jam bands"}},
+		{Artist: "Grateful Dead", TotalAvailable: 20, TotalDownloaded: 10, MissingCount: 10, FailedCount: 1},
+		{Artist: "Allman Brothers", TotalAvailable: 40, TotalDownloaded: 20, MissingCount: 20, Tags: []string{"classic rock"}},
+	}
+
+	groups := groupByTag(reports)
+	require.Len(t, groups, 4)
+	assert.Equal(t, []string{"classic rock", "jam bands", "Vermont", untaggedGroup},
+		[]string{groups[0].Group, groups[1].Group, groups[2].Group, groups[3].Group})
+
+	// Phish lists "jam bands" twice but counts once
+	assert.Equal(t, GroupSummary{
+		Group:           "jam bands",
+		Artists:         2,
+		TotalShowsHave:  35,
+		TotalShowsAvail: 40,
+		Completion:      87.5,
+		TotalMissing:    5,
+		TotalUpgrades:   2,
+	}, groups[1])
+	assert.Equal(t, 1, groups[3].TotalFailed)
+	assert.Equal(t, 50.0, groups[3].Completion)
+
+	html, err := generateHTMLContent(reports, ReportSummary{Groups: groups}, dashboardOptions{Theme: "dark"})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<div class="stat-label">classic rock</div>`)
+	assert.Contains(t, html, "35/40 shows · 2 artists · 5 missing")
+
+	html, err = generateHTMLContent(reports, ReportSummary{}, dashboardOptions{Theme: "dark"})
+	require.NoError(t, err)
+	assert.NotContains(t, html, "🏷️ Groups")
+}
+
+func TestValidGroupBy(t *testing.T) {
+	assert.True(t, validGroupBy(""))
+	assert.True(t, validGroupBy("tag"))
+	assert.False(t, validGroupBy("genre"))
+}
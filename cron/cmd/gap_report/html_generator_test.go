@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// embeddedArtists decodes the artistsData the page's scripts read
+func embeddedArtists(t *testing.T, html string) []GapReport {
+	const prefix = "const artistsData = "
+	start := strings.Index(html, prefix)
+	require.NotEqual(t, -1, start, "artistsData not found")
+	data := html[start+len(prefix):]
+	data = data[:strings.Index(data, ";\n")]
+
+	var artists []GapReport
+	require.NoError(t, json.Unmarshal([]byte(data), &artists), data)
+	return artists
+}
+
+func TestGenerateHTMLContent_EscapesArtistData(t *testing.T) {
+	names := []string{
+		`Grace "Potter" & the Nocturnals`,
+		`Back\slash Band`,
+		"Line\nBreak O'Brien",
+		"Ólafur Arnalds",
+		"ゆらゆら帝国",
+		"</script><script>alert(1)</script>",
+	}
+
+	var reports []GapReport
+	for i, name := range names {
+		reports = append(reports, GapReport{
+			Artist:         name,
+			ArtistID:       i + 1,
+			TotalAvailable: 10,
+			CompletionPct:  50,
+			MissingShows:   []MissingShow{{ContainerID: 100 + i, Venue: `The "Venue" \ ` + name}},
+			MissingCount:   1,
+		})
+	}
+
+	html, err := generateHTMLContent(reports, ReportSummary{TotalArtists: len(names)}, dashboardOptions{Theme: "dark"})
+	require.NoError(t, err)
+
+	artists := embeddedArtists(t, html)
+	require.Len(t, artists, len(names))
+	for i, name := range names {
+		assert.Equal(t, name, artists[i].Artist)
+		assert.Equal(t, `The "Venue" \ `+name, artists[i].MissingShows[0].Venue)
+	}
+
+	assert.NotContains(t, html, "<script>alert(1)")
+	assert.Contains(t, html, `Grace &#34;Potter&#34; &amp; the Nocturnals`)
+	assert.Contains(t, html, `<div class="artist-avatar">Ó</div>`)
+	assert.Contains(t, html, `<div class="artist-avatar">ゆ</div>`)
+	assert.Contains(t, html, `onclick="showMissingDetails( 1 )"`)
+}
+
+func TestGenerateHTMLContent_NoReports(t *testing.T) {
+	html, err := generateHTMLContent(nil, ReportSummary{}, dashboardOptions{Theme: "dark"})
+	require.NoError(t, err)
+	assert.Empty(t, embeddedArtists(t, html))
+}
+
+func TestGenerateHTMLContent_Summary(t *testing.T) {
+	summary := ReportSummary{
+		TotalArtists:      3,
+		TotalShowsHave:    120,
+		TotalShowsAvail:   150,
+		OverallCompletion: 80,
+		TotalMissing:      30,
+	}
+
+	html, err := generateHTMLContent([]GapReport{{Artist: "Goose", ArtistID: 7, CompletionPct: 100}}, summary, dashboardOptions{Theme: "dark"})
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `<div class="stat-value">120</div>`)
+	assert.Contains(t, html, `<div class="stat-value">80.0%</div>`)
+	assert.Contains(t, html, `<span class="badge badge-success">0 shows</span>`)
+	assert.Contains(t, html, `data: [ 120 ,  30 ]`)
+}
+
+func TestGenerateHTMLContent_Themes(t *testing.T) {
+	reports := []GapReport{
+		{Artist: "Goose", ArtistID: 1, CompletionPct: 100},
+		{Artist: "Phish", ArtistID: 2, CompletionPct: 80},
+		{Artist: "moe.", ArtistID: 3, CompletionPct: 60},
+		{Artist: "Twiddle", ArtistID: 4, CompletionPct: 10},
+	}
+
+	for _, theme := range Themes {
+		t.Run(theme, func(t *testing.T) {
+			html, err := generateHTMLContent(reports, ReportSummary{}, dashboardOptions{Theme: theme})
+			require.NoError(t, err)
+
+			assert.Contains(t, html, `<html lang="en" data-theme="`+theme+`">`)
+			// Completion colors come from the theme palette rather than fixed hex values
+			for _, color := range []string{"var(--success)", "var(--warning)", "var(--secondary)", "var(--danger)"} {
+				assert.Contains(t, html, `<span class="progress-text" style="color: `+color+`;">`)
+			}
+		})
+	}
+
+	_, err := generateHTMLContent(reports, ReportSummary{}, dashboardOptions{Theme: "sepia"})
+	assert.Error(t, err)
+}
+
+func TestGenerateHTMLContent_SelfContained(t *testing.T) {
+	defer func(assets fs.FS) { dashboardAssets = assets }(dashboardAssets)
+	dashboardAssets = fstest.MapFS{
+		"assets/chart.umd.min.js": {Data: []byte(`var Chart = function() { return "</script>"; };`)},
+		"assets/gsap.min.js":      {Data: []byte(`var gsap = {};`)},
+	}
+
+	html, err := generateHTMLContent(nil, ReportSummary{}, dashboardOptions{Theme: "dark", SelfContained: true})
+	require.NoError(t, err)
+
+	assert.Contains(t, html, `<script>var Chart = function() { return "<\/script>"; };</script>`)
+	assert.Contains(t, html, `<script>var gsap = {};</script>`)
+	assert.NotContains(t, html, "https://")
+
+	html, err = generateHTMLContent(nil, ReportSummary{}, dashboardOptions{Theme: "dark"})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<script src="https://cdn.jsdelivr.net/npm/chart.js@4.4.0/dist/chart.umd.min.js"></script>`)
+	assert.Contains(t, html, "fonts.googleapis.com")
+}
+
+func TestGenerateHTMLContent_SelfContainedMissingAssets(t *testing.T) {
+	defer func(assets fs.FS) { dashboardAssets = assets }(dashboardAssets)
+	dashboardAssets = fstest.MapFS{}
+
+	_, err := generateHTMLContent(nil, ReportSummary{}, dashboardOptions{Theme: "dark", SelfContained: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "make gap_report_assets")
+}
+
+func TestWriteHTMLOutput_DirectoryWritesSidecars(t *testing.T) {
+	dir := t.TempDir()
+	reports := []GapReport{{Artist: `Grace "Potter"`, ArtistID: 1, TotalAvailable: 4, TotalDownloaded: 3, CompletionPct: 75}}
+	summary := ReportSummary{TotalArtists: 1, TotalShowsHave: 3, TotalShowsAvail: 4, OverallCompletion: 75, TotalMissing: 1}
+
+	writeHTMLOutput(reports, summary, dashboardOptions{Theme: "dark"}, dir)
+
+	html, err := os.ReadFile(filepath.Join(dir, dashboardFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "const sidecar =  true ;")
+	assert.Equal(t, reports, embeddedArtists(t, string(html)))
+
+	// The sidecar holds exactly the data the page renders
+	saved, err := loadSavedReport(filepath.Join(dir, jsonSidecarFile))
+	require.NoError(t, err)
+	assert.Equal(t, summary, saved.Summary)
+	assert.Equal(t, reports, saved.Reports)
+
+	csv, err := os.ReadFile(filepath.Join(dir, csvSidecarFile))
+	require.NoError(t, err)
+	assert.Equal(t, csvReport(reports, false), string(csv))
+}
+
+func TestWithoutComplete(t *testing.T) {
+	reports := []GapReport{
+		{Artist: "Goose", ArtistID: 1, TotalAvailable: 4, TotalDownloaded: 4, CompletionPct: 100},
+		{Artist: "Phish", ArtistID: 2, TotalAvailable: 4, TotalDownloaded: 3, CompletionPct: 75},
+	}
+
+	incomplete := withoutComplete(reports)
+	require.Len(t, incomplete, 1)
+	assert.Equal(t, "Phish", incomplete[0].Artist)
+
+	html, err := generateHTMLContent(incomplete, ReportSummary{TotalArtists: 2}, dashboardOptions{Theme: "dark", HideComplete: true})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<option value="complete" disabled>100% Complete (hidden by --include-complete=false)</option>`)
+
+	html, err = generateHTMLContent(reports, ReportSummary{TotalArtists: 2}, dashboardOptions{Theme: "dark"})
+	require.NoError(t, err)
+	assert.Contains(t, html, `<option value="complete">100% Complete</option>`)
+}
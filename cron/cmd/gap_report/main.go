@@ -1,17 +1,23 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
-	"time"
-	
+
 	"github.com/jmagar/nugs/cron/internal/catalog"
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
+	"github.com/jmagar/nugs/cron/internal/quality"
+	"github.com/jmagar/nugs/cron/internal/showsdata"
 )
 
 type MissingShow struct {
@@ -22,38 +28,90 @@ type MissingShow struct {
 	State       string `json:"state"`
 }
 
+// FailedShow is a show the monitor stopped retrying after repeated download failures
+type FailedShow struct {
+	MissingShow
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error"`
+	LastAttempt string `json:"last_attempt"`
+}
+
+// UpgradeCandidate is a downloaded show held in a format below the best one
+// on the quality ladder
+type UpgradeCandidate struct {
+	MissingShow
+	Format string `json:"format"`
+	Target string `json:"target"`
+}
+
 type GapReport struct {
-	Artist          string        `json:"artist"`
-	ArtistID        int           `json:"artist_id"`
-	TotalAvailable  int           `json:"total_available"`
-	TotalDownloaded int           `json:"total_downloaded"`
-	CompletionPct   float64       `json:"completion_pct"`
-	MissingShows    []MissingShow `json:"missing_shows"`
-	MissingCount    int           `json:"missing_count"`
+	Artist           string        `json:"artist"`
+	ArtistID         int           `json:"artist_id"`
+	TotalAvailable   int           `json:"total_available"`
+	TotalDownloaded  int           `json:"total_downloaded"`
+	CompletionPct    float64       `json:"completion_pct"`
+	MissingShows     []MissingShow `json:"missing_shows"`
+	MissingCount     int           `json:"missing_count"`
+	FailedShows      []FailedShow  `json:"failed_shows,omitempty"`
+	FailedCount      int           `json:"failed_count"`
+	UnmatchedFolders []string      `json:"unmatched_folders,omitempty"`
+
+	UpgradeCandidates []UpgradeCandidate `json:"upgrade_candidates,omitempty"`
+	UpgradeCount      int                `json:"upgrade_count"`
+
+	Tags []string `json:"tags,omitempty"`
 }
 
 type ReportSummary struct {
-	TotalArtists       int     `json:"total_artists"`
-	TotalShowsHave     int     `json:"total_shows_have"`
-	TotalShowsAvail    int     `json:"total_shows_available"`
-	OverallCompletion  float64 `json:"overall_completion"`
-	TotalMissing       int     `json:"total_missing"`
+	TotalArtists      int     `json:"total_artists"`
+	TotalShowsHave    int     `json:"total_shows_have"`
+	TotalShowsAvail   int     `json:"total_shows_available"`
+	OverallCompletion float64 `json:"overall_completion"`
+	TotalMissing      int     `json:"total_missing"`
+	TotalFailed       int     `json:"total_failed"`
+	TotalUnmatched    int     `json:"total_unmatched"`
+	TotalUpgrades     int     `json:"total_upgrades"`
+
+	// Groups holds per-group totals when --group-by is set
+	Groups []GroupSummary `json:"groups,omitempty"`
 }
 
 func main() {
+	// Re-rendering a saved JSON report skips the analysis entirely
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		renderReport(os.Args[2:])
+		return
+	}
+
 	// Command line flags
 	var (
-		format      = flag.String("format", "terminal", "Output format: terminal, html, csv, json")
-		sortBy      = flag.String("sort", "artist", "Sort by: artist, completion, missing, total")
-		artistName  = flag.String("artist", "", "Generate report for specific artist only")
-		minMissing  = flag.Int("min-missing", 0, "Only show artists with at least N missing shows")
-		outputFile  = flag.String("output", "", "Output file (default: stdout)")
+		format          = flag.String("format", "terminal", "Output format: terminal, html, csv, json")
+		sortBy          = flag.String("sort", "artist", "Sort by: artist, completion, missing, total")
+		artistName      = flag.String("artist", "", "Generate report for specific artist only")
+		minMissing      = flag.Int("min-missing", 0, "Only show artists with at least N missing shows")
+		outputFile      = flag.String("output", "", "Output file (default: stdout)")
+		theme           = flag.String("theme", "dark", "HTML color theme: "+strings.Join(Themes, ", "))
+		selfContained   = flag.Bool("self-contained", false, "Inline the HTML dashboard's scripts so it renders offline")
+		groupBy         = flag.String("group-by", "", "Add per-group totals to the summary: "+strings.Join(GroupBys, ", "))
+		includeComplete = flag.Bool("include-complete", true, "List 100% complete artists (they're always counted in the summary)")
+		summaryOnly     = flag.Bool("summary-only", false, "Print only the summary totals, skipping the catalog lookups (terminal and json)")
+		csvBOM          = flag.Bool("csv-bom", false, "Start CSV output with a UTF-8 byte order mark, so Excel reads non-ASCII names correctly")
 	)
 	flag.Parse()
 
+	if !validTheme(*theme) {
+		log.Fatalf("Unknown theme: %s", *theme)
+	}
+	if !validGroupBy(*groupBy) {
+		log.Fatalf("Unknown group-by: %s", *groupBy)
+	}
+	if *summaryOnly && *format != "terminal" && *format != "json" {
+		log.Fatalf("--summary-only supports terminal and json output, not %s", *format)
+	}
+
 	// Load shows data
-	log.Println("Loading shows data from data/shows.json...")
-	showsData, err := loadShowsData()
+	log.Printf("Loading shows data from %s...", paths.Data("shows.json"))
+	showsData, err := showsdata.Load(paths.Data("shows.json"), false)
 	if err != nil {
 		log.Fatal("Error loading shows data:", err)
 	}
@@ -61,23 +119,43 @@ func main() {
 
 	// Load monitor config to get monitored artists
 	log.Println("Loading monitor config...")
-	monitorConfig, err := loadMonitorConfig("configs/monitor_config.json")
+	monitorConfig, err := loadMonitorConfig(paths.Config("monitor_config.json"))
 	if err != nil {
 		log.Fatal("Error loading monitor config:", err)
 	}
 	log.Printf("Monitor config loaded with %d artists", len(monitorConfig.Artists))
 
-	// Create catalog manager and pre-load catalog
-	log.Println("Initializing catalog manager...")
-	catalogManager := catalog.NewCatalogManager()
-	
+	ladder, err := quality.NewLadder(monitorConfig.QualityLadder)
+	if err != nil {
+		log.Fatal("Error in monitor config quality_ladder:", err)
+	}
+
+	// The summary needs only shows.json, so the catalog is never loaded
+	if *summaryOnly {
+		summary := summarizeShows(monitorConfig, showsData, ladder, *artistName, *minMissing, *groupBy)
+		if *format == "json" {
+			jsonData, _ := jsonSummary(summary)
+			writeJSONOutput(jsonData, *outputFile)
+		} else {
+			printSummary(summary)
+		}
+		return
+	}
+
+	// Create the catalog source and pre-load catalog
+	log.Println("Initializing catalog source...")
+	catalogSource, err := catalog.LoadSource()
+	if err != nil {
+		log.Fatal("Error in config catalogSource:", err)
+	}
+
 	log.Println("Pre-loading catalog for fast lookups...")
-	catalogData, err := catalogManager.GetCatalog()
+	catalogData, err := catalogSource.GetCatalog()
 	if err != nil {
 		log.Fatal("Error loading catalog:", err)
 	}
 	log.Printf("Catalog loaded: %d total shows", len(catalogData.AllShows))
-	
+
 	// Create fast lookup map
 	showMap := make(map[int]*catalog.ShowContainer)
 	for i := range catalogData.AllShows {
@@ -98,7 +176,7 @@ func main() {
 		}
 
 		// Filter by specific artist if requested
-		if *artistName != "" && !strings.Contains(strings.ToLower(artistConfig.Artist), strings.ToLower(*artistName)) {
+		if !artistSelected(artistConfig.Artist, *artistName) {
 			continue
 		}
 
@@ -112,7 +190,7 @@ func main() {
 			continue
 		}
 
-		log.Printf("  Found %d available shows, %d downloaded, %d missing", 
+		log.Printf("  Found %d available shows, %d downloaded, %d missing",
 			len(artistData.Available), len(artistData.Downloaded), len(artistData.Missing))
 
 		// Get missing shows with venue details using fast lookup
@@ -134,19 +212,60 @@ func main() {
 			})
 		}
 
+		var failedShows []FailedShow
+		for _, showID := range artistData.Failed {
+			failed := FailedShow{MissingShow: MissingShow{ContainerID: showID}}
+			if show, exists := showMap[showID]; exists {
+				failed.Date = show.PerformanceDateShort
+				failed.Venue = show.VenueName
+				failed.City = show.VenueCity
+				failed.State = show.VenueState
+			}
+			if attempt, exists := artistData.Attempts[showID]; exists {
+				failed.Attempts = attempt.Count
+				failed.LastError = attempt.LastError
+				failed.LastAttempt = attempt.LastAttempt
+			}
+			failedShows = append(failedShows, failed)
+		}
+
+		var upgrades []UpgradeCandidate
+		for _, showID := range artistData.Downloaded {
+			format := artistData.Formats[showID]
+			if !ladder.NeedsUpgrade(format) {
+				continue
+			}
+			upgrade := UpgradeCandidate{MissingShow: MissingShow{ContainerID: showID}, Format: format, Target: ladder.Best()}
+			if show, exists := showMap[showID]; exists {
+				upgrade.Date = show.PerformanceDateShort
+				upgrade.Venue = show.VenueName
+				upgrade.City = show.VenueCity
+				upgrade.State = show.VenueState
+			}
+			upgrades = append(upgrades, upgrade)
+		}
+
 		completionPct := 0.0
 		if len(artistData.Available) > 0 {
 			completionPct = float64(len(artistData.Downloaded)) / float64(len(artistData.Available)) * 100
 		}
 
 		report := GapReport{
-			Artist:          artistConfig.Artist,
-			ArtistID:        artistConfig.ID,
-			TotalAvailable:  len(artistData.Available),
-			TotalDownloaded: len(artistData.Downloaded),
-			CompletionPct:   completionPct,
-			MissingShows:    missingShows,
-			MissingCount:    len(missingShows),
+			Artist:           artistConfig.Artist,
+			ArtistID:         artistConfig.ID,
+			TotalAvailable:   len(artistData.Available),
+			TotalDownloaded:  len(artistData.Downloaded),
+			CompletionPct:    completionPct,
+			MissingShows:     missingShows,
+			MissingCount:     len(missingShows),
+			FailedShows:      failedShows,
+			FailedCount:      len(failedShows),
+			UnmatchedFolders: artistData.Unmatched,
+
+			UpgradeCandidates: upgrades,
+			UpgradeCount:      len(upgrades),
+
+			Tags: artistConfig.Tags,
 		}
 
 		// Apply minimum missing filter
@@ -158,15 +277,25 @@ func main() {
 		summary.TotalShowsHave += len(artistData.Downloaded)
 		summary.TotalShowsAvail += len(artistData.Available)
 		summary.TotalMissing += len(artistData.Missing)
+		summary.TotalFailed += len(artistData.Failed)
+		summary.TotalUnmatched += len(artistData.Unmatched)
+		summary.TotalUpgrades += len(upgrades)
 	}
 
 	summary.TotalArtists = len(reports)
+	if *groupBy == "tag" {
+		summary.Groups = groupByTag(reports)
+	}
+	// Complete artists are counted above, then dropped from the detail
+	if !*includeComplete {
+		reports = withoutComplete(reports)
+	}
 	if summary.TotalShowsAvail > 0 {
 		summary.OverallCompletion = float64(summary.TotalShowsHave) / float64(summary.TotalShowsAvail) * 100
 	}
 
 	log.Printf("Generated reports for %d artists", len(reports))
-	log.Printf("Summary: %d shows have, %d shows available, %.1f%% completion", 
+	log.Printf("Summary: %d shows have, %d shows available, %.1f%% completion",
 		summary.TotalShowsHave, summary.TotalShowsAvail, summary.OverallCompletion)
 
 	// Sort reports
@@ -177,861 +306,83 @@ func main() {
 	log.Printf("Generating %s output...", *format)
 	switch *format {
 	case "html":
-		log.Println("Generating HTML content...")
-		html := generateHTMLContent(reports, summary)
-		log.Printf("Generated HTML content: %d bytes", len(html))
-		if *outputFile != "" {
-			log.Printf("Writing HTML to file: %s", *outputFile)
-			err := ioutil.WriteFile(*outputFile, []byte(html), 0644)
-			if err != nil {
-				log.Fatal("Error writing HTML file:", err)
-			}
-			fmt.Printf("Modern HTML dashboard written to: %s\n", *outputFile)
-		} else {
-			fmt.Print(html)
-		}
+		writeHTMLOutput(reports, summary, dashboardOptions{Theme: *theme, SelfContained: *selfContained, HideComplete: !*includeComplete, CSVBOM: *csvBOM}, *outputFile)
 	case "json":
-		output := map[string]interface{}{
-			"summary": summary,
-			"reports": reports,
-		}
-		jsonData, _ := json.MarshalIndent(output, "", "  ")
-		if *outputFile != "" {
-			err := ioutil.WriteFile(*outputFile, jsonData, 0644)
-			if err != nil {
-				log.Fatal("Error writing JSON file:", err)
-			}
-			fmt.Printf("JSON report written to: %s\n", *outputFile)
-		} else {
-			fmt.Print(string(jsonData))
-		}
+		jsonData, _ := jsonReport(reports, summary)
+		writeJSONOutput(jsonData, *outputFile)
 	case "csv":
-		generateCSVOutput(reports, summary, *outputFile)
+		generateCSVOutput(reports, *csvBOM, *outputFile)
 	default:
 		printTerminalOutput(reports, summary)
 	}
 }
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>🎵 Nugs Collection Gap Analysis</title>
-    <link rel="icon" href="data:image/svg+xml,<svg xmlns=%22http://www.w3.org/2000/svg%22 viewBox=%220 0 100 100%22><text y=%22.9em%22 font-size=%2290%22>🎵</text></svg>">
-    <style>
-        @import url('https://fonts.googleapis.com/css2?family=Inter:wght@300;400;500;600;700;800&display=swap');
-        
-        * { box-sizing: border-box; }
-        body { 
-            font-family: 'Inter', system-ui, -apple-system, sans-serif;
-            margin: 0;
-            background: linear-gradient(135deg, #667eea 0%, #764ba2 100%);
-            min-height: 100vh;
-            color: #2d3748;
-            line-height: 1.6;
-        }
-        
-        .container {
-            max-width: 1400px;
-            margin: 0 auto;
-            padding: 40px 20px;
-        }
-        
-        .header {
-            text-align: center;
-            margin-bottom: 48px;
-            color: white;
-        }
-        
-        .main-title {
-            font-size: 4rem;
-            font-weight: 800;
-            margin-bottom: 16px;
-            text-shadow: 0 4px 20px rgba(255, 255, 255, 0.3);
-        }
-        
-        .subtitle {
-            font-size: 1.25rem;
-            opacity: 0.8;
-            max-width: 600px;
-            margin: 0 auto;
-        }
-        
-        .stats-grid {
-            display: grid;
-            grid-template-columns: repeat(auto-fit, minmax(220px, 1fr));
-            gap: 24px;
-            margin-bottom: 48px;
-        }
-        
-        .stat-card {
-            background: rgba(255, 255, 255, 0.95);
-            border-radius: 16px;
-            padding: 32px 24px;
-            text-align: center;
-            box-shadow: 0 10px 30px rgba(0, 0, 0, 0.1);
-            backdrop-filter: blur(10px);
-            border: 1px solid rgba(255, 255, 255, 0.2);
-            transition: all 0.3s ease;
-        }
-        
-        .stat-card:hover {
-            transform: translateY(-2px);
-            box-shadow: 0 15px 35px rgba(0, 0, 0, 0.15);
-        }
-        
-        .stat-icon {
-            font-size: 2.5rem;
-            margin-bottom: 16px;
-        }
-        
-        .stat-value {
-            font-size: 2.8rem;
-            font-weight: 800;
-            margin-bottom: 8px;
-        }
-        
-        .stat-title {
-            font-size: 16px;
-            font-weight: 600;
-            margin-bottom: 4px;
-            color: #4a5568;
-        }
-        
-        .stat-subtitle {
-            font-size: 14px;
-            color: #718096;
-        }
-        
-        .controls {
-            background: rgba(255, 255, 255, 0.15);
-            border: 1px solid rgba(255, 255, 255, 0.2);
-            border-radius: 16px;
-            padding: 24px;
-            margin-bottom: 32px;
-            backdrop-filter: blur(10px);
-        }
-        
-        .controls-grid {
-            display: grid;
-            grid-template-columns: repeat(auto-fit, minmax(250px, 1fr));
-            gap: 20px;
-        }
-        
-        .control-group label {
-            color: white;
-            font-weight: 600;
-            font-size: 14px;
-            margin-bottom: 8px;
-            display: flex;
-            align-items: center;
-        }
-        
-        .control-group i {
-            margin-right: 8px;
-        }
-        
-        .control-input {
-            background: rgba(255, 255, 255, 0.9);
-            border: none;
-            border-radius: 8px;
-            padding: 12px 16px;
-            width: 100%;
-            font-size: 15px;
-            font-family: inherit;
-            transition: all 0.2s ease;
-        }
-        
-        .control-input:focus {
-            outline: none;
-            background: white;
-            box-shadow: 0 0 0 3px rgba(99, 102, 241, 0.3);
-        }
-        
-        .artists-grid {
-            display: grid;
-            grid-template-columns: repeat(auto-fill, minmax(400px, 1fr));
-            gap: 24px;
-            margin-bottom: 60px;
-        }
-        
-        .artist-card {
-            background: rgba(255, 255, 255, 0.98);
-            border-radius: 20px;
-            padding: 28px;
-            box-shadow: 0 8px 25px rgba(0, 0, 0, 0.1);
-            border: 1px solid rgba(255, 255, 255, 0.3);
-            backdrop-filter: blur(10px);
-            transition: all 0.3s ease;
-            cursor: pointer;
-            position: relative;
-            overflow: hidden;
-        }
-        
-        .artist-card:hover {
-            transform: translateY(-5px);
-            box-shadow: 0 20px 40px rgba(0, 0, 0, 0.15);
-        }
-        
-        .artist-card::before {
-            content: '';
-            position: absolute;
-            top: 0;
-            left: 0;
-            right: 0;
-            height: 4px;
-            background: linear-gradient(90deg, var(--completion-color, #ef4444), var(--completion-color, #ef4444));
-        }
-        
-        .artist-header {
-            display: flex;
-            align-items: flex-start;
-            gap: 20px;
-            margin-bottom: 24px;
-        }
-        
-        .album-art {
-            width: 80px;
-            height: 80px;
-            border-radius: 12px;
-            object-fit: cover;
-            border: 2px solid rgba(255, 255, 255, 0.8);
-            box-shadow: 0 4px 12px rgba(0, 0, 0, 0.1);
-            transition: all 0.3s ease;
-        }
-        
-        .artist-card:hover .album-art {
-            transform: scale(1.05);
-            box-shadow: 0 6px 16px rgba(0, 0, 0, 0.15);
-        }
-        
-        .artist-info {
-            flex: 1;
-        }
-        
-        .artist-name {
-            font-size: 1.3rem;
-            font-weight: 700;
-            color: #1a202c;
-            margin-bottom: 8px;
-            line-height: 1.2;
-        }
-        
-        .artist-stats-line {
-            font-size: 15px;
-            color: #4a5568;
-            margin-bottom: 16px;
-        }
-        
-        .completion-bar {
-            width: 100%;
-            height: 8px;
-            background: #e2e8f0;
-            border-radius: 4px;
-            overflow: hidden;
-            margin-bottom: 12px;
-        }
-        
-        .completion-fill {
-            height: 100%;
-            border-radius: 4px;
-            transition: all 0.8s ease;
-            background: var(--completion-color, #ef4444);
-        }
-        
-        .completion-percentage {
-            font-size: 18px;
-            font-weight: 700;
-            color: var(--completion-color, #ef4444);
-        }
-        
-        .artist-numbers {
-            display: flex;
-            justify-content: space-between;
-            align-items: center;
-            margin-top: 20px;
-        }
-        
-        .stat-group {
-            display: flex;
-            gap: 24px;
-        }
-        
-        .mini-stat {
-            text-align: center;
-        }
-        
-        .mini-stat-value {
-            font-size: 1.4rem;
-            font-weight: 700;
-        }
-        
-        .mini-stat-label {
-            font-size: 12px;
-            color: #718096;
-            font-weight: 500;
-        }
-        
-        .view-button {
-            background: linear-gradient(135deg, #667eea, #764ba2);
-            color: white;
-            border: none;
-            border-radius: 10px;
-            padding: 8px 16px;
-            font-size: 13px;
-            font-weight: 600;
-            cursor: pointer;
-            transition: all 0.3s ease;
-            display: flex;
-            align-items: center;
-            gap: 6px;
-        }
-        
-        .view-button:hover {
-            transform: translateY(-1px);
-            box-shadow: 0 6px 16px rgba(102, 126, 234, 0.4);
-        }
-        
-        .modal-overlay {
-            position: fixed;
-            top: 0;
-            left: 0;
-            right: 0;
-            bottom: 0;
-            background: rgba(0, 0, 0, 0.75);
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            z-index: 1000;
-            padding: 20px;
-            backdrop-filter: blur(8px);
-        }
-        
-        .modal {
-            background: rgba(255, 255, 255, 0.98);
-            border-radius: 20px;
-            padding: 32px;
-            max-width: 900px;
-            max-height: 80vh;
-            overflow-y: auto;
-            border: 1px solid rgba(255, 255, 255, 0.3);
-            backdrop-filter: blur(15px);
-            box-shadow: 0 25px 50px rgba(0, 0, 0, 0.25);
-        }
-        
-        .modal-header {
-            display: flex;
-            justify-content: space-between;
-            align-items: center;
-            margin-bottom: 28px;
-            padding-bottom: 20px;
-            border-bottom: 2px solid #e2e8f0;
-        }
-        
-        .modal-title {
-            font-size: 1.8rem;
-            font-weight: 800;
-            color: #1a202c;
-        }
-        
-        .close-button {
-            background: #ef4444;
-            color: white;
-            border: none;
-            border-radius: 8px;
-            padding: 8px 12px;
-            cursor: pointer;
-            font-size: 16px;
-            transition: all 0.2s ease;
-        }
-        
-        .close-button:hover {
-            background: #dc2626;
-            transform: scale(1.05);
-        }
-        
-        .modal-stats {
-            display: grid;
-            grid-template-columns: repeat(auto-fit, minmax(120px, 1fr));
-            gap: 16px;
-            margin-bottom: 32px;
-        }
-        
-        .modal-stat-card {
-            background: linear-gradient(135deg, #f8fafc, #e2e8f0);
-            padding: 20px 16px;
-            border-radius: 12px;
-            text-align: center;
-            border: 1px solid #e2e8f0;
-        }
-        
-        .missing-shows-section h3 {
-            color: #1a202c;
-            margin-bottom: 20px;
-            font-size: 1.2rem;
-            font-weight: 700;
-            display: flex;
-            align-items: center;
-            gap: 8px;
-        }
-        
-        .missing-shows-list {
-            max-height: 400px;
-            overflow-y: auto;
-            border: 1px solid #e2e8f0;
-            border-radius: 12px;
-            background: #f8fafc;
-        }
-        
-        .missing-show-item {
-            display: flex;
-            align-items: center;
-            gap: 16px;
-            padding: 16px;
-            border-bottom: 1px solid #e2e8f0;
-            transition: all 0.2s ease;
-        }
-        
-        .missing-show-item:hover {
-            background: #e2e8f0;
-        }
-        
-        .missing-show-item:last-child {
-            border-bottom: none;
-        }
-        
-        .missing-show-art {
-            width: 50px;
-            height: 50px;
-            border-radius: 8px;
-            object-fit: cover;
-            border: 1px solid #cbd5e0;
-        }
-        
-        .missing-show-info {
-            flex: 1;
-        }
-        
-        .missing-show-date {
-            font-weight: 700;
-            color: #2d3748;
-            margin-bottom: 4px;
-        }
-        
-        .missing-show-date a {
-            color: #667eea;
-            text-decoration: none;
-            transition: color 0.2s ease;
-        }
-        
-        .missing-show-date a:hover {
-            color: #5a67d8;
-            text-decoration: underline;
-        }
-        
-        .missing-show-venue {
-            font-size: 14px;
-            color: #4a5568;
-            margin-bottom: 2px;
-        }
-        
-        .missing-show-id {
-            font-size: 12px;
-            color: #718096;
-        }
-        
-        .empty-state {
-            text-align: center;
-            padding: 80px 20px;
-            color: rgba(255, 255, 255, 0.8);
-        }
-        
-        .empty-state i {
-            font-size: 4rem;
-            margin-bottom: 20px;
-            opacity: 0.5;
-        }
-        
-        .empty-state-text {
-            font-size: 1.25rem;
-            font-weight: 500;
-        }
-        
-        .footer {
-            text-align: center;
-            padding: 40px 20px;
-            color: rgba(255, 255, 255, 0.7);
-            border-top: 1px solid rgba(255, 255, 255, 0.1);
-            margin-top: 60px;
-        }
-        
-        .footer-date {
-            margin-bottom: 12px;
-            font-size: 16px;
-        }
-        
-        .footer-credit {
-            font-size: 14px;
-            opacity: 0.8;
-        }
-        
-        .hidden { display: none !important; }
-        
-        /* Color variables for completion */
-        .completion-high { --completion-color: #10b981; }
-        .completion-medium { --completion-color: #f59e0b; }
-        .completion-low { --completion-color: #ef4444; }
-        
-        /* Responsive design */
-        @media (max-width: 768px) {
-            .main-title { font-size: 2.5rem; }
-            .artists-grid { grid-template-columns: 1fr; }
-            .controls-grid { grid-template-columns: 1fr; }
-            .stats-grid { grid-template-columns: repeat(2, 1fr); }
-            .modal { margin: 10px; padding: 24px; }
-        }
-        
-        @media (max-width: 480px) {
-            .stats-grid { grid-template-columns: 1fr; }
-            .artist-header { flex-direction: column; text-align: center; }
-            .artist-numbers { flex-direction: column; gap: 16px; }
-        }
-    </style>
-    <link rel="stylesheet" href="https://cdnjs.cloudflare.com/ajax/libs/font-awesome/6.4.0/css/all.min.css">
-</head>
-<body>
-    <div class="container">
-        <!-- Header -->
-        <div class="header">
-            <h1 class="main-title">🎵 Nugs Collection Analysis</h1>
-            <p class="subtitle">Comprehensive gap analysis of your music collection with detailed insights</p>
-        </div>
-        
-        <!-- Summary Stats -->
-        <div class="stats-grid">`)
-
-	// Summary stats
-	html.WriteString(fmt.Sprintf(`
-            <div class="stat-card">
-                <div class="stat-icon" style="color: #667eea;"><i class="fas fa-users"></i></div>
-                <div class="stat-value" style="color: #667eea;">%d</div>
-                <div class="stat-title">Artists Monitored</div>
-                <div class="stat-subtitle">active collections</div>
-            </div>
-            <div class="stat-card">
-                <div class="stat-icon" style="color: #10b981;"><i class="fas fa-download"></i></div>
-                <div class="stat-value" style="color: #10b981;">%s</div>
-                <div class="stat-title">Shows Downloaded</div>
-                <div class="stat-subtitle">in collection</div>
-            </div>
-            <div class="stat-card">
-                <div class="stat-icon" style="color: #f59e0b;"><i class="fas fa-music"></i></div>
-                <div class="stat-value" style="color: #f59e0b;">%s</div>
-                <div class="stat-title">Shows Available</div>
-                <div class="stat-subtitle">on platform</div>
-            </div>`,
-		summary.TotalArtists,
-		formatNumber(summary.TotalShowsHave),
-		formatNumber(summary.TotalShowsAvail)))
-	
-	completionColor := "#ef4444" // red
-	if summary.OverallCompletion >= 90 {
-		completionColor = "#10b981" // green
-	} else if summary.OverallCompletion >= 70 {
-		completionColor = "#f59e0b" // yellow
-	}
-	
-	html.WriteString(fmt.Sprintf(`
-            <div class="stat-card">
-                <div class="stat-icon" style="color: %s;"><i class="fas fa-chart-pie"></i></div>
-                <div class="stat-value" style="color: %s;">%.1f%%</div>
-                <div class="stat-title">Overall Completion</div>
-                <div class="stat-subtitle">collection progress</div>
-            </div>
-            <div class="stat-card">
-                <div class="stat-icon" style="color: #ef4444;"><i class="fas fa-exclamation-triangle"></i></div>
-                <div class="stat-value" style="color: #ef4444;">%s</div>
-                <div class="stat-title">Missing Shows</div>
-                <div class="stat-subtitle">to download</div>
-            </div>
-        </div>`,
-		completionColor, completionColor, summary.OverallCompletion,
-		formatNumber(summary.TotalMissing)))
-
-	// Controls
-	html.WriteString(`
-        <!-- Controls -->
-        <div class="controls">
-            <div class="controls-grid">
-                <div class="control-group">
-                    <label for="searchInput"><i class="fas fa-search"></i>Search Artists</label>
-                    <input type="text" id="searchInput" class="control-input" placeholder="Search by artist name..." oninput="filterArtists()">
-                </div>
-                <div class="control-group">
-                    <label for="sortSelect"><i class="fas fa-sort"></i>Sort By</label>
-                    <select id="sortSelect" class="control-input" onchange="sortArtists()">
-                        <option value="completion">Completion %</option>
-                        <option value="artist">Artist Name</option>
-                        <option value="missing">Missing Shows</option>
-                        <option value="total">Total Shows</option>
-                    </select>
-                </div>
-                <div class="control-group">
-                    <label for="minMissingInput"><i class="fas fa-filter"></i>Min Missing Shows</label>
-                    <input type="number" id="minMissingInput" class="control-input" placeholder="0" min="0" oninput="filterArtists()">
-                </div>
-            </div>
-        </div>
-        
-        <!-- Artists Grid -->
-        <div class="artists-grid" id="artistsGrid">`)
-
-	// Artist cards
-	for _, report := range reports {
-		completionClass := "completion-low"
-		if report.CompletionPct >= 90 {
-			completionClass = "completion-high"
-		} else if report.CompletionPct >= 70 {
-			completionClass = "completion-medium"
-		}
-		
-		latestShowID := report.ArtistID
-		if len(report.MissingShows) > 0 {
-			latestShowID = report.MissingShows[0].ContainerID
+
+// writeJSONOutput writes a JSON report to outputFile, or stdout if empty
+func writeJSONOutput(jsonData []byte, outputFile string) {
+	if outputFile != "" {
+		err := ioutil.WriteFile(outputFile, jsonData, 0644)
+		if err != nil {
+			log.Fatal("Error writing JSON file:", err)
 		}
-		
-		html.WriteString(fmt.Sprintf(`
-            <div class="artist-card %s" data-artist="%s" data-completion="%.1f" data-missing="%d" data-total="%d" onclick="openModal('%s')">
-                <div class="artist-header">
-                    <img class="album-art" src="https://nugs.net/images/releases/%d/%d_300.jpg" 
-                         alt="%s album art" 
-                         onerror="this.src='data:image/svg+xml,<svg xmlns=\"http://www.w3.org/2000/svg\" viewBox=\"0 0 100 100\"><rect width=\"100\" height=\"100\" fill=\"%%236366f1\"/><text x=\"50\" y=\"55\" text-anchor=\"middle\" fill=\"white\" font-size=\"40\">🎵</text></svg>'">
-                    <div class="artist-info">
-                        <h2 class="artist-name">%s</h2>
-                        <div class="artist-stats-line">%d/%d shows • %.1f%% complete</div>
-                        <div class="completion-bar">
-                            <div class="completion-fill" style="width: %.1f%%;"></div>
-                        </div>
-                        <div class="completion-percentage">%.1f%% Complete</div>
-                    </div>
-                </div>
-                
-                <div class="artist-numbers">
-                    <div class="stat-group">
-                        <div class="mini-stat">
-                            <div class="mini-stat-value" style="color: #10b981;">%d</div>
-                            <div class="mini-stat-label">Downloaded</div>
-                        </div>
-                        <div class="mini-stat">
-                            <div class="mini-stat-value" style="color: #ef4444;">%d</div>
-                            <div class="mini-stat-label">Missing</div>
-                        </div>
-                    </div>
-                    <button class="view-button">
-                        <i class="fas fa-eye"></i>
-                        View Details
-                    </button>
-                </div>
-            </div>`,
-			completionClass,
-			report.Artist,
-			report.CompletionPct,
-			report.MissingCount,
-			report.TotalAvailable,
-			strings.ReplaceAll(report.Artist, "'", "\\'"),
-			latestShowID, latestShowID,
-			report.Artist,
-			report.Artist,
-			report.TotalDownloaded, report.TotalAvailable, report.CompletionPct,
-			report.CompletionPct,
-			report.CompletionPct,
-			report.TotalDownloaded,
-			report.MissingCount))
+		fmt.Printf("JSON report written to: %s\n", outputFile)
+	} else {
+		fmt.Print(string(jsonData))
 	}
+}
 
-	html.WriteString(`
-        </div>
-        
-        <!-- Empty State -->
-        <div class="empty-state hidden" id="emptyState">
-            <i class="fas fa-search"></i>
-            <div class="empty-state-text">No artists match your current filters</div>
-        </div>
-        
-        <!-- Footer -->
-        <div class="footer">
-            <div class="footer-date">Generated on ` + 
-            fmt.Sprintf("%s", time.Now().Format("January 2, 2006 at 3:04 PM")) + `</div>
-            <div class="footer-credit">🎵 Nugs Collection Gap Report</div>
-        </div>
-    </div>
-
-    <!-- Modal -->
-    <div class="modal-overlay hidden" id="modalOverlay" onclick="closeModal()">
-        <div class="modal" onclick="event.stopPropagation()">
-            <div class="modal-header">
-                <h2 class="modal-title" id="modalTitle">Artist Details</h2>
-                <button class="close-button" onclick="closeModal()">
-                    <i class="fas fa-times"></i>
-                </button>
-            </div>
-            <div class="modal-stats" id="modalStats">
-                <!-- Stats will be populated by JavaScript -->
-            </div>
-            <div class="missing-shows-section" id="missingShowsSection">
-                <!-- Missing shows will be populated by JavaScript -->
-            </div>
-        </div>
-    </div>
-
-    <script>`)
-
-	// Embed artist data as JavaScript
-	html.WriteString(`
-        const artistsData = {`)
-	
-	for i, report := range reports {
-		if i > 0 {
-			html.WriteString(`,`)
+// Files written when the HTML output is a directory. The dashboard's export
+// buttons download the sidecars, so exports match the rendered data exactly.
+const (
+	dashboardFile   = "index.html"
+	jsonSidecarFile = "report.json"
+	csvSidecarFile  = "report.csv"
+)
+
+// writeHTMLOutput renders the HTML dashboard to outputFile, or stdout if
+// empty. When outputFile is a directory the dashboard is written there as
+// index.html, with the report alongside as report.json and report.csv.
+func writeHTMLOutput(reports []GapReport, summary ReportSummary, options dashboardOptions, outputFile string) {
+	if info, err := os.Stat(outputFile); err == nil && info.IsDir() {
+		writeSidecars(reports, summary, options.CSVBOM, outputFile)
+		options.Sidecar = true
+		outputFile = filepath.Join(outputFile, dashboardFile)
+	}
+
+	log.Println("Generating HTML content...")
+	html, err := generateHTMLContent(reports, summary, options)
+	if err != nil {
+		log.Fatal("Error generating HTML:", err)
+	}
+	log.Printf("Generated HTML content: %d bytes", len(html))
+	if outputFile != "" {
+		log.Printf("Writing HTML to file: %s", outputFile)
+		err := ioutil.WriteFile(outputFile, []byte(html), 0644)
+		if err != nil {
+			log.Fatal("Error writing HTML file:", err)
 		}
-		missingShowsJSON, _ := json.Marshal(report.MissingShows)
-		html.WriteString(fmt.Sprintf(`
-            "%s": {
-                "artist": "%s",
-                "total_available": %d,
-                "total_downloaded": %d,
-                "completion_pct": %.1f,
-                "missing_shows": %s
-            }`,
-			strings.ReplaceAll(report.Artist, `"`, `\"`),
-			strings.ReplaceAll(report.Artist, `"`, `\"`),
-			report.TotalAvailable,
-			report.TotalDownloaded,
-			report.CompletionPct,
-			string(missingShowsJSON)))
+		fmt.Printf("Modern HTML dashboard written to: %s\n", outputFile)
+	} else {
+		fmt.Print(html)
 	}
-	
-	html.WriteString(`
-        };
-        
-        function filterArtists() {
-            const searchTerm = document.getElementById('searchInput').value.toLowerCase();
-            const minMissing = parseInt(document.getElementById('minMissingInput').value) || 0;
-            const cards = document.querySelectorAll('.artist-card');
-            let visibleCount = 0;
-            
-            cards.forEach(card => {
-                const artistName = card.dataset.artist.toLowerCase();
-                const missing = parseInt(card.dataset.missing);
-                
-                if (artistName.includes(searchTerm) && missing >= minMissing) {
-                    card.classList.remove('hidden');
-                    visibleCount++;
-                } else {
-                    card.classList.add('hidden');
-                }
-            });
-            
-            document.getElementById('emptyState').classList.toggle('hidden', visibleCount > 0);
-        }
-        
-        function sortArtists() {
-            const sortBy = document.getElementById('sortSelect').value;
-            const container = document.getElementById('artistsGrid');
-            const cards = Array.from(container.querySelectorAll('.artist-card'));
-            
-            cards.sort((a, b) => {
-                switch(sortBy) {
-                    case 'artist':
-                        return a.dataset.artist.localeCompare(b.dataset.artist);
-                    case 'completion':
-                        return parseFloat(b.dataset.completion) - parseFloat(a.dataset.completion);
-                    case 'missing':
-                        return parseInt(b.dataset.missing) - parseInt(a.dataset.missing);
-                    case 'total':
-                        return parseInt(b.dataset.total) - parseInt(a.dataset.total);
-                    default:
-                        return 0;
-                }
-            });
-            
-            cards.forEach(card => container.appendChild(card));
-        }
-        
-        function openModal(artistName) {
-            const data = artistsData[artistName];
-            if (!data) return;
-            
-            document.getElementById('modalTitle').textContent = artistName;
-            
-            // Update modal stats
-            const statsHtml = ` + "`" + `
-                <div class="modal-stat-card">
-                    <div class="stat-icon" style="color: #10b981;"><i class="fas fa-check-circle"></i></div>
-                    <div class="stat-value" style="color: #10b981;">${data.total_downloaded}</div>
-                    <div class="stat-title">Downloaded</div>
-                </div>
-                <div class="modal-stat-card">
-                    <div class="stat-icon" style="color: #ef4444;"><i class="fas fa-exclamation-circle"></i></div>
-                    <div class="stat-value" style="color: #ef4444;">${data.missing_shows.length}</div>
-                    <div class="stat-title">Missing</div>
-                </div>
-                <div class="modal-stat-card">
-                    <div class="stat-icon" style="color: #667eea;"><i class="fas fa-music"></i></div>
-                    <div class="stat-value" style="color: #667eea;">${data.total_available}</div>
-                    <div class="stat-title">Total Available</div>
-                </div>
-                <div class="modal-stat-card">
-                    <div class="stat-icon" style="color: ${data.completion_pct >= 90 ? '#10b981' : data.completion_pct >= 70 ? '#f59e0b' : '#ef4444'};"><i class="fas fa-chart-pie"></i></div>
-                    <div class="stat-value" style="color: ${data.completion_pct >= 90 ? '#10b981' : data.completion_pct >= 70 ? '#f59e0b' : '#ef4444'};">${data.completion_pct.toFixed(1)}%</div>
-                    <div class="stat-title">Completion</div>
-                </div>
-            ` + "`" + `;
-            document.getElementById('modalStats').innerHTML = statsHtml;
-            
-            // Update missing shows
-            if (data.missing_shows.length > 0) {
-                let showsHtml = ` + "`" + `<h3><i class="fas fa-list-ul"></i>Missing Shows (${data.missing_shows.length})</h3><div class="missing-shows-list">` + "`" + `;
-                
-                data.missing_shows.forEach(show => {
-                    showsHtml += ` + "`" + `
-                        <div class="missing-show-item">
-                            <img class="missing-show-art" src="https://nugs.net/images/releases/${show.container_id}/${show.container_id}_300.jpg" 
-                                 alt="Show artwork"
-                                 onerror="this.src='data:image/svg+xml,<svg xmlns=\"http://www.w3.org/2000/svg\" viewBox=\"0 0 100 100\"><rect width=\"100\" height=\"100\" fill=\"%236366f1\"/><text x=\"50\" y=\"55\" text-anchor=\"middle\" fill=\"white\" font-size=\"20\">🎵</text></svg>'">
-                            <div class="missing-show-info">
-                                <div class="missing-show-date">
-                                    <a href="https://nugs.net/${show.container_id}" target="_blank">${show.date}</a>
-                                </div>
-                                <div class="missing-show-venue">${show.venue}, ${show.city} ${show.state}</div>
-                                <div class="missing-show-id">ID: ${show.container_id}</div>
-                            </div>
-                        </div>
-                    ` + "`" + `;
-                });
-                
-                showsHtml += ` + "`" + `</div>` + "`" + `;
-                document.getElementById('missingShowsSection').innerHTML = showsHtml;
-            } else {
-                document.getElementById('missingShowsSection').innerHTML = ` + "`" + `<h3><i class="fas fa-check-circle" style="color: #10b981;"></i>All Shows Downloaded!</h3><p style="color: #10b981; font-weight: 600;">This collection is complete! 🎉</p>` + "`" + `;
-            }
-            
-            document.getElementById('modalOverlay').classList.remove('hidden');
-            document.body.style.overflow = 'hidden';
-        }
-        
-        function closeModal() {
-            document.getElementById('modalOverlay').classList.add('hidden');
-            document.body.style.overflow = 'auto';
-        }
-        
-        // Initialize default sorting
-        sortArtists();
-    </script>
-</body>
-</html>`)
-
-	return html.String()
 }
 
-func printTerminalOutput(reports []GapReport, summary ReportSummary) {
+// writeSidecars writes the JSON and CSV reports the dashboard links to into dir
+func writeSidecars(reports []GapReport, summary ReportSummary, csvBOM bool, dir string) {
+	jsonData, err := jsonReport(reports, summary)
+	if err != nil {
+		log.Fatal("Error encoding JSON report:", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, jsonSidecarFile), jsonData, 0644); err != nil {
+		log.Fatal("Error writing JSON report:", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, csvSidecarFile), []byte(csvReport(reports, csvBOM)), 0644); err != nil {
+		log.Fatal("Error writing CSV report:", err)
+	}
+	log.Printf("Wrote %s and %s to %s", jsonSidecarFile, csvSidecarFile, dir)
+}
+
+// printSummary prints the summary block that heads the terminal report
+func printSummary(summary ReportSummary) {
 	fmt.Println("🎵 Nugs Collection Gap Report")
 	fmt.Println("=" + strings.Repeat("=", 50))
 	fmt.Printf("📊 Summary: %d artists monitored\n", summary.TotalArtists)
@@ -1039,7 +390,27 @@ func printTerminalOutput(reports []GapReport, summary ReportSummary) {
 	fmt.Printf("📀 Shows available: %d\n", summary.TotalShowsAvail)
 	fmt.Printf("📈 Overall completion: %.1f%%\n", summary.OverallCompletion)
 	fmt.Printf("❌ Missing shows: %d\n", summary.TotalMissing)
+	if summary.TotalFailed > 0 {
+		fmt.Printf("⚠️  Failed shows: %d (download attempts exhausted)\n", summary.TotalFailed)
+	}
+	if summary.TotalUnmatched > 0 {
+		fmt.Printf("❓ Unmatched folders: %d (no matching catalog show)\n", summary.TotalUnmatched)
+	}
+	if summary.TotalUpgrades > 0 {
+		fmt.Printf("⬆️  Upgrade candidates: %d (below the best quality ladder format)\n", summary.TotalUpgrades)
+	}
+	if len(summary.Groups) > 0 {
+		fmt.Println("🏷️  By group:")
+		for _, group := range summary.Groups {
+			fmt.Printf("   %s: %d artists, %d/%d shows (%.1f%% complete), %d missing\n",
+				group.Group, group.Artists, group.TotalShowsHave, group.TotalShowsAvail, group.Completion, group.TotalMissing)
+		}
+	}
 	fmt.Println()
+}
+
+func printTerminalOutput(reports []GapReport, summary ReportSummary) {
+	printSummary(summary)
 
 	for _, report := range reports {
 		fmt.Printf("🎤 %s\n", report.Artist)
@@ -1055,85 +426,160 @@ func printTerminalOutput(reports []GapReport, summary ReportSummary) {
 		} else if len(report.MissingShows) > 20 {
 			fmt.Printf("     ... %d missing shows (use --format html for full list)\n", len(report.MissingShows))
 		}
+
+		if len(report.FailedShows) > 0 {
+			fmt.Printf("   Failed: %d shows\n", len(report.FailedShows))
+			for _, failed := range report.FailedShows {
+				fmt.Printf("     • %s - %s, %s %s (#%d) - %d attempts, last error: %s\n",
+					failed.Date, failed.Venue, failed.City, failed.State, failed.ContainerID,
+					failed.Attempts, failed.LastError)
+			}
+		}
+
+		if len(report.UnmatchedFolders) > 0 {
+			fmt.Printf("   Unmatched folders: %d\n", len(report.UnmatchedFolders))
+			for _, folder := range report.UnmatchedFolders {
+				fmt.Printf("     • %s\n", folder)
+			}
+		}
+
+		if len(report.UpgradeCandidates) > 0 {
+			fmt.Printf("   Upgrade candidates: %d shows\n", len(report.UpgradeCandidates))
+			for _, upgrade := range report.UpgradeCandidates {
+				fmt.Printf("     • %s - %s, %s %s (#%d) - %s -> %s\n",
+					upgrade.Date, upgrade.Venue, upgrade.City, upgrade.State, upgrade.ContainerID,
+					upgrade.Format, upgrade.Target)
+			}
+		}
 		fmt.Println()
 	}
 }
 
-func generateCSVOutput(reports []GapReport, summary ReportSummary, outputFile string) {
+// jsonReport encodes the reports in the shape render and the dashboard's
+// sidecar share
+func jsonReport(reports []GapReport, summary ReportSummary) ([]byte, error) {
+	return json.MarshalIndent(SavedReport{Summary: summary, Reports: reports}, "", "  ")
+}
+
+func generateCSVOutput(reports []GapReport, bom bool, outputFile string) {
+	output := csvReport(reports, bom)
+
+	if outputFile != "" {
+		err := ioutil.WriteFile(outputFile, []byte(output), 0644)
+		if err != nil {
+			log.Fatal("Error writing CSV file:", err)
+		}
+		fmt.Printf("CSV report written to: %s\n", outputFile)
+	} else {
+		fmt.Print(output)
+	}
+}
+
+// utf8BOM marks a file as UTF-8 for readers such as Excel that otherwise
+// assume the local code page
+const utf8BOM = "\ufeff"
+
+// csvReport formats one CSV row per artist, quoted as RFC 4180 requires. bom
+// starts the report with a UTF-8 byte order mark.
+func csvReport(reports []GapReport, bom bool) string {
 	var output strings.Builder
-	
-	// CSV Header
-	output.WriteString("Artist,Total Available,Total Downloaded,Completion %,Missing Count,Missing Show IDs\n")
-	
-	// Data rows
+	if bom {
+		output.WriteString(utf8BOM)
+	}
+
+	// Writes to a strings.Builder can't fail, so neither can the writer's
+	writer := csv.NewWriter(&output)
+	writer.Write([]string{"Artist", "Total Available", "Total Downloaded", "Completion %",
+		"Missing Count", "Missing Show IDs", "Failed Count", "Failed Show IDs",
+		"Upgrade Count", "Upgrade Show IDs"})
+
 	for _, report := range reports {
 		var missingIDs []string
 		for _, missing := range report.MissingShows {
 			missingIDs = append(missingIDs, fmt.Sprintf("%d", missing.ContainerID))
 		}
-		
-		output.WriteString(fmt.Sprintf("%s,%d,%d,%.1f,%d,\"%s\"\n",
-			report.Artist,
-			report.TotalAvailable,
-			report.TotalDownloaded,
-			report.CompletionPct,
-			len(report.MissingShows),
-			strings.Join(missingIDs, ",")))
-	}
-	
-	if outputFile != "" {
-		err := ioutil.WriteFile(outputFile, []byte(output.String()), 0644)
-		if err != nil {
-			log.Fatal("Error writing CSV file:", err)
+		var failedIDs []string
+		for _, failed := range report.FailedShows {
+			failedIDs = append(failedIDs, fmt.Sprintf("%d", failed.ContainerID))
 		}
-		fmt.Printf("CSV report written to: %s\n", outputFile)
-	} else {
-		fmt.Print(output.String())
+		var upgradeIDs []string
+		for _, upgrade := range report.UpgradeCandidates {
+			upgradeIDs = append(upgradeIDs, fmt.Sprintf("%d", upgrade.ContainerID))
+		}
+
+		writer.Write([]string{
+			csvText(report.Artist),
+			strconv.Itoa(report.TotalAvailable),
+			strconv.Itoa(report.TotalDownloaded),
+			strconv.FormatFloat(report.CompletionPct, 'f', 1, 64),
+			strconv.Itoa(len(report.MissingShows)),
+			strings.Join(missingIDs, ","),
+			strconv.Itoa(len(report.FailedShows)),
+			strings.Join(failedIDs, ","),
+			strconv.Itoa(len(report.UpgradeCandidates)),
+			strings.Join(upgradeIDs, ","),
+		})
+	}
+	writer.Flush()
+
+	return output.String()
+}
+
+// csvText neutralizes a free-text field a spreadsheet would otherwise run as a
+// formula, by prefixing a leading =, +, -, @, tab or carriage return with a
+// single quote
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
 	}
+	return value
 }
 
+// sortReports orders reports by sortBy, breaking ties by artist name and
+// then ID so the same data always sorts the same way
 func sortReports(reports []GapReport, sortBy string) {
-	sort.Slice(reports, func(i, j int) bool {
+	sort.SliceStable(reports, func(i, j int) bool {
+		a, b := reports[i], reports[j]
 		switch sortBy {
 		case "completion":
-			return reports[j].CompletionPct < reports[i].CompletionPct // Lowest completion first
+			if a.CompletionPct != b.CompletionPct {
+				return b.CompletionPct < a.CompletionPct // Highest completion first
+			}
 		case "missing":
-			return reports[i].MissingCount > reports[j].MissingCount // Most missing first
+			if a.MissingCount != b.MissingCount {
+				return a.MissingCount > b.MissingCount // Most missing first
+			}
 		case "total":
-			return reports[i].TotalAvailable > reports[j].TotalAvailable // Most shows first
-		default: // "artist"
-			return reports[i].Artist < reports[j].Artist
+			if a.TotalAvailable != b.TotalAvailable {
+				return a.TotalAvailable > b.TotalAvailable // Most shows first
+			}
 		}
+		if a.Artist != b.Artist {
+			return a.Artist < b.Artist
+		}
+		return a.ArtistID < b.ArtistID
 	})
 }
 
-// Helper functions
-func loadShowsData() (*models.ShowsData, error) {
-	data, err := ioutil.ReadFile("data/shows.json")
-	if err != nil {
-		return nil, err
-	}
-	
-	var shows models.ShowsData
-	err = json.Unmarshal(data, &shows)
-	if err != nil {
-		return nil, err
-	}
-	
-	if shows.Artists == nil {
-		shows.Artists = make(map[string]models.ArtistShowData)
+// withoutComplete drops artists with every available show downloaded
+func withoutComplete(reports []GapReport) []GapReport {
+	var incomplete []GapReport
+	for _, report := range reports {
+		if report.CompletionPct < 100 {
+			incomplete = append(incomplete, report)
+		}
 	}
-	
-	return &shows, nil
+	return incomplete
 }
 
+// Helper functions
 func loadMonitorConfig(filename string) (*models.MonitorConfig, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	var config models.MonitorConfig
 	err = json.Unmarshal(data, &config)
 	return &config, err
-}
\ No newline at end of file
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortReports_TiesSortByArtist(t *testing.T) {
+	reports := func() []GapReport {
+		return []GapReport{
+			{Artist: "Twiddle", ArtistID: 4, CompletionPct: 100, TotalAvailable: 10},
+			{Artist: "Goose", ArtistID: 2, CompletionPct: 50, MissingCount: 5, TotalAvailable: 10},
+			{Artist: "Phish", ArtistID: 3, CompletionPct: 100, TotalAvailable: 30},
+			{Artist: "Goose", ArtistID: 1, CompletionPct: 50, MissingCount: 5, TotalAvailable: 10},
+			{Artist: "Billy Strings", ArtistID: 5, CompletionPct: 100, TotalAvailable: 10},
+		}
+	}
+	ids := func(reports []GapReport) []int {
+		var ids []int
+		for _, report := range reports {
+			ids = append(ids, report.ArtistID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		sortBy   string
+		expected []int
+	}{
+		{"artist", []int{5, 1, 2, 3, 4}},
+		{"completion", []int{5, 3, 4, 1, 2}},
+		{"missing", []int{1, 2, 5, 3, 4}},
+		{"total", []int{3, 5, 1, 2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			r := reports()
+			sortReports(r, tt.sortBy)
+			assert.Equal(t, tt.expected, ids(r))
+		})
+	}
+}
+
+func TestCSVReport_Quoting(t *testing.T) {
+	reports := []GapReport{
+		{
+			Artist:          `Béla Fleck, "The Flecktones"`,
+			TotalAvailable:  3,
+			TotalDownloaded: 1,
+			CompletionPct:   100.0 / 3,
+			MissingShows:    []MissingShow{{ContainerID: 101}, {ContainerID: 102}},
+		},
+		{Artist: "Phish", TotalAvailable: 1, TotalDownloaded: 1, CompletionPct: 100},
+	}
+
+	records, err := csv.NewReader(strings.NewReader(csvReport(reports, false))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "Artist", records[0][0])
+	assert.Equal(t, []string{`Béla Fleck, "The Flecktones"`, "3", "1", "33.3", "2", "101,102", "0", "", "0", ""}, records[1])
+	assert.Equal(t, []string{"Phish", "1", "1", "100.0", "0", "", "0", "", "0", ""}, records[2])
+
+	// The byte order mark precedes an otherwise identical report
+	withBOM := csvReport(reports, true)
+	assert.True(t, strings.HasPrefix(withBOM, "\xef\xbb\xbf"))
+	assert.Equal(t, csvReport(reports, false), strings.TrimPrefix(withBOM, utf8BOM))
+}
+
+func TestCSVReport_NeutralizesFormulas(t *testing.T) {
+	reports := []GapReport{
+		{Artist: `=HYPERLINK("http://evil","x")`},
+		{Artist: "+1 Band"},
+		{Artist: "-ish"},
+		{Artist: "@home"},
+		{Artist: "Tab\tin the middle"},
+	}
+
+	records, err := csv.NewReader(strings.NewReader(csvReport(reports, false))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+	assert.Equal(t, `'=HYPERLINK("http://evil","x")`, records[1][0])
+	assert.Equal(t, "'+1 Band", records[2][0])
+	assert.Equal(t, "'-ish", records[3][0])
+	assert.Equal(t, "'@home", records[4][0])
+	assert.Equal(t, "Tab\tin the middle", records[5][0])
+	// Numeric columns are left alone
+	assert.Equal(t, "0", records[1][1])
+}
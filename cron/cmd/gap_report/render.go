@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// SavedReport is the shape written by --format json
+type SavedReport struct {
+	Summary ReportSummary `json:"summary"`
+	Reports []GapReport   `json:"reports"`
+}
+
+// renderReport regenerates the HTML dashboard from a saved JSON report,
+// without loading the catalog or scanning tootie
+func renderReport(args []string) {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	sortBy := flags.String("sort", "", "Re-sort by: artist, completion, missing, total (default: keep the saved order)")
+	outputFile := flags.String("output", "", "Output file (default: stdout)")
+	theme := flags.String("theme", "dark", "Color theme: "+strings.Join(Themes, ", "))
+	selfContained := flags.Bool("self-contained", false, "Inline the scripts so the dashboard renders offline")
+	includeComplete := flags.Bool("include-complete", true, "List 100% complete artists (the saved summary still counts them)")
+	groupBy := flags.String("group-by", "", "Recompute per-group totals: "+strings.Join(GroupBys, ", "))
+	csvBOM := flags.Bool("csv-bom", false, "Start the report.csv sidecar and CSV export with a UTF-8 byte order mark")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: gap_report render [--sort by] [--theme name] [--self-contained] [--group-by tag] [--include-complete=false] [--csv-bom] [--output file] <report.json>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if !validTheme(*theme) {
+		log.Fatalf("Unknown theme: %s", *theme)
+	}
+	if !validGroupBy(*groupBy) {
+		log.Fatalf("Unknown group-by: %s", *groupBy)
+	}
+
+	saved, err := loadSavedReport(flags.Arg(0))
+	if err != nil {
+		log.Fatal("Error loading saved report:", err)
+	}
+	log.Printf("Loaded saved report with %d artists", len(saved.Reports))
+
+	if *sortBy != "" {
+		sortReports(saved.Reports, *sortBy)
+	}
+	if *groupBy == "tag" {
+		saved.Summary.Groups = groupByTag(saved.Reports)
+	}
+	if !*includeComplete {
+		saved.Reports = withoutComplete(saved.Reports)
+	}
+
+	writeHTMLOutput(saved.Reports, saved.Summary, dashboardOptions{Theme: *theme, SelfContained: *selfContained, HideComplete: !*includeComplete, CSVBOM: *csvBOM}, *outputFile)
+}
+
+// loadSavedReport reads a report written by --format json
+func loadSavedReport(filename string) (*SavedReport, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// A report with no artists saves "reports" as null, so check the key is there
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%s is not a gap report: %v", filename, err)
+	}
+	if _, ok := fields["reports"]; !ok {
+		return nil, fmt.Errorf("%s has no reports, was it written with --format json?", filename)
+	}
+
+	var saved SavedReport
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("%s is not a gap report: %v", filename, err)
+	}
+
+	return &saved, nil
+}
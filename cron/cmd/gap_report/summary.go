@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/quality"
+)
+
+// artistSelected reports whether a monitored artist passes the --artist filter
+func artistSelected(artist, filter string) bool {
+	return filter == "" || strings.Contains(strings.ToLower(artist), strings.ToLower(filter))
+}
+
+// summarizeShows computes the report totals from shows.json alone, without
+// the catalog lookups that give missing shows their venue details. An
+// artist counts toward --min-missing by its shows.json missing list, since
+// there's no catalog to drop shows that have since been pulled.
+func summarizeShows(monitorConfig *models.MonitorConfig, showsData *models.ShowsData, ladder *quality.Ladder, artistFilter string, minMissing int, groupBy string) ReportSummary {
+	var summary ReportSummary
+	// Count-only reports, so groups roll up the same way as the full report
+	var counts []GapReport
+	for _, artistConfig := range monitorConfig.Artists {
+		if !artistConfig.Monitor || !artistSelected(artistConfig.Artist, artistFilter) {
+			continue
+		}
+
+		artistData, exists := showsData.Artists[artistConfig.Artist]
+		if !exists {
+			log.Printf("Warning: No show data found for monitored artist: %s", artistConfig.Artist)
+			continue
+		}
+
+		upgrades := 0
+		for _, showID := range artistData.Downloaded {
+			if ladder.NeedsUpgrade(artistData.Formats[showID]) {
+				upgrades++
+			}
+		}
+
+		if len(artistData.Missing) >= minMissing {
+			counts = append(counts, GapReport{
+				TotalAvailable:  len(artistData.Available),
+				TotalDownloaded: len(artistData.Downloaded),
+				MissingCount:    len(artistData.Missing),
+				FailedCount:     len(artistData.Failed),
+				UpgradeCount:    upgrades,
+				Tags:            artistConfig.Tags,
+			})
+		}
+
+		summary.TotalShowsHave += len(artistData.Downloaded)
+		summary.TotalShowsAvail += len(artistData.Available)
+		summary.TotalMissing += len(artistData.Missing)
+		summary.TotalFailed += len(artistData.Failed)
+		summary.TotalUnmatched += len(artistData.Unmatched)
+		summary.TotalUpgrades += upgrades
+	}
+
+	summary.TotalArtists = len(counts)
+	if groupBy == "tag" {
+		summary.Groups = groupByTag(counts)
+	}
+
+	if summary.TotalShowsAvail > 0 {
+		summary.OverallCompletion = float64(summary.TotalShowsHave) / float64(summary.TotalShowsAvail) * 100
+	}
+	return summary
+}
+
+// jsonSummary encodes just the summary block, for --summary-only
+func jsonSummary(summary ReportSummary) ([]byte, error) {
+	return json.MarshalIndent(struct {
+		Summary ReportSummary `json:"summary"`
+	}{summary}, "", "  ")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/quality"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeShows(t *testing.T) {
+	monitorConfig := &models.MonitorConfig{Artists: []models.Artist{
+		{ID: 1, Artist: "Goose", Monitor: true},
+		{ID: 2, Artist: "Phish", Monitor: true},
+		{ID: 3, Artist: "moe.", Monitor: false},
+		{ID: 4, Artist: "Twiddle", Monitor: true},
+	}}
+	showsData := &models.ShowsData{Artists: map[string]models.ArtistShowData{
+		"Goose": {
+			Available:  []int{1, 2, 3, 4},
+			Downloaded: []int{1, 2},
+			Missing:    []int{3},
+			Failed:     []int{4},
+			Formats:    map[int]string{1: "flac", 2: "aac"},
+		},
+		"Phish": {
+			Available:  []int{10, 11},
+			Downloaded: []int{10, 11},
+			Unmatched:  []string{"Phish - misc"},
+		},
+		"moe.": {Available: []int{20}, Missing: []int{20}},
+	}}
+	ladder, err := quality.NewLadder([]string{"flac", "aac"})
+	require.NoError(t, err)
+
+	summary := summarizeShows(monitorConfig, showsData, ladder, "", 0, "")
+	assert.Equal(t, ReportSummary{
+		TotalArtists:      2,
+		TotalShowsHave:    4,
+		TotalShowsAvail:   6,
+		OverallCompletion: float64(4) / 6 * 100,
+		TotalMissing:      1,
+		TotalFailed:       1,
+		TotalUnmatched:    1,
+		TotalUpgrades:     1,
+	}, summary)
+
+	// --min-missing drops Phish from the artist count, not from the totals
+	summary = summarizeShows(monitorConfig, showsData, ladder, "", 1, "")
+	assert.Equal(t, 1, summary.TotalArtists)
+	assert.Equal(t, 6, summary.TotalShowsAvail)
+
+	summary = summarizeShows(monitorConfig, showsData, ladder, "phi", 0, "")
+	assert.Equal(t, 1, summary.TotalArtists)
+	assert.Equal(t, 2, summary.TotalShowsHave)
+	assert.Equal(t, 100.0, summary.OverallCompletion)
+}
@@ -2,16 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
+	"github.com/jmagar/nugs/cron/internal/version"
 )
 
 // APIConfig holds configuration for API safety features
@@ -22,28 +27,84 @@ type APIConfig struct {
 	MaxConsecutiveErrors int    `json:"max_consecutive_errors"`
 	RetryDelaySeconds    int    `json:"retry_delay_seconds"`
 	RetryMaxAttempts     int    `json:"retry_max_attempts"`
+	RequestTimeoutSecs   int    `json:"request_timeout_seconds"`
 	EnableEmergencyStop  bool   `json:"enable_emergency_stop"`
 	LogDirectory         string `json:"log_directory"`
+	// UserAgent identifies this client to nugs.net. Empty uses the default
+	// "nugs-cron/<version>".
+	UserAgent string `json:"user_agent"`
+	// ErrorRateThreshold opens the circuit breaker when at least this
+	// percentage of the last ErrorRateWindow requests failed. 0 disables it.
+	ErrorRateThreshold float64 `json:"error_rate_threshold"`
+	ErrorRateWindow    int     `json:"error_rate_window"`
+	// BreakerCooldownSecs is how long an open circuit breaker blocks
+	// requests before letting one through to test recovery
+	BreakerCooldownSecs int `json:"breaker_cooldown_seconds"`
+	// DedupeWindowSecs is how long a successful response is reused for an
+	// identical request. 0 only coalesces requests made at the same time.
+	DedupeWindowSecs int `json:"dedupe_window_seconds"`
 }
 
-// APIStats tracks API usage statistics
+// Defaults for API config values that must be positive
+const (
+	defaultMaxConsecutiveErrors = 5
+	defaultErrorRateWindow      = 20
+	defaultBreakerCooldownSecs  = 300
+)
+
+// APIStats tracks API usage statistics. The embedded counters cover every
+// request; Accounts breaks them down per nugs.net account.
 type APIStats struct {
-	TotalRequestsToday int                      `json:"total_requests_today"`
-	RequestsThisHour   int                      `json:"requests_this_hour"`
-	RequestsThisMinute int                      `json:"requests_this_minute"`
-	LastRequestTime    string                   `json:"last_request_time"`
-	Endpoints          map[string]EndpointStats `json:"endpoints"`
-	ConsecutiveErrors  int                      `json:"consecutive_errors"`
-	CircuitBreakerOpen bool                     `json:"circuit_breaker_open"`
-	CurrentDate        string                   `json:"current_date"`
-	CurrentHour        int                      `json:"current_hour"`
-	CurrentMinute      int                      `json:"current_minute"`
+	RequestCounters
+	Endpoints map[string]EndpointStats `json:"endpoints"`
+	Accounts  map[string]*AccountStats `json:"accounts,omitempty"`
+	// DedupedRequests counts requests answered by an identical request
+	// instead of nugs.net
+	DedupedRequests int `json:"deduped_requests"`
+}
+
+// RequestCounters holds the rate limit counters and circuit breaker state
+type RequestCounters struct {
+	TotalRequestsToday int    `json:"total_requests_today"`
+	RequestsThisHour   int    `json:"requests_this_hour"`
+	RequestsThisMinute int    `json:"requests_this_minute"`
+	LastRequestTime    string `json:"last_request_time"`
+	ConsecutiveErrors  int    `json:"consecutive_errors"`
+	CircuitBreakerOpen bool   `json:"circuit_breaker_open"`
+	CurrentDate        string `json:"current_date"`
+	CurrentHour        int    `json:"current_hour"`
+	CurrentMinute      int    `json:"current_minute"`
+
+	// recentFailures records whether each of the most recent requests failed,
+	// oldest first, for the rolling error rate. It is not persisted.
+	recentFailures []bool
+}
+
+// AccountStats tracks one account's requests. Rate limits and the circuit
+// breaker apply to each account separately.
+type AccountStats struct {
+	RequestCounters
+	Authenticated bool `json:"authenticated"`
 }
 
 // EndpointStats tracks per-endpoint statistics
 type EndpointStats struct {
 	Count  int `json:"count"`
 	Errors int `json:"errors"`
+	// Latencies holds the response times in ms of the most recent
+	// maxLatencySamples responses, oldest first
+	Latencies []int64 `json:"latencies_ms,omitempty"`
+}
+
+// maxLatencySamples bounds the response times kept per endpoint
+const maxLatencySamples = 500
+
+// Percentile returns the p-th percentile of the endpoint's recent response
+// times in ms, or 0 when none were recorded
+func (s EndpointStats) Percentile(p int) int64 {
+	samples := make([]int64, len(s.Latencies))
+	copy(samples, s.Latencies)
+	return percentile(samples, p)
 }
 
 // APILogEntry represents a single API request log entry
@@ -54,15 +115,69 @@ type APILogEntry struct {
 	ResponseCode int    `json:"response_code"`
 	ResponseTime int64  `json:"response_time_ms"`
 	Error        string `json:"error,omitempty"`
+	// Classification is the kind of failure, empty on success
+	Classification ErrorClass `json:"classification,omitempty"`
+}
+
+// ErrorClass classifies a failed request by how the client reacts to it
+type ErrorClass string
+
+const (
+	// ErrorClassNetwork is a transport failure, retried with backoff
+	ErrorClassNetwork ErrorClass = "network"
+	// ErrorClassRateLimited is a 429, retried after its Retry-After
+	ErrorClassRateLimited ErrorClass = "rate_limited"
+	// ErrorClassServer is a 5xx, retried with backoff
+	ErrorClassServer ErrorClass = "server"
+	// ErrorClassAuth is a 401, retried once after logging in again
+	ErrorClassAuth ErrorClass = "auth"
+	// ErrorClassNotFound is a 404. It is not retried and does not count
+	// towards the circuit breaker.
+	ErrorClassNotFound ErrorClass = "not_found"
+	// ErrorClassClient is any other 4xx, which is not retried
+	ErrorClassClient ErrorClass = "client"
+)
+
+// StatusError is returned when nugs.net answers with a non-200 status
+type StatusError struct {
+	StatusCode int
+	Class      ErrorClass
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
 }
 
-// SafeAPIClient provides rate-limited, logged API access
+// ErrorRateAlert describes a circuit breaker opened by a high rolling error
+// rate rather than by consecutive errors
+type ErrorRateAlert struct {
+	Account   string  `json:"account,omitempty"` // empty for unauthenticated requests
+	Endpoint  string  `json:"endpoint"`          // the request that crossed the threshold
+	ErrorRate float64 `json:"error_rate"`
+	Threshold float64 `json:"threshold"`
+	Window    int     `json:"window"`
+}
+
+// SafeAPIClient provides rate-limited, logged API access. Authenticated
+// requests rotate round-robin among the logged in accounts, skipping accounts
+// whose circuit breaker is open or that have reached a rate limit.
 type SafeAPIClient struct {
-	config     *APIConfig
-	stats      *APIStats
-	mutex      sync.Mutex
-	httpClient *http.Client
-	token      string
+	config      *APIConfig
+	stats       *APIStats
+	mutex       sync.Mutex
+	httpClient  *http.Client
+	accounts    []*nugsAccount
+	nextAccount int
+	onErrorRate func(ErrorRateAlert)
+	requests    *flightGroup
+}
+
+// nugsAccount is a logged in account and its API token. The password is
+// kept so the account can log in again when its token is rejected.
+type nugsAccount struct {
+	email    string
+	password string
+	token    string
 }
 
 // NewSafeAPIClient creates a new safe API client
@@ -74,118 +189,238 @@ func NewSafeAPIClient() *SafeAPIClient {
 	os.MkdirAll(config.LogDirectory, 0755)
 
 	return &SafeAPIClient{
-		config: config,
-		stats:  stats,
+		config:   config,
+		stats:    stats,
+		requests: requestGroup,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: time.Duration(config.RequestTimeoutSecs) * time.Second,
 		},
 	}
 }
 
-// Authenticate with Nugs.net API
+// SetRequestTimeout overrides the per-request timeout from the API config
+func (c *SafeAPIClient) SetRequestTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+}
+
+// OnErrorRateAlert sets a function called after the rolling error rate opens
+// a circuit breaker, in addition to the log message
+func (c *SafeAPIClient) OnErrorRateAlert(fn func(ErrorRateAlert)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onErrorRate = fn
+}
+
+// get sends a GET request carrying the configured User-Agent
+func (c *SafeAPIClient) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	return c.httpClient.Do(req)
+}
+
+// Authenticate with Nugs.net API using a single account
 func (c *SafeAPIClient) Authenticate(email, password string) error {
+	return c.AuthenticateAccounts([]models.NugsAccount{{Email: email, Password: password}})
+}
+
+// AuthenticateAccounts logs in to each account and rotates later requests
+// among the ones that succeeded. It fails only if no account could log in.
+func (c *SafeAPIClient) AuthenticateAccounts(accounts []models.NugsAccount) error {
+	if len(accounts) == 0 {
+		return fmt.Errorf("no nugs.net accounts configured")
+	}
+
+	var loggedIn, failed []*nugsAccount
+	var lastErr error
+	for _, creds := range accounts {
+		account := &nugsAccount{email: creds.Email, password: creds.Password}
+		token, err := c.login(account, creds.Password)
+		if err != nil {
+			log.Printf("Authentication failed for %s: %v", creds.Email, err)
+			failed = append(failed, account)
+			lastErr = err
+			continue
+		}
+		account.token = token
+		loggedIn = append(loggedIn, account)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, account := range failed {
+		c.accountStats(account).Authenticated = false
+	}
+	for _, account := range loggedIn {
+		c.accountStats(account).Authenticated = true
+	}
+	c.saveAPIStats()
+
+	if len(loggedIn) == 0 {
+		return fmt.Errorf("authentication failed for all %d accounts: %v", len(accounts), lastErr)
+	}
+
+	c.accounts = loggedIn
+	c.nextAccount = 0
+
+	return nil
+}
+
+// login fetches an API token for account
+func (c *SafeAPIClient) login(account *nugsAccount, password string) (string, error) {
 	// First login call
 	loginURL := fmt.Sprintf("https://streamapi.nugs.net/api.aspx?method=user.site.login&pw=%s&username=%s",
 		strings.Replace(url.QueryEscape(password), "+", "%20", -1),
-		url.QueryEscape(email))
+		url.QueryEscape(account.email))
 
-	_, err := c.safeGet(loginURL, "user.site.login")
+	_, err := c.safeGet(loginURL, "user.site.login", account)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Second call to get the actual token
 	tokenURL := fmt.Sprintf("https://streamapi.nugs.net/secureapi.aspx?method=user.site.login&pw=%s&username=%s",
 		strings.Replace(url.QueryEscape(password), "+", "%20", -1),
-		url.QueryEscape(email))
+		url.QueryEscape(account.email))
 
-	body, err := c.safeGet(tokenURL, "user.site.login.secure")
+	body, err := c.safeGet(tokenURL, "user.site.login.secure", account)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Parse the response to get the token
 	var result map[string]interface{}
 	err = json.Unmarshal(body, &result)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Navigate through the nested structure
 	if response, ok := result["Response"].(map[string]interface{}); ok {
 		if token, ok := response["secureAuthenticationString"].(string); ok && token != "" {
-			c.token = token
-			return nil
+			return token, nil
 		}
 	}
 
-	return fmt.Errorf("authentication failed - could not extract token")
+	return "", fmt.Errorf("authentication failed - could not extract token")
 }
 
 // GetArtistCatalog fetches the full artist catalog
 func (c *SafeAPIClient) GetArtistCatalog() ([]byte, error) {
-	return c.safeGet("https://streamapi.nugs.net/api.aspx?method=catalog.artists", "catalog.artists")
+	return c.safeGet("https://streamapi.nugs.net/api.aspx?method=catalog.artists", "catalog.artists", nil)
 }
 
 // GetArtistShows fetches all shows for a specific artist
 func (c *SafeAPIClient) GetArtistShows(artistID int) ([]byte, error) {
-	if c.token == "" {
-		return nil, fmt.Errorf("not authenticated - call Authenticate() first")
+	account, err := c.pickAccount()
+	if err != nil {
+		return nil, err
 	}
 
-	showsURL := fmt.Sprintf("https://streamapi.nugs.net/api.aspx?method=catalog.containersAll&artistList=%d&availableOnly=1&token=%s",
-		artistID, c.token)
+	return c.authedGet("catalog.containersAll", account, func(token string) string {
+		return fmt.Sprintf("https://streamapi.nugs.net/api.aspx?method=catalog.containersAll&artistList=%d&availableOnly=1&token=%s",
+			artistID, token)
+	})
+}
+
+// authedGet performs a safeGet with account's token. When the token is
+// rejected it logs the account in again and retries once with the new token.
+func (c *SafeAPIClient) authedGet(endpoint string, account *nugsAccount, buildURL func(token string) string) ([]byte, error) {
+	body, err := c.safeGet(buildURL(c.accountToken(account)), endpoint, account)
 
-	return c.safeGet(showsURL, "catalog.containersAll")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Class != ErrorClassAuth {
+		return body, err
+	}
+
+	log.Printf("Token for %s was rejected, logging in again", account.email)
+	token, loginErr := c.login(account, account.password)
+	if loginErr != nil {
+		return nil, fmt.Errorf("re-authentication failed for %s: %v", account.email, loginErr)
+	}
+
+	c.mutex.Lock()
+	account.token = token
+	c.mutex.Unlock()
+
+	return c.safeGet(buildURL(token), endpoint, account)
+}
+
+// accountToken returns account's current API token
+func (c *SafeAPIClient) accountToken(account *nugsAccount) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return account.token
 }
 
 // GetFullCatalog fetches the complete catalog (no authentication needed)
 func (c *SafeAPIClient) GetFullCatalog() ([]byte, error) {
-	return c.safeGet("https://streamapi.nugs.net/api.aspx?method=catalog.containersAll&availableOnly=1", "catalog.containersAll.full")
+	return c.safeGet("https://streamapi.nugs.net/api.aspx?method=catalog.containersAll&availableOnly=1", "catalog.containersAll.full", nil)
 }
 
-// safeGet performs a safe HTTP GET with all safety features
-func (c *SafeAPIClient) safeGet(url, endpoint string) ([]byte, error) {
+// pickAccount returns the next logged in account that is under its rate
+// limits and whose circuit breaker is closed
+func (c *SafeAPIClient) pickAccount() (*nugsAccount, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	startTime := time.Now()
+	if len(c.accounts) == 0 {
+		return nil, fmt.Errorf("not authenticated - call Authenticate() first")
+	}
 
-	// Check emergency stop
-	if c.config.EnableEmergencyStop {
-		if _, err := os.Stat("configs/STOP_API"); err == nil {
-			return nil, fmt.Errorf("API calls stopped by emergency stop file")
+	var lastErr error
+	for i := 0; i < len(c.accounts); i++ {
+		account := c.accounts[(c.nextAccount+i)%len(c.accounts)]
+		if err := c.checkAvailable(&c.accountStats(account).RequestCounters); err != nil {
+			lastErr = err
+			continue
 		}
+		c.nextAccount = (c.nextAccount + i + 1) % len(c.accounts)
+		return account, nil
 	}
 
-	// Check circuit breaker
-	if c.stats.CircuitBreakerOpen {
-		// Try to recover after 5 minutes
-		if lastReq, err := time.Parse(time.RFC3339, c.stats.LastRequestTime); err == nil {
-			if time.Since(lastReq) > 5*time.Minute {
-				c.stats.CircuitBreakerOpen = false
-				c.stats.ConsecutiveErrors = 0
-				log.Println("Circuit breaker reset - attempting recovery")
-			} else {
-				return nil, fmt.Errorf("circuit breaker open - too many consecutive errors")
-			}
-		}
+	return nil, fmt.Errorf("no nugs.net account available: %v", lastErr)
+}
+
+// safeGet performs a safe HTTP GET with all safety features. The safety checks
+// and statistics are serialized, but the requests themselves may run
+// concurrently so callers can fetch in parallel within the rate limits.
+// Requests made for an account count against that account's limits;
+// unauthenticated requests pass a nil account and use the overall limits.
+//
+// Identical requests are coalesced: a URL already being fetched, or fetched
+// successfully within the dedupe window, is not requested again and does
+// not count against the limits.
+func (c *SafeAPIClient) safeGet(url, endpoint string, account *nugsAccount) ([]byte, error) {
+	reuse := time.Duration(c.config.DedupeWindowSecs) * time.Second
+	body, err, shared := c.requests.do(url, reuse, func() ([]byte, error) {
+		return c.fetch(url, endpoint, account)
+	})
+	if shared {
+		c.mutex.Lock()
+		c.stats.DedupedRequests++
+		c.mutex.Unlock()
 	}
+	return body, err
+}
 
-	// Check rate limits
-	if err := c.checkRateLimits(); err != nil {
+// fetch performs the request behind safeGet
+func (c *SafeAPIClient) fetch(url, endpoint string, account *nugsAccount) ([]byte, error) {
+	if err := c.beginRequest(account); err != nil {
 		return nil, err
 	}
 
-	// Update counters before request
-	c.updateRequestCounters()
+	startTime := time.Now()
 
 	// Make the actual HTTP request with retries
 	var body []byte
 	var lastError error
 
 	for attempt := 1; attempt <= c.config.RetryMaxAttempts; attempt++ {
-		resp, err := c.httpClient.Get(url)
+		resp, err := c.get(url)
 		responseTime := time.Since(startTime).Milliseconds()
 
 		logEntry := APILogEntry{
@@ -198,13 +433,13 @@ func (c *SafeAPIClient) safeGet(url, endpoint string) ([]byte, error) {
 		if err != nil {
 			logEntry.Error = err.Error()
 			logEntry.ResponseCode = 0
-			c.logRequest(logEntry)
+			logEntry.Classification = ErrorClassNetwork
+			c.recordFailure(logEntry, endpoint, account)
 
 			lastError = err
-			c.handleError(endpoint)
 
 			if attempt < c.config.RetryMaxAttempts {
-				backoff := time.Duration(c.config.RetryDelaySeconds*attempt) * time.Second
+				backoff := c.retryBackoff(attempt)
 				log.Printf("Request failed (attempt %d/%d), retrying in %v: %v",
 					attempt, c.config.RetryMaxAttempts, backoff, err)
 				time.Sleep(backoff)
@@ -221,19 +456,42 @@ func (c *SafeAPIClient) safeGet(url, endpoint string) ([]byte, error) {
 
 		if err != nil {
 			logEntry.Error = err.Error()
+			logEntry.Classification = ErrorClassNetwork
+			c.mutex.Lock()
 			c.logRequest(logEntry)
+			c.mutex.Unlock()
 			lastError = err
 			continue
 		}
 
 		if resp.StatusCode != 200 {
-			logEntry.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
-			c.logRequest(logEntry)
-			c.handleError(endpoint)
-			lastError = fmt.Errorf("HTTP %d", resp.StatusCode)
+			statusErr := &StatusError{StatusCode: resp.StatusCode, Class: classifyStatus(resp.StatusCode)}
+			logEntry.Error = statusErr.Error()
+			logEntry.Classification = statusErr.Class
+
+			// A missing resource is permanent and says nothing about the
+			// health of the API, so it neither retries nor trips the breaker
+			if statusErr.Class == ErrorClassNotFound {
+				c.mutex.Lock()
+				c.logRequest(logEntry)
+				c.mutex.Unlock()
+				return nil, statusErr
+			}
+
+			c.recordFailure(logEntry, endpoint, account)
+			lastError = statusErr
+
+			// Only rate limiting and server errors are worth retrying here.
+			// Auth failures are retried by authedGet after logging in again.
+			if statusErr.Class != ErrorClassRateLimited && statusErr.Class != ErrorClassServer {
+				return nil, statusErr
+			}
 
 			if attempt < c.config.RetryMaxAttempts {
-				backoff := time.Duration(c.config.RetryDelaySeconds*attempt) * time.Second
+				backoff := c.retryBackoff(attempt)
+				if statusErr.Class == ErrorClassRateLimited {
+					backoff = retryAfter(resp.Header.Get("Retry-After"), backoff)
+				}
 				log.Printf("HTTP error %d (attempt %d/%d), retrying in %v",
 					resp.StatusCode, attempt, c.config.RetryMaxAttempts, backoff)
 				time.Sleep(backoff)
@@ -244,71 +502,222 @@ func (c *SafeAPIClient) safeGet(url, endpoint string) ([]byte, error) {
 		}
 
 		// Success
+		c.mutex.Lock()
 		c.logRequest(logEntry)
-		c.handleSuccess(endpoint)
+		c.handleSuccess(endpoint, account)
 		c.saveAPIStats()
+		c.mutex.Unlock()
 		return body, nil
 	}
 
-	return nil, fmt.Errorf("request failed after %d attempts: %v", c.config.RetryMaxAttempts, lastError)
+	return nil, fmt.Errorf("request failed after %d attempts: %w", c.config.RetryMaxAttempts, lastError)
 }
 
-// checkRateLimits verifies we haven't exceeded any rate limits
-func (c *SafeAPIClient) checkRateLimits() error {
-	now := time.Now()
+// retryBackoff returns the linear backoff before retrying after attempt
+func (c *SafeAPIClient) retryBackoff(attempt int) time.Duration {
+	return time.Duration(c.config.RetryDelaySeconds*attempt) * time.Second
+}
 
-	// Reset counters if needed
-	currentDate := now.Format("2006-01-02")
-	currentHour := now.Hour()
-	currentMinute := now.Minute()
+// maxRetryAfter caps how long a 429 response can make a request wait
+const maxRetryAfter = 5 * time.Minute
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date. It returns fallback when the header is missing or invalid.
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return fallback
+	}
 
-	if c.stats.CurrentDate != currentDate {
-		c.stats.TotalRequestsToday = 0
-		c.stats.RequestsThisHour = 0
-		c.stats.RequestsThisMinute = 0
-		c.stats.CurrentDate = currentDate
-		c.stats.CurrentHour = currentHour
-		c.stats.CurrentMinute = currentMinute
-	} else if c.stats.CurrentHour != currentHour {
-		c.stats.RequestsThisHour = 0
-		c.stats.RequestsThisMinute = 0
-		c.stats.CurrentHour = currentHour
-		c.stats.CurrentMinute = currentMinute
-	} else if c.stats.CurrentMinute != currentMinute {
-		c.stats.RequestsThisMinute = 0
-		c.stats.CurrentMinute = currentMinute
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		wait = time.Until(at)
+	} else {
+		return fallback
 	}
 
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait
+}
+
+// classifyStatus maps a non-200 HTTP status to its error class
+func classifyStatus(code int) ErrorClass {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case code == http.StatusUnauthorized:
+		return ErrorClassAuth
+	case code == http.StatusNotFound:
+		return ErrorClassNotFound
+	case code >= 500:
+		return ErrorClassServer
+	default:
+		return ErrorClassClient
+	}
+}
+
+// beginRequest runs the emergency stop, circuit breaker and rate limit checks
+// and counts the request against the limits
+func (c *SafeAPIClient) beginRequest(account *nugsAccount) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Check emergency stop
+	if c.config.EnableEmergencyStop {
+		if _, err := os.Stat(paths.Config("STOP_API")); err == nil {
+			return fmt.Errorf("API calls stopped by emergency stop file")
+		}
+	}
+
+	counters := c.limitCounters(account)
+	if err := c.checkAvailable(counters); err != nil {
+		return err
+	}
+
+	// Update counters before request. The overall counters always count the
+	// request so the totals cover every account.
+	updateRequestCounters(counters)
+	if counters != &c.stats.RequestCounters {
+		resetExpiredCounters(&c.stats.RequestCounters)
+		updateRequestCounters(&c.stats.RequestCounters)
+	}
+
+	return nil
+}
+
+// checkAvailable runs the circuit breaker and rate limit checks on counters
+func (c *SafeAPIClient) checkAvailable(counters *RequestCounters) error {
+	// Check circuit breaker
+	if counters.CircuitBreakerOpen {
+		// Try to recover after the cooldown
+		if lastReq, err := time.Parse(time.RFC3339, counters.LastRequestTime); err == nil {
+			if time.Since(lastReq) > c.breakerCooldown() {
+				counters.CircuitBreakerOpen = false
+				counters.ConsecutiveErrors = 0
+				log.Println("Circuit breaker reset - attempting recovery")
+			} else {
+				return fmt.Errorf("circuit breaker open - too many errors")
+			}
+		}
+	}
+
+	// Check rate limits
+	return c.checkRateLimits(counters)
+}
+
+// breakerCooldown returns how long an open circuit breaker stays open
+func (c *SafeAPIClient) breakerCooldown() time.Duration {
+	if c.config.BreakerCooldownSecs <= 0 {
+		return defaultBreakerCooldownSecs * time.Second
+	}
+	return time.Duration(c.config.BreakerCooldownSecs) * time.Second
+}
+
+// limitCounters returns the counters a request for account is limited by
+func (c *SafeAPIClient) limitCounters(account *nugsAccount) *RequestCounters {
+	if account == nil {
+		return &c.stats.RequestCounters
+	}
+	return &c.accountStats(account).RequestCounters
+}
+
+// accountStats returns the stats for account, creating them on first use
+func (c *SafeAPIClient) accountStats(account *nugsAccount) *AccountStats {
+	if c.stats.Accounts == nil {
+		c.stats.Accounts = make(map[string]*AccountStats)
+	}
+
+	stats, exists := c.stats.Accounts[account.email]
+	if !exists {
+		stats = &AccountStats{}
+		c.stats.Accounts[account.email] = stats
+	}
+	return stats
+}
+
+// recordFailure logs a failed request and counts it towards the circuit breaker
+func (c *SafeAPIClient) recordFailure(entry APILogEntry, endpoint string, account *nugsAccount) {
+	c.mutex.Lock()
+	c.logRequest(entry)
+	alert := c.handleError(endpoint, account)
+	onErrorRate := c.onErrorRate
+	c.mutex.Unlock()
+
+	if alert != nil && onErrorRate != nil {
+		onErrorRate(*alert)
+	}
+}
+
+// checkRateLimits verifies counters haven't exceeded any rate limits
+func (c *SafeAPIClient) checkRateLimits(counters *RequestCounters) error {
+	resetExpiredCounters(counters)
+
 	// Check limits
-	if c.stats.RequestsThisMinute >= c.config.MaxRequestsPerMinute {
+	if counters.RequestsThisMinute >= c.config.MaxRequestsPerMinute {
 		return fmt.Errorf("rate limit exceeded: %d requests this minute (max: %d)",
-			c.stats.RequestsThisMinute, c.config.MaxRequestsPerMinute)
+			counters.RequestsThisMinute, c.config.MaxRequestsPerMinute)
 	}
 
-	if c.stats.RequestsThisHour >= c.config.MaxRequestsPerHour {
+	if counters.RequestsThisHour >= c.config.MaxRequestsPerHour {
 		return fmt.Errorf("rate limit exceeded: %d requests this hour (max: %d)",
-			c.stats.RequestsThisHour, c.config.MaxRequestsPerHour)
+			counters.RequestsThisHour, c.config.MaxRequestsPerHour)
 	}
 
-	if c.stats.TotalRequestsToday >= c.config.MaxRequestsPerDay {
+	if counters.TotalRequestsToday >= c.config.MaxRequestsPerDay {
 		return fmt.Errorf("rate limit exceeded: %d requests today (max: %d)",
-			c.stats.TotalRequestsToday, c.config.MaxRequestsPerDay)
+			counters.TotalRequestsToday, c.config.MaxRequestsPerDay)
 	}
 
 	return nil
 }
 
+// resetExpiredCounters zeroes the counters whose day, hour or minute has passed
+func resetExpiredCounters(counters *RequestCounters) {
+	now := time.Now()
+
+	// Reset counters if needed
+	currentDate := now.Format("2006-01-02")
+	currentHour := now.Hour()
+	currentMinute := now.Minute()
+
+	if counters.CurrentDate != currentDate {
+		counters.TotalRequestsToday = 0
+		counters.RequestsThisHour = 0
+		counters.RequestsThisMinute = 0
+		counters.CurrentDate = currentDate
+		counters.CurrentHour = currentHour
+		counters.CurrentMinute = currentMinute
+	} else if counters.CurrentHour != currentHour {
+		counters.RequestsThisHour = 0
+		counters.RequestsThisMinute = 0
+		counters.CurrentHour = currentHour
+		counters.CurrentMinute = currentMinute
+	} else if counters.CurrentMinute != currentMinute {
+		counters.RequestsThisMinute = 0
+		counters.CurrentMinute = currentMinute
+	}
+}
+
 // updateRequestCounters increments all request counters
-func (c *SafeAPIClient) updateRequestCounters() {
-	c.stats.TotalRequestsToday++
-	c.stats.RequestsThisHour++
-	c.stats.RequestsThisMinute++
-	c.stats.LastRequestTime = time.Now().Format(time.RFC3339)
+func updateRequestCounters(counters *RequestCounters) {
+	counters.TotalRequestsToday++
+	counters.RequestsThisHour++
+	counters.RequestsThisMinute++
+	counters.LastRequestTime = time.Now().Format(time.RFC3339)
 }
 
-// handleError processes API errors and updates circuit breaker
-func (c *SafeAPIClient) handleError(endpoint string) {
-	c.stats.ConsecutiveErrors++
+// handleError processes API errors and updates circuit breaker. It returns
+// an alert when the rolling error rate opened the breaker.
+func (c *SafeAPIClient) handleError(endpoint string, account *nugsAccount) *ErrorRateAlert {
+	counters := c.limitCounters(account)
+	counters.ConsecutiveErrors++
 
 	if c.stats.Endpoints == nil {
 		c.stats.Endpoints = make(map[string]EndpointStats)
@@ -318,17 +727,71 @@ func (c *SafeAPIClient) handleError(endpoint string) {
 	stats.Errors++
 	c.stats.Endpoints[endpoint] = stats
 
+	rate, full := c.recordOutcome(counters, true)
+
 	// Open circuit breaker if too many consecutive errors
-	if c.stats.ConsecutiveErrors >= c.config.MaxConsecutiveErrors {
-		c.stats.CircuitBreakerOpen = true
-		log.Printf("Circuit breaker opened after %d consecutive errors", c.stats.ConsecutiveErrors)
+	if counters.ConsecutiveErrors >= c.config.MaxConsecutiveErrors {
+		counters.CircuitBreakerOpen = true
+		if account != nil {
+			log.Printf("Circuit breaker opened for %s after %d consecutive errors", account.email, counters.ConsecutiveErrors)
+		} else {
+			log.Printf("Circuit breaker opened after %d consecutive errors", counters.ConsecutiveErrors)
+		}
+		return nil
+	}
+
+	// Errors spread among successes never trip the consecutive check, so a
+	// high error rate over the window opens the breaker too
+	if !full || c.config.ErrorRateThreshold <= 0 || rate < c.config.ErrorRateThreshold || counters.CircuitBreakerOpen {
+		return nil
+	}
+
+	counters.CircuitBreakerOpen = true
+	counters.recentFailures = nil
+
+	alert := &ErrorRateAlert{
+		Endpoint:  endpoint,
+		ErrorRate: rate,
+		Threshold: c.config.ErrorRateThreshold,
+		Window:    c.config.ErrorRateWindow,
+	}
+	if account != nil {
+		alert.Account = account.email
+		log.Printf("Circuit breaker opened for %s: %.0f%% of the last %d requests failed", account.email, rate, alert.Window)
+	} else {
+		log.Printf("Circuit breaker opened: %.0f%% of the last %d requests failed", rate, alert.Window)
+	}
+	return alert
+}
+
+// recordOutcome adds a request's outcome to counters' rolling window and
+// returns the window's error rate and whether the window is full
+func (c *SafeAPIClient) recordOutcome(counters *RequestCounters, failed bool) (float64, bool) {
+	window := c.config.ErrorRateWindow
+	if window <= 0 {
+		return 0, false
+	}
+
+	counters.recentFailures = append(counters.recentFailures, failed)
+	if len(counters.recentFailures) > window {
+		counters.recentFailures = counters.recentFailures[len(counters.recentFailures)-window:]
+	}
+
+	failures := 0
+	for _, f := range counters.recentFailures {
+		if f {
+			failures++
+		}
 	}
+	return errorRate(failures, len(counters.recentFailures)), len(counters.recentFailures) == window
 }
 
 // handleSuccess processes successful API responses
-func (c *SafeAPIClient) handleSuccess(endpoint string) {
-	c.stats.ConsecutiveErrors = 0
-	c.stats.CircuitBreakerOpen = false
+func (c *SafeAPIClient) handleSuccess(endpoint string, account *nugsAccount) {
+	counters := c.limitCounters(account)
+	counters.ConsecutiveErrors = 0
+	counters.CircuitBreakerOpen = false
+	c.recordOutcome(counters, false)
 
 	if c.stats.Endpoints == nil {
 		c.stats.Endpoints = make(map[string]EndpointStats)
@@ -339,10 +802,12 @@ func (c *SafeAPIClient) handleSuccess(endpoint string) {
 	c.stats.Endpoints[endpoint] = stats
 }
 
-// logRequest writes a request log entry to the daily log file
+// logRequest records the request's latency and writes its log entry to the
+// daily log file
 func (c *SafeAPIClient) logRequest(entry APILogEntry) {
-	logFile := filepath.Join(c.config.LogDirectory,
-		fmt.Sprintf("api_requests_%s.log", time.Now().Format("2006-01-02")))
+	c.recordLatency(entry)
+
+	logFile := RequestLogFile(c.config.LogDirectory, time.Now().Format("2006-01-02"))
 
 	logData, _ := json.Marshal(entry)
 	logLine := string(logData) + "\n"
@@ -357,36 +822,84 @@ func (c *SafeAPIClient) logRequest(entry APILogEntry) {
 	file.WriteString(logLine)
 }
 
-// LoadAPIConfig loads configuration from configs/api_config.json
+// recordLatency adds entry's response time to its endpoint's samples.
+// Requests that got no response have no meaningful latency and are skipped.
+func (c *SafeAPIClient) recordLatency(entry APILogEntry) {
+	if entry.ResponseCode == 0 {
+		return
+	}
+
+	if c.stats.Endpoints == nil {
+		c.stats.Endpoints = make(map[string]EndpointStats)
+	}
+
+	stats := c.stats.Endpoints[entry.Endpoint]
+	stats.Latencies = append(stats.Latencies, entry.ResponseTime)
+	if len(stats.Latencies) > maxLatencySamples {
+		stats.Latencies = stats.Latencies[len(stats.Latencies)-maxLatencySamples:]
+	}
+	c.stats.Endpoints[entry.Endpoint] = stats
+}
+
+// LoadAPIConfig loads configuration from configs/api_config.json under NUGS_HOME
 func LoadAPIConfig() *APIConfig {
 	config := &APIConfig{
 		MaxRequestsPerMinute: 30,
 		MaxRequestsPerHour:   500,
 		MaxRequestsPerDay:    5000,
-		MaxConsecutiveErrors: 5,
+		MaxConsecutiveErrors: defaultMaxConsecutiveErrors,
 		RetryDelaySeconds:    2,
 		RetryMaxAttempts:     3,
+		RequestTimeoutSecs:   30,
 		EnableEmergencyStop:  true,
 		LogDirectory:         "logs/api_logs",
+		ErrorRateThreshold:   50,
+		ErrorRateWindow:      defaultErrorRateWindow,
+		BreakerCooldownSecs:  defaultBreakerCooldownSecs,
+		DedupeWindowSecs:     10,
 	}
 
-	if data, err := ioutil.ReadFile("configs/api_config.json"); err == nil {
+	if data, err := ioutil.ReadFile(paths.Config("api_config.json")); err == nil {
 		json.Unmarshal(data, config)
 	}
 
+	if config.RequestTimeoutSecs <= 0 {
+		config.RequestTimeoutSecs = 30
+	}
+	if config.MaxConsecutiveErrors <= 0 {
+		config.MaxConsecutiveErrors = defaultMaxConsecutiveErrors
+	}
+	if config.ErrorRateWindow <= 0 {
+		config.ErrorRateWindow = defaultErrorRateWindow
+	}
+	if config.BreakerCooldownSecs <= 0 {
+		config.BreakerCooldownSecs = defaultBreakerCooldownSecs
+	}
+	if config.DedupeWindowSecs < 0 {
+		config.DedupeWindowSecs = 0
+	}
+	if strings.TrimSpace(config.UserAgent) == "" {
+		config.UserAgent = version.UserAgent("nugs-cron")
+	}
+
+	// Relative log directories live under NUGS_HOME
+	config.LogDirectory = paths.Resolve(config.LogDirectory)
+
 	return config
 }
 
 // loadAPIStats loads statistics from data/api_stats.json
 func loadAPIStats() *APIStats {
 	stats := &APIStats{
-		Endpoints:     make(map[string]EndpointStats),
-		CurrentDate:   time.Now().Format("2006-01-02"),
-		CurrentHour:   time.Now().Hour(),
-		CurrentMinute: time.Now().Minute(),
+		RequestCounters: RequestCounters{
+			CurrentDate:   time.Now().Format("2006-01-02"),
+			CurrentHour:   time.Now().Hour(),
+			CurrentMinute: time.Now().Minute(),
+		},
+		Endpoints: make(map[string]EndpointStats),
 	}
 
-	if data, err := ioutil.ReadFile("data/api_stats.json"); err == nil {
+	if data, err := ioutil.ReadFile(paths.Data("api_stats.json")); err == nil {
 		json.Unmarshal(data, stats)
 	}
 
@@ -396,7 +909,7 @@ func loadAPIStats() *APIStats {
 // saveAPIStats saves current statistics to data/api_stats.json
 func (c *SafeAPIClient) saveAPIStats() {
 	data, _ := json.MarshalIndent(c.stats, "", "  ")
-	ioutil.WriteFile("data/api_stats.json", data, 0644)
+	ioutil.WriteFile(paths.Data("api_stats.json"), data, 0644)
 }
 
 // GetStats returns current API statistics
@@ -416,7 +929,12 @@ func (c *SafeAPIClient) ResetStats() {
 	c.stats.RequestsThisMinute = 0
 	c.stats.ConsecutiveErrors = 0
 	c.stats.CircuitBreakerOpen = false
+	c.stats.recentFailures = nil
+	c.stats.DedupedRequests = 0
 	c.stats.Endpoints = make(map[string]EndpointStats)
+	for _, account := range c.stats.Accounts {
+		account.RequestCounters = RequestCounters{}
+	}
 
 	c.saveAPIStats()
 	log.Println("API statistics reset")
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/paths"
+	"github.com/jmagar/nugs/cron/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client logged in to the given accounts without
+// touching the network or the stats file
+func newTestClient(emails ...string) *SafeAPIClient {
+	client := &SafeAPIClient{
+		config: &APIConfig{
+			MaxRequestsPerMinute: 5,
+			MaxRequestsPerHour:   100,
+			MaxRequestsPerDay:    1000,
+			MaxConsecutiveErrors: 3,
+		},
+		stats:    &APIStats{Endpoints: make(map[string]EndpointStats)},
+		requests: &flightGroup{calls: make(map[string]*flightCall)},
+	}
+	for _, email := range emails {
+		client.accounts = append(client.accounts, &nugsAccount{email: email, token: "token-" + email})
+	}
+	return client
+}
+
+func TestPickAccount_RotatesRoundRobin(t *testing.T) {
+	client := newTestClient("a@example.com", "b@example.com", "c@example.com")
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		account, err := client.pickAccount()
+		require.NoError(t, err)
+		picked = append(picked, account.email)
+	}
+
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "c@example.com", "a@example.com"}, picked)
+}
+
+func TestPickAccount_SkipsUnavailableAccounts(t *testing.T) {
+	client := newTestClient("a@example.com", "b@example.com")
+	a, b := client.accounts[0], client.accounts[1]
+
+	// a's circuit breaker opened just now
+	for i := 0; i < client.config.MaxConsecutiveErrors; i++ {
+		require.NoError(t, client.beginRequest(a))
+		client.handleError("catalog.containersAll", a)
+	}
+	assert.True(t, client.accountStats(a).CircuitBreakerOpen)
+
+	account, err := client.pickAccount()
+	require.NoError(t, err)
+	assert.Equal(t, b.email, account.email)
+
+	// b reaches its per-minute limit, leaving no account
+	for i := 0; i < client.config.MaxRequestsPerMinute; i++ {
+		require.NoError(t, client.beginRequest(b))
+	}
+	_, err = client.pickAccount()
+	assert.Error(t, err)
+
+	// The overall counters include every account's requests
+	assert.Equal(t, client.config.MaxConsecutiveErrors+client.config.MaxRequestsPerMinute, client.stats.TotalRequestsToday)
+}
+
+func TestPickAccount_BreakerRecovers(t *testing.T) {
+	client := newTestClient("a@example.com")
+	stats := client.accountStats(client.accounts[0])
+	stats.CircuitBreakerOpen = true
+	stats.LastRequestTime = time.Now().Add(-10 * time.Minute).Format(time.RFC3339)
+
+	account, err := client.pickAccount()
+	require.NoError(t, err)
+	assert.Equal(t, "a@example.com", account.email)
+	assert.False(t, stats.CircuitBreakerOpen)
+}
+
+func TestPickAccount_NotAuthenticated(t *testing.T) {
+	client := newTestClient()
+
+	_, err := client.pickAccount()
+	assert.Error(t, err)
+}
+
+func TestLoadAPIConfig_UserAgent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnv, home)
+
+	assert.Equal(t, "nugs-cron/"+version.Version, LoadAPIConfig().UserAgent)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "configs"), 0755))
+	require.NoError(t, os.WriteFile(paths.Config("api_config.json"), []byte(`{"user_agent": "my-client/2.0"}`), 0644))
+	assert.Equal(t, "my-client/2.0", LoadAPIConfig().UserAgent)
+}
+
+func TestSafeGet_SendsUserAgent(t *testing.T) {
+	t.Setenv(paths.HomeEnv, t.TempDir())
+
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.UserAgent()
+	}))
+	defer server.Close()
+
+	client := newTestClient()
+	client.config.UserAgent = "my-client/2.0"
+	client.config.RetryMaxAttempts = 1
+	client.config.LogDirectory = t.TempDir()
+	client.httpClient = server.Client()
+
+	_, err := client.safeGet(server.URL, "test", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "my-client/2.0", got)
+}
+
+// roundTripFunc serves the client's requests from a handler, whatever the host
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newHandlerClient returns an authenticated test client whose requests are
+// answered by handler
+func newHandlerClient(t *testing.T, handler http.HandlerFunc) *SafeAPIClient {
+	t.Setenv(paths.HomeEnv, t.TempDir())
+
+	client := newTestClient("a@example.com")
+	client.config.RetryMaxAttempts = 3
+	client.config.LogDirectory = t.TempDir()
+	client.httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, r)
+		return recorder.Result(), nil
+	})}
+	return client
+}
+
+func TestClassifyStatus(t *testing.T) {
+	assert.Equal(t, ErrorClassRateLimited, classifyStatus(http.StatusTooManyRequests))
+	assert.Equal(t, ErrorClassAuth, classifyStatus(http.StatusUnauthorized))
+	assert.Equal(t, ErrorClassNotFound, classifyStatus(http.StatusNotFound))
+	assert.Equal(t, ErrorClassServer, classifyStatus(http.StatusBadGateway))
+	assert.Equal(t, ErrorClassClient, classifyStatus(http.StatusBadRequest))
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 7*time.Second, retryAfter("7", time.Second))
+	assert.Equal(t, time.Second, retryAfter("", time.Second))
+	assert.Equal(t, time.Second, retryAfter("soon", time.Second))
+	assert.Equal(t, maxRetryAfter, retryAfter("86400", time.Second))
+
+	wait := retryAfter(time.Now().Add(30*time.Second).UTC().Format(http.TimeFormat), time.Second)
+	assert.InDelta(t, 30*time.Second, wait, float64(2*time.Second))
+}
+
+func TestSafeGet_NotFoundSkipsBreaker(t *testing.T) {
+	requests := 0
+	client := newHandlerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := client.safeGet("https://example.com/missing", "test", nil)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, ErrorClassNotFound, statusErr.Class)
+	assert.Equal(t, 1, requests)
+	assert.Zero(t, client.stats.ConsecutiveErrors)
+}
+
+func TestSafeGet_RetriesServerErrors(t *testing.T) {
+	requests := 0
+	client := newHandlerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+
+	body, err := client.safeGet("https://example.com/flaky", "test", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, 3, requests)
+}
+
+func TestSafeGet_ClientErrorNotRetried(t *testing.T) {
+	requests := 0
+	client := newHandlerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	_, err := client.safeGet("https://example.com/bad", "test", nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 1, client.stats.ConsecutiveErrors)
+}
+
+func TestGetArtistShows_ReauthenticatesOnce(t *testing.T) {
+	logins := 0
+	client := newHandlerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch query.Get("method") {
+		case "user.site.login":
+			logins++
+			fmt.Fprint(w, `{"Response": {"secureAuthenticationString": "fresh-token"}}`)
+		case "catalog.containersAll":
+			if query.Get("token") != "fresh-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, "shows")
+		}
+	})
+
+	body, err := client.GetArtistShows(1)
+	require.NoError(t, err)
+	assert.Equal(t, "shows", string(body))
+	assert.Equal(t, 2, logins) // both login calls, made once
+	assert.Equal(t, "fresh-token", client.accounts[0].token)
+}
+
+func TestEndpointStats_Percentile(t *testing.T) {
+	stats := EndpointStats{Latencies: []int64{50, 10, 40, 20, 30}}
+
+	assert.Equal(t, int64(30), stats.Percentile(50))
+	assert.Equal(t, int64(50), stats.Percentile(99))
+	assert.Equal(t, []int64{50, 10, 40, 20, 30}, stats.Latencies, "samples stay in arrival order")
+	assert.Zero(t, EndpointStats{}.Percentile(95))
+}
+
+func TestRecordLatency_KeepsRecentSamples(t *testing.T) {
+	client := newTestClient()
+	for i := 1; i <= maxLatencySamples+10; i++ {
+		client.recordLatency(APILogEntry{Endpoint: "catalog.artists", ResponseCode: 200, ResponseTime: int64(i)})
+	}
+	client.recordLatency(APILogEntry{Endpoint: "catalog.artists", ResponseTime: 99999})
+
+	latencies := client.stats.Endpoints["catalog.artists"].Latencies
+	require.Len(t, latencies, maxLatencySamples)
+	assert.Equal(t, int64(11), latencies[0])
+	assert.Equal(t, int64(maxLatencySamples+10), latencies[len(latencies)-1])
+}
+
+func TestHandleError_ErrorRateOpensBreaker(t *testing.T) {
+	client := newTestClient("a@example.com")
+	client.config.MaxConsecutiveErrors = 5
+	client.config.ErrorRateThreshold = 50
+	client.config.ErrorRateWindow = 6
+	client.config.LogDirectory = t.TempDir()
+	account := client.accounts[0]
+
+	var alerts []ErrorRateAlert
+	client.OnErrorRateAlert(func(alert ErrorRateAlert) { alerts = append(alerts, alert) })
+
+	// Alternating failures never reach 5 in a row but fail half the requests
+	for i := 0; i < 3; i++ {
+		client.handleSuccess("catalog.containersAll", account)
+		client.recordFailure(APILogEntry{}, "catalog.containersAll", account)
+	}
+
+	stats := client.accountStats(account)
+	assert.True(t, stats.CircuitBreakerOpen)
+	assert.Equal(t, 1, stats.ConsecutiveErrors)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "a@example.com", alerts[0].Account)
+	assert.Equal(t, 50.0, alerts[0].ErrorRate)
+	assert.Equal(t, 6, alerts[0].Window)
+}
+
+func TestHandleError_ErrorRateNeedsFullWindow(t *testing.T) {
+	client := newTestClient("a@example.com")
+	client.config.MaxConsecutiveErrors = 5
+	client.config.ErrorRateThreshold = 50
+	client.config.ErrorRateWindow = 20
+	account := client.accounts[0]
+
+	client.handleSuccess("catalog.containersAll", account)
+	assert.Nil(t, client.handleError("catalog.containersAll", account))
+	assert.False(t, client.accountStats(account).CircuitBreakerOpen)
+}
+
+func TestCheckAvailable_ConfiguredCooldown(t *testing.T) {
+	client := newTestClient("a@example.com")
+	client.config.BreakerCooldownSecs = 60
+	stats := client.accountStats(client.accounts[0])
+	stats.CircuitBreakerOpen = true
+
+	stats.LastRequestTime = time.Now().Add(-30 * time.Second).Format(time.RFC3339)
+	assert.Error(t, client.checkAvailable(&stats.RequestCounters))
+
+	stats.LastRequestTime = time.Now().Add(-2 * time.Minute).Format(time.RFC3339)
+	assert.NoError(t, client.checkAvailable(&stats.RequestCounters))
+	assert.False(t, stats.CircuitBreakerOpen)
+}
+
+func TestLoadAPIConfig_BreakerDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnv, home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "configs"), 0755))
+	require.NoError(t, os.WriteFile(paths.Config("api_config.json"),
+		[]byte(`{"max_consecutive_errors": 0, "breaker_cooldown_seconds": -1, "error_rate_threshold": 0}`), 0644))
+
+	config := LoadAPIConfig()
+	assert.Equal(t, defaultMaxConsecutiveErrors, config.MaxConsecutiveErrors)
+	assert.Equal(t, defaultBreakerCooldownSecs, config.BreakerCooldownSecs)
+	assert.Zero(t, config.ErrorRateThreshold, "0 disables the error rate check")
+}
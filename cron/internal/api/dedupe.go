@@ -0,0 +1,71 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// requestGroup is shared by every client NewSafeAPIClient creates, so a URL
+// fetched by several callers in the process at once, or again shortly after,
+// is requested from nugs.net only once
+var requestGroup = &flightGroup{calls: make(map[string]*flightCall)}
+
+// flightGroup runs one call per key at a time and shares its result with the
+// callers that ask for the same key while it runs or within its reuse window
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is a running or finished call. expires is set once it finished
+// successfully and its result may be reused until then.
+type flightCall struct {
+	wg      sync.WaitGroup
+	body    []byte
+	err     error
+	expires time.Time
+	dups    int // callers that shared the result
+}
+
+// do returns the result of fn for key, calling it only when no call for key
+// is running and no successful result is younger than reuse. shared reports
+// whether the result came from another caller's call. Callers must not
+// modify the returned body.
+func (g *flightGroup) do(key string, reuse time.Duration, fn func() ([]byte, error)) (body []byte, err error, shared bool) {
+	g.mu.Lock()
+	g.pruneLocked()
+	if call, ok := g.calls[key]; ok {
+		call.dups++
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.body, call.err, true
+	}
+
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.body, call.err = fn()
+
+	g.mu.Lock()
+	if call.err == nil && reuse > 0 {
+		call.expires = time.Now().Add(reuse)
+	} else {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	call.wg.Done()
+
+	return call.body, call.err, false
+}
+
+// pruneLocked drops finished calls whose reuse window has passed
+func (g *flightGroup) pruneLocked() {
+	now := time.Now()
+	for key, call := range g.calls {
+		if !call.expires.IsZero() && now.After(call.expires) {
+			delete(g.calls, key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeGet_CoalescesConcurrentRequests(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	client := newHandlerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		fmt.Fprint(w, "catalog")
+	})
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body, err := client.safeGet("https://example.com/dedupe-concurrent", "test", nil)
+			assert.NoError(t, err)
+			bodies[i] = string(body)
+		}(i)
+	}
+
+	// Let the other callers join the running request before it finishes
+	require.Eventually(t, func() bool {
+		client.requests.mu.Lock()
+		defer client.requests.mu.Unlock()
+		call, ok := client.requests.calls["https://example.com/dedupe-concurrent"]
+		return ok && call.dups == len(bodies)-1
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Equal(t, 1, client.stats.TotalRequestsToday)
+	assert.Equal(t, len(bodies)-1, client.stats.DedupedRequests)
+	for _, body := range bodies {
+		assert.Equal(t, "catalog", body)
+	}
+}
+
+func TestSafeGet_ReusesRecentResponse(t *testing.T) {
+	requests := 0
+	client := newHandlerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "catalog")
+	})
+	client.config.DedupeWindowSecs = 60
+
+	for i := 0; i < 3; i++ {
+		body, err := client.safeGet("https://example.com/dedupe-reuse", "test", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "catalog", string(body))
+	}
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 2, client.stats.DedupedRequests)
+}
+
+func TestSafeGet_DoesNotReuseErrors(t *testing.T) {
+	requests := 0
+	client := newHandlerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	})
+	client.config.DedupeWindowSecs = 60
+
+	for i := 0; i < 2; i++ {
+		_, err := client.safeGet("https://example.com/dedupe-error", "test", nil)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, requests)
+}
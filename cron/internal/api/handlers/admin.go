@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,6 +31,15 @@ func NewAdminHandler(db *sql.DB, jobManager *models.JobManager) *AdminHandler {
 	}
 }
 
+// clientInfo captures the caller's address and user agent for the audit log.
+// ClientIP only honors X-Forwarded-For when the request came through a trusted proxy.
+func clientInfo(c *gin.Context) models.ClientInfo {
+	return models.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
 // User Management
 // POST /api/v1/admin/users
 func (h *AdminHandler) CreateUser(c *gin.Context) {
@@ -37,7 +51,7 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	response, err := h.AdminService.CreateUser(&req)
+	response, err := h.AdminService.CreateUser(&req, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
@@ -54,22 +68,38 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 // GET /api/v1/admin/users
 func (h *AdminHandler) GetUsers(c *gin.Context) {
 	// Parse pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	params, ok := parsePagination(c, h.DB)
+	if !ok {
+		return
 	}
+	page, pageSize := params.Page, params.PageSize
 
+	search := c.Query("search")
 	role := c.Query("role")
 	active := c.Query("active")
 
+	switch models.UserRole(role) {
+	case "", models.UserRoleAdmin, models.UserRoleModerator, models.UserRoleUser, models.UserRoleReadonly:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role filter"})
+		return
+	}
+
+	if active != "" && active != "true" && active != "false" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid active filter, must be true or false"})
+		return
+	}
+
 	// Build WHERE clause
 	whereClause := "WHERE 1=1"
 	args := []interface{}{}
 
+	if search != "" {
+		whereClause += " AND (username LIKE ? OR email LIKE ?)"
+		searchPattern := "%" + search + "%"
+		args = append(args, searchPattern, searchPattern)
+	}
+
 	if role != "" {
 		whereClause += " AND role = ?"
 		args = append(args, role)
@@ -89,8 +119,8 @@ func (h *AdminHandler) GetUsers(c *gin.Context) {
 		return
 	}
 
-	// Get users
-	offset := (page - 1) * pageSize
+	// Get users. password_hash is never selected so it can't leak into the response.
+	offset := params.Offset
 	query := `
 		SELECT id, username, email, role, active, last_login, created_at, updated_at
 		FROM users ` + whereClause + `
@@ -159,7 +189,7 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 	// Get current user from context (simplified)
 	updatedBy := "admin" // In real implementation, get from JWT
 
-	err = h.AdminService.UpdateUser(userID, &req, updatedBy)
+	err = h.AdminService.UpdateUser(userID, &req, updatedBy, clientInfo(c))
 	if err != nil {
 		if err.Error() == "user not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -185,9 +215,11 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 
 	deletedBy := "admin" // In real implementation, get from JWT
 
-	err = h.AdminService.DeleteUser(userID, deletedBy)
+	err = h.AdminService.DeleteUser(userID, deletedBy, clientInfo(c))
 	if err != nil {
-		if err.Error() == "user not found" {
+		if errors.Is(err, services.ErrMaintenanceBlocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else if err.Error() == "user not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
@@ -236,7 +268,7 @@ func (h *AdminHandler) UpdateConfig(c *gin.Context) {
 
 	updatedBy := "admin" // In real implementation, get from JWT
 
-	err := h.AdminService.UpdateConfig(key, &req, updatedBy)
+	err := h.AdminService.UpdateConfig(key, &req, updatedBy, clientInfo(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -288,7 +320,13 @@ func (h *AdminHandler) RunCleanup(c *gin.Context) {
 
 	runBy := "admin" // In real implementation, get from JWT
 
-	job, err := h.AdminService.RunCleanup(&req, runBy)
+	job, err := h.AdminService.RunCleanup(&req, runBy, clientInfo(c))
+	if errors.Is(err, services.ErrMaintenanceBlocked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to start cleanup: " + err.Error(),
@@ -308,28 +346,12 @@ func (h *AdminHandler) RunCleanup(c *gin.Context) {
 // GET /api/v1/admin/audit
 func (h *AdminHandler) GetAuditLogs(c *gin.Context) {
 	// Parse pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 200 {
-		pageSize = 200
-	}
-
-	// Parse filters
-	filters := make(map[string]string)
-	if userID := c.Query("user_id"); userID != "" {
-		filters["user_id"] = userID
-	}
-	if action := c.Query("action"); action != "" {
-		filters["action"] = action
-	}
-	if resource := c.Query("resource"); resource != "" {
-		filters["resource"] = resource
+	page, pageSize, ok := parsePage(c, h.DB, 50)
+	if !ok {
+		return
 	}
 
-	logs, total, err := h.AdminService.GetAuditLogs(page, pageSize, filters)
+	logs, total, err := h.AdminService.GetAuditLogs(page, pageSize, auditFilters(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to get audit logs",
@@ -352,27 +374,179 @@ func (h *AdminHandler) GetAuditLogs(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// auditFilters reads the audit log filters from the query string
+func auditFilters(c *gin.Context) map[string]string {
+	filters := make(map[string]string)
+	if userID := c.Query("user_id"); userID != "" {
+		filters["user_id"] = userID
+	}
+	if action := c.Query("action"); action != "" {
+		filters["action"] = action
+	}
+	if resource := c.Query("resource"); resource != "" {
+		filters["resource"] = resource
+	}
+	return filters
+}
+
+// GET /api/v1/admin/audit/export
+func (h *AdminHandler) ExportAuditLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Must be 'json' or 'csv'"})
+		return
+	}
+
+	logs, err := h.AdminService.ExportAuditLogs(auditFilters(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export audit logs"})
+		return
+	}
+
+	filename := fmt.Sprintf("audit_logs_%s.%s", time.Now().Format("20060102_150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	if format == "json" {
+		c.JSON(http.StatusOK, logs)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	// bom=true prepends a UTF-8 byte order mark so Excel reads non-ASCII names
+	if bom, _ := strconv.ParseBool(c.Query("bom")); bom {
+		c.Writer.WriteString("\ufeff")
+	}
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"id", "user_id", "username", "action", "resource", "resource_id",
+		"details", "ip_address", "user_agent", "success", "created_at", "prev_hash", "hash"})
+	for _, log := range logs {
+		writer.Write([]string{
+			strconv.Itoa(log.ID), strconv.Itoa(log.UserID), log.Username, log.Action,
+			log.Resource, log.ResourceID, log.Details, log.IPAddress, log.UserAgent,
+			strconv.FormatBool(log.Success), log.CreatedAt.UTC().Format(time.RFC3339),
+			log.PrevHash, log.Hash,
+		})
+	}
+	writer.Flush()
+}
+
+// GET /api/v1/admin/audit/verify
+func (h *AdminHandler) VerifyAuditLogs(c *gin.Context) {
+	result, err := h.AdminService.VerifyAuditChain()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // Job Management
 // GET /api/v1/admin/jobs
 func (h *AdminHandler) GetJobs(c *gin.Context) {
-	jobs := h.AdminService.JobManager.ListJobs()
-
-	// Filter by status if provided
-	status := c.Query("status")
-	if status != "" {
-		var filteredJobs []*models.Job
-		for _, job := range jobs {
-			if string(job.Status) == status {
-				filteredJobs = append(filteredJobs, job)
-			}
+	params, ok := parsePagination(c, h.DB)
+	if !ok {
+		return
+	}
+
+	filter := models.JobFilter{Search: strings.TrimSpace(c.Query("search"))}
+
+	// Filter by status and type, each a comma-separated list
+	for _, status := range queryList(c, "status") {
+		if !validJobStatus(models.JobStatus(status)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job status", "valid_statuses": models.JobStatuses})
+			return
 		}
-		jobs = filteredJobs
+		filter.Statuses = append(filter.Statuses, models.JobStatus(status))
+	}
+	for _, jobType := range queryList(c, "type") {
+		if !validJobType(models.JobType(jobType)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job type", "valid_types": models.JobTypes})
+			return
+		}
+		filter.Types = append(filter.Types, models.JobType(jobType))
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data":  jobs,
-		"total": len(jobs),
-	})
+	// Filter by creation time; a bare end date includes the whole day
+	if start := c.Query("start_date"); start != "" {
+		t, _, err := parseJobTime(start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date: " + err.Error()})
+			return
+		}
+		filter.CreatedAfter = &t
+	}
+	if end := c.Query("end_date"); end != "" {
+		t, dateOnly, err := parseJobTime(end)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date: " + err.Error()})
+			return
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.CreatedBefore = &t
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be before end_date"})
+		return
+	}
+
+	jobs := h.AdminService.JobManager.FindJobs(filter)
+	total := len(jobs)
+	page := []*models.Job{}
+	if params.Offset < total {
+		page = jobs[params.Offset:min(params.Offset+params.PageSize, total)]
+	}
+
+	c.JSON(http.StatusOK, createPaginatedResponse(page, params, int64(total)))
+}
+
+// queryList splits a comma-separated query parameter, dropping empty entries
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// parseJobTime accepts an RFC 3339 timestamp or a YYYY-MM-DD date, reporting
+// which it got
+func parseJobTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected YYYY-MM-DD or RFC 3339, got %q", value)
+	}
+	return t, true, nil
+}
+
+// validJobStatus reports whether status is one of models.JobStatuses
+func validJobStatus(status models.JobStatus) bool {
+	for _, known := range models.JobStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// validJobType reports whether jobType is one of models.JobTypes
+func validJobType(jobType models.JobType) bool {
+	for _, known := range models.JobTypes {
+		if jobType == known {
+			return true
+		}
+	}
+	return false
 }
 
 // GET /api/v1/admin/jobs/:id
@@ -419,6 +593,13 @@ func (h *AdminHandler) CancelJob(c *gin.Context) {
 // Database Management
 // POST /api/v1/admin/database/backup
 func (h *AdminHandler) CreateDatabaseBackup(c *gin.Context) {
+	if err := services.CheckMaintenanceAllowed(h.DB); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	// Simplified backup endpoint
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
@@ -429,42 +610,141 @@ func (h *AdminHandler) CreateDatabaseBackup(c *gin.Context) {
 
 // POST /api/v1/admin/database/optimize
 func (h *AdminHandler) OptimizeDatabase(c *gin.Context) {
-	// Run VACUUM on SQLite database
-	_, err := h.DB.Exec("VACUUM")
+	runBy := "admin" // In real implementation, get from JWT
+
+	job, err := h.AdminService.OptimizeDatabase(runBy, clientInfo(c))
+	if errors.Is(err, services.ErrMaintenanceBlocked) || errors.Is(err, services.ErrDatabaseBusy) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to optimize database",
+			"error": "Failed to start database optimize: " + err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusAccepted, gin.H{
 		"success": true,
-		"message": "Database optimized successfully",
+		"job_id":  job.ID,
+		"message": "Database optimize started",
+		"status":  job.Status,
 	})
 }
 
-// GET /api/v1/admin/database/stats
-func (h *AdminHandler) GetDatabaseStats(c *gin.Context) {
-	stats := make(map[string]interface{})
+// Full-system Backup
+// GET /api/v1/admin/backup/export
+func (h *AdminHandler) ExportBackup(c *gin.Context) {
+	runBy := "admin" // In real implementation, get from JWT
+
+	bundle, err := h.AdminService.PrepareBackup(runBy, clientInfo(c))
+	if errors.Is(err, services.ErrMaintenanceBlocked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to prepare backup: " + err.Error(),
+		})
+		return
+	}
+	defer bundle.Close()
 
-	// Get table sizes
-	tables := []string{"users", "artists", "shows", "downloads", "webhooks", "artist_monitors"}
-	tableCounts := make(map[string]int64)
+	// Large bundles take longer to stream than the server's write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
 
-	for _, table := range tables {
-		var count int64
-		if h.DB.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&count) == nil {
-			tableCounts[table] = count
+	filename := "nugs-backup-" + bundle.Manifest.CreatedAt.Format("20060102-150405") + ".tar.gz"
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// The status is already sent, so a failure can only cut the archive short
+	if err := bundle.Stream(c.Writer); err != nil {
+		log.Printf("Failed to stream backup bundle: %v", err)
+	}
+}
+
+// POST /api/v1/admin/backup/import?confirm=true&mode=replace|merge
+func (h *AdminHandler) ImportBackup(c *gin.Context) {
+	req := models.BackupImportRequest{
+		Mode: c.DefaultQuery("mode", models.BackupImportReplace),
+	}
+	req.Confirm, _ = strconv.ParseBool(c.Query("confirm"))
+	req.ReseedCatalog, _ = strconv.ParseBool(c.DefaultQuery("reseed_catalog", "true"))
+
+	if req.Mode != models.BackupImportReplace && req.Mode != models.BackupImportMerge {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid mode, expected replace or merge",
+		})
+		return
+	}
+	if !req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Importing a backup overwrites current data, repeat the request with confirm=true",
+		})
+		return
+	}
+
+	// Uploading a large bundle takes longer than the server's read timeout
+	http.NewResponseController(c.Writer).SetReadDeadline(time.Time{})
+
+	// The bundle is either the raw request body or a multipart "file" field
+	var archive io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Missing backup file: " + err.Error(),
+			})
+			return
 		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Failed to read backup file: " + err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+		archive = file
+	}
+
+	runBy := "admin" // In real implementation, get from JWT
+
+	job, err := h.AdminService.ImportBackup(archive, req, runBy, clientInfo(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrMaintenanceBlocked) {
+			status = http.StatusConflict
+		} else if errors.Is(err, services.ErrInvalidBackup) || errors.Is(err, services.ErrImportNotConfirmed) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": "Failed to import backup: " + err.Error(),
+		})
+		return
 	}
 
-	stats["table_counts"] = tableCounts
-	stats["total_tables"] = len(tables)
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"job_id":  job.ID,
+		"message": "Backup import started",
+		"status":  job.Status,
+	})
+}
 
-	// Get database file size
-	if stat, err := os.Stat("./data/nugs_api.db"); err == nil {
-		stats["database_size_mb"] = float64(stat.Size()) / (1024 * 1024)
+// GET /api/v1/admin/database/stats
+func (h *AdminHandler) GetDatabaseStats(c *gin.Context) {
+	stats, err := h.AdminService.GetDatabaseStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get database stats: " + err.Error(),
+		})
+		return
 	}
 
 	c.JSON(http.StatusOK, stats)
@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jmagar/nugs/cron/internal/lockfile"
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -34,6 +42,8 @@ func setupAdminTestRouter(t *testing.T) (*gin.Engine, *models.JobManager) {
 		admin.GET("/stats", adminHandler.GetAdminStats)
 		admin.POST("/maintenance/cleanup", adminHandler.RunCleanup)
 		admin.GET("/audit", adminHandler.GetAuditLogs)
+		admin.GET("/audit/export", adminHandler.ExportAuditLogs)
+		admin.GET("/audit/verify", adminHandler.VerifyAuditLogs)
 		admin.GET("/jobs", adminHandler.GetJobs)
 		admin.GET("/jobs/:id", adminHandler.GetJob)
 		admin.DELETE("/jobs/:id", adminHandler.CancelJob)
@@ -165,6 +175,24 @@ func TestAdminHandler_GetUsers(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			checkFields:    []string{"data", "page", "page_size", "total"},
 		},
+		{
+			name:           "search by username or email",
+			queryParams:    "?search=admin&role=admin&active=true",
+			expectedStatus: http.StatusOK,
+			checkFields:    []string{"data", "page", "page_size", "total"},
+		},
+		{
+			name:           "invalid role filter",
+			queryParams:    "?role=superuser",
+			expectedStatus: http.StatusBadRequest,
+			checkFields:    []string{"error"},
+		},
+		{
+			name:           "invalid active filter",
+			queryParams:    "?active=maybe",
+			expectedStatus: http.StatusBadRequest,
+			checkFields:    []string{"error"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -258,6 +286,15 @@ func TestAdminHandler_UpdateConfig(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			checkError:     false,
 		},
+		{
+			name:      "type mismatch",
+			configKey: "max_concurrent_downloads",
+			requestBody: map[string]interface{}{
+				"value": "five",
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkError:     true,
+		},
 		{
 			name:           "missing value",
 			configKey:      "max_concurrent_downloads",
@@ -386,6 +423,544 @@ func TestAdminHandler_RunCleanup(t *testing.T) {
 	}
 }
 
+func TestAdminHandler_RunCleanupDryRun(t *testing.T) {
+	db := setupTestDB(t)
+	jobManager := models.NewJobManager()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	adminHandler := NewAdminHandler(db, jobManager)
+	router.POST("/admin/maintenance/cleanup", adminHandler.RunCleanup)
+
+	// One old and one recent webhook delivery
+	_, err := db.Exec(`INSERT INTO webhooks (id, user_id, name, url, events) VALUES (101, 1, 'Test', 'http://example.com', '[]')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload, created_at) VALUES
+			(101, 'new_show', '{}', datetime('now', '-60 days')),
+			(101, 'new_show', '{}', datetime('now'))
+	`)
+	require.NoError(t, err)
+
+	// One orphaned and one recently written file in the download directory
+	downloadDir := t.TempDir()
+	_, err = db.Exec("UPDATE system_config SET value = ? WHERE key = 'default_download_path'", downloadDir)
+	require.NoError(t, err)
+	orphan := filepath.Join(downloadDir, "orphan.flac")
+	require.NoError(t, os.WriteFile(orphan, []byte("x"), 0644))
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(orphan, old, old))
+	require.NoError(t, os.WriteFile(filepath.Join(downloadDir, "recent.flac"), []byte("x"), 0644))
+
+	// One job that finished long ago
+	oldJob := jobManager.CreateJob(models.JobTypeCatalogRefresh)
+	jobManager.UpdateJob(oldJob.ID, func(j *models.Job) {
+		finished := time.Now().AddDate(0, 0, -30)
+		j.Status = models.JobStatusCompleted
+		j.CompletedAt = &finished
+	})
+
+	runCleanup := func(dryRun bool) *models.CleanupResult {
+		body, _ := json.Marshal(map[string]interface{}{
+			"old_jobs": true, "old_deliveries": true, "old_files": true, "dry_run": dryRun,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/admin/maintenance/cleanup", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		jobID := response["job_id"].(string)
+
+		require.Eventually(t, func() bool {
+			job, _ := jobManager.GetJob(jobID)
+			return job.Status == models.JobStatusCompleted
+		}, 5*time.Second, 10*time.Millisecond)
+
+		job, _ := jobManager.GetJob(jobID)
+		result, ok := job.Result.(*models.CleanupResult)
+		require.True(t, ok)
+		return result
+	}
+
+	countDeliveries := func() int {
+		var count int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM webhook_deliveries").Scan(&count))
+		return count
+	}
+
+	// The dry run lists one item per category and deletes nothing
+	plan := runCleanup(true)
+	assert.True(t, plan.DryRun)
+	assert.Equal(t, 3, plan.TotalItems)
+	assert.Equal(t, 1, plan.Categories["old_jobs"].Count)
+	assert.Equal(t, 1, plan.Categories["old_deliveries"].Count)
+	assert.Equal(t, []string{orphan}, plan.Categories["orphaned_files"].Sample)
+	assert.Equal(t, 2, countDeliveries())
+	assert.FileExists(t, orphan)
+	_, exists := jobManager.GetJob(oldJob.ID)
+	assert.True(t, exists)
+
+	// The real run deletes exactly those items
+	result := runCleanup(false)
+	assert.False(t, result.DryRun)
+	assert.Equal(t, plan.TotalItems, result.TotalItems)
+	for name, items := range plan.Categories {
+		assert.Equal(t, items.Count, result.Categories[name].Count, name)
+		assert.Zero(t, result.Categories[name].Failed, name)
+	}
+	assert.Equal(t, 1, countDeliveries())
+	assert.NoFileExists(t, orphan)
+	assert.FileExists(t, filepath.Join(downloadDir, "recent.flac"))
+	_, exists = jobManager.GetJob(oldJob.ID)
+	assert.False(t, exists)
+}
+
+func TestAdminHandler_CleanupOldExecutions(t *testing.T) {
+	db := setupTestDB(t)
+	jobManager := models.NewJobManager()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	adminHandler := NewAdminHandler(db, jobManager)
+	router.POST("/admin/maintenance/cleanup", adminHandler.RunCleanup)
+
+	_, err := db.Exec("UPDATE system_config SET value = '2' WHERE key = 'schedule_execution_keep_recent'")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO schedules (id, name, cron, job_type) VALUES (101, 'Busy', '* * * * *', 'health_check'), (102, 'Rare', '0 2 * * 0', 'system_cleanup')`)
+	require.NoError(t, err)
+
+	// Schedule 101 has a recent run, four old ones and an old one still running.
+	// Schedule 102 only has old runs, fewer than the history kept.
+	_, err = db.Exec(`
+		INSERT INTO schedule_executions (id, schedule_id, status, started_at) VALUES
+			(1, 101, 'completed', datetime('now', '-1 hours')),
+			(2, 101, 'completed', datetime('now', '-40 days')),
+			(3, 101, 'failed', datetime('now', '-50 days')),
+			(4, 101, 'completed', datetime('now', '-60 days')),
+			(5, 101, 'running', datetime('now', '-70 days')),
+			(6, 101, 'completed', datetime('now', '-80 days')),
+			(7, 102, 'completed', datetime('now', '-90 days')),
+			(8, 102, 'completed', datetime('now', '-97 days'))
+	`)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/maintenance/cleanup", bytes.NewBufferString(`{"old_executions": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	jobID := response["job_id"].(string)
+
+	require.Eventually(t, func() bool {
+		job, _ := jobManager.GetJob(jobID)
+		return job.Status == models.JobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	job, _ := jobManager.GetJob(jobID)
+	result, ok := job.Result.(*models.CleanupResult)
+	require.True(t, ok)
+	assert.Equal(t, 3, result.Categories["old_executions"].Count)
+
+	var remaining []int
+	rows, err := db.Query("SELECT id FROM schedule_executions ORDER BY id")
+	require.NoError(t, err)
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		remaining = append(remaining, id)
+	}
+	rows.Close()
+	assert.Equal(t, []int{1, 2, 5, 7, 8}, remaining)
+}
+
+func TestAdminHandler_CleanupOldReports(t *testing.T) {
+	db := setupTestDB(t)
+	jobManager := models.NewJobManager()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	adminHandler := NewAdminHandler(db, jobManager)
+	router.POST("/admin/maintenance/cleanup", adminHandler.RunCleanup)
+
+	_, err := db.Exec("UPDATE system_config SET value = '2' WHERE key = 'report_keep_recent'")
+	require.NoError(t, err)
+
+	// Days old. The two most recent are kept even though "old" is past
+	// the 30 day retention.
+	now := time.Now()
+	for id, age := range map[string]int{"recent": 2, "old": 40, "older": 50, "oldest": 60} {
+		_, err := db.Exec(`INSERT INTO analytics_reports (id, report_type, content, generated_at) VALUES (?, 'collection_summary', '{}', ?)`,
+			id, now.AddDate(0, 0, -age))
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/maintenance/cleanup", bytes.NewBufferString(`{"old_reports": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	jobID := response["job_id"].(string)
+
+	require.Eventually(t, func() bool {
+		job, _ := jobManager.GetJob(jobID)
+		return job.Status == models.JobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	job, _ := jobManager.GetJob(jobID)
+	result, ok := job.Result.(*models.CleanupResult)
+	require.True(t, ok)
+	assert.Equal(t, 2, result.Categories["old_reports"].Count)
+
+	var remaining []string
+	rows, err := db.Query("SELECT id FROM analytics_reports ORDER BY id")
+	require.NoError(t, err)
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		remaining = append(remaining, id)
+	}
+	rows.Close()
+	assert.Equal(t, []string{"old", "recent"}, remaining)
+}
+
+func TestAdminHandler_MaintenanceBlock(t *testing.T) {
+	db := setupTestDB(t)
+	jobManager := models.NewJobManager()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	adminHandler := NewAdminHandler(db, jobManager)
+	router.GET("/admin/status", adminHandler.GetSystemStatus)
+	router.POST("/admin/maintenance/cleanup", adminHandler.RunCleanup)
+	router.POST("/admin/database/optimize", adminHandler.OptimizeDatabase)
+	router.POST("/admin/database/backup", adminHandler.CreateDatabaseBackup)
+	router.DELETE("/admin/users/:id", adminHandler.DeleteUser)
+
+	// A window around the current minute blocks destructive operations
+	now := time.Now()
+	window := now.Add(-time.Minute).Format("15:04") + "-" + now.Add(2*time.Minute).Format("15:04")
+	_, err := db.Exec("UPDATE system_config SET value = ? WHERE key = 'maintenance_windows'", "03:00-03:01, bogus, "+window)
+	require.NoError(t, err)
+
+	request := func(method, path string, body map[string]interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(data))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodGet, "/admin/status", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status models.SystemStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(t, status.Maintenance.Blocked)
+	assert.False(t, status.Maintenance.Locked)
+	assert.Equal(t, window, status.Maintenance.ActiveWindow)
+	assert.Equal(t, []string{"03:00-03:01", window}, status.Maintenance.Windows)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           map[string]interface{}
+		expectedStatus int
+	}{
+		{"cleanup", http.MethodPost, "/admin/maintenance/cleanup", map[string]interface{}{"old_jobs": true}, http.StatusConflict},
+		{"cleanup dry run", http.MethodPost, "/admin/maintenance/cleanup", map[string]interface{}{"old_jobs": true, "dry_run": true}, http.StatusOK},
+		{"optimize", http.MethodPost, "/admin/database/optimize", nil, http.StatusConflict},
+		{"backup", http.MethodPost, "/admin/database/backup", nil, http.StatusConflict},
+		{"delete user", http.MethodDelete, "/admin/users/1", nil, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+
+	// The lock blocks outside any window, and clearing both allows maintenance again
+	_, err = db.Exec("UPDATE system_config SET value = '' WHERE key = 'maintenance_windows'")
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE system_config SET value = 'true' WHERE key = 'maintenance_locked'")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/admin/database/optimize", nil).Code)
+
+	_, err = db.Exec("UPDATE system_config SET value = 'false' WHERE key = 'maintenance_locked'")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, request(http.MethodPost, "/admin/database/optimize", nil).Code)
+}
+
+func TestAdminHandler_OptimizeDatabase(t *testing.T) {
+	router, jobManager := setupAdminTestRouter(t)
+
+	optimize := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/database/optimize", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Refused while a download is writing to the database
+	download := jobManager.CreateJob(models.JobTypeDownload)
+	jobManager.UpdateJob(download.ID, func(j *models.Job) { j.Status = models.JobStatusRunning })
+	assert.Equal(t, http.StatusConflict, optimize().Code)
+	jobManager.UpdateJob(download.ID, func(j *models.Job) { j.Status = models.JobStatusCompleted })
+
+	w := optimize()
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	jobID := response["job_id"].(string)
+
+	require.Eventually(t, func() bool {
+		job, _ := jobManager.GetJob(jobID)
+		return job.Status == models.JobStatusCompleted || job.Status == models.JobStatusFailed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	job, _ := jobManager.GetJob(jobID)
+	require.Equal(t, models.JobStatusCompleted, job.Status, job.Error)
+	result, ok := job.Result.(*models.DatabaseOptimizeResult)
+	require.True(t, ok)
+	assert.Greater(t, result.SizeBeforeBytes, int64(0))
+	assert.Greater(t, result.SizeAfterBytes, int64(0))
+	assert.Equal(t, result.SizeBeforeBytes-result.SizeAfterBytes, result.FreedBytes)
+}
+
+func TestAdminHandler_BackupExportImport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnv, home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "configs"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "data"), 0755))
+	configPath := filepath.Join(home, "configs", "monitor_config.json")
+	showsPath := filepath.Join(home, "data", "shows.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"artists": []}`), 0644))
+	require.NoError(t, os.WriteFile(showsPath, []byte(`{"artists": {}}`), 0644))
+
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	adminHandler := NewAdminHandler(db, models.NewJobManager())
+	router.GET("/admin/backup/export", adminHandler.ExportBackup)
+	router.POST("/admin/backup/import", adminHandler.ImportBackup)
+
+	_, err := db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/backup/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	bundle := w.Body.Bytes()
+
+	// The manifest comes first and lists every other entry
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var entries []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries = append(entries, header.Name)
+	}
+	assert.Equal(t, []string{"manifest.json", "configs/monitor_config.json", "data/shows.json", "database/nugs_api.db"}, entries)
+
+	// Change everything the bundle holds, then import it
+	_, err = db.Exec("DELETE FROM artists WHERE id = 101")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"artists": [{"id": 1}]}`), 0644))
+	require.NoError(t, os.Remove(showsPath))
+
+	// Nothing happens without confirmation
+	req = httptest.NewRequest(http.MethodPost, "/admin/backup/import", bytes.NewReader(bundle))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/backup/import?confirm=true&reseed_catalog=false", bytes.NewReader(bundle))
+	req.Header.Set("Content-Type", "application/gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	var started struct {
+		JobID string `json:"job_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+
+	var job *models.Job
+	require.Eventually(t, func() bool {
+		var ok bool
+		job, ok = adminHandler.AdminService.JobManager.GetJob(started.JobID)
+		return ok && job.Status == models.JobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+
+	result, ok := job.Result.(*models.BackupImportResult)
+	require.True(t, ok)
+	assert.Equal(t, models.BackupImportReplace, result.Mode)
+	assert.True(t, result.DatabaseRestored)
+	assert.Equal(t, []string{"configs/monitor_config.json", "data/shows.json"}, result.FilesRestored)
+	assert.Empty(t, result.CatalogRefreshJobID)
+
+	// The state before the import was saved first
+	assert.Equal(t, filepath.Join(home, "data", "backups"), filepath.Dir(result.PreImportBackup))
+	assert.FileExists(t, result.PreImportBackup)
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM artists WHERE id = 101").Scan(&name))
+	assert.Equal(t, "Billy Strings", name)
+	restored, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"artists": []}`, string(restored))
+	assert.FileExists(t, showsPath)
+
+	// A merge keeps rows added since the backup
+	_, err = db.Exec(`INSERT INTO artists (id, name, slug) VALUES (102, 'Goose', 'goose')`)
+	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM artists WHERE id = 101")
+	require.NoError(t, err)
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/backup/import?confirm=true&mode=merge&reseed_catalog=false", bytes.NewReader(bundle))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+
+	require.Eventually(t, func() bool {
+		var ok bool
+		job, ok = adminHandler.AdminService.JobManager.GetJob(started.JobID)
+		return ok && job.Status == models.JobStatusCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	result = job.Result.(*models.BackupImportResult)
+	assert.Equal(t, int64(1), result.RowsMerged["artists"])
+
+	// Billy Strings comes back under a new id, Goose keeps 102
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM artists WHERE name IN ('Billy Strings', 'Goose')").Scan(&count))
+	assert.Equal(t, 2, count)
+	require.NoError(t, db.QueryRow("SELECT name FROM artists WHERE id = 102").Scan(&name))
+	assert.Equal(t, "Goose", name)
+
+	// A monitor run holding its lock fails the import before anything changes
+	lock, err := lockfile.Acquire(filepath.Join(home, "data", "monitor.lock"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(showsPath, []byte(`{"artists": {"Goose": {}}}`), 0644))
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/backup/import?confirm=true&reseed_catalog=false", bytes.NewReader(bundle))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+
+	require.Eventually(t, func() bool {
+		var ok bool
+		job, ok = adminHandler.AdminService.JobManager.GetJob(started.JobID)
+		return ok && job.Status == models.JobStatusFailed
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, job.Error, lockfile.ErrLocked.Error())
+	shows, err := os.ReadFile(showsPath)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"artists": {"Goose": {}}}`, string(shows))
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM artists WHERE name IN ('Billy Strings', 'Goose')").Scan(&count))
+	assert.Equal(t, 2, count)
+	lock.Release()
+
+	// Anything else is rejected without touching the system
+	req = httptest.NewRequest(http.MethodPost, "/admin/backup/import?confirm=true", bytes.NewReader([]byte("not a backup")))
+	req.Header.Set("Content-Type", "application/gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdminHandler_ExportAuditLogs(t *testing.T) {
+	router, _ := setupAdminTestRouter(t)
+
+	tests := []struct {
+		name           string
+		queryParams    string
+		expectedStatus int
+		contentType    string
+	}{
+		{
+			name:           "export as json",
+			queryParams:    "",
+			expectedStatus: http.StatusOK,
+			contentType:    "application/json",
+		},
+		{
+			name:           "export as csv",
+			queryParams:    "?format=csv&action=create_user",
+			expectedStatus: http.StatusOK,
+			contentType:    "text/csv",
+		},
+		{
+			name:           "invalid format",
+			queryParams:    "?format=xml",
+			expectedStatus: http.StatusBadRequest,
+			contentType:    "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/audit/export"+tt.queryParams, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), tt.contentType)
+		})
+	}
+}
+
+func TestAdminHandler_VerifyAuditLogs(t *testing.T) {
+	router, _ := setupAdminTestRouter(t)
+
+	// Creating users writes chained audit entries
+	for _, username := range []string{"audituser1", "audituser2"} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"username": username,
+			"email":    username + "@example.com",
+			"password": "testpassword123",
+			"role":     "user",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/admin/users", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/audit/verify", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.AuditVerification
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.True(t, response.Valid)
+	assert.Equal(t, 2, response.CheckedEntries)
+}
+
 func TestAdminHandler_GetJobs(t *testing.T) {
 	router, jobManager := setupAdminTestRouter(t)
 
@@ -438,6 +1013,97 @@ func TestAdminHandler_GetJobs(t *testing.T) {
 	}
 }
 
+func TestAdminHandler_GetJobs_TypeFilter(t *testing.T) {
+	router, jobManager := setupAdminTestRouter(t)
+
+	cleanup := jobManager.CreateJob(models.JobTypeCleanup)
+	jobManager.CreateJob(models.JobTypeBackup)
+	jobManager.CreateJob(models.JobTypeAnalytics)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs?type=cleanup", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data  []models.Job `json:"data"`
+		Total int          `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Total)
+	assert.Equal(t, cleanup.ID, response.Data[0].ID)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/jobs?type=reports", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "health_check")
+}
+
+func TestAdminHandler_GetJobs_Search(t *testing.T) {
+	router, jobManager := setupAdminTestRouter(t)
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	failed := jobManager.CreateJob(models.JobTypeDownload)
+	require.NoError(t, jobManager.UpdateJob(failed.ID, func(j *models.Job) {
+		j.Status = models.JobStatusFailed
+		j.Error = "connection reset"
+		j.CreatedAt = yesterday
+	}))
+	older := jobManager.CreateJob(models.JobTypeDownload)
+	require.NoError(t, jobManager.UpdateJob(older.ID, func(j *models.Job) {
+		j.Status = models.JobStatusFailed
+		j.CreatedAt = yesterday.AddDate(0, 0, -3)
+	}))
+	jobManager.CreateJob(models.JobTypeDownload)
+	jobManager.CreateJob(models.JobTypeBackup)
+
+	get := func(query string) (int, PaginatedResponse, []models.Job) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/jobs"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response PaginatedResponse
+		var jobs []models.Job
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			data, _ := json.Marshal(response.Data)
+			require.NoError(t, json.Unmarshal(data, &jobs))
+		}
+		return w.Code, response, jobs
+	}
+
+	// Failed downloads from yesterday
+	day := yesterday.Format("2006-01-02")
+	code, response, jobs := get("?type=download&status=failed&start_date=" + day + "&end_date=" + day)
+	require.Equal(t, http.StatusOK, code)
+	require.EqualValues(t, 1, response.Total)
+	assert.Equal(t, failed.ID, jobs[0].ID)
+
+	// Search matches the error message
+	_, response, jobs = get("?search=RESET")
+	require.EqualValues(t, 1, response.Total)
+	assert.Equal(t, failed.ID, jobs[0].ID)
+
+	// Several statuses, newest first, paginated
+	code, response, jobs = get("?status=pending,failed&page_size=2&page=2")
+	require.Equal(t, http.StatusOK, code)
+	assert.EqualValues(t, 4, response.Total)
+	assert.Equal(t, 2, response.TotalPages)
+	assert.True(t, response.HasPrev)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, older.ID, jobs[1].ID)
+
+	// Past the last page is empty
+	_, response, jobs = get("?page=5")
+	assert.EqualValues(t, 4, response.Total)
+	assert.Empty(t, jobs)
+
+	for _, query := range []string{"?status=stuck", "?start_date=yesterday", "?start_date=2026-02-02&end_date=2026-01-01"} {
+		code, _, _ = get(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
 func TestAdminHandler_GetDatabaseStats(t *testing.T) {
 	router, _ := setupAdminTestRouter(t)
 
@@ -452,10 +1118,30 @@ func TestAdminHandler_GetDatabaseStats(t *testing.T) {
 	require.NoError(t, err)
 
 	expectedFields := []string{
-		"table_counts", "total_tables",
+		"table_counts", "total_tables", "tables", "largest_tables", "index_count",
+		"page_size", "free_pages", "wal_size_bytes",
 	}
 
 	for _, field := range expectedFields {
 		assert.Contains(t, response, field)
 	}
+
+	var stats models.DatabaseStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+
+	// Every table is counted, not just a fixed list
+	assert.Contains(t, stats.TableCounts, "system_config")
+	assert.Contains(t, stats.TableCounts, "webhook_deliveries")
+	assert.Equal(t, len(stats.Tables), stats.TotalTables)
+	assert.Greater(t, stats.IndexCount, 0)
+	assert.Greater(t, stats.PageSize, int64(0))
+	assert.Equal(t, stats.PageCount*stats.PageSize, stats.SizeBytes)
+	require.NotEmpty(t, stats.LargestTables)
+	assert.Equal(t, stats.Tables[0], stats.LargestTables[0])
+	if !stats.TableSizesAvailable {
+		// Ranked by rows when sizes are unknown
+		for i := 1; i < len(stats.Tables); i++ {
+			assert.GreaterOrEqual(t, stats.Tables[i-1].Rows, stats.Tables[i].Rows)
+		}
+	}
 }
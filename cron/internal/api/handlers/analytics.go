@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -27,6 +30,64 @@ func NewAnalyticsHandler(db *sql.DB, jobManager *models.JobManager) *AnalyticsHa
 	}
 }
 
+// Deadline bounds each analytics request by analytics_timeout_seconds, on
+// top of the server-wide request timeout. 0 leaves only the latter.
+func (h *AnalyticsHandler) Deadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seconds := services.GetConfigInt(h.DB, "analytics_timeout_seconds", services.DefaultAnalyticsTimeoutSeconds)
+		if seconds <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(seconds)*time.Second)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// parseMonitoredOnly reads the optional monitored_only query parameter. Nil
+// leaves it to the analytics_monitored_only config value.
+func parseMonitoredOnly(c *gin.Context) (*bool, error) {
+	value := c.Query("monitored_only")
+	if value == "" {
+		return nil, nil
+	}
+	monitoredOnly, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &monitoredOnly, nil
+}
+
+// setLocation reads the optional venue, city and state filters into query
+func setLocation(c *gin.Context, query *models.AnalyticsQuery) {
+	query.Venue = c.Query("venue")
+	query.City = c.Query("city")
+	query.State = c.Query("state")
+}
+
+// setDateRange reads the optional start_date and end_date filters into query
+func setDateRange(c *gin.Context, query *models.AnalyticsQuery) {
+	if start := c.Query("start_date"); start != "" {
+		query.StartDate = &start
+	}
+	if end := c.Query("end_date"); end != "" {
+		query.EndDate = &end
+	}
+}
+
+// invalidQuery answers a 400 for an analytics query the service rejected as
+// invalid, and reports whether it did
+func invalidQuery(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrInvalidDateRange) || strings.Contains(err.Error(), "unsupported report type") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return true
+	}
+	return false
+}
+
 // POST /api/v1/analytics/reports
 func (h *AnalyticsHandler) GenerateReport(c *gin.Context) {
 	var query models.AnalyticsQuery
@@ -42,39 +103,110 @@ func (h *AnalyticsHandler) GenerateReport(c *gin.Context) {
 		query.Timeframe = models.TimeframeMonth
 	}
 
-	report, err := h.AnalyticsService.GenerateReport(&query)
-	if err != nil {
-		// Check if it's a validation error
-		if strings.Contains(err.Error(), "unsupported report type") {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": err.Error(),
-			})
+	// async=true generates the report in the background, to be fetched from
+	// the reports endpoint once the job completes
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		if !services.ValidReportType(query.ReportType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported report type: " + query.ReportType})
+			return
+		}
+		if err := services.ValidateDateRange(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate report: " + err.Error(),
+		job := h.AnalyticsService.GenerateReportAsync(&query)
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"job_id":  job.ID,
+			"message": "Report generation started",
+			"status":  job.Status,
 		})
 		return
 	}
 
+	report, err := h.AnalyticsService.GenerateReport(c.Request.Context(), &query)
+	if err != nil {
+		if invalidQuery(c, err) {
+			return
+		}
+		queryFailed(c, err, "Failed to generate report: "+err.Error())
+		return
+	}
+
+	// The report is still returned when it can't be kept for later
+	if err := h.AnalyticsService.SaveReport(c.Request.Context(), report); err != nil {
+		log.Printf("Failed to save report %s: %v", report.ReportID, err)
+	}
+
 	c.JSON(http.StatusOK, report)
 }
 
+// GET /api/v1/analytics/reports
+func (h *AnalyticsHandler) ListReports(c *gin.Context) {
+	params, ok := parsePagination(c, h.DB)
+	if !ok {
+		return
+	}
+
+	reports, total, err := h.AnalyticsService.ListReports(c.Request.Context(), params.PageSize, params.Offset)
+	if err != nil {
+		queryFailed(c, err, "Failed to list reports")
+		return
+	}
+
+	c.JSON(http.StatusOK, createPaginatedResponse(reports, params, total))
+}
+
+// reportContentTypes maps a stored report's format to the Content-Type it's
+// served with
+var reportContentTypes = map[string]string{
+	models.ReportFormatJSON: "application/json; charset=utf-8",
+}
+
+// GET /api/v1/analytics/reports/:id
+func (h *AnalyticsHandler) GetReport(c *gin.Context) {
+	report, content, err := h.AnalyticsService.GetReport(c.Request.Context(), c.Param("id"))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+	if err != nil {
+		queryFailed(c, err, "Failed to get report")
+		return
+	}
+
+	contentType, ok := reportContentTypes[report.Format]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", report.ReportID, report.Format))
+	c.Data(http.StatusOK, contentType, content)
+}
+
 // GET /api/v1/analytics/collection
 func (h *AnalyticsHandler) GetCollectionStats(c *gin.Context) {
 	timeframe := models.AnalyticsTimeframe(c.DefaultQuery("timeframe", "month"))
+	monitoredOnly, err := parseMonitoredOnly(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid monitored_only, must be true or false"})
+		return
+	}
 
 	query := &models.AnalyticsQuery{
-		ReportType: "collection",
-		Timeframe:  timeframe,
+		ReportType:    "collection",
+		Timeframe:     timeframe,
+		MonitoredOnly: monitoredOnly,
 	}
+	setLocation(c, query)
+	setDateRange(c, query)
 
-	stats, err := h.AnalyticsService.GetCollectionStats(query)
+	stats, err := h.AnalyticsService.GetCollectionStats(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get collection statistics",
-		})
+		if invalidQuery(c, err) {
+			return
+		}
+		queryFailed(c, err, "Failed to get collection statistics")
 		return
 	}
 
@@ -83,25 +215,33 @@ func (h *AnalyticsHandler) GetCollectionStats(c *gin.Context) {
 
 // GET /api/v1/analytics/artists
 func (h *AnalyticsHandler) GetArtistAnalytics(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	limit, ok := parseLimit(c, h.DB, "limit", 50)
+	if !ok {
+		return
+	}
 	timeframe := models.AnalyticsTimeframe(c.DefaultQuery("timeframe", "month"))
+	monitoredOnly, err := parseMonitoredOnly(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid monitored_only, must be true or false"})
+		return
+	}
 
 	query := &models.AnalyticsQuery{
-		ReportType: "artists",
-		Timeframe:  timeframe,
-		Limit:      limit,
+		ReportType:    "artists",
+		Timeframe:     timeframe,
+		Limit:         limit,
+		MonitoredOnly: monitoredOnly,
 	}
+	setLocation(c, query)
 
 	// Parse artist IDs if provided
 	if artistIDsStr := c.Query("artist_ids"); artistIDsStr != "" {
 		// Could parse comma-separated IDs, simplified for now
 	}
 
-	analytics, err := h.AnalyticsService.GetArtistAnalytics(query)
+	analytics, err := h.AnalyticsService.GetArtistAnalytics(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get artist analytics",
-		})
+		queryFailed(c, err, "Failed to get artist analytics")
 		return
 	}
 
@@ -112,6 +252,38 @@ func (h *AnalyticsHandler) GetArtistAnalytics(c *gin.Context) {
 	})
 }
 
+// GET /api/v1/analytics/artists/:id/timeline
+func (h *AnalyticsHandler) GetArtistTimeline(c *gin.Context) {
+	artistID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid artist ID"})
+		return
+	}
+
+	timeline, err := h.AnalyticsService.GetArtistTimeline(c.Request.Context(), artistID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
+		return
+	}
+	if err != nil {
+		queryFailed(c, err, "Failed to get artist timeline")
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// GET /api/v1/analytics/completion/years
+func (h *AnalyticsHandler) GetCompletionMatrix(c *gin.Context) {
+	matrix, err := h.AnalyticsService.GetCompletionMatrix(c.Request.Context())
+	if err != nil {
+		queryFailed(c, err, "Failed to get completion matrix")
+		return
+	}
+
+	c.JSON(http.StatusOK, matrix)
+}
+
 // GET /api/v1/analytics/downloads
 func (h *AnalyticsHandler) GetDownloadAnalytics(c *gin.Context) {
 	timeframe := models.AnalyticsTimeframe(c.DefaultQuery("timeframe", "month"))
@@ -121,12 +293,15 @@ func (h *AnalyticsHandler) GetDownloadAnalytics(c *gin.Context) {
 		Timeframe:         timeframe,
 		IncludeTimeSeries: c.Query("include_time_series") == "true",
 	}
+	setLocation(c, query)
+	setDateRange(c, query)
 
-	analytics, err := h.AnalyticsService.GetDownloadAnalytics(query)
+	analytics, err := h.AnalyticsService.GetDownloadAnalytics(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get download analytics",
-		})
+		if invalidQuery(c, err) {
+			return
+		}
+		queryFailed(c, err, "Failed to get download analytics")
 		return
 	}
 
@@ -136,7 +311,7 @@ func (h *AnalyticsHandler) GetDownloadAnalytics(c *gin.Context) {
 	}
 
 	if query.IncludeTimeSeries {
-		timeSeries, err := h.AnalyticsService.GenerateReport(query)
+		timeSeries, err := h.AnalyticsService.GenerateReport(c.Request.Context(), query)
 		if err == nil && timeSeries.TimeSeries != nil {
 			response["time_series"] = timeSeries.TimeSeries
 		}
@@ -147,17 +322,26 @@ func (h *AnalyticsHandler) GetDownloadAnalytics(c *gin.Context) {
 
 // GET /api/v1/analytics/system
 func (h *AnalyticsHandler) GetSystemMetrics(c *gin.Context) {
-	metrics, err := h.AnalyticsService.GetSystemMetrics()
+	metrics, err := h.AnalyticsService.GetSystemMetrics(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get system metrics",
-		})
+		queryFailed(c, err, "Failed to get system metrics")
 		return
 	}
 
 	c.JSON(http.StatusOK, metrics)
 }
 
+// GET /api/v1/analytics/storage
+func (h *AnalyticsHandler) GetStorageBreakdown(c *gin.Context) {
+	breakdown, err := h.AnalyticsService.GetStorageBreakdown(c.Request.Context())
+	if err != nil {
+		queryFailed(c, err, "Failed to get storage breakdown")
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
 // GET /api/v1/analytics/performance
 func (h *AnalyticsHandler) GetPerformanceMetrics(c *gin.Context) {
 	metrics, err := h.AnalyticsService.GetPerformanceMetrics()
@@ -173,90 +357,69 @@ func (h *AnalyticsHandler) GetPerformanceMetrics(c *gin.Context) {
 
 // GET /api/v1/analytics/top/artists
 func (h *AnalyticsHandler) GetTopArtists(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	sortBy := c.DefaultQuery("sort_by", "downloads") // downloads, shows, size
-
-	var orderClause string
-	switch sortBy {
-	case "shows":
-		orderClause = "total_shows DESC"
-	case "size":
-		orderClause = "total_size_gb DESC"
-	default:
-		orderClause = "total_downloads DESC"
+	limit, ok := parseLimit(c, h.DB, "limit", 10)
+	if !ok {
+		return
 	}
+	// sort_by is the metric's older name
+	metric := c.Query("metric")
+	if metric == "" {
+		metric = c.DefaultQuery("sort_by", "downloads")
+	}
+	timeframe := models.AnalyticsTimeframe(c.DefaultQuery("timeframe", string(models.TimeframeAll)))
 
-	query := `
-		SELECT 
-			a.id, a.name,
-			COUNT(DISTINCT s.id) as total_shows,
-			COUNT(DISTINCT d.id) as total_downloads,
-			COALESCE(SUM(CASE WHEN d.status = 'completed' THEN d.file_size ELSE 0 END), 0) / 1073741824.0 as total_size_gb
-		FROM artists a
-		LEFT JOIN shows s ON a.id = s.artist_id
-		LEFT JOIN downloads d ON s.id = d.show_id
-		GROUP BY a.id, a.name
-		HAVING total_downloads > 0
-		ORDER BY ` + orderClause + `
-		LIMIT ?
-	`
-
-	rows, err := h.DB.Query(query, limit)
+	topItems, err := h.AnalyticsService.GetTopArtists(c.Request.Context(), metric, timeframe, limit)
+	if errors.Is(err, services.ErrInvalidTopArtists) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get top artists",
-		})
+		queryFailed(c, err, "Failed to get top artists")
 		return
 	}
-	defer rows.Close()
 
-	var topItems []models.TopListItem
-	for rows.Next() {
-		var id int
-		var name string
-		var shows, downloads int64
-		var sizeGB float64
+	c.JSON(http.StatusOK, gin.H{
+		"data":      topItems,
+		"metric":    metric,
+		"sort_by":   metric,
+		"timeframe": timeframe,
+		"limit":     limit,
+		"total":     len(topItems),
+	})
+}
 
-		if rows.Scan(&id, &name, &shows, &downloads, &sizeGB) == nil {
-			var value, secondaryValue float64
-			var unit string
-
-			switch sortBy {
-			case "shows":
-				value = float64(shows)
-				secondaryValue = float64(downloads)
-				unit = "shows"
-			case "size":
-				value = sizeGB
-				secondaryValue = float64(downloads)
-				unit = "GB"
-			default:
-				value = float64(downloads)
-				secondaryValue = sizeGB
-				unit = "downloads"
-			}
+// GET /api/v1/analytics/recommendations
+func (h *AnalyticsHandler) GetDownloadRecommendations(c *gin.Context) {
+	limit, ok := parseLimit(c, h.DB, "limit", 20)
+	if !ok {
+		return
+	}
+	strategy := models.RecommendationStrategy(c.DefaultQuery("strategy", string(models.RecommendByCompletion)))
 
-			topItems = append(topItems, models.TopListItem{
-				ID:             id,
-				Name:           name,
-				Value:          value,
-				SecondaryValue: secondaryValue,
-				Unit:           unit,
-			})
-		}
+	recommendations, missing, err := h.AnalyticsService.GetDownloadRecommendations(c.Request.Context(), strategy, limit)
+	if errors.Is(err, services.ErrInvalidStrategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		queryFailed(c, err, "Failed to get download recommendations")
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":    topItems,
-		"sort_by": sortBy,
-		"limit":   limit,
-		"total":   len(topItems),
+		"data":          recommendations,
+		"strategy":      strategy,
+		"limit":         limit,
+		"missing_shows": missing,
 	})
 }
 
 // GET /api/v1/analytics/top/venues
 func (h *AnalyticsHandler) GetTopVenues(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	limit, ok := parseLimit(c, h.DB, "limit", 10)
+	if !ok {
+		return
+	}
 
 	query := `
 		SELECT s.venue_name, s.venue_city, s.venue_state,
@@ -368,162 +531,13 @@ func (h *AnalyticsHandler) GetDownloadTrends(c *gin.Context) {
 
 // GET /api/v1/analytics/summary
 func (h *AnalyticsHandler) GetDashboardSummary(c *gin.Context) {
-	// Get key metrics for dashboard display
-	var summary gin.H = gin.H{}
-
-	// Collection overview
-	var totalArtists, totalShows, totalDownloads int64
-	var totalSizeGB float64
-	h.DB.QueryRow(`
-		SELECT 
-			(SELECT COUNT(*) FROM artists) as total_artists,
-			(SELECT COUNT(*) FROM shows) as total_shows,
-			(SELECT COUNT(*) FROM downloads) as total_downloads,
-			(SELECT COALESCE(SUM(file_size), 0) / 1073741824.0 FROM downloads WHERE status = 'completed') as total_size_gb
-	`).Scan(&totalArtists, &totalShows, &totalDownloads, &totalSizeGB)
-
-	summary["collection"] = gin.H{
-		"total_artists":   totalArtists,
-		"total_shows":     totalShows,
-		"total_downloads": totalDownloads,
-		"total_size_gb":   totalSizeGB,
-	}
-
-	// Recent activity (last 24 hours)
-	var recentShows, recentDownloads int64
-	h.DB.QueryRow(`SELECT COUNT(*) FROM shows WHERE created_at >= datetime('now', '-1 day')`).Scan(&recentShows)
-	h.DB.QueryRow(`SELECT COUNT(*) FROM downloads WHERE created_at >= datetime('now', '-1 day')`).Scan(&recentDownloads)
-
-	summary["recent_activity"] = gin.H{
-		"new_shows_24h":     recentShows,
-		"new_downloads_24h": recentDownloads,
-	}
-
-	// System status
-	var activeMonitors, runningJobs, failedJobs int64
-	h.DB.QueryRow(`SELECT COUNT(*) FROM artist_monitors WHERE status = 'active'`).Scan(&activeMonitors)
-
-	jobs := h.AnalyticsService.JobManager.ListJobs()
-	for _, job := range jobs {
-		switch job.Status {
-		case models.JobStatusRunning:
-			runningJobs++
-		case models.JobStatusFailed:
-			failedJobs++
-		}
-	}
-
-	summary["system_status"] = gin.H{
-		"active_monitors": activeMonitors,
-		"running_jobs":    runningJobs,
-		"failed_jobs":     failedJobs,
-	}
-
-	// Top format breakdown
-	formatRows, err := h.DB.Query(`
-		SELECT format, COUNT(*) as count
-		FROM downloads
-		GROUP BY format
-		ORDER BY count DESC
-		LIMIT 3
-	`)
-	if err == nil {
-		defer formatRows.Close()
-		var formats []gin.H
-		for formatRows.Next() {
-			var format string
-			var count int64
-			if formatRows.Scan(&format, &count) == nil {
-				percentage := float64(count) / float64(totalDownloads) * 100
-				formats = append(formats, gin.H{
-					"format":     format,
-					"count":      count,
-					"percentage": percentage,
-				})
-			}
-		}
-		summary["popular_formats"] = formats
-	}
-
+	// Sections whose subsystem fails are left empty and listed under "errors"
+	summary := h.AnalyticsService.GetDashboardSummary()
 	c.JSON(http.StatusOK, summary)
 }
 
 // GET /api/v1/analytics/health
 func (h *AnalyticsHandler) GetHealthScore(c *gin.Context) {
-	// Calculate overall system health score
-	score := models.HealthScore{
-		Categories:      make(map[string]int),
-		Issues:          []string{},
-		Recommendations: []string{},
-		LastUpdated:     time.Now(),
-	}
-
-	// Database health (check for recent activity)
-	var recentActivity int64
-	h.DB.QueryRow(`SELECT COUNT(*) FROM shows WHERE created_at >= datetime('now', '-7 days')`).Scan(&recentActivity)
-	if recentActivity > 0 {
-		score.Categories["database"] = 90
-	} else {
-		score.Categories["database"] = 60
-		score.Issues = append(score.Issues, "No new shows added in the last week")
-		score.Recommendations = append(score.Recommendations, "Consider running a catalog refresh")
-	}
-
-	// Download health (check success rate)
-	var totalDownloads, completedDownloads int64
-	h.DB.QueryRow(`
-		SELECT COUNT(*), COUNT(CASE WHEN status = 'completed' THEN 1 END)
-		FROM downloads WHERE created_at >= datetime('now', '-7 days')
-	`).Scan(&totalDownloads, &completedDownloads)
-
-	if totalDownloads > 0 {
-		successRate := float64(completedDownloads) / float64(totalDownloads) * 100
-		if successRate > 90 {
-			score.Categories["downloads"] = 95
-		} else if successRate > 70 {
-			score.Categories["downloads"] = 75
-		} else {
-			score.Categories["downloads"] = 50
-			score.Issues = append(score.Issues, fmt.Sprintf("Download success rate is %.1f%%", successRate))
-			score.Recommendations = append(score.Recommendations, "Check download system configuration")
-		}
-	} else {
-		score.Categories["downloads"] = 80 // Neutral if no downloads
-	}
-
-	// Monitoring health
-	var activeMonitors int64
-	h.DB.QueryRow(`SELECT COUNT(*) FROM artist_monitors WHERE status = 'active'`).Scan(&activeMonitors)
-	if activeMonitors > 0 {
-		score.Categories["monitoring"] = 85
-	} else {
-		score.Categories["monitoring"] = 40
-		score.Issues = append(score.Issues, "No active artist monitors")
-		score.Recommendations = append(score.Recommendations, "Set up monitoring for favorite artists")
-	}
-
-	// Storage health (simplified)
-	metrics, err := h.AnalyticsService.GetSystemMetrics()
-	if err == nil && metrics.AvailableStorage > 1.0 { // > 1GB free
-		score.Categories["storage"] = 90
-	} else {
-		score.Categories["storage"] = 70
-		if err == nil && metrics.AvailableStorage < 1.0 {
-			score.Issues = append(score.Issues, "Low disk space")
-			score.Recommendations = append(score.Recommendations, "Clean up old downloads or expand storage")
-		}
-	}
-
-	// Calculate overall score
-	total := 0
-	count := 0
-	for _, categoryScore := range score.Categories {
-		total += categoryScore
-		count++
-	}
-	if count > 0 {
-		score.Overall = total / count
-	}
-
+	score := h.AnalyticsService.GetHealthScore()
 	c.JSON(http.StatusOK, score)
 }
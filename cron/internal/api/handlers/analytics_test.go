@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmagar/nugs/cron/internal/models"
@@ -25,13 +27,19 @@ func setupAnalyticsTestRouter(t *testing.T) (*gin.Engine, *models.JobManager) {
 	analytics := router.Group("/analytics")
 	{
 		analytics.POST("/reports", analyticsHandler.GenerateReport)
+		analytics.GET("/reports", analyticsHandler.ListReports)
+		analytics.GET("/reports/:id", analyticsHandler.GetReport)
 		analytics.GET("/collection", analyticsHandler.GetCollectionStats)
 		analytics.GET("/artists", analyticsHandler.GetArtistAnalytics)
+		analytics.GET("/artists/:id/timeline", analyticsHandler.GetArtistTimeline)
+		analytics.GET("/completion/years", analyticsHandler.GetCompletionMatrix)
 		analytics.GET("/downloads", analyticsHandler.GetDownloadAnalytics)
 		analytics.GET("/system", analyticsHandler.GetSystemMetrics)
+		analytics.GET("/storage", analyticsHandler.GetStorageBreakdown)
 		analytics.GET("/performance", analyticsHandler.GetPerformanceMetrics)
 		analytics.GET("/top/artists", analyticsHandler.GetTopArtists)
 		analytics.GET("/top/venues", analyticsHandler.GetTopVenues)
+		analytics.GET("/recommendations", analyticsHandler.GetDownloadRecommendations)
 		analytics.GET("/trends/downloads", analyticsHandler.GetDownloadTrends)
 		analytics.GET("/summary", analyticsHandler.GetDashboardSummary)
 		analytics.GET("/health", analyticsHandler.GetHealthScore)
@@ -121,8 +129,8 @@ func TestAnalyticsHandler_GetCollectionStats(t *testing.T) {
 	require.NoError(t, err)
 
 	expectedFields := []string{
-		"total_artists", "total_shows", "total_downloads",
-		"total_size_gb", "average_shows_per_artist", "recent_activity",
+		"total_artists", "total_shows", "downloaded_shows", "completion_pct",
+		"total_downloads", "total_size_gb", "average_shows_per_artist", "recent_activity",
 	}
 
 	for _, field := range expectedFields {
@@ -130,6 +138,246 @@ func TestAnalyticsHandler_GetCollectionStats(t *testing.T) {
 	}
 }
 
+func TestAnalyticsHandler_LocationFilter(t *testing.T) {
+	router, _ := setupAnalyticsTestRouter(t)
+
+	// The seeded shows include two in New York state
+	req := httptest.NewRequest(http.MethodGet, "/analytics/collection?state=NY", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var stats models.CollectionStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.EqualValues(t, 2, stats.TotalShows)
+
+	req = httptest.NewRequest(http.MethodGet, "/analytics/artists?venue=wrigley", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Data []models.ArtistAnalytics `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 1)
+	assert.Equal(t, "Dead & Company", response.Data[0].ArtistName)
+}
+
+func TestAnalyticsHandler_DateRange(t *testing.T) {
+	router, _ := setupAnalyticsTestRouter(t)
+
+	// Every seeded show was created today, so a range in the past has none
+	req := httptest.NewRequest(http.MethodGet, "/analytics/collection?start_date=2020-01-01&end_date=2020-12-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var stats models.CollectionStats
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.EqualValues(t, 0, stats.TotalShows)
+
+	for _, url := range []string{
+		"/analytics/collection?start_date=2024-02-01&end_date=2024-01-01",
+		"/analytics/downloads?start_date=yesterday",
+	} {
+		req = httptest.NewRequest(http.MethodGet, url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"report_type": "downloads", "start_date": "2024-02-01", "end_date": "2024-01-01",
+	})
+	req = httptest.NewRequest(http.MethodPost, "/analytics/reports", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnalyticsHandler_GetTopArtists(t *testing.T) {
+	router, _ := setupAnalyticsTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/top/artists?metric=completion&limit=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Data   []models.TopListItem `json:"data"`
+		Metric string               `json:"metric"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "completion", response.Metric)
+	for i, item := range response.Data {
+		assert.Equal(t, i+1, item.Rank)
+		assert.Equal(t, "%", item.Unit)
+	}
+
+	for _, query := range []string{"?metric=popularity", "?metric=growth&timeframe=all"} {
+		req = httptest.NewRequest(http.MethodGet, "/analytics/top/artists"+query, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestAnalyticsHandler_GetDownloadRecommendations(t *testing.T) {
+	router, _ := setupAnalyticsTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/recommendations?strategy=recency", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	for _, field := range []string{"data", "strategy", "limit", "missing_shows"} {
+		assert.Contains(t, response, field)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/analytics/recommendations?strategy=random", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnalyticsHandler_RequestContextEnded(t *testing.T) {
+	router, _ := setupAnalyticsTestRouter(t)
+
+	// A request whose deadline passed gets a 504 instead of running the queries
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/analytics/collection", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "Request timed out")
+
+	// A client that went away gets no response at all
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	req = httptest.NewRequest(http.MethodGet, "/analytics/artists", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Empty(t, w.Body.String())
+}
+
+func TestAnalyticsHandler_Deadline(t *testing.T) {
+	db := setupTestDB(t)
+	handler := NewAnalyticsHandler(db, models.NewJobManager())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.Deadline())
+	router.GET("/deadline", func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		c.JSON(http.StatusOK, gin.H{"set": ok, "in": time.Until(deadline).Seconds()})
+	})
+
+	get := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/deadline", nil))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// The seeded analytics_timeout_seconds bounds the request
+	response := get()
+	assert.Equal(t, true, response["set"])
+	assert.InDelta(t, 10, response["in"], 1)
+
+	// 0 leaves the request unbounded
+	_, err := db.Exec("UPDATE system_config SET value = '0' WHERE key = 'analytics_timeout_seconds'")
+	require.NoError(t, err)
+	assert.Equal(t, false, get()["set"])
+}
+
+func TestAnalyticsHandler_MonitoredOnly(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	analyticsHandler := NewAnalyticsHandler(db, models.NewJobManager())
+	router.GET("/analytics/collection", analyticsHandler.GetCollectionStats)
+	router.GET("/analytics/artists", analyticsHandler.GetArtistAnalytics)
+
+	userID := createTestUser(t, db, "monitored", "monitored@example.com", "user")
+	_, err := db.Exec(`INSERT INTO artists (id, name, slug) VALUES (201, 'Monitored Band', 'monitored-band'), (202, 'Unmonitored Band', 'unmonitored-band')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO shows (id, artist_id, date, venue, container_id) VALUES
+			(201, 201, '2023-06-01', 'Red Rocks', 20100),
+			(202, 202, '2023-07-01', 'MSG', 20200),
+			(203, 202, '2023-07-02', 'MSG', 20300)
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality, status)
+		VALUES (?, 202, 20200, 'Unmonitored Band', '2023-07-01', 'MSG', 'FLAC', 'lossless', 'completed')
+	`, userID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO monitors (artist_id, user_id, status, settings) VALUES (201, ?, 'active', '{}')`, userID)
+	require.NoError(t, err)
+
+	collection := func(t *testing.T, params string) models.CollectionStats {
+		req := httptest.NewRequest(http.MethodGet, "/analytics/collection"+params, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var stats models.CollectionStats
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		return stats
+	}
+
+	t.Run("all artists by default", func(t *testing.T) {
+		stats := collection(t, "")
+		assert.GreaterOrEqual(t, int(stats.TotalArtists), 2)
+		assert.GreaterOrEqual(t, int(stats.TotalShows), 3)
+		assert.Equal(t, 1, int(stats.TotalDownloads))
+	})
+
+	t.Run("monitored artists only", func(t *testing.T) {
+		stats := collection(t, "?monitored_only=true")
+		assert.Equal(t, 1, int(stats.TotalArtists))
+		assert.Equal(t, 1, int(stats.TotalShows))
+		assert.Equal(t, 0, int(stats.TotalDownloads))
+
+		req := httptest.NewRequest(http.MethodGet, "/analytics/artists?monitored_only=true", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data []models.ArtistAnalytics `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Data, 1)
+		assert.Equal(t, "Monitored Band", response.Data[0].ArtistName)
+	})
+
+	t.Run("config default", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE system_config SET value = 'true' WHERE key = 'analytics_monitored_only'`)
+		require.NoError(t, err)
+		defer db.Exec(`UPDATE system_config SET value = 'false' WHERE key = 'analytics_monitored_only'`)
+
+		assert.Equal(t, 1, int(collection(t, "").TotalArtists))
+		assert.GreaterOrEqual(t, int(collection(t, "?monitored_only=false").TotalArtists), 2)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/analytics/collection?monitored_only=maybe", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
 func TestAnalyticsHandler_GetArtistAnalytics(t *testing.T) {
 	router, _ := setupAnalyticsTestRouter(t)
 
@@ -178,6 +426,81 @@ func TestAnalyticsHandler_GetArtistAnalytics(t *testing.T) {
 	}
 }
 
+func TestAnalyticsHandler_GetArtistTimeline(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	analyticsHandler := NewAnalyticsHandler(db, models.NewJobManager())
+	router.GET("/analytics/artists/:id/timeline", analyticsHandler.GetArtistTimeline)
+
+	userID := createTestUser(t, db, "timeline", "timeline@example.com", "user")
+	_, err := db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO shows (id, artist_id, date, venue, city, state, container_id) VALUES
+			(101, 101, '2019-12-31', 'The Tabernacle', 'Atlanta', 'GA', 10100),
+			(102, 101, '2022-09-02', 'Red Rocks Amphitheatre', 'Morrison', 'CO', 10200)
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality, status, completed_at)
+		VALUES (?, 101, 10100, 'Billy Strings', '2019-12-31', 'The Tabernacle', 'FLAC', 'lossless', 'completed', '2023-01-15 10:00:00')
+	`, userID)
+	require.NoError(t, err)
+
+	t.Run("events in chronological order", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/analytics/artists/101/timeline", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var timeline models.ArtistTimeline
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &timeline))
+
+		assert.Equal(t, "Billy Strings", timeline.ArtistName)
+		assert.Equal(t, 2, timeline.TotalPerformances)
+		assert.Equal(t, 1, timeline.TotalDownloads)
+		require.Len(t, timeline.Events, 3)
+
+		assert.Equal(t, models.TimelineEventPerformance, timeline.Events[0].Type)
+		assert.Equal(t, "2019-12-31", timeline.Events[0].Date)
+		assert.Equal(t, models.TimelineEventPerformance, timeline.Events[1].Type)
+		assert.Equal(t, "2022-09-02", timeline.Events[1].Date)
+		assert.Equal(t, models.TimelineEventDownload, timeline.Events[2].Type)
+		assert.Equal(t, "2023-01-15 10:00:00", timeline.Events[2].Date)
+		assert.Equal(t, "2019-12-31", timeline.Events[2].ShowDate)
+	})
+
+	t.Run("unknown artist", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/analytics/artists/999/timeline", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid artist ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/analytics/artists/abc/timeline", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestAnalyticsHandler_GetCompletionMatrix(t *testing.T) {
+	router, _ := setupAnalyticsTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/completion/years", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Without monitors the matrix is empty rather than null
+	assert.JSONEq(t, `{"years": [], "artists": []}`, w.Body.String())
+}
+
 func TestAnalyticsHandler_GetDownloadAnalytics(t *testing.T) {
 	router, _ := setupAnalyticsTestRouter(t)
 
@@ -248,11 +571,122 @@ func TestAnalyticsHandler_GetDashboardSummary(t *testing.T) {
 
 	expectedSections := []string{
 		"collection", "popular_formats", "recent_activity", "system_status",
+		"downloads", "recent_alerts", "scheduler", "webhooks", "health", "generated_at",
 	}
 
 	for _, section := range expectedSections {
 		assert.Contains(t, response, section)
 	}
+
+	collection := response["collection"].(map[string]interface{})
+	assert.Contains(t, collection, "completion_pct")
+	assert.NotNil(t, response["downloads"])
+	assert.NotNil(t, response["webhooks"])
+	assert.NotNil(t, response["health"])
+
+	// The test router has no scheduler; the rest of the summary is still returned
+	assert.Nil(t, response["scheduler"])
+	errors := response["errors"].(map[string]interface{})
+	assert.Contains(t, errors, "scheduler")
+	assert.NotContains(t, errors, "webhooks")
+}
+
+func TestAnalyticsHandler_GetStorageBreakdown(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	analyticsHandler := NewAnalyticsHandler(db, models.NewJobManager())
+	router.GET("/analytics/storage", analyticsHandler.GetStorageBreakdown)
+
+	for _, d := range []struct {
+		container int
+		format    string
+		quality   string
+		sizeMB    float64
+		status    string
+	}{
+		{10101, "FLAC", "24bit/96kHz", 3072, "completed"},
+		{10102, "FLAC", "16bit/44.1kHz", 1024, "completed"},
+		{10103, "ALAC", "16bit/44.1kHz", 1024, "completed"},
+		{10104, "MP3", "320kbps", 512, "failed"},
+	} {
+		_, err := db.Exec(`INSERT INTO downloads (user_id, container_id, artist_name, show_date, venue, format, quality, size_mb, status)
+			VALUES (1, ?, 'Billy Strings', '2024-01-01', 'Ryman', ?, ?, ?, ?)`, d.container, d.format, d.quality, d.sizeMB, d.status)
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/analytics/storage", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var breakdown models.StorageBreakdown
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &breakdown))
+
+	// Only completed downloads take up storage
+	assert.Equal(t, int64(3), breakdown.TotalDownloads)
+	assert.InDelta(t, 5.0, breakdown.TotalSizeGB, 0.001)
+
+	require.Len(t, breakdown.ByFormat, 2)
+	assert.Equal(t, "FLAC", breakdown.ByFormat[0].Name)
+	assert.Equal(t, int64(2), breakdown.ByFormat[0].Downloads)
+	assert.InDelta(t, 4.0, breakdown.ByFormat[0].TotalSizeGB, 0.001)
+	assert.InDelta(t, 2.0, breakdown.ByFormat[0].AverageSizeGB, 0.001)
+	assert.InDelta(t, 80.0, breakdown.ByFormat[0].Percentage, 0.001)
+	assert.Equal(t, "ALAC", breakdown.ByFormat[1].Name)
+	assert.InDelta(t, 20.0, breakdown.ByFormat[1].Percentage, 0.001)
+
+	require.Len(t, breakdown.ByQuality, 2)
+	assert.Equal(t, "24bit/96kHz", breakdown.ByQuality[0].Name)
+	assert.InDelta(t, 60.0, breakdown.ByQuality[0].Percentage, 0.001)
+	assert.Equal(t, "16bit/44.1kHz", breakdown.ByQuality[1].Name)
+	assert.Equal(t, int64(2), breakdown.ByQuality[1].Downloads)
+}
+
+func TestAnalyticsHandler_StorageForecast(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	analyticsHandler := NewAnalyticsHandler(db, models.NewJobManager())
+	router.GET("/analytics/system", analyticsHandler.GetSystemMetrics)
+	router.GET("/analytics/summary", analyticsHandler.GetDashboardSummary)
+
+	_, err := db.Exec("UPDATE system_config SET value = ? WHERE key = 'default_download_path'", t.TempDir())
+	require.NoError(t, err)
+
+	getForecast := func(path, field string) models.StorageForecast {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Contains(t, response, field)
+		var forecast models.StorageForecast
+		require.NoError(t, json.Unmarshal(response[field], &forecast))
+		return forecast
+	}
+
+	// Without recent downloads there is nothing to project
+	forecast := getForecast("/analytics/system", "storage_forecast")
+	assert.Greater(t, forecast.TotalGB, 0.0)
+	assert.Equal(t, 30, forecast.WindowDays)
+	assert.Zero(t, forecast.GrowthGBPerDay)
+	assert.Nil(t, forecast.DaysToFull)
+	assert.Nil(t, forecast.FullAt)
+
+	// 30 GB over the 30 day window grows storage by 1 GB a day
+	_, err = db.Exec(`INSERT INTO downloads (user_id, container_id, artist_name, show_date, venue, format, quality, size_mb, status)
+		VALUES (1, 10100, 'Billy Strings', '2024-01-01', 'Ryman', 'FLAC', 'hi-res', 30720, 'completed')`)
+	require.NoError(t, err)
+
+	forecast = getForecast("/analytics/summary", "storage")
+	assert.InDelta(t, 1.0, forecast.GrowthGBPerDay, 0.001)
+	require.NotNil(t, forecast.DaysToFull)
+	assert.InDelta(t, forecast.FreeGB, *forecast.DaysToFull, 0.5)
+	require.NotNil(t, forecast.FullAt)
+	assert.WithinDuration(t, time.Now().Add(time.Duration(*forecast.DaysToFull*24)*time.Hour), *forecast.FullAt, 2*time.Hour)
 }
 
 func TestAnalyticsHandler_GetHealthScore(t *testing.T) {
@@ -276,3 +710,104 @@ func TestAnalyticsHandler_GetHealthScore(t *testing.T) {
 		assert.Contains(t, response, field)
 	}
 }
+
+func TestAnalyticsHandler_ReportArtifacts(t *testing.T) {
+	router, _ := setupAnalyticsTestRouter(t)
+
+	body, _ := json.Marshal(map[string]interface{}{"report_type": "collection"})
+	req := httptest.NewRequest(http.MethodPost, "/analytics/reports", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var generated models.AnalyticsReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &generated))
+
+	// The generated report is listed
+	req = httptest.NewRequest(http.MethodGet, "/analytics/reports", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var list struct {
+		Data  []models.ReportArtifact `json:"data"`
+		Total int64                   `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	assert.EqualValues(t, 1, list.Total)
+	assert.Equal(t, generated.ReportID, list.Data[0].ReportID)
+	assert.Equal(t, "collection", list.Data[0].ReportType)
+	assert.Equal(t, models.ReportFormatJSON, list.Data[0].Format)
+	assert.Positive(t, list.Data[0].SizeBytes)
+
+	// And can be fetched again as it was generated
+	req = httptest.NewRequest(http.MethodGet, "/analytics/reports/"+generated.ReportID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+	var fetched models.AnalyticsReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.Equal(t, generated.ReportID, fetched.ReportID)
+	assert.Equal(t, generated.Summary, fetched.Summary)
+	require.NotNil(t, fetched.CollectionStats)
+	assert.Equal(t, generated.CollectionStats.TotalShows, fetched.CollectionStats.TotalShows)
+
+	req = httptest.NewRequest(http.MethodGet, "/analytics/reports/report_missing", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAnalyticsHandler_GenerateReportAsync(t *testing.T) {
+	router, jobManager := setupAnalyticsTestRouter(t)
+
+	body, _ := json.Marshal(map[string]interface{}{"report_type": "downloads"})
+	req := httptest.NewRequest(http.MethodPost, "/analytics/reports?async=true", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var started struct {
+		JobID string `json:"job_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+	require.NotEmpty(t, started.JobID)
+
+	var artifact *models.ReportArtifact
+	require.Eventually(t, func() bool {
+		job, ok := jobManager.GetJob(started.JobID)
+		require.True(t, ok)
+		require.NotEqual(t, models.JobStatusFailed, job.Status, job.Error)
+		if job.Status != models.JobStatusCompleted {
+			return false
+		}
+		artifact, _ = job.Result.(*models.ReportArtifact)
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NotNil(t, artifact)
+	assert.Equal(t, "downloads", artifact.ReportType)
+
+	// The finished report is fetched from the reports endpoint
+	req = httptest.NewRequest(http.MethodGet, "/analytics/reports/"+artifact.ReportID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report models.AnalyticsReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, artifact.ReportID, report.ReportID)
+	assert.NotNil(t, report.DownloadAnalytics)
+
+	// An unsupported type is still rejected up front
+	body, _ = json.Marshal(map[string]interface{}{"report_type": "weather"})
+	req = httptest.NewRequest(http.MethodPost, "/analytics/reports?async=true", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
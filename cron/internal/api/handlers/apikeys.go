@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/services"
+)
+
+type APIKeyHandler struct {
+	APIKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(db *sql.DB) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyService: services.NewAPIKeyService(db),
+	}
+}
+
+// POST /api/v1/admin/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req models.APIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	response, err := h.APIKeyService.CreateKey(&req, c.GetInt("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	if !response.Success {
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GET /api/v1/admin/api-keys
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.APIKeyService.ListKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  keys,
+		"total": len(keys),
+	})
+}
+
+// DELETE /api/v1/admin/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	err = h.APIKeyService.RevokeKey(keyID)
+	if err != nil {
+		if err.Error() == "API key not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "API key revoked successfully",
+	})
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmagar/nugs/cron/internal/api/middleware"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWTSecret = "test-secret"
+
+func setupAPIKeyTestRouter(t *testing.T) *gin.Engine {
+	db := setupTestDB(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	apiKeyHandler := NewAPIKeyHandler(db)
+	catalogHandler := NewCatalogHandler(db)
+	apiKeyService := services.NewAPIKeyService(db)
+
+	protected := router.Group("/")
+	protected.Use(middleware.APIKeyOrJWTAuth(testJWTSecret, apiKeyService.Authenticate))
+	{
+		catalog := protected.Group("/catalog")
+		catalog.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeCatalog))
+		{
+			catalog.GET("/artists", catalogHandler.GetArtists)
+		}
+
+		downloads := protected.Group("/downloads")
+		downloads.Use(middleware.RequireAPIKeyScope(models.APIKeyScopeDownloads))
+		{
+			downloads.GET("/", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+		}
+
+		admin := protected.Group("/admin")
+		admin.Use(middleware.DenyAPIKey())
+		{
+			admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+			admin.GET("/api-keys", apiKeyHandler.GetAPIKeys)
+			admin.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+		}
+	}
+
+	return router
+}
+
+func createTestAPIKey(t *testing.T, router *gin.Engine, token string, scopes []string) models.APIKeyResponse {
+	body, _ := json.Marshal(map[string]interface{}{"name": "test key", "scopes": scopes})
+	req := httptest.NewRequest("POST", "/admin/api-keys", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var response models.APIKeyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestAPIKeyHandler_CreateAPIKey(t *testing.T) {
+	router := setupAPIKeyTestRouter(t)
+	token, err := middleware.GenerateToken(1, "admin", "admin", testJWTSecret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+	}{
+		{
+			name:           "read-only key",
+			requestBody:    map[string]interface{}{"name": "dashboard", "scopes": []string{"read"}},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "scoped key",
+			requestBody:    map[string]interface{}{"name": "catalog sync", "scopes": []string{"catalog", "analytics"}},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "unknown scope",
+			requestBody:    map[string]interface{}{"name": "bad", "scopes": []string{"admin"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing scopes",
+			requestBody:    map[string]interface{}{"name": "bad"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing name",
+			requestBody:    map[string]interface{}{"scopes": []string{"read"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.requestBody)
+			req := httptest.NewRequest("POST", "/admin/api-keys", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusCreated {
+				var response models.APIKeyResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.True(t, response.Success)
+				assert.Contains(t, response.Key, "nugs_")
+				require.NotNil(t, response.APIKey)
+				assert.True(t, len(response.Key) > len(response.APIKey.KeyPrefix))
+				assert.Equal(t, response.Key[:len(response.APIKey.KeyPrefix)], response.APIKey.KeyPrefix)
+			}
+		})
+	}
+
+	// The plaintext key is never listed
+	req := httptest.NewRequest("GET", "/admin/api-keys", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"key":`)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, float64(2), response["total"])
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := setupAPIKeyTestRouter(t)
+	token, err := middleware.GenerateToken(1, "admin", "admin", testJWTSecret)
+	require.NoError(t, err)
+
+	readKey := createTestAPIKey(t, router, token, []string{"read"}).Key
+	catalogKey := createTestAPIKey(t, router, token, []string{"catalog"}).Key
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		apiKey         string
+		expectedStatus int
+	}{
+		{
+			name:           "read key on catalog",
+			method:         "GET",
+			path:           "/catalog/artists",
+			apiKey:         readKey,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "scoped key on its scope",
+			method:         "GET",
+			path:           "/catalog/artists",
+			apiKey:         catalogKey,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "scoped key outside its scope",
+			method:         "GET",
+			path:           "/downloads/",
+			apiKey:         catalogKey,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "write request with key",
+			method:         "POST",
+			path:           "/admin/api-keys",
+			apiKey:         readKey,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin read with key",
+			method:         "GET",
+			path:           "/admin/api-keys",
+			apiKey:         readKey,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unknown key",
+			method:         "GET",
+			path:           "/catalog/artists",
+			apiKey:         "nugs_invalid",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-API-Key", tt.apiKey)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestAPIKeyHandler_RevokeAPIKey(t *testing.T) {
+	router := setupAPIKeyTestRouter(t)
+	token, err := middleware.GenerateToken(1, "admin", "admin", testJWTSecret)
+	require.NoError(t, err)
+
+	created := createTestAPIKey(t, router, token, []string{"read"})
+
+	revoke := func(id string) int {
+		req := httptest.NewRequest("DELETE", "/admin/api-keys/"+id, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, revoke(strconv.Itoa(created.APIKey.ID)))
+	assert.Equal(t, http.StatusNotFound, revoke(strconv.Itoa(created.APIKey.ID)))
+	assert.Equal(t, http.StatusNotFound, revoke("999"))
+	assert.Equal(t, http.StatusBadRequest, revoke("invalid"))
+
+	// A revoked key no longer authenticates
+	req := httptest.NewRequest("GET", "/catalog/artists", nil)
+	req.Header.Set("X-API-Key", created.Key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
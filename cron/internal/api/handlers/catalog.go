@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
 	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jmagar/nugs/cron/internal/services"
 )
 
 // CatalogHandler handles catalog-related endpoints
@@ -73,18 +75,55 @@ func NewCatalogHandler(db *sql.DB) *CatalogHandler {
 	return &CatalogHandler{DB: db}
 }
 
-// validatePagination ensures pagination parameters are valid
-func validatePagination(params *PaginationParams) {
-	if params.Page < 1 {
-		params.Page = 1
+// DefaultMaxPageSize caps page_size and limit when the max_page_size config
+// key is unset
+const DefaultMaxPageSize = 100
+
+// parseLimit reads the query parameter name as a page size or result limit,
+// defaulting to def. A value that isn't a whole number from 1 to max_page_size
+// is answered with a 400 and ok is false.
+func parseLimit(c *gin.Context, db *sql.DB, name string, def int) (limit int, ok bool) {
+	max := services.GetConfigInt(db, "max_page_size", DefaultMaxPageSize)
+
+	value := c.Query(name)
+	if value == "" {
+		if def > max {
+			def = max
+		}
+		return def, true
 	}
-	if params.PageSize < 1 {
-		params.PageSize = 20 // Default page size
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > max {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":         fmt.Sprintf("Invalid %s, must be between 1 and %d", name, max),
+			"max_page_size": max,
+		})
+		return 0, false
 	}
-	if params.PageSize > 100 {
-		params.PageSize = 100 // Max page size
+	return limit, true
+}
+
+// parsePage reads the page and page_size query parameters. Pages start at 1,
+// and page_size defaults to defSize and is limited by parseLimit.
+func parsePage(c *gin.Context, db *sql.DB, defSize int) (page, pageSize int, ok bool) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
 	}
-	params.Offset = (params.Page - 1) * params.PageSize
+	pageSize, ok = parseLimit(c, db, "page_size", defSize)
+	return page, pageSize, ok
+}
+
+// parsePagination reads the page and page_size query parameters into
+// PaginationParams, page_size defaulting to 20
+func parsePagination(c *gin.Context, db *sql.DB) (PaginationParams, bool) {
+	page, pageSize, ok := parsePage(c, db, 20)
+	return PaginationParams{
+		Page:     page,
+		PageSize: pageSize,
+		Offset:   (page - 1) * pageSize,
+	}, ok
 }
 
 // createPaginatedResponse creates a standardized paginated response
@@ -105,18 +144,10 @@ func createPaginatedResponse(data interface{}, params PaginationParams, total in
 // GetArtists returns a paginated list of artists
 func (h *CatalogHandler) GetArtists(c *gin.Context) {
 	// Parse pagination parameters
-	params := PaginationParams{}
-	if page := c.Query("page"); page != "" {
-		if p, err := strconv.Atoi(page); err == nil {
-			params.Page = p
-		}
-	}
-	if pageSize := c.Query("page_size"); pageSize != "" {
-		if ps, err := strconv.Atoi(pageSize); err == nil {
-			params.PageSize = ps
-		}
+	params, ok := parsePagination(c, h.DB)
+	if !ok {
+		return
 	}
-	validatePagination(&params)
 
 	// Parse filters
 	search := c.Query("search")
@@ -144,9 +175,9 @@ func (h *CatalogHandler) GetArtists(c *gin.Context) {
 	// Count total records
 	countQuery := "SELECT COUNT(*) FROM artists " + whereClause
 	var total int64
-	err := h.DB.QueryRow(countQuery, args...).Scan(&total)
+	err := h.DB.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count artists"})
+		queryFailed(c, err, "Failed to count artists")
 		return
 	}
 
@@ -161,9 +192,9 @@ func (h *CatalogHandler) GetArtists(c *gin.Context) {
 
 	args = append(args, params.PageSize, params.Offset)
 
-	rows, err := h.DB.Query(query, args...)
+	rows, err := h.DB.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query artists"})
+		queryFailed(c, err, "Failed to query artists")
 		return
 	}
 	defer rows.Close()
@@ -183,6 +214,10 @@ func (h *CatalogHandler) GetArtists(c *gin.Context) {
 		}
 		artists = append(artists, artist)
 	}
+	if err := rows.Err(); err != nil {
+		queryFailed(c, err, "Failed to query artists")
+		return
+	}
 
 	response := createPaginatedResponse(artists, params, total)
 	c.JSON(http.StatusOK, response)
@@ -223,22 +258,14 @@ func (h *CatalogHandler) GetArtistShows(c *gin.Context) {
 	artistID := c.Param("id")
 
 	// Parse pagination parameters
-	params := PaginationParams{}
-	if page := c.Query("page"); page != "" {
-		if p, err := strconv.Atoi(page); err == nil {
-			params.Page = p
-		}
-	}
-	if pageSize := c.Query("page_size"); pageSize != "" {
-		if ps, err := strconv.Atoi(pageSize); err == nil {
-			params.PageSize = ps
-		}
+	params, ok := parsePagination(c, h.DB)
+	if !ok {
+		return
 	}
-	validatePagination(&params)
 
 	// Verify artist exists
 	var artistExists bool
-	err := h.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM artists WHERE id = ? OR slug = ?)", artistID, artistID).Scan(&artistExists)
+	err := h.DB.QueryRowContext(c.Request.Context(), "SELECT EXISTS(SELECT 1 FROM artists WHERE id = ? OR slug = ?)", artistID, artistID).Scan(&artistExists)
 	if err != nil || !artistExists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Artist not found"})
 		return
@@ -247,9 +274,9 @@ func (h *CatalogHandler) GetArtistShows(c *gin.Context) {
 	// Count total shows
 	countQuery := "SELECT COUNT(*) FROM shows WHERE artist_id = ?"
 	var total int64
-	err = h.DB.QueryRow(countQuery, artistID).Scan(&total)
+	err = h.DB.QueryRowContext(c.Request.Context(), countQuery, artistID).Scan(&total)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count shows"})
+		queryFailed(c, err, "Failed to count shows")
 		return
 	}
 
@@ -266,9 +293,9 @@ func (h *CatalogHandler) GetArtistShows(c *gin.Context) {
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := h.DB.Query(query, artistID, params.PageSize, params.Offset)
+	rows, err := h.DB.QueryContext(c.Request.Context(), query, artistID, params.PageSize, params.Offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query shows"})
+		queryFailed(c, err, "Failed to query shows")
 		return
 	}
 	defer rows.Close()
@@ -290,6 +317,10 @@ func (h *CatalogHandler) GetArtistShows(c *gin.Context) {
 		}
 		shows = append(shows, show)
 	}
+	if err := rows.Err(); err != nil {
+		queryFailed(c, err, "Failed to query shows")
+		return
+	}
 
 	response := createPaginatedResponse(shows, params, total)
 	c.JSON(http.StatusOK, response)
@@ -334,18 +365,10 @@ func (h *CatalogHandler) GetShow(c *gin.Context) {
 // SearchShows performs a comprehensive search across shows
 func (h *CatalogHandler) SearchShows(c *gin.Context) {
 	// Parse pagination parameters
-	params := PaginationParams{}
-	if page := c.Query("page"); page != "" {
-		if p, err := strconv.Atoi(page); err == nil {
-			params.Page = p
-		}
-	}
-	if pageSize := c.Query("page_size"); pageSize != "" {
-		if ps, err := strconv.Atoi(pageSize); err == nil {
-			params.PageSize = ps
-		}
+	params, ok := parsePagination(c, h.DB)
+	if !ok {
+		return
 	}
-	validatePagination(&params)
 
 	// Parse search parameters
 	search := c.Query("search")
@@ -374,9 +397,9 @@ func (h *CatalogHandler) SearchShows(c *gin.Context) {
 	// Count total records
 	countQuery := "SELECT COUNT(*) FROM shows s JOIN artists a ON s.artist_id = a.id " + whereClause
 	var total int64
-	err := h.DB.QueryRow(countQuery, args...).Scan(&total)
+	err := h.DB.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count shows"})
+		queryFailed(c, err, "Failed to count shows")
 		return
 	}
 
@@ -394,9 +417,9 @@ func (h *CatalogHandler) SearchShows(c *gin.Context) {
 
 	args = append(args, params.PageSize, params.Offset)
 
-	rows, err := h.DB.Query(query, args...)
+	rows, err := h.DB.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query shows"})
+		queryFailed(c, err, "Failed to query shows")
 		return
 	}
 	defer rows.Close()
@@ -418,6 +441,10 @@ func (h *CatalogHandler) SearchShows(c *gin.Context) {
 		}
 		shows = append(shows, show)
 	}
+	if err := rows.Err(); err != nil {
+		queryFailed(c, err, "Failed to query shows")
+		return
+	}
 
 	response := createPaginatedResponse(shows, params, total)
 	c.JSON(http.StatusOK, response)
@@ -245,3 +245,51 @@ func TestCatalogHandler_GetShow(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLimit_MaxPageSize(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/items", func(c *gin.Context) {
+		page, pageSize, ok := parsePage(c, db, 20)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"page": page, "page_size": pageSize})
+	})
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items"+query, nil))
+		return w
+	}
+
+	w := get("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"page": 1, "page_size": 20}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, get("?page=2&page_size=100").Code)
+	for _, query := range []string{"?page_size=101", "?page_size=0", "?page_size=ten"} {
+		w := get(query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), "between 1 and 100", query)
+	}
+
+	// A lower max_page_size also caps the default
+	_, err := db.Exec("UPDATE system_config SET value = '10' WHERE key = 'max_page_size'")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"page": 1, "page_size": 10}`, get("").Body.String())
+	assert.Equal(t, http.StatusBadRequest, get("?page_size=11").Code)
+}
+
+func TestCatalogHandler_GetArtistsPageSizeTooLarge(t *testing.T) {
+	router := setupCatalogTestRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/catalog/artists?page_size=5000", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.EqualValues(t, 100, response["max_page_size"])
+}
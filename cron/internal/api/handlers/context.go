@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryFailed answers a request whose database work failed. When the
+// request's context ended first the query was abandoned, so a timeout gets
+// 504 and a client that went away gets nothing. Anything else is a 500 with
+// message.
+func queryFailed(c *gin.Context, err error, message string) {
+	if ctxErr := c.Request.Context().Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+			return
+		}
+		c.Abort()
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -3,12 +3,17 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,6 +34,10 @@ func setupDownloadTestRouter(t *testing.T) (*gin.Engine, *models.JobManager) {
 		downloads.GET("/queue", downloadHandler.GetDownloadQueue)
 		downloads.POST("/queue/reorder", downloadHandler.ReorderQueue)
 		downloads.GET("/stats", downloadHandler.GetDownloadStats)
+		downloads.GET("/duplicates", downloadHandler.GetDuplicates)
+		downloads.POST("/duplicates/cleanup", downloadHandler.CleanupDuplicates)
+		downloads.POST("/scan", downloadHandler.ScanShows)
+		downloads.POST("/fetch-new", downloadHandler.FetchNewShows)
 		downloads.GET("/:id", downloadHandler.GetDownload)
 		downloads.DELETE("/:id", downloadHandler.CancelDownload)
 	}
@@ -75,6 +84,16 @@ func TestDownloadHandler_QueueDownload(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			checkFields:    []string{"error"},
 		},
+		{
+			name: "invalid track number",
+			requestBody: map[string]interface{}{
+				"show_id": 4,
+				"format":  "flac",
+				"tracks":  []int{0, 2},
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkFields:    []string{"error"},
+		},
 		{
 			name: "invalid format",
 			requestBody: map[string]interface{}{
@@ -163,6 +182,117 @@ func TestDownloadHandler_GetDownloads(t *testing.T) {
 	}
 }
 
+func TestDownloadHandler_OwnerScoping(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	alice := createTestUser(t, db, "alice", "alice@example.com", "user")
+	bob := createTestUser(t, db, "bob", "bob@example.com", "user")
+
+	_, err := db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO shows (id, artist_id, date, venue, city, state, container_id) VALUES (101, 101, '2022-09-02', 'Red Rocks Amphitheatre', 'Morrison', 'CO', 10100)`)
+	require.NoError(t, err)
+
+	for _, userID := range []int64{alice, bob} {
+		_, err := db.Exec(`
+			INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality, status)
+			VALUES (?, 101, 10100, 'Billy Strings', '2022-09-02', 'Red Rocks Amphitheatre', 'FLAC', 'lossless', 'completed')
+		`, userID)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name          string
+		userID        int64
+		role          string
+		expectedTotal float64
+	}{
+		{name: "user sees own downloads", userID: alice, role: "user", expectedTotal: 1},
+		{name: "other user sees only theirs", userID: bob, role: "user", expectedTotal: 1},
+		{name: "admin sees all downloads", userID: 1, role: "admin", expectedTotal: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(asUser(tt.userID, tt.role))
+			downloadHandler := NewDownloadHandler(db, models.NewJobManager())
+			router.GET("/downloads/", downloadHandler.GetDownloads)
+
+			req := httptest.NewRequest(http.MethodGet, "/downloads/", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			pagination := response["pagination"].(map[string]interface{})
+			assert.Equal(t, tt.expectedTotal, pagination["total"])
+		})
+	}
+}
+
+func TestDownloadHandler_CrossUserWrites(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	alice := createTestUser(t, db, "alice", "alice@example.com", "user")
+	bob := createTestUser(t, db, "bob", "bob@example.com", "user")
+
+	_, err := db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO shows (id, artist_id, date, venue, city, state, container_id) VALUES (101, 101, '2022-09-02', 'Red Rocks Amphitheatre', 'Morrison', 'CO', 10100)`)
+	require.NoError(t, err)
+
+	result, err := db.Exec(`
+		INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality, status, queue_position)
+		VALUES (?, 101, 10100, 'Billy Strings', '2022-09-02', 'Red Rocks Amphitheatre', 'FLAC', 'lossless', 'queued', 1)
+	`, alice)
+	require.NoError(t, err)
+	downloadID, _ := result.LastInsertId()
+
+	newRouter := func(userID int64, role string) *gin.Engine {
+		router := gin.New()
+		router.Use(asUser(userID, role))
+		downloadHandler := NewDownloadHandler(db, models.NewJobManager())
+		router.POST("/downloads/queue/reorder", downloadHandler.ReorderQueue)
+		router.DELETE("/downloads/:id", downloadHandler.CancelDownload)
+		return router
+	}
+
+	reorder := func(userID int64, role string) int {
+		body, _ := json.Marshal(map[string]interface{}{"download_ids": []string{fmt.Sprint(downloadID)}})
+		req := httptest.NewRequest(http.MethodPost, "/downloads/queue/reorder", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		newRouter(userID, role).ServeHTTP(w, req)
+		return w.Code
+	}
+	cancel := func(userID int64, role string) int {
+		req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/downloads/%d", downloadID), nil)
+		w := httptest.NewRecorder()
+		newRouter(userID, role).ServeHTTP(w, req)
+		return w.Code
+	}
+	status := func() string {
+		var status string
+		require.NoError(t, db.QueryRow(`SELECT status FROM downloads WHERE id = ?`, downloadID).Scan(&status))
+		return status
+	}
+
+	// Another user can neither reorder nor cancel the download
+	assert.Equal(t, http.StatusNotFound, reorder(bob, "user"))
+	assert.Equal(t, http.StatusNotFound, cancel(bob, "user"))
+	assert.Equal(t, "queued", status())
+
+	// The owner and admins can
+	assert.Equal(t, http.StatusOK, reorder(alice, "user"))
+	assert.Equal(t, http.StatusOK, reorder(1, "admin"))
+	assert.Equal(t, http.StatusOK, cancel(alice, "user"))
+	assert.Equal(t, "cancelled", status())
+}
+
 func TestDownloadHandler_GetDownloadQueue(t *testing.T) {
 	router, _ := setupDownloadTestRouter(t)
 
@@ -334,3 +464,228 @@ func TestDownloadHandler_CancelDownload(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadHandler_Duplicates(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	downloadHandler := NewDownloadHandler(db, models.NewJobManager())
+	router.GET("/downloads/duplicates", downloadHandler.GetDuplicates)
+	router.POST("/downloads/duplicates/cleanup", downloadHandler.CleanupDuplicates)
+
+	downloadPath := t.TempDir()
+	_, err := db.Exec("UPDATE system_config SET value = ? WHERE key = 'default_download_path'", downloadPath)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+	for _, show := range []struct{ id, container int }{{101, 10101}, {102, 10102}} {
+		_, err = db.Exec(`INSERT INTO shows (id, artist_id, date, venue, city, state, container_id) VALUES (?, 101, '2022-09-02', 'Red Rocks Amphitheatre', 'Morrison', 'CO', ?)`,
+			show.id, show.container)
+		require.NoError(t, err)
+	}
+
+	// Show 101 is downloaded three times, show 102 once
+	for _, d := range []struct {
+		id, show, container int
+		format, quality     string
+		sizeMB              float64
+	}{
+		{101, 101, 10101, "MP3", "320kbps", 200},
+		{102, 101, 10101, "FLAC", "24bit/96kHz", 2000},
+		{103, 101, 10101, "ALAC", "16bit/44.1kHz", 900},
+		{104, 102, 10102, "FLAC", "16bit/44.1kHz", 1000},
+	} {
+		_, err := db.Exec(`
+			INSERT INTO downloads (id, user_id, show_id, container_id, artist_name, show_date, venue, format, quality, size_mb, status)
+			VALUES (?, 1, ?, ?, 'Billy Strings', '2022-09-02', 'Red Rocks Amphitheatre', ?, ?, ?, 'completed')
+		`, d.id, d.show, d.container, d.format, d.quality, d.sizeMB)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(downloadPath, fmt.Sprintf("Billy_Strings_%d.%s", d.container, d.format)), []byte("audio"), 0644))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/downloads/duplicates", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report models.DuplicateReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Equal(t, 1, report.TotalShows)
+	assert.Equal(t, 2, report.TotalRedundant)
+	assert.Equal(t, 1100.0, report.ReclaimableMB)
+
+	show := report.Shows[0]
+	assert.Equal(t, 101, show.ShowID)
+	assert.Equal(t, "2022-09-02", show.ShowDate)
+	assert.Equal(t, 102, show.Keep.DownloadID, "the 24 bit FLAC is the best copy")
+	require.Len(t, show.Redundant, 2)
+	assert.Equal(t, 101, show.Redundant[0].DownloadID)
+	assert.Equal(t, filepath.Join(downloadPath, "Billy_Strings_10101.MP3"), show.Redundant[0].FilePath)
+	assert.Equal(t, 103, show.Redundant[1].DownloadID)
+
+	// A dry run removes nothing
+	req = httptest.NewRequest(http.MethodPost, "/downloads/duplicates/cleanup", bytes.NewBufferString(`{"dry_run": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result models.DuplicateCleanupResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.Removed)
+	assert.FileExists(t, filepath.Join(downloadPath, "Billy_Strings_10101.MP3"))
+
+	// A real run keeps the best copy's record and file
+	req = httptest.NewRequest(http.MethodPost, "/downloads/duplicates/cleanup", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	result = models.DuplicateCleanupResult{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.False(t, result.DryRun)
+	assert.Equal(t, 2, result.Removed)
+	assert.Zero(t, result.Failed)
+	assert.Equal(t, 1100.0, result.ReclaimedMB)
+
+	assert.NoFileExists(t, filepath.Join(downloadPath, "Billy_Strings_10101.MP3"))
+	assert.NoFileExists(t, filepath.Join(downloadPath, "Billy_Strings_10101.ALAC"))
+	assert.FileExists(t, filepath.Join(downloadPath, "Billy_Strings_10101.FLAC"))
+	assert.FileExists(t, filepath.Join(downloadPath, "Billy_Strings_10102.FLAC"))
+
+	var ids []int
+	rows, err := db.Query("SELECT id FROM downloads WHERE id >= 101 ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []int{102, 104}, ids)
+}
+
+func TestDownloadHandler_Duplicates_PerUserAndPartial(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	downloadHandler := NewDownloadHandler(db, models.NewJobManager())
+	router.GET("/downloads/duplicates", downloadHandler.GetDuplicates)
+	router.POST("/downloads/duplicates/cleanup", downloadHandler.CleanupDuplicates)
+
+	downloadPath := t.TempDir()
+	_, err := db.Exec("UPDATE system_config SET value = ? WHERE key = 'default_download_path'", downloadPath)
+	require.NoError(t, err)
+
+	alice := createTestUser(t, db, "alice", "alice@example.com", "user")
+	bob := createTestUser(t, db, "bob", "bob@example.com", "user")
+
+	_, err = db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+	for _, show := range []struct{ id, container int }{{101, 10101}, {102, 10102}, {103, 10103}} {
+		_, err = db.Exec(`INSERT INTO shows (id, artist_id, date, venue, city, state, container_id) VALUES (?, 101, '2022-09-02', 'Red Rocks Amphitheatre', 'Morrison', 'CO', ?)`,
+			show.id, show.container)
+		require.NoError(t, err)
+	}
+
+	for _, d := range []struct {
+		id, show, container int
+		userID              int64
+		format, quality     string
+		tracks              interface{}
+	}{
+		// Each user has one copy of show 101
+		{201, 101, 10101, alice, "MP3", "320kbps", nil},
+		{202, 101, 10101, bob, "FLAC", "24bit/96kHz", nil},
+		// Alice's higher quality download of show 102 is only two tracks
+		{203, 102, 10102, alice, "FLAC", "16bit/44.1kHz", nil},
+		{204, 102, 10102, alice, "FLAC", "24bit/96kHz", "[1,2]"},
+		// Alice's redundant MP3 of show 103 is the same file as bob's copy
+		{205, 103, 10103, alice, "FLAC", "24bit/96kHz", nil},
+		{206, 103, 10103, alice, "MP3", "320kbps", nil},
+		{207, 103, 10103, bob, "MP3", "320kbps", nil},
+	} {
+		_, err := db.Exec(`
+			INSERT INTO downloads (id, user_id, show_id, container_id, artist_name, show_date, venue, format, quality, size_mb, status, tracks)
+			VALUES (?, ?, ?, ?, 'Billy Strings', '2022-09-02', 'Red Rocks Amphitheatre', ?, ?, 500, 'completed', ?)
+		`, d.id, d.userID, d.show, d.container, d.format, d.quality, d.tracks)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(downloadPath, fmt.Sprintf("Billy_Strings_%d.%s", d.container, d.format)), []byte("audio"), 0644))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/downloads/duplicates", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report models.DuplicateReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Equal(t, 1, report.TotalShows)
+	show := report.Shows[0]
+	assert.Equal(t, int(alice), show.UserID)
+	assert.Equal(t, 103, show.ShowID)
+	assert.Equal(t, 205, show.Keep.DownloadID)
+	require.Len(t, show.Redundant, 1)
+	assert.Equal(t, 206, show.Redundant[0].DownloadID)
+
+	req = httptest.NewRequest(http.MethodPost, "/downloads/duplicates/cleanup", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result models.DuplicateCleanupResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, result.Removed)
+	assert.Zero(t, result.Failed)
+
+	// Only alice's redundant record goes; the file stays for bob's download
+	var remaining int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM downloads WHERE id BETWEEN 201 AND 207").Scan(&remaining))
+	assert.Equal(t, 6, remaining)
+	for _, file := range []string{"10101.MP3", "10101.FLAC", "10102.FLAC", "10103.FLAC", "10103.MP3"} {
+		assert.FileExists(t, filepath.Join(downloadPath, "Billy_Strings_"+file))
+	}
+}
+
+func TestDownloadHandler_ScanAndFetchNew(t *testing.T) {
+	// No configs or data under NUGS_HOME, so the jobs fail without touching tootie
+	t.Setenv(paths.HomeEnv, t.TempDir())
+	router, jobManager := setupDownloadTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/downloads/fetch-new", bytes.NewBufferString(`{"limit": -1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/downloads/scan", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	jobID, _ := response["job_id"].(string)
+	require.NotEmpty(t, jobID)
+
+	job, ok := jobManager.GetJob(jobID)
+	require.True(t, ok)
+	assert.Equal(t, models.JobTypeShowScan, job.Type)
+	assert.Eventually(t, func() bool {
+		job, _ := jobManager.GetJob(jobID)
+		return job.Status == models.JobStatusFailed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Only one scan or fetch runs at a time
+	running := jobManager.CreateJob(models.JobTypeFetchNew)
+	jobManager.UpdateJob(running.ID, func(j *models.Job) { j.Status = models.JobStatusRunning })
+
+	for _, path := range []string{"/downloads/scan", "/downloads/fetch-new"} {
+		req = httptest.NewRequest(http.MethodPost, path, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code, path)
+	}
+}
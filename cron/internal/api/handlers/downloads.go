@@ -2,6 +2,10 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -14,6 +18,7 @@ import (
 
 type DownloadHandler struct {
 	DownloadManager *services.DownloadManager
+	SyncService     *services.SyncService
 	DB              *sql.DB
 }
 
@@ -22,20 +27,44 @@ func NewDownloadHandler(db *sql.DB, jobManager *models.JobManager) *DownloadHand
 
 	return &DownloadHandler{
 		DownloadManager: downloadManager,
+		SyncService:     services.NewSyncService(db, jobManager),
 		DB:              db,
 	}
 }
 
+// defaultOwnerID is the seeded admin account, which owned every monitor and
+// download before records were scoped per user
+const defaultOwnerID = 1
+
+// ownerID returns the user that new monitors and downloads belong to
+func ownerID(c *gin.Context) int {
+	if userID := c.GetInt("user_id"); userID > 0 {
+		return userID
+	}
+	return defaultOwnerID
+}
+
+// ownerFilter returns the user that list and get queries are limited to, and
+// false when the caller may see every user's records. Admins see everything,
+// as do API keys, which only admins can issue and which carry no user.
+func ownerFilter(c *gin.Context) (int, bool) {
+	if c.GetString("role") == string(models.UserRoleAdmin) {
+		return 0, false
+	}
+
+	userID := c.GetInt("user_id")
+	if userID == 0 {
+		return 0, false
+	}
+	return userID, true
+}
+
 // GET /api/v1/downloads
 func (h *DownloadHandler) GetDownloads(c *gin.Context) {
 	// Parse pagination and filters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	artistID := c.Query("artist_id")
@@ -46,6 +75,11 @@ func (h *DownloadHandler) GetDownloads(c *gin.Context) {
 	whereClause := "WHERE 1=1"
 	args := []interface{}{}
 
+	if userID, scoped := ownerFilter(c); scoped {
+		whereClause += " AND d.user_id = ?"
+		args = append(args, userID)
+	}
+
 	if artistID != "" {
 		whereClause += " AND s.artist_id = ?"
 		args = append(args, artistID)
@@ -69,9 +103,9 @@ func (h *DownloadHandler) GetDownloads(c *gin.Context) {
 		JOIN artists a ON s.artist_id = a.id ` + whereClause
 
 	var total int64
-	err := h.DB.QueryRow(countQuery, args...).Scan(&total)
+	err := h.DB.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count downloads"})
+		queryFailed(c, err, "Failed to count downloads")
 		return
 	}
 
@@ -90,9 +124,9 @@ func (h *DownloadHandler) GetDownloads(c *gin.Context) {
 
 	args = append(args, pageSize, offset)
 
-	rows, err := h.DB.Query(query, args...)
+	rows, err := h.DB.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query downloads"})
+		queryFailed(c, err, "Failed to query downloads")
 		return
 	}
 	defer rows.Close()
@@ -134,6 +168,10 @@ func (h *DownloadHandler) GetDownloads(c *gin.Context) {
 
 		downloads = append(downloads, download)
 	}
+	if err := rows.Err(); err != nil {
+		queryFailed(c, err, "Failed to query downloads")
+		return
+	}
 
 	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
 
@@ -159,6 +197,7 @@ type QueueDownloadRequest struct {
 	Format      string `json:"format" binding:"required"`
 	Quality     string `json:"quality"`
 	Priority    int    `json:"priority"`
+	Tracks      []int  `json:"tracks"` // Optional setlist track numbers for a partial download
 }
 
 // POST /api/v1/downloads/queue
@@ -200,6 +239,15 @@ func (h *DownloadHandler) QueueDownload(c *gin.Context) {
 		return
 	}
 
+	for _, track := range req.Tracks {
+		if track < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Track numbers must be positive",
+			})
+			return
+		}
+	}
+
 	qualityStr := "standard" // Default
 	switch strings.ToLower(req.Quality) {
 	case "16bit/44.1khz", "lossless", "flac":
@@ -216,9 +264,10 @@ func (h *DownloadHandler) QueueDownload(c *gin.Context) {
 		Format:   models.DownloadFormat(formatStr),
 		Quality:  models.DownloadQuality(qualityStr),
 		Priority: req.Priority,
+		Tracks:   req.Tracks,
 	}
 
-	response, err := h.DownloadManager.QueueDownload(standardReq)
+	response, err := h.DownloadManager.QueueDownload(standardReq, ownerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -247,20 +296,27 @@ func (h *DownloadHandler) GetDownload(c *gin.Context) {
 
 	query := `
 		SELECT d.id, d.show_id, d.container_id, d.artist_name, d.file_path,
-		       d.file_size, d.quality, d.format, d.status, d.downloaded_at, d.created_at,
+		       d.file_size, d.quality, d.format, d.status, d.downloaded_at, d.created_at, d.tracks,
 		       s.container_info, s.venue_name, s.venue_city, s.venue_state, s.performance_date_formatted
 		FROM downloads d
 		JOIN shows s ON d.show_id = s.id
 		WHERE d.id = ?
 	`
+	args := []interface{}{downloadID}
+
+	// Other users' downloads are reported as missing rather than forbidden
+	if userID, scoped := ownerFilter(c); scoped {
+		query += " AND d.user_id = ?"
+		args = append(args, userID)
+	}
 
 	var download models.Download
-	var filePath, downloadedAt sql.NullString
+	var filePath, downloadedAt, tracks sql.NullString
 
-	err = h.DB.QueryRow(query, downloadID).Scan(
+	err = h.DB.QueryRow(query, args...).Scan(
 		&download.ID, &download.ShowID, &download.ContainerID, &download.ArtistName,
 		&filePath, &download.FileSize, &download.Quality, &download.Format,
-		&download.Status, &downloadedAt, &download.CreatedAt,
+		&download.Status, &downloadedAt, &download.CreatedAt, &tracks,
 		&download.ShowTitle, &download.VenueName, &download.VenueCity,
 		&download.VenueState, &download.PerformanceDate,
 	)
@@ -279,6 +335,10 @@ func (h *DownloadHandler) GetDownload(c *gin.Context) {
 		download.FilePath = filePath
 	}
 
+	if tracks.Valid {
+		json.Unmarshal([]byte(tracks.String), &download.Tracks)
+	}
+
 	if downloadedAt.Valid {
 		if t, err := time.Parse("2006-01-02 15:04:05", downloadedAt.String); err == nil {
 			download.DownloadedAt = &t
@@ -296,9 +356,17 @@ func (h *DownloadHandler) CancelDownload(c *gin.Context) {
 		return
 	}
 
-	// Check if download exists and get its status
+	// Check if download exists and get its status. Other users' downloads are
+	// reported as missing rather than forbidden.
+	query := "SELECT status FROM downloads WHERE id = ?"
+	args := []interface{}{downloadID}
+	if userID, scoped := ownerFilter(c); scoped {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
+
 	var status string
-	err = h.DB.QueryRow("SELECT status FROM downloads WHERE id = ?", downloadID).Scan(&status)
+	err = h.DB.QueryRow(query, args...).Scan(&status)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Download not found"})
@@ -338,6 +406,92 @@ func (h *DownloadHandler) GetDownloadStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GET /api/v1/downloads/duplicates
+func (h *DownloadHandler) GetDuplicates(c *gin.Context) {
+	userID, scoped := ownerFilter(c)
+
+	report, err := h.DownloadManager.FindDuplicates(userID, scoped)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicate downloads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /api/v1/downloads/duplicates/cleanup
+func (h *DownloadHandler) CleanupDuplicates(c *gin.Context) {
+	var req models.DuplicateCleanupRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	userID, scoped := ownerFilter(c)
+
+	result, err := h.DownloadManager.RemoveDuplicates(&req, userID, scoped)
+	if errors.Is(err, services.ErrMaintenanceBlocked) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clean up duplicate downloads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// POST /api/v1/downloads/scan
+func (h *DownloadHandler) ScanShows(c *gin.Context) {
+	job, err := h.SyncService.StartScan()
+	if errors.Is(err, services.ErrPipelineBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start show scan: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"job_id":  job.ID,
+		"message": "Show scan started",
+		"status":  job.Status,
+	})
+}
+
+// POST /api/v1/downloads/fetch-new
+func (h *DownloadHandler) FetchNewShows(c *gin.Context) {
+	var req models.FetchNewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if req.Limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be 0 or more"})
+		return
+	}
+
+	job, err := h.SyncService.StartFetchNew(req.Limit)
+	if errors.Is(err, services.ErrPipelineBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start fetching new shows: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"job_id":  job.ID,
+		"message": "Fetching new shows started",
+		"status":  job.Status,
+	})
+}
+
 // GET /api/v1/downloads/queue
 func (h *DownloadHandler) GetDownloadQueue(c *gin.Context) {
 	query := `
@@ -347,12 +501,18 @@ func (h *DownloadHandler) GetDownloadQueue(c *gin.Context) {
 		FROM downloads d
 		JOIN shows s ON d.show_id = s.id
 		WHERE d.status IN ('pending', 'queued') AND d.queue_position IS NOT NULL
-		ORDER BY d.queue_position ASC
 	`
+	args := []interface{}{}
+
+	if userID, scoped := ownerFilter(c); scoped {
+		query += " AND d.user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY d.queue_position ASC"
 
-	rows, err := h.DB.Query(query)
+	rows, err := h.DB.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get download queue"})
+		queryFailed(c, err, "Failed to get download queue")
 		return
 	}
 	defer rows.Close()
@@ -395,6 +555,10 @@ func (h *DownloadHandler) GetDownloadQueue(c *gin.Context) {
 			"created_at":       createdAt,
 		})
 	}
+	if err := rows.Err(); err != nil {
+		queryFailed(c, err, "Failed to get download queue")
+		return
+	}
 
 	// Count items by status
 	pendingItems := 0
@@ -456,6 +620,22 @@ func (h *DownloadHandler) ReorderQueue(c *gin.Context) {
 	}
 	defer tx.Rollback()
 
+	// Users may only reorder their own downloads; anyone else's is reported as missing
+	if userID, scoped := ownerFilter(c); scoped {
+		for _, downloadID := range downloadIDs {
+			var owned int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM downloads WHERE id = ? AND user_id = ?`, downloadID, userID).Scan(&owned)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder queue"})
+				return
+			}
+			if owned == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Download not found: %d", downloadID)})
+				return
+			}
+		}
+	}
+
 	// Update positions
 	for i, downloadID := range downloadIDs {
 		_, err := tx.Exec(`
@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmagar/nugs/cron/internal/models"
@@ -35,7 +34,7 @@ func (h *MonitoringHandler) CreateMonitor(c *gin.Context) {
 		return
 	}
 
-	response, err := h.MonitoringService.CreateMonitor(&req)
+	response, err := h.MonitoringService.CreateMonitor(&req, ownerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create monitor"})
 		return
@@ -59,7 +58,7 @@ func (h *MonitoringHandler) CreateBulkMonitors(c *gin.Context) {
 		return
 	}
 
-	response, err := h.MonitoringService.CreateBulkMonitors(&req)
+	response, err := h.MonitoringService.CreateBulkMonitors(&req, ownerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bulk monitors"})
 		return
@@ -71,13 +70,9 @@ func (h *MonitoringHandler) CreateBulkMonitors(c *gin.Context) {
 // GET /api/v1/monitoring/monitors
 func (h *MonitoringHandler) GetMonitors(c *gin.Context) {
 	// Parse pagination and filters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	status := c.Query("status")
@@ -87,6 +82,11 @@ func (h *MonitoringHandler) GetMonitors(c *gin.Context) {
 	whereClause := "WHERE 1=1"
 	args := []interface{}{}
 
+	if userID, scoped := ownerFilter(c); scoped {
+		whereClause += " AND m.user_id = ?"
+		args = append(args, userID)
+	}
+
 	if status != "" {
 		whereClause += " AND m.status = ?"
 		args = append(args, status)
@@ -104,9 +104,9 @@ func (h *MonitoringHandler) GetMonitors(c *gin.Context) {
 		JOIN artists a ON m.artist_id = a.id 
 	` + whereClause
 	var total int64
-	err := h.DB.QueryRow(countQuery, args...).Scan(&total)
+	err := h.DB.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count monitors"})
+		queryFailed(c, err, "Failed to count monitors")
 		return
 	}
 
@@ -123,9 +123,9 @@ func (h *MonitoringHandler) GetMonitors(c *gin.Context) {
 
 	args = append(args, pageSize, offset)
 
-	rows, err := h.DB.Query(query, args...)
+	rows, err := h.DB.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query monitors"})
+		queryFailed(c, err, "Failed to query monitors")
 		return
 	}
 	defer rows.Close()
@@ -163,6 +163,10 @@ func (h *MonitoringHandler) GetMonitors(c *gin.Context) {
 
 		monitors = append(monitors, monitor)
 	}
+	if err := rows.Err(); err != nil {
+		queryFailed(c, err, "Failed to query monitors")
+		return
+	}
 
 	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
 
@@ -190,21 +194,33 @@ func (h *MonitoringHandler) GetMonitor(c *gin.Context) {
 	}
 
 	query := `
-		SELECT id, artist_id, artist_name, status, check_interval, last_checked,
-		       last_new_show, total_shows, new_shows_found, notify_new_shows,
-		       notify_show_updates, created_at, updated_at
-		FROM artist_monitors 
-		WHERE id = ?
+		SELECT m.id, m.artist_id, a.name, m.status,
+		       COALESCE(json_extract(m.settings, '$.check_interval'), 60), m.last_check,
+		       (SELECT COUNT(*) FROM shows s WHERE s.artist_id = m.artist_id), m.shows_found,
+		       COALESCE(json_extract(m.settings, '$.notify_new_shows'), 0),
+		       COALESCE(json_extract(m.settings, '$.notify_show_updates'), 0),
+		       m.completion_goal, m.created_at, m.updated_at
+		FROM monitors m
+		JOIN artists a ON m.artist_id = a.id
+		WHERE m.id = ?
 	`
+	args := []interface{}{monitorID}
+
+	// Other users' monitors are reported as missing rather than forbidden
+	if userID, scoped := ownerFilter(c); scoped {
+		query += " AND m.user_id = ?"
+		args = append(args, userID)
+	}
 
 	var monitor models.ArtistMonitor
-	var lastChecked, lastNewShow sql.NullString
+	var lastChecked sql.NullTime
+	var completionGoal sql.NullFloat64
 
-	err = h.DB.QueryRow(query, monitorID).Scan(
+	err = h.DB.QueryRow(query, args...).Scan(
 		&monitor.ID, &monitor.ArtistID, &monitor.ArtistName, &monitor.Status,
-		&monitor.CheckInterval, &lastChecked, &lastNewShow, &monitor.TotalShows,
+		&monitor.CheckInterval, &lastChecked, &monitor.TotalShows,
 		&monitor.NewShowsFound, &monitor.NotifyNewShows, &monitor.NotifyShowUpdates,
-		&monitor.CreatedAt, &monitor.UpdatedAt,
+		&completionGoal, &monitor.CreatedAt, &monitor.UpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -218,15 +234,15 @@ func (h *MonitoringHandler) GetMonitor(c *gin.Context) {
 	}
 
 	if lastChecked.Valid {
-		if t, err := time.Parse("2006-01-02 15:04:05", lastChecked.String); err == nil {
-			monitor.LastChecked = &t
-		}
+		monitor.LastChecked = &lastChecked.Time
 	}
 
-	if lastNewShow.Valid {
-		if t, err := time.Parse("2006-01-02 15:04:05", lastNewShow.String); err == nil {
-			monitor.LastNewShow = &t
-		}
+	// Goal vs. actual completion
+	if completionGoal.Valid {
+		monitor.CompletionGoal = &completionGoal.Float64
+	}
+	if completion, err := h.MonitoringService.GetArtistCompletion(monitor.ArtistID, monitor.CompletionGoal); err == nil {
+		monitor.Completion = completion
 	}
 
 	c.JSON(http.StatusOK, monitor)
@@ -248,7 +264,8 @@ func (h *MonitoringHandler) UpdateMonitor(c *gin.Context) {
 		return
 	}
 
-	err = h.MonitoringService.UpdateMonitor(monitorID, &req)
+	userID, scoped := ownerFilter(c)
+	err = h.MonitoringService.UpdateMonitor(monitorID, &req, userID, scoped)
 	if err != nil {
 		if err.Error() == "monitor not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
@@ -272,7 +289,8 @@ func (h *MonitoringHandler) DeleteMonitor(c *gin.Context) {
 		return
 	}
 
-	err = h.MonitoringService.DeleteMonitor(monitorID)
+	userID, scoped := ownerFilter(c)
+	err = h.MonitoringService.DeleteMonitor(monitorID, userID, scoped)
 	if err != nil {
 		if err.Error() == "monitor not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Monitor not found"})
@@ -320,13 +338,9 @@ func (h *MonitoringHandler) CheckArtist(c *gin.Context) {
 // GET /api/v1/monitoring/alerts
 func (h *MonitoringHandler) GetAlerts(c *gin.Context) {
 	// Parse pagination and filters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	alertType := c.Query("alert_type")
@@ -359,9 +373,9 @@ func (h *MonitoringHandler) GetAlerts(c *gin.Context) {
 		JOIN monitors m ON ma.monitor_id = m.id ` + whereClause
 
 	var total int64
-	err := h.DB.QueryRow(countQuery, args...).Scan(&total)
+	err := h.DB.QueryRowContext(c.Request.Context(), countQuery, args...).Scan(&total)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count alerts"})
+		queryFailed(c, err, "Failed to count alerts")
 		return
 	}
 
@@ -381,9 +395,9 @@ func (h *MonitoringHandler) GetAlerts(c *gin.Context) {
 
 	args = append(args, pageSize, offset)
 
-	rows, err := h.DB.Query(query, args...)
+	rows, err := h.DB.QueryContext(c.Request.Context(), query, args...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query alerts"})
+		queryFailed(c, err, "Failed to query alerts")
 		return
 	}
 	defer rows.Close()
@@ -420,6 +434,10 @@ func (h *MonitoringHandler) GetAlerts(c *gin.Context) {
 
 		alerts = append(alerts, alert)
 	}
+	if err := rows.Err(); err != nil {
+		queryFailed(c, err, "Failed to query alerts")
+		return
+	}
 
 	totalPages := int((total + int64(pageSize) - 1) / int64(pageSize))
 
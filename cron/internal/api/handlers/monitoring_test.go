@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -77,6 +78,15 @@ func TestMonitoringHandler_CreateMonitor(t *testing.T) {
 			expectedStatus: http.StatusCreated,
 			checkFields:    []string{"monitor_id", "message"},
 		},
+		{
+			name: "completion goal out of range",
+			requestBody: map[string]interface{}{
+				"artist_id":       3,
+				"completion_goal": 150,
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkFields:    []string{"error"},
+		},
 		{
 			name: "missing artist_id",
 			requestBody: map[string]interface{}{
@@ -165,6 +175,152 @@ func TestMonitoringHandler_GetMonitors(t *testing.T) {
 	}
 }
 
+func TestMonitoringHandler_OwnerScoping(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	alice := createTestUser(t, db, "alice", "alice@example.com", "user")
+	bob := createTestUser(t, db, "bob", "bob@example.com", "user")
+
+	_, err := db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings'), (102, 'Goose', 'goose')`)
+	require.NoError(t, err)
+
+	newRouter := func(userID int64, role string) *gin.Engine {
+		router := gin.New()
+		router.Use(asUser(userID, role))
+		monitoringHandler := NewMonitoringHandler(db, models.NewJobManager())
+		router.POST("/monitoring/monitors", monitoringHandler.CreateMonitor)
+		router.GET("/monitoring/monitors", monitoringHandler.GetMonitors)
+		return router
+	}
+
+	// Both users monitor artist 101; only alice monitors artist 102
+	for _, create := range []struct {
+		userID   int64
+		artistID int
+	}{{alice, 101}, {alice, 102}, {bob, 101}} {
+		body, _ := json.Marshal(map[string]interface{}{"artist_id": create.artistID})
+		req := httptest.NewRequest(http.MethodPost, "/monitoring/monitors", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		newRouter(create.userID, "user").ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name          string
+		userID        int64
+		role          string
+		expectedTotal float64
+	}{
+		{name: "user sees own monitors", userID: alice, role: "user", expectedTotal: 2},
+		{name: "other user sees only theirs", userID: bob, role: "user", expectedTotal: 1},
+		{name: "admin sees all monitors", userID: 1, role: "admin", expectedTotal: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/monitoring/monitors", nil)
+			w := httptest.NewRecorder()
+			newRouter(tt.userID, tt.role).ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			pagination := response["pagination"].(map[string]interface{})
+			assert.Equal(t, tt.expectedTotal, pagination["total"])
+		})
+	}
+}
+
+func TestMonitoringHandler_CrossUserWrites(t *testing.T) {
+	db := setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	alice := createTestUser(t, db, "alice", "alice@example.com", "user")
+	bob := createTestUser(t, db, "bob", "bob@example.com", "user")
+
+	newRouter := func(userID int64, role string) *gin.Engine {
+		router := gin.New()
+		router.Use(asUser(userID, role))
+		monitoringHandler := NewMonitoringHandler(db, models.NewJobManager())
+		router.POST("/monitoring/monitors", monitoringHandler.CreateMonitor)
+		router.PUT("/monitoring/monitors/:id", monitoringHandler.UpdateMonitor)
+		router.DELETE("/monitoring/monitors/:id", monitoringHandler.DeleteMonitor)
+		return router
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"artist_id": 2})
+	req := httptest.NewRequest(http.MethodPost, "/monitoring/monitors", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	newRouter(alice, "user").ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	path := fmt.Sprintf("/monitoring/monitors/%v", created["monitor_id"])
+
+	send := func(method string, userID int64, role string) int {
+		body, _ := json.Marshal(map[string]interface{}{"status": "paused", "check_interval": 30})
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		newRouter(userID, role).ServeHTTP(w, req)
+		return w.Code
+	}
+	monitor := func() (status string, checkInterval int, found bool) {
+		err := db.QueryRow(`SELECT status, json_extract(settings, '$.check_interval') FROM monitors WHERE id = ?`,
+			created["monitor_id"]).Scan(&status, &checkInterval)
+		return status, checkInterval, err == nil
+	}
+
+	// Another user can neither change nor delete the monitor
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPut, bob, "user"))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, bob, "user"))
+	status, checkInterval, found := monitor()
+	require.True(t, found)
+	assert.Equal(t, "active", status)
+	assert.Equal(t, 60, checkInterval)
+
+	// The owner can change it and an admin can delete it
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, alice, "user"))
+	status, checkInterval, _ = monitor()
+	assert.Equal(t, "paused", status)
+	assert.Equal(t, 30, checkInterval)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, 1, "admin"))
+	_, _, found = monitor()
+	assert.False(t, found)
+}
+
+func TestMonitoringHandler_GetMonitor_CompletionGoal(t *testing.T) {
+	router, _ := setupMonitoringTestRouter(t)
+
+	body, _ := json.Marshal(map[string]interface{}{"artist_id": 2, "completion_goal": 75})
+	req := httptest.NewRequest(http.MethodPost, "/monitoring/monitors", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/monitoring/monitors/%v", created["monitor_id"]), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var monitor map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &monitor))
+	assert.Equal(t, "Phish", monitor["artist_name"])
+	assert.Equal(t, 75.0, monitor["completion_goal"])
+	assert.Equal(t, 60.0, monitor["check_interval"])
+	completion := monitor["completion"].(map[string]interface{})
+	assert.Equal(t, false, completion["meets_goal"])
+}
+
 func TestMonitoringHandler_CheckAllMonitors(t *testing.T) {
 	router, _ := setupMonitoringTestRouter(t)
 
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -17,8 +18,10 @@ type RefreshHandler struct {
 }
 
 type RefreshRequest struct {
-	Force      bool `json:"force"`
-	Background bool `json:"background"`
+	Force      bool  `json:"force"` // refresh even if the catalog was refreshed in the last 4 hours; full refreshes only
+	Background bool  `json:"background"`
+	ArtistIDs  []int `json:"artist_ids,omitempty"` // nugs.net artist IDs; refreshes only these artists when set
+	Resume     bool  `json:"resume"`               // resume the last cancelled or interrupted artist refresh
 }
 
 type RefreshResponse struct {
@@ -27,6 +30,7 @@ type RefreshResponse struct {
 	Status           string `json:"status"`
 	Message          string `json:"message"`
 	EstimatedSeconds int    `json:"estimated_time_seconds"`
+	Force            bool   `json:"force,omitempty"`
 	Error            string `json:"error,omitempty"`
 }
 
@@ -53,11 +57,70 @@ func NewRefreshHandler(db *sql.DB, jobManager *models.JobManager) *RefreshHandle
 
 // POST /api/v1/catalog/refresh
 func (h *RefreshHandler) StartRefresh(c *gin.Context) {
+	// No body starts an unforced full refresh
 	var req RefreshRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
 		c.JSON(http.StatusBadRequest, RefreshResponse{
 			Success: false,
-			Error:   "Invalid request format",
+			Error:   "Invalid request format: " + err.Error(),
+		})
+		return
+	}
+
+	// Artist and resumed refreshes always fetch, so forcing them means nothing
+	if req.Force && (req.Resume || len(req.ArtistIDs) > 0) {
+		c.JSON(http.StatusBadRequest, RefreshResponse{
+			Success: false,
+			Error:   "force applies only to a full catalog refresh, not with artist_ids or resume",
+		})
+		return
+	}
+
+	if req.Resume {
+		job, err := h.RefreshService.ResumeArtistRefresh()
+		if err == services.ErrNoRefreshCheckpoint {
+			c.JSON(http.StatusNotFound, RefreshResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, RefreshResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, RefreshResponse{
+			Success: true,
+			JobID:   job.ID,
+			Status:  string(job.Status),
+			Message: "Artist refresh resumed",
+		})
+		return
+	}
+
+	if len(req.ArtistIDs) > 0 {
+		for _, artistID := range req.ArtistIDs {
+			if artistID < 1 {
+				c.JSON(http.StatusBadRequest, RefreshResponse{
+					Success: false,
+					Error:   "Invalid artist ID: " + strconv.Itoa(artistID),
+				})
+				return
+			}
+		}
+
+		job := h.RefreshService.StartArtistRefresh(req.ArtistIDs)
+
+		c.JSON(http.StatusAccepted, RefreshResponse{
+			Success:          true,
+			JobID:            job.ID,
+			Status:           string(job.Status),
+			Message:          "Artist refresh initiated",
+			EstimatedSeconds: len(req.ArtistIDs) * 2,
 		})
 		return
 	}
@@ -71,6 +134,10 @@ func (h *RefreshHandler) StartRefresh(c *gin.Context) {
 		Status:           string(job.Status),
 		Message:          "Catalog refresh initiated",
 		EstimatedSeconds: 300, // 5 minutes estimate
+		Force:            req.Force,
+	}
+	if req.Force {
+		response.Message = "Forced catalog refresh initiated"
 	}
 
 	c.JSON(http.StatusAccepted, response)
@@ -115,10 +182,9 @@ func (h *RefreshHandler) GetRefreshStatus(c *gin.Context) {
 // GET /api/v1/catalog/refresh/jobs
 func (h *RefreshHandler) ListRefreshJobs(c *gin.Context) {
 	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit > 100 {
-		limit = 10
+	limit, ok := parseLimit(c, h.RefreshService.DB, "limit", 10)
+	if !ok {
+		return
 	}
 
 	statusFilter := c.Query("status")
@@ -252,6 +318,11 @@ func (h *RefreshHandler) GetRefreshInfo(c *gin.Context) {
 	info["refresh_in_progress"] = runningJobs > 0
 	info["running_jobs"] = runningJobs
 
+	info["resumable_refresh"] = nil
+	if checkpoint, err := h.RefreshService.GetRefreshCheckpoint(); err == nil {
+		info["resumable_refresh"] = checkpoint
+	}
+
 	// Add missing fields that tests expect
 	info["next_scheduled_refresh"] = nil // Would be calculated from schedule
 	info["refresh_frequency"] = "daily"  // Default from config
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os/exec"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmagar/nugs/cron/internal/models"
@@ -65,6 +68,30 @@ func TestRefreshHandler_StartRefresh(t *testing.T) {
 			expectedStatus: http.StatusAccepted,
 			checkFields:    []string{"job_id", "message"},
 		},
+		{
+			name: "resume without an interrupted refresh",
+			requestBody: map[string]interface{}{
+				"resume": true,
+			},
+			expectedStatus: http.StatusNotFound,
+			checkFields:    []string{"error"},
+		},
+		{
+			name: "start artist refresh",
+			requestBody: map[string]interface{}{
+				"artist_ids": []int{461, 1125},
+			},
+			expectedStatus: http.StatusAccepted,
+			checkFields:    []string{"job_id", "message"},
+		},
+		{
+			name: "start artist refresh with invalid artist id",
+			requestBody: map[string]interface{}{
+				"artist_ids": []int{461, 0},
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkFields:    []string{"error"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -231,3 +258,123 @@ func TestRefreshHandler_GetRefreshInfo(t *testing.T) {
 		assert.Contains(t, response, field)
 	}
 }
+
+func TestRefreshHandler_StartRefresh_Force(t *testing.T) {
+	db := setupTestDB(t)
+	jobManager := models.NewJobManager()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	refreshHandler := NewRefreshHandler(db, jobManager)
+	router.POST("/catalog/refresh", refreshHandler.StartRefresh)
+
+	var fetches [][]string
+	var mu sync.Mutex
+	refreshHandler.RefreshService.CatalogManager = func(args ...string) *exec.Cmd {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches = append(fetches, args)
+		return exec.Command("true")
+	}
+
+	// The catalog was refreshed a minute ago
+	_, err := db.Exec(`INSERT OR REPLACE INTO system_config (key, value, description) VALUES ('last_catalog_refresh', ?, 'last refresh')`,
+		time.Now().Add(-time.Minute).Format(time.RFC3339))
+	require.NoError(t, err)
+
+	start := func(body string) (int, RefreshResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/catalog/refresh", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response RefreshResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+	finished := func(jobID string) *models.Job {
+		var job *models.Job
+		require.Eventually(t, func() bool {
+			job, _ = jobManager.GetJob(jobID)
+			return job != nil && job.CompletedAt != nil
+		}, 5*time.Second, 10*time.Millisecond)
+		return job
+	}
+
+	// Unforced, and with no body at all, the recent refresh is kept
+	for _, body := range []string{`{"force": false}`, ``} {
+		code, response := start(body)
+		require.Equal(t, http.StatusAccepted, code)
+		assert.False(t, response.Force)
+		job := finished(response.JobID)
+		assert.Equal(t, models.JobStatusCompleted, job.Status)
+		assert.Equal(t, "Catalog is already up to date", job.Message)
+	}
+
+	mu.Lock()
+	assert.Empty(t, fetches)
+	mu.Unlock()
+
+	// Forced, the catalog is fetched again
+	code, response := start(`{"force": true}`)
+	require.Equal(t, http.StatusAccepted, code)
+	assert.True(t, response.Force)
+	job := finished(response.JobID)
+	assert.Equal(t, models.JobStatusCompleted, job.Status)
+	assert.NotEqual(t, "Catalog is already up to date", job.Message)
+	mu.Lock()
+	assert.Equal(t, [][]string{{"refresh"}}, fetches)
+	mu.Unlock()
+
+	for _, body := range []string{
+		`{"force": "yes"}`,
+		`{"force": true, "artist_ids": [461]}`,
+		`{"force": true, "resume": true}`,
+	} {
+		code, response := start(body)
+		assert.Equal(t, http.StatusBadRequest, code, body)
+		assert.NotEmpty(t, response.Error, body)
+	}
+}
+
+func TestRefreshHandler_ResumeRefresh(t *testing.T) {
+	db := setupTestDB(t)
+	jobManager := models.NewJobManager()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	refreshHandler := NewRefreshHandler(db, jobManager)
+	router.POST("/catalog/refresh", refreshHandler.StartRefresh)
+	router.GET("/catalog/refresh/info", refreshHandler.GetRefreshInfo)
+
+	_, err := db.Exec(`
+		INSERT INTO system_config (key, value, description)
+		VALUES ('catalog_refresh_checkpoint', '{"job_id":"job_1","pending_artist_ids":[461,1125],"total_artists":5}', 'checkpoint')
+	`)
+	require.NoError(t, err)
+
+	// The checkpoint is reported as resumable
+	req := httptest.NewRequest(http.MethodGet, "/catalog/refresh/info", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var info map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	resumable, ok := info["resumable_refresh"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, []interface{}{float64(461), float64(1125)}, resumable["pending_artist_ids"])
+
+	// Resuming starts a job for the pending artists
+	body, _ := json.Marshal(map[string]interface{}{"resume": true})
+	req = httptest.NewRequest(http.MethodPost, "/catalog/refresh", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	_, exists := jobManager.GetJob(response["job_id"].(string))
+	assert.True(t, exists)
+}
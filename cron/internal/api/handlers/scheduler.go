@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -72,6 +73,28 @@ func (h *SchedulerHandler) GetSchedulerStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
+// GET /api/v1/scheduler/upcoming
+func (h *SchedulerHandler) GetUpcomingRuns(c *gin.Context) {
+	count, err := strconv.Atoi(c.DefaultQuery("count", "10"))
+	if err != nil || count < 1 || count > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 100"})
+		return
+	}
+
+	runs, err := h.SchedulerService.UpcomingRuns(count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to get upcoming runs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"upcoming": runs,
+		"count":    len(runs),
+	})
+}
+
 // GET /api/v1/scheduler/stats
 func (h *SchedulerHandler) GetSchedulerStats(c *gin.Context) {
 	stats, err := h.SchedulerService.GetStats()
@@ -115,13 +138,9 @@ func (h *SchedulerHandler) CreateSchedule(c *gin.Context) {
 // GET /api/v1/scheduler/schedules
 func (h *SchedulerHandler) GetSchedules(c *gin.Context) {
 	// Parse pagination and filters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	status := c.Query("status")
@@ -345,6 +364,8 @@ func (h *SchedulerHandler) UpdateSchedule(c *gin.Context) {
 	if err != nil {
 		if err.Error() == "schedule not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		} else if errors.Is(err, services.ErrInvalidScheduleType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "valid_types": models.ScheduleTypes})
 		} else {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		}
@@ -390,13 +411,9 @@ func (h *SchedulerHandler) GetScheduleExecutions(c *gin.Context) {
 	}
 
 	// Parse pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	status := c.Query("status")
@@ -575,13 +592,9 @@ func (h *SchedulerHandler) BulkScheduleOperation(c *gin.Context) {
 // GET /api/v1/scheduler/executions
 func (h *SchedulerHandler) GetAllExecutions(c *gin.Context) {
 	// Parse pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	status := c.Query("status")
@@ -28,6 +28,7 @@ func setupSchedulerTestRouter(t *testing.T) (*gin.Engine, *models.JobManager) {
 		scheduler.POST("/stop", schedulerHandler.StopScheduler)
 		scheduler.GET("/status", schedulerHandler.GetSchedulerStatus)
 		scheduler.GET("/stats", schedulerHandler.GetSchedulerStats)
+		scheduler.GET("/upcoming", schedulerHandler.GetUpcomingRuns)
 		scheduler.POST("/schedules", schedulerHandler.CreateSchedule)
 		scheduler.GET("/schedules", schedulerHandler.GetSchedules)
 		scheduler.GET("/schedules/:id", schedulerHandler.GetSchedule)
@@ -100,6 +101,18 @@ func TestSchedulerHandler_GetSchedulerStatus(t *testing.T) {
 	}
 }
 
+func TestSchedulerHandler_GetUpcomingRuns_InvalidCount(t *testing.T) {
+	router, _ := setupSchedulerTestRouter(t)
+
+	for _, count := range []string{"0", "101", "-5", "soon"} {
+		req := httptest.NewRequest(http.MethodGet, "/scheduler/upcoming?count="+count, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, count)
+	}
+}
+
 func TestSchedulerHandler_CreateSchedule(t *testing.T) {
 	router, _ := setupSchedulerTestRouter(t)
 
@@ -178,6 +191,40 @@ func TestSchedulerHandler_CreateSchedule(t *testing.T) {
 	}
 }
 
+func TestSchedulerHandler_InvalidScheduleType(t *testing.T) {
+	router, _ := setupSchedulerTestRouter(t)
+
+	for _, tt := range []struct {
+		method, path string
+		body         map[string]interface{}
+	}{
+		{http.MethodPost, "/scheduler/schedules", map[string]interface{}{
+			"name": "Typo", "type": "catalog_refesh", "cron_expr": "0 * * * *",
+		}},
+		{http.MethodPost, "/scheduler/schedules", map[string]interface{}{
+			"name": "Custom", "type": "custom", "cron_expr": "0 * * * *",
+		}},
+		{http.MethodPut, "/scheduler/schedules/1", map[string]interface{}{"type": "backup"}},
+	} {
+		body, _ := json.Marshal(tt.body)
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, tt.body)
+		var response struct {
+			Error      string   `json:"error"`
+			ValidTypes []string `json:"valid_types"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Contains(t, response.Error, "unsupported schedule type")
+		assert.ElementsMatch(t, []string{
+			"catalog_refresh", "monitor_check", "system_cleanup", "database_backup", "health_check",
+		}, response.ValidTypes)
+	}
+}
+
 func TestSchedulerHandler_GetSchedules(t *testing.T) {
 	router, _ := setupSchedulerTestRouter(t)
 
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"database/sql"
+	"fmt"
+	"sync/atomic"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -10,10 +12,17 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// setupTestDB initializes an in-memory database for testing
+// testDBSeq names each test database so tests never share one
+var testDBSeq int64
+
+// setupTestDB initializes an in-memory database for testing. It is shared by
+// all of the pool's connections, so jobs running in the background see the
+// same data as the test.
 func setupTestDB(t *testing.T) *sql.DB {
-	db, err := database.Initialize(":memory:")
+	name := fmt.Sprintf("file:handlers_%d?mode=memory&cache=shared", atomic.AddInt64(&testDBSeq, 1))
+	db, err := database.Initialize(name)
 	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
 	return db
 }
 
@@ -40,3 +49,13 @@ func createTestUser(t *testing.T, db *sql.DB, username, email, role string) int6
 func setupGinTestMode() {
 	gin.SetMode(gin.TestMode)
 }
+
+// asUser returns middleware that authenticates every request as the given user,
+// standing in for JWTAuth in handler tests
+func asUser(userID int64, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", int(userID))
+		c.Set("role", role)
+		c.Next()
+	}
+}
@@ -26,6 +26,17 @@ func NewWebhookHandler(db *sql.DB, jobManager *models.JobManager) *WebhookHandle
 	}
 }
 
+// webhookStatus derives a webhook's status from the schema's active flag. An
+// inactive webhook with failed deliveries was switched off by delivery
+// failures rather than by hand.
+const webhookStatus = `CASE WHEN w.active THEN 'active' WHEN w.failed_deliveries > 0 THEN 'failed' ELSE 'disabled' END`
+
+// webhookColumns selects a webhook in the order GetWebhooks and GetWebhook scan it
+const webhookColumns = `w.id, w.name, w.url, w.events, ` + webhookStatus + `, w.secret, COALESCE(w.headers, '{}'),
+		       w.timeout_seconds, w.retry_count, w.last_triggered, w.failed_deliveries,
+		       w.created_at, w.updated_at, w.digest_window_minutes, w.digest_max_events, w.payload_template,
+		       w.total_deliveries, w.successful_deliveries`
+
 // POST /api/v1/webhooks
 func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 	var req models.WebhookRequest
@@ -36,7 +47,7 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 		return
 	}
 
-	response, err := h.WebhookService.CreateWebhook(&req)
+	response, err := h.WebhookService.CreateWebhook(&req, ownerID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
 		return
@@ -53,13 +64,9 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 // GET /api/v1/webhooks
 func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
 	// Parse pagination and filters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	status := c.Query("status")
@@ -70,17 +77,17 @@ func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
 	args := []interface{}{}
 
 	if status != "" {
-		whereClause += " AND status = ?"
+		whereClause += " AND " + webhookStatus + " = ?"
 		args = append(args, status)
 	}
 
 	if event != "" {
-		whereClause += " AND events LIKE ?"
+		whereClause += " AND w.events LIKE ?"
 		args = append(args, "%\""+event+"\"%")
 	}
 
 	// Count total
-	countQuery := "SELECT COUNT(*) FROM webhooks " + whereClause
+	countQuery := "SELECT COUNT(*) FROM webhooks w " + whereClause
 	var total int64
 	err := h.DB.QueryRow(countQuery, args...).Scan(&total)
 	if err != nil {
@@ -90,15 +97,8 @@ func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
 
 	// Get webhooks
 	offset := (page - 1) * pageSize
-	query := `
-		SELECT w.id, w.name, w.url, w.events, w.status, w.secret, w.headers, 
-		       w.timeout, w.retries, w.last_fired, w.last_status, w.failure_count,
-		       w.created_at, w.updated_at,
-		       COUNT(wd.id) as total_fired,
-		       COUNT(CASE WHEN wd.success = 1 THEN 1 END) as success_count
-		FROM webhooks w
-		LEFT JOIN webhook_deliveries wd ON w.id = wd.webhook_id ` + whereClause + `
-		GROUP BY w.id
+	query := `SELECT ` + webhookColumns + `
+		FROM webhooks w ` + whereClause + `
 		ORDER BY w.created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -116,14 +116,16 @@ func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
 	for rows.Next() {
 		var webhook models.Webhook
 		var eventsJSON, headersJSON string
-		var lastFired sql.NullString
+		var lastFired sql.NullTime
 		var secret sql.NullString
 
 		err := rows.Scan(
 			&webhook.ID, &webhook.Name, &webhook.URL, &eventsJSON, &webhook.Status,
 			&secret, &headersJSON, &webhook.Timeout, &webhook.Retries,
-			&lastFired, &webhook.LastStatus, &webhook.FailureCount,
-			&webhook.CreatedAt, &webhook.UpdatedAt, &webhook.TotalFired, &webhook.SuccessCount,
+			&lastFired, &webhook.FailureCount,
+			&webhook.CreatedAt, &webhook.UpdatedAt, &webhook.DigestWindowMinutes, &webhook.DigestMaxEvents,
+			&webhook.PayloadTemplate,
+			&webhook.TotalFired, &webhook.SuccessCount,
 		)
 
 		if err != nil {
@@ -141,11 +143,8 @@ func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
 			webhook.Secret = "***" // Mask the secret
 		}
 
-		// Parse last fired time
 		if lastFired.Valid {
-			if t, err := time.Parse("2006-01-02 15:04:05", lastFired.String); err == nil {
-				webhook.LastFired = &t
-			}
+			webhook.LastFired = &lastFired.Time
 		}
 
 		// Calculate failure rate
@@ -179,28 +178,23 @@ func (h *WebhookHandler) GetWebhook(c *gin.Context) {
 		return
 	}
 
-	query := `
-		SELECT w.id, w.name, w.url, w.events, w.status, w.secret, w.headers, 
-		       w.timeout, w.retries, w.last_fired, w.last_status, w.failure_count,
-		       w.created_at, w.updated_at,
-		       COUNT(wd.id) as total_fired,
-		       COUNT(CASE WHEN wd.success = 1 THEN 1 END) as success_count
+	query := `SELECT ` + webhookColumns + `
 		FROM webhooks w
-		LEFT JOIN webhook_deliveries wd ON w.id = wd.webhook_id
 		WHERE w.id = ?
-		GROUP BY w.id
 	`
 
 	var webhook models.Webhook
 	var eventsJSON, headersJSON string
-	var lastFired sql.NullString
+	var lastFired sql.NullTime
 	var secret sql.NullString
 
 	err = h.DB.QueryRow(query, webhookID).Scan(
 		&webhook.ID, &webhook.Name, &webhook.URL, &eventsJSON, &webhook.Status,
 		&secret, &headersJSON, &webhook.Timeout, &webhook.Retries,
-		&lastFired, &webhook.LastStatus, &webhook.FailureCount,
-		&webhook.CreatedAt, &webhook.UpdatedAt, &webhook.TotalFired, &webhook.SuccessCount,
+		&lastFired, &webhook.FailureCount,
+		&webhook.CreatedAt, &webhook.UpdatedAt, &webhook.DigestWindowMinutes, &webhook.DigestMaxEvents,
+		&webhook.PayloadTemplate,
+		&webhook.TotalFired, &webhook.SuccessCount,
 	)
 
 	if err == sql.ErrNoRows {
@@ -224,11 +218,8 @@ func (h *WebhookHandler) GetWebhook(c *gin.Context) {
 		webhook.Secret = "***" // Mask the secret
 	}
 
-	// Parse last fired time
 	if lastFired.Valid {
-		if t, err := time.Parse("2006-01-02 15:04:05", lastFired.String); err == nil {
-			webhook.LastFired = &t
-		}
+		webhook.LastFired = &lastFired.Time
 	}
 
 	// Calculate failure rate
@@ -336,13 +327,9 @@ func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
 	}
 
 	// Parse pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	event := c.Query("event")
@@ -442,13 +429,9 @@ func (h *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
 // GET /api/v1/webhooks/deliveries
 func (h *WebhookHandler) GetAllDeliveries(c *gin.Context) {
 	// Parse pagination and filters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	if page < 1 {
-		page = 1
-	}
-	if pageSize > 100 {
-		pageSize = 100
+	page, pageSize, ok := parsePage(c, h.DB, 20)
+	if !ok {
+		return
 	}
 
 	webhookID := c.Query("webhook_id")
@@ -560,6 +543,25 @@ func (h *WebhookHandler) GetWebhookStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GET /api/v1/webhooks/stats/trend
+func (h *WebhookHandler) GetDeliveryTrend(c *gin.Context) {
+	timeframe := models.AnalyticsTimeframe(c.DefaultQuery("timeframe", string(models.TimeframeWeek)))
+	switch timeframe {
+	case models.TimeframeDay, models.TimeframeWeek, models.TimeframeMonth, models.TimeframeYear:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timeframe, must be day, week, month or year"})
+		return
+	}
+
+	trend, err := h.WebhookService.GetDeliveryTrend(c.Request.Context(), timeframe)
+	if err != nil {
+		queryFailed(c, err, "Failed to get webhook delivery trend")
+		return
+	}
+
+	c.JSON(http.StatusOK, trend)
+}
+
 // GET /api/v1/webhooks/events
 func (h *WebhookHandler) GetAvailableEvents(c *gin.Context) {
 	events := []gin.H{
@@ -587,6 +589,14 @@ func (h *WebhookHandler) GetAvailableEvents(c *gin.Context) {
 			"event":       models.WebhookEventSystemAlert,
 			"description": "Triggered for system-level alerts and issues",
 		},
+		{
+			"event":       models.WebhookEventScheduleNewShows,
+			"description": "Triggered when a scheduled catalog refresh finds new shows",
+		},
+		{
+			"event":       models.WebhookEventGapThreshold,
+			"description": "Triggered when a monitored artist's completion drops below its goal",
+		},
 	}
 
 	c.JSON(http.StatusOK, gin.H{
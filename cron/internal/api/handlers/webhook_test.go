@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -34,6 +35,7 @@ func setupWebhookTestRouter(t *testing.T) (*gin.Engine, *models.JobManager) {
 		webhooks.GET("/deliveries", webhookHandler.GetAllDeliveries)
 		webhooks.GET("/events", webhookHandler.GetAvailableEvents)
 		webhooks.GET("/stats", webhookHandler.GetWebhookStats)
+		webhooks.GET("/stats/trend", webhookHandler.GetDeliveryTrend)
 	}
 
 	return router, jobManager
@@ -126,6 +128,101 @@ func TestWebhookHandler_CreateWebhook(t *testing.T) {
 	}
 }
 
+func TestWebhookHandler_CreateWebhookVerifyOnCreate(t *testing.T) {
+	router, _ := setupWebhookTestRouter(t)
+
+	var healthyPings int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&healthyPings, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer broken.Close()
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{
+			name:           "endpoint answers 2xx",
+			url:            healthy.URL,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "endpoint answers 404",
+			url:            broken.URL,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"name":             "verified",
+				"url":              tt.url,
+				"events":           []string{"download_complete"},
+				"verify_on_create": true,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if tt.expectedStatus == http.StatusBadRequest {
+				assert.Contains(t, w.Body.String(), "Endpoint verification failed")
+			}
+		})
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&healthyPings))
+}
+
+func TestWebhookHandler_CreateWebhookPayloadTemplate(t *testing.T) {
+	router, _ := setupWebhookTestRouter(t)
+
+	tests := []struct {
+		name     string
+		template string
+		rejected bool
+	}{
+		{"renders json", `{"text": {{json .data.download.show_title}}}`, false},
+		{"unterminated action", `{"text": {{json .event}`, true},
+		{"renders text", `done: {{.event}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"name":             "templated",
+				"url":              "https://example.com/hook",
+				"events":           []string{"download_complete"},
+				"payload_template": tt.template,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Only validation is checked here; saving the webhook is covered
+			// by TestWebhookHandler_CreateWebhook
+			if tt.rejected {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				assert.Contains(t, w.Body.String(), "invalid payload_template")
+			} else {
+				assert.NotContains(t, w.Body.String(), "invalid payload_template")
+			}
+		})
+	}
+}
+
 func TestWebhookHandler_GetWebhooks(t *testing.T) {
 	router, _ := setupWebhookTestRouter(t)
 
@@ -367,3 +464,14 @@ func TestWebhookHandler_GetWebhookStats(t *testing.T) {
 		assert.Contains(t, response, field)
 	}
 }
+
+func TestWebhookHandler_GetDeliveryTrendInvalidTimeframe(t *testing.T) {
+	router, _ := setupWebhookTestRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/stats/trend?timeframe=all", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid timeframe")
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader is the request header carrying an API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyValidator resolves an API key to its ID and scopes
+type APIKeyValidator func(key string) (int, []string, error)
+
+// APIKeyOrJWTAuth authenticates requests with either an API key or a JWT.
+// API keys are read-only: they are only accepted on GET and HEAD requests and
+// never carry a role, so role-protected routes stay closed to them.
+func APIKeyOrJWTAuth(secretKey string, validate APIKeyValidator) gin.HandlerFunc {
+	jwtAuth := JWTAuth(secretKey)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			c.Set("auth_type", "jwt")
+			jwtAuth(c)
+			return
+		}
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "API_KEY_READ_ONLY",
+					"message": "API keys can only be used for read requests",
+				},
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			})
+			c.Abort()
+			return
+		}
+
+		keyID, scopes, err := validate(key)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "INVALID_API_KEY",
+					"message": "Invalid or revoked API key",
+				},
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("auth_type", "api_key")
+		c.Set("api_key_id", keyID)
+		c.Set("api_key_scopes", scopes)
+
+		c.Next()
+	}
+}
+
+// RequireAPIKeyScope limits API key requests to keys holding the given scope
+// or the catch-all "read" scope. JWT requests pass through unchanged.
+func RequireAPIKeyScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != "api_key" {
+			c.Next()
+			return
+		}
+
+		scopes, _ := c.Get("api_key_scopes")
+		keyScopes, _ := scopes.([]string)
+		for _, s := range keyScopes {
+			if s == scope || s == "read" {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "INSUFFICIENT_SCOPE",
+				"message": "API key does not have access to this resource",
+				"details": gin.H{
+					"required_scope": scope,
+				},
+			},
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+		c.Abort()
+	}
+}
+
+// DenyAPIKey closes a route group to API keys entirely
+func DenyAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != "api_key" {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error": gin.H{
+				"code":    "API_KEY_NOT_ALLOWED",
+				"message": "API keys cannot be used for this resource",
+			},
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		})
+		c.Abort()
+	}
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,6 +12,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/version"
 )
 
 // RequestID generates and adds a unique request ID to each request
@@ -152,7 +156,7 @@ func HealthCheck() gin.HandlerFunc {
 			"status":    "healthy",
 			"timestamp": time.Now().UTC().Format(time.RFC3339),
 			"uptime":    uptime.String(),
-			"version":   "1.0.0",
+			"version":   version.Version,
 		})
 	}
 }
@@ -167,6 +171,82 @@ func NoCache() gin.HandlerFunc {
 	}
 }
 
+// ETag adds entity tags to successful GET responses and answers matching
+// If-None-Match requests with 304 Not Modified. It buffers the whole body, so
+// it is only mounted on JSON endpoints; attachments, which carry a
+// Content-Disposition header, are passed through untagged.
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		// Buffer the response so the tag can be computed from the full body
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, body: &bytes.Buffer{}, status: http.StatusOK}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+
+		if writer.status != http.StatusOK || original.Header().Get("Content-Disposition") != "" {
+			original.WriteHeader(writer.status)
+			original.Write(writer.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(writer.body.Bytes())
+		etag := fmt.Sprintf(`W/"%x"`, sum[:16])
+		original.Header().Set("ETag", etag)
+
+		if c.GetHeader("If-None-Match") == etag {
+			models.DefaultCacheMetrics.RecordHit(models.CacheETag)
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+
+		models.DefaultCacheMetrics.RecordMiss(models.CacheETag)
+		original.WriteHeader(http.StatusOK)
+		original.Write(writer.body.Bytes())
+	}
+}
+
+// bufferedWriter holds the response body and status until the handler chain completes
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body   *bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
 // Compress enables gzip compression (Gin has built-in support)
 func Compress() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DailySummary is a compact rollup of one day's request log, so the totals
+// can be read without scanning the whole log again
+type DailySummary struct {
+	Date          string                     `json:"date"`
+	GeneratedAt   string                     `json:"generated_at"`
+	TotalRequests int                        `json:"total_requests"`
+	Errors        int                        `json:"errors"`
+	ErrorRate     float64                    `json:"error_rate"`
+	P50Ms         int64                      `json:"p50_ms"`
+	P95Ms         int64                      `json:"p95_ms"`
+	P99Ms         int64                      `json:"p99_ms"`
+	ErrorClasses  map[ErrorClass]int         `json:"error_classes,omitempty"`
+	Endpoints     map[string]EndpointSummary `json:"endpoints"`
+}
+
+// EndpointSummary is one endpoint's share of a DailySummary
+type EndpointSummary struct {
+	Count     int     `json:"count"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50Ms     int64   `json:"p50_ms"`
+	P95Ms     int64   `json:"p95_ms"`
+	P99Ms     int64   `json:"p99_ms"`
+}
+
+// RequestLogFile returns the request log for date (YYYY-MM-DD) in logDir
+func RequestLogFile(logDir, date string) string {
+	return filepath.Join(logDir, fmt.Sprintf("api_requests_%s.log", date))
+}
+
+// SummaryFile returns the rollup file for date (YYYY-MM-DD) in logDir
+func SummaryFile(logDir, date string) string {
+	return filepath.Join(logDir, fmt.Sprintf("api_summary_%s.json", date))
+}
+
+// BuildDailySummary reads date's request log from logDir and summarizes it.
+// Lines that aren't log entries are skipped, and requests that got no
+// response are left out of the latencies.
+func BuildDailySummary(logDir, date string) (*DailySummary, error) {
+	file, err := os.Open(RequestLogFile(logDir, date))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	summary := &DailySummary{
+		Date:         date,
+		ErrorClasses: make(map[ErrorClass]int),
+		Endpoints:    make(map[string]EndpointSummary),
+	}
+	var latencies []int64
+	endpointLatencies := make(map[string][]int64)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry APILogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+
+		failed := entry.Error != "" || entry.ResponseCode >= 400
+
+		summary.TotalRequests++
+		if entry.ResponseCode != 0 {
+			latencies = append(latencies, entry.ResponseTime)
+			endpointLatencies[entry.Endpoint] = append(endpointLatencies[entry.Endpoint], entry.ResponseTime)
+		}
+
+		endpoint := summary.Endpoints[entry.Endpoint]
+		endpoint.Count++
+		if failed {
+			summary.Errors++
+			endpoint.Errors++
+			if entry.Classification != "" {
+				summary.ErrorClasses[entry.Classification]++
+			}
+		}
+		summary.Endpoints[entry.Endpoint] = endpoint
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	summary.ErrorRate = errorRate(summary.Errors, summary.TotalRequests)
+	summary.P50Ms = percentile(latencies, 50)
+	summary.P95Ms = percentile(latencies, 95)
+	summary.P99Ms = percentile(latencies, 99)
+	for name, endpoint := range summary.Endpoints {
+		endpoint.ErrorRate = errorRate(endpoint.Errors, endpoint.Count)
+		endpoint.P50Ms = percentile(endpointLatencies[name], 50)
+		endpoint.P95Ms = percentile(endpointLatencies[name], 95)
+		endpoint.P99Ms = percentile(endpointLatencies[name], 99)
+		summary.Endpoints[name] = endpoint
+	}
+	summary.GeneratedAt = time.Now().Format(time.RFC3339)
+
+	return summary, nil
+}
+
+// SaveDailySummary writes summary to its rollup file in logDir
+func SaveDailySummary(logDir string, summary *DailySummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(SummaryFile(logDir, summary.Date), data, 0644)
+}
+
+// LoadDailySummary reads date's rollup from logDir
+func LoadDailySummary(logDir, date string) (*DailySummary, error) {
+	data, err := ioutil.ReadFile(SummaryFile(logDir, date))
+	if err != nil {
+		return nil, err
+	}
+
+	var summary DailySummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// errorRate returns errors as a percentage of total
+func errorRate(errors, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(errors) / float64(total) * 100
+}
+
+// percentile returns the nearest-rank p-th percentile of values. It sorts
+// values in place.
+func percentile(values []int64, p int) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	rank := (p*len(values) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return values[rank-1]
+}
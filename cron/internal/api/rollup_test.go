@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDailySummary(t *testing.T) {
+	logDir := t.TempDir()
+	entries := []APILogEntry{
+		{Endpoint: "catalog.artists", ResponseCode: 200, ResponseTime: 10},
+		{Endpoint: "catalog.artists", ResponseCode: 200, ResponseTime: 20},
+		{Endpoint: "catalog.artists", ResponseCode: 200, ResponseTime: 30},
+		{Endpoint: "catalog.artists", ResponseCode: 200, ResponseTime: 400},
+		{Endpoint: "catalog.containersAll", ResponseCode: 503, ResponseTime: 5, Error: "HTTP 503", Classification: ErrorClassServer},
+	}
+
+	file, err := os.Create(RequestLogFile(logDir, "2026-01-02"))
+	require.NoError(t, err)
+	for _, entry := range entries {
+		data, _ := json.Marshal(entry)
+		file.Write(append(data, '\n'))
+	}
+	file.WriteString("not json\n")
+	require.NoError(t, file.Close())
+
+	summary, err := BuildDailySummary(logDir, "2026-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, 5, summary.TotalRequests)
+	assert.Equal(t, 1, summary.Errors)
+	assert.InDelta(t, 20.0, summary.ErrorRate, 0.01)
+	assert.Equal(t, int64(20), summary.P50Ms)
+	assert.Equal(t, int64(400), summary.P95Ms)
+	assert.Equal(t, 1, summary.ErrorClasses[ErrorClassServer])
+
+	artists := summary.Endpoints["catalog.artists"]
+	assert.Equal(t, 4, artists.Count)
+	assert.Equal(t, int64(20), artists.P50Ms)
+	assert.Equal(t, int64(400), artists.P95Ms)
+	assert.Equal(t, 100.0, summary.Endpoints["catalog.containersAll"].ErrorRate)
+
+	require.NoError(t, SaveDailySummary(logDir, summary))
+	loaded, err := LoadDailySummary(logDir, "2026-01-02")
+	require.NoError(t, err)
+	assert.Equal(t, summary.TotalRequests, loaded.TotalRequests)
+	assert.Equal(t, summary.Endpoints, loaded.Endpoints)
+}
+
+func TestBuildDailySummary_MissingLog(t *testing.T) {
+	_, err := BuildDailySummary(t.TempDir(), "2026-01-02")
+	assert.True(t, os.IsNotExist(err))
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/jmagar/nugs/cron/internal/api"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
 )
 
 // CatalogManager handles the full Nugs catalog
@@ -57,7 +59,7 @@ type CatalogCache struct {
 // NewCatalogManager creates a new catalog manager
 func NewCatalogManager() *CatalogManager {
 	return &CatalogManager{
-		catalogFile: "data/catalog_cache.json",
+		catalogFile: paths.Data("catalog_cache.json"),
 		maxAge:      24 * time.Hour, // Refresh daily
 	}
 }
@@ -66,11 +68,14 @@ func NewCatalogManager() *CatalogManager {
 func (cm *CatalogManager) GetCatalog() (*CatalogCache, error) {
 	// Check if we need to refresh
 	if cm.needsRefresh() {
+		models.DefaultCacheMetrics.RecordMiss(models.CacheCatalog)
 		log.Println("Catalog needs refresh, fetching from API...")
 		if err := cm.refreshCatalog(); err != nil {
 			log.Printf("Failed to refresh catalog: %v", err)
 			// Try to load existing cache even if refresh failed
 		}
+	} else {
+		models.DefaultCacheMetrics.RecordHit(models.CacheCatalog)
 	}
 
 	return cm.loadCatalogCache()
@@ -78,17 +83,7 @@ func (cm *CatalogManager) GetCatalog() (*CatalogCache, error) {
 
 // GetShowsForArtist returns all shows for a specific artist
 func (cm *CatalogManager) GetShowsForArtist(artistName string) ([]ShowContainer, error) {
-	catalog, err := cm.GetCatalog()
-	if err != nil {
-		return nil, err
-	}
-
-	shows, exists := catalog.ShowsByArtist[artistName]
-	if !exists {
-		return []ShowContainer{}, nil
-	}
-
-	return shows, nil
+	return showsForArtist(cm, artistName)
 }
 
 // GetAllArtists returns a list of all artists in the catalog
@@ -170,55 +165,63 @@ func (cm *CatalogManager) refreshCatalog() error {
 
 	log.Printf("Fetched %d shows from API", len(response.Response.Containers))
 
+	cache := newCatalogCache(response.Response.Containers, time.Now().Format(time.RFC3339))
+
+	// Save to cache file
+	if err := cm.saveCatalogCache(&cache); err != nil {
+		return fmt.Errorf("failed to save catalog cache: %v", err)
+	}
+
+	log.Printf("Catalog updated: %d shows from %d artists", cache.TotalShows, cache.TotalArtists)
+	return nil
+}
+
+// newCatalogCache builds a catalog from its shows, grouping them by artist
+// newest first
+func newCatalogCache(shows []ShowContainer, lastUpdate string) CatalogCache {
 	// Organize shows by artist
 	showsByArtist := make(map[string][]ShowContainer)
-	artistCounts := make(map[string]int)
 
-	for _, show := range response.Response.Containers {
+	for _, show := range shows {
 		artistName := strings.TrimSpace(show.ArtistName)
 		showsByArtist[artistName] = append(showsByArtist[artistName], show)
-		artistCounts[artistName]++
 	}
 
 	// Sort shows for each artist by date (newest first)
 	for artistName := range showsByArtist {
-		shows := showsByArtist[artistName]
-		sort.Slice(shows, func(i, j int) bool {
+		artistShows := showsByArtist[artistName]
+		sort.Slice(artistShows, func(i, j int) bool {
 			// Try to parse dates and sort by performance date
-			dateI, errI := time.Parse("1/2/2006", shows[i].PerformanceDate)
-			dateJ, errJ := time.Parse("1/2/2006", shows[j].PerformanceDate)
+			dateI, errI := time.Parse("1/2/2006", artistShows[i].PerformanceDate)
+			dateJ, errJ := time.Parse("1/2/2006", artistShows[j].PerformanceDate)
 
 			if errI != nil || errJ != nil {
 				// Fall back to container ID if date parsing fails
-				return shows[i].ContainerID > shows[j].ContainerID
+				return artistShows[i].ContainerID > artistShows[j].ContainerID
 			}
 
 			return dateI.After(dateJ)
 		})
-		showsByArtist[artistName] = shows
+		showsByArtist[artistName] = artistShows
 	}
 
-	// Create cache structure
-	cache := CatalogCache{
-		LastUpdate:    time.Now().Format(time.RFC3339),
-		TotalShows:    len(response.Response.Containers),
+	return CatalogCache{
+		LastUpdate:    lastUpdate,
+		TotalShows:    len(shows),
 		TotalArtists:  len(showsByArtist),
 		ShowsByArtist: showsByArtist,
-		AllShows:      response.Response.Containers,
+		AllShows:      shows,
 	}
-
-	// Save to cache file
-	if err := cm.saveCatalogCache(&cache); err != nil {
-		return fmt.Errorf("failed to save catalog cache: %v", err)
-	}
-
-	log.Printf("Catalog updated: %d shows from %d artists", cache.TotalShows, cache.TotalArtists)
-	return nil
 }
 
 // loadCatalogCache loads the cached catalog from disk
 func (cm *CatalogManager) loadCatalogCache() (*CatalogCache, error) {
-	data, err := ioutil.ReadFile(cm.catalogFile)
+	return readCatalogCache(cm.catalogFile)
+}
+
+// readCatalogCache reads a catalog in the cache format from file
+func readCatalogCache(file string) (*CatalogCache, error) {
+	data, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read catalog cache: %v", err)
 	}
@@ -282,14 +285,17 @@ func (cm *CatalogManager) PrintCatalogStats() {
 	}
 }
 
-// RunCLI provides the CLI functionality for catalog_manager
-func RunCLI() {
+// RunCLI provides the CLI functionality for catalog_manager. download
+// fetches the shows picked by search --download.
+func RunCLI(download func([]ShowContainer) error) {
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: catalog_manager <command>")
 		fmt.Println("Commands:")
 		fmt.Println("  stats    - Show catalog statistics")
 		fmt.Println("  refresh  - Force catalog refresh")
 		fmt.Println("  artist <name> - Show all shows for an artist")
+		fmt.Println("  search [--artist name] [--venue name] [--date date] [--year yyyy] [--download]")
+		fmt.Println("           - Search the catalog, optionally downloading every match")
 		return
 	}
 
@@ -320,6 +326,8 @@ func RunCLI() {
 				show.ContainerID, show.PerformanceDateShort,
 				show.VenueName, show.VenueCity, show.VenueState)
 		}
+	case "search":
+		runSearch(cm, os.Args[2:], download)
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 	}
@@ -0,0 +1,109 @@
+package catalog
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/downloader"
+)
+
+// SearchQuery holds the filters for a catalog search. Empty fields match everything.
+type SearchQuery struct {
+	Artist string // case-insensitive substring of the artist name
+	Venue  string // case-insensitive substring of the venue name, city or state
+	Date   string // exact performance date, M/D/YYYY or MM/DD/YY
+	Year   int    // performance year
+}
+
+// SearchShows returns all catalog shows matching the query
+func (cm *CatalogManager) SearchShows(query SearchQuery) ([]ShowContainer, error) {
+	catalog, err := cm.GetCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ShowContainer
+	for _, show := range catalog.AllShows {
+		if query.matches(show) {
+			results = append(results, show)
+		}
+	}
+
+	return results, nil
+}
+
+// matches reports whether a show satisfies every filter set on the query
+func (q SearchQuery) matches(show ShowContainer) bool {
+	if q.Artist != "" && !containsFold(show.ArtistName, q.Artist) {
+		return false
+	}
+
+	if q.Venue != "" && !containsFold(show.VenueName, q.Venue) &&
+		!containsFold(show.VenueCity, q.Venue) && !containsFold(show.VenueState, q.Venue) {
+		return false
+	}
+
+	if q.Date != "" && q.Date != show.PerformanceDate && q.Date != show.PerformanceDateShort {
+		return false
+	}
+
+	if q.Year != 0 {
+		date, err := time.Parse("1/2/2006", show.PerformanceDate)
+		if err != nil || date.Year() != q.Year {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// runSearch implements the "search" CLI command. With --download the
+// matching shows are handed to download.
+func runSearch(cm *CatalogManager, args []string, download func([]ShowContainer) error) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	artist := fs.String("artist", "", "Artist name (substring match)")
+	venue := fs.String("venue", "", "Venue name, city or state (substring match)")
+	date := fs.String("date", "", "Performance date (M/D/YYYY or MM/DD/YY)")
+	year := fs.Int("year", 0, "Performance year")
+	downloadMatches := fs.Bool("download", false, "Download every matching show with nugs-dl")
+	fs.Parse(args)
+
+	query := SearchQuery{Artist: *artist, Venue: *venue, Date: *date, Year: *year}
+
+	// Allow a bare artist name as a positional argument
+	if query.Artist == "" && fs.NArg() > 0 {
+		query.Artist = strings.Join(fs.Args(), " ")
+	}
+
+	if query == (SearchQuery{}) {
+		fmt.Println("Usage: catalog_manager search [--artist name] [--venue name] [--date date] [--year yyyy] [--download]")
+		return
+	}
+
+	shows, err := cm.SearchShows(query)
+	if err != nil {
+		log.Fatal("Error:", err)
+	}
+
+	for _, show := range shows {
+		fmt.Printf("%d\t%s\t%s\t%s, %s %s\t%s\n",
+			show.ContainerID, show.PerformanceDateShort, show.ArtistName,
+			show.VenueName, show.VenueCity, show.VenueState,
+			downloader.ReleaseURL(show.ContainerID))
+	}
+	fmt.Fprintf(os.Stderr, "%d matching shows\n", len(shows))
+
+	if *downloadMatches && len(shows) > 0 {
+		if err := download(shows); err != nil {
+			log.Fatal("Error downloading shows:", err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package catalog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestCatalog(t *testing.T, shows []ShowContainer) *CatalogManager {
+	cacheFile := filepath.Join(t.TempDir(), "catalog_cache.json")
+	data, err := json.Marshal(CatalogCache{AllShows: shows})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(cacheFile, data, 0644))
+
+	return &CatalogManager{catalogFile: cacheFile, maxAge: time.Hour}
+}
+
+func TestSearchShows(t *testing.T) {
+	cm := setupTestCatalog(t, []ShowContainer{
+		{ContainerID: 1, ArtistName: "Phish", VenueName: "Madison Square Garden", VenueCity: "New York", VenueState: "NY",
+			PerformanceDate: "12/31/1997", PerformanceDateShort: "12/31/97"},
+		{ContainerID: 2, ArtistName: "Phish", VenueName: "The Gorge", VenueCity: "George", VenueState: "WA",
+			PerformanceDate: "7/3/1999", PerformanceDateShort: "07/03/99"},
+		{ContainerID: 3, ArtistName: "Billy Strings", VenueName: "Red Rocks Amphitheatre", VenueCity: "Morrison", VenueState: "CO",
+			PerformanceDate: "9/2/2022", PerformanceDateShort: "09/02/22"},
+	})
+
+	tests := []struct {
+		name     string
+		query    SearchQuery
+		expected []int
+	}{
+		{"artist substring, case-insensitive", SearchQuery{Artist: "phish"}, []int{1, 2}},
+		{"venue name", SearchQuery{Venue: "gorge"}, []int{2}},
+		{"venue state", SearchQuery{Venue: "CO"}, []int{3}},
+		{"full date", SearchQuery{Date: "12/31/1997"}, []int{1}},
+		{"short date", SearchQuery{Date: "07/03/99"}, []int{2}},
+		{"year", SearchQuery{Year: 2022}, []int{3}},
+		{"combined filters", SearchQuery{Artist: "Phish", Year: 1999}, []int{2}},
+		{"no match", SearchQuery{Artist: "Goose"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shows, err := cm.SearchShows(tt.query)
+			require.NoError(t, err)
+
+			var ids []int
+			for _, show := range shows {
+				ids = append(ids, show.ContainerID)
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
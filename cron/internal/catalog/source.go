@@ -0,0 +1,131 @@
+package catalog
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/nugsconfig"
+	"github.com/jmagar/nugs/cron/internal/paths"
+)
+
+// CatalogSource provides the catalog the detector, monitor and gap report
+// work from
+type CatalogSource interface {
+	GetCatalog() (*CatalogCache, error)
+	GetShowsForArtist(artistName string) ([]ShowContainer, error)
+	GetCatalogStats() (*CatalogCache, error)
+}
+
+// Values of the catalogSource setting in config.json
+const (
+	// SourceAPI fetches the catalog from nugs.net, cached for a day
+	SourceAPI = "api"
+	// SourceFile reads a catalog snapshot and never contacts nugs.net
+	SourceFile = "file"
+)
+
+// NewSource returns the catalog source config selects. The file source
+// reads config.CatalogSnapshot, defaulting to the catalog cache.
+func NewSource(config *models.Config) (CatalogSource, error) {
+	switch config.CatalogSource {
+	case "", SourceAPI:
+		return NewCatalogManager(), nil
+	case SourceFile:
+		file := paths.Data("catalog_cache.json")
+		if config.CatalogSnapshot != "" {
+			file = paths.Resolve(config.CatalogSnapshot)
+		}
+		return NewSnapshotSource(file), nil
+	default:
+		return nil, fmt.Errorf("unknown catalogSource %q, expected %q or %q", config.CatalogSource, SourceAPI, SourceFile)
+	}
+}
+
+// LoadSource returns the catalog source selected in config.json. Without a
+// readable config.json it falls back to the API.
+func LoadSource() (CatalogSource, error) {
+	config, err := nugsconfig.Load(paths.Config("config.json"))
+	if err != nil {
+		log.Printf("Using the nugs.net catalog, config.json not loaded: %v", err)
+		return NewCatalogManager(), nil
+	}
+	return NewSource(config)
+}
+
+// SnapshotSource serves a catalog snapshot in the catalog cache format, for
+// working offline. The file is read once.
+type SnapshotSource struct {
+	file  string
+	once  sync.Once
+	cache *CatalogCache
+	err   error
+}
+
+// NewSnapshotSource returns a source reading the snapshot in file
+func NewSnapshotSource(file string) *SnapshotSource {
+	return &SnapshotSource{file: file}
+}
+
+// GetCatalog returns the snapshot
+func (s *SnapshotSource) GetCatalog() (*CatalogCache, error) {
+	s.once.Do(func() {
+		s.cache, s.err = readCatalogCache(s.file)
+		if s.err == nil && s.cache.ShowsByArtist == nil {
+			// Snapshots listing only all_shows are grouped here
+			*s.cache = newCatalogCache(s.cache.AllShows, s.cache.LastUpdate)
+		}
+	})
+	return s.cache, s.err
+}
+
+// GetShowsForArtist returns all shows for a specific artist
+func (s *SnapshotSource) GetShowsForArtist(artistName string) ([]ShowContainer, error) {
+	return showsForArtist(s, artistName)
+}
+
+// GetCatalogStats returns statistics about the catalog
+func (s *SnapshotSource) GetCatalogStats() (*CatalogCache, error) {
+	return s.GetCatalog()
+}
+
+// StaticSource serves a fixed in-memory catalog, for tests
+type StaticSource struct {
+	cache CatalogCache
+}
+
+// NewStaticSource returns a source serving shows
+func NewStaticSource(shows []ShowContainer) *StaticSource {
+	return &StaticSource{cache: newCatalogCache(shows, "")}
+}
+
+// GetCatalog returns the catalog
+func (s *StaticSource) GetCatalog() (*CatalogCache, error) {
+	return &s.cache, nil
+}
+
+// GetShowsForArtist returns all shows for a specific artist
+func (s *StaticSource) GetShowsForArtist(artistName string) ([]ShowContainer, error) {
+	return showsForArtist(s, artistName)
+}
+
+// GetCatalogStats returns statistics about the catalog
+func (s *StaticSource) GetCatalogStats() (*CatalogCache, error) {
+	return s.GetCatalog()
+}
+
+// showsForArtist returns the artist's shows from source's catalog, newest first
+func showsForArtist(source CatalogSource, artistName string) ([]ShowContainer, error) {
+	catalog, err := source.GetCatalog()
+	if err != nil {
+		return nil, err
+	}
+
+	shows, exists := catalog.ShowsByArtist[artistName]
+	if !exists {
+		return []ShowContainer{}, nil
+	}
+
+	return shows, nil
+}
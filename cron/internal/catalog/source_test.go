@@ -0,0 +1,93 @@
+package catalog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
+)
+
+var sourceShows = []ShowContainer{
+	{ContainerID: 1, ArtistName: "Phish", PerformanceDate: "12/31/1997"},
+	{ContainerID: 2, ArtistName: "Phish ", PerformanceDate: "7/3/1999"},
+	{ContainerID: 3, ArtistName: "Goose", PerformanceDate: "9/2/2022"},
+}
+
+func TestNewSource(t *testing.T) {
+	t.Setenv(paths.HomeEnv, "/opt/nugs")
+
+	source, err := NewSource(&models.Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &CatalogManager{}, source)
+
+	source, err = NewSource(&models.Config{CatalogSource: SourceFile})
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/nugs/data/catalog_cache.json", source.(*SnapshotSource).file)
+
+	source, err = NewSource(&models.Config{CatalogSource: SourceFile, CatalogSnapshot: "snapshots/catalog.json"})
+	require.NoError(t, err)
+	assert.Equal(t, "/opt/nugs/snapshots/catalog.json", source.(*SnapshotSource).file)
+
+	_, err = NewSource(&models.Config{CatalogSource: "ftp"})
+	assert.Error(t, err)
+}
+
+func TestSnapshotSource_GroupsAllShows(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	data, err := json.Marshal(CatalogCache{LastUpdate: "2026-01-02T00:00:00Z", AllShows: sourceShows})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, data, 0644))
+
+	source := NewSnapshotSource(file)
+	shows, err := source.GetShowsForArtist("Phish")
+	require.NoError(t, err)
+	require.Len(t, shows, 2)
+	assert.Equal(t, 2, shows[0].ContainerID, "newest first")
+
+	stats, err := source.GetCatalogStats()
+	require.NoError(t, err)
+	assert.Equal(t, "2026-01-02T00:00:00Z", stats.LastUpdate)
+	assert.Equal(t, 3, stats.TotalShows)
+	assert.Equal(t, 2, stats.TotalArtists)
+}
+
+func TestSnapshotSource_MissingFile(t *testing.T) {
+	_, err := NewSnapshotSource(filepath.Join(t.TempDir(), "missing.json")).GetCatalog()
+	assert.Error(t, err)
+}
+
+func TestStaticSource(t *testing.T) {
+	var source CatalogSource = NewStaticSource(sourceShows)
+
+	shows, err := source.GetShowsForArtist("Goose")
+	require.NoError(t, err)
+	assert.Equal(t, []ShowContainer{sourceShows[2]}, shows)
+
+	shows, err = source.GetShowsForArtist("Billy Strings")
+	require.NoError(t, err)
+	assert.Empty(t, shows)
+}
+
+func TestCatalogManager_RecordsCacheHits(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnv, home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "data"), 0755))
+	data, err := json.Marshal(newCatalogCache(sourceShows, "2026-01-02T00:00:00Z"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(home, "data", "catalog_cache.json"), data, 0644))
+
+	before := models.DefaultCacheMetrics.Snapshot()[models.CacheCatalog]
+	_, err = NewCatalogManager().GetShowsForArtist("Goose")
+	require.NoError(t, err)
+
+	// The cache is fresh, so it is read without contacting nugs.net
+	after := models.DefaultCacheMetrics.Snapshot()[models.CacheCatalog]
+	assert.Equal(t, before.Hits+1, after.Hits)
+	assert.Equal(t, before.Misses, after.Misses)
+}
@@ -0,0 +1,567 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrIncompatibleBackup is returned for backups that are not nugs databases,
+// are damaged, or were migrated by a newer build than this one
+var ErrIncompatibleBackup = errors.New("incompatible backup")
+
+// Snapshot writes a consistent copy of the database to path, which must not
+// exist yet. The copy is taken with VACUUM INTO, so it is compacted and can be
+// made while the database is in use.
+func Snapshot(db *sql.DB, path string) error {
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to snapshot database: %v", err)
+	}
+	return nil
+}
+
+// CheckBackup verifies that the database file at path can be restored: it
+// must pass an integrity check, be a nugs database and contain no migrations
+// this build doesn't know.
+func CheckBackup(path string) error {
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer src.Close()
+
+	var integrity string
+	if err := src.QueryRow("PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return fmt.Errorf("%w: %v", ErrIncompatibleBackup, err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("%w: failed integrity check: %s", ErrIncompatibleBackup, integrity)
+	}
+	var hasMigrations int
+	if err := src.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'migrations'").Scan(&hasMigrations); err != nil || hasMigrations == 0 {
+		return fmt.Errorf("%w: not a nugs database", ErrIncompatibleBackup)
+	}
+
+	known, err := getMigrationFiles()
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %v", err)
+	}
+	knownSet := make(map[string]bool, len(known))
+	for _, name := range known {
+		knownSet[name] = true
+	}
+
+	applied, err := getExecutedMigrations(src)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIncompatibleBackup, err)
+	}
+	var unknown []string
+	for name := range applied {
+		if !knownSet[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: made by a newer version, unknown migrations %s", ErrIncompatibleBackup, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// Restore replaces the contents of db with the database file at path using
+// SQLite's online backup API, then runs any migrations the copy predates.
+// The file is checked with CheckBackup before anything is overwritten.
+func Restore(db *sql.DB, path string) error {
+	if err := CheckBackup(path); err != nil {
+		return err
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer src.Close()
+
+	ctx := context.Background()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %v", err)
+	}
+	defer srcConn.Close()
+
+	dstConn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer dstConn.Close()
+
+	err = dstConn.Raw(func(dst interface{}) error {
+		return srcConn.Raw(func(src interface{}) error {
+			backup, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Close()
+				return err
+			}
+			return backup.Close()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore database: %v", err)
+	}
+
+	if err := runMigrations(db); err != nil {
+		return fmt.Errorf("failed to migrate restored database: %v", err)
+	}
+
+	return nil
+}
+
+// naturalKeys identify the rows of tables with no unique constraint besides
+// their id. Rows of other such tables match when every column is equal.
+var naturalKeys = map[string][]string{
+	"downloads": {"user_id", "container_id", "created_at"},
+	"webhooks":  {"user_id", "url"},
+}
+
+// Merge copies the rows of the database file at path into db, keeping rows db
+// already has. Rows are matched on their natural keys rather than their ids,
+// which two databases assign independently: unique columns such as a show's
+// container_id or a monitor's user and artist, or the naturalKeys above. Rows
+// db lacks are inserted with new ids and the references to them remapped.
+// system_config is the exception: values from the backup replace the current
+// ones key by key. Returns the rows added per table.
+func Merge(db *sql.DB, path string) (map[string]int64, error) {
+	if err := CheckBackup(path); err != nil {
+		return nil, err
+	}
+
+	// ATTACH and temporary tables are per connection, so the merge holds one
+	// connection throughout
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS backup", path); err != nil {
+		return nil, fmt.Errorf("failed to attach backup: %v", err)
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE backup")
+
+	tables, err := mergeableTables(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin merge: %v", err)
+	}
+	defer tx.Rollback()
+
+	var plans []*mergeTable
+	for _, table := range tables {
+		plan, err := planMerge(ctx, tx, table)
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil {
+			plans = append(plans, plan)
+		}
+	}
+
+	// merge_ids maps each backup id to the id of the same row in db
+	if _, err := tx.ExecContext(ctx, `CREATE TEMP TABLE merge_ids (
+		tbl TEXT NOT NULL, old_id INTEGER NOT NULL, new_id INTEGER NOT NULL,
+		PRIMARY KEY (tbl, old_id))`); err != nil {
+		return nil, fmt.Errorf("failed to prepare merge: %v", err)
+	}
+
+	// Parents are merged before the tables referencing them, so foreign keys
+	// stay enforced and every reference can be remapped
+	merged := make(map[string]int64)
+	mapped := make(map[string]bool)
+	for _, plan := range mergeOrder(plans) {
+		rows, err := plan.merge(ctx, tx, mapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge %s: %v", plan.name, err)
+		}
+		if rows > 0 {
+			merged[plan.name] = rows
+		}
+		if plan.hasID {
+			mapped[plan.name] = true
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DROP TABLE temp.merge_ids"); err != nil {
+		return nil, fmt.Errorf("failed to clean up merge: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %v", err)
+	}
+
+	return merged, nil
+}
+
+// mergeTable describes how the rows of one table are matched and copied
+type mergeTable struct {
+	name    string
+	columns []string // columns in both databases
+
+	// hasID is set when id is an INTEGER PRIMARY KEY, so inserted rows get a
+	// new id. Other tables are copied as they are, keeping rows db already has.
+	hasID bool
+
+	uniqueKeys  [][]string // a row matches on any one of them, compared with =
+	matchKey    []string   // compared with IS, for tables without unique keys
+	foreignKeys []mergeForeignKey
+}
+
+// mergeForeignKey is a column referencing the id of another table
+type mergeForeignKey struct {
+	column  string
+	parent  string
+	setNull bool // ON DELETE SET NULL, so a reference that can't be remapped is cleared
+}
+
+// planMerge reads how table is keyed and what it references. Returns nil for
+// tables with no columns in common.
+func planMerge(ctx context.Context, tx *sql.Tx, table string) (*mergeTable, error) {
+	columns, err := sharedColumns(ctx, tx, table)
+	if err != nil || len(columns) == 0 {
+		return nil, err
+	}
+	shared := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		shared[column] = true
+	}
+
+	plan := &mergeTable{name: table, columns: columns}
+
+	var pkName, pkType string
+	var pkColumns int
+	rows, err := tx.QueryContext(ctx, `SELECT name, type FROM pragma_table_info(?, 'main') WHERE pk > 0`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key of %s: %v", table, err)
+	}
+	for rows.Next() {
+		if err := rows.Scan(&pkName, &pkType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan primary key: %v", err)
+		}
+		pkColumns++
+	}
+	rows.Close()
+	plan.hasID = pkColumns == 1 && pkName == "id" && strings.EqualFold(pkType, "INTEGER") && shared["id"]
+
+	indexes, err := uniqueIndexes(ctx, tx, table)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		usable := len(index) > 0
+		for _, column := range index {
+			usable = usable && column != "id" && shared[column]
+		}
+		if usable {
+			plan.uniqueKeys = append(plan.uniqueKeys, index)
+		}
+	}
+
+	if key, ok := naturalKeys[table]; ok {
+		plan.matchKey = key
+	} else if len(plan.uniqueKeys) == 0 {
+		for _, column := range columns {
+			if column != "id" {
+				plan.matchKey = append(plan.matchKey, column)
+			}
+		}
+	}
+
+	rows, err = tx.QueryContext(ctx, `SELECT "from", "table", COALESCE("to", 'id'), on_delete FROM pragma_foreign_key_list(?, 'main')`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys of %s: %v", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column, parent, to, onDelete string
+		if err := rows.Scan(&column, &parent, &to, &onDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %v", err)
+		}
+		if to == "id" && parent != table && shared[column] {
+			plan.foreignKeys = append(plan.foreignKeys, mergeForeignKey{column: column, parent: parent, setNull: onDelete == "SET NULL"})
+		}
+	}
+	return plan, rows.Err()
+}
+
+// uniqueIndexes lists the columns of each unique constraint on table, leaving
+// out the primary key, partial indexes and indexes on expressions
+func uniqueIndexes(ctx context.Context, tx *sql.Tx, table string) ([][]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_index_list(?, 'main') WHERE "unique" = 1 AND origin != 'pk' AND partial = 0 ORDER BY name`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %v", table, err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan index name: %v", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	var indexes [][]string
+	for _, name := range names {
+		rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_index_info(?, 'main') ORDER BY seqno`, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index %s: %v", name, err)
+		}
+		var columns []string
+		for rows.Next() {
+			var column sql.NullString
+			if err := rows.Scan(&column); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan index column: %v", err)
+			}
+			if !column.Valid {
+				columns = nil
+				break
+			}
+			columns = append(columns, column.String)
+		}
+		rows.Close()
+		if len(columns) > 0 {
+			indexes = append(indexes, columns)
+		}
+	}
+	return indexes, nil
+}
+
+// mergeOrder sorts the tables so every table comes after the tables it
+// references. Reference cycles are broken at the table first reached.
+func mergeOrder(plans []*mergeTable) []*mergeTable {
+	byName := make(map[string]*mergeTable, len(plans))
+	for _, plan := range plans {
+		byName[plan.name] = plan
+	}
+
+	var ordered []*mergeTable
+	visited := make(map[string]bool)
+	var visit func(plan *mergeTable)
+	visit = func(plan *mergeTable) {
+		if visited[plan.name] {
+			return
+		}
+		visited[plan.name] = true
+		for _, fk := range plan.foreignKeys {
+			if parent, ok := byName[fk.parent]; ok {
+				visit(parent)
+			}
+		}
+		ordered = append(ordered, plan)
+	}
+	for _, plan := range plans {
+		visit(plan)
+	}
+	return ordered
+}
+
+// value is the SQL expression for a column of backup row b, with references
+// to merged tables remapped to their ids in db
+func (t *mergeTable) value(column string, mapped map[string]bool) string {
+	for _, fk := range t.foreignKeys {
+		if fk.column == column && mapped[fk.parent] {
+			return `(SELECT new_id FROM temp.merge_ids WHERE tbl = '` + fk.parent + `' AND old_id = b."` + column + `")`
+		}
+	}
+	return `b."` + column + `"`
+}
+
+// referencesResolved is the SQL condition that every required reference of
+// backup row b could be remapped
+func (t *mergeTable) referencesResolved(mapped map[string]bool) string {
+	conditions := []string{"true"}
+	for _, fk := range t.foreignKeys {
+		if !fk.setNull && mapped[fk.parent] {
+			conditions = append(conditions, `(b."`+fk.column+`" IS NULL OR `+t.value(fk.column, mapped)+` IS NOT NULL)`)
+		}
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// matchCondition is the SQL condition that row m of db is backup row b
+func (t *mergeTable) matchCondition(mapped map[string]bool) string {
+	var keys []string
+	for _, key := range t.uniqueKeys {
+		var equal []string
+		for _, column := range key {
+			equal = append(equal, `m."`+column+`" = `+t.value(column, mapped))
+		}
+		keys = append(keys, "("+strings.Join(equal, " AND ")+")")
+	}
+	if len(t.matchKey) > 0 {
+		var equal []string
+		for _, column := range t.matchKey {
+			equal = append(equal, `m."`+column+`" IS `+t.value(column, mapped))
+		}
+		keys = append(keys, "("+strings.Join(equal, " AND ")+")")
+	}
+	if len(keys) == 0 {
+		return "false"
+	}
+	return strings.Join(keys, " OR ")
+}
+
+// merge copies the backup rows db lacks and records the id of every backup
+// row in merge_ids. Returns the rows inserted, plus the config values
+// replaced for system_config.
+func (t *mergeTable) merge(ctx context.Context, tx *sql.Tx, mapped map[string]bool) (int64, error) {
+	var columns, values []string
+	for _, column := range t.columns {
+		if t.hasID && column == "id" {
+			continue
+		}
+		columns = append(columns, `"`+column+`"`)
+		values = append(values, t.value(column, mapped))
+	}
+	insert := `INSERT INTO main."` + t.name + `" (` + strings.Join(columns, ", ") + `)
+		SELECT ` + strings.Join(values, ", ") + ` FROM backup."` + t.name + `" b`
+
+	if !t.hasID {
+		result, err := tx.ExecContext(ctx, strings.Replace(insert, "INSERT INTO", "INSERT OR IGNORE INTO", 1)+
+			` WHERE `+t.referencesResolved(mapped))
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT b.id FROM backup."`+t.name+`" b WHERE `+t.referencesResolved(mapped)+` ORDER BY b.id`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	match := `SELECT m.id FROM main."` + t.name + `" m, backup."` + t.name + `" b
+		WHERE b.id = ? AND (` + t.matchCondition(mapped) + `) ORDER BY m.id LIMIT 1`
+	insert += ` WHERE b.id = ?`
+
+	var merged int64
+	for _, id := range ids {
+		var newID int64
+		err := tx.QueryRowContext(ctx, match, id).Scan(&newID)
+		switch {
+		case err == sql.ErrNoRows:
+			result, err := tx.ExecContext(ctx, insert, id)
+			if err != nil {
+				return 0, err
+			}
+			if newID, err = result.LastInsertId(); err != nil {
+				return 0, err
+			}
+			merged++
+		case err != nil:
+			return 0, err
+		case t.name == "system_config":
+			result, err := tx.ExecContext(ctx, `UPDATE main.system_config
+				SET value = (SELECT value FROM backup.system_config WHERE id = ?), updated_at = CURRENT_TIMESTAMP
+				WHERE id = ? AND value IS NOT (SELECT value FROM backup.system_config WHERE id = ?)`, id, newID, id)
+			if err != nil {
+				return 0, err
+			}
+			if replaced, _ := result.RowsAffected(); replaced > 0 {
+				merged++
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO temp.merge_ids (tbl, old_id, new_id) VALUES (?, ?, ?)`, t.name, id, newID); err != nil {
+			return 0, err
+		}
+	}
+	return merged, nil
+}
+
+// mergeableTables lists the tables present in both databases, leaving out
+// SQLite's own tables and the migrations bookkeeping
+func mergeableTables(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT b.name FROM backup.sqlite_master b
+		JOIN main.sqlite_master m ON m.name = b.name AND m.type = 'table'
+		WHERE b.type = 'table' AND b.name NOT LIKE 'sqlite_%' AND b.name != 'migrations'
+		ORDER BY b.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backup tables: %v", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %v", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// sharedColumns lists the columns table has in both databases, so a backup
+// from before a column was added still merges
+func sharedColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	backupColumns := make(map[string]bool)
+	if err := forEachColumn(ctx, tx, "backup", table, func(name string) { backupColumns[name] = true }); err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	err := forEachColumn(ctx, tx, "main", table, func(name string) {
+		if backupColumns[name] {
+			columns = append(columns, name)
+		}
+	})
+	return columns, err
+}
+
+func forEachColumn(ctx context.Context, tx *sql.Tx, schema, table string, fn func(name string)) error {
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM pragma_table_info(?, ?)`, table, schema)
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s.%s: %v", schema, table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to scan column: %v", err)
+		}
+		fn(name)
+	}
+	return rows.Err()
+}
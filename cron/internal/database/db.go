@@ -25,8 +25,9 @@ func Initialize(databaseURL string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create database directory: %v", err)
 	}
 
-	// Open database connection
-	db, err := sql.Open("sqlite3", databaseURL+"?_foreign_keys=on")
+	// Open database connection. SQLite only enforces foreign keys when the
+	// pragma is set per connection, so it is part of the DSN for every pooled connection.
+	db, err := sql.Open("sqlite3", buildDSN(databaseURL))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
@@ -36,6 +37,11 @@ func Initialize(databaseURL string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
+	// Verify foreign key enforcement is active
+	if err := checkForeignKeys(db); err != nil {
+		return nil, err
+	}
+
 	// Run migrations
 	if err := runMigrations(db); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %v", err)
@@ -45,6 +51,27 @@ func Initialize(databaseURL string) (*sql.DB, error) {
 	return db, nil
 }
 
+// buildDSN appends the connection parameters required by the application
+func buildDSN(databaseURL string) string {
+	separator := "?"
+	if strings.Contains(databaseURL, "?") {
+		separator = "&"
+	}
+	return databaseURL + separator + "_foreign_keys=on"
+}
+
+// checkForeignKeys ensures SQLite is enforcing foreign key constraints
+func checkForeignKeys(db *sql.DB) error {
+	var enabled int
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&enabled); err != nil {
+		return fmt.Errorf("failed to check foreign key enforcement: %v", err)
+	}
+	if enabled != 1 {
+		return fmt.Errorf("foreign key enforcement is not enabled")
+	}
+	return nil
+}
+
 // runMigrations executes all migration files
 func runMigrations(db *sql.DB) error {
 	// Create migrations table if it doesn't exist
@@ -119,6 +146,7 @@ func getMigrationFiles() ([]string, error) {
 		"internal/database/migrations",          // from project root
 		"../../../internal/database/migrations", // from test directories
 		"../../database/migrations",             // from internal/api/handlers
+		"../database/migrations",                // from internal/services and siblings
 		"../internal/database/migrations",       // from test/ directory
 		"database/migrations",                   // from internal/
 		"./migrations",                          // from internal/database/
@@ -167,6 +195,7 @@ func executeMigration(db *sql.DB, filename string) error {
 		"internal/database/migrations",          // from project root
 		"../../../internal/database/migrations", // from test directories
 		"../../database/migrations",             // from internal/api/handlers
+		"../database/migrations",                // from internal/services and siblings
 		"../internal/database/migrations",       // from test/ directory
 		"database/migrations",                   // from internal/
 		"./migrations",                          // from internal/database/
@@ -0,0 +1,317 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// explainQueryPlan returns the detail column of EXPLAIN QUERY PLAN joined into one string
+func explainQueryPlan(t *testing.T, db *sql.DB, query string, args ...interface{}) string {
+	rows, err := db.Query("EXPLAIN QUERY PLAN "+query, args...)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var details []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		require.NoError(t, rows.Scan(&id, &parent, &notUsed, &detail))
+		details = append(details, detail)
+	}
+	require.NoError(t, rows.Err())
+
+	return strings.Join(details, "\n")
+}
+
+func TestInitialize_ForeignKeysEnabled(t *testing.T) {
+	db, err := Initialize(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var enabled int
+	require.NoError(t, db.QueryRow("PRAGMA foreign_keys").Scan(&enabled))
+	assert.Equal(t, 1, enabled)
+}
+
+func TestInitialize_SeedsDefaultConfig(t *testing.T) {
+	db, err := Initialize(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		key      string
+		value    string
+		dataType string
+	}{
+		{key: "max_concurrent_downloads", value: "5", dataType: "integer"},
+		{key: "default_download_path", value: "/downloads", dataType: "string"},
+		{key: "auto_refresh_enabled", value: "true", dataType: "boolean"},
+		{key: "webhook_delivery_retention_days", value: "30", dataType: "integer"},
+		{key: "config_change_alerts_enabled", value: "true", dataType: "boolean"},
+		{key: "catalog_refresh_workers", value: "4", dataType: "integer"},
+		{key: "catalog_refresh_timeout_seconds", value: "30", dataType: "integer"},
+		{key: "catalog_refresh_new_show_webhooks", value: "false", dataType: "boolean"},
+		{key: "job_retention_days", value: "7", dataType: "integer"},
+		{key: "maintenance_locked", value: "false", dataType: "boolean"},
+		{key: "maintenance_windows", value: "", dataType: "string"},
+		{key: "storage_alerts_enabled", value: "true", dataType: "boolean"},
+		{key: "storage_warning_percent", value: "75", dataType: "integer"},
+		{key: "storage_critical_percent", value: "90", dataType: "integer"},
+		{key: "analytics_monitored_only", value: "false", dataType: "boolean"},
+		{key: "sync_enabled", value: "false", dataType: "boolean"},
+		{key: "sync_interval_minutes", value: "60", dataType: "integer"},
+		{key: "schedule_execution_retention_days", value: "30", dataType: "integer"},
+		{key: "schedule_execution_keep_recent", value: "20", dataType: "integer"},
+		{key: "report_retention_days", value: "30", dataType: "integer"},
+		{key: "report_keep_recent", value: "20", dataType: "integer"},
+		{key: "analytics_timeout_seconds", value: "10", dataType: "integer"},
+		{key: "max_page_size", value: "100", dataType: "integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			var value, dataType, description string
+			err := db.QueryRow("SELECT value, data_type, description FROM system_config WHERE key = ?", tt.key).
+				Scan(&value, &dataType, &description)
+			require.NoError(t, err)
+			assert.Equal(t, tt.value, value)
+			assert.Equal(t, tt.dataType, dataType)
+			assert.NotEmpty(t, description)
+		})
+	}
+
+	// Re-running the seed must not overwrite values changed since
+	_, err = db.Exec("UPDATE system_config SET value = '8' WHERE key = 'max_concurrent_downloads'")
+	require.NoError(t, err)
+	require.NoError(t, executeMigration(db, "007_default_system_config.sql"))
+
+	var value string
+	require.NoError(t, db.QueryRow("SELECT value FROM system_config WHERE key = 'max_concurrent_downloads'").Scan(&value))
+	assert.Equal(t, "8", value)
+}
+
+func TestAnalyticsIndexes_UsedByQueryPlanner(t *testing.T) {
+	db, err := Initialize(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := []struct {
+		name          string
+		query         string
+		args          []interface{}
+		expectedIndex string
+	}{
+		{
+			name:          "downloads joined by show",
+			query:         "SELECT id FROM downloads WHERE show_id = ?",
+			args:          []interface{}{1},
+			expectedIndex: "idx_downloads_show",
+		},
+		{
+			name:          "downloads by status and date range",
+			query:         "SELECT COUNT(*) FROM downloads WHERE status = ? AND created_at >= datetime('now', '-7 days')",
+			args:          []interface{}{"completed"},
+			expectedIndex: "idx_downloads_status_created",
+		},
+		{
+			name:          "shows by artist ordered by date",
+			query:         "SELECT id FROM shows WHERE artist_id = ? ORDER BY date",
+			args:          []interface{}{1},
+			expectedIndex: "idx_shows_artist_date",
+		},
+		{
+			name:          "webhook deliveries ordered by date",
+			query:         "SELECT id FROM webhook_deliveries WHERE webhook_id = ? ORDER BY created_at DESC",
+			args:          []interface{}{1},
+			expectedIndex: "idx_deliveries_webhook_created",
+		},
+		{
+			name:          "audit logs ordered by date",
+			query:         "SELECT id FROM audit_logs ORDER BY created_at DESC LIMIT 20",
+			expectedIndex: "idx_audit_created",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := explainQueryPlan(t, db, tt.query, tt.args...)
+			assert.Contains(t, plan, tt.expectedIndex)
+		})
+	}
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	db, err := Initialize(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, Snapshot(db, path))
+
+	// Changes made after the snapshot are undone by restoring it
+	_, err = db.Exec("DELETE FROM artists WHERE id = 101")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO artists (id, name, slug) VALUES (102, 'Goose', 'goose')`)
+	require.NoError(t, err)
+
+	require.NoError(t, Restore(db, path))
+
+	var names []string
+	rows, err := db.Query("SELECT name FROM artists WHERE id >= 101 ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"Billy Strings"}, names)
+
+	// Anything that is not a nugs database is refused
+	other := filepath.Join(t.TempDir(), "other.db")
+	otherDB, err := sql.Open("sqlite3", other)
+	require.NoError(t, err)
+	_, err = otherDB.Exec("CREATE TABLE t (x INTEGER)")
+	require.NoError(t, err)
+	otherDB.Close()
+	assert.Error(t, Restore(db, other))
+}
+
+func TestMergeAndCheckBackup(t *testing.T) {
+	db, err := Initialize(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO artists (id, name, slug) VALUES (101, 'Billy Strings', 'billy-strings')`)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE system_config SET value = '9' WHERE key = 'max_concurrent_downloads'")
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, Snapshot(db, path))
+
+	// Current rows win over the backup's, except for config values
+	_, err = db.Exec("UPDATE artists SET name = 'Billy Strings Band' WHERE id = 101")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO artists (id, name, slug) VALUES (102, 'Goose', 'goose')`)
+	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM shows WHERE id = 1")
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE system_config SET value = '2' WHERE key = 'max_concurrent_downloads'")
+	require.NoError(t, err)
+
+	merged, err := Merge(db, path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), merged["shows"])
+	assert.Equal(t, int64(1), merged["system_config"])
+	assert.NotContains(t, merged, "artists")
+
+	var name, value string
+	require.NoError(t, db.QueryRow("SELECT name FROM artists WHERE id = 101").Scan(&name))
+	assert.Equal(t, "Billy Strings Band", name)
+	require.NoError(t, db.QueryRow("SELECT name FROM artists WHERE id = 102").Scan(&name))
+	assert.Equal(t, "Goose", name)
+	require.NoError(t, db.QueryRow("SELECT value FROM system_config WHERE key = 'max_concurrent_downloads'").Scan(&value))
+	assert.Equal(t, "9", value)
+
+	var enabled int
+	require.NoError(t, db.QueryRow("PRAGMA foreign_keys").Scan(&enabled))
+	assert.Equal(t, 1, enabled)
+
+	// A backup migrated by a newer build is refused
+	newer, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = newer.Exec("INSERT INTO migrations (filename) VALUES ('999_from_the_future.sql')")
+	require.NoError(t, err)
+	newer.Close()
+
+	err = CheckBackup(path)
+	assert.ErrorIs(t, err, ErrIncompatibleBackup)
+	assert.Contains(t, err.Error(), "999_from_the_future.sql")
+	assert.ErrorIs(t, Restore(db, path), ErrIncompatibleBackup)
+}
+
+func TestMerge_RemapsCollidingIDs(t *testing.T) {
+	// seed gives a user their own artist, show, download, monitor and webhook.
+	// Both databases start from the same migrations, so their ids collide.
+	type seeded struct{ user, artist, show, download int64 }
+	seed := func(db *sql.DB, username, artist string, containerID int) seeded {
+		insert := func(query string, args ...interface{}) int64 {
+			result, err := db.Exec(query, args...)
+			require.NoError(t, err)
+			id, err := result.LastInsertId()
+			require.NoError(t, err)
+			return id
+		}
+		var ids seeded
+		ids.user = insert(`INSERT INTO users (username, email, password_hash) VALUES (?, ?, 'hash')`, username, username+"@example.com")
+		ids.artist = insert(`INSERT INTO artists (name, slug) VALUES (?, ?)`, artist, strings.ToLower(strings.ReplaceAll(artist, " ", "-")))
+		ids.show = insert(`INSERT INTO shows (artist_id, date, venue, container_id) VALUES (?, '2024-08-01', 'Red Rocks', ?)`, ids.artist, containerID)
+		ids.download = insert(`INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality)
+			VALUES (?, ?, ?, ?, '2024-08-01', 'Red Rocks', 'FLAC', 'hd')`, ids.user, ids.show, containerID, artist)
+		insert(`INSERT INTO monitors (user_id, artist_id, settings) VALUES (?, ?, '{}')`, ids.user, ids.artist)
+		insert(`INSERT INTO webhooks (user_id, name, url, events) VALUES (?, 'alerts', 'https://hooks.example.com/nugs', '["new_show"]')`, ids.user)
+		return ids
+	}
+
+	backupDB, err := Initialize(":memory:")
+	require.NoError(t, err)
+	defer backupDB.Close()
+	bob := seed(backupDB, "bob", "Goose", 900001)
+	path := filepath.Join(t.TempDir(), "backup.db")
+	require.NoError(t, Snapshot(backupDB, path))
+
+	db, err := Initialize(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	carol := seed(db, "carol", "Billy Strings", 900002)
+	require.Equal(t, bob, carol)
+
+	merged, err := Merge(db, path)
+	require.NoError(t, err)
+	for _, table := range []string{"users", "artists", "shows", "downloads", "monitors", "webhooks"} {
+		assert.Equal(t, int64(1), merged[table], table)
+	}
+
+	// Carol's rows keep their ids, and bob's rows point at bob's copies
+	owners := func(query string) []string {
+		rows, err := db.Query(query)
+		require.NoError(t, err)
+		defer rows.Close()
+		var owners []string
+		for rows.Next() {
+			var owner string
+			require.NoError(t, rows.Scan(&owner))
+			owners = append(owners, owner)
+		}
+		require.NoError(t, rows.Err())
+		return owners
+	}
+	var username string
+	require.NoError(t, db.QueryRow("SELECT username FROM users WHERE id = ?", carol.user).Scan(&username))
+	assert.Equal(t, "carol", username)
+	assert.Equal(t, []string{"bob 900001 Goose", "carol 900002 Billy Strings"}, owners(`
+		SELECT u.username || ' ' || s.container_id || ' ' || a.name FROM downloads d
+		JOIN users u ON u.id = d.user_id JOIN shows s ON s.id = d.show_id JOIN artists a ON a.id = s.artist_id
+		ORDER BY u.username`))
+	assert.Equal(t, []string{"bob Goose", "carol Billy Strings"}, owners(`
+		SELECT u.username || ' ' || a.name FROM monitors m
+		JOIN users u ON u.id = m.user_id JOIN artists a ON a.id = m.artist_id ORDER BY u.username`))
+	assert.Equal(t, []string{"bob", "carol"}, owners(`
+		SELECT u.username FROM webhooks w JOIN users u ON u.id = w.user_id
+		WHERE w.url = 'https://hooks.example.com/nugs' ORDER BY u.username`))
+	assert.Empty(t, owners(`SELECT "table" FROM pragma_foreign_key_check`))
+
+	// Merging the same backup again finds every row already present
+	merged, err = Merge(db, path)
+	require.NoError(t, err)
+	assert.Empty(t, merged)
+}
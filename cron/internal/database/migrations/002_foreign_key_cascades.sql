@@ -0,0 +1,40 @@
+-- Enforce cascading deletes from shows to their downloads.
+-- SQLite cannot alter an existing constraint, so the downloads table is rebuilt.
+CREATE TABLE IF NOT EXISTS downloads_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    show_id INTEGER,
+    container_id INTEGER NOT NULL,
+    artist_name TEXT NOT NULL,
+    show_date DATE NOT NULL,
+    venue TEXT NOT NULL,
+    format TEXT NOT NULL CHECK (format IN ('FLAC', 'MP3', 'ALAC')),
+    quality TEXT NOT NULL,
+    size_mb REAL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'queued', 'downloading', 'completed', 'failed', 'cancelled')),
+    progress INTEGER DEFAULT 0 CHECK (progress >= 0 AND progress <= 100),
+    download_path TEXT,
+    error_message TEXT,
+    queue_position INTEGER,
+    retry_count INTEGER DEFAULT 0,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (show_id) REFERENCES shows(id) ON DELETE CASCADE
+);
+
+-- Orphaned rows would violate the new constraints, so only valid rows are copied
+INSERT INTO downloads_new
+SELECT * FROM downloads
+WHERE user_id IN (SELECT id FROM users)
+  AND (show_id IS NULL OR show_id IN (SELECT id FROM shows));
+
+DROP TABLE downloads;
+
+ALTER TABLE downloads_new RENAME TO downloads;
+
+CREATE INDEX IF NOT EXISTS idx_downloads_user ON downloads(user_id);
+CREATE INDEX IF NOT EXISTS idx_downloads_status ON downloads(status);
+CREATE INDEX IF NOT EXISTS idx_downloads_queue ON downloads(queue_position) WHERE status = 'queued';
@@ -0,0 +1,6 @@
+-- Indexes for the analytics aggregations and list endpoints.
+-- audit_logs(created_at) is already covered by idx_audit_created.
+CREATE INDEX IF NOT EXISTS idx_downloads_show ON downloads(show_id);
+CREATE INDEX IF NOT EXISTS idx_downloads_status_created ON downloads(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_shows_artist_date ON shows(artist_id, date);
+CREATE INDEX IF NOT EXISTS idx_deliveries_webhook_created ON webhook_deliveries(webhook_id, created_at)
@@ -0,0 +1,31 @@
+-- Target completion percentage per monitored artist, NULL means no goal
+ALTER TABLE monitors ADD COLUMN completion_goal REAL;
+
+-- Allow the missing_show and below_goal alert types raised by monitor checks.
+-- SQLite cannot alter an existing CHECK constraint, so monitor_alerts is rebuilt.
+CREATE TABLE IF NOT EXISTS monitor_alerts_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    monitor_id INTEGER NOT NULL,
+    artist_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('new_show', 'show_update', 'show_available', 'missing_show', 'below_goal')),
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    data TEXT, -- JSON object with alert details
+    severity TEXT NOT NULL DEFAULT 'info' CHECK (severity IN ('info', 'warning', 'high', 'critical')),
+    acknowledged BOOLEAN DEFAULT false,
+    acknowledged_at TIMESTAMP,
+    acknowledged_by INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (monitor_id) REFERENCES monitors(id) ON DELETE CASCADE,
+    FOREIGN KEY (artist_id) REFERENCES artists(id) ON DELETE CASCADE,
+    FOREIGN KEY (acknowledged_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+INSERT INTO monitor_alerts_new SELECT * FROM monitor_alerts;
+
+DROP TABLE monitor_alerts;
+
+ALTER TABLE monitor_alerts_new RENAME TO monitor_alerts;
+
+CREATE INDEX IF NOT EXISTS idx_alerts_monitor ON monitor_alerts(monitor_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_unacked ON monitor_alerts(acknowledged) WHERE acknowledged = false;
@@ -0,0 +1,2 @@
+-- Track numbers selected for a partial download (JSON array), NULL downloads the whole show
+ALTER TABLE downloads ADD COLUMN tracks TEXT
@@ -0,0 +1,8 @@
+-- Tamper-evident audit log: each row hashes its contents together with the
+-- previous row's hash. Rows written before this migration have no hash.
+ALTER TABLE audit_logs ADD COLUMN prev_hash TEXT;
+ALTER TABLE audit_logs ADD COLUMN hash TEXT;
+-- Columns the admin service records and hashes alongside resource_type
+ALTER TABLE audit_logs ADD COLUMN username TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN resource TEXT NOT NULL DEFAULT '';
+ALTER TABLE audit_logs ADD COLUMN success BOOLEAN NOT NULL DEFAULT true
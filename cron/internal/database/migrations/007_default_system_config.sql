@@ -0,0 +1,7 @@
+-- Default system configuration. INSERT OR IGNORE keeps any value already set.
+-- last_catalog_refresh is left unset until the first refresh records it.
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('max_concurrent_downloads', '5', 'Maximum number of downloads running at once', 'integer'),
+    ('default_download_path', '/downloads', 'Directory new downloads are written to', 'string'),
+    ('auto_refresh_enabled', 'true', 'Refresh the catalog automatically on schedule', 'boolean'),
+    ('webhook_delivery_retention_days', '30', 'Days to keep webhook delivery records before cleanup removes them', 'integer')
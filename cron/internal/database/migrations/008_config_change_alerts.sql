@@ -0,0 +1,3 @@
+-- Toggle for the system_alert webhook fired when an admin changes a setting
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('config_change_alerts_enabled', 'true', 'Send a system_alert webhook with the old and new value when a setting is changed', 'boolean')
@@ -0,0 +1,4 @@
+-- Concurrency and per-request timeout for per-artist catalog refreshes
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('catalog_refresh_workers', '4', 'Maximum number of artists fetched from the Nugs.net API at the same time during a catalog refresh', 'integer'),
+    ('catalog_refresh_timeout_seconds', '30', 'Timeout in seconds for each Nugs.net API request made during a catalog refresh', 'integer')
@@ -0,0 +1,3 @@
+-- Opt-in new_show webhooks for shows a catalog refresh finds
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('catalog_refresh_new_show_webhooks', 'false', 'Send a new_show webhook for every show a catalog refresh adds, except on the first import', 'boolean')
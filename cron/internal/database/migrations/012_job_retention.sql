@@ -0,0 +1,3 @@
+-- Retention for finished in-memory jobs removed by the maintenance cleanup
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('job_retention_days', '7', 'Days to keep finished jobs before cleanup removes them', 'integer')
@@ -0,0 +1,6 @@
+-- Destructive maintenance (cleanup, database optimize and backup, user deletion)
+-- is rejected while maintenance_locked is true or during a maintenance window.
+-- maintenance_windows holds daily local HH:MM-HH:MM ranges separated by commas.
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('maintenance_locked', 'false', 'Reject destructive maintenance operations until unset', 'boolean'),
+    ('maintenance_windows', '', 'Daily HH:MM-HH:MM windows, comma separated, during which destructive maintenance is rejected, e.g. 18:00-23:30', 'string')
@@ -0,0 +1,6 @@
+-- Storage alerts fire a system_alert webhook when usage of the download
+-- directory's filesystem moves between ok, warning and critical
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('storage_alerts_enabled', 'true', 'Send system_alert webhooks when storage crosses the warning or critical threshold', 'boolean'),
+    ('storage_warning_percent', '75', 'Storage usage percentage that raises a warning', 'integer'),
+    ('storage_critical_percent', '90', 'Storage usage percentage that is critical', 'integer')
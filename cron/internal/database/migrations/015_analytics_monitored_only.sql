@@ -0,0 +1,5 @@
+-- Scopes the collection and artist analytics to artists with an active
+-- monitor, matching the artists the gap report covers, unless a request
+-- passes monitored_only itself
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('analytics_monitored_only', 'false', 'Limit collection and artist analytics to monitored artists by default', 'boolean')
@@ -0,0 +1,6 @@
+-- The sync worker runs the detector inside the API, keeping shows.json and
+-- the downloads table in step with tootie. It is off by default so existing
+-- cron setups keep working unchanged.
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('sync_enabled', 'false', 'Rescan monitored artists from the API and keep shows.json and downloads in sync', 'boolean'),
+    ('sync_interval_minutes', '60', 'Minutes between sync worker scans', 'integer')
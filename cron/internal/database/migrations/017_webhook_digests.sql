@@ -0,0 +1,17 @@
+-- Digest delivery for webhooks. A webhook with a digest window collects its
+-- events and delivers them together as one digest event once the window has
+-- passed or digest_max_events are waiting. A window of 0 delivers each event
+-- as it happens.
+ALTER TABLE webhooks ADD COLUMN digest_window_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE webhooks ADD COLUMN digest_max_events INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS webhook_digest_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    data TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_digest_events_webhook ON webhook_digest_events(webhook_id, id);
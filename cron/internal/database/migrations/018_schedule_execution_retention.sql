@@ -0,0 +1,6 @@
+-- Retention for schedule execution history removed by the maintenance cleanup.
+-- Executions older than the retention are removed, except the most recent
+-- ones of each schedule, which are kept as its history.
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('schedule_execution_retention_days', '30', 'Days to keep schedule executions before cleanup removes them', 'integer'),
+    ('schedule_execution_keep_recent', '20', 'Most recent executions of each schedule that cleanup always keeps', 'integer')
@@ -0,0 +1,4 @@
+-- Deadline for each analytics request, on top of the server-wide request
+-- timeout. Sections still running at the deadline are left out of the response.
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('analytics_timeout_seconds', '10', 'Seconds an analytics request may run before its queries are canceled, 0 for no limit', 'integer')
@@ -0,0 +1,4 @@
+-- Largest page_size or limit a list or analytics request may ask for. Larger
+-- requests are refused with a 400 rather than scanning without bound.
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('max_page_size', '100', 'Largest page_size or limit accepted by list and analytics endpoints', 'integer')
@@ -0,0 +1,4 @@
+-- Payload templates for webhooks. A webhook with a payload template sends the
+-- template rendered against its payload instead of the payload itself, so a
+-- receiver gets the JSON body it expects. Empty sends the payload unchanged.
+ALTER TABLE webhooks ADD COLUMN payload_template TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Completion percentage at a monitor's last check, so a check can tell when
+-- an artist has dropped below its completion goal. NULL until first checked.
+ALTER TABLE monitors ADD COLUMN last_completion_pct REAL
@@ -0,0 +1,20 @@
+-- Reports generated through POST /api/v1/analytics/reports, kept so they can
+-- be listed and fetched again later. content holds the report serialized in
+-- format.
+CREATE TABLE IF NOT EXISTS analytics_reports (
+    id TEXT PRIMARY KEY,
+    report_type TEXT NOT NULL,
+    timeframe TEXT NOT NULL DEFAULT '',
+    format TEXT NOT NULL DEFAULT 'json',
+    content TEXT NOT NULL,
+    generated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_reports_generated ON analytics_reports(generated_at);
+
+-- Retention for generated analytics reports removed by the maintenance
+-- cleanup. Reports older than the retention are removed, except the most
+-- recent ones, which are always kept.
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('report_retention_days', '30', 'Days to keep generated analytics reports before cleanup removes them', 'integer'),
+    ('report_keep_recent', '20', 'Most recent analytics reports that cleanup always keeps', 'integer')
@@ -0,0 +1,3 @@
+-- Longest a job may run before the reaper fails it as stuck
+INSERT OR IGNORE INTO system_config (key, value, description, data_type) VALUES
+    ('job_max_runtime_minutes', '360', 'Minutes a job may run before it is failed as timed out, 0 to never time out', 'integer')
@@ -0,0 +1,233 @@
+// Package detection works out which of a monitored artist's catalog shows
+// are downloaded, missing or failed. It is shared by the detector and the
+// API's sync service so both write shows.json the same way.
+package detection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/folders"
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// Lister lists the entries of an artist folder on the remote store
+type Lister func(artistFolder string) ([]string, error)
+
+// SSHLister lists an artist folder on tootie over SSH. A folder that doesn't
+// exist yet is reported as os.ErrNotExist.
+func SSHLister(artistFolder string) ([]string, error) {
+	cmd := exec.Command("ssh", "tootie", "ls", "-1", fmt.Sprintf("'%s'", artistFolder))
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), "No such file or directory") {
+			return nil, fmt.Errorf("listing %s: %w", artistFolder, os.ErrNotExist)
+		}
+		return nil, fmt.Errorf("ssh ls %s: %v: %s", artistFolder, err, strings.TrimSpace(string(output)))
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n"), nil
+}
+
+// LoadMonitorConfig reads monitor_config.json
+func LoadMonitorConfig(filename string) (*models.MonitorConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var config models.MonitorConfig
+	err = json.Unmarshal(data, &config)
+	return &config, err
+}
+
+// Artist rebuilds an artist's shows.json entry from the catalog and the show
+// folders on the remote store. previous is the artist's current entry, whose
+// retry tracking and recorded formats carry over for shows still relevant.
+func Artist(source catalog.CatalogSource, artist models.Artist, patterns []models.FolderPattern,
+	previous models.ArtistShowData, list Lister) (models.ArtistShowData, error) {
+	// Get available shows from cached catalog
+	availableShows, err := source.GetShowsForArtist(artist.Artist)
+	if err != nil {
+		return models.ArtistShowData{}, fmt.Errorf("getting shows for %s: %w", artist.Artist, err)
+	}
+
+	availableIDs := make([]int, len(availableShows))
+	for i, show := range availableShows {
+		availableIDs[i] = show.ContainerID
+	}
+
+	downloadedIDs, unmatched, err := DownloadedShows(availableShows, artist.ArtistFolder, artist.Artist, patterns, list)
+	if err != nil {
+		return models.ArtistShowData{}, fmt.Errorf("scanning downloaded shows for %s: %w", artist.Artist, err)
+	}
+
+	// Keep the monitor's retry tracking, dropping shows that have since been downloaded
+	failedIDs, attempts := PruneDownloadedAttempts(previous, downloadedIDs)
+	formats := KeepDownloadedFormats(previous, downloadedIDs)
+
+	// Calculate missing shows; shows the monitor gave up on are reported as failed instead
+	skipIDs := append(append([]int{}, downloadedIDs...), failedIDs...)
+	missingIDs := FindMissingShows(availableIDs, skipIDs)
+
+	return models.ArtistShowData{
+		ArtistID:   artist.ID,
+		Downloaded: downloadedIDs,
+		Available:  availableIDs,
+		Missing:    missingIDs,
+		Failed:     failedIDs,
+		Attempts:   attempts,
+		Unmatched:  unmatched,
+		Formats:    formats,
+	}, nil
+}
+
+// DownloadedShows matches the artist's folders on the remote store to the
+// container IDs of their catalog shows. Folders that match no catalog show
+// are returned as unmatched.
+func DownloadedShows(shows []catalog.ShowContainer, artistFolder, artistName string,
+	patterns []models.FolderPattern, list Lister) ([]int, []string, error) {
+	// Recognized folder naming schemes, from monitor_config.json or the defaults
+	matcher, err := folders.NewMatcher(patterns, artistName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// An artist folder that doesn't exist yet holds no shows. Any other
+	// failure is returned, so a transient ssh error isn't taken for an empty
+	// folder and the caller keeps the artist's previous entry.
+	entries, err := list(artistFolder)
+	if errors.Is(err, os.ErrNotExist) {
+		return []int{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Parse directory names, skipping hidden files and artwork/notes
+	var showFolders []string
+	for _, folder := range entries {
+		if folder == "" || strings.HasPrefix(folder, ".") || strings.HasSuffix(folder, ".nfo") ||
+			strings.HasSuffix(folder, ".jpg") || strings.HasSuffix(folder, ".png") ||
+			strings.HasSuffix(folder, ".md") {
+			continue
+		}
+		showFolders = append(showFolders, folder)
+	}
+
+	// Create a map of normalized dates to container IDs for fast lookup
+	dateToContainerID := make(map[string]int)
+	for _, show := range shows {
+		if date, ok := folders.CatalogDate(show.PerformanceDate, show.PerformanceDateShort); ok {
+			dateToContainerID[date] = show.ContainerID
+		}
+	}
+
+	// Parse each folder name and try to match to a container ID
+	downloadedIDs := []int{}
+	var unmatched []string
+	foundCount := 0
+	for _, folder := range showFolders {
+		dates := matcher.Dates(folder)
+		if len(dates) == 0 {
+			// Folder doesn't match expected patterns
+			unmatched = append(unmatched, folder)
+			continue
+		}
+		foundCount++
+
+		matched := false
+		for _, date := range dates {
+			if containerID, exists := dateToContainerID[date]; exists {
+				downloadedIDs = append(downloadedIDs, containerID)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, folder)
+		}
+	}
+
+	if foundCount > 0 {
+		log.Printf("Found %d show folders for %s", foundCount, artistName)
+	}
+
+	log.Printf("Successfully matched %d folders to container IDs for %s", len(downloadedIDs), artistName)
+	return downloadedIDs, unmatched, nil
+}
+
+// FindMissingShows returns the available shows not in downloaded, sorted
+func FindMissingShows(available, downloaded []int) []int {
+	// Convert downloaded to map for faster lookup
+	downloadedMap := make(map[int]bool)
+	for _, id := range downloaded {
+		downloadedMap[id] = true
+	}
+
+	// Find missing shows
+	var missing []int
+	for _, id := range available {
+		if !downloadedMap[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	// Sort for consistent output
+	sort.Ints(missing)
+	return missing
+}
+
+// PruneDownloadedAttempts returns the artist's failed shows and attempt records,
+// minus any show that is now downloaded
+func PruneDownloadedAttempts(previous models.ArtistShowData, downloaded []int) ([]int, map[int]models.DownloadAttempt) {
+	downloadedMap := make(map[int]bool)
+	for _, id := range downloaded {
+		downloadedMap[id] = true
+	}
+
+	var failed []int
+	for _, id := range previous.Failed {
+		if !downloadedMap[id] {
+			failed = append(failed, id)
+		}
+	}
+
+	var attempts map[int]models.DownloadAttempt
+	for id, attempt := range previous.Attempts {
+		if downloadedMap[id] {
+			continue
+		}
+		if attempts == nil {
+			attempts = make(map[int]models.DownloadAttempt)
+		}
+		attempts[id] = attempt
+	}
+
+	return failed, attempts
+}
+
+// KeepDownloadedFormats returns the artist's recorded download formats for
+// the shows still on disk
+func KeepDownloadedFormats(previous models.ArtistShowData, downloaded []int) map[int]string {
+	var formats map[int]string
+	for _, id := range downloaded {
+		format, ok := previous.Formats[id]
+		if !ok {
+			continue
+		}
+		if formats == nil {
+			formats = make(map[int]string)
+		}
+		formats[id] = format
+	}
+	return formats
+}
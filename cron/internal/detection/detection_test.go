@@ -0,0 +1,127 @@
+package detection
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+)
+
+func TestDownloadedShows(t *testing.T) {
+	shows := []catalog.ShowContainer{
+		{ContainerID: 100, PerformanceDate: "9/2/2022"},
+		{ContainerID: 200, PerformanceDate: "12/31/2019"},
+		{ContainerID: 300, PerformanceDate: "7/4/2023"},
+	}
+	list := func(artistFolder string) ([]string, error) {
+		assert.Equal(t, "/music/Billy Strings", artistFolder)
+		return []string{
+			"09_02_22 Red Rocks Amphitheatre",
+			"Billy Strings - 12_31_19 Tabernacle",
+			"01_01_20 Not In Catalog",
+			"artwork",
+			".DS_Store",
+			"folder.jpg",
+		}, nil
+	}
+
+	downloaded, unmatched, err := DownloadedShows(shows, "/music/Billy Strings", "Billy Strings", nil, list)
+	require.NoError(t, err)
+	assert.Equal(t, []int{100, 200}, downloaded)
+	assert.Equal(t, []string{"01_01_20 Not In Catalog", "artwork"}, unmatched)
+}
+
+func TestDownloadedShows_TwoDigitYears(t *testing.T) {
+	shows := []catalog.ShowContainer{
+		{ContainerID: 1965, PerformanceDate: "12/4/1965"},
+		{ContainerID: 1968, PerformanceDate: "2/14/1968"},
+		{ContainerID: 1977, PerformanceDate: "5/8/1977"},
+		{ContainerID: 2000, PerformanceDate: "6/28/2000"},
+		{ContainerID: 2023, PerformanceDate: "7/1/2023"},
+	}
+	list := func(string) ([]string, error) {
+		return []string{"12_04_65", "02_14_68", "05_08_77", "06_28_00", "07_01_23", "07_01_33"}, nil
+	}
+
+	downloaded, unmatched, err := DownloadedShows(shows, "/music/Grateful Dead", "Grateful Dead", nil, list)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1965, 1968, 1977, 2000, 2023}, downloaded)
+	assert.Equal(t, []string{"07_01_33"}, unmatched)
+}
+
+func TestArtist_StaticCatalog(t *testing.T) {
+	source := catalog.NewStaticSource([]catalog.ShowContainer{
+		{ContainerID: 100, ArtistName: "Billy Strings", PerformanceDate: "9/2/2022"},
+		{ContainerID: 300, ArtistName: "Billy Strings", PerformanceDate: "7/4/2023"},
+		{ContainerID: 400, ArtistName: "Goose", PerformanceDate: "7/4/2023"},
+	})
+	list := func(string) ([]string, error) { return []string{"09_02_22 Red Rocks Amphitheatre"}, nil }
+	artist := models.Artist{ID: 1, Artist: "Billy Strings", ArtistFolder: "/music/Billy Strings"}
+
+	data, err := Artist(source, artist, nil, models.ArtistShowData{}, list)
+	require.NoError(t, err)
+	assert.Equal(t, []int{300, 100}, data.Available)
+	assert.Equal(t, []int{100}, data.Downloaded)
+	assert.Equal(t, []int{300}, data.Missing)
+}
+
+func TestArtist_FixtureCatalogGap(t *testing.T) {
+	list := func(string) ([]string, error) {
+		return []string{"12_31_97 Madison Square Garden", "07_14_23 Merriweather Post Pavilion"}, nil
+	}
+	artist := models.Artist{ID: testutil.PhishID, Artist: "Phish", ArtistFolder: "/music/Phish"}
+
+	data, err := Artist(testutil.Catalog(), artist, nil, models.ArtistShowData{}, list)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{67892, 67893}, data.Downloaded)
+	assert.Equal(t, []int{67895}, data.Missing)
+	assert.Empty(t, data.Unmatched)
+}
+
+func TestDownloadedShows_ListError(t *testing.T) {
+	list := func(string) ([]string, error) { return nil, errors.New("ssh: connect to host tootie") }
+
+	_, _, err := DownloadedShows(nil, "/music/Goose", "Goose", nil, list)
+	assert.Error(t, err)
+
+	// A folder that doesn't exist yet has no shows
+	list = func(string) ([]string, error) { return nil, fmt.Errorf("listing: %w", os.ErrNotExist) }
+	downloaded, unmatched, err := DownloadedShows(nil, "/music/Goose", "Goose", nil, list)
+	require.NoError(t, err)
+	assert.Empty(t, downloaded)
+	assert.Empty(t, unmatched)
+}
+
+func TestFindMissingShows(t *testing.T) {
+	assert.Equal(t, []int{1, 3}, FindMissingShows([]int{3, 2, 1}, []int{2, 4}))
+	assert.Nil(t, FindMissingShows([]int{1}, []int{1}))
+}
+
+func TestPruneDownloadedAttempts(t *testing.T) {
+	previous := models.ArtistShowData{
+		Failed: []int{1, 2},
+		Attempts: map[int]models.DownloadAttempt{
+			1: {Count: 3},
+			2: {Count: 3},
+			5: {Count: 1},
+		},
+	}
+
+	failed, attempts := PruneDownloadedAttempts(previous, []int{2})
+	assert.Equal(t, []int{1}, failed)
+	assert.Equal(t, map[int]models.DownloadAttempt{1: {Count: 3}, 5: {Count: 1}}, attempts)
+}
+
+func TestKeepDownloadedFormats(t *testing.T) {
+	previous := models.ArtistShowData{Formats: map[int]string{1: "flac", 2: "aac"}}
+
+	assert.Equal(t, map[int]string{2: "aac"}, KeepDownloadedFormats(previous, []int{2, 3}))
+	assert.Nil(t, KeepDownloadedFormats(previous, []int{3}))
+}
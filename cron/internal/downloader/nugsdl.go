@@ -0,0 +1,87 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
+)
+
+// abortGracePeriod is how long nugs-dl has to exit after SIGTERM before it is killed
+const abortGracePeriod = 10 * time.Second
+
+// ReleaseURL returns the play.nugs.net URL for a catalog container
+func ReleaseURL(containerID int) string {
+	return fmt.Sprintf("https://play.nugs.net/release/%d", containerID)
+}
+
+// ResolveBinary returns the nugs-dl binary to run, verifying it exists and is executable
+func ResolveBinary(configured string) (string, error) {
+	if configured == "" {
+		configured = paths.Bin("nugs-dl")
+	} else if !strings.ContainsRune(configured, filepath.Separator) {
+		// Bare command name, e.g. a system-installed nugs-dl
+		path, err := exec.LookPath(configured)
+		if err != nil {
+			return "", fmt.Errorf("%s not found on PATH (set nugsDlPath in config.json)", configured)
+		}
+		return path, nil
+	} else {
+		configured = paths.Resolve(configured)
+	}
+
+	info, err := os.Stat(configured)
+	if err != nil {
+		return "", fmt.Errorf("%s does not exist (set nugsDlPath in config.json)", configured)
+	}
+	if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("%s is not an executable file", configured)
+	}
+
+	return configured, nil
+}
+
+// ArtistPath returns the local output directory for an artist's downloads
+func ArtistPath(config *models.Config, artistName string) string {
+	return filepath.Join(config.OutPath, SanitizeFilename(artistName))
+}
+
+// Download runs nugs-dl for a single release, writing into outDir.
+// The combined output is returned so callers can log it on failure.
+// Cancelling ctx sends nugs-dl SIGTERM, then kills it if it hasn't exited after abortGracePeriod.
+func Download(ctx context.Context, binary string, config *models.Config, outDir string, containerID int) ([]byte, error) {
+	args := []string{"-f", fmt.Sprintf("%d", config.Format), "-o", outDir}
+	args = append(args, config.NugsDLArgs...)
+	args = append(args, ReleaseURL(containerID))
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = abortGracePeriod
+
+	return cmd.CombinedOutput()
+}
+
+// SanitizeFilename replaces characters that might cause issues in filenames
+func SanitizeFilename(name string) string {
+	replacer := strings.NewReplacer(
+		"/", "-",
+		"\\", "-",
+		":", "-",
+		"*", "-",
+		"?", "-",
+		"\"", "-",
+		"<", "-",
+		">", "-",
+		"|", "-",
+	)
+	return replacer.Replace(name)
+}
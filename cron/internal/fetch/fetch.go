@@ -0,0 +1,705 @@
+// Package fetch downloads the new shows of every monitored artist with
+// nugs-dl and syncs them to tootie. It is the monitor's run, shared by the
+// monitor CLI and the API's fetch-new job, and also downloads the shows
+// picked by catalog_manager search --download.
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/api"
+	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/detection"
+	"github.com/jmagar/nugs/cron/internal/downloader"
+	"github.com/jmagar/nugs/cron/internal/folders"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/nugsconfig"
+	"github.com/jmagar/nugs/cron/internal/paths"
+	"github.com/jmagar/nugs/cron/internal/quality"
+	"github.com/jmagar/nugs/cron/internal/showsdata"
+)
+
+// Options control a fetch run
+type Options struct {
+	// Limit caps the shows downloaded, new and upgrades together. 0 means no limit.
+	Limit int
+	// AllowEmptyShows starts from empty shows data if shows.json is corrupt
+	AllowEmptyShows bool
+}
+
+// Result counts what a fetch run did
+type Result struct {
+	Downloaded      int  `json:"downloaded"`
+	Upgraded        int  `json:"upgraded"`
+	AlreadyPresent  int  `json:"already_present"`
+	Errors          int  `json:"errors"`
+	MarkedFailed    int  `json:"marked_failed"`
+	SkippedForLimit int  `json:"skipped_for_limit"`
+	Interrupted     bool `json:"interrupted"`
+}
+
+// Run checks every monitored artist for shows missing from shows.json and
+// tootie, downloads them and syncs them to tootie, then saves shows.json.
+// Cancelling ctx aborts the running download, removes its partial files and
+// saves the progress so far, with Result.Interrupted set. The caller holds
+// the monitor lock.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	s, err := newSession(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the catalog source (no authentication needed for catalog lookups)
+	catalogSource, err := catalog.NewSource(s.config)
+	if err != nil {
+		return nil, fmt.Errorf("loading catalog: %w", err)
+	}
+
+	log.Printf("Checking monitored artists for new shows...")
+
+	// Artists are checked in monitor_config.json order and each artist's shows
+	// newest first, so the limit keeps the highest-priority downloads
+
+	// Check each monitored artist for new shows
+artists:
+	for _, artist := range s.monitorConfig.Artists {
+		if ctx.Err() != nil {
+			break
+		}
+		if !artist.Monitor {
+			continue
+		}
+		log.Printf("\nChecking %s (ID: %d)...", artist.Artist, artist.ID)
+
+		shows, err := catalogSource.GetShowsForArtist(artist.Artist)
+		if err != nil {
+			log.Printf("Error getting shows for %s: %v", artist.Artist, err)
+			continue
+		}
+
+		// Check all shows to find missing ones (no date restriction), and with
+		// auto_upgrade the downloaded ones held below the ladder's best format
+		var newShows, upgrades []catalog.ShowContainer
+
+		for _, show := range shows {
+			switch {
+			case isShowDownloaded(artist.Artist, show.ContainerID, s.showsData):
+				if s.monitorConfig.AutoUpgrade && s.ladder.NeedsUpgrade(s.showsData.Artists[artist.Artist].Formats[show.ContainerID]) {
+					upgrades = append(upgrades, show)
+				}
+			case !isShowFailed(artist.Artist, show.ContainerID, s.showsData):
+				newShows = append(newShows, show)
+			}
+		}
+
+		if len(newShows) == 0 && len(upgrades) == 0 {
+			log.Printf("No new shows found for %s", artist.Artist)
+			continue
+		}
+
+		log.Printf("Found %d new shows for %s", len(newShows), artist.Artist)
+		if len(upgrades) > 0 {
+			log.Printf("Found %d shows to upgrade to %s for %s", len(upgrades), s.ladder.Best(), artist.Artist)
+		}
+
+		// shows.json can be stale if the detector hasn't run since the last download,
+		// so check what is already on tootie before downloading anything
+		remoteFolders, err := getRemoteShowFolders(artist.ArtistFolder, artist.Artist, s.monitorConfig.FolderPatterns)
+		if err != nil {
+			log.Printf("Warning: could not list remote folder for %s: %v", artist.Artist, err)
+		}
+
+		// Download new shows
+		if !s.downloadNewShows(ctx, artist, newShows, remoteFolders) {
+			break artists
+		}
+
+		// Upgrades come after new shows so the limit favours filling gaps
+		for _, show := range upgrades {
+			if ctx.Err() != nil {
+				break artists
+			}
+
+			date, _ := folders.CatalogDate(show.PerformanceDate, show.PerformanceDateShort)
+			remoteFolder := remoteFolders[date]
+			if remoteFolder == "" {
+				log.Printf("Show %d (%s) not found on tootie, skipping upgrade", show.ContainerID, show.PerformanceDateShort)
+				continue
+			}
+
+			if !s.takeSlot() {
+				continue
+			}
+
+			held := s.showsData.Artists[artist.Artist].Formats[show.ContainerID]
+			log.Printf("Upgrading %s to %s: %s - %s, %s %s", held, s.format, show.PerformanceDateShort,
+				show.VenueName, show.VenueCity, show.VenueState)
+
+			if !upgradeShow(ctx, s.nugsDLPath, s.config, artist, show.ContainerID, remoteFolder) {
+				if ctx.Err() != nil {
+					break artists
+				}
+				s.result.Errors++
+				continue
+			}
+
+			recordShowFormat(artist.Artist, show.ContainerID, s.format, s.showsData)
+			s.result.Upgraded++
+		}
+	}
+
+	return s.finish(ctx)
+}
+
+// Shows downloads the given catalog shows the way Run downloads new ones:
+// shows already in shows.json or on tootie are skipped, the rest are
+// downloaded, synced to the artist folder on tootie and recorded in
+// shows.json. The artist folder comes from monitor_config.json, so shows of
+// artists missing from it are counted as errors. Shows marked as failed are
+// skipped. The caller holds the monitor lock.
+func Shows(ctx context.Context, shows []catalog.ShowContainer, opts Options) (*Result, error) {
+	s, err := newSession(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the shows by artist, keeping the order they were given in
+	var order []string
+	byArtist := make(map[string][]catalog.ShowContainer)
+	for _, show := range shows {
+		if _, seen := byArtist[show.ArtistName]; !seen {
+			order = append(order, show.ArtistName)
+		}
+		byArtist[show.ArtistName] = append(byArtist[show.ArtistName], show)
+	}
+
+	for _, name := range order {
+		if ctx.Err() != nil {
+			break
+		}
+
+		artist, ok := s.artist(name)
+		if !ok {
+			log.Printf("%s has no artist_folder in monitor_config.json, skipping %d shows", name, len(byArtist[name]))
+			s.result.Errors += len(byArtist[name])
+			continue
+		}
+
+		var newShows []catalog.ShowContainer
+		for _, show := range byArtist[name] {
+			switch {
+			case isShowDownloaded(artist.Artist, show.ContainerID, s.showsData):
+				log.Printf("Show %d (%s) already downloaded", show.ContainerID, show.PerformanceDateShort)
+				s.result.AlreadyPresent++
+			case isShowFailed(artist.Artist, show.ContainerID, s.showsData):
+				log.Printf("Show %d (%s) is marked as failed, skipping", show.ContainerID, show.PerformanceDateShort)
+			default:
+				newShows = append(newShows, show)
+			}
+		}
+		if len(newShows) == 0 {
+			continue
+		}
+
+		remoteFolders, err := getRemoteShowFolders(artist.ArtistFolder, artist.Artist, s.monitorConfig.FolderPatterns)
+		if err != nil {
+			log.Printf("Warning: could not list remote folder for %s: %v", artist.Artist, err)
+		}
+
+		if !s.downloadNewShows(ctx, artist, newShows, remoteFolders) {
+			break
+		}
+	}
+
+	return s.finish(ctx)
+}
+
+// session is the configuration and shows data loaded for one fetch run,
+// along with its progress
+type session struct {
+	opts          Options
+	config        *models.Config
+	nugsDLPath    string
+	monitorConfig *models.MonitorConfig
+	ladder        *quality.Ladder
+	format        string
+	showsData     *models.ShowsData
+
+	result        *Result
+	downloadCount int
+}
+
+// newSession loads the configs, nugs-dl and shows.json needed before
+// downloading anything
+func newSession(opts Options) (*session, error) {
+	// Load main config
+	config, err := nugsconfig.Load(paths.Config("config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+
+	// Locate the downloader before doing any work
+	nugsDLPath, err := downloader.ResolveBinary(config.NugsDLPath)
+	if err != nil {
+		return nil, fmt.Errorf("locating nugs-dl: %w", err)
+	}
+
+	// Load monitor config
+	monitorConfig, err := detection.LoadMonitorConfig(paths.Config("monitor_config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("loading monitor config: %w", err)
+	}
+	if _, err := folders.NewMatcher(monitorConfig.FolderPatterns, ""); err != nil {
+		return nil, fmt.Errorf("monitor config folder_patterns: %w", err)
+	}
+	ladder, err := quality.NewLadder(monitorConfig.QualityLadder)
+	if err != nil {
+		return nil, fmt.Errorf("monitor config quality_ladder: %w", err)
+	}
+
+	// With a quality ladder, shows are downloaded in its best format
+	if !ladder.Empty() {
+		ladderConfig := *config
+		ladderConfig.Format = quality.Formats[ladder.Best()]
+		config = &ladderConfig
+	}
+
+	// Load shows data, refusing to continue on a corrupt file unless told to
+	showsData, err := showsdata.Load(paths.Data("shows.json"), opts.AllowEmptyShows)
+	if err != nil {
+		return nil, fmt.Errorf("loading shows data: %w", err)
+	}
+
+	return &session{
+		opts:          opts,
+		config:        config,
+		nugsDLPath:    nugsDLPath,
+		monitorConfig: monitorConfig,
+		ladder:        ladder,
+		format:        quality.FormatName(config.Format),
+		showsData:     showsData,
+		result:        &Result{},
+	}, nil
+}
+
+// artist finds an artist in the monitor config by name
+func (s *session) artist(name string) (models.Artist, bool) {
+	for _, artist := range s.monitorConfig.Artists {
+		if strings.EqualFold(artist.Artist, name) {
+			return artist, true
+		}
+	}
+	return models.Artist{}, false
+}
+
+// takeSlot counts a download against the limit, or counts the show as
+// skipped once the limit is reached
+func (s *session) takeSlot() bool {
+	if s.opts.Limit > 0 && s.downloadCount >= s.opts.Limit {
+		s.result.SkippedForLimit++
+		return false
+	}
+	s.downloadCount++
+	return true
+}
+
+// downloadNewShows downloads each show not already on tootie, syncs it to
+// the artist folder and records it in shows.json. Returns false if ctx was
+// cancelled.
+func (s *session) downloadNewShows(ctx context.Context, artist models.Artist, shows []catalog.ShowContainer, remoteFolders map[string]string) bool {
+	for _, show := range shows {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if date, ok := folders.CatalogDate(show.PerformanceDate, show.PerformanceDateShort); ok && remoteFolders[date] != "" {
+			log.Printf("Show %d (%s) already present on tootie, recording as downloaded",
+				show.ContainerID, show.PerformanceDateShort)
+			markShowDownloaded(artist.Artist, show.ContainerID, s.showsData)
+			s.result.AlreadyPresent++
+			continue
+		}
+
+		if !s.takeSlot() {
+			continue
+		}
+
+		log.Printf("Downloading: %s - %s, %s %s",
+			show.PerformanceDateShort, show.VenueName, show.VenueCity, show.VenueState)
+
+		// Create API client only when we need to download
+		apiClient := api.NewSafeAPIClient()
+		err := apiClient.AuthenticateAccounts(s.config.NugsAccounts())
+		if err != nil {
+			log.Printf("Authentication failed for download: %v", err)
+			s.result.Errors++
+			continue
+		}
+
+		// Create artist-specific output directory
+		artistPath := downloader.ArtistPath(s.config, artist.Artist)
+
+		// Run nugs-dl command
+		existing := listEntries(artistPath)
+		output, err := downloader.Download(ctx, s.nugsDLPath, s.config, artistPath, show.ContainerID)
+		if ctx.Err() != nil {
+			// Don't leave a half-downloaded show behind for the next rsync to pick up
+			log.Printf("Interrupted while downloading show %d, removing partial files", show.ContainerID)
+			removePartialDownload(artistPath, existing)
+			return false
+		}
+		if err != nil {
+			log.Printf("Error downloading show %d: %v\nOutput: %s\n",
+				show.ContainerID, err, string(output))
+			s.result.Errors++
+			if recordFailedAttempt(artist.Artist, show.ContainerID, err, output, s.config.GetMaxDownloadAttempts(), s.showsData) {
+				log.Printf("Show %d failed %d times, marking as failed", show.ContainerID, s.config.GetMaxDownloadAttempts())
+				s.result.MarkedFailed++
+			}
+			continue
+		}
+
+		log.Printf("Successfully downloaded show %d", show.ContainerID)
+
+		// Rsync to tootie
+		err = rsyncToTootie(artistPath, artist.ArtistFolder)
+		if err != nil {
+			log.Printf("Error syncing show %d to tootie: %v", show.ContainerID, err)
+			s.result.Errors++
+			continue
+		}
+
+		log.Printf("Successfully synced show %d to tootie", show.ContainerID)
+
+		// Clean up local files
+		err = cleanupLocalFiles(artistPath)
+		if err != nil {
+			log.Printf("Warning: Could not cleanup local files: %v", err)
+		}
+
+		// Mark as downloaded
+		markShowDownloaded(artist.Artist, show.ContainerID, s.showsData)
+		recordShowFormat(artist.Artist, show.ContainerID, s.format, s.showsData)
+		s.result.Downloaded++
+	}
+	return true
+}
+
+// finish saves shows.json and returns the run's result
+func (s *session) finish(ctx context.Context) (*Result, error) {
+	// Save updated shows data
+	if err := showsdata.Save(paths.Data("shows.json"), s.showsData); err != nil {
+		return nil, fmt.Errorf("saving shows data: %w", err)
+	}
+
+	s.result.Interrupted = ctx.Err() != nil
+	return s.result, nil
+}
+
+func isShowDownloaded(artistName string, containerID int, shows *models.ShowsData) bool {
+	artistData, exists := shows.Artists[artistName]
+	if !exists {
+		return false
+	}
+
+	for _, id := range artistData.Downloaded {
+		if id == containerID {
+			return true
+		}
+	}
+	return false
+}
+
+func markShowDownloaded(artistName string, containerID int, shows *models.ShowsData) {
+	if shows.Artists == nil {
+		shows.Artists = make(map[string]models.ArtistShowData)
+	}
+
+	artistData := shows.Artists[artistName]
+	artistData.Downloaded = append(artistData.Downloaded, containerID)
+	delete(artistData.Attempts, containerID)
+	shows.Artists[artistName] = artistData
+}
+
+// recordShowFormat notes the format a show was downloaded in. An unknown
+// format leaves the show unrecorded.
+func recordShowFormat(artistName string, containerID int, format string, shows *models.ShowsData) {
+	if format == "" {
+		return
+	}
+
+	artistData := shows.Artists[artistName]
+	if artistData.Formats == nil {
+		artistData.Formats = make(map[int]string)
+	}
+	artistData.Formats[containerID] = format
+	shows.Artists[artistName] = artistData
+}
+
+// upgradeShow downloads a show again in config's format and replaces
+// remoteFolder on tootie with it. The new copy is uploaded to a staging folder
+// next to the old one and only swapped in once it is complete, so the archive
+// always holds one of them. Returns false if the upgrade didn't complete.
+func upgradeShow(ctx context.Context, nugsDLPath string, config *models.Config, artist models.Artist, containerID int, remoteFolder string) bool {
+	apiClient := api.NewSafeAPIClient()
+	if err := apiClient.AuthenticateAccounts(config.NugsAccounts()); err != nil {
+		log.Printf("Authentication failed for upgrade: %v", err)
+		return false
+	}
+
+	artistPath := downloader.ArtistPath(config, artist.Artist)
+
+	existing := listEntries(artistPath)
+	output, err := downloader.Download(ctx, nugsDLPath, config, artistPath, containerID)
+	if ctx.Err() != nil {
+		log.Printf("Interrupted while upgrading show %d, removing partial files", containerID)
+		removePartialDownload(artistPath, existing)
+		return false
+	}
+	if err != nil {
+		// The old copy is still in place, so the next run simply tries again
+		log.Printf("Error upgrading show %d: %v\nOutput: %s\n", containerID, err, string(output))
+		removePartialDownload(artistPath, existing)
+		return false
+	}
+
+	var downloaded []string
+	for name := range listEntries(artistPath) {
+		if !existing[name] {
+			downloaded = append(downloaded, name)
+		}
+	}
+	if len(downloaded) == 0 {
+		log.Printf("Upgrade of show %d downloaded nothing", containerID)
+		return false
+	}
+
+	// Until the swap, the old copy stays where it is. A failed upload leaves
+	// it alone and the next run tries the upgrade again.
+	staging := fmt.Sprintf(".upgrade-%d", containerID)
+	if err := rsyncEntriesToTootie(artistPath, downloaded, filepath.Join(artist.ArtistFolder, staging)); err != nil {
+		log.Printf("Error syncing upgraded show %d to tootie: %v", containerID, err)
+		if err := removeRemoteFolder(artist.ArtistFolder, staging); err != nil {
+			log.Printf("Warning: could not remove staged upgrade of show %d: %v", containerID, err)
+		}
+		removePartialDownload(artistPath, existing)
+		return false
+	}
+
+	args, err := remoteSwapArgs(artist.ArtistFolder, staging, remoteFolder)
+	if err == nil {
+		var swapOutput []byte
+		if swapOutput, err = exec.Command("ssh", args...).CombinedOutput(); err != nil {
+			err = fmt.Errorf("ssh swap failed: %v\nOutput: %s", err, string(swapOutput))
+		}
+	}
+	if err != nil {
+		// Whatever the swap got through, the old copy is either in place or
+		// kept beside the staging folder
+		log.Printf("Error swapping upgraded show %d into place on tootie: %v", containerID, err)
+		removePartialDownload(artistPath, existing)
+		return false
+	}
+
+	// The upload kept the local copy; it is on tootie now
+	removePartialDownload(artistPath, existing)
+	log.Printf("Successfully upgraded show %d", containerID)
+	return true
+}
+
+func isShowFailed(artistName string, containerID int, shows *models.ShowsData) bool {
+	for _, id := range shows.Artists[artistName].Failed {
+		if id == containerID {
+			return true
+		}
+	}
+	return false
+}
+
+// recordFailedAttempt counts a failed download and returns true once the show
+// reaches maxAttempts and is moved to the artist's failed list
+func recordFailedAttempt(artistName string, containerID int, err error, output []byte, maxAttempts int, shows *models.ShowsData) bool {
+	if shows.Artists == nil {
+		shows.Artists = make(map[string]models.ArtistShowData)
+	}
+
+	artistData := shows.Artists[artistName]
+	if artistData.Attempts == nil {
+		artistData.Attempts = make(map[int]models.DownloadAttempt)
+	}
+
+	// The last line of nugs-dl output usually carries the actual reason
+	lastError := err.Error()
+	if lines := strings.Split(strings.TrimSpace(string(output)), "\n"); lines[len(lines)-1] != "" {
+		lastError = fmt.Sprintf("%v: %s", err, lines[len(lines)-1])
+	}
+
+	attempt := artistData.Attempts[containerID]
+	attempt.Count++
+	attempt.LastError = lastError
+	attempt.LastAttempt = time.Now().Format(time.RFC3339)
+	artistData.Attempts[containerID] = attempt
+
+	failed := attempt.Count >= maxAttempts
+	if failed {
+		artistData.Failed = append(artistData.Failed, containerID)
+	}
+
+	shows.Artists[artistName] = artistData
+	return failed
+}
+
+// getRemoteShowFolders lists the artist folder on tootie and returns the show
+// folders by normalized show date (YYYY-MM-DD), using the detector's folder patterns
+func getRemoteShowFolders(artistFolder, artistName string, patterns []models.FolderPattern) (map[string]string, error) {
+	dates := make(map[string]string)
+
+	matcher, err := folders.NewMatcher(patterns, artistName)
+	if err != nil {
+		return dates, err
+	}
+
+	entries, err := detection.SSHLister(artistFolder)
+	if err != nil {
+		return dates, fmt.Errorf("ssh ls failed: %v", err)
+	}
+
+	// A two-digit year is listed under both centuries; only the one the
+	// catalog has is ever looked up
+	for _, folder := range entries {
+		for _, date := range matcher.Dates(folder) {
+			dates[date] = folder
+		}
+	}
+
+	return dates, nil
+}
+
+// removeRemoteFolder deletes a show folder from the artist folder on tootie
+func removeRemoteFolder(artistFolder, folder string) error {
+	args, err := remoteRemoveArgs(artistFolder, folder)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("ssh", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ssh rm failed: %v\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// remoteRemoveArgs builds the ssh arguments for removing a show folder. The
+// remote shell re-parses the command line, so the path is single-quoted with
+// embedded quotes escaped, and a folder that would resolve to the artist
+// folder itself is refused.
+func remoteRemoveArgs(artistFolder, folder string) ([]string, error) {
+	if err := checkRemoteFolder(folder); err != nil {
+		return nil, err
+	}
+
+	return []string{"tootie", "rm", "-rf", "--", shellQuote(filepath.Join(artistFolder, folder))}, nil
+}
+
+// remoteSwapArgs builds the ssh arguments for replacing oldFolder with the
+// show folders uploaded to staging. The old copy is moved aside first and
+// only deleted once the new one is in place, so a failure at any step leaves
+// one of them in the artist folder.
+func remoteSwapArgs(artistFolder, staging, oldFolder string) ([]string, error) {
+	for _, folder := range []string{staging, oldFolder} {
+		if err := checkRemoteFolder(folder); err != nil {
+			return nil, err
+		}
+	}
+
+	replaced := shellQuote(staging + ".replaced")
+	script := strings.Join([]string{
+		"cd -- " + shellQuote(artistFolder),
+		"mv -- " + shellQuote(oldFolder) + " " + replaced,
+		"mv -- " + shellQuote(staging) + "/* .",
+		"rmdir -- " + shellQuote(staging),
+		"rm -rf -- " + replaced,
+	}, " && ")
+	return []string{"tootie", script}, nil
+}
+
+// checkRemoteFolder refuses a folder name that is empty or would resolve
+// outside the artist folder
+func checkRemoteFolder(folder string) error {
+	if strings.TrimSpace(folder) == "" || folder == "." || folder == ".." || strings.Contains(folder, "/") {
+		return fmt.Errorf("refusing remote folder %q", folder)
+	}
+	return nil
+}
+
+// shellQuote wraps value in single quotes for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func rsyncToTootie(localPath, remotePath string) error {
+	cmd := exec.Command("rsync", "-avP", "--remove-source-files",
+		localPath+"/",
+		fmt.Sprintf("tootie:%s/", remotePath))
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rsync failed: %v\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// rsyncEntriesToTootie uploads the named entries of localDir into remotePath,
+// creating it. The local copies are kept, so a failed upload can be retried.
+func rsyncEntriesToTootie(localDir string, names []string, remotePath string) error {
+	args := []string{"-avP"}
+	for _, name := range names {
+		args = append(args, filepath.Join(localDir, name))
+	}
+	args = append(args, fmt.Sprintf("tootie:%s/", remotePath))
+
+	output, err := exec.Command("rsync", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rsync failed: %v\nOutput: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// listEntries returns the names in dir, or an empty set if it doesn't exist yet
+func listEntries(dir string) map[string]bool {
+	names := make(map[string]bool)
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+	return names
+}
+
+// removePartialDownload deletes anything in dir that wasn't listed in existing
+func removePartialDownload(dir string, existing map[string]bool) {
+	for name := range listEntries(dir) {
+		if existing[name] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			log.Printf("Warning: could not remove partial download %s: %v", name, err)
+		}
+	}
+}
+
+func cleanupLocalFiles(localPath string) error {
+	// Remove empty directories after rsync
+	cmd := exec.Command("find", localPath, "-type", "d", "-empty", "-delete")
+	_, err := cmd.CombinedOutput()
+	return err
+}
@@ -0,0 +1,101 @@
+package fetch
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/paths"
+	"github.com/jmagar/nugs/cron/internal/showsdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteRemoveArgs_QuotesApostrophes(t *testing.T) {
+	args, err := remoteRemoveArgs("/mnt/music/Grateful Dead", "Grateful Dead - 1977-05-08 Barton Hall, Cornell's")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tootie", "rm", "-rf", "--",
+		`'/mnt/music/Grateful Dead/Grateful Dead - 1977-05-08 Barton Hall, Cornell'\''s'`}, args)
+
+	// The remote shell must see the whole path as a single word
+	output, err := exec.Command("sh", "-c", "printf '%s\\n' "+args[4]).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "/mnt/music/Grateful Dead/Grateful Dead - 1977-05-08 Barton Hall, Cornell's\n", string(output))
+}
+
+func TestRemoteRemoveArgs_RefusesArtistFolder(t *testing.T) {
+	for _, folder := range []string{"", "  ", ".", "..", "../Phish", "a/b"} {
+		_, err := remoteRemoveArgs("/mnt/music/Phish", folder)
+		assert.Error(t, err, folder)
+	}
+}
+
+func TestShows_SkipsDownloadedAndUnconfiguredArtists(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(paths.HomeEnv, home)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "configs"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "data"), 0755))
+	writeFile := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(home, name), []byte(content), 0644))
+	}
+	writeFile("configs/config.json", `{"nugsDlPath": "true"}`)
+	writeFile("configs/monitor_config.json", `{"artists": [{"id": 1, "artist": "Phish", "monitor": true, "artist_folder": "/mnt/music/Phish"}]}`)
+	writeFile("data/shows.json", `{"artists": {"Phish": {"downloaded": [100]}}}`)
+
+	result, err := Shows(context.Background(), []catalog.ShowContainer{
+		{ContainerID: 100, ArtistName: "phish", PerformanceDateShort: "12/31/99"},
+		{ContainerID: 200, ArtistName: "Goose", PerformanceDateShort: "8/1/23"},
+		{ContainerID: 201, ArtistName: "Goose", PerformanceDateShort: "8/2/23"},
+	}, Options{})
+	require.NoError(t, err)
+
+	// The downloaded show is matched to its artist despite the case, and
+	// Goose has no artist folder to sync to
+	assert.Equal(t, 1, result.AlreadyPresent)
+	assert.Equal(t, 2, result.Errors)
+	assert.Zero(t, result.Downloaded)
+	assert.False(t, result.Interrupted)
+
+	// shows.json is saved at the end of the run
+	showsData, err := showsdata.Load(paths.Data("shows.json"), false)
+	require.NoError(t, err)
+	assert.Equal(t, []int{100}, showsData.Artists["Phish"].Downloaded)
+}
+
+func TestRemoteSwapArgs_ReplacesOldCopy(t *testing.T) {
+	artistFolder := filepath.Join(t.TempDir(), "Grateful Dead")
+	old := "Grateful Dead - 1977-05-08 Cornell's"
+	require.NoError(t, os.MkdirAll(filepath.Join(artistFolder, old), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(artistFolder, old, "d1t01.mp3"), nil, 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(artistFolder, ".upgrade-100", old), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(artistFolder, ".upgrade-100", old, "d1t01.flac"), nil, 0644))
+
+	args, err := remoteSwapArgs(artistFolder, ".upgrade-100", old)
+	require.NoError(t, err)
+	require.Equal(t, "tootie", args[0])
+
+	// Run the script the remote shell would
+	output, err := exec.Command("sh", "-c", args[1]).CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	entries, err := os.ReadDir(artistFolder)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, old, entries[0].Name())
+	assert.FileExists(t, filepath.Join(artistFolder, old, "d1t01.flac"))
+	assert.NoFileExists(t, filepath.Join(artistFolder, old, "d1t01.mp3"))
+
+	// Without the old copy the swap stops before touching the staging folder
+	require.NoError(t, os.MkdirAll(filepath.Join(artistFolder, ".upgrade-101", "new"), 0755))
+	args, err = remoteSwapArgs(artistFolder, ".upgrade-101", "missing")
+	require.NoError(t, err)
+	assert.Error(t, exec.Command("sh", "-c", args[1]).Run())
+	assert.DirExists(t, filepath.Join(artistFolder, ".upgrade-101", "new"))
+
+	_, err = remoteSwapArgs(artistFolder, ".upgrade-101", "../Phish")
+	assert.Error(t, err)
+}
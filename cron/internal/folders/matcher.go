@@ -0,0 +1,139 @@
+package folders
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// dateLayout is the normalized form dates are compared in
+const dateLayout = "2006-01-02"
+
+// DefaultPatterns match the MM_DD_YY (newer shows) and "Artist Name - MM_DD_YY" (older shows) folder names
+var DefaultPatterns = []models.FolderPattern{
+	{Pattern: `^(\d{2})_(\d{2})_(\d{2})`, Month: 1, Day: 2, Year: 3},
+	{Pattern: `^{artist} - (\d{2})_(\d{2})_(\d{2})`, Month: 1, Day: 2, Year: 3},
+}
+
+type compiledPattern struct {
+	re               *regexp.Regexp
+	year, month, day int
+}
+
+// Matcher extracts normalized show dates from an artist's folder names
+type Matcher struct {
+	patterns []compiledPattern
+}
+
+// NewMatcher compiles the patterns for an artist, falling back to DefaultPatterns when none are given
+func NewMatcher(patterns []models.FolderPattern, artistName string) (*Matcher, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+
+	m := &Matcher{}
+	for _, p := range patterns {
+		expr := strings.ReplaceAll(p.Pattern, "{artist}", regexp.QuoteMeta(artistName))
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid folder pattern %q: %v", p.Pattern, err)
+		}
+
+		groups := re.NumSubexp()
+		for _, group := range []int{p.Year, p.Month, p.Day} {
+			if group < 1 || group > groups {
+				return nil, fmt.Errorf("folder pattern %q: capture group %d out of range (pattern has %d)", p.Pattern, group, groups)
+			}
+		}
+
+		m.patterns = append(m.patterns, compiledPattern{re: re, year: p.Year, month: p.Month, day: p.Day})
+	}
+
+	return m, nil
+}
+
+// Dates returns the normalized show dates (YYYY-MM-DD) a folder name may
+// refer to, using the first pattern that yields a valid date. A two-digit
+// year doesn't say which century it is in, so both the 19xx and 20xx dates
+// are returned and the catalog date that matches settles it; nugs has shows
+// from 1965 on, which no fixed pivot year covers.
+func (m *Matcher) Dates(folder string) []string {
+	for _, p := range m.patterns {
+		matches := p.re.FindStringSubmatch(folder)
+		if matches == nil {
+			continue
+		}
+
+		if dates := buildDates(matches[p.year], matches[p.month], matches[p.day]); len(dates) > 0 {
+			return dates
+		}
+	}
+	return nil
+}
+
+// CatalogDate normalizes a catalog show's date (M/D/YYYY, or MM/DD/YY as a fallback) to YYYY-MM-DD
+func CatalogDate(performanceDate, performanceDateShort string) (string, bool) {
+	if date, err := time.Parse("1/2/2006", performanceDate); err == nil {
+		return date.Format(dateLayout), true
+	}
+	if date, err := time.Parse("01/02/06", performanceDateShort); err == nil {
+		// Catalog shows have already been played, so a two-digit year that
+		// would be in the future is from the last century
+		if date.After(time.Now()) {
+			date = date.AddDate(-100, 0, 0)
+		}
+		return date.Format(dateLayout), true
+	}
+	return "", false
+}
+
+// buildDates returns the valid dates for the captured year, month and day,
+// in both centuries when the year has two digits
+func buildDates(yearStr, monthStr, dayStr string) []string {
+	if len(yearStr) > 2 {
+		if date, ok := buildDate(yearStr, monthStr, dayStr); ok {
+			return []string{date}
+		}
+		return nil
+	}
+
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		return nil
+	}
+
+	var dates []string
+	for _, century := range []int{1900, 2000} {
+		if date, ok := buildDate(strconv.Itoa(century+year), monthStr, dayStr); ok {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+func buildDate(yearStr, monthStr, dayStr string) (string, bool) {
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		return "", false
+	}
+	month, err := strconv.Atoi(monthStr)
+	if err != nil {
+		return "", false
+	}
+	day, err := strconv.Atoi(dayStr)
+	if err != nil {
+		return "", false
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(month) || date.Day() != day {
+		// Rolled over, e.g. 02/30
+		return "", false
+	}
+
+	return date.Format(dateLayout), true
+}
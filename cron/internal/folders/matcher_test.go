@@ -0,0 +1,102 @@
+package folders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+func TestMatcher_DefaultPatterns(t *testing.T) {
+	m, err := NewMatcher(nil, "Billy Strings")
+	require.NoError(t, err)
+
+	tests := []struct {
+		folder   string
+		expected []string
+	}{
+		{"09_02_22 Red Rocks Amphitheatre", []string{"1922-09-02", "2022-09-02"}},
+		{"Billy Strings - 12_31_19 Tabernacle", []string{"1919-12-31", "2019-12-31"}},
+		{"12_31_97", []string{"1997-12-31", "2097-12-31"}},
+		{"02_29_00 Leap Day", []string{"2000-02-29"}},
+		{"Goose - 12_31_19", nil},
+		{"02_30_22 Invalid Date", nil},
+		{"artwork", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			assert.Equal(t, tt.expected, m.Dates(tt.folder))
+		})
+	}
+}
+
+// Two-digit years resolve against the catalog in both centuries, including
+// the 1965-1968 shows a fixed 69 pivot would place in the 2060s
+func TestMatcher_TwoDigitYearCenturies(t *testing.T) {
+	m, err := NewMatcher(nil, "Grateful Dead")
+	require.NoError(t, err)
+
+	tests := []struct {
+		folder      string
+		catalogDate string
+	}{
+		{"12_04_65 Big Nig's House", "1965-12-04"},
+		{"01_08_66 Fillmore Auditorium", "1966-01-08"},
+		{"01_14_67 Golden Gate Park", "1967-01-14"},
+		{"02_14_68 Carousel Ballroom", "1968-02-14"},
+		{"Grateful Dead - 02_27_69 Fillmore West", "1969-02-27"},
+		{"06_28_00 Alpine Valley", "2000-06-28"},
+		{"07_05_09 Rothbury", "2009-07-05"},
+		{"07_01_23 Wrigley Field", "2023-07-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.folder, func(t *testing.T) {
+			assert.Contains(t, m.Dates(tt.folder), tt.catalogDate)
+		})
+	}
+}
+
+func TestMatcher_CustomPatterns(t *testing.T) {
+	m, err := NewMatcher([]models.FolderPattern{
+		{Pattern: `^(\d{4})-(\d{2})-(\d{2})`, Year: 1, Month: 2, Day: 3},
+		{Pattern: `^{artist} (\d{2})\.(\d{2})\.(\d{4})`, Month: 1, Day: 2, Year: 3},
+	}, "Phish")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1997-12-31"}, m.Dates("1997-12-31 Madison Square Garden"))
+	assert.Equal(t, []string{"1999-07-03"}, m.Dates("Phish 07.03.1999"))
+	assert.Empty(t, m.Dates("12_31_97"))
+}
+
+func TestNewMatcher_InvalidPatterns(t *testing.T) {
+	_, err := NewMatcher([]models.FolderPattern{{Pattern: `^(\d{4}`, Year: 1, Month: 2, Day: 3}}, "Phish")
+	assert.Error(t, err)
+
+	_, err = NewMatcher([]models.FolderPattern{{Pattern: `^(\d{4})-(\d{2})`, Year: 1, Month: 2, Day: 3}}, "Phish")
+	assert.Error(t, err)
+}
+
+func TestCatalogDate(t *testing.T) {
+	date, ok := CatalogDate("9/2/2022", "09/02/22")
+	assert.True(t, ok)
+	assert.Equal(t, "2022-09-02", date)
+
+	date, ok = CatalogDate("", "12/31/97")
+	assert.True(t, ok)
+	assert.Equal(t, "1997-12-31", date)
+
+	date, ok = CatalogDate("", "12/04/65")
+	assert.True(t, ok)
+	assert.Equal(t, "1965-12-04", date)
+
+	date, ok = CatalogDate("", "06/28/00")
+	assert.True(t, ok)
+	assert.Equal(t, "2000-06-28", date)
+
+	_, ok = CatalogDate("", "")
+	assert.False(t, ok)
+}
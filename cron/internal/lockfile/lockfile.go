@@ -0,0 +1,103 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrLocked is returned by Acquire when another process holds the lock
+var ErrLocked = errors.New("lock is held by another process")
+
+// Lock is an exclusive flock on a file containing the holder's PID.
+// The kernel drops the flock when the process exits for any reason,
+// including being killed by a signal, so a stale file never blocks a later run.
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Acquire takes the lock at path without blocking. If another process
+// holds it the returned error wraps ErrLocked and names that process's PID.
+func Acquire(path string) (*Lock, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file %s: %v", path, err)
+		}
+
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			file.Close()
+			if err == syscall.EWOULDBLOCK {
+				if pid := readPID(path); pid > 0 {
+					return nil, fmt.Errorf("%w (pid %d, lock file %s)", ErrLocked, pid, path)
+				}
+				return nil, fmt.Errorf("%w (lock file %s)", ErrLocked, path)
+			}
+			return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+		}
+
+		// Release unlinks the file before dropping the lock. If that happened
+		// between our open and flock we hold a file nobody else will open, so
+		// start over on whatever is at path now.
+		current, err := isCurrent(path, file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to check lock file %s: %v", path, err)
+		}
+		if !current {
+			file.Close()
+			continue
+		}
+
+		// Record our PID for the benefit of whoever finds the lock held
+		if err := file.Truncate(0); err == nil {
+			file.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+		}
+
+		return &Lock{path: path, file: file}, nil
+	}
+}
+
+// isCurrent reports whether file is still the file at path
+func isCurrent(path string, file *os.File) (bool, error) {
+	opened, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	onDisk, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(opened, onDisk), nil
+}
+
+// Release removes the lock file and drops the lock. Safe to call more than once.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	// Remove while still holding the lock. Anyone who opened the old file in
+	// the meantime finds it unlinked once they lock it and retries on a new one.
+	os.Remove(l.path)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func readPID(path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
@@ -0,0 +1,72 @@
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.lock")
+
+	lock, err := Acquire(path)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d\n", os.Getpid()), string(data))
+
+	// A second acquire fails while the first is held
+	_, err = Acquire(path)
+	assert.True(t, errors.Is(err, ErrLocked))
+	assert.Contains(t, err.Error(), fmt.Sprintf("pid %d", os.Getpid()))
+
+	require.NoError(t, lock.Release())
+	require.NoError(t, lock.Release())
+	assert.NoFileExists(t, path)
+
+	// Once released the lock can be taken again
+	lock, err = Acquire(path)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestAcquire_SkipsUnlinkedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.lock")
+
+	holder, err := Acquire(path)
+	require.NoError(t, err)
+
+	// A second run opens the file, then the holder releases before that run
+	// gets to lock it. The flock succeeds on the unlinked file.
+	late, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	defer late.Close()
+	current, err := isCurrent(path, late)
+	require.NoError(t, err)
+	assert.True(t, current)
+
+	require.NoError(t, holder.Release())
+	require.NoError(t, syscall.Flock(int(late.Fd()), syscall.LOCK_EX|syscall.LOCK_NB))
+	current, err = isCurrent(path, late)
+	require.NoError(t, err)
+	assert.False(t, current, "the late run must notice and start over")
+
+	// A third run takes the file now at path, which the late run can't see
+	lock, err := Acquire(path)
+	require.NoError(t, err)
+	defer lock.Release()
+	current, err = isCurrent(path, late)
+	require.NoError(t, err)
+	assert.False(t, current)
+
+	// and holds the lock against everyone else
+	_, err = Acquire(path)
+	assert.True(t, errors.Is(err, ErrLocked))
+}
@@ -64,6 +64,27 @@ type AuditLog struct {
 	UserAgent  string    `json:"user_agent,omitempty" db:"user_agent"`
 	Success    bool      `json:"success" db:"success"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+
+	// Hash chains this entry to the previous one; empty for entries written
+	// before hashing was introduced
+	PrevHash string `json:"prev_hash,omitempty" db:"prev_hash"`
+	Hash     string `json:"hash,omitempty" db:"hash"`
+}
+
+// ClientInfo identifies the client that made a request, for the audit log.
+// It is empty for actions started by the system, such as scheduled cleanups.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// AuditVerification is the result of checking the audit log hash chain
+type AuditVerification struct {
+	Valid          bool   `json:"valid"`
+	CheckedEntries int    `json:"checked_entries"`
+	LegacyEntries  int    `json:"legacy_entries"` // Unhashed entries from before the chain began
+	BrokenAtID     int    `json:"broken_at_id,omitempty"`
+	Reason         string `json:"reason,omitempty"`
 }
 
 type SystemStatus struct {
@@ -76,9 +97,47 @@ type SystemStatus struct {
 	Services    map[string]ServiceStatus `json:"services"`
 	Performance PerformanceStatus        `json:"performance"`
 	Health      SystemHealth             `json:"health"`
+	Maintenance MaintenanceStatus        `json:"maintenance"`
 	LastUpdated time.Time                `json:"last_updated"`
 }
 
+// MaintenanceStatus reports whether destructive maintenance (cleanup,
+// database optimize and backup, user deletion) is currently blocked
+type MaintenanceStatus struct {
+	Blocked      bool     `json:"blocked"`
+	Reason       string   `json:"reason,omitempty"`
+	Locked       bool     `json:"locked"`                  // maintenance_locked is set
+	Windows      []string `json:"windows"`                 // Daily HH:MM-HH:MM windows from maintenance_windows
+	ActiveWindow string   `json:"active_window,omitempty"` // The window blocking maintenance now
+}
+
+// DatabaseStats describes the database's tables and storage for capacity planning
+type DatabaseStats struct {
+	TableCounts         map[string]int64 `json:"table_counts"`
+	TotalTables         int              `json:"total_tables"`
+	Tables              []TableStats     `json:"tables"`         // Every table, largest first
+	LargestTables       []TableStats     `json:"largest_tables"` // The first few of Tables
+	TableSizesAvailable bool             `json:"table_sizes_available"`
+	IndexCount          int              `json:"index_count"`
+	PageSize            int64            `json:"page_size"`
+	PageCount           int64            `json:"page_count"`
+	FreePages           int64            `json:"free_pages"`
+	FreeBytes           int64            `json:"free_bytes"`
+	SizeBytes           int64            `json:"size_bytes"`
+	DatabaseSizeMB      float64          `json:"database_size_mb"`
+	JournalMode         string           `json:"journal_mode"`
+	WALSizeBytes        int64            `json:"wal_size_bytes"`
+}
+
+// TableStats describes one table. SizeBytes covers the table and its indexes
+// and is only set when SQLite was built with the dbstat virtual table.
+type TableStats struct {
+	Name      string `json:"name"`
+	Rows      int64  `json:"rows"`
+	Indexes   int    `json:"indexes"`
+	SizeBytes *int64 `json:"size_bytes,omitempty"`
+}
+
 type DatabaseStatus struct {
 	Connected    bool             `json:"connected"`
 	Size         float64          `json:"size_mb"`
@@ -100,6 +159,7 @@ type JobSystemStatus struct {
 }
 
 type StorageStatus struct {
+	Path         string     `json:"path"` // The download directory's filesystem is measured
 	TotalGB      float64    `json:"total_gb"`
 	UsedGB       float64    `json:"used_gb"`
 	FreeGB       float64    `json:"free_gb"`
@@ -178,10 +238,73 @@ type CleanupRequest struct {
 	OldLogs       bool `json:"old_logs"`       // Clean logs older than retention period
 	OldJobs       bool `json:"old_jobs"`       // Clean completed jobs older than retention
 	OldDeliveries bool `json:"old_deliveries"` // Clean webhook deliveries
+	OldExecutions bool `json:"old_executions"` // Clean schedule executions beyond the recent history kept
+	OldReports    bool `json:"old_reports"`    // Clean generated analytics reports beyond the recent ones kept
 	OldFiles      bool `json:"old_files"`      // Clean orphaned download files
 	DryRun        bool `json:"dry_run"`        // Preview what would be cleaned
 }
 
+// CleanupResult is the job result of a cleanup, itemized by category
+// (old_jobs, old_deliveries, old_executions, old_reports, orphaned_files). A dry run lists exactly what a
+// real run would delete without deleting anything.
+type CleanupResult struct {
+	DryRun     bool                     `json:"dry_run"`
+	TotalItems int                      `json:"total_items"`
+	Categories map[string]*CleanupItems `json:"categories"`
+}
+
+// DatabaseOptimizeResult is the job result of a database optimize. Sizes are
+// page_count * page_size, so they reflect the main database file.
+type DatabaseOptimizeResult struct {
+	SizeBeforeBytes int64   `json:"size_before_bytes"`
+	SizeAfterBytes  int64   `json:"size_after_bytes"`
+	FreedBytes      int64   `json:"freed_bytes"`
+	FreedMB         float64 `json:"freed_mb"`
+	VacuumMs        int64   `json:"vacuum_ms"`
+	AnalyzeMs       int64   `json:"analyze_ms"`
+	DurationMs      int64   `json:"duration_ms"`
+}
+
+// CleanupItems describes one cleanup category
+type CleanupItems struct {
+	Criteria string   `json:"criteria"`         // What makes an item eligible, e.g. "created more than 30 days ago"
+	Count    int      `json:"count"`            // Items deleted, or that would be deleted in a dry run
+	Failed   int      `json:"failed,omitempty"` // Items that could not be deleted
+	Sample   []string `json:"sample"`           // The first few items
+}
+
+// BackupManifest describes a full-system backup bundle. It is the first
+// entry of the archive, manifest.json.
+type BackupManifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Files     []string  `json:"files"` // Archive entries besides the manifest
+}
+
+// Backup import modes
+const (
+	BackupImportReplace = "replace" // The backup's database replaces the current one
+	BackupImportMerge   = "merge"   // Rows the current database lacks are added from the backup
+)
+
+// BackupImportRequest holds the options of a backup import
+type BackupImportRequest struct {
+	Mode          string // BackupImportReplace or BackupImportMerge
+	Confirm       bool   // Must be set, the import overwrites current data
+	ReseedCatalog bool   // Start a catalog refresh once the import finishes
+}
+
+// BackupImportResult lists what importing a backup bundle restored
+type BackupImportResult struct {
+	Mode                string           `json:"mode"`
+	BackupCreatedAt     time.Time        `json:"backup_created_at"`
+	PreImportBackup     string           `json:"pre_import_backup"` // Bundle of the state before the import
+	DatabaseRestored    bool             `json:"database_restored"`
+	RowsMerged          map[string]int64 `json:"rows_merged,omitempty"` // Merge mode only
+	FilesRestored       []string         `json:"files_restored"`
+	CatalogRefreshJobID string           `json:"catalog_refresh_job_id,omitempty"`
+}
+
 type BackupRequest struct {
 	IncludeDatabase bool   `json:"include_database"`
 	IncludeFiles    bool   `json:"include_files"`
@@ -18,6 +18,8 @@ type CollectionStats struct {
 	TotalDownloads        int64   `json:"total_downloads"`
 	TotalSizeGB           float64 `json:"total_size_gb"`
 	AverageShowsPerArtist float64 `json:"average_shows_per_artist"`
+	DownloadedShows       int64   `json:"downloaded_shows"` // shows with a completed download
+	CompletionPct         float64 `json:"completion_pct"`
 	RecentActivity        struct {
 		NewShowsToday      int64 `json:"new_shows_today"`
 		NewShowsThisWeek   int64 `json:"new_shows_this_week"`
@@ -26,6 +28,8 @@ type CollectionStats struct {
 		DownloadsThisWeek  int64 `json:"downloads_this_week"`
 		DownloadsThisMonth int64 `json:"downloads_this_month"`
 	} `json:"recent_activity"`
+	// Warnings name the recent activity counts that failed and were left at 0
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 type ArtistAnalytics struct {
@@ -45,6 +49,58 @@ type ArtistAnalytics struct {
 	DownloadGrowthLastMonth int64   `json:"download_growth_last_month"`
 }
 
+type TimelineEventType string
+
+const (
+	TimelineEventPerformance TimelineEventType = "performance"
+	TimelineEventDownload    TimelineEventType = "download"
+)
+
+// TimelineEvent is a show being performed or a show being downloaded
+type TimelineEvent struct {
+	Type        TimelineEventType `json:"type"`
+	Date        string            `json:"date"` // performance date, or download completion time
+	ShowID      int               `json:"show_id"`
+	ContainerID *int              `json:"container_id,omitempty"`
+	ShowDate    string            `json:"show_date"`
+	Venue       string            `json:"venue"`
+	City        string            `json:"city,omitempty"`
+	State       string            `json:"state,omitempty"`
+	DownloadID  int               `json:"download_id,omitempty"`
+	Format      string            `json:"format,omitempty"`
+}
+
+type ArtistTimeline struct {
+	ArtistID          int             `json:"artist_id"`
+	ArtistName        string          `json:"artist_name"`
+	TotalPerformances int             `json:"total_performances"`
+	TotalDownloads    int             `json:"total_downloads"`
+	Events            []TimelineEvent `json:"events"`
+}
+
+// CompletionMatrix is each monitored artist's completion by performance year
+type CompletionMatrix struct {
+	Years   []string               `json:"years"` // every year any artist played, ascending
+	Artists []ArtistYearCompletion `json:"artists"`
+}
+
+type ArtistYearCompletion struct {
+	ArtistID        int     `json:"artist_id"`
+	ArtistName      string  `json:"artist_name"`
+	AvailableShows  int     `json:"available_shows"`
+	DownloadedShows int     `json:"downloaded_shows"`
+	CompletionPct   float64 `json:"completion_pct"`
+	// Years lists only the years the artist played, ascending
+	Years []YearCompletion `json:"years"`
+}
+
+type YearCompletion struct {
+	Year            string  `json:"year"`
+	AvailableShows  int     `json:"available_shows"`
+	DownloadedShows int     `json:"downloaded_shows"`
+	CompletionPct   float64 `json:"completion_pct"`
+}
+
 type DownloadAnalytics struct {
 	TotalDownloads      int64            `json:"total_downloads"`
 	CompletedDownloads  int64            `json:"completed_downloads"`
@@ -60,6 +116,8 @@ type DownloadAnalytics struct {
 	DownloadTrends      []TrendPoint     `json:"download_trends"`
 	AverageDownloadTime float64          `json:"average_download_time_minutes"`
 	PeakDownloadHours   []HourStats      `json:"peak_download_hours"`
+	// Warnings name the sections that failed and were left out
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 type VenueStats struct {
@@ -78,6 +136,23 @@ type FormatStats struct {
 	AverageSizeGB float64 `json:"average_size_gb"`
 }
 
+// StorageBreakdown is the storage completed downloads use, by format and by quality
+type StorageBreakdown struct {
+	TotalDownloads int64          `json:"total_downloads"`
+	TotalSizeGB    float64        `json:"total_size_gb"`
+	ByFormat       []StorageUsage `json:"by_format"`
+	ByQuality      []StorageUsage `json:"by_quality"`
+}
+
+// StorageUsage is the storage used by one format or quality
+type StorageUsage struct {
+	Name          string  `json:"name"`
+	Downloads     int64   `json:"downloads"`
+	TotalSizeGB   float64 `json:"total_size_gb"`
+	AverageSizeGB float64 `json:"average_size_gb"`
+	Percentage    float64 `json:"percentage"` // Share of the total size
+}
+
 type TrendPoint struct {
 	Date   string  `json:"date"`
 	Count  int64   `json:"count"`
@@ -90,14 +165,15 @@ type HourStats struct {
 }
 
 type SystemMetrics struct {
-	DatabaseSize       float64 `json:"database_size_mb"`
-	TotalFiles         int64   `json:"total_files"`
-	TotalStorage       float64 `json:"total_storage_gb"`
-	AvailableStorage   float64 `json:"available_storage_gb"`
-	ActiveDownloads    int     `json:"active_downloads"`
-	ActiveMonitors     int64   `json:"active_monitors"`
-	SystemUptime       string  `json:"system_uptime"`
-	LastCatalogRefresh *string `json:"last_catalog_refresh,omitempty"`
+	DatabaseSize       float64          `json:"database_size_mb"`
+	TotalFiles         int64            `json:"total_files"`
+	TotalStorage       float64          `json:"total_storage_gb"`
+	AvailableStorage   float64          `json:"available_storage_gb"`
+	ActiveDownloads    int              `json:"active_downloads"`
+	ActiveMonitors     int64            `json:"active_monitors"`
+	SystemUptime       string           `json:"system_uptime"`
+	LastCatalogRefresh *string          `json:"last_catalog_refresh,omitempty"`
+	StorageForecast    *StorageForecast `json:"storage_forecast,omitempty"`
 	APIRequests        struct {
 		Today     int64 `json:"today"`
 		ThisWeek  int64 `json:"this_week"`
@@ -111,14 +187,28 @@ type SystemMetrics struct {
 	} `json:"job_stats"`
 }
 
+// StorageForecast projects when the filesystem holding the download
+// directory runs out of space at the recent rate of downloads
+type StorageForecast struct {
+	Path           string     `json:"path"`
+	TotalGB        float64    `json:"total_gb"`
+	FreeGB         float64    `json:"free_gb"`
+	UsagePercent   float64    `json:"usage_percent"`
+	GrowthGBPerDay float64    `json:"growth_gb_per_day"` // Averaged over WindowDays
+	WindowDays     int        `json:"window_days"`
+	DaysToFull     *float64   `json:"days_to_full,omitempty"` // Unset when storage isn't growing
+	FullAt         *time.Time `json:"full_at,omitempty"`
+}
+
 type PerformanceMetrics struct {
-	AverageResponseTime  float64 `json:"average_response_time_ms"`
-	DatabaseResponseTime float64 `json:"database_response_time_ms"`
-	CacheHitRate         float64 `json:"cache_hit_rate"`
-	ErrorRate            float64 `json:"error_rate"`
-	ThroughputPerSecond  float64 `json:"throughput_per_second"`
-	MemoryUsageMB        float64 `json:"memory_usage_mb"`
-	CPUUsagePercent      float64 `json:"cpu_usage_percent"`
+	AverageResponseTime  float64               `json:"average_response_time_ms"`
+	DatabaseResponseTime float64               `json:"database_response_time_ms"`
+	CacheHitRate         float64               `json:"cache_hit_rate"`
+	CacheBreakdown       map[string]CacheStats `json:"cache_breakdown"`
+	ErrorRate            float64               `json:"error_rate"`
+	ThroughputPerSecond  float64               `json:"throughput_per_second"`
+	MemoryUsageMB        float64               `json:"memory_usage_mb"`
+	CPUUsagePercent      float64               `json:"cpu_usage_percent"`
 }
 
 type TimeSeriesData struct {
@@ -148,6 +238,19 @@ type AnalyticsReport struct {
 	Recommendations []string `json:"recommendations,omitempty"`
 }
 
+// ReportFormatJSON is the format generated reports are stored and served in
+const ReportFormatJSON = "json"
+
+// ReportArtifact describes a stored report. Its content is fetched on its own.
+type ReportArtifact struct {
+	ReportID    string             `json:"report_id"`
+	ReportType  string             `json:"report_type"`
+	Timeframe   AnalyticsTimeframe `json:"timeframe"`
+	Format      string             `json:"format"`
+	SizeBytes   int64              `json:"size_bytes"`
+	GeneratedAt time.Time          `json:"generated_at"`
+}
+
 type AnalyticsQuery struct {
 	ReportType        string                 `json:"report_type" binding:"required"` // collection, artists, downloads, system, performance
 	Timeframe         AnalyticsTimeframe     `json:"timeframe"`
@@ -158,9 +261,49 @@ type AnalyticsQuery struct {
 	GroupBy           string                 `json:"group_by,omitempty"` // day, week, month, artist, format, venue
 	Limit             int                    `json:"limit,omitempty"`
 	IncludeTimeSeries bool                   `json:"include_time_series,omitempty"`
+	// MonitoredOnly limits collection and artist analytics to artists with an
+	// active monitor. Nil uses the analytics_monitored_only config value.
+	MonitoredOnly *bool `json:"monitored_only,omitempty"`
+	// Venue, City and State limit collection, artist and download analytics
+	// to shows at matching locations. Venue and city match any part of the
+	// name, state matches exactly, and case is ignored.
+	Venue string `json:"venue,omitempty"`
+	City  string `json:"city,omitempty"`
+	State string `json:"state,omitempty"`
+}
+
+// RecommendationStrategy orders the missing shows recommended for download
+type RecommendationStrategy string
+
+const (
+	// RecommendByCompletion finishes the most complete artists first
+	RecommendByCompletion RecommendationStrategy = "completion"
+	// RecommendByRecency takes the most recently performed shows first
+	RecommendByRecency RecommendationStrategy = "recency"
+	// RecommendByVenue takes shows at the most downloaded venues first
+	RecommendByVenue RecommendationStrategy = "venue"
+)
+
+// DownloadRecommendation is a missing show of a monitored artist, ranked for
+// download by a strategy
+type DownloadRecommendation struct {
+	Rank                int     `json:"rank"`
+	ShowID              int     `json:"show_id"`
+	ContainerID         *int    `json:"container_id,omitempty"`
+	ArtistID            int     `json:"artist_id"`
+	ArtistName          string  `json:"artist_name"`
+	ShowDate            string  `json:"show_date"`
+	Venue               string  `json:"venue"`
+	City                string  `json:"city,omitempty"`
+	State               string  `json:"state,omitempty"`
+	ArtistCompletionPct float64 `json:"artist_completion_pct"`
+	ArtistMissingShows  int     `json:"artist_missing_shows"`
+	VenueDownloads      int     `json:"venue_downloads"` // completed downloads of any show at the venue
+	Reason              string  `json:"reason"`
 }
 
 type TopListItem struct {
+	Rank           int     `json:"rank,omitempty"` // 1 for the top item
 	ID             int     `json:"id,omitempty"`
 	Name           string  `json:"name"`
 	Value          float64 `json:"value"`
@@ -185,3 +328,46 @@ type HealthScore struct {
 	Recommendations []string       `json:"recommendations,omitempty"`
 	LastUpdated     time.Time      `json:"last_updated"`
 }
+
+// DashboardSummary is a snapshot of every subsystem for a dashboard's first
+// load. A section is left empty when its subsystem fails; Errors says why.
+type DashboardSummary struct {
+	Collection     *DashboardCollection `json:"collection"`
+	RecentActivity *DashboardActivity   `json:"recent_activity"`
+	SystemStatus   *DashboardSystem     `json:"system_status"`
+	Storage        *StorageForecast     `json:"storage"`
+	PopularFormats []DashboardFormat    `json:"popular_formats"`
+	Downloads      *DownloadStats       `json:"downloads"`
+	RecentAlerts   []MonitorAlert       `json:"recent_alerts"`
+	Scheduler      *SchedulerStatus     `json:"scheduler"`
+	Webhooks       *WebhookStats        `json:"webhooks"`
+	Health         *HealthScore         `json:"health"`
+	Errors         map[string]string    `json:"errors,omitempty"` // Keyed by section name
+	GeneratedAt    time.Time            `json:"generated_at"`
+}
+
+type DashboardCollection struct {
+	TotalArtists    int64   `json:"total_artists"`
+	TotalShows      int64   `json:"total_shows"`
+	DownloadedShows int64   `json:"downloaded_shows"` // Shows with at least one completed download
+	CompletionPct   float64 `json:"completion_pct"`
+	TotalDownloads  int64   `json:"total_downloads"`
+	TotalSizeGB     float64 `json:"total_size_gb"`
+}
+
+type DashboardActivity struct {
+	NewShows24h     int64 `json:"new_shows_24h"`
+	NewDownloads24h int64 `json:"new_downloads_24h"`
+}
+
+type DashboardSystem struct {
+	ActiveMonitors int64 `json:"active_monitors"`
+	RunningJobs    int64 `json:"running_jobs"`
+	FailedJobs     int64 `json:"failed_jobs"`
+}
+
+type DashboardFormat struct {
+	Format     string  `json:"format"`
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// API key scopes. ScopeRead grants every read-only scope; the others limit a
+// key to one group of GET endpoints. Admin endpoints are never reachable with
+// an API key.
+const (
+	APIKeyScopeRead       = "read"
+	APIKeyScopeCatalog    = "catalog"
+	APIKeyScopeDownloads  = "downloads"
+	APIKeyScopeMonitoring = "monitoring"
+	APIKeyScopeAnalytics  = "analytics"
+	APIKeyScopeWebhooks   = "webhooks"
+	APIKeyScopeScheduler  = "scheduler"
+)
+
+// APIKeyScopes lists the scopes that may be granted to a key
+var APIKeyScopes = []string{
+	APIKeyScopeRead,
+	APIKeyScopeCatalog,
+	APIKeyScopeDownloads,
+	APIKeyScopeMonitoring,
+	APIKeyScopeAnalytics,
+	APIKeyScopeWebhooks,
+	APIKeyScopeScheduler,
+}
+
+type APIKey struct {
+	ID         int        `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	KeyPrefix  string     `json:"key_prefix" db:"key_prefix"` // First characters of the key, for identification
+	Scopes     []string   `json:"scopes" db:"scopes"`         // Stored as JSON string
+	CreatedBy  int        `json:"created_by,omitempty" db:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type APIKeyRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+}
+
+type APIKeyResponse struct {
+	Success bool    `json:"success"`
+	Key     string  `json:"key,omitempty"` // Plaintext key, only returned when it is created
+	APIKey  *APIKey `json:"api_key,omitempty"`
+	Message string  `json:"message,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import "sync"
+
+// Names of the caches that report hit/miss counters
+const (
+	CacheCatalog = "catalog"
+	CacheETag    = "etag"
+)
+
+// CacheStats holds the hit/miss counters for a single cache
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // %
+}
+
+// CacheMetrics tracks hit/miss counters for each named cache
+type CacheMetrics struct {
+	caches map[string]*CacheStats
+	mu     sync.RWMutex
+}
+
+// DefaultCacheMetrics is the process-wide registry used by caches and reporting
+var DefaultCacheMetrics = NewCacheMetrics()
+
+func NewCacheMetrics() *CacheMetrics {
+	return &CacheMetrics{
+		caches: make(map[string]*CacheStats),
+	}
+}
+
+// RecordHit increments the hit counter for the named cache
+func (cm *CacheMetrics) RecordHit(name string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.get(name).Hits++
+}
+
+// RecordMiss increments the miss counter for the named cache
+func (cm *CacheMetrics) RecordMiss(name string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.get(name).Misses++
+}
+
+// Snapshot returns a copy of the counters for every cache that has recorded activity
+func (cm *CacheMetrics) Snapshot() map[string]CacheStats {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	snapshot := make(map[string]CacheStats, len(cm.caches))
+	for name, stats := range cm.caches {
+		snapshot[name] = CacheStats{
+			Hits:    stats.Hits,
+			Misses:  stats.Misses,
+			HitRate: hitRate(stats.Hits, stats.Misses),
+		}
+	}
+
+	return snapshot
+}
+
+// HitRate returns the combined hit rate across all caches as a percentage
+func (cm *CacheMetrics) HitRate() float64 {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	var hits, misses int64
+	for _, stats := range cm.caches {
+		hits += stats.Hits
+		misses += stats.Misses
+	}
+
+	return hitRate(hits, misses)
+}
+
+// Reset clears all recorded counters
+func (cm *CacheMetrics) Reset() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	cm.caches = make(map[string]*CacheStats)
+}
+
+// get returns the counters for name, creating them if needed. Callers must hold the write lock.
+func (cm *CacheMetrics) get(name string) *CacheStats {
+	stats, exists := cm.caches[name]
+	if !exists {
+		stats = &CacheStats{}
+		cm.caches[name] = stats
+	}
+	return stats
+}
+
+func hitRate(hits, misses int64) float64 {
+	total := hits + misses
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total) * 100
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheMetrics_HitRate(t *testing.T) {
+	cm := NewCacheMetrics()
+
+	// No activity yet
+	assert.Equal(t, 0.0, cm.HitRate())
+	assert.Empty(t, cm.Snapshot())
+
+	cm.RecordHit(CacheCatalog)
+	cm.RecordHit(CacheCatalog)
+	cm.RecordHit(CacheCatalog)
+	cm.RecordMiss(CacheCatalog)
+	cm.RecordMiss(CacheETag)
+
+	assert.InDelta(t, 60.0, cm.HitRate(), 0.001)
+
+	snapshot := cm.Snapshot()
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, int64(3), snapshot[CacheCatalog].Hits)
+	assert.Equal(t, int64(1), snapshot[CacheCatalog].Misses)
+	assert.InDelta(t, 75.0, snapshot[CacheCatalog].HitRate, 0.001)
+	assert.Equal(t, int64(0), snapshot[CacheETag].Hits)
+	assert.Equal(t, 0.0, snapshot[CacheETag].HitRate)
+}
+
+func TestCacheMetrics_Reset(t *testing.T) {
+	cm := NewCacheMetrics()
+	cm.RecordHit(CacheCatalog)
+
+	cm.Reset()
+
+	assert.Empty(t, cm.Snapshot())
+	assert.Equal(t, 0.0, cm.HitRate())
+}
+
+func TestCacheMetrics_ConcurrentAccess(t *testing.T) {
+	cm := NewCacheMetrics()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cm.RecordHit(CacheETag)
+		}()
+		go func() {
+			defer wg.Done()
+			cm.RecordMiss(CacheETag)
+		}()
+	}
+	wg.Wait()
+
+	snapshot := cm.Snapshot()
+	assert.Equal(t, int64(50), snapshot[CacheETag].Hits)
+	assert.Equal(t, int64(50), snapshot[CacheETag].Misses)
+}
@@ -46,6 +46,7 @@ type Download struct {
 	DownloadedAt *time.Time      `json:"downloaded_at,omitempty" db:"downloaded_at"`
 	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at,omitempty"`
+	Tracks       []int           `json:"tracks,omitempty" db:"tracks"` // Empty for the whole show
 
 	// Show details (populated via JOIN)
 	ShowTitle       string `json:"show_title,omitempty"`
@@ -68,6 +69,7 @@ type DownloadRequest struct {
 	Format   DownloadFormat  `json:"format" binding:"required"`
 	Quality  DownloadQuality `json:"quality"`
 	Priority int             `json:"priority"` // 1-10, default 5
+	Tracks   []int           `json:"tracks"`   // Setlist track numbers (1-based), empty for the whole show
 }
 
 type DownloadResponse struct {
@@ -92,3 +94,57 @@ type DownloadStats struct {
 	ActiveDownloads     int64            `json:"active_downloads"`
 	AverageSpeedMbps    float64          `json:"average_speed_mbps"`
 }
+
+// DuplicateReport lists shows with more than one completed download
+type DuplicateReport struct {
+	Shows          []DuplicateShow `json:"shows"`
+	TotalShows     int             `json:"total_shows"`
+	TotalRedundant int             `json:"total_redundant"`
+	ReclaimableMB  float64         `json:"reclaimable_mb"`
+}
+
+// DuplicateShow is a show a user downloaded more than once. Keep is the
+// highest quality copy and Redundant the copies a cleanup removes.
+type DuplicateShow struct {
+	UserID        int            `json:"user_id"`
+	ShowID        int            `json:"show_id"`
+	ContainerID   int            `json:"container_id"`
+	ArtistName    string         `json:"artist_name"`
+	ShowDate      string         `json:"show_date"`
+	Venue         string         `json:"venue"`
+	Keep          DownloadCopy   `json:"keep"`
+	Redundant     []DownloadCopy `json:"redundant"`
+	ReclaimableMB float64        `json:"reclaimable_mb"`
+}
+
+// DownloadCopy is one completed download of a show
+type DownloadCopy struct {
+	DownloadID  int     `json:"download_id"`
+	Format      string  `json:"format"`
+	Quality     string  `json:"quality"`
+	SizeMB      float64 `json:"size_mb"`
+	FilePath    string  `json:"file_path,omitempty"` // Unset when no file is found
+	CompletedAt *string `json:"completed_at,omitempty"`
+}
+
+// DuplicateCleanupRequest removes the redundant copies of duplicate downloads
+type DuplicateCleanupRequest struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// FetchNewRequest downloads the new shows of every monitored artist, as the
+// monitor does. A Limit of 0 downloads them all.
+type FetchNewRequest struct {
+	Limit int `json:"limit"`
+}
+
+// DuplicateCleanupResult reports what a duplicate cleanup removed, or would
+// remove for a dry run
+type DuplicateCleanupResult struct {
+	DryRun      bool            `json:"dry_run"`
+	Removed     int             `json:"removed"`
+	Failed      int             `json:"failed"`
+	ReclaimedMB float64         `json:"reclaimed_mb"`
+	Shows       []DuplicateShow `json:"shows"`
+	Errors      []string        `json:"errors,omitempty"`
+}
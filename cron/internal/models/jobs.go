@@ -1,7 +1,10 @@
 package models
 
 import (
+	"crypto/rand"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -16,6 +19,11 @@ const (
 	JobStatusCancelled JobStatus = "cancelled"
 )
 
+// JobStatuses lists every job status, for validating a status filter
+var JobStatuses = []JobStatus{
+	JobStatusPending, JobStatusRunning, JobStatusCompleted, JobStatusFailed, JobStatusCancelled,
+}
+
 type JobType string
 
 const (
@@ -23,8 +31,22 @@ const (
 	JobTypeDownload       JobType = "download"
 	JobTypeMonitorCheck   JobType = "monitor_check"
 	JobTypeAnalytics      JobType = "analytics"
+	JobTypeShowScan       JobType = "show_scan"
+	JobTypeFetchNew       JobType = "fetch_new"
+	JobTypeBackup         JobType = "backup"
+	JobTypeBackupImport   JobType = "backup_import"
+	JobTypeHealthCheck    JobType = "health_check"
+	JobTypeCleanup        JobType = "cleanup"
+	JobTypeOptimize       JobType = "database_optimize"
 )
 
+// JobTypes lists every job type, for validating a type filter
+var JobTypes = []JobType{
+	JobTypeCatalogRefresh, JobTypeDownload, JobTypeMonitorCheck, JobTypeAnalytics,
+	JobTypeShowScan, JobTypeFetchNew, JobTypeBackup, JobTypeBackupImport,
+	JobTypeHealthCheck, JobTypeCleanup, JobTypeOptimize,
+}
+
 type Job struct {
 	ID          string     `json:"id"`
 	Type        JobType    `json:"type"`
@@ -51,6 +73,17 @@ func (j *Job) IsCancellationRequested() bool {
 	}
 }
 
+// snapshot copies the job, so it can be read while the manager updates the
+// original. The copy shares the Cancel channel.
+func (j *Job) snapshot() *Job {
+	copy := *j
+	return &copy
+}
+
+// JobManager tracks jobs for every handler and service. Jobs are only changed
+// under its lock, through UpdateJob, and CreateJob, GetJob and ListJobs hand
+// out snapshots, so callers never read a job while another goroutine
+// updates it.
 type JobManager struct {
 	jobs map[string]*Job
 	mu   sync.RWMutex
@@ -66,8 +99,13 @@ func (jm *JobManager) CreateJob(jobType JobType) *Job {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
 
+	id := generateJobID()
+	for jm.jobs[id] != nil {
+		id = generateJobID()
+	}
+
 	job := &Job{
-		ID:        generateJobID(),
+		ID:        id,
 		Type:      jobType,
 		Status:    JobStatusPending,
 		Progress:  0,
@@ -76,7 +114,7 @@ func (jm *JobManager) CreateJob(jobType JobType) *Job {
 	}
 
 	jm.jobs[job.ID] = job
-	return job
+	return job.snapshot()
 }
 
 func (jm *JobManager) GetJob(id string) (*Job, bool) {
@@ -84,9 +122,14 @@ func (jm *JobManager) GetJob(id string) (*Job, bool) {
 	defer jm.mu.RUnlock()
 
 	job, exists := jm.jobs[id]
-	return job, exists
+	if !exists {
+		return nil, false
+	}
+	return job.snapshot(), true
 }
 
+// UpdateJob applies updates to the job under the manager's lock, so a
+// read-modify-write in updates can't interleave with another caller's
 func (jm *JobManager) UpdateJob(id string, updates func(*Job)) error {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
@@ -106,15 +149,87 @@ func (jm *JobManager) ListJobs() []*Job {
 
 	jobs := make([]*Job, 0, len(jm.jobs))
 	for _, job := range jm.jobs {
-		jobs = append(jobs, job)
+		jobs = append(jobs, job.snapshot())
 	}
 
 	return jobs
 }
 
-func (jm *JobManager) CancelJob(id string) error {
+// JobFilter selects jobs. Empty fields match every job.
+type JobFilter struct {
+	Types         []JobType
+	Statuses      []JobStatus
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	// Search matches any part of the ID, message or error, ignoring case
+	Search string
+}
+
+func (f JobFilter) matches(job *Job) bool {
+	if len(f.Types) > 0 && !containsJobType(f.Types, job.Type) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsJobStatus(f.Statuses, job.Status) {
+		return false
+	}
+	if f.CreatedAfter != nil && job.CreatedAt.Before(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && !job.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		if !strings.Contains(strings.ToLower(job.ID), search) &&
+			!strings.Contains(strings.ToLower(job.Message), search) &&
+			!strings.Contains(strings.ToLower(job.Error), search) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsJobType(types []JobType, jobType JobType) bool {
+	for _, t := range types {
+		if t == jobType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsJobStatus(statuses []JobStatus, status JobStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// FindJobs returns snapshots of the jobs filter matches, newest first
+func (jm *JobManager) FindJobs(filter JobFilter) []*Job {
 	jm.mu.RLock()
-	defer jm.mu.RUnlock()
+	jobs := []*Job{}
+	for _, job := range jm.jobs {
+		if filter.matches(job) {
+			jobs = append(jobs, job.snapshot())
+		}
+	}
+	jm.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+func (jm *JobManager) CancelJob(id string) error {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
 
 	job, exists := jm.jobs[id]
 	if !exists {
@@ -131,6 +246,37 @@ func (jm *JobManager) CancelJob(id string) error {
 	return nil
 }
 
+// FailStuckJobs marks jobs that have been running longer than maxRuntime as
+// failed, asking them to cancel in case they are still alive, and returns
+// snapshots of the jobs it failed. A job whose goroutine died would
+// otherwise stay running forever.
+func (jm *JobManager) FailStuckJobs(maxRuntime time.Duration) []*Job {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-maxRuntime)
+	failed := []*Job{}
+
+	for _, job := range jm.jobs {
+		if job.Status != JobStatusRunning || job.StartedAt.IsZero() || !job.StartedAt.Before(cutoff) {
+			continue
+		}
+
+		select {
+		case job.Cancel <- true:
+		default:
+		}
+		completedAt := now
+		job.Status = JobStatusFailed
+		job.Error = fmt.Sprintf("timed out: still running after %s", maxRuntime)
+		job.CompletedAt = &completedAt
+		failed = append(failed, job.snapshot())
+	}
+
+	return failed
+}
+
 func (jm *JobManager) CleanupOldJobs(maxAge time.Duration) int {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
@@ -148,11 +294,33 @@ func (jm *JobManager) CleanupOldJobs(maxAge time.Duration) int {
 	return cleaned
 }
 
+// RemoveJobs deletes the given jobs, skipping any that are running or
+// pending, and returns how many were removed
+func (jm *JobManager) RemoveJobs(ids []string) int {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	removed := 0
+	for _, id := range ids {
+		job, exists := jm.jobs[id]
+		if !exists || job.Status == JobStatusRunning || job.Status == JobStatusPending {
+			continue
+		}
+		delete(jm.jobs, id)
+		removed++
+	}
+
+	return removed
+}
+
 func generateJobID() string {
 	// Generate UUID v4
 	b := make([]byte, 16)
-	for i := range b {
-		b[i] = byte(time.Now().UnixNano() % 256)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand doesn't fail on supported platforms; fall back to the clock
+		for i := range b {
+			b[i] = byte(time.Now().UnixNano() >> (i % 8 * 8))
+		}
 	}
 
 	// Set version (4) and variant bits
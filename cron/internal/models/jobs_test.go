@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -140,6 +141,40 @@ func TestJobManager_CleanupOldJobs(t *testing.T) {
 	assert.True(t, exists)
 }
 
+func TestJobManager_FailStuckJobs(t *testing.T) {
+	jm := NewJobManager()
+
+	stuck := jm.CreateJob(JobTypeDownload)
+	require.NoError(t, jm.UpdateJob(stuck.ID, func(j *Job) {
+		j.Status = JobStatusRunning
+		j.StartedAt = time.Now().Add(-3 * time.Hour)
+	}))
+	recent := jm.CreateJob(JobTypeCatalogRefresh)
+	require.NoError(t, jm.UpdateJob(recent.ID, func(j *Job) {
+		j.Status = JobStatusRunning
+		j.StartedAt = time.Now()
+	}))
+	pending := jm.CreateJob(JobTypeBackup)
+
+	failed := jm.FailStuckJobs(2 * time.Hour)
+	require.Len(t, failed, 1)
+	assert.Equal(t, stuck.ID, failed[0].ID)
+
+	job, _ := jm.GetJob(stuck.ID)
+	assert.Equal(t, JobStatusFailed, job.Status)
+	assert.Contains(t, job.Error, "timed out")
+	assert.NotNil(t, job.CompletedAt)
+	assert.True(t, stuck.IsCancellationRequested())
+
+	job, _ = jm.GetJob(recent.ID)
+	assert.Equal(t, JobStatusRunning, job.Status)
+	job, _ = jm.GetJob(pending.ID)
+	assert.Equal(t, JobStatusPending, job.Status)
+
+	// Already failed jobs are not reaped again
+	assert.Empty(t, jm.FailStuckJobs(2*time.Hour))
+}
+
 func TestJob_IsCancellationRequested(t *testing.T) {
 	jm := NewJobManager()
 	job := jm.CreateJob(JobTypeDownload)
@@ -154,3 +189,61 @@ func TestJob_IsCancellationRequested(t *testing.T) {
 	// Now cancellation should be requested
 	assert.True(t, job.IsCancellationRequested())
 }
+
+func TestJobManager_Concurrent(t *testing.T) {
+	jm := NewJobManager()
+	shared := jm.CreateJob(JobTypeAnalytics)
+
+	const workers, updates = 8, 100
+	var wg sync.WaitGroup
+	ids := make(chan string, workers*updates)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < updates; i++ {
+				ids <- jm.CreateJob(JobTypeDownload).ID
+				// Each increment reads and writes Progress, so a lost update
+				// would leave the total short
+				assert.NoError(t, jm.UpdateJob(shared.ID, func(j *Job) {
+					j.Progress++
+					j.Status = JobStatusRunning
+				}))
+				for _, job := range jm.ListJobs() {
+					_ = job.Status
+				}
+				if job, ok := jm.GetJob(shared.ID); ok {
+					_ = job.Progress
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	job, ok := jm.GetJob(shared.ID)
+	require.True(t, ok)
+	assert.Equal(t, workers*updates, job.Progress)
+
+	// Every job got an ID of its own
+	seen := make(map[string]bool)
+	for id := range ids {
+		assert.False(t, seen[id], "duplicate job ID %s", id)
+		seen[id] = true
+	}
+	assert.Len(t, jm.ListJobs(), workers*updates+1)
+}
+
+func TestJobManager_GetJobReturnsSnapshot(t *testing.T) {
+	jm := NewJobManager()
+	job := jm.CreateJob(JobTypeDownload)
+
+	snapshot, ok := jm.GetJob(job.ID)
+	require.True(t, ok)
+	require.NoError(t, jm.UpdateJob(job.ID, func(j *Job) { j.Status = JobStatusRunning }))
+
+	// The earlier snapshot is unchanged, a new one sees the update
+	assert.Equal(t, JobStatusPending, snapshot.Status)
+	current, _ := jm.GetJob(job.ID)
+	assert.Equal(t, JobStatusRunning, current.Status)
+}
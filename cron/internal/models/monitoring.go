@@ -19,6 +19,7 @@ const (
 	AlertTypeNewShow     AlertType = "new_show"
 	AlertTypeShowUpdate  AlertType = "show_update"
 	AlertTypeMissingShow AlertType = "missing_show"
+	AlertTypeBelowGoal   AlertType = "below_goal"
 )
 
 type ArtistMonitor struct {
@@ -33,8 +34,20 @@ type ArtistMonitor struct {
 	NewShowsFound     int           `json:"new_shows_found" db:"new_shows_found"`
 	NotifyNewShows    bool          `json:"notify_new_shows" db:"notify_new_shows"`
 	NotifyShowUpdates bool          `json:"notify_show_updates" db:"notify_show_updates"`
+	CompletionGoal    *float64      `json:"completion_goal,omitempty" db:"completion_goal"` // percent
 	CreatedAt         time.Time     `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time     `json:"updated_at" db:"updated_at"`
+
+	// Current completion, populated when the monitor is fetched individually
+	Completion *ArtistCompletion `json:"completion,omitempty"`
+}
+
+// ArtistCompletion is how much of an artist's catalog has been downloaded
+type ArtistCompletion struct {
+	TotalShows      int     `json:"total_shows"`
+	DownloadedShows int     `json:"downloaded_shows"`
+	CompletionPct   float64 `json:"completion_pct"`
+	MeetsGoal       *bool   `json:"meets_goal,omitempty"` // nil when no goal is set
 }
 
 type MonitorAlert struct {
@@ -68,6 +81,8 @@ type MonitorRequest struct {
 	CheckInterval     int  `json:"check_interval"` // minutes, default 60
 	NotifyNewShows    bool `json:"notify_new_shows"`
 	NotifyShowUpdates bool `json:"notify_show_updates"`
+	// CompletionGoal is the target completion percentage; an alert is raised when the artist falls below it
+	CompletionGoal *float64 `json:"completion_goal,omitempty" binding:"omitempty,min=0,max=100"`
 }
 
 type MonitorUpdateRequest struct {
@@ -75,6 +90,7 @@ type MonitorUpdateRequest struct {
 	CheckInterval     *int           `json:"check_interval,omitempty"`
 	NotifyNewShows    *bool          `json:"notify_new_shows,omitempty"`
 	NotifyShowUpdates *bool          `json:"notify_show_updates,omitempty"`
+	CompletionGoal    *float64       `json:"completion_goal,omitempty" binding:"omitempty,min=0,max=100"`
 }
 
 type MonitorResponse struct {
@@ -93,6 +109,8 @@ type CheckResult struct {
 	CheckDuration string `json:"check_duration"`
 	Success       bool   `json:"success"`
 	Error         string `json:"error,omitempty"`
+
+	Completion *ArtistCompletion `json:"completion,omitempty"`
 }
 
 type BulkMonitorRequest struct {
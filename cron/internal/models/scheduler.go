@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"sort"
 	"time"
 )
 
@@ -24,6 +26,94 @@ const (
 	ScheduleTypeCustom         ScheduleType = "custom"
 )
 
+// ScheduleTypes lists the schedule types the scheduler can run. Custom
+// schedules have nothing to run yet, so they are not accepted.
+var ScheduleTypes = []ScheduleType{
+	ScheduleTypeCatalogRefresh,
+	ScheduleTypeMonitorCheck,
+	ScheduleTypeSystemCleanup,
+	ScheduleTypeDatabaseBackup,
+	ScheduleTypeHealthCheck,
+}
+
+// Valid reports whether t is one of ScheduleTypes
+func (t ScheduleType) Valid() bool {
+	for _, known := range ScheduleTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduleParameterType is the JSON type a schedule parameter takes
+type ScheduleParameterType string
+
+const (
+	ScheduleParameterBool   ScheduleParameterType = "boolean"
+	ScheduleParameterString ScheduleParameterType = "string"
+)
+
+// ScheduleParameters lists the parameters each schedule type accepts. The
+// database_backup parameters mirror BackupRequest.
+var ScheduleParameters = map[ScheduleType]map[string]ScheduleParameterType{
+	ScheduleTypeCatalogRefresh: {
+		"force": ScheduleParameterBool,
+	},
+	ScheduleTypeMonitorCheck: {},
+	ScheduleTypeSystemCleanup: {
+		"old_logs":       ScheduleParameterBool,
+		"old_jobs":       ScheduleParameterBool,
+		"old_deliveries": ScheduleParameterBool,
+		"old_executions": ScheduleParameterBool,
+		"old_reports":    ScheduleParameterBool,
+		"old_files":      ScheduleParameterBool,
+		"dry_run":        ScheduleParameterBool,
+	},
+	ScheduleTypeDatabaseBackup: {
+		"include_database": ScheduleParameterBool,
+		"include_files":    ScheduleParameterBool,
+		"include_config":   ScheduleParameterBool,
+		"compress":         ScheduleParameterBool,
+		"destination":      ScheduleParameterString,
+	},
+	ScheduleTypeHealthCheck: {},
+}
+
+// ValidateScheduleParameters describes each of params that scheduleType does
+// not accept or that has the wrong type, ordered by key. It returns nil when
+// all of them are valid.
+func ValidateScheduleParameters(scheduleType ScheduleType, params map[string]interface{}) []string {
+	accepted := ScheduleParameters[scheduleType]
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		want, ok := accepted[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown parameter %q", key))
+			continue
+		}
+
+		var valid bool
+		switch want {
+		case ScheduleParameterBool:
+			_, valid = params[key].(bool)
+		case ScheduleParameterString:
+			_, valid = params[key].(string)
+		}
+		if !valid {
+			problems = append(problems, fmt.Sprintf("parameter %q must be a %s", key, want))
+		}
+	}
+	return problems
+}
+
 type Schedule struct {
 	ID          int            `json:"id" db:"id"`
 	Name        string         `json:"name" db:"name"`
@@ -65,6 +155,15 @@ type ScheduleExecution struct {
 	ScheduleType string `json:"schedule_type,omitempty"`
 }
 
+// UpcomingRun is a future run of an active schedule
+type UpcomingRun struct {
+	ScheduleID   int          `json:"schedule_id"`
+	ScheduleName string       `json:"schedule_name"`
+	Type         ScheduleType `json:"type"`
+	CronExpr     string       `json:"cron_expr"`
+	RunAt        time.Time    `json:"run_at"`
+}
+
 type SchedulerStatus struct {
 	IsRunning       bool       `json:"is_running"`
 	StartTime       time.Time  `json:"start_time"`
@@ -77,6 +176,11 @@ type SchedulerStatus struct {
 	ExecutionsToday int64      `json:"executions_today"`
 	FailuresToday   int64      `json:"failures_today"`
 	Uptime          string     `json:"uptime"`
+	// Restarts counts scheduler loop restarts after a panic since it started
+	Restarts  int    `json:"restarts"`
+	LastPanic string `json:"last_panic,omitempty"`
+	// Failed is set once the scheduler gave up restarting after panics
+	Failed bool `json:"failed"`
 }
 
 type SchedulerStats struct {
@@ -120,6 +224,7 @@ type ScheduleRequest struct {
 type ScheduleUpdateRequest struct {
 	Name        *string                 `json:"name,omitempty"`
 	Description *string                 `json:"description,omitempty"`
+	Type        *ScheduleType           `json:"type,omitempty"`
 	CronExpr    *string                 `json:"cron_expr,omitempty"`
 	Status      *ScheduleStatus         `json:"status,omitempty"`
 	Parameters  *map[string]interface{} `json:"parameters,omitempty"`
@@ -130,6 +235,10 @@ type ScheduleResponse struct {
 	ScheduleID int    `json:"schedule_id,omitempty"`
 	Message    string `json:"message"`
 	Error      string `json:"error,omitempty"`
+	// ValidTypes lists the schedule types when Type was not one of them
+	ValidTypes []ScheduleType `json:"valid_types,omitempty"`
+	// ParameterErrors describes each parameter the type rejected
+	ParameterErrors []string `json:"parameter_errors,omitempty"`
 }
 
 type ScheduleTestRequest struct {
@@ -196,6 +305,8 @@ var DefaultScheduleTemplates = []ScheduleTemplate{
 			"old_logs":       true,
 			"old_jobs":       true,
 			"old_deliveries": true,
+			"old_executions": true,
+			"old_reports":    true,
 			"dry_run":        false,
 		},
 		Category: "Maintenance",
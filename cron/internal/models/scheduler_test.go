@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateScheduleParameters(t *testing.T) {
+	assert.Nil(t, ValidateScheduleParameters(ScheduleTypeCatalogRefresh, map[string]interface{}{"force": true}))
+	assert.Nil(t, ValidateScheduleParameters(ScheduleTypeMonitorCheck, nil))
+
+	assert.Equal(t, []string{
+		`parameter "force" must be a boolean`,
+		`unknown parameter "forced"`,
+	}, ValidateScheduleParameters(ScheduleTypeCatalogRefresh, map[string]interface{}{
+		"forced": true,
+		"force":  "yes",
+	}))
+
+	assert.Equal(t, []string{`unknown parameter "old_jobs"`},
+		ValidateScheduleParameters(ScheduleTypeHealthCheck, map[string]interface{}{"old_jobs": true}))
+	assert.Equal(t, []string{`parameter "destination" must be a string`},
+		ValidateScheduleParameters(ScheduleTypeDatabaseBackup, map[string]interface{}{"destination": 3.0}))
+}
+
+func TestDefaultScheduleTemplates_ValidParameters(t *testing.T) {
+	for _, template := range DefaultScheduleTemplates {
+		assert.True(t, template.Type.Valid(), template.Name)
+		assert.Nil(t, ValidateScheduleParameters(template.Type, template.Parameters), template.Name)
+	}
+}
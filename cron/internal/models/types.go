@@ -6,11 +6,78 @@ type Config struct {
 	Password string `json:"password"`
 	Format   int    `json:"format"`
 	OutPath  string `json:"outPath"`
+
+	// NugsDLPath is the nugs-dl binary to run. Bare names are looked up on PATH;
+	// relative paths resolve against NUGS_HOME. Defaults to bin/nugs-dl.
+	NugsDLPath string `json:"nugsDlPath,omitempty"`
+	// NugsDLArgs are extra arguments passed to nugs-dl before the release URL
+	NugsDLArgs []string `json:"nugsDlArgs,omitempty"`
+	// MaxDownloadAttempts is how many times a show may fail to download before
+	// the monitor marks it failed and stops retrying. Defaults to DefaultMaxDownloadAttempts.
+	MaxDownloadAttempts int `json:"maxDownloadAttempts,omitempty"`
+	// Accounts are extra nugs.net accounts the API client rotates requests
+	// among. When set they replace Email/Password for API calls.
+	Accounts []NugsAccount `json:"accounts,omitempty"`
+	// CatalogSource selects where the detector, monitor and gap report get
+	// the catalog: "api" (default) or "file" for an offline snapshot
+	CatalogSource string `json:"catalogSource,omitempty"`
+	// CatalogSnapshot is the snapshot the file source reads. Relative paths
+	// resolve against NUGS_HOME. Defaults to data/catalog_cache.json.
+	CatalogSnapshot string `json:"catalogSnapshot,omitempty"`
+}
+
+// NugsAccount is one set of nugs.net credentials
+type NugsAccount struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// NugsAccounts returns the accounts the API client rotates among: Accounts
+// when set, otherwise the single Email/Password pair
+func (c *Config) NugsAccounts() []NugsAccount {
+	if len(c.Accounts) > 0 {
+		return c.Accounts
+	}
+	if c.Email == "" {
+		return nil
+	}
+	return []NugsAccount{{Email: c.Email, Password: c.Password}}
+}
+
+// DefaultMaxDownloadAttempts is used when Config.MaxDownloadAttempts is not set
+const DefaultMaxDownloadAttempts = 3
+
+// GetMaxDownloadAttempts returns the configured attempt limit, or the default
+func (c *Config) GetMaxDownloadAttempts() int {
+	if c.MaxDownloadAttempts > 0 {
+		return c.MaxDownloadAttempts
+	}
+	return DefaultMaxDownloadAttempts
 }
 
 // MonitorConfig holds configuration for which artists to monitor
 type MonitorConfig struct {
 	Artists []Artist `json:"artists"`
+	// FolderPatterns are the show folder naming schemes to recognize.
+	// Defaults to MM_DD_YY and "Artist - MM_DD_YY" when empty.
+	FolderPatterns []FolderPattern `json:"folder_patterns,omitempty"`
+	// QualityLadder lists the preferred download formats, best first, e.g.
+	// ["flac", "alac", "aac"]. When set, new shows are downloaded in the first
+	// format and shows held in a lower one are reported as upgrade candidates.
+	QualityLadder []string `json:"quality_ladder,omitempty"`
+	// AutoUpgrade has the monitor re-download upgrade candidates in the best
+	// format and replace the older copy
+	AutoUpgrade bool `json:"auto_upgrade,omitempty"`
+}
+
+// FolderPattern is a regular expression that finds a show date in a folder name.
+// The literal {artist} is replaced with the quoted artist name. Year, Month and
+// Day are capture group numbers; years may be two or four digits.
+type FolderPattern struct {
+	Pattern string `json:"pattern"`
+	Year    int    `json:"year"`
+	Month   int    `json:"month"`
+	Day     int    `json:"day"`
 }
 
 // Artist represents an artist configuration for monitoring
@@ -19,6 +86,8 @@ type Artist struct {
 	Artist       string `json:"artist"`
 	Monitor      bool   `json:"monitor"`
 	ArtistFolder string `json:"artist_folder"`
+	// Tags group artists for gap_report --group-by=tag, e.g. ["jam bands"]
+	Tags []string `json:"tags,omitempty"`
 }
 
 // ShowsData represents the complete tracking data structure
@@ -36,4 +105,23 @@ type ArtistShowData struct {
 	Downloaded []int `json:"downloaded"`
 	Available  []int `json:"available"`
 	Missing    []int `json:"missing"`
+
+	// Failed holds shows that reached the download attempt limit. They are
+	// no longer retried by the monitor or counted as missing by the detector.
+	Failed []int `json:"failed,omitempty"`
+	// Attempts records failed download attempts by container ID
+	Attempts map[int]DownloadAttempt `json:"attempts,omitempty"`
+	// Unmatched lists downloaded folders that matched no show in the catalog,
+	// e.g. shows pulled from the catalog, renamed or mis-filed folders
+	Unmatched []string `json:"unmatched,omitempty"`
+	// Formats records the format each show was downloaded in by container ID.
+	// Shows found on disk without a download by the monitor have no entry.
+	Formats map[int]string `json:"formats,omitempty"`
+}
+
+// DownloadAttempt tracks failed download attempts for a single show
+type DownloadAttempt struct {
+	Count       int    `json:"count"`
+	LastError   string `json:"last_error"`
+	LastAttempt string `json:"last_attempt"`
 }
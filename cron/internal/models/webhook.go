@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -21,6 +22,15 @@ const (
 	WebhookEventCatalogRefresh   WebhookEvent = "catalog_refresh"
 	WebhookEventMonitorAlert     WebhookEvent = "monitor_alert"
 	WebhookEventSystemAlert      WebhookEvent = "system_alert"
+	WebhookEventScheduleNewShows WebhookEvent = "schedule_new_shows"
+	WebhookEventGapThreshold     WebhookEvent = "gap_threshold"
+
+	// WebhookEventPing is only sent to verify an endpoint; webhooks can't subscribe to it
+	WebhookEventPing WebhookEvent = "ping"
+
+	// WebhookEventDigest delivers the events a digest webhook collected; webhooks
+	// get it by setting a digest window rather than subscribing to it
+	WebhookEventDigest WebhookEvent = "digest"
 )
 
 type Webhook struct {
@@ -39,6 +49,14 @@ type Webhook struct {
 	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
 
+	// Digest delivery, off while DigestWindowMinutes is 0
+	DigestWindowMinutes int `json:"digest_window_minutes" db:"digest_window_minutes"`
+	DigestMaxEvents     int `json:"digest_max_events" db:"digest_max_events"` // 0 for no count threshold
+
+	// PayloadTemplate is a text/template rendered against the payload and sent
+	// instead of it, empty to send the payload as is
+	PayloadTemplate string `json:"payload_template,omitempty" db:"payload_template"`
+
 	// Statistics
 	TotalFired   int64   `json:"total_fired"`
 	SuccessCount int64   `json:"success_count"`
@@ -72,6 +90,17 @@ type WebhookRequest struct {
 	Headers map[string]string `json:"headers,omitempty"`
 	Timeout int               `json:"timeout"` // seconds, default 10
 	Retries int               `json:"retries"` // default 3
+
+	// DigestWindowMinutes batches events into one digest delivered at most this
+	// often, and DigestMaxEvents flushes it early once that many are waiting
+	DigestWindowMinutes int `json:"digest_window_minutes"`
+	DigestMaxEvents     int `json:"digest_max_events"`
+
+	// PayloadTemplate renders the body sent instead of the standard payload
+	PayloadTemplate string `json:"payload_template,omitempty"`
+
+	// VerifyOnCreate pings the URL and rejects the webhook unless it answers 2xx
+	VerifyOnCreate bool `json:"verify_on_create"`
 }
 
 type WebhookUpdateRequest struct {
@@ -83,6 +112,11 @@ type WebhookUpdateRequest struct {
 	Headers *map[string]string `json:"headers,omitempty"`
 	Timeout *int               `json:"timeout,omitempty"`
 	Retries *int               `json:"retries,omitempty"`
+
+	DigestWindowMinutes *int `json:"digest_window_minutes,omitempty"`
+	DigestMaxEvents     *int `json:"digest_max_events,omitempty"`
+
+	PayloadTemplate *string `json:"payload_template,omitempty"`
 }
 
 type WebhookResponse struct {
@@ -119,6 +153,21 @@ type NewShowPayload struct {
 	MonitorID int `json:"monitor_id,omitempty"`
 }
 
+// WebhookDigestPayload is the data of a digest event: every event the webhook
+// collected since the oldest one waiting, oldest first
+type WebhookDigestPayload struct {
+	Count  int                  `json:"count"`
+	Counts map[WebhookEvent]int `json:"counts"`
+	Since  time.Time            `json:"since"`
+	Events []WebhookDigestEvent `json:"events"`
+}
+
+type WebhookDigestEvent struct {
+	Event     WebhookEvent    `json:"event"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
 type DownloadCompletePayload struct {
 	Download struct {
 		ID          int     `json:"id"`
@@ -148,12 +197,52 @@ type DownloadFailedPayload struct {
 }
 
 type CatalogRefreshPayload struct {
-	JobID           string `json:"job_id"`
-	Status          string `json:"status"` // completed, failed
-	Duration        string `json:"duration"`
-	ShowsImported   int64  `json:"shows_imported"`
-	ArtistsImported int64  `json:"artists_imported"`
-	Error           string `json:"error,omitempty"`
+	JobID           string          `json:"job_id"`
+	Status          string          `json:"status"` // completed, failed
+	Duration        string          `json:"duration"`
+	ShowsImported   int64           `json:"shows_imported"`
+	ArtistsImported int64           `json:"artists_imported"`
+	Changes         *CatalogChanges `json:"changes,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+// CatalogChanges is what a catalog refresh changed compared to the shows
+// stored before it. The show lists are capped; the counts are always exact.
+type CatalogChanges struct {
+	AddedCount          int                   `json:"added_count"`
+	RemovedCount        int                   `json:"removed_count"`
+	AddedShows          []CatalogChangeShow   `json:"added_shows"`
+	RemovedShows        []CatalogChangeShow   `json:"removed_shows"`
+	ArtistsWithNewShows []CatalogArtistChange `json:"artists_with_new_shows"`
+	Truncated           bool                  `json:"truncated,omitempty"`
+	InitialImport       bool                  `json:"initial_import,omitempty"` // Nothing was stored before, so every show is new
+}
+
+type CatalogChangeShow struct {
+	ContainerID     int    `json:"container_id"`
+	ArtistName      string `json:"artist_name"`
+	VenueName       string `json:"venue_name"`
+	VenueCity       string `json:"venue_city"`
+	VenueState      string `json:"venue_state"`
+	PerformanceDate string `json:"performance_date"`
+}
+
+type CatalogArtistChange struct {
+	ArtistName string `json:"artist_name"`
+	NewShows   int    `json:"new_shows"`
+}
+
+// ScheduleNewShowsPayload reports a scheduled catalog refresh that found new
+// shows. Sample lists the newest of them.
+type ScheduleNewShowsPayload struct {
+	Schedule struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"schedule"`
+	JobID               string                `json:"job_id"`
+	AddedCount          int                   `json:"added_count"`
+	Sample              []CatalogChangeShow   `json:"sample"`
+	ArtistsWithNewShows []CatalogArtistChange `json:"artists_with_new_shows"`
 }
 
 type MonitorAlertPayload struct {
@@ -172,6 +261,23 @@ type MonitorAlertPayload struct {
 	} `json:"monitor"`
 }
 
+// GapThresholdPayload is sent when a monitor check finds an artist has
+// dropped below its completion goal since the previous check
+type GapThresholdPayload struct {
+	Artist struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+	Monitor struct {
+		ID int `json:"id"`
+	} `json:"monitor"`
+	Threshold          float64 `json:"threshold"` // the monitor's completion goal
+	PreviousCompletion float64 `json:"previous_completion_pct"`
+	Completion         float64 `json:"completion_pct"`
+	MissingShows       int     `json:"missing_shows"`
+	TotalShows         int     `json:"total_shows"`
+}
+
 type SystemAlertPayload struct {
 	Alert struct {
 		Type      string `json:"type"`
@@ -185,6 +291,41 @@ type SystemAlertPayload struct {
 		Status      string `json:"status"`
 		Version     string `json:"version,omitempty"`
 	} `json:"system"`
+	ConfigChange *ConfigChangePayload `json:"config_change,omitempty"`
+	Storage      *StorageAlertPayload `json:"storage,omitempty"`
+	APIErrorRate *APIErrorRatePayload `json:"api_error_rate,omitempty"`
+}
+
+// ConfigChangePayload describes a system config update in a system alert
+type ConfigChangePayload struct {
+	Key       string `json:"key"`
+	OldValue  string `json:"old_value"`
+	NewValue  string `json:"new_value"`
+	ChangedBy string `json:"changed_by"`
+}
+
+// StorageAlertPayload describes a storage level change in a system alert
+type StorageAlertPayload struct {
+	Level           string   `json:"level"` // ok, warning, critical
+	PreviousLevel   string   `json:"previous_level"`
+	Path            string   `json:"path"`
+	UsagePercent    float64  `json:"usage_percent"`
+	FreeGB          float64  `json:"free_gb"`
+	TotalGB         float64  `json:"total_gb"`
+	WarningPercent  float64  `json:"warning_percent"`
+	CriticalPercent float64  `json:"critical_percent"`
+	GrowthGBPerDay  float64  `json:"growth_gb_per_day"`      // Average over the last 30 days of downloads
+	DaysToFull      *float64 `json:"days_to_full,omitempty"` // Unset without recent growth
+}
+
+// APIErrorRatePayload describes a nugs.net API circuit breaker opened by a
+// high rolling error rate in a system alert
+type APIErrorRatePayload struct {
+	Account   string  `json:"account,omitempty"` // Unset for unauthenticated requests
+	Endpoint  string  `json:"endpoint"`
+	ErrorRate float64 `json:"error_rate"`
+	Threshold float64 `json:"threshold"`
+	Window    int     `json:"window"` // Number of recent requests the rate covers
 }
 
 type WebhookStats struct {
@@ -198,6 +339,26 @@ type WebhookStats struct {
 	AverageResponseTime  float64          `json:"average_response_time_ms"`
 	DeliverySuccessRate  float64          `json:"delivery_success_rate"`
 	EventBreakdown       map[string]int64 `json:"event_breakdown"`
+	ActiveDeliveries     int64            `json:"active_deliveries"`
+	QueuedDeliveries     int64            `json:"queued_deliveries"` // Waiting for a free delivery slot
+}
+
+// WebhookDeliveryTrend is the delivery volume of all webhooks over a
+// timeframe, bucketed by hour, day or month
+type WebhookDeliveryTrend struct {
+	Timeframe AnalyticsTimeframe     `json:"timeframe"`
+	Bucket    string                 `json:"bucket"`
+	Points    []WebhookDeliveryPoint `json:"points"`
+}
+
+// WebhookDeliveryPoint is one bucket of a WebhookDeliveryTrend
+type WebhookDeliveryPoint struct {
+	Period              string  `json:"period"`
+	Deliveries          int64   `json:"deliveries"`
+	Successful          int64   `json:"successful"`
+	Failed              int64   `json:"failed"`
+	SuccessRate         float64 `json:"success_rate"`
+	AverageResponseTime float64 `json:"average_response_time_ms"`
 }
 
 type WebhookTestRequest struct {
@@ -0,0 +1,150 @@
+package nugsconfig
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyEnv is the environment variable holding the passphrase an encrypted
+// config.json is sealed with
+const KeyEnv = "NUGS_CONFIG_KEY"
+
+// EmailEnv and PasswordEnv supply nugs.net credentials without storing them
+// in config.json. When EmailEnv is set they replace the file's credentials.
+const (
+	EmailEnv    = "NUGS_EMAIL"
+	PasswordEnv = "NUGS_PASSWORD"
+)
+
+// sealedFormat marks a config file written by Encrypt
+const sealedFormat = "nugs-secretbox-v1"
+
+// ErrNoKey is returned when an encrypted config is read without KeyEnv set
+var ErrNoKey = errors.New(KeyEnv + " is not set")
+
+// sealedConfig is the on-disk form of an encrypted config. The key is derived
+// from the passphrase with scrypt and the config is sealed with NaCl secretbox.
+type sealedConfig struct {
+	Format string `json:"format"`
+	Salt   []byte `json:"salt"`
+	Nonce  []byte `json:"nonce"`
+	Data   []byte `json:"data"`
+}
+
+// Load reads config.json, decrypting it with the KeyEnv passphrase when it
+// was sealed by Encrypt, then applies the credential environment variables.
+// A missing file is not an error when the credentials come from the environment.
+func Load(filename string) (*models.Config, error) {
+	var config models.Config
+
+	data, err := ioutil.ReadFile(filename)
+	switch {
+	case err == nil:
+		if IsEncrypted(data) {
+			passphrase := os.Getenv(KeyEnv)
+			if passphrase == "" {
+				return nil, fmt.Errorf("%s is encrypted: %w", filename, ErrNoKey)
+			}
+			if data, err = Decrypt(data, passphrase); err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s: %v", filename, err)
+			}
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+	case os.IsNotExist(err) && os.Getenv(EmailEnv) != "":
+	default:
+		return nil, err
+	}
+
+	if email := os.Getenv(EmailEnv); email != "" {
+		config.Email = email
+		config.Password = os.Getenv(PasswordEnv)
+		config.Accounts = nil
+	}
+
+	return &config, nil
+}
+
+// IsEncrypted reports whether data is a config sealed by Encrypt
+func IsEncrypted(data []byte) bool {
+	var sealed sealedConfig
+	return json.Unmarshal(data, &sealed) == nil && sealed.Format == sealedFormat
+}
+
+// Encrypt seals a plaintext config with the passphrase
+func Encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrNoKey
+	}
+
+	sealed := sealedConfig{
+		Format: sealedFormat,
+		Salt:   make([]byte, 16),
+		Nonce:  make([]byte, 24),
+	}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, err
+	}
+
+	key, err := deriveKey(passphrase, sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], sealed.Nonce)
+	sealed.Data = secretbox.Seal(nil, plaintext, &nonce, key)
+
+	return json.MarshalIndent(sealed, "", "  ")
+}
+
+// Decrypt opens a config sealed by Encrypt
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	var sealed sealedConfig
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, err
+	}
+	if sealed.Format != sealedFormat {
+		return nil, fmt.Errorf("unsupported format %q", sealed.Format)
+	}
+	if len(sealed.Nonce) != 24 {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	key, err := deriveKey(passphrase, sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [24]byte
+	copy(nonce[:], sealed.Nonce)
+	plaintext, ok := secretbox.Open(nil, sealed.Data, &nonce, key)
+	if !ok {
+		return nil, fmt.Errorf("wrong %s or corrupted file", KeyEnv)
+	}
+
+	return plaintext, nil
+}
+
+func deriveKey(passphrase string, salt []byte) (*[32]byte, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	var key [32]byte
+	copy(key[:], derived)
+	return &key, nil
+}
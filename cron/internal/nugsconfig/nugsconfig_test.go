@@ -0,0 +1,74 @@
+package nugsconfig
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const plainConfig = `{"email": "file@example.com", "password": "secret", "format": 2, "outPath": "/music"}`
+
+func writeConfig(t *testing.T, data []byte) string {
+	filename := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, ioutil.WriteFile(filename, data, 0600))
+	return filename
+}
+
+func TestLoad_Plaintext(t *testing.T) {
+	t.Setenv(EmailEnv, "")
+
+	config, err := Load(writeConfig(t, []byte(plainConfig)))
+	require.NoError(t, err)
+	assert.Equal(t, "file@example.com", config.Email)
+	assert.Equal(t, "secret", config.Password)
+	assert.Equal(t, 2, config.Format)
+}
+
+func TestLoad_Encrypted(t *testing.T) {
+	t.Setenv(EmailEnv, "")
+
+	sealed, err := Encrypt([]byte(plainConfig), "passphrase")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.NotContains(t, string(sealed), "file@example.com")
+	filename := writeConfig(t, sealed)
+
+	t.Setenv(KeyEnv, "")
+	_, err = Load(filename)
+	assert.True(t, errors.Is(err, ErrNoKey))
+
+	t.Setenv(KeyEnv, "wrong")
+	_, err = Load(filename)
+	assert.Error(t, err)
+
+	t.Setenv(KeyEnv, "passphrase")
+	config, err := Load(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "file@example.com", config.Email)
+	assert.Equal(t, "/music", config.OutPath)
+}
+
+func TestLoad_EnvironmentCredentials(t *testing.T) {
+	t.Setenv(EmailEnv, "env@example.com")
+	t.Setenv(PasswordEnv, "env-secret")
+
+	config, err := Load(writeConfig(t, []byte(`{"accounts": [{"email": "a@example.com", "password": "a"}], "format": 2}`)))
+	require.NoError(t, err)
+	assert.Equal(t, "env@example.com", config.Email)
+	assert.Equal(t, "env-secret", config.Password)
+	assert.Equal(t, 2, config.Format)
+	assert.Len(t, config.NugsAccounts(), 1)
+
+	// The file is optional when the credentials come from the environment
+	config, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "env@example.com", config.Email)
+
+	t.Setenv(EmailEnv, "")
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
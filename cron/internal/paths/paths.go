@@ -0,0 +1,48 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// HomeEnv is the environment variable that sets the base directory for
+// configs, data, logs and bundled binaries
+const HomeEnv = "NUGS_HOME"
+
+// Home returns the base directory all relative paths resolve against.
+// Defaults to the current working directory when NUGS_HOME is not set.
+func Home() string {
+	if home := os.Getenv(HomeEnv); home != "" {
+		return home
+	}
+	return "."
+}
+
+// Resolve joins the path elements onto the home directory. Absolute paths are returned unchanged.
+func Resolve(elem ...string) string {
+	path := filepath.Join(elem...)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(Home(), path)
+}
+
+// Config returns the path of a file in the configs directory
+func Config(name string) string {
+	return Resolve("configs", name)
+}
+
+// Data returns the path of a file in the data directory
+func Data(name string) string {
+	return Resolve("data", name)
+}
+
+// Logs returns the path of a file or directory in the logs directory
+func Logs(name string) string {
+	return Resolve("logs", name)
+}
+
+// Bin returns the path of a bundled executable
+func Bin(name string) string {
+	return Resolve("bin", name)
+}
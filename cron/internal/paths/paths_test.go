@@ -0,0 +1,44 @@
+package paths
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHome(t *testing.T) {
+	t.Setenv(HomeEnv, "")
+	assert.Equal(t, ".", Home())
+
+	t.Setenv(HomeEnv, "/opt/nugs")
+	assert.Equal(t, "/opt/nugs", Home())
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv(HomeEnv, "/opt/nugs")
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"config file", Config("monitor_config.json"), "/opt/nugs/configs/monitor_config.json"},
+		{"data file", Data("shows.json"), "/opt/nugs/data/shows.json"},
+		{"log directory", Logs("api_logs"), "/opt/nugs/logs/api_logs"},
+		{"binary", Bin("nugs-dl"), "/opt/nugs/bin/nugs-dl"},
+		{"relative path", Resolve("logs/custom"), "/opt/nugs/logs/custom"},
+		{"absolute path", Resolve("/var/log/nugs"), "/var/log/nugs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.path)
+		})
+	}
+}
+
+func TestResolve_DefaultHome(t *testing.T) {
+	t.Setenv(HomeEnv, "")
+	assert.Equal(t, filepath.Join("data", "shows.json"), Data("shows.json"))
+}
@@ -0,0 +1,74 @@
+package quality
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Formats maps the format names used in a quality ladder to nugs-dl's -f codes
+var Formats = map[string]int{
+	"alac": 1, // 16-bit / 44.1 kHz ALAC
+	"flac": 2, // 16-bit / 44.1 kHz FLAC
+	"mqa":  3, // 24-bit / 48 kHz MQA
+	"360":  4, // 360 Reality Audio, or the best available
+	"aac":  5, // 150 Kbps AAC
+}
+
+// FormatName returns the ladder name of a nugs-dl format code, or "" if unknown
+func FormatName(code int) string {
+	for name, c := range Formats {
+		if c == code {
+			return name
+		}
+	}
+	return ""
+}
+
+// Ladder is an order of preferred formats, best first
+type Ladder struct {
+	formats []string
+	rank    map[string]int
+}
+
+// NewLadder validates the formats, best first. An empty list gives an empty
+// ladder, under which no show is ever an upgrade candidate.
+func NewLadder(formats []string) (*Ladder, error) {
+	l := &Ladder{rank: make(map[string]int)}
+	for _, format := range formats {
+		name := strings.ToLower(strings.TrimSpace(format))
+		if _, ok := Formats[name]; !ok {
+			return nil, fmt.Errorf("unknown format %q in quality ladder", format)
+		}
+		if _, dup := l.rank[name]; dup {
+			return nil, fmt.Errorf("format %q listed twice in quality ladder", format)
+		}
+		l.rank[name] = len(l.formats)
+		l.formats = append(l.formats, name)
+	}
+	return l, nil
+}
+
+// Empty reports whether the ladder lists no formats
+func (l *Ladder) Empty() bool {
+	return len(l.formats) == 0
+}
+
+// Best returns the most preferred format, or "" for an empty ladder
+func (l *Ladder) Best() string {
+	if l.Empty() {
+		return ""
+	}
+	return l.formats[0]
+}
+
+// NeedsUpgrade reports whether a show held in format should be replaced by
+// the ladder's best format. Formats missing from the ladder rank below every
+// rung. An unknown (empty) format never does, so shows downloaded before the
+// format was recorded aren't fetched again wholesale.
+func (l *Ladder) NeedsUpgrade(format string) bool {
+	if l.Empty() || format == "" {
+		return false
+	}
+	rank, ok := l.rank[strings.ToLower(format)]
+	return !ok || rank > 0
+}
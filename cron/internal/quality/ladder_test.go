@@ -0,0 +1,52 @@
+package quality
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLadder_NeedsUpgrade(t *testing.T) {
+	l, err := NewLadder([]string{"FLAC", "alac", "aac"})
+	require.NoError(t, err)
+	assert.Equal(t, "flac", l.Best())
+
+	tests := []struct {
+		format   string
+		expected bool
+	}{
+		{"flac", false},
+		{"alac", true},
+		{"aac", true},
+		{"mqa", true},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			assert.Equal(t, tt.expected, l.NeedsUpgrade(tt.format))
+		})
+	}
+}
+
+func TestLadder_Empty(t *testing.T) {
+	l, err := NewLadder(nil)
+	require.NoError(t, err)
+	assert.True(t, l.Empty())
+	assert.Equal(t, "", l.Best())
+	assert.False(t, l.NeedsUpgrade("aac"))
+}
+
+func TestNewLadder_Invalid(t *testing.T) {
+	_, err := NewLadder([]string{"flac", "mp3"})
+	assert.Error(t, err)
+
+	_, err = NewLadder([]string{"flac", "FLAC"})
+	assert.Error(t, err)
+}
+
+func TestFormatName(t *testing.T) {
+	assert.Equal(t, "flac", FormatName(2))
+	assert.Equal(t, "", FormatName(9))
+}
@@ -3,19 +3,23 @@ package services
 import (
 	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"runtime"
-	"syscall"
+	"sync"
 	"time"
 
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/version"
 )
 
 type AdminService struct {
 	DB         *sql.DB
 	JobManager *models.JobManager
 	startTime  time.Time
+	webhooks   *WebhookService
 }
 
 func NewAdminService(db *sql.DB, jobManager *models.JobManager) *AdminService {
@@ -23,11 +27,12 @@ func NewAdminService(db *sql.DB, jobManager *models.JobManager) *AdminService {
 		DB:         db,
 		JobManager: jobManager,
 		startTime:  time.Now(),
+		webhooks:   NewWebhookService(db, jobManager),
 	}
 }
 
 // User Management
-func (s *AdminService) CreateUser(req *models.UserCreateRequest) (*models.UserResponse, error) {
+func (s *AdminService) CreateUser(req *models.UserCreateRequest, client models.ClientInfo) (*models.UserResponse, error) {
 	// Hash password (simplified - in production use proper bcrypt)
 	h := sha256.New()
 	h.Write([]byte(req.Password))
@@ -65,7 +70,7 @@ func (s *AdminService) CreateUser(req *models.UserCreateRequest) (*models.UserRe
 
 	// Log audit trail
 	s.logAuditAction(0, "system", "create_user", "user", fmt.Sprintf("%d", userID),
-		fmt.Sprintf("Created user: %s", req.Username), "", "", true)
+		fmt.Sprintf("Created user: %s", req.Username), client.IPAddress, client.UserAgent, true)
 
 	return &models.UserResponse{
 		Success: true,
@@ -74,7 +79,7 @@ func (s *AdminService) CreateUser(req *models.UserCreateRequest) (*models.UserRe
 	}, nil
 }
 
-func (s *AdminService) UpdateUser(userID int, req *models.UserUpdateRequest, updatedBy string) error {
+func (s *AdminService) UpdateUser(userID int, req *models.UserUpdateRequest, updatedBy string, client models.ClientInfo) error {
 	updates := []string{}
 	args := []interface{}{}
 
@@ -123,12 +128,16 @@ func (s *AdminService) UpdateUser(userID int, req *models.UserUpdateRequest, upd
 
 	// Log audit trail
 	s.logAuditAction(0, updatedBy, "update_user", "user", fmt.Sprintf("%d", userID),
-		"Updated user profile", "", "", true)
+		"Updated user profile", client.IPAddress, client.UserAgent, true)
 
 	return nil
 }
 
-func (s *AdminService) DeleteUser(userID int, deletedBy string) error {
+func (s *AdminService) DeleteUser(userID int, deletedBy string, client models.ClientInfo) error {
+	if err := CheckMaintenanceAllowed(s.DB); err != nil {
+		return err
+	}
+
 	result, err := s.DB.Exec("DELETE FROM users WHERE id = ?", userID)
 	if err != nil {
 		return err
@@ -141,12 +150,12 @@ func (s *AdminService) DeleteUser(userID int, deletedBy string) error {
 
 	// Log audit trail
 	s.logAuditAction(0, deletedBy, "delete_user", "user", fmt.Sprintf("%d", userID),
-		"Deleted user", "", "", true)
+		"Deleted user", client.IPAddress, client.UserAgent, true)
 
 	return nil
 }
 
-func (s *AdminService) ChangePassword(userID int, req *models.PasswordChangeRequest) error {
+func (s *AdminService) ChangePassword(userID int, req *models.PasswordChangeRequest, client models.ClientInfo) error {
 	// Get current password hash
 	var currentHash string
 	err := s.DB.QueryRow("SELECT password_hash FROM users WHERE id = ?", userID).Scan(&currentHash)
@@ -181,7 +190,7 @@ func (s *AdminService) ChangePassword(userID int, req *models.PasswordChangeRequ
 
 	// Log audit trail
 	s.logAuditAction(userID, "", "change_password", "user", fmt.Sprintf("%d", userID),
-		"Changed password", "", "", true)
+		"Changed password", client.IPAddress, client.UserAgent, true)
 
 	return nil
 }
@@ -212,21 +221,24 @@ func (s *AdminService) GetSystemConfig() ([]models.SystemConfig, error) {
 	return configs, nil
 }
 
-func (s *AdminService) UpdateConfig(key string, req *models.ConfigUpdateRequest, updatedBy string) error {
-	// Check if config exists
-	var exists bool
-	err := s.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM system_config WHERE key = ?)", key).Scan(&exists)
+func (s *AdminService) UpdateConfig(key string, req *models.ConfigUpdateRequest, updatedBy string, client models.ClientInfo) error {
+	// Check if config exists and get its current value and declared type
+	var oldValue string
+	var dataType sql.NullString
+	err := s.DB.QueryRow("SELECT value, data_type FROM system_config WHERE key = ?", key).Scan(&oldValue, &dataType)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("configuration key not found: %s", key)
+	}
 	if err != nil {
 		return err
 	}
 
-	if !exists {
-		return fmt.Errorf("configuration key not found: %s", key)
+	// Validate against the declared type and convert to string for storage
+	valueStr, err := formatConfigValue(dataType.String, req.Value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %v", key, err)
 	}
 
-	// Convert value to string for storage
-	valueStr := fmt.Sprintf("%v", req.Value)
-
 	// Update config
 	_, err = s.DB.Exec(`
 		UPDATE system_config 
@@ -240,16 +252,37 @@ func (s *AdminService) UpdateConfig(key string, req *models.ConfigUpdateRequest,
 
 	// Log audit trail
 	s.logAuditAction(0, updatedBy, "update_config", "system_config", key,
-		fmt.Sprintf("Updated config %s", key), "", "", true)
+		fmt.Sprintf("Updated config %s", key), client.IPAddress, client.UserAgent, true)
+
+	if valueStr != oldValue && GetConfigBool(s.DB, "config_change_alerts_enabled", true) {
+		s.alertConfigChanged(key, oldValue, valueStr, updatedBy)
+	}
 
 	return nil
 }
 
+// alertConfigChanged notifies system_alert webhooks that a setting changed
+func (s *AdminService) alertConfigChanged(key, oldValue, newValue, changedBy string) {
+	var payload models.SystemAlertPayload
+	payload.Alert.Type = "config_changed"
+	payload.Alert.Severity = "info"
+	payload.Alert.Message = fmt.Sprintf("Configuration %s changed by %s", key, changedBy)
+	payload.Alert.Component = "system_config"
+	payload.System.Version = "v" + version.Version
+	payload.ConfigChange = &models.ConfigChangePayload{
+		Key:       key,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+		ChangedBy: changedBy,
+	}
+	s.webhooks.TriggerEvent(models.WebhookEventSystemAlert, payload)
+}
+
 // System Status
 func (s *AdminService) GetSystemStatus() (*models.SystemStatus, error) {
 	status := &models.SystemStatus{
 		Status:      "healthy",
-		Version:     "v1.0.0",
+		Version:     "v" + version.Version,
 		Uptime:      time.Since(s.startTime).String(),
 		Services:    make(map[string]models.ServiceStatus),
 		LastUpdated: time.Now(),
@@ -275,6 +308,8 @@ func (s *AdminService) GetSystemStatus() (*models.SystemStatus, error) {
 	perfStatus := s.getPerformanceStatus()
 	status.Performance = *perfStatus
 
+	status.Maintenance = GetMaintenanceStatus(s.DB, time.Now())
+
 	// Health check
 	health := s.calculateSystemHealth(status)
 	status.Health = *health
@@ -358,19 +393,11 @@ func (s *AdminService) getJobSystemStatus() *models.JobSystemStatus {
 }
 
 func (s *AdminService) getStorageStatus() (*models.StorageStatus, error) {
-	status := &models.StorageStatus{}
-
-	// Get filesystem stats
-	var stat syscall.Statfs_t
-	if err := syscall.Statfs("/home/jmagar/code/nugs/downloads", &stat); err != nil {
+	status, err := statStorage(s.DB)
+	if err != nil {
 		return nil, err
 	}
 
-	status.TotalGB = float64(stat.Blocks*uint64(stat.Bsize)) / (1024 * 1024 * 1024)
-	status.FreeGB = float64(stat.Bavail*uint64(stat.Bsize)) / (1024 * 1024 * 1024)
-	status.UsedGB = status.TotalGB - status.FreeGB
-	status.UsagePercent = (status.UsedGB / status.TotalGB) * 100
-
 	// Count files
 	var fileCount int64
 	s.DB.QueryRow(`SELECT COUNT(*) FROM downloads WHERE file_path IS NOT NULL AND file_path != ''`).Scan(&fileCount)
@@ -430,7 +457,8 @@ func (s *AdminService) calculateSystemHealth(status *models.SystemStatus) *model
 	}
 
 	// Storage health
-	if status.Storage.UsagePercent > 90 {
+	warning, critical := s.storageThresholds()
+	if level := storageLevel(status.Storage.UsagePercent, warning, critical); level == StorageLevelCritical {
 		score -= 20
 		issues = append(issues, models.HealthIssue{
 			Type:      "error",
@@ -439,7 +467,7 @@ func (s *AdminService) calculateSystemHealth(status *models.SystemStatus) *model
 			Severity:  4,
 			Action:    "Clean up old files or expand storage",
 		})
-	} else if status.Storage.UsagePercent > 75 {
+	} else if level == StorageLevelWarning {
 		score -= 5
 		issues = append(issues, models.HealthIssue{
 			Type:      "warning",
@@ -509,66 +537,53 @@ func (s *AdminService) calculateSystemHealth(status *models.SystemStatus) *model
 }
 
 // Maintenance
-func (s *AdminService) RunCleanup(req *models.CleanupRequest, runBy string) (*models.Job, error) {
-	job := s.JobManager.CreateJob(models.JobTypeAnalytics) // Reuse analytics job type
+func (s *AdminService) RunCleanup(req *models.CleanupRequest, runBy string, client models.ClientInfo) (*models.Job, error) {
+	// A dry run deletes nothing, so it may run during a maintenance window
+	if !req.DryRun {
+		if err := CheckMaintenanceAllowed(s.DB); err != nil {
+			return nil, err
+		}
+	}
 
-	go s.performCleanup(job, req, runBy)
+	job := s.JobManager.CreateJob(models.JobTypeCleanup)
+
+	go s.performCleanup(job, req, runBy, client)
 
 	return job, nil
 }
 
-func (s *AdminService) performCleanup(job *models.Job, req *models.CleanupRequest, runBy string) {
+func (s *AdminService) performCleanup(job *models.Job, req *models.CleanupRequest, runBy string, client models.ClientInfo) {
 	startTime := time.Now()
 
 	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
 		j.Status = models.JobStatusRunning
 		j.StartedAt = startTime
-		j.Message = "Starting system cleanup..."
+		j.Message = "Planning system cleanup..."
 	})
 
-	cleanupResults := make(map[string]int64)
-	totalCleaned := int64(0)
-
-	// Clean old jobs
-	if req.OldJobs {
+	plan, err := s.planCleanup(req, job.ID)
+	if err != nil {
+		completedAt := time.Now()
 		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
-			j.Progress = 25
-			j.Message = "Cleaning old job records..."
+			j.Status = models.JobStatusFailed
+			j.Error = err.Error()
+			j.Message = "Cleanup failed"
+			j.CompletedAt = &completedAt
 		})
-
-		// In a real implementation, clean jobs older than retention period
-		cleanupResults["old_jobs"] = 0 // Placeholder
+		return
 	}
 
-	// Clean old webhook deliveries
-	if req.OldDeliveries {
+	message := fmt.Sprintf("Cleanup plan: %d items would be cleaned", plan.result.TotalItems)
+	details := "Previewed system cleanup"
+	if !req.DryRun {
 		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
 			j.Progress = 50
-			j.Message = "Cleaning old webhook deliveries..."
+			j.Message = fmt.Sprintf("Cleaning %d items...", plan.result.TotalItems)
 		})
 
-		if !req.DryRun {
-			result, err := s.DB.Exec(`
-				DELETE FROM webhook_deliveries 
-				WHERE created_at < datetime('now', '-30 days')
-			`)
-			if err == nil {
-				cleaned, _ := result.RowsAffected()
-				cleanupResults["old_deliveries"] = cleaned
-				totalCleaned += cleaned
-			}
-		}
-	}
-
-	// Clean orphaned files
-	if req.OldFiles {
-		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
-			j.Progress = 75
-			j.Message = "Cleaning orphaned files..."
-		})
-
-		// In a real implementation, scan filesystem and remove orphaned files
-		cleanupResults["orphaned_files"] = 0 // Placeholder
+		s.applyCleanup(plan)
+		message = fmt.Sprintf("Cleanup completed: %d items cleaned", plan.result.TotalItems)
+		details = "Performed system cleanup"
 	}
 
 	// Complete job
@@ -576,14 +591,14 @@ func (s *AdminService) performCleanup(job *models.Job, req *models.CleanupReques
 	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
 		j.Status = models.JobStatusCompleted
 		j.Progress = 100
-		j.Message = fmt.Sprintf("Cleanup completed: %d items cleaned", totalCleaned)
-		j.Result = cleanupResults
+		j.Message = message
+		j.Result = plan.result
 		j.CompletedAt = &completedAt
 	})
 
 	// Log audit trail
 	s.logAuditAction(0, runBy, "system_cleanup", "maintenance", job.ID,
-		"Performed system cleanup", "", "", true)
+		details, client.IPAddress, client.UserAgent, true)
 }
 
 func (s *AdminService) GetAdminStats() (*models.AdminStats, error) {
@@ -623,16 +638,68 @@ func (s *AdminService) GetAdminStats() (*models.AdminStats, error) {
 	return stats, nil
 }
 
+// auditChainMu serializes audit writes across AdminService instances so each
+// entry is chained to the one written before it
+var auditChainMu sync.Mutex
+
+// auditTimeLayout matches SQLite's datetime('now') so stored timestamps hash
+// the same way they read back
+const auditTimeLayout = "2006-01-02 15:04:05"
+
+// auditLogColumns is the column list read by scanAuditLog
+const auditLogColumns = `id, user_id, username, action, resource, resource_id,
+		       details, ip_address, user_agent, success, created_at, prev_hash, hash`
+
 func (s *AdminService) logAuditAction(userID int, username, action, resource, resourceID, details, ipAddress, userAgent string, success bool) {
+	auditChainMu.Lock()
+	defer auditChainMu.Unlock()
+
+	var prevHash sql.NullString
+	s.DB.QueryRow("SELECT hash FROM audit_logs ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+
+	entry := models.AuditLog{
+		UserID:     userID,
+		Username:   username,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Details:    details,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		Success:    success,
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+		PrevHash:   prevHash.String,
+	}
+	entry.Hash = auditHash(&entry)
+
+	var user sql.NullInt64
+	if userID > 0 {
+		user = sql.NullInt64{Int64: int64(userID), Valid: true}
+	}
+
 	s.DB.Exec(`
-		INSERT INTO audit_logs (user_id, username, action, resource, resource_id, 
-		                       details, ip_address, user_agent, success, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
-	`, userID, username, action, resource, resourceID, details, ipAddress, userAgent, success)
+		INSERT INTO audit_logs (user_id, username, action, resource, resource_type, resource_id, 
+		                       details, ip_address, user_agent, success, created_at, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, user, username, action, resource, resource, resourceID, details, ipAddress, userAgent, success,
+		entry.CreatedAt.Format(auditTimeLayout), entry.PrevHash, entry.Hash)
 }
 
-func (s *AdminService) GetAuditLogs(page, pageSize int, filters map[string]string) ([]models.AuditLog, int64, error) {
-	// Build WHERE clause
+// auditHash hashes an entry's contents together with the previous entry's hash.
+// user_id is left out because deleting a user nulls it (ON DELETE SET NULL);
+// the username still identifies the actor.
+func auditHash(entry *models.AuditLog) string {
+	content, _ := json.Marshal([]interface{}{
+		entry.PrevHash, entry.Username, entry.Action, entry.Resource, entry.ResourceID,
+		entry.Details, entry.IPAddress, entry.UserAgent, entry.Success,
+		entry.CreatedAt.UTC().Format(auditTimeLayout),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditLogFilter builds the WHERE clause shared by the audit log queries
+func auditLogFilter(filters map[string]string) (string, []interface{}) {
 	whereClause := "WHERE 1=1"
 	args := []interface{}{}
 
@@ -651,6 +718,35 @@ func (s *AdminService) GetAuditLogs(page, pageSize int, filters map[string]strin
 		args = append(args, resource)
 	}
 
+	return whereClause, args
+}
+
+func scanAuditLog(rows *sql.Rows) (models.AuditLog, error) {
+	var log models.AuditLog
+	var userID sql.NullInt64
+	var resourceID, details, ipAddress, userAgent, prevHash, hash sql.NullString
+
+	err := rows.Scan(&log.ID, &userID, &log.Username, &log.Action,
+		&log.Resource, &resourceID, &details, &ipAddress, &userAgent,
+		&log.Success, &log.CreatedAt, &prevHash, &hash)
+	if err != nil {
+		return log, err
+	}
+
+	log.UserID = int(userID.Int64)
+	log.ResourceID = resourceID.String
+	log.Details = details.String
+	log.IPAddress = ipAddress.String
+	log.UserAgent = userAgent.String
+	log.PrevHash = prevHash.String
+	log.Hash = hash.String
+
+	return log, nil
+}
+
+func (s *AdminService) GetAuditLogs(page, pageSize int, filters map[string]string) ([]models.AuditLog, int64, error) {
+	whereClause, args := auditLogFilter(filters)
+
 	// Count total
 	countQuery := "SELECT COUNT(*) FROM audit_logs " + whereClause
 	var total int64
@@ -662,8 +758,7 @@ func (s *AdminService) GetAuditLogs(page, pageSize int, filters map[string]strin
 	// Get logs
 	offset := (page - 1) * pageSize
 	query := `
-		SELECT id, user_id, username, action, resource, resource_id, 
-		       details, ip_address, user_agent, success, created_at
+		SELECT ` + auditLogColumns + `
 		FROM audit_logs ` + whereClause + `
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
@@ -679,32 +774,98 @@ func (s *AdminService) GetAuditLogs(page, pageSize int, filters map[string]strin
 
 	var logs []models.AuditLog
 	for rows.Next() {
-		var log models.AuditLog
-		var resourceID, details, ipAddress, userAgent sql.NullString
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			continue
+		}
 
-		err := rows.Scan(&log.ID, &log.UserID, &log.Username, &log.Action,
-			&log.Resource, &resourceID, &details, &ipAddress, &userAgent,
-			&log.Success, &log.CreatedAt)
+		logs = append(logs, log)
+	}
+
+	return logs, total, nil
+}
+
+// ExportAuditLogs returns every audit entry matching filters, oldest first,
+// including the hashes needed to verify the chain offline
+func (s *AdminService) ExportAuditLogs(filters map[string]string) ([]models.AuditLog, error) {
+	whereClause, args := auditLogFilter(filters)
+
+	rows, err := s.DB.Query(`
+		SELECT `+auditLogColumns+`
+		FROM audit_logs `+whereClause+`
+		ORDER BY id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
+	logs := []models.AuditLog{}
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("failed to read audit log: %v", err)
 		}
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
+
+// VerifyAuditChain walks the audit log in insertion order and reports the
+// first entry whose hash or link to the previous entry doesn't match, which
+// indicates an edited, removed or inserted row
+func (s *AdminService) VerifyAuditChain() (*models.AuditVerification, error) {
+	rows, err := s.DB.Query(`
+		SELECT ` + auditLogColumns + `
+		FROM audit_logs
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := &models.AuditVerification{Valid: true}
+	prevHash := ""
+	chained := false
 
-		if resourceID.Valid {
-			log.ResourceID = resourceID.String
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %v", err)
 		}
-		if details.Valid {
-			log.Details = details.String
+
+		if entry.Hash == "" {
+			if !chained {
+				// Written before hashing was introduced
+				result.LegacyEntries++
+				continue
+			}
+			result.Valid = false
+			result.BrokenAtID = entry.ID
+			result.Reason = "entry has no hash"
+			return result, nil
 		}
-		if ipAddress.Valid {
-			log.IPAddress = ipAddress.String
+		chained = true
+
+		if entry.PrevHash != prevHash {
+			result.Valid = false
+			result.BrokenAtID = entry.ID
+			result.Reason = "previous hash does not match, an entry was removed or inserted before this one"
+			return result, nil
 		}
-		if userAgent.Valid {
-			log.UserAgent = userAgent.String
+
+		if auditHash(&entry) != entry.Hash {
+			result.Valid = false
+			result.BrokenAtID = entry.ID
+			result.Reason = "entry contents do not match its hash"
+			return result, nil
 		}
 
-		logs = append(logs, log)
+		prevHash = entry.Hash
+		result.CheckedEntries++
 	}
 
-	return logs, total, nil
+	return result, rows.Err()
 }
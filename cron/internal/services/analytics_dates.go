@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// ErrInvalidDateRange is returned for an analytics query whose start or end
+// date can't be read, or whose start isn't before its end
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+// sqliteTime is how SQLite's CURRENT_TIMESTAMP writes created_at columns
+const sqliteTime = "2006-01-02 15:04:05"
+
+// dateRange limits analytics to rows created from start up to, but not
+// including, end. Either bound may be empty, leaving that side open. Bounds
+// are formatted by newDateRange, so they're safe to write into SQL.
+type dateRange struct {
+	start, end string
+}
+
+// newDateRange reads the query's start and end dates, each a date or an RFC
+// 3339 time. An end date without a time includes the whole of that day.
+func newDateRange(query *models.AnalyticsQuery) (dateRange, error) {
+	var dates dateRange
+	if query == nil {
+		return dates, nil
+	}
+
+	var start, end time.Time
+	var err error
+	if query.StartDate != nil && *query.StartDate != "" {
+		if start, err = parseRangeDate(*query.StartDate, false); err != nil {
+			return dates, fmt.Errorf("%w: start_date %q must be a date or RFC 3339 time", ErrInvalidDateRange, *query.StartDate)
+		}
+		dates.start = start.Format(sqliteTime)
+	}
+	if query.EndDate != nil && *query.EndDate != "" {
+		if end, err = parseRangeDate(*query.EndDate, true); err != nil {
+			return dates, fmt.Errorf("%w: end_date %q must be a date or RFC 3339 time", ErrInvalidDateRange, *query.EndDate)
+		}
+		dates.end = end.Format(sqliteTime)
+	}
+	if dates.start != "" && dates.end != "" && !start.Before(end) {
+		return dates, fmt.Errorf("%w: start_date must be before end_date", ErrInvalidDateRange)
+	}
+	return dates, nil
+}
+
+// parseRangeDate reads value as an RFC 3339 time or a date, in UTC. A date
+// ending a range stands for the start of the following day.
+func parseRangeDate(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// ValidateDateRange checks the query's start and end dates without running it
+func ValidateDateRange(query *models.AnalyticsQuery) error {
+	_, err := newDateRange(query)
+	return err
+}
+
+func (r dateRange) set() bool {
+	return r.start != "" || r.end != ""
+}
+
+// on returns a condition that column falls in the range, or 1=1 without one
+func (r dateRange) on(column string) string {
+	cond := "1=1"
+	if r.start != "" {
+		cond += " AND " + column + " >= '" + r.start + "'"
+	}
+	if r.end != "" {
+		cond += " AND " + column + " < '" + r.end + "'"
+	}
+	return cond
+}
+
+// orSince returns on(column) for a set range, and otherwise a condition that
+// column is after now shifted by the SQLite datetime modifier
+func (r dateRange) orSince(column, modifier string) string {
+	if r.set() {
+		return r.on(column)
+	}
+	return column + " >= datetime('now', '" + modifier + "')"
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// ErrInvalidStrategy is returned for a recommendation strategy
+// GetDownloadRecommendations doesn't know
+var ErrInvalidStrategy = errors.New("invalid recommendation strategy")
+
+// recommendationOrders orders missing shows for each strategy, best first
+var recommendationOrders = map[models.RecommendationStrategy]string{
+	models.RecommendByCompletion: "downloaded * 1.0 / total DESC, total - downloaded, s.date DESC, s.id",
+	models.RecommendByRecency:    "s.date DESC, s.id",
+	models.RecommendByVenue:      "venue_downloads DESC, s.date DESC, s.id",
+}
+
+// GetDownloadRecommendations ranks the missing shows of monitored artists,
+// the shows the gap report lists, by strategy and returns the first limit,
+// with how many are missing in all. A show is missing until it has a
+// completed download; shows already queued or downloading are left out.
+func (s *AnalyticsService) GetDownloadRecommendations(ctx context.Context, strategy models.RecommendationStrategy, limit int) ([]models.DownloadRecommendation, int, error) {
+	order, ok := recommendationOrders[strategy]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %q, must be completion, recency or venue", ErrInvalidStrategy, strategy)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		WITH artist_totals AS (
+			SELECT s.artist_id, COUNT(*) as total,
+				COUNT(CASE WHEN EXISTS (
+					SELECT 1 FROM downloads d WHERE d.show_id = s.id AND d.status = 'completed'
+				) THEN 1 END) as downloaded
+			FROM shows s
+			WHERE s.artist_id IN (`+monitoredArtistIDs+`)
+			GROUP BY s.artist_id
+		), venue_totals AS (
+			SELECT s.venue, COUNT(*) as downloads
+			FROM downloads d
+			JOIN shows s ON s.id = d.show_id
+			WHERE d.status = 'completed'
+			GROUP BY s.venue
+		)
+		SELECT s.id, s.container_id, a.id, a.name, date(s.date), s.venue,
+			COALESCE(s.city, ''), COALESCE(s.state, ''),
+			t.total, t.downloaded, COALESCE(v.downloads, 0) as venue_downloads,
+			COUNT(*) OVER ()
+		FROM shows s
+		JOIN artists a ON a.id = s.artist_id
+		JOIN artist_totals t ON t.artist_id = s.artist_id
+		LEFT JOIN venue_totals v ON v.venue = s.venue
+		WHERE NOT EXISTS (
+			SELECT 1 FROM downloads d
+			WHERE d.show_id = s.id AND d.status IN ('completed', 'pending', 'queued', 'downloading')
+		)
+		ORDER BY `+order+`
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	recommendations := []models.DownloadRecommendation{}
+	missing := 0
+	for rows.Next() {
+		var rec models.DownloadRecommendation
+		var containerID sql.NullInt64
+		var total, downloaded int
+		if err := rows.Scan(&rec.ShowID, &containerID, &rec.ArtistID, &rec.ArtistName, &rec.ShowDate,
+			&rec.Venue, &rec.City, &rec.State, &total, &downloaded, &rec.VenueDownloads, &missing); err != nil {
+			return nil, 0, err
+		}
+		if containerID.Valid {
+			id := int(containerID.Int64)
+			rec.ContainerID = &id
+		}
+		rec.ArtistCompletionPct = float64(downloaded) / float64(total) * 100
+		rec.ArtistMissingShows = total - downloaded
+		rec.Rank = len(recommendations) + 1
+		rec.Reason = recommendationReason(strategy, rec)
+		recommendations = append(recommendations, rec)
+	}
+	return recommendations, missing, rows.Err()
+}
+
+// recommendationReason explains why strategy ranked rec where it did
+func recommendationReason(strategy models.RecommendationStrategy, rec models.DownloadRecommendation) string {
+	switch strategy {
+	case models.RecommendByRecency:
+		return fmt.Sprintf("Performed %s, among the most recent missing shows", rec.ShowDate)
+	case models.RecommendByVenue:
+		return fmt.Sprintf("%d completed downloads of shows at %s", rec.VenueDownloads, rec.Venue)
+	default:
+		if rec.ArtistMissingShows == 1 {
+			return fmt.Sprintf("%s is %.1f%% complete and this is its last missing show", rec.ArtistName, rec.ArtistCompletionPct)
+		}
+		return fmt.Sprintf("%s is %.1f%% complete with %d shows missing", rec.ArtistName, rec.ArtistCompletionPct, rec.ArtistMissingShows)
+	}
+}
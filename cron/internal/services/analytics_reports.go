@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// SaveReport stores a generated report so it can be listed and fetched later
+func (s *AnalyticsService) SaveReport(ctx context.Context, report *models.AnalyticsReport) error {
+	content, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.ExecContext(ctx, `
+		INSERT INTO analytics_reports (id, report_type, timeframe, format, content, generated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, report.ReportID, report.ReportType, report.Timeframe, models.ReportFormatJSON, string(content), report.GeneratedAt)
+	return err
+}
+
+// ListReports returns a page of stored reports, newest first, with the total
+// number stored
+func (s *AnalyticsService) ListReports(ctx context.Context, limit, offset int) ([]models.ReportArtifact, int64, error) {
+	var total int64
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM analytics_reports`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT id, report_type, timeframe, format, LENGTH(CAST(content AS BLOB)), generated_at
+		FROM analytics_reports
+		ORDER BY generated_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reports := []models.ReportArtifact{}
+	for rows.Next() {
+		var report models.ReportArtifact
+		if err := rows.Scan(&report.ReportID, &report.ReportType, &report.Timeframe, &report.Format,
+			&report.SizeBytes, &report.GeneratedAt); err != nil {
+			return nil, 0, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, total, rows.Err()
+}
+
+// GetReport returns a stored report and its content, or sql.ErrNoRows
+func (s *AnalyticsService) GetReport(ctx context.Context, reportID string) (*models.ReportArtifact, []byte, error) {
+	var report models.ReportArtifact
+	var content string
+	err := s.DB.QueryRowContext(ctx, `
+		SELECT id, report_type, timeframe, format, content, generated_at
+		FROM analytics_reports
+		WHERE id = ?
+	`, reportID).Scan(&report.ReportID, &report.ReportType, &report.Timeframe, &report.Format,
+		&content, &report.GeneratedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.SizeBytes = int64(len(content))
+	return &report, []byte(content), nil
+}
+
+// reportTypes are the report types GenerateReport supports
+var reportTypes = map[string]bool{
+	"collection":  true,
+	"artists":     true,
+	"downloads":   true,
+	"system":      true,
+	"performance": true,
+}
+
+// ValidReportType reports whether GenerateReport supports reportType
+func ValidReportType(reportType string) bool {
+	return reportTypes[reportType]
+}
+
+// GenerateReportAsync generates and saves a report in the background. The
+// job's result is the saved report's ReportArtifact, for fetching it.
+func (s *AnalyticsService) GenerateReportAsync(query *models.AnalyticsQuery) *models.Job {
+	job := s.JobManager.CreateJob(models.JobTypeAnalytics)
+	go s.runReportJob(job, query)
+	return job
+}
+
+func (s *AnalyticsService) runReportJob(job *models.Job, query *models.AnalyticsQuery) {
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusRunning
+		j.StartedAt = time.Now()
+		j.Progress = 10
+		j.Message = "Generating " + query.ReportType + " report..."
+	})
+
+	fail := func(err error) {
+		completedAt := time.Now()
+		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+			j.Status = models.JobStatusFailed
+			j.Error = err.Error()
+			j.Message = "Report generation failed"
+			j.CompletedAt = &completedAt
+		})
+	}
+
+	// The request that started the job is long gone, so it has no deadline
+	ctx := context.Background()
+	report, err := s.GenerateReport(ctx, query)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Progress = 90
+		j.Message = "Saving report..."
+	})
+	if err := s.SaveReport(ctx, report); err != nil {
+		fail(err)
+		return
+	}
+
+	artifact, _, err := s.GetReport(ctx, report.ReportID)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	completedAt := time.Now()
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusCompleted
+		j.Progress = 100
+		j.Message = "Report " + report.ReportID + " generated"
+		j.Result = artifact
+		j.CompletedAt = &completedAt
+	})
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateReport_Collection(t *testing.T) {
+	db := testutil.NewDB(t)
+	testutil.InsertShow(t, db, testutil.PhishID, 67895, "2024-08-30", "Dick's Sporting Goods Park")
+	testutil.InsertDownload(t, db, 67892, "completed", 1024)
+	testutil.InsertDownload(t, db, 67895, "completed", 512)
+	testutil.InsertDownload(t, db, 67890, "failed", 0)
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	report, err := service.GenerateReport(context.Background(), &models.AnalyticsQuery{ReportType: "collection"})
+	require.NoError(t, err)
+
+	stats := report.CollectionStats
+	require.NotNil(t, stats)
+	assert.EqualValues(t, testutil.SeededArtists, stats.TotalArtists)
+	assert.EqualValues(t, testutil.SeededShows+1, stats.TotalShows)
+	assert.EqualValues(t, 3, stats.TotalDownloads)
+	assert.InDelta(t, 1.5, stats.TotalSizeGB, 0.001)
+	assert.EqualValues(t, 3, stats.RecentActivity.DownloadsToday)
+	assert.NotEmpty(t, report.Summary)
+}
+
+func TestGenerateReport_UnsupportedType(t *testing.T) {
+	service := NewAnalyticsService(testutil.NewDB(t), models.NewJobManager())
+
+	_, err := service.GenerateReport(context.Background(), &models.AnalyticsQuery{ReportType: "weather"})
+	assert.Error(t, err)
+}
+
+func TestGetArtistAnalytics_CanceledContext(t *testing.T) {
+	service := NewAnalyticsService(testutil.NewDB(t), models.NewJobManager())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := service.GetArtistAnalytics(ctx, &models.AnalyticsQuery{ReportType: "artists"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGetDownloadAnalytics_Sections(t *testing.T) {
+	db := testutil.NewDB(t)
+	testutil.InsertDownload(t, db, 67892, "completed", 1024)
+	testutil.InsertDownload(t, db, 67893, "completed", 512)
+	testutil.InsertDownload(t, db, 67890, "failed", 0)
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	analytics, err := service.GetDownloadAnalytics(context.Background(), &models.AnalyticsQuery{ReportType: "downloads"})
+	require.NoError(t, err)
+
+	assert.Empty(t, analytics.Warnings)
+	assert.EqualValues(t, 3, analytics.FormatBreakdown["FLAC"])
+	assert.EqualValues(t, 3, analytics.QualityBreakdown["16bit"])
+	require.Len(t, analytics.PopularVenues, 3)
+	assert.Len(t, analytics.PeakDownloadHours, 24)
+	assert.NotEmpty(t, analytics.DownloadTrends)
+}
+
+func TestAnalytics_LocationFilter(t *testing.T) {
+	db := testutil.NewDB(t)
+	testutil.InsertDownload(t, db, 67890, "completed", 1024) // Ithaca, NY
+	testutil.InsertDownload(t, db, 67892, "completed", 512)  // New York, NY
+	testutil.InsertDownload(t, db, 67893, "completed", 256)  // Columbia, MD
+	testutil.InsertDownload(t, db, 67894, "failed", 0)       // Chicago, IL
+	service := NewAnalyticsService(db, models.NewJobManager())
+	ctx := context.Background()
+
+	t.Run("collection", func(t *testing.T) {
+		stats, err := service.GetCollectionStats(ctx, &models.AnalyticsQuery{ReportType: "collection"})
+		require.NoError(t, err)
+		assert.InDelta(t, 60.0, stats.CompletionPct, 0.01)
+
+		stats, err = service.GetCollectionStats(ctx, &models.AnalyticsQuery{ReportType: "collection", State: "ny"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, stats.TotalArtists)
+		assert.EqualValues(t, 2, stats.TotalShows)
+		assert.EqualValues(t, 2, stats.DownloadedShows)
+		assert.InDelta(t, 100.0, stats.CompletionPct, 0.01)
+		assert.EqualValues(t, 2, stats.TotalDownloads)
+		assert.InDelta(t, 1.5, stats.TotalSizeGB, 0.001)
+		assert.EqualValues(t, 2, stats.RecentActivity.DownloadsToday)
+
+		stats, err = service.GetCollectionStats(ctx, &models.AnalyticsQuery{ReportType: "collection", Venue: "garden", City: "new york"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, stats.TotalShows)
+		assert.EqualValues(t, 1, stats.TotalDownloads)
+	})
+
+	t.Run("artists", func(t *testing.T) {
+		analytics, err := service.GetArtistAnalytics(ctx, &models.AnalyticsQuery{ReportType: "artists", State: "MD"})
+		require.NoError(t, err)
+		require.Len(t, analytics, 1)
+		assert.Equal(t, testutil.PhishID, analytics[0].ArtistID)
+		assert.EqualValues(t, 1, analytics[0].TotalShows)
+		assert.EqualValues(t, 1, analytics[0].TotalDownloads)
+		assert.EqualValues(t, 1, analytics[0].DownloadGrowthLastMonth)
+	})
+
+	t.Run("downloads", func(t *testing.T) {
+		analytics, err := service.GetDownloadAnalytics(ctx, &models.AnalyticsQuery{ReportType: "downloads", State: "NY"})
+		require.NoError(t, err)
+		assert.Empty(t, analytics.Warnings)
+		assert.EqualValues(t, 2, analytics.TotalDownloads)
+		assert.EqualValues(t, 2, analytics.FormatBreakdown["FLAC"])
+		assert.Len(t, analytics.PopularVenues, 2)
+		require.Len(t, analytics.DownloadTrends, 1)
+		assert.EqualValues(t, 2, analytics.DownloadTrends[0].Count)
+	})
+
+	t.Run("no matching shows", func(t *testing.T) {
+		stats, err := service.GetCollectionStats(ctx, &models.AnalyticsQuery{ReportType: "collection", Venue: "Red Rocks"})
+		require.NoError(t, err)
+		assert.Zero(t, stats.TotalShows)
+		assert.Zero(t, stats.CompletionPct)
+	})
+}
+
+func TestGetCompletionMatrix(t *testing.T) {
+	db := testutil.NewDB(t)
+	for _, artistID := range []int{testutil.GratefulDeadID, testutil.PhishID} {
+		_, err := db.Exec(`INSERT INTO monitors (user_id, artist_id, status, settings) VALUES (?, ?, 'active', '{}')`,
+			testutil.AdminUserID, artistID)
+		require.NoError(t, err)
+	}
+	testutil.InsertShow(t, db, testutil.PhishID, 67895, "2023-08-30", "Dick's Sporting Goods Park")
+	testutil.InsertDownload(t, db, 67890, "completed", 1024) // Grateful Dead 1977
+	testutil.InsertDownload(t, db, 67893, "completed", 512)  // Phish 2023
+	testutil.InsertDownload(t, db, 67892, "failed", 0)       // Phish 1997
+	testutil.InsertDownload(t, db, 67894, "completed", 512)  // Dead & Company, not monitored
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	matrix, err := service.GetCompletionMatrix(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1977", "1989", "1997", "2023"}, matrix.Years)
+	require.Len(t, matrix.Artists, 2)
+
+	dead := matrix.Artists[0]
+	assert.Equal(t, "Grateful Dead", dead.ArtistName)
+	assert.Equal(t, 2, dead.AvailableShows)
+	assert.Equal(t, 1, dead.DownloadedShows)
+	assert.InDelta(t, 50.0, dead.CompletionPct, 0.01)
+	assert.Equal(t, []models.YearCompletion{
+		{Year: "1977", AvailableShows: 1, DownloadedShows: 1, CompletionPct: 100},
+		{Year: "1989", AvailableShows: 1},
+	}, dead.Years)
+
+	phish := matrix.Artists[1]
+	assert.Equal(t, testutil.PhishID, phish.ArtistID)
+	assert.Equal(t, 3, phish.AvailableShows)
+	assert.Equal(t, 1, phish.DownloadedShows)
+	require.Len(t, phish.Years, 2)
+	assert.Equal(t, models.YearCompletion{Year: "1997", AvailableShows: 1}, phish.Years[0])
+	assert.Equal(t, models.YearCompletion{Year: "2023", AvailableShows: 2, DownloadedShows: 1, CompletionPct: 50}, phish.Years[1])
+}
+
+func TestRunSections_FailedSectionWarns(t *testing.T) {
+	var ran bool
+	warnings := runSections(context.Background(), []analyticsSection{
+		{"broken", func(context.Context) error { return errors.New("no such column") }},
+		{"working", func(context.Context) error { ran = true; return nil }},
+	})
+
+	assert.True(t, ran)
+	assert.Equal(t, []string{"broken unavailable: no such column"}, warnings)
+}
+
+func TestGetArtistAnalytics_Details(t *testing.T) {
+	db := testutil.NewDB(t)
+	testutil.InsertDownload(t, db, 67892, "completed", 1024)
+	testutil.InsertDownload(t, db, 67893, "completed", 512)
+	_, err := db.Exec(`UPDATE downloads SET format = 'MP3', quality = '320k' WHERE container_id = 67893`)
+	require.NoError(t, err)
+	testutil.InsertDownload(t, db, 67892, "completed", 1024)
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	analytics, err := service.GetArtistAnalytics(context.Background(), &models.AnalyticsQuery{ReportType: "artists"})
+	require.NoError(t, err)
+	require.Len(t, analytics, testutil.SeededArtists)
+
+	phish := analytics[0]
+	assert.Equal(t, testutil.PhishID, phish.ArtistID)
+	assert.EqualValues(t, 3, phish.TotalDownloads)
+	assert.Equal(t, "FLAC", phish.PreferredFormat)
+	assert.Equal(t, "16bit", phish.PreferredQuality)
+	assert.EqualValues(t, 2, phish.ShowGrowthLastMonth)
+	assert.EqualValues(t, 3, phish.DownloadGrowthLastMonth)
+
+	for _, artist := range analytics[1:] {
+		assert.Empty(t, artist.PreferredFormat, artist.ArtistName)
+		assert.Zero(t, artist.DownloadGrowthLastMonth, artist.ArtistName)
+	}
+}
+
+func BenchmarkGetArtistAnalytics(b *testing.B) {
+	db := testutil.NewDB(b)
+	containerID := 100000
+	for a := 0; a < 100; a++ {
+		artistID := testutil.InsertArtist(b, db, fmt.Sprintf("Artist %d", a), fmt.Sprintf("artist-%d", a))
+		for s := 0; s < 20; s++ {
+			containerID++
+			testutil.InsertShow(b, db, artistID, containerID, fmt.Sprintf("2023-01-%02d", s+1), "Venue")
+			testutil.InsertDownload(b, db, containerID, "completed", 500)
+			testutil.InsertDownload(b, db, containerID, "failed", 0)
+		}
+	}
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	query := &models.AnalyticsQuery{ReportType: "artists"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := service.GetArtistAnalytics(context.Background(), query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetDownloadAnalytics_DateRange(t *testing.T) {
+	db := testutil.NewDB(t)
+	old := testutil.InsertDownload(t, db, 67892, "completed", 1024)
+	testutil.InsertDownload(t, db, 67893, "completed", 512)
+	_, err := db.Exec(`UPDATE downloads SET created_at = '2023-03-15 12:00:00' WHERE id = ?`, old)
+	require.NoError(t, err)
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	start, end := "2023-03-01", "2023-03-15"
+	analytics, err := service.GetDownloadAnalytics(context.Background(), &models.AnalyticsQuery{
+		ReportType: "downloads", StartDate: &start, EndDate: &end,
+	})
+	require.NoError(t, err)
+	// The end date includes the whole day
+	assert.EqualValues(t, 1, analytics.TotalDownloads)
+	assert.InDelta(t, 1.0, analytics.TotalSizeGB, 0.001)
+	require.Len(t, analytics.DownloadTrends, 1)
+	assert.Equal(t, "2023-03-15", analytics.DownloadTrends[0].Date)
+
+	// An open-ended range from a time leaves out the older download
+	start = "2024-01-01T00:00:00Z"
+	analytics, err = service.GetDownloadAnalytics(context.Background(), &models.AnalyticsQuery{
+		ReportType: "downloads", StartDate: &start,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, analytics.TotalDownloads)
+}
+
+func TestNewDateRange_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+	}{
+		{"unreadable start", "last tuesday", ""},
+		{"unreadable end", "", "2024-13-01"},
+		{"start after end", "2024-02-01", "2024-01-01"},
+		{"start equals end", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &models.AnalyticsQuery{StartDate: &tt.start, EndDate: &tt.end}
+			_, err := newDateRange(query)
+			assert.ErrorIs(t, err, ErrInvalidDateRange)
+		})
+	}
+
+	// A single day is a valid range
+	day := "2024-01-01"
+	_, err := newDateRange(&models.AnalyticsQuery{StartDate: &day, EndDate: &day})
+	assert.NoError(t, err)
+}
+
+func TestGetTopArtists_Metrics(t *testing.T) {
+	db := testutil.NewDB(t)
+	testutil.InsertDownload(t, db, 67890, "completed", 2048)
+	testutil.InsertDownload(t, db, 67892, "completed", 512)
+	testutil.InsertDownload(t, db, 67893, "completed", 512)
+	old := testutil.InsertDownload(t, db, 67892, "completed", 100)
+	_, err := db.Exec(`UPDATE downloads SET created_at = datetime('now', '-45 days') WHERE id = ?`, old)
+	require.NoError(t, err)
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	top := func(metric string, timeframe models.AnalyticsTimeframe) []models.TopListItem {
+		items, err := service.GetTopArtists(context.Background(), metric, timeframe, 10)
+		require.NoError(t, err)
+		return items
+	}
+
+	downloads := top("downloads", models.TimeframeAll)
+	require.Len(t, downloads, 2)
+	assert.Equal(t, models.TopListItem{Rank: 1, ID: testutil.PhishID, Name: "Phish", Value: 3, SecondaryValue: 1124.0 / 1024, Unit: "downloads"}, downloads[0])
+	assert.Equal(t, 2, downloads[1].Rank)
+	assert.Equal(t, "Grateful Dead", downloads[1].Name)
+
+	// The timeframe leaves out the older download
+	assert.EqualValues(t, 2, top("downloads", models.TimeframeMonth)[0].Value)
+
+	size := top("size", models.TimeframeAll)
+	assert.Equal(t, "Grateful Dead", size[0].Name)
+	assert.InDelta(t, 2.0, size[0].Value, 0.001)
+
+	completion := top("completion", models.TimeframeAll)
+	require.Len(t, completion, 2)
+	assert.Equal(t, "Phish", completion[0].Name)
+	assert.InDelta(t, 100.0, completion[0].Value, 0.001)
+	assert.InDelta(t, 50.0, completion[1].Value, 0.001)
+
+	// Both grew by one download, so they're ranked by name
+	growth := top("growth", models.TimeframeMonth)
+	require.Len(t, growth, 2)
+	assert.Equal(t, "Grateful Dead", growth[0].Name)
+	assert.Equal(t, "Phish", growth[1].Name)
+	assert.EqualValues(t, 1, growth[1].Value)
+	assert.InDelta(t, 100.0, growth[1].Percentage, 0.001)
+
+	items, err := service.GetTopArtists(context.Background(), "downloads", models.TimeframeAll, 1)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	_, err = service.GetTopArtists(context.Background(), "growth", models.TimeframeAll, 10)
+	assert.ErrorIs(t, err, ErrInvalidTopArtists)
+	_, err = service.GetTopArtists(context.Background(), "popularity", models.TimeframeAll, 10)
+	assert.ErrorIs(t, err, ErrInvalidTopArtists)
+}
+
+func TestGetDownloadRecommendations(t *testing.T) {
+	db := testutil.NewDB(t)
+	for _, artistID := range []int{testutil.GratefulDeadID, testutil.PhishID} {
+		_, err := db.Exec(`INSERT INTO monitors (user_id, artist_id, status, settings) VALUES (?, ?, 'active', '{}')`,
+			testutil.AdminUserID, artistID)
+		require.NoError(t, err)
+	}
+	testutil.InsertShow(t, db, testutil.PhishID, 67895, "2024-08-30", "Dick's Sporting Goods Park")
+	testutil.InsertShow(t, db, testutil.DeadAndCoID, 67896, "2023-06-10", "Madison Square Garden")
+	testutil.InsertDownload(t, db, 67890, "completed", 1024) // Grateful Dead 1977
+	testutil.InsertDownload(t, db, 67893, "completed", 512)  // Phish 2023
+	testutil.InsertDownload(t, db, 67895, "pending", 0)      // Phish 2024, already on its way
+	testutil.InsertDownload(t, db, 67896, "completed", 512)  // Dead & Company, not monitored
+	testutil.InsertDownload(t, db, 67896, "completed", 512)
+
+	service := NewAnalyticsService(db, models.NewJobManager())
+	recommend := func(strategy models.RecommendationStrategy) []models.DownloadRecommendation {
+		recs, missing, err := service.GetDownloadRecommendations(context.Background(), strategy, 10)
+		require.NoError(t, err)
+		// Grateful Dead 1989 and Phish 1997
+		assert.Equal(t, 2, missing)
+		require.Len(t, recs, 2)
+		assert.Equal(t, 1, recs[0].Rank)
+		assert.Equal(t, 2, recs[1].Rank)
+		return recs
+	}
+
+	// Grateful Dead is 50% complete, Phish only a third
+	recs := recommend(models.RecommendByCompletion)
+	assert.Equal(t, "Grateful Dead", recs[0].ArtistName)
+	assert.Equal(t, "1989-07-07", recs[0].ShowDate)
+	assert.InDelta(t, 50.0, recs[0].ArtistCompletionPct, 0.01)
+	assert.Equal(t, 1, recs[0].ArtistMissingShows)
+	assert.Contains(t, recs[0].Reason, "last missing show")
+	require.NotNil(t, recs[0].ContainerID)
+	assert.Equal(t, 67891, *recs[0].ContainerID)
+
+	recs = recommend(models.RecommendByRecency)
+	assert.Equal(t, "1997-12-31", recs[0].ShowDate)
+	assert.Equal(t, "1989-07-07", recs[1].ShowDate)
+
+	// Madison Square Garden has two downloads of another artist's show
+	recs = recommend(models.RecommendByVenue)
+	assert.Equal(t, "Madison Square Garden", recs[0].Venue)
+	assert.Equal(t, 2, recs[0].VenueDownloads)
+	assert.Equal(t, 0, recs[1].VenueDownloads)
+
+	recs, missing, err := service.GetDownloadRecommendations(context.Background(), models.RecommendByRecency, 1)
+	require.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, 2, missing)
+
+	_, _, err = service.GetDownloadRecommendations(context.Background(), "popularity", 10)
+	assert.ErrorIs(t, err, ErrInvalidStrategy)
+}
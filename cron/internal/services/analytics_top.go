@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// ErrInvalidTopArtists is returned for a top artists metric or timeframe
+// GetTopArtists can't rank by
+var ErrInvalidTopArtists = errors.New("invalid top artists query")
+
+// TopArtistMetrics are the metrics GetTopArtists ranks by, with the unit of
+// each value
+var TopArtistMetrics = map[string]string{
+	"downloads":  "downloads",
+	"shows":      "shows",
+	"size":       "GB",
+	"completion": "%",
+	"growth":     "downloads",
+}
+
+// timeframeDays returns how many days back a timeframe reaches, or 0 for all
+// time
+func timeframeDays(timeframe models.AnalyticsTimeframe) int {
+	switch timeframe {
+	case models.TimeframeDay:
+		return 1
+	case models.TimeframeWeek:
+		return 7
+	case models.TimeframeMonth:
+		return 30
+	case models.TimeframeYear:
+		return 365
+	default:
+		return 0
+	}
+}
+
+// artistTotals are the figures an artist is ranked by
+type artistTotals struct {
+	id                int
+	name              string
+	shows             int64 // added in the timeframe
+	downloads         int64 // created in the timeframe
+	sizeGB            float64
+	catalogShows      int64
+	downloadedShows   int64
+	previousDownloads int64 // created in the timeframe before this one
+}
+
+// GetTopArtists ranks artists by metric, best first, and returns the top
+// limit. Downloads, shows and size count what was added in the timeframe;
+// completion is the share of the artist's whole catalog downloaded; growth is
+// the change in downloads from the previous timeframe, so it needs a bounded
+// one. Artists scoring nothing on the metric are left out.
+func (s *AnalyticsService) GetTopArtists(ctx context.Context, metric string, timeframe models.AnalyticsTimeframe, limit int) ([]models.TopListItem, error) {
+	unit, ok := TopArtistMetrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported metric %q", ErrInvalidTopArtists, metric)
+	}
+	days := timeframeDays(timeframe)
+	if metric == "growth" && days == 0 {
+		return nil, fmt.Errorf("%w: the growth metric needs a timeframe of day, week, month or year", ErrInvalidTopArtists)
+	}
+
+	// Conditions for the timeframe and the one before it. days is a whole
+	// number, so the modifiers are safe to write into SQL.
+	current, previous := "1=1", "0=1"
+	if days > 0 {
+		current = fmt.Sprintf("created_at >= datetime('now', '-%d days')", days)
+		previous = fmt.Sprintf("created_at >= datetime('now', '-%d days') AND created_at < datetime('now', '-%d days')", 2*days, days)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT a.id, a.name,
+			(SELECT COUNT(*) FROM shows WHERE artist_id = a.id AND `+current+`),
+			(SELECT COUNT(*) FROM downloads WHERE `+current+`
+			 AND show_id IN (SELECT id FROM shows WHERE artist_id = a.id)),
+			(SELECT COALESCE(SUM(size_mb), 0) / 1024.0 FROM downloads WHERE status = 'completed' AND `+current+`
+			 AND show_id IN (SELECT id FROM shows WHERE artist_id = a.id)),
+			(SELECT COUNT(*) FROM shows WHERE artist_id = a.id),
+			(SELECT COUNT(DISTINCT show_id) FROM downloads WHERE status = 'completed'
+			 AND show_id IN (SELECT id FROM shows WHERE artist_id = a.id)),
+			(SELECT COUNT(*) FROM downloads WHERE `+previous+`
+			 AND show_id IN (SELECT id FROM shows WHERE artist_id = a.id))
+		FROM artists a
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.TopListItem
+	for rows.Next() {
+		var a artistTotals
+		if err := rows.Scan(&a.id, &a.name, &a.shows, &a.downloads, &a.sizeGB,
+			&a.catalogShows, &a.downloadedShows, &a.previousDownloads); err != nil {
+			return nil, err
+		}
+
+		item := models.TopListItem{ID: a.id, Name: a.name, Unit: unit}
+		switch metric {
+		case "shows":
+			item.Value, item.SecondaryValue = float64(a.shows), float64(a.downloads)
+		case "size":
+			item.Value, item.SecondaryValue = a.sizeGB, float64(a.downloads)
+		case "completion":
+			if a.catalogShows > 0 {
+				item.Value = float64(a.downloadedShows) / float64(a.catalogShows) * 100
+			}
+			item.SecondaryValue = float64(a.downloadedShows)
+		case "growth":
+			item.Value, item.SecondaryValue = float64(a.downloads-a.previousDownloads), float64(a.downloads)
+			if a.previousDownloads > 0 {
+				item.Percentage = item.Value / float64(a.previousDownloads) * 100
+			}
+		default:
+			item.Value, item.SecondaryValue = float64(a.downloads), a.sizeGB
+		}
+
+		// Growth can be negative, so there it's downloads on neither side
+		// that scores nothing
+		scored := item.Value > 0
+		if metric == "growth" {
+			scored = a.downloads > 0 || a.previousDownloads > 0
+		}
+		if scored {
+			items = append(items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Value != items[j].Value {
+			return items[i].Value > items[j].Value
+		}
+		return items[i].Name < items[j].Name
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	for i := range items {
+		items[i].Rank = i + 1
+	}
+	return items, nil
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// apiKeyPrefix marks nugs API keys so they are recognizable in configs and logs
+const apiKeyPrefix = "nugs_"
+
+// ErrInvalidAPIKey is returned for unknown and revoked keys
+var ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+
+type APIKeyService struct {
+	DB *sql.DB
+}
+
+func NewAPIKeyService(db *sql.DB) *APIKeyService {
+	return &APIKeyService{DB: db}
+}
+
+// CreateKey issues a new key. The plaintext key is only returned here; the
+// database keeps its hash.
+func (s *APIKeyService) CreateKey(req *models.APIKeyRequest, createdBy int) (*models.APIKeyResponse, error) {
+	for _, scope := range req.Scopes {
+		if !isValidAPIKeyScope(scope) {
+			return &models.APIKeyResponse{
+				Success: false,
+				Error:   fmt.Sprintf("Invalid scope: %s", scope),
+			}, nil
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %v", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+	keyPrefix := key[:len(apiKeyPrefix)+8]
+
+	scopesJSON, _ := json.Marshal(req.Scopes)
+
+	var creator sql.NullInt64
+	if createdBy > 0 {
+		creator = sql.NullInt64{Int64: int64(createdBy), Valid: true}
+	}
+
+	result, err := s.DB.Exec(`
+		INSERT INTO api_keys (name, key_prefix, key_hash, scopes, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'))
+	`, req.Name, keyPrefix, hashAPIKey(key), string(scopesJSON), creator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store API key: %v", err)
+	}
+
+	keyID, _ := result.LastInsertId()
+	apiKey, err := s.GetKey(int(keyID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyResponse{
+		Success: true,
+		Key:     key,
+		APIKey:  apiKey,
+		Message: "API key created. Store it now, it will not be shown again",
+	}, nil
+}
+
+// ListKeys returns all keys, including revoked ones, newest first
+func (s *APIKeyService) ListKeys() ([]models.APIKey, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, name, key_prefix, scopes, created_by, last_used_at, revoked_at, created_at
+		FROM api_keys
+		ORDER BY created_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetKey returns a key by ID
+func (s *APIKeyService) GetKey(keyID int) (*models.APIKey, error) {
+	row := s.DB.QueryRow(`
+		SELECT id, name, key_prefix, scopes, created_by, last_used_at, revoked_at, created_at
+		FROM api_keys WHERE id = ?
+	`, keyID)
+	return scanAPIKey(row)
+}
+
+// RevokeKey stops a key from authenticating. Revoked keys stay listed for auditing.
+func (s *APIKeyService) RevokeKey(keyID int) error {
+	result, err := s.DB.Exec(`
+		UPDATE api_keys SET revoked_at = datetime('now')
+		WHERE id = ? AND revoked_at IS NULL
+	`, keyID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key not found")
+	}
+
+	return nil
+}
+
+// Authenticate looks up an active key, records its use and returns its ID and scopes
+func (s *APIKeyService) Authenticate(key string) (int, []string, error) {
+	var keyID int
+	var scopesJSON string
+	err := s.DB.QueryRow(`
+		SELECT id, scopes FROM api_keys
+		WHERE key_hash = ? AND revoked_at IS NULL
+	`, hashAPIKey(key)).Scan(&keyID, &scopesJSON)
+	if err == sql.ErrNoRows {
+		return 0, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var scopes []string
+	if err := json.Unmarshal([]byte(scopesJSON), &scopes); err != nil {
+		return 0, nil, fmt.Errorf("failed to parse API key scopes: %v", err)
+	}
+
+	s.DB.Exec("UPDATE api_keys SET last_used_at = datetime('now') WHERE id = ?", keyID)
+
+	return keyID, scopes, nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func isValidAPIKeyScope(scope string) bool {
+	for _, valid := range models.APIKeyScopes {
+		if scope == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopesJSON string
+	var createdBy sql.NullInt64
+	var lastUsedAt, revokedAt sql.NullTime
+
+	err := row.Scan(&key.ID, &key.Name, &key.KeyPrefix, &scopesJSON, &createdBy,
+		&lastUsedAt, &revokedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal([]byte(scopesJSON), &key.Scopes)
+	key.CreatedBy = int(createdBy.Int64)
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
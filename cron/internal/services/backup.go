@@ -0,0 +1,509 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/database"
+	"github.com/jmagar/nugs/cron/internal/lockfile"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
+)
+
+// backupFormatVersion is the BackupManifest version this build writes and reads
+const backupFormatVersion = 1
+
+// Archive entry names in a backup bundle
+const (
+	backupManifestEntry = "manifest.json"
+	backupDatabaseEntry = "database/nugs_api.db"
+)
+
+// backupDataFiles are the files under data/ a bundle carries: the shows
+// tracking file and the cached catalog
+var backupDataFiles = []string{"shows.json", "catalog_cache.json"}
+
+// ErrInvalidBackup is returned when an imported archive is not a backup bundle
+var ErrInvalidBackup = errors.New("invalid backup archive")
+
+// ErrImportNotConfirmed is returned for imports requested without confirmation
+var ErrImportNotConfirmed = errors.New("backup import must be confirmed")
+
+// BackupBundle is a prepared full-system backup: a database snapshot plus the
+// config and data files, ready to be streamed as a tar.gz archive
+type BackupBundle struct {
+	Manifest models.BackupManifest
+
+	dir     string            // Temp directory holding the database snapshot
+	sources map[string]string // Archive entry name to local file
+}
+
+// PrepareBackup snapshots the database and collects the config and data files
+// for a backup bundle. Close the bundle to remove the snapshot.
+func (s *AdminService) PrepareBackup(runBy string, client models.ClientInfo) (*BackupBundle, error) {
+	if err := CheckMaintenanceAllowed(s.DB); err != nil {
+		return nil, err
+	}
+
+	bundle, err := s.newBackupBundle()
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAuditAction(0, runBy, "export_backup", "maintenance", "",
+		fmt.Sprintf("Exported backup bundle with %d files", len(bundle.Manifest.Files)),
+		client.IPAddress, client.UserAgent, true)
+
+	return bundle, nil
+}
+
+func (s *AdminService) newBackupBundle() (*BackupBundle, error) {
+	dir, err := ioutil.TempDir("", "nugs-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %v", err)
+	}
+
+	bundle := &BackupBundle{
+		Manifest: models.BackupManifest{
+			Version:   backupFormatVersion,
+			CreatedAt: time.Now().UTC(),
+		},
+		dir:     dir,
+		sources: make(map[string]string),
+	}
+
+	snapshot := filepath.Join(dir, "nugs_api.db")
+	if err := database.Snapshot(s.DB, snapshot); err != nil {
+		bundle.Close()
+		return nil, err
+	}
+	bundle.sources[backupDatabaseEntry] = snapshot
+
+	configs, err := ioutil.ReadDir(paths.Resolve("configs"))
+	if err != nil && !os.IsNotExist(err) {
+		bundle.Close()
+		return nil, fmt.Errorf("failed to read configs directory: %v", err)
+	}
+	for _, entry := range configs {
+		if entry.Mode().IsRegular() && strings.HasSuffix(entry.Name(), ".json") {
+			bundle.sources["configs/"+entry.Name()] = paths.Config(entry.Name())
+		}
+	}
+
+	for _, name := range backupDataFiles {
+		if stat, err := os.Stat(paths.Data(name)); err == nil && stat.Mode().IsRegular() {
+			bundle.sources["data/"+name] = paths.Data(name)
+		}
+	}
+
+	for name := range bundle.sources {
+		bundle.Manifest.Files = append(bundle.Manifest.Files, name)
+	}
+	sort.Strings(bundle.Manifest.Files)
+
+	return bundle, nil
+}
+
+// Stream writes the bundle to w as a tar.gz archive, manifest first
+func (b *BackupBundle) Stream(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    backupManifestEntry,
+		Mode:    0600,
+		Size:    int64(len(manifest)),
+		ModTime: b.Manifest.CreatedAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	for _, name := range b.Manifest.Files {
+		if err := writeTarFile(tw, name, b.sources[name]); err != nil {
+			return fmt.Errorf("failed to add %s: %v", name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeTarFile adds the file at source to the archive. The size is taken
+// when the file is opened, so a file growing meanwhile is cut at that size.
+func writeTarFile(tw *tar.Writer, name, source string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	}); err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tw, file, stat.Size())
+	return err
+}
+
+// Close removes the database snapshot
+func (b *BackupBundle) Close() error {
+	return os.RemoveAll(b.dir)
+}
+
+// ImportBackup starts a job restoring a bundle written by BackupBundle.Stream.
+// The archive is unpacked and validated before the job starts, so a bad
+// bundle is rejected up front. The job saves the current state as a bundle
+// under data/backups, restores or merges the database, replaces each config
+// and data file atomically and optionally starts a catalog refresh.
+func (s *AdminService) ImportBackup(r io.Reader, req models.BackupImportRequest, runBy string, client models.ClientInfo) (*models.Job, error) {
+	if !req.Confirm {
+		return nil, ErrImportNotConfirmed
+	}
+	if err := CheckMaintenanceAllowed(s.DB); err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "nugs-restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %v", err)
+	}
+
+	manifest, staged, err := unpackBackup(r, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	if path, ok := staged[backupDatabaseEntry]; ok {
+		if err := database.CheckBackup(path); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+	}
+
+	job := s.JobManager.CreateJob(models.JobTypeBackupImport)
+
+	go func() {
+		defer os.RemoveAll(dir)
+		s.performImport(job, req, manifest, staged, runBy, client)
+	}()
+
+	return job, nil
+}
+
+func (s *AdminService) performImport(job *models.Job, req models.BackupImportRequest, manifest *models.BackupManifest, staged map[string]string, runBy string, client models.ClientInfo) {
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusRunning
+		j.StartedAt = time.Now()
+		j.Progress = 5
+		j.Message = "Backing up current state..."
+	})
+
+	result := &models.BackupImportResult{
+		Mode:            req.Mode,
+		BackupCreatedAt: manifest.CreatedAt,
+		FilesRestored:   []string{},
+	}
+
+	err := s.restoreBundle(job, req, manifest, staged, result)
+	completedAt := time.Now()
+	if err != nil {
+		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+			j.Status = models.JobStatusFailed
+			j.Error = err.Error()
+			j.Message = "Backup import failed"
+			j.Result = result
+			j.CompletedAt = &completedAt
+		})
+		s.logAuditAction(0, runBy, "import_backup", "maintenance", job.ID,
+			"Backup import failed: "+err.Error(), client.IPAddress, client.UserAgent, false)
+		return
+	}
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusCompleted
+		j.Progress = 100
+		j.Message = fmt.Sprintf("Backup from %s imported", manifest.CreatedAt.Format(time.RFC3339))
+		j.Result = result
+		j.CompletedAt = &completedAt
+	})
+
+	s.logAuditAction(0, runBy, "import_backup", "maintenance", job.ID,
+		fmt.Sprintf("Imported backup bundle from %s (%s), previous state saved to %s",
+			manifest.CreatedAt.Format(time.RFC3339), req.Mode, result.PreImportBackup),
+		client.IPAddress, client.UserAgent, true)
+}
+
+// restoreBundle saves the current state, then restores the staged bundle,
+// recording what it did in result. The locks it takes are released before it
+// returns, so they are free again by the time the job is reported finished.
+func (s *AdminService) restoreBundle(job *models.Job, req models.BackupImportRequest, manifest *models.BackupManifest, staged map[string]string, result *models.BackupImportResult) error {
+	// The detector, monitor and sync service rewrite shows.json under these
+	// locks. They are taken before anything changes, so a run in progress
+	// fails the import rather than writing its copy back over the restored one.
+	if len(manifest.Files) > 0 {
+		for _, name := range []string{"detector.lock", "monitor.lock"} {
+			lock, err := lockfile.Acquire(paths.Data(name))
+			if err != nil {
+				return fmt.Errorf("cannot restore files: %w", err)
+			}
+			defer lock.Release()
+		}
+	}
+
+	preImport, err := s.savePreImportBackup()
+	if err != nil {
+		return fmt.Errorf("failed to back up current state: %v", err)
+	}
+	result.PreImportBackup = preImport
+
+	if path, ok := staged[backupDatabaseEntry]; ok {
+		if req.Mode == models.BackupImportMerge {
+			s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+				j.Progress = 30
+				j.Message = "Merging database..."
+			})
+			merged, err := database.Merge(s.DB, path)
+			if err != nil {
+				return err
+			}
+			result.RowsMerged = merged
+		} else {
+			s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+				j.Progress = 30
+				j.Message = "Restoring database..."
+			})
+			if err := database.Restore(s.DB, path); err != nil {
+				return err
+			}
+		}
+		result.DatabaseRestored = true
+	}
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Progress = 70
+		j.Message = "Restoring config and data files..."
+	})
+
+	for _, name := range manifest.Files {
+		var target string
+		switch {
+		case strings.HasPrefix(name, "configs/"):
+			target = paths.Config(path.Base(name))
+		case strings.HasPrefix(name, "data/"):
+			target = paths.Data(path.Base(name))
+		default:
+			continue
+		}
+
+		if err := replaceFile(target, staged[name]); err != nil {
+			return fmt.Errorf("failed to restore %s: %v", name, err)
+		}
+		result.FilesRestored = append(result.FilesRestored, name)
+	}
+
+	if req.ReseedCatalog {
+		// Not forced, so a catalog the bundle brought back fresh is kept
+		refresh := NewCatalogRefreshService(s.DB, s.JobManager).StartRefresh(false)
+		result.CatalogRefreshJobID = refresh.ID
+	}
+
+	return nil
+}
+
+// savePreImportBackup writes a bundle of the current state to data/backups
+// and returns its path
+func (s *AdminService) savePreImportBackup() (string, error) {
+	bundle, err := s.newBackupBundle()
+	if err != nil {
+		return "", err
+	}
+	defer bundle.Close()
+
+	dir := paths.Data("backups")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	// TempFile keeps imports within the same second from sharing a file
+	file, err := ioutil.TempFile(dir, "pre-import-"+bundle.Manifest.CreatedAt.Format("20060102-150405")+"-*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	target := file.Name()
+	if err := bundle.Stream(file); err != nil {
+		file.Close()
+		os.Remove(target)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(target)
+		return "", err
+	}
+
+	return target, nil
+}
+
+// unpackBackup extracts the archive into dir and returns its manifest and the
+// staged path of every entry it lists
+func unpackBackup(r io.Reader, dir string) (*models.BackupManifest, map[string]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	defer gz.Close()
+
+	var manifest *models.BackupManifest
+	staged := make(map[string]string)
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		if header.Typeflag != tar.TypeReg || !validBackupEntry(header.Name) {
+			return nil, nil, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBackup, header.Name)
+		}
+
+		if header.Name == backupManifestEntry {
+			manifest = &models.BackupManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("%w: unreadable manifest: %v", ErrInvalidBackup, err)
+			}
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return nil, nil, err
+		}
+		file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = io.Copy(file, tr)
+		file.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		staged[header.Name] = target
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("%w: missing %s", ErrInvalidBackup, backupManifestEntry)
+	}
+	if manifest.Version != backupFormatVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, manifest.Version)
+	}
+	for _, name := range manifest.Files {
+		if _, ok := staged[name]; !ok {
+			return nil, nil, fmt.Errorf("%w: %s is listed but missing", ErrInvalidBackup, name)
+		}
+		// Small JSON files are checked so a damaged bundle can't clobber good config
+		if strings.HasSuffix(name, ".json") && name != "data/catalog_cache.json" {
+			data, err := ioutil.ReadFile(staged[name])
+			if err != nil {
+				return nil, nil, err
+			}
+			if !json.Valid(data) {
+				return nil, nil, fmt.Errorf("%w: %s is not valid JSON", ErrInvalidBackup, name)
+			}
+		}
+	}
+
+	return manifest, staged, nil
+}
+
+// validBackupEntry reports whether name is an entry a bundle may contain
+func validBackupEntry(name string) bool {
+	switch {
+	case name == backupManifestEntry, name == backupDatabaseEntry:
+		return true
+	case strings.HasPrefix(name, "configs/"):
+		base := strings.TrimPrefix(name, "configs/")
+		return base != "" && !strings.ContainsAny(base, `/\`) && base != ".." && strings.HasSuffix(base, ".json")
+	case strings.HasPrefix(name, "data/"):
+		base := strings.TrimPrefix(name, "data/")
+		for _, allowed := range backupDataFiles {
+			if base == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// replaceFile copies source over target through a temp file in target's
+// directory, so target is never left partially written. An existing target
+// keeps its permissions and a new one is readable only by the owner.
+func replaceFile(target, source string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(target), filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if stat, err := os.Stat(target); err == nil {
+		if err := os.Chmod(tmpPath, stat.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmpPath, target)
+}
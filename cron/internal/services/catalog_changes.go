@@ -0,0 +1,234 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/api"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/version"
+)
+
+// catalogChangesListLimit caps how many shows a refresh lists as added or removed
+const catalogChangesListLimit = 100
+
+// catalogChangeTracker compares the shows stored before a refresh with the
+// shows the refreshed catalog contains
+type catalogChangeTracker struct {
+	before map[int]models.CatalogChangeShow
+	after  map[int]models.CatalogChangeShow
+}
+
+func newCatalogChangeTracker() *catalogChangeTracker {
+	return &catalogChangeTracker{
+		before: make(map[int]models.CatalogChangeShow),
+		after:  make(map[int]models.CatalogChangeShow),
+	}
+}
+
+// snapshot records the stored shows matching where, e.g. "a.name = ?", as
+// they were before the refresh
+func (t *catalogChangeTracker) snapshot(s *CatalogRefreshService, where string, args ...interface{}) error {
+	rows, err := s.DB.Query(`
+		SELECT s.container_id, a.name, s.venue, COALESCE(s.city, ''), COALESCE(s.state, ''), s.date
+		FROM shows s
+		JOIN artists a ON s.artist_id = a.id
+		WHERE s.container_id IS NOT NULL AND `+where, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var show models.CatalogChangeShow
+		if err := rows.Scan(&show.ContainerID, &show.ArtistName, &show.VenueName,
+			&show.VenueCity, &show.VenueState, &show.PerformanceDate); err != nil {
+			return err
+		}
+		if len(show.PerformanceDate) > 10 {
+			show.PerformanceDate = show.PerformanceDate[:10]
+		}
+		t.before[show.ContainerID] = show
+	}
+
+	return rows.Err()
+}
+
+// seen records a show present in the refreshed catalog
+func (t *catalogChangeTracker) seen(artistName string, show Show) {
+	date := show.PerformanceDate
+	if parsed, err := time.Parse("1/2/2006", show.PerformanceDate); err == nil {
+		date = parsed.Format("2006-01-02")
+	} else if parsed, err := time.Parse("2006/01/02", show.PerformanceDateFormatted); err == nil {
+		date = parsed.Format("2006-01-02")
+	}
+
+	t.after[show.ContainerID] = models.CatalogChangeShow{
+		ContainerID:     show.ContainerID,
+		ArtistName:      artistName,
+		VenueName:       show.VenueName,
+		VenueCity:       show.VenueCity,
+		VenueState:      show.VenueState,
+		PerformanceDate: date,
+	}
+}
+
+// initialImport reports whether nothing was stored before the refresh
+func (t *catalogChangeTracker) initialImport() bool {
+	return len(t.before) == 0
+}
+
+// added returns every show new in the catalog, newest first
+func (t *catalogChangeTracker) added() []models.CatalogChangeShow {
+	return sortedCatalogShows(t.after, t.before)
+}
+
+// removed returns every show that disappeared from the catalog, newest first
+func (t *catalogChangeTracker) removed() []models.CatalogChangeShow {
+	return sortedCatalogShows(t.before, t.after)
+}
+
+// changes summarizes the refresh, capping the listed shows
+func (t *catalogChangeTracker) changes() *models.CatalogChanges {
+	added := t.added()
+	removed := t.removed()
+
+	changes := &models.CatalogChanges{
+		AddedCount:          len(added),
+		RemovedCount:        len(removed),
+		ArtistsWithNewShows: []models.CatalogArtistChange{},
+		InitialImport:       t.initialImport(),
+	}
+
+	newShows := make(map[string]int)
+	for _, show := range added {
+		newShows[show.ArtistName]++
+	}
+	for name, count := range newShows {
+		changes.ArtistsWithNewShows = append(changes.ArtistsWithNewShows, models.CatalogArtistChange{
+			ArtistName: name,
+			NewShows:   count,
+		})
+	}
+	sort.Slice(changes.ArtistsWithNewShows, func(i, j int) bool {
+		a, b := changes.ArtistsWithNewShows[i], changes.ArtistsWithNewShows[j]
+		if a.NewShows != b.NewShows {
+			return a.NewShows > b.NewShows
+		}
+		return a.ArtistName < b.ArtistName
+	})
+
+	if len(added) > catalogChangesListLimit {
+		added = added[:catalogChangesListLimit]
+		changes.Truncated = true
+	}
+	if len(removed) > catalogChangesListLimit {
+		removed = removed[:catalogChangesListLimit]
+		changes.Truncated = true
+	}
+	changes.AddedShows = added
+	changes.RemovedShows = removed
+
+	return changes
+}
+
+// sortedCatalogShows returns the shows in from that are missing in other
+func sortedCatalogShows(from, other map[int]models.CatalogChangeShow) []models.CatalogChangeShow {
+	shows := []models.CatalogChangeShow{}
+	for containerID, show := range from {
+		if _, exists := other[containerID]; !exists {
+			shows = append(shows, show)
+		}
+	}
+
+	sort.Slice(shows, func(i, j int) bool {
+		if shows[i].PerformanceDate != shows[j].PerformanceDate {
+			return shows[i].PerformanceDate > shows[j].PerformanceDate
+		}
+		return shows[i].ContainerID > shows[j].ContainerID
+	})
+
+	return shows
+}
+
+// notifyRefreshCompleted fires the catalog_refresh webhook with the changes and,
+// when catalog_refresh_new_show_webhooks is enabled, a new_show webhook per
+// added show. New-show webhooks are skipped for the initial import, where
+// every show is new.
+func (s *CatalogRefreshService) notifyRefreshCompleted(job *models.Job, result *RefreshResult, tracker *catalogChangeTracker) {
+	payload := models.CatalogRefreshPayload{
+		JobID:           job.ID,
+		Status:          string(models.JobStatusCompleted),
+		Duration:        result.Duration,
+		ShowsImported:   result.ImportedShows,
+		ArtistsImported: result.ImportedArtists,
+		Changes:         result.Changes,
+	}
+	if err := s.webhooks.TriggerEvent(models.WebhookEventCatalogRefresh, payload); err != nil {
+		log.Printf("Failed to trigger catalog_refresh webhooks: %v", err)
+	}
+
+	if tracker.initialImport() || !GetConfigBool(s.DB, "catalog_refresh_new_show_webhooks", false) {
+		return
+	}
+
+	for _, show := range tracker.added() {
+		var newShow models.NewShowPayload
+		err := s.DB.QueryRow(`
+			SELECT s.id, a.id FROM shows s
+			JOIN artists a ON s.artist_id = a.id
+			WHERE s.container_id = ?
+		`, show.ContainerID).Scan(&newShow.Show.ID, &newShow.Artist.ID)
+		if err != nil {
+			continue
+		}
+
+		newShow.Artist.Name = show.ArtistName
+		newShow.Show.ContainerID = show.ContainerID
+		newShow.Show.Title = show.ArtistName + " - " + show.VenueName + " - " + show.PerformanceDate
+		newShow.Show.VenueName = show.VenueName
+		newShow.Show.VenueCity = show.VenueCity
+		newShow.Show.VenueState = show.VenueState
+		newShow.Show.PerformanceDate = show.PerformanceDate
+
+		if err := s.webhooks.TriggerEvent(models.WebhookEventNewShow, newShow); err != nil {
+			log.Printf("Failed to trigger new_show webhooks: %v", err)
+			return
+		}
+	}
+}
+
+// notifyRefreshFailed fires the catalog_refresh webhook for a failed refresh
+func (s *CatalogRefreshService) notifyRefreshFailed(job *models.Job, err error) {
+	payload := models.CatalogRefreshPayload{
+		JobID:  job.ID,
+		Status: string(models.JobStatusFailed),
+		Error:  err.Error(),
+	}
+	if err := s.webhooks.TriggerEvent(models.WebhookEventCatalogRefresh, payload); err != nil {
+		log.Printf("Failed to trigger catalog_refresh webhooks: %v", err)
+	}
+}
+
+// alertAPIErrorRate notifies system_alert webhooks that the refresh's API
+// client opened its circuit breaker because too many requests failed
+func (s *CatalogRefreshService) alertAPIErrorRate(alert api.ErrorRateAlert) {
+	var payload models.SystemAlertPayload
+	payload.Alert.Type = "api_error_rate"
+	payload.Alert.Severity = "error"
+	payload.Alert.Message = fmt.Sprintf("nugs.net API error rate is %.0f%% over the last %d requests, circuit breaker opened", alert.ErrorRate, alert.Window)
+	payload.Alert.Component = "nugs_api"
+	payload.System.Version = "v" + version.Version
+	payload.APIErrorRate = &models.APIErrorRatePayload{
+		Account:   alert.Account,
+		Endpoint:  alert.Endpoint,
+		ErrorRate: alert.ErrorRate,
+		Threshold: alert.Threshold,
+		Window:    alert.Window,
+	}
+	if err := s.webhooks.TriggerEvent(models.WebhookEventSystemAlert, payload); err != nil {
+		log.Printf("Failed to trigger system_alert webhooks: %v", err)
+	}
+}
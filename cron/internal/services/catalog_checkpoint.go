@@ -0,0 +1,81 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// catalogRefreshCheckpointKey is the system_config row holding the artists an
+// interrupted artist refresh has not imported yet
+const catalogRefreshCheckpointKey = "catalog_refresh_checkpoint"
+
+// ErrNoRefreshCheckpoint is returned when there is no interrupted refresh to resume
+var ErrNoRefreshCheckpoint = errors.New("no interrupted catalog refresh to resume")
+
+// RefreshCheckpoint records how far an artist refresh got. It is written after
+// every imported artist, so a cancelled or crashed refresh can pick up where it
+// stopped instead of fetching every artist again.
+type RefreshCheckpoint struct {
+	JobID            string    `json:"job_id"`
+	PendingArtistIDs []int     `json:"pending_artist_ids"`
+	TotalArtists     int       `json:"total_artists"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// GetRefreshCheckpoint returns the checkpoint of the last interrupted artist refresh
+func (s *CatalogRefreshService) GetRefreshCheckpoint() (*RefreshCheckpoint, error) {
+	var value string
+	err := s.DB.QueryRow("SELECT value FROM system_config WHERE key = ?", catalogRefreshCheckpointKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoRefreshCheckpoint
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint RefreshCheckpoint
+	if err := json.Unmarshal([]byte(value), &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse refresh checkpoint: %v", err)
+	}
+	if len(checkpoint.PendingArtistIDs) == 0 {
+		return nil, ErrNoRefreshCheckpoint
+	}
+
+	return &checkpoint, nil
+}
+
+// ResumeArtistRefresh starts a new artist refresh for the artists the last
+// interrupted refresh did not import
+func (s *CatalogRefreshService) ResumeArtistRefresh() (*models.Job, error) {
+	checkpoint, err := s.GetRefreshCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.StartArtistRefresh(checkpoint.PendingArtistIDs), nil
+}
+
+func (s *CatalogRefreshService) saveRefreshCheckpoint(checkpoint *RefreshCheckpoint) error {
+	checkpoint.UpdatedAt = time.Now()
+	value, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(`
+		INSERT OR REPLACE INTO system_config (key, value, description, updated_at)
+		VALUES (?, ?, 'Artists left to import by an interrupted catalog refresh', ?)
+	`, catalogRefreshCheckpointKey, string(value), time.Now())
+
+	return err
+}
+
+func (s *CatalogRefreshService) clearRefreshCheckpoint() error {
+	_, err := s.DB.Exec("DELETE FROM system_config WHERE key = ?", catalogRefreshCheckpointKey)
+	return err
+}
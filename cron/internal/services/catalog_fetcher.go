@@ -0,0 +1,79 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// Defaults for per-artist catalog fetches, used when the
+// catalog_refresh_workers and catalog_refresh_timeout_seconds config keys are unset
+const (
+	DefaultCatalogFetchWorkers        = 4
+	DefaultCatalogFetchTimeoutSeconds = 30
+)
+
+// artistShowsClient fetches one artist's shows. SafeAPIClient implements it and
+// enforces the upstream rate limits on every call.
+type artistShowsClient interface {
+	GetArtistShows(artistID int) ([]byte, error)
+}
+
+type artistFetchResult struct {
+	ArtistID int
+	Body     []byte
+	Err      error
+}
+
+// fetchArtistShows fetches each artist's shows with at most workers requests
+// in flight. Progress is reported into the job, scaled between startPct and
+// endPct. If the job is cancelled no new fetches are started, results for
+// artists that were never fetched are omitted and cancelled is true.
+func (s *CatalogRefreshService) fetchArtistShows(job *models.Job, client artistShowsClient, artistIDs []int, workers, startPct, endPct int) (fetched []artistFetchResult, cancelled bool) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(artistIDs) {
+		workers = len(artistIDs)
+	}
+
+	work := make(chan int)
+	results := make(chan artistFetchResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for artistID := range work {
+				body, err := client.GetArtistShows(artistID)
+				results <- artistFetchResult{ArtistID: artistID, Body: body, Err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(work)
+		for _, artistID := range artistIDs {
+			if job.IsCancellationRequested() {
+				cancelled = true
+				return
+			}
+			work <- artistID
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	fetched = make([]artistFetchResult, 0, len(artistIDs))
+	for result := range results {
+		fetched = append(fetched, result)
+
+		s.reportArtistProgress(job, len(fetched), len(artistIDs), startPct, endPct, "fetched")
+	}
+
+	return fetched, cancelled
+}
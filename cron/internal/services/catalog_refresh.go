@@ -1,8 +1,10 @@
 package services
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -12,13 +14,23 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jmagar/nugs/cron/internal/api"
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/nugsconfig"
+	"github.com/jmagar/nugs/cron/internal/paths"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// errRefreshCancelled is returned by the refresh steps when the job is cancelled mid-run
+var errRefreshCancelled = errors.New("refresh cancelled")
+
 type CatalogRefreshService struct {
 	DB         *sql.DB
 	JobManager *models.JobManager
+	webhooks   *WebhookService
+
+	// CatalogManager builds the catalog_manager command used to fetch the catalog
+	CatalogManager func(args ...string) *exec.Cmd
 }
 
 type RefreshResult struct {
@@ -29,7 +41,11 @@ type RefreshResult struct {
 	ErrorShows      int64  `json:"error_shows"`
 	TotalArtists    int64  `json:"total_artists"`
 	ImportedArtists int64  `json:"imported_artists"`
+	FailedArtists   int64  `json:"failed_artists,omitempty"` // Artists whose shows could not be fetched
 	Duration        string `json:"duration"`
+
+	// Changes lists the shows added and removed compared to before the refresh
+	Changes *models.CatalogChanges `json:"changes,omitempty"`
 }
 
 type CatalogResponse struct {
@@ -68,8 +84,10 @@ type CatalogCache struct {
 
 func NewCatalogRefreshService(db *sql.DB, jobManager *models.JobManager) *CatalogRefreshService {
 	return &CatalogRefreshService{
-		DB:         db,
-		JobManager: jobManager,
+		DB:             db,
+		JobManager:     jobManager,
+		webhooks:       NewWebhookService(db, jobManager),
+		CatalogManager: catalogManagerCommand,
 	}
 }
 
@@ -82,6 +100,234 @@ func (s *CatalogRefreshService) StartRefresh(force bool) *models.Job {
 	return job
 }
 
+// StartArtistRefresh refreshes the shows of specific artists, identified by
+// their nugs.net artist IDs, fetching them concurrently from the API
+func (s *CatalogRefreshService) StartArtistRefresh(nugsArtistIDs []int) *models.Job {
+	job := s.JobManager.CreateJob(models.JobTypeCatalogRefresh)
+
+	go s.runArtistRefresh(job, nugsArtistIDs)
+
+	return job
+}
+
+func (s *CatalogRefreshService) runArtistRefresh(job *models.Job, nugsArtistIDs []int) {
+	startTime := time.Now()
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusRunning
+		j.StartedAt = startTime
+		j.Message = "Authenticating with Nugs.net..."
+	})
+
+	fail := func(err error) {
+		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+			j.Status = models.JobStatusFailed
+			j.Error = err.Error()
+			j.Message = "Artist refresh failed"
+			completedAt := time.Now()
+			j.CompletedAt = &completedAt
+		})
+		s.notifyRefreshFailed(job, err)
+	}
+
+	config, err := loadNugsConfig()
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	client := api.NewSafeAPIClient()
+	timeout := GetConfigInt(s.DB, "catalog_refresh_timeout_seconds", DefaultCatalogFetchTimeoutSeconds)
+	client.SetRequestTimeout(time.Duration(timeout) * time.Second)
+	client.OnErrorRateAlert(s.alertAPIErrorRate)
+
+	if err := client.AuthenticateAccounts(config.NugsAccounts()); err != nil {
+		fail(fmt.Errorf("authentication failed: %v", err))
+		return
+	}
+
+	checkpoint := &RefreshCheckpoint{
+		JobID:            job.ID,
+		PendingArtistIDs: append([]int(nil), nugsArtistIDs...),
+		TotalArtists:     len(nugsArtistIDs),
+	}
+	if err := s.saveRefreshCheckpoint(checkpoint); err != nil {
+		log.Printf("Failed to save refresh checkpoint: %v", err)
+	}
+
+	workers := GetConfigInt(s.DB, "catalog_refresh_workers", DefaultCatalogFetchWorkers)
+	fetched, cancelled := s.fetchArtistShows(job, client, nugsArtistIDs, workers, 10, 80)
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Progress = 80
+		j.Message = "Importing shows..."
+	})
+
+	// Artists fetched before a cancellation are still imported, so the
+	// checkpoint only keeps the ones that were never fetched
+	result := &RefreshResult{TotalArtists: int64(len(nugsArtistIDs))}
+	tracker := newCatalogChangeTracker()
+	for i, artist := range fetched {
+		s.reportArtistProgress(job, i+1, len(fetched), 80, 100, "imported")
+
+		if artist.Err != nil {
+			log.Printf("Failed to fetch shows for artist %d: %v", artist.ArtistID, artist.Err)
+			result.FailedArtists++
+			continue
+		}
+
+		if err := s.importArtistShows(artist.ArtistID, artist.Body, result, tracker); err != nil {
+			log.Printf("Failed to import shows for artist %d: %v", artist.ArtistID, err)
+			result.FailedArtists++
+			continue
+		}
+		result.ImportedArtists++
+
+		checkpoint.PendingArtistIDs = removeArtistID(checkpoint.PendingArtistIDs, artist.ArtistID)
+		if err := s.saveRefreshCheckpoint(checkpoint); err != nil {
+			log.Printf("Failed to save refresh checkpoint: %v", err)
+		}
+	}
+
+	if len(checkpoint.PendingArtistIDs) == 0 {
+		if err := s.clearRefreshCheckpoint(); err != nil {
+			log.Printf("Failed to clear refresh checkpoint: %v", err)
+		}
+	}
+
+	result.Duration = time.Since(startTime).String()
+	result.Changes = tracker.changes()
+	completedAt := time.Now()
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		if cancelled {
+			j.Status = models.JobStatusCancelled
+			j.Message = fmt.Sprintf("Artist refresh cancelled after %d of %d artists, %d left to resume",
+				result.ImportedArtists, len(nugsArtistIDs), len(checkpoint.PendingArtistIDs))
+		} else {
+			j.Status = models.JobStatusCompleted
+			j.Progress = 100
+			j.Message = fmt.Sprintf("Refreshed %d shows from %d artists (%d failed): %d new, %d removed",
+				result.ImportedShows, result.ImportedArtists, result.FailedArtists,
+				result.Changes.AddedCount, result.Changes.RemovedCount)
+		}
+		j.Result = result
+		j.CompletedAt = &completedAt
+	})
+
+	if !cancelled {
+		s.notifyRefreshCompleted(job, result, tracker)
+	}
+}
+
+// removeArtistID returns ids without artistID
+func removeArtistID(ids []int, artistID int) []int {
+	for i, id := range ids {
+		if id == artistID {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// importArtistShows upserts the artist and shows from a catalog.containersAll
+// response, recording the artist's shows before and after in tracker
+func (s *CatalogRefreshService) importArtistShows(nugsArtistID int, body []byte, result *RefreshResult, tracker *catalogChangeTracker) error {
+	var response CatalogResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to parse shows: %v", err)
+	}
+
+	var shows []Show
+	for _, container := range response.Response.Containers {
+		var show Show
+		if err := json.Unmarshal(container, &show); err != nil {
+			result.ErrorShows++
+			continue
+		}
+		shows = append(shows, show)
+	}
+	result.TotalShows += int64(len(response.Response.Containers))
+
+	if len(shows) == 0 {
+		return nil
+	}
+
+	artistName := strings.TrimSpace(shows[0].ArtistName)
+	slug := strings.ToLower(strings.ReplaceAll(artistName, " ", "-"))
+	slug = strings.ReplaceAll(slug, "&", "and")
+
+	if err := tracker.snapshot(s, "a.name = ?", artistName); err != nil {
+		return fmt.Errorf("failed to read existing shows: %v", err)
+	}
+	for _, show := range shows {
+		tracker.seen(artistName, show)
+	}
+
+	_, err := s.DB.Exec(`
+		INSERT INTO artists (name, slug, show_count, is_active, nugs_artist_id, created_at, updated_at)
+		VALUES (?, ?, ?, true, ?, datetime('now'), datetime('now'))
+		ON CONFLICT(name) DO UPDATE SET
+			show_count = excluded.show_count,
+			nugs_artist_id = excluded.nugs_artist_id,
+			updated_at = excluded.updated_at
+	`, artistName, slug, len(shows), nugsArtistID)
+	if err != nil {
+		return fmt.Errorf("failed to save artist: %v", err)
+	}
+
+	var artistID int
+	if err := s.DB.QueryRow("SELECT id FROM artists WHERE name = ?", artistName).Scan(&artistID); err != nil {
+		return fmt.Errorf("failed to look up artist: %v", err)
+	}
+
+	for _, show := range shows {
+		result.ProcessedShows++
+
+		performanceDate, err := time.Parse("1/2/2006", show.PerformanceDate)
+		if err != nil {
+			performanceDate, err = time.Parse("2006/01/02", show.PerformanceDateFormatted)
+			if err != nil {
+				log.Printf("Failed to parse date for show %d: %v", show.ContainerID, err)
+				result.ErrorShows++
+				continue
+			}
+		}
+
+		_, err = s.DB.Exec(`
+			INSERT INTO shows (container_id, artist_id, date, venue, city, state, country,
+				duration_minutes, is_available, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, 'USA', 0, ?, datetime('now'), datetime('now'))
+			ON CONFLICT(container_id) DO UPDATE SET
+				venue = excluded.venue,
+				city = excluded.city,
+				state = excluded.state,
+				is_available = excluded.is_available,
+				updated_at = excluded.updated_at
+		`, show.ContainerID, artistID, performanceDate, show.VenueName,
+			show.VenueCity, show.VenueState, show.ActiveState == "AVAILABLE")
+		if err != nil {
+			log.Printf("Failed to save show %d: %v", show.ContainerID, err)
+			result.ErrorShows++
+			continue
+		}
+
+		result.ImportedShows++
+	}
+
+	return nil
+}
+
+// loadNugsConfig reads the nugs.net credentials from configs/config.json
+func loadNugsConfig() (*models.Config, error) {
+	config, err := nugsconfig.Load(paths.Config("config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load nugs config: %v", err)
+	}
+
+	return config, nil
+}
+
 func (s *CatalogRefreshService) runRefresh(job *models.Job, force bool) {
 	startTime := time.Now()
 
@@ -89,9 +335,13 @@ func (s *CatalogRefreshService) runRefresh(job *models.Job, force bool) {
 		j.Status = models.JobStatusRunning
 		j.StartedAt = startTime
 		j.Message = "Starting catalog refresh..."
+		if force {
+			j.Message = "Starting forced catalog refresh..."
+		}
 	})
 
-	// Check if we should skip refresh based on last update time
+	// Check if we should skip refresh based on last update time. Forcing
+	// rebuilds the catalog regardless.
 	if !force {
 		lastRefresh, err := s.getLastRefreshTime()
 		if err == nil && time.Since(lastRefresh) < 4*time.Hour {
@@ -107,9 +357,19 @@ func (s *CatalogRefreshService) runRefresh(job *models.Job, force bool) {
 	}
 
 	result := &RefreshResult{}
+	tracker := newCatalogChangeTracker()
 
 	// Use existing catalog_manager command
-	err := s.refreshUsingCatalogManager(job, result)
+	err := s.refreshUsingCatalogManager(job, result, tracker)
+	if err == errRefreshCancelled {
+		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+			j.Status = models.JobStatusCancelled
+			j.Message = "Catalog refresh cancelled"
+			completedAt := time.Now()
+			j.CompletedAt = &completedAt
+		})
+		return
+	}
 	if err != nil {
 		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
 			j.Status = models.JobStatusFailed
@@ -118,26 +378,31 @@ func (s *CatalogRefreshService) runRefresh(job *models.Job, force bool) {
 			completedAt := time.Now()
 			j.CompletedAt = &completedAt
 		})
+		s.notifyRefreshFailed(job, err)
 		return
 	}
 
 	// Update job with final results
 	result.Duration = time.Since(startTime).String()
+	result.Changes = tracker.changes()
 	completedAt := time.Now()
 
 	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
 		j.Status = models.JobStatusCompleted
 		j.Progress = 100
-		j.Message = fmt.Sprintf("Refresh completed: %d shows from %d artists", result.TotalShows, result.TotalArtists)
+		j.Message = fmt.Sprintf("Refresh completed: %d shows from %d artists, %d new, %d removed",
+			result.TotalShows, result.TotalArtists, result.Changes.AddedCount, result.Changes.RemovedCount)
 		j.Result = result
 		j.CompletedAt = &completedAt
 	})
 
+	s.notifyRefreshCompleted(job, result, tracker)
+
 	// Update last refresh time
 	s.setLastRefreshTime(time.Now())
 }
 
-func (s *CatalogRefreshService) refreshUsingCatalogManager(job *models.Job, result *RefreshResult) error {
+func (s *CatalogRefreshService) refreshUsingCatalogManager(job *models.Job, result *RefreshResult, tracker *catalogChangeTracker) error {
 	// Update progress
 	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
 		j.Progress = 10
@@ -145,17 +410,33 @@ func (s *CatalogRefreshService) refreshUsingCatalogManager(job *models.Job, resu
 	})
 
 	// Check for cancellation
+	if job.IsCancellationRequested() {
+		return errRefreshCancelled
+	}
+
+	// Execute catalog_manager refresh, killing it if the job is cancelled
+	cmd := s.CatalogManager("refresh")
+
+	var outputBuf bytes.Buffer
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = &outputBuf
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start catalog_manager: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var err error
 	select {
+	case err = <-done:
 	case <-job.Cancel:
-		return fmt.Errorf("refresh cancelled")
-	default:
+		cmd.Process.Kill()
+		<-done
+		return errRefreshCancelled
 	}
 
-	// Execute catalog_manager refresh
-	cmd := exec.Command("./bin/catalog_manager", "refresh")
-	cmd.Dir = "/home/jmagar/code/nugs/cron"
-
-	output, err := cmd.CombinedOutput()
+	output := outputBuf.Bytes()
 	if err != nil {
 		return fmt.Errorf("catalog_manager failed: %v, output: %s", err, string(output))
 	}
@@ -169,7 +450,10 @@ func (s *CatalogRefreshService) refreshUsingCatalogManager(job *models.Job, resu
 	// Read and process catalog cache file
 	outputStr := string(output)
 	if strings.Contains(outputStr, "Catalog refreshed successfully") {
-		err = s.importCatalogData(job, result)
+		err = s.importCatalogData(job, result, tracker)
+		if err == errRefreshCancelled {
+			return err
+		}
 		if err != nil {
 			return fmt.Errorf("failed to import catalog data: %v", err)
 		}
@@ -184,7 +468,7 @@ func (s *CatalogRefreshService) refreshUsingCatalogManager(job *models.Job, resu
 	return nil
 }
 
-func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *RefreshResult) error {
+func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *RefreshResult, tracker *catalogChangeTracker) error {
 	// Read the catalog cache file
 	catalogPath := filepath.Join("data", "catalog_cache.json")
 	data, err := ioutil.ReadFile(catalogPath)
@@ -202,19 +486,17 @@ func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *Refre
 	// Update progress
 	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
 		j.Progress = 60
-		j.Message = "Clearing existing data..."
+		j.Message = "Updating existing data..."
 	})
 
-	// Clear existing data (removing seed data)
-	_, err = s.DB.Exec("DELETE FROM shows")
-	if err != nil {
-		return fmt.Errorf("failed to clear shows table: %v", err)
+	// Remember what was stored so the refresh can report what changed
+	if err := tracker.snapshot(s, "1 = 1"); err != nil {
+		return fmt.Errorf("failed to read existing shows: %v", err)
 	}
 
-	_, err = s.DB.Exec("DELETE FROM artists")
-	if err != nil {
-		return fmt.Errorf("failed to clear artists table: %v", err)
-	}
+	// Artists and shows are updated in place rather than cleared and
+	// reinserted. Deleting them would cascade to monitors and downloads,
+	// wiping both on every full refresh.
 
 	// Update progress
 	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
@@ -222,9 +504,8 @@ func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *Refre
 		j.Message = "Importing artists..."
 	})
 
-	// Extract and insert unique artists
+	// Upsert unique artists by name
 	artistMap := make(map[string]int)
-	artistCounter := 1
 
 	for artistName := range catalog.ShowsByArtist {
 		if artistName == "" {
@@ -235,19 +516,23 @@ func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *Refre
 		slug := strings.ToLower(strings.ReplaceAll(artistName, " ", "-"))
 		slug = strings.ReplaceAll(slug, "&", "and")
 
-		// Insert artist
-		_, err = s.DB.Exec(`
-			INSERT INTO artists (id, name, slug, show_count, is_active, nugs_artist_id, created_at, updated_at) 
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-			artistCounter, artistName, slug, len(catalog.ShowsByArtist[artistName]), true, nil, time.Now(), time.Now())
+		var artistID int
+		err = s.DB.QueryRow(`
+			INSERT INTO artists (name, slug, show_count, is_active, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(name) DO UPDATE SET
+				show_count = excluded.show_count,
+				is_active = excluded.is_active,
+				updated_at = excluded.updated_at
+			RETURNING id`,
+			artistName, slug, len(catalog.ShowsByArtist[artistName]), true, time.Now(), time.Now()).Scan(&artistID)
 
 		if err != nil {
-			log.Printf("Failed to insert artist %s: %v", artistName, err)
+			log.Printf("Failed to import artist %s: %v", artistName, err)
 			continue
 		}
 
-		artistMap[artistName] = artistCounter
-		artistCounter++
+		artistMap[artistName] = artistID
 	}
 
 	// Update progress
@@ -256,15 +541,27 @@ func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *Refre
 		j.Message = "Importing shows..."
 	})
 
-	// Insert shows
+	// Upsert shows by container ID
 	showCounter := 0
+	artistsProcessed := 0
+	inCatalog := make(map[int]bool)
 	for artistName, shows := range catalog.ShowsByArtist {
 		artistID, exists := artistMap[artistName]
 		if !exists {
 			continue
 		}
 
+		if job.IsCancellationRequested() {
+			return errRefreshCancelled
+		}
+
+		artistsProcessed++
+		s.reportArtistProgress(job, artistsProcessed, len(artistMap), 80, 90, "processed")
+
 		for _, show := range shows {
+			tracker.seen(artistName, show)
+			inCatalog[show.ContainerID] = true
+
 			// Parse the performance date
 			performanceDate, err := time.Parse("1/2/2006", show.PerformanceDate)
 			if err != nil {
@@ -277,15 +574,23 @@ func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *Refre
 			}
 
 			_, err = s.DB.Exec(`
-				INSERT INTO shows (container_id, artist_id, date, venue, city, state, country, 
-					duration_minutes, is_available, created_at, updated_at) 
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-				show.ContainerID, artistID, performanceDate, show.VenueName, 
-				show.VenueCity, show.VenueState, "USA", 0, 
+				INSERT INTO shows (container_id, artist_id, date, venue, city, state, country,
+					duration_minutes, is_available, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(container_id) DO UPDATE SET
+					artist_id = excluded.artist_id,
+					date = excluded.date,
+					venue = excluded.venue,
+					city = excluded.city,
+					state = excluded.state,
+					is_available = excluded.is_available,
+					updated_at = excluded.updated_at`,
+				show.ContainerID, artistID, performanceDate, show.VenueName,
+				show.VenueCity, show.VenueState, "USA", 0,
 				show.ActiveState == "AVAILABLE", time.Now(), time.Now())
 
 			if err != nil {
-				log.Printf("Failed to insert show %d: %v", show.ContainerID, err)
+				log.Printf("Failed to import show %d: %v", show.ContainerID, err)
 				continue
 			}
 
@@ -293,6 +598,12 @@ func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *Refre
 		}
 	}
 
+	// Shows gone from the catalog stay, with their downloads, but are no
+	// longer available
+	if err := s.markUnavailable(inCatalog); err != nil {
+		return err
+	}
+
 	// Update result statistics
 	result.TotalShows = int64(catalog.TotalShows)
 	result.ImportedShows = int64(showCounter)
@@ -303,6 +614,59 @@ func (s *CatalogRefreshService) importCatalogData(job *models.Job, result *Refre
 	return nil
 }
 
+// markUnavailable flags every show whose container is not in inCatalog as
+// unavailable
+func (s *CatalogRefreshService) markUnavailable(inCatalog map[int]bool) error {
+	rows, err := s.DB.Query(`SELECT container_id FROM shows WHERE container_id IS NOT NULL AND is_available = 1`)
+	if err != nil {
+		return fmt.Errorf("failed to list shows: %v", err)
+	}
+	var gone []int
+	for rows.Next() {
+		var containerID int
+		if err := rows.Scan(&containerID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan show: %v", err)
+		}
+		if !inCatalog[containerID] {
+			gone = append(gone, containerID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list shows: %v", err)
+	}
+
+	for _, containerID := range gone {
+		if _, err := s.DB.Exec(`UPDATE shows SET is_available = 0, updated_at = ? WHERE container_id = ?`, time.Now(), containerID); err != nil {
+			return fmt.Errorf("failed to mark show %d unavailable: %v", containerID, err)
+		}
+	}
+	return nil
+}
+
+// reportArtistProgress sets the job's progress to done/total scaled between
+// startPct and endPct. Updates are limited to about one per percent of the
+// artists so large catalogs don't flood job status readers.
+func (s *CatalogRefreshService) reportArtistProgress(job *models.Job, done, total, startPct, endPct int, verb string) {
+	if total <= 0 {
+		return
+	}
+
+	step := total / 100
+	if step < 1 {
+		step = 1
+	}
+	if done%step != 0 && done != total {
+		return
+	}
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Progress = startPct + (endPct-startPct)*done/total
+		j.Message = fmt.Sprintf("%d/%d artists %s", done, total, verb)
+	})
+}
+
 func (s *CatalogRefreshService) getLastRefreshTime() (time.Time, error) {
 	var lastRefresh string
 	err := s.DB.QueryRow(`
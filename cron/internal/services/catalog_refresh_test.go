@@ -0,0 +1,58 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportCatalogData_KeepsDownloadsAndMonitors(t *testing.T) {
+	db := testutil.NewDB(t)
+	downloadID := testutil.InsertDownload(t, db, 67890, "completed", 512)
+	_, err := db.Exec(`INSERT INTO monitors (user_id, artist_id, settings) VALUES (?, ?, '{}')`,
+		testutil.AdminUserID, testutil.GratefulDeadID)
+	require.NoError(t, err)
+
+	// The refreshed catalog still has Barton Hall, with a corrected venue,
+	// and no longer lists JFK Stadium
+	catalog := CatalogCache{ShowsByArtist: map[string][]Show{
+		"Grateful Dead": {{
+			ContainerID: 67890, ArtistName: "Grateful Dead", VenueName: "Barton Hall",
+			VenueCity: "Ithaca", VenueState: "NY", PerformanceDate: "5/8/1977", ActiveState: "AVAILABLE",
+		}},
+	}}
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "data"), 0o755))
+	data, err := json.Marshal(catalog)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data", "catalog_cache.json"), data, 0o644))
+	t.Chdir(dir)
+
+	jobManager := models.NewJobManager()
+	service := NewCatalogRefreshService(db, jobManager)
+	job := jobManager.CreateJob(models.JobTypeCatalogRefresh)
+	require.NoError(t, service.importCatalogData(job, &RefreshResult{}, newCatalogChangeTracker()))
+
+	// The download still points at its show, which was updated in place
+	var venue string
+	err = db.QueryRow(`
+		SELECT s.venue FROM downloads d JOIN shows s ON s.id = d.show_id
+		WHERE d.id = ?`, downloadID).Scan(&venue)
+	require.NoError(t, err)
+	assert.Equal(t, "Barton Hall", venue)
+
+	var monitors int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM monitors WHERE artist_id = ?`, testutil.GratefulDeadID).Scan(&monitors))
+	assert.Equal(t, 1, monitors)
+
+	// The show missing from the catalog is kept but unavailable
+	var available bool
+	require.NoError(t, db.QueryRow(`SELECT is_available FROM shows WHERE container_id = 67891`).Scan(&available))
+	assert.False(t, available)
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// cleanupSampleSize caps how many items each cleanup category lists
+const cleanupSampleSize = 10
+
+// orphanedFileMinAge keeps cleanup away from files a running download may
+// still be writing
+const orphanedFileMinAge = 24 * time.Hour
+
+// cleanupPlan is everything a cleanup will delete. A real run deletes
+// exactly the items its plan enumerated, so a preceding dry run shows
+// the same items unless the data changed in between.
+type cleanupPlan struct {
+	jobIDs       []string
+	deliveryIDs  []int64
+	executionIDs []int64
+	reportIDs    []string
+	files        []string
+	result       *models.CleanupResult
+}
+
+// planCleanup enumerates the items the requested cleanup categories cover
+func (s *AdminService) planCleanup(req *models.CleanupRequest, currentJobID string) (*cleanupPlan, error) {
+	plan := &cleanupPlan{
+		result: &models.CleanupResult{
+			DryRun:     req.DryRun,
+			Categories: make(map[string]*models.CleanupItems),
+		},
+	}
+
+	if req.OldJobs {
+		retentionDays := GetConfigInt(s.DB, "job_retention_days", 7)
+		cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+		items := &models.CleanupItems{
+			Criteria: fmt.Sprintf("finished more than %d days ago", retentionDays),
+			Sample:   []string{},
+		}
+
+		jobs := s.JobManager.ListJobs()
+		sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+		for _, job := range jobs {
+			if job.ID == currentJobID || job.Status == models.JobStatusRunning || job.Status == models.JobStatusPending {
+				continue
+			}
+			finishedAt := job.CreatedAt
+			if job.CompletedAt != nil {
+				finishedAt = *job.CompletedAt
+			}
+			if !finishedAt.Before(cutoff) {
+				continue
+			}
+
+			plan.jobIDs = append(plan.jobIDs, job.ID)
+			if len(items.Sample) < cleanupSampleSize {
+				items.Sample = append(items.Sample, fmt.Sprintf("%s %s %s at %s",
+					job.ID, job.Type, job.Status, finishedAt.Format(time.RFC3339)))
+			}
+		}
+		items.Count = len(plan.jobIDs)
+		plan.result.Categories["old_jobs"] = items
+	}
+
+	if req.OldDeliveries {
+		retentionDays := GetConfigInt(s.DB, "webhook_delivery_retention_days", 30)
+
+		items := &models.CleanupItems{
+			Criteria: fmt.Sprintf("created more than %d days ago", retentionDays),
+			Sample:   []string{},
+		}
+
+		rows, err := s.DB.Query(`
+			SELECT id, event_type, created_at FROM webhook_deliveries
+			WHERE created_at < datetime('now', ?)
+			ORDER BY created_at, id
+		`, fmt.Sprintf("-%d days", retentionDays))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list webhook deliveries: %v", err)
+		}
+		for rows.Next() {
+			var id int64
+			var eventType, createdAt string
+			if err := rows.Scan(&id, &eventType, &createdAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+			}
+
+			plan.deliveryIDs = append(plan.deliveryIDs, id)
+			if len(items.Sample) < cleanupSampleSize {
+				items.Sample = append(items.Sample, fmt.Sprintf("#%d %s at %s", id, eventType, createdAt))
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list webhook deliveries: %v", err)
+		}
+		items.Count = len(plan.deliveryIDs)
+		plan.result.Categories["old_deliveries"] = items
+	}
+
+	if req.OldExecutions {
+		retentionDays := GetConfigInt(s.DB, "schedule_execution_retention_days", 30)
+		keepRecent := GetConfigInt(s.DB, "schedule_execution_keep_recent", 20)
+
+		items := &models.CleanupItems{
+			Criteria: fmt.Sprintf("started more than %d days ago, beyond the %d most recent per schedule", retentionDays, keepRecent),
+			Sample:   []string{},
+		}
+
+		// Unfinished executions are left alone however old they are
+		rows, err := s.DB.Query(`
+			SELECT id, schedule_id, status, started_at FROM (
+				SELECT id, schedule_id, status, started_at,
+				       ROW_NUMBER() OVER (PARTITION BY schedule_id ORDER BY started_at DESC, id DESC) AS recency
+				FROM schedule_executions
+			)
+			WHERE recency > ? AND started_at < datetime('now', ?) AND status NOT IN ('pending', 'running')
+			ORDER BY started_at, id
+		`, keepRecent, fmt.Sprintf("-%d days", retentionDays))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list schedule executions: %v", err)
+		}
+		for rows.Next() {
+			var id int64
+			var scheduleID int
+			var status, startedAt string
+			if err := rows.Scan(&id, &scheduleID, &status, &startedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan schedule execution: %v", err)
+			}
+
+			plan.executionIDs = append(plan.executionIDs, id)
+			if len(items.Sample) < cleanupSampleSize {
+				items.Sample = append(items.Sample, fmt.Sprintf("#%d schedule %d %s at %s", id, scheduleID, status, startedAt))
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list schedule executions: %v", err)
+		}
+		items.Count = len(plan.executionIDs)
+		plan.result.Categories["old_executions"] = items
+	}
+
+	if req.OldReports {
+		retentionDays := GetConfigInt(s.DB, "report_retention_days", 30)
+		keepRecent := GetConfigInt(s.DB, "report_keep_recent", 20)
+
+		items := &models.CleanupItems{
+			Criteria: fmt.Sprintf("generated more than %d days ago, beyond the %d most recent", retentionDays, keepRecent),
+			Sample:   []string{},
+		}
+
+		// generated_at is stored by the driver with its offset, so the ages
+		// are compared as times rather than in SQL
+		cutoff := time.Now().AddDate(0, 0, -retentionDays)
+		rows, err := s.DB.Query(`SELECT id, report_type, generated_at FROM analytics_reports`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list analytics reports: %v", err)
+		}
+		type report struct {
+			id, reportType string
+			generatedAt    time.Time
+		}
+		var reports []report
+		for rows.Next() {
+			var r report
+			if err := rows.Scan(&r.id, &r.reportType, &r.generatedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan analytics report: %v", err)
+			}
+			reports = append(reports, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list analytics reports: %v", err)
+		}
+
+		// Newest first, so the first keepRecent are always kept
+		sort.Slice(reports, func(i, j int) bool {
+			if !reports[i].generatedAt.Equal(reports[j].generatedAt) {
+				return reports[i].generatedAt.After(reports[j].generatedAt)
+			}
+			return reports[i].id > reports[j].id
+		})
+		for i := len(reports) - 1; i >= keepRecent && i >= 0; i-- {
+			r := reports[i]
+			if !r.generatedAt.Before(cutoff) {
+				continue
+			}
+			plan.reportIDs = append(plan.reportIDs, r.id)
+			if len(items.Sample) < cleanupSampleSize {
+				items.Sample = append(items.Sample, fmt.Sprintf("%s %s at %s", r.id, r.reportType, r.generatedAt.Format(time.RFC3339)))
+			}
+		}
+		items.Count = len(plan.reportIDs)
+		plan.result.Categories["old_reports"] = items
+	}
+
+	if req.OldFiles {
+		downloadPath := GetConfigString(s.DB, "default_download_path", "/downloads")
+
+		items := &models.CleanupItems{
+			Criteria: fmt.Sprintf("files in %s no download references, untouched for %s", downloadPath, orphanedFileMinAge),
+			Sample:   []string{},
+		}
+
+		files, err := s.findOrphanedFiles(downloadPath)
+		if err != nil {
+			return nil, err
+		}
+		plan.files = files
+		for _, file := range files {
+			if len(items.Sample) >= cleanupSampleSize {
+				break
+			}
+			items.Sample = append(items.Sample, file)
+		}
+		items.Count = len(plan.files)
+		plan.result.Categories["orphaned_files"] = items
+	}
+
+	for _, items := range plan.result.Categories {
+		plan.result.TotalItems += items.Count
+	}
+
+	return plan, nil
+}
+
+// findOrphanedFiles lists the files directly in downloadPath that no download
+// record points at, either through download_path or by the
+// <Artist>_<container_id>.<format> name the download manager writes
+func (s *AdminService) findOrphanedFiles(downloadPath string) ([]string, error) {
+	entries, err := ioutil.ReadDir(downloadPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download directory: %v", err)
+	}
+
+	referenced := make(map[string]bool)
+	rows, err := s.DB.Query("SELECT container_id, artist_name, format, COALESCE(download_path, '') FROM downloads")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var containerID int
+		var artistName, format, path string
+		if err := rows.Scan(&containerID, &artistName, &format, &path); err != nil {
+			return nil, fmt.Errorf("failed to scan download: %v", err)
+		}
+		if path != "" {
+			referenced[filepath.Clean(path)] = true
+		}
+		referenced[filepath.Join(downloadPath, fmt.Sprintf("%s_%d.%s",
+			strings.ReplaceAll(artistName, " ", "_"), containerID, format))] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %v", err)
+	}
+
+	var files []string
+	cutoff := time.Now().Add(-orphanedFileMinAge)
+	for _, entry := range entries {
+		path := filepath.Join(downloadPath, entry.Name())
+		if !entry.Mode().IsRegular() || entry.ModTime().After(cutoff) || referenced[path] {
+			continue
+		}
+		files = append(files, path)
+	}
+
+	return files, nil
+}
+
+// applyCleanup deletes the items in the plan and records what was actually
+// deleted in its result
+func (s *AdminService) applyCleanup(plan *cleanupPlan) {
+	if items, ok := plan.result.Categories["old_jobs"]; ok {
+		items.Count = s.JobManager.RemoveJobs(plan.jobIDs)
+		items.Failed = len(plan.jobIDs) - items.Count
+	}
+
+	if items, ok := plan.result.Categories["old_deliveries"]; ok {
+		items.Count = deleteRows(s.DB, "webhook_deliveries", plan.deliveryIDs)
+		items.Failed = len(plan.deliveryIDs) - items.Count
+	}
+
+	if items, ok := plan.result.Categories["old_executions"]; ok {
+		items.Count = deleteRows(s.DB, "schedule_executions", plan.executionIDs)
+		items.Failed = len(plan.executionIDs) - items.Count
+	}
+
+	if items, ok := plan.result.Categories["old_reports"]; ok {
+		items.Count = deleteRows(s.DB, "analytics_reports", plan.reportIDs)
+		items.Failed = len(plan.reportIDs) - items.Count
+	}
+
+	if items, ok := plan.result.Categories["orphaned_files"]; ok {
+		items.Count = 0
+		for _, file := range plan.files {
+			if err := os.Remove(file); err != nil {
+				items.Failed++
+				continue
+			}
+			items.Count++
+		}
+	}
+
+	plan.result.TotalItems = 0
+	for _, items := range plan.result.Categories {
+		plan.result.TotalItems += items.Count
+	}
+}
+
+// deleteRows deletes the rows of table with the given IDs in batches, and
+// returns how many were deleted
+func deleteRows[ID int64 | string](db *sql.DB, table string, ids []ID) int {
+	deleted := 0
+	for start := 0; start < len(ids); start += 500 {
+		end := start + 500
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		result, err := db.Exec(`DELETE FROM `+table+` WHERE id IN (?`+
+			strings.Repeat(", ?", len(batch)-1)+`)`, args...)
+		if err != nil {
+			continue
+		}
+		count, _ := result.RowsAffected()
+		deleted += int(count)
+	}
+	return deleted
+}
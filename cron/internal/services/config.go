@@ -0,0 +1,101 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GetConfigString returns a system_config value, or def if the key is missing
+func GetConfigString(db *sql.DB, key, def string) string {
+	var value string
+	if err := db.QueryRow("SELECT value FROM system_config WHERE key = ?", key).Scan(&value); err != nil {
+		return def
+	}
+	return value
+}
+
+// GetConfigInt returns an integer system_config value, or def if the key is
+// missing or doesn't hold an integer
+func GetConfigInt(db *sql.DB, key string, def int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(GetConfigString(db, key, "")))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// GetConfigBool returns a boolean system_config value, or def if the key is
+// missing or doesn't hold a boolean
+func GetConfigBool(db *sql.DB, key string, def bool) bool {
+	b, err := strconv.ParseBool(strings.TrimSpace(GetConfigString(db, key, "")))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// formatConfigValue checks a decoded JSON value against a system_config
+// data_type and returns its string form for storage. Strings holding a valid
+// value of the declared type are accepted too, e.g. "5" for an integer.
+func formatConfigValue(dataType string, value interface{}) (string, error) {
+	switch dataType {
+	case "integer":
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) && !math.IsInf(v, 0) {
+				return strconv.FormatInt(int64(v), 10), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return strconv.FormatInt(n, 10), nil
+			}
+		}
+		return "", fmt.Errorf("expected an integer, got %v", value)
+
+	case "float":
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return strconv.FormatFloat(f, 'f', -1, 64), nil
+			}
+		}
+		return "", fmt.Errorf("expected a number, got %v", value)
+
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return strconv.FormatBool(b), nil
+			}
+		}
+		return "", fmt.Errorf("expected true or false, got %v", value)
+
+	case "json":
+		if v, ok := value.(string); ok {
+			if !json.Valid([]byte(v)) {
+				return "", fmt.Errorf("expected valid JSON")
+			}
+			return v, nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("expected valid JSON: %v", err)
+		}
+		return string(data), nil
+
+	default:
+		v, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a string, got %v", value)
+		}
+		return v, nil
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// dashboardAlertLimit is how many recent alerts the dashboard summary includes
+const dashboardAlertLimit = 5
+
+// GetDashboardSummary assembles a snapshot of each subsystem, calling each
+// service once. A failing subsystem leaves its section empty and is reported
+// in Errors instead of failing the whole summary.
+func (s *AnalyticsService) GetDashboardSummary() *models.DashboardSummary {
+	summary := &models.DashboardSummary{
+		PopularFormats: []models.DashboardFormat{},
+		RecentAlerts:   []models.MonitorAlert{},
+		Errors:         make(map[string]string),
+		GeneratedAt:    time.Now(),
+	}
+
+	if collection, err := s.getDashboardCollection(); err != nil {
+		summary.Errors["collection"] = err.Error()
+	} else {
+		summary.Collection = collection
+		summary.PopularFormats = s.getDashboardFormats(collection.TotalDownloads)
+	}
+
+	activity := &models.DashboardActivity{}
+	err := s.DB.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM shows WHERE created_at >= datetime('now', '-1 day')),
+			(SELECT COUNT(*) FROM downloads WHERE created_at >= datetime('now', '-1 day'))
+	`).Scan(&activity.NewShows24h, &activity.NewDownloads24h)
+	if err != nil {
+		summary.Errors["recent_activity"] = err.Error()
+	} else {
+		summary.RecentActivity = activity
+	}
+
+	summary.SystemStatus = s.getDashboardSystem()
+
+	if forecast, err := GetStorageForecast(s.DB); err != nil {
+		summary.Errors["storage"] = err.Error()
+	} else {
+		summary.Storage = forecast
+	}
+
+	downloadStats, err := NewDownloadManager(s.DB, s.JobManager).GetDownloadStats()
+	if err != nil {
+		summary.Errors["downloads"] = err.Error()
+	} else {
+		summary.Downloads = downloadStats
+	}
+
+	alerts, err := NewMonitoringService(s.DB, s.JobManager).GetRecentAlerts(dashboardAlertLimit)
+	if err != nil {
+		summary.Errors["recent_alerts"] = err.Error()
+	} else {
+		summary.RecentAlerts = alerts
+	}
+
+	if s.scheduler == nil {
+		summary.Errors["scheduler"] = "scheduler not available"
+	} else if status, err := s.scheduler.GetStatus(); err != nil {
+		summary.Errors["scheduler"] = err.Error()
+	} else {
+		summary.Scheduler = status
+	}
+
+	webhookStats, err := NewWebhookService(s.DB, s.JobManager).GetWebhookStats()
+	if err != nil {
+		summary.Errors["webhooks"] = err.Error()
+	} else {
+		summary.Webhooks = webhookStats
+	}
+
+	summary.Health = s.GetHealthScore()
+
+	return summary
+}
+
+// getDashboardCollection counts the collection and how much of it has been downloaded
+func (s *AnalyticsService) getDashboardCollection() (*models.DashboardCollection, error) {
+	collection := &models.DashboardCollection{}
+
+	err := s.DB.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM artists),
+			(SELECT COUNT(*) FROM shows),
+			(SELECT COUNT(*) FROM shows s WHERE EXISTS (
+				SELECT 1 FROM downloads d WHERE d.show_id = s.id AND d.status = 'completed'
+			)),
+			(SELECT COUNT(*) FROM downloads),
+			(SELECT COALESCE(SUM(size_mb), 0) / 1024.0 FROM downloads WHERE status = 'completed')
+	`).Scan(&collection.TotalArtists, &collection.TotalShows, &collection.DownloadedShows,
+		&collection.TotalDownloads, &collection.TotalSizeGB)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.TotalShows > 0 {
+		collection.CompletionPct = float64(collection.DownloadedShows) / float64(collection.TotalShows) * 100
+	}
+
+	return collection, nil
+}
+
+// getDashboardFormats returns the three most downloaded formats
+func (s *AnalyticsService) getDashboardFormats(totalDownloads int64) []models.DashboardFormat {
+	formats := []models.DashboardFormat{}
+
+	rows, err := s.DB.Query(`
+		SELECT format, COUNT(*) as count
+		FROM downloads
+		GROUP BY format
+		ORDER BY count DESC
+		LIMIT 3
+	`)
+	if err != nil {
+		return formats
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var format models.DashboardFormat
+		if rows.Scan(&format.Format, &format.Count) == nil {
+			if totalDownloads > 0 {
+				format.Percentage = float64(format.Count) / float64(totalDownloads) * 100
+			}
+			formats = append(formats, format)
+		}
+	}
+
+	return formats
+}
+
+func (s *AnalyticsService) getDashboardSystem() *models.DashboardSystem {
+	system := &models.DashboardSystem{}
+
+	s.DB.QueryRow(`SELECT COUNT(*) FROM monitors WHERE status = 'active'`).Scan(&system.ActiveMonitors)
+
+	for _, job := range s.JobManager.ListJobs() {
+		switch job.Status {
+		case models.JobStatusRunning:
+			system.RunningJobs++
+		case models.JobStatusFailed:
+			system.FailedJobs++
+		}
+	}
+
+	return system
+}
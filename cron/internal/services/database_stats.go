@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// largestTablesCount is how many tables DatabaseStats lists as largest
+const largestTablesCount = 5
+
+// GetDatabaseStats reports row counts, index counts and, where SQLite
+// supports it, sizes for every table along with page and WAL usage
+func (s *AdminService) GetDatabaseStats() (*models.DatabaseStats, error) {
+	stats := &models.DatabaseStats{
+		TableCounts:   make(map[string]int64),
+		Tables:        []models.TableStats{},
+		LargestTables: []models.TableStats{},
+	}
+
+	// Tables and the table each index belongs to
+	rows, err := s.DB.Query(`
+		SELECT type, name, tbl_name FROM sqlite_master
+		WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %v", err)
+	}
+	var tableNames []string
+	indexTable := make(map[string]string)
+	for rows.Next() {
+		var objectType, name, tableName string
+		if err := rows.Scan(&objectType, &name, &tableName); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan table: %v", err)
+		}
+		if objectType == "table" {
+			tableNames = append(tableNames, name)
+		} else {
+			indexTable[name] = tableName
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %v", err)
+	}
+
+	indexCounts := make(map[string]int)
+	for _, tableName := range indexTable {
+		indexCounts[tableName]++
+	}
+	stats.IndexCount = len(indexTable)
+
+	sizes, sizesAvailable := s.tableSizes(indexTable)
+	stats.TableSizesAvailable = sizesAvailable
+
+	for _, name := range tableNames {
+		table := models.TableStats{Name: name, Indexes: indexCounts[name]}
+		quoted := `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+		if err := s.DB.QueryRow("SELECT COUNT(*) FROM " + quoted).Scan(&table.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count rows in %s: %v", name, err)
+		}
+		if sizesAvailable {
+			size := sizes[name]
+			table.SizeBytes = &size
+		}
+
+		stats.TableCounts[name] = table.Rows
+		stats.Tables = append(stats.Tables, table)
+	}
+	stats.TotalTables = len(stats.Tables)
+
+	// Largest first, by size when known and otherwise by rows
+	sort.SliceStable(stats.Tables, func(i, j int) bool {
+		a, b := stats.Tables[i], stats.Tables[j]
+		if sizesAvailable && *a.SizeBytes != *b.SizeBytes {
+			return *a.SizeBytes > *b.SizeBytes
+		}
+		return a.Rows > b.Rows
+	})
+	for i := 0; i < len(stats.Tables) && i < largestTablesCount; i++ {
+		stats.LargestTables = append(stats.LargestTables, stats.Tables[i])
+	}
+
+	// Pages
+	for pragma, target := range map[string]*int64{
+		"page_size":      &stats.PageSize,
+		"page_count":     &stats.PageCount,
+		"freelist_count": &stats.FreePages,
+	} {
+		if err := s.DB.QueryRow("PRAGMA " + pragma).Scan(target); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", pragma, err)
+		}
+	}
+	stats.SizeBytes = stats.PageCount * stats.PageSize
+	stats.FreeBytes = stats.FreePages * stats.PageSize
+	stats.DatabaseSizeMB = float64(stats.SizeBytes) / (1024 * 1024)
+
+	// WAL file, which only exists in WAL journal mode
+	s.DB.QueryRow("PRAGMA journal_mode").Scan(&stats.JournalMode)
+	var seq int
+	var name, file string
+	if s.DB.QueryRow("PRAGMA database_list").Scan(&seq, &name, &file) == nil && file != "" {
+		if stat, err := os.Stat(file + "-wal"); err == nil {
+			stats.WALSizeBytes = stat.Size()
+		}
+	}
+
+	return stats, nil
+}
+
+// tableSizes returns the bytes each table and its indexes occupy. It reports
+// false when SQLite was built without the dbstat virtual table.
+func (s *AdminService) tableSizes(indexTable map[string]string) (map[string]int64, bool) {
+	rows, err := s.DB.Query("SELECT name, SUM(pgsize) FROM dbstat GROUP BY name")
+	if err != nil {
+		return nil, false
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var size int64
+		if err := rows.Scan(&name, &size); err != nil {
+			return nil, false
+		}
+		if tableName, ok := indexTable[name]; ok {
+			name = tableName
+		}
+		sizes[name] += size
+	}
+
+	return sizes, rows.Err() == nil
+}
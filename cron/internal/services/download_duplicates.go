@@ -0,0 +1,264 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+var (
+	qualityBitDepth   = regexp.MustCompile(`(?i)(\d+)\s*-?\s*bit`)
+	qualitySampleRate = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*khz`)
+	qualityBitrate    = regexp.MustCompile(`(?i)(\d+)\s*kbps`)
+)
+
+// copyRank orders the copies of a show from best to worst: lossless formats
+// first, then bit depth, sample rate and bitrate parsed from the quality,
+// then size. The newest download breaks any remaining tie.
+type copyRank struct {
+	lossless   bool
+	bitDepth   float64
+	sampleRate float64
+	bitrate    float64
+	sizeMB     float64
+	downloadID int
+}
+
+func rankCopy(dl models.DownloadCopy) copyRank {
+	rank := copyRank{
+		lossless:   strings.EqualFold(dl.Format, string(models.DownloadFormatFLAC)) || strings.EqualFold(dl.Format, string(models.DownloadFormatALAC)),
+		sizeMB:     dl.SizeMB,
+		downloadID: dl.DownloadID,
+	}
+	rank.bitDepth = parseQualityNumber(qualityBitDepth, dl.Quality)
+	rank.sampleRate = parseQualityNumber(qualitySampleRate, dl.Quality)
+	rank.bitrate = parseQualityNumber(qualityBitrate, dl.Quality)
+	return rank
+}
+
+func parseQualityNumber(pattern *regexp.Regexp, quality string) float64 {
+	match := pattern.FindStringSubmatch(quality)
+	if match == nil {
+		return 0
+	}
+	n, _ := strconv.ParseFloat(match[1], 64)
+	return n
+}
+
+// better reports whether r ranks above other
+func (r copyRank) better(other copyRank) bool {
+	if r.lossless != other.lossless {
+		return r.lossless
+	}
+	for _, pair := range [][2]float64{
+		{r.bitDepth, other.bitDepth},
+		{r.sampleRate, other.sampleRate},
+		{r.bitrate, other.bitrate},
+		{r.sizeMB, other.sizeMB},
+	} {
+		if pair[0] != pair[1] {
+			return pair[0] > pair[1]
+		}
+	}
+	return r.downloadID > other.downloadID
+}
+
+// FindDuplicates lists shows a user has more than one complete download of.
+// Each user's copies are compared only with their own, and partial (selected
+// track) downloads are left out since they aren't copies of the whole show.
+// When scoped is set only userID's downloads are considered.
+func (dm *DownloadManager) FindDuplicates(userID int, scoped bool) (*models.DuplicateReport, error) {
+	downloadPath := GetConfigString(dm.DB, "default_download_path", "/downloads")
+
+	query := `
+		SELECT id, user_id, show_id, container_id, artist_name, date(show_date), venue, format, quality,
+		       COALESCE(size_mb, 0), COALESCE(download_path, ''), completed_at
+		FROM downloads
+		WHERE status = 'completed' AND show_id IS NOT NULL AND tracks IS NULL`
+	var args []interface{}
+	if scoped {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
+	query += " ORDER BY user_id, show_id, id"
+
+	rows, err := dm.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %v", err)
+	}
+	defer rows.Close()
+
+	var shows []*models.DuplicateShow
+	var copies [][]models.DownloadCopy
+	for rows.Next() {
+		var show models.DuplicateShow
+		var dl models.DownloadCopy
+		var path string
+		var completedAt sql.NullString
+		if err := rows.Scan(&dl.DownloadID, &show.UserID, &show.ShowID, &show.ContainerID, &show.ArtistName, &show.ShowDate,
+			&show.Venue, &dl.Format, &dl.Quality, &dl.SizeMB, &path, &completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan download: %v", err)
+		}
+		if completedAt.Valid {
+			dl.CompletedAt = &completedAt.String
+		}
+		dl.FilePath = findDownloadFile(downloadPath, path, show.ArtistName, show.ContainerID, dl.Format)
+
+		if n := len(shows); n > 0 && shows[n-1].UserID == show.UserID && shows[n-1].ShowID == show.ShowID {
+			copies[n-1] = append(copies[n-1], dl)
+			continue
+		}
+		shows = append(shows, &show)
+		copies = append(copies, []models.DownloadCopy{dl})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list downloads: %v", err)
+	}
+
+	report := &models.DuplicateReport{Shows: []models.DuplicateShow{}}
+	for i, show := range shows {
+		if len(copies[i]) < 2 {
+			continue
+		}
+
+		best := 0
+		for j := 1; j < len(copies[i]); j++ {
+			if rankCopy(copies[i][j]).better(rankCopy(copies[i][best])) {
+				best = j
+			}
+		}
+
+		show.Keep = copies[i][best]
+		show.Redundant = []models.DownloadCopy{}
+		for j, dl := range copies[i] {
+			if j == best {
+				continue
+			}
+			show.Redundant = append(show.Redundant, dl)
+			show.ReclaimableMB += dl.SizeMB
+		}
+
+		report.Shows = append(report.Shows, *show)
+		report.TotalRedundant += len(show.Redundant)
+		report.ReclaimableMB += show.ReclaimableMB
+	}
+	report.TotalShows = len(report.Shows)
+
+	return report, nil
+}
+
+// findDownloadFile returns the file a download wrote: its download_path when
+// that is a file, otherwise the <Artist>_<container_id>.<format> name the
+// download manager writes in downloadPath. Returns "" when neither exists.
+func findDownloadFile(downloadPath, path, artistName string, containerID int, format string) string {
+	candidates := []string{}
+	if path != "" {
+		candidates = append(candidates, filepath.Clean(path))
+	}
+	candidates = append(candidates, filepath.Join(downloadPath, fmt.Sprintf("%s_%d.%s",
+		strings.ReplaceAll(artistName, " ", "_"), containerID, format)))
+
+	for _, candidate := range candidates {
+		if stat, err := os.Stat(candidate); err == nil && stat.Mode().IsRegular() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// RemoveDuplicates deletes the redundant copies FindDuplicates reports, file
+// and download record, keeping the highest quality copy of each show. A dry
+// run only reports what would be removed.
+func (dm *DownloadManager) RemoveDuplicates(req *models.DuplicateCleanupRequest, userID int, scoped bool) (*models.DuplicateCleanupResult, error) {
+	if !req.DryRun {
+		if err := CheckMaintenanceAllowed(dm.DB); err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := dm.FindDuplicates(userID, scoped)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DuplicateCleanupResult{
+		DryRun: req.DryRun,
+		Shows:  report.Shows,
+	}
+
+	if req.DryRun {
+		result.Removed = report.TotalRedundant
+		result.ReclaimedMB = report.ReclaimableMB
+		return result, nil
+	}
+
+	removing := make(map[int]bool)
+	for _, show := range report.Shows {
+		for _, dl := range show.Redundant {
+			removing[dl.DownloadID] = true
+		}
+	}
+
+	for _, show := range report.Shows {
+		for _, dl := range show.Redundant {
+			// Copies in the same format resolve to the same file, which may
+			// belong to the copy being kept or to another user's download
+			if dl.FilePath != "" && !dm.fileInUse(dl.FilePath, show.ContainerID, removing) {
+				if err := os.Remove(dl.FilePath); err != nil && !os.IsNotExist(err) {
+					result.Failed++
+					result.Errors = append(result.Errors, fmt.Sprintf("download %d: %v", dl.DownloadID, err))
+					continue
+				}
+			}
+
+			if _, err := dm.DB.Exec("DELETE FROM downloads WHERE id = ? AND status = 'completed'", dl.DownloadID); err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, fmt.Sprintf("download %d: %v", dl.DownloadID, err))
+				continue
+			}
+
+			result.Removed++
+			result.ReclaimedMB += dl.SizeMB
+		}
+	}
+
+	return result, nil
+}
+
+// fileInUse reports whether a completed download of the show that isn't being
+// removed, by any user and including partial downloads, resolves to path. A
+// failed lookup counts as in use so the file is kept.
+func (dm *DownloadManager) fileInUse(path string, containerID int, removing map[int]bool) bool {
+	downloadPath := GetConfigString(dm.DB, "default_download_path", "/downloads")
+
+	rows, err := dm.DB.Query(`
+		SELECT id, artist_name, format, COALESCE(download_path, '')
+		FROM downloads
+		WHERE container_id = ? AND status = 'completed'
+	`, containerID)
+	if err != nil {
+		return true
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var artistName, format, downloadFile string
+		if err := rows.Scan(&id, &artistName, &format, &downloadFile); err != nil {
+			return true
+		}
+		if removing[id] {
+			continue
+		}
+		if findDownloadFile(downloadPath, downloadFile, artistName, containerID, format) == path {
+			return true
+		}
+	}
+	return rows.Err() != nil
+}
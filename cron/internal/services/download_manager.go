@@ -2,10 +2,13 @@ package services
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +25,10 @@ type DownloadManager struct {
 	queueMutex      sync.Mutex
 }
 
+// nugsDLTrackFlag selects individual setlist tracks of a release. nugs-dl
+// downloads the whole show when it is omitted.
+const nugsDLTrackFlag = "--tracks"
+
 type ActiveDownload struct {
 	Download   *models.Download
 	Job        *models.Job
@@ -38,7 +45,7 @@ func NewDownloadManager(db *sql.DB, jobManager *models.JobManager) *DownloadMana
 	}
 }
 
-func (dm *DownloadManager) QueueDownload(req *models.DownloadRequest) (*models.DownloadResponse, error) {
+func (dm *DownloadManager) QueueDownload(req *models.DownloadRequest, userID int) (*models.DownloadResponse, error) {
 	// Validate show exists and get details
 	var showExists int
 	var artistName sql.NullString
@@ -70,13 +77,27 @@ func (dm *DownloadManager) QueueDownload(req *models.DownloadRequest) (*models.D
 		req.Priority = 5
 	}
 
+	// Partial downloads must name tracks that exist in the show's setlist
+	if len(req.Tracks) > 0 {
+		tracks, err := dm.validateTracks(req.ShowID, req.Tracks)
+		if err != nil {
+			return &models.DownloadResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		req.Tracks = tracks
+	}
+	tracks := encodeTracks(req.Tracks)
+
 	// Check if download already exists
 	var existingID int
 	err = dm.DB.QueryRow(`
 		SELECT id FROM downloads 
-		WHERE show_id = ? AND format = ? AND quality = ?
+		WHERE user_id = ? AND show_id = ? AND format = ? AND quality = ?
+		AND COALESCE(tracks, '') = COALESCE(?, '')
 		AND status NOT IN ('failed', 'cancelled')
-	`, req.ShowID, req.Format, req.Quality).Scan(&existingID)
+	`, userID, req.ShowID, req.Format, req.Quality, tracks).Scan(&existingID)
 
 	if err == nil {
 		return &models.DownloadResponse{
@@ -87,10 +108,10 @@ func (dm *DownloadManager) QueueDownload(req *models.DownloadRequest) (*models.D
 
 	// Create download record
 	result, err := dm.DB.Exec(`
-		INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality, status, size_mb, created_at)
-		SELECT 1, s.id, s.container_id, ?, s.date, s.venue, ?, ?, 'pending', 0, datetime('now')
+		INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality, tracks, status, size_mb, created_at)
+		SELECT ?, s.id, s.container_id, ?, s.date, s.venue, ?, ?, ?, 'pending', 0, datetime('now')
 		FROM shows s WHERE s.container_id = ?
-	`, artistNameStr, string(req.Format), string(req.Quality), req.ShowID)
+	`, userID, artistNameStr, string(req.Format), string(req.Quality), tracks, req.ShowID)
 
 	if err != nil {
 		return &models.DownloadResponse{
@@ -127,14 +148,63 @@ func (dm *DownloadManager) QueueDownload(req *models.DownloadRequest) (*models.D
 	// Start download processing if not at capacity
 	go dm.processQueue()
 
+	message := fmt.Sprintf("Download queued for %s", artistNameStr)
+	if len(req.Tracks) > 0 {
+		message = fmt.Sprintf("Download of %d tracks queued for %s", len(req.Tracks), artistNameStr)
+	}
+
 	return &models.DownloadResponse{
 		Success:    true,
 		DownloadID: int(downloadID),
 		Status:     "queued",
-		Message:    fmt.Sprintf("Download queued for %s", artistNameStr),
+		Message:    message,
 	}, nil
 }
 
+// validateTracks checks the requested track numbers against the show's setlist
+// and returns them sorted with duplicates removed
+func (dm *DownloadManager) validateTracks(containerID int, tracks []int) ([]int, error) {
+	var setList sql.NullString
+	err := dm.DB.QueryRow("SELECT set_list FROM shows WHERE container_id = ?", containerID).Scan(&setList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load setlist: %v", err)
+	}
+
+	var songs []json.RawMessage
+	if setList.Valid && setList.String != "" {
+		if err := json.Unmarshal([]byte(setList.String), &songs); err != nil {
+			return nil, fmt.Errorf("failed to parse setlist: %v", err)
+		}
+	}
+	if len(songs) == 0 {
+		return nil, fmt.Errorf("no setlist available for show %d, cannot select tracks", containerID)
+	}
+
+	seen := make(map[int]bool)
+	var valid []int
+	for _, track := range tracks {
+		if track < 1 || track > len(songs) {
+			return nil, fmt.Errorf("track %d is not in the setlist (1-%d)", track, len(songs))
+		}
+		if !seen[track] {
+			seen[track] = true
+			valid = append(valid, track)
+		}
+	}
+	sort.Ints(valid)
+
+	return valid, nil
+}
+
+// encodeTracks stores a track selection as a JSON array, or NULL for the whole show
+func encodeTracks(tracks []int) sql.NullString {
+	if len(tracks) == 0 {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(tracks)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
 func (dm *DownloadManager) processQueue() {
 	dm.queueMutex.Lock()
 	defer dm.queueMutex.Unlock()
@@ -153,7 +223,7 @@ func (dm *DownloadManager) processQueue() {
 	// Get next download from queue
 	rows, err := dm.DB.Query(`
 		SELECT d.id, d.show_id, d.container_id, d.artist_name, 
-		       d.format, d.quality, d.status, s.venue, s.city
+		       d.format, d.quality, d.status, d.tracks, s.venue, s.city
 		FROM downloads d
 		JOIN shows s ON d.show_id = s.id
 		WHERE d.status = 'queued' AND d.queue_position IS NOT NULL
@@ -169,9 +239,10 @@ func (dm *DownloadManager) processQueue() {
 	for rows.Next() {
 		var downloadID, showID, containerID int
 		var artistName, format, quality, status, venueName, venueCity string
+		var tracks sql.NullString
 
 		err := rows.Scan(&downloadID, &showID, &containerID, &artistName,
-			&format, &quality, &status, &venueName, &venueCity)
+			&format, &quality, &status, &tracks, &venueName, &venueCity)
 		if err != nil {
 			continue
 		}
@@ -187,6 +258,9 @@ func (dm *DownloadManager) processQueue() {
 			ShowTitle:   venueName + ", " + venueCity,
 			VenueName:   venueName,
 		}
+		if tracks.Valid {
+			json.Unmarshal([]byte(tracks.String), &download.Tracks)
+		}
 
 		// Start download in background
 		go dm.startDownload(download)
@@ -267,10 +341,15 @@ func (dm *DownloadManager) executeDownload(download *models.Download, job *model
 	// Build nugs-dl command - nugs-dl expects URLs as positional arguments
 	// Based on REFERENCE_CODE/README.md, container IDs map to release URLs
 	containerURL := fmt.Sprintf("https://play.nugs.net/release/%d", download.ContainerID)
-	cmd := exec.Command("./nugs-dl",
-		"--format", formatNum,
-		"--outpath", dm.downloadPath,
-		containerURL)
+	args := []string{"--format", formatNum, "--outpath", dm.downloadPath}
+	if len(download.Tracks) > 0 {
+		trackList := make([]string, len(download.Tracks))
+		for i, track := range download.Tracks {
+			trackList[i] = strconv.Itoa(track)
+		}
+		args = append(args, nugsDLTrackFlag, strings.Join(trackList, ","))
+	}
+	cmd := exec.Command("./nugs-dl", append(args, containerURL)...)
 
 	cmd.Dir = "/home/jmagar/code/nugs"
 
@@ -386,43 +465,37 @@ func (dm *DownloadManager) GetDownloadStats() (*models.DownloadStats, error) {
 	stats.ActiveDownloads = stats.InProgressDownloads
 	stats.AverageSpeedMbps = 0.0 // Placeholder - would need actual speed tracking
 
-	// Get format breakdown
-	rows, err := dm.DB.Query(`
-		SELECT format, COUNT(*) 
-		FROM downloads 
-		GROUP BY format
-	`)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var format string
-			var count int64
-			if rows.Scan(&format, &count) == nil {
-				stats.FormatBreakdown[format] = count
-			}
-		}
+	// Get format and quality breakdowns
+	if err := dm.countBy("format", stats.FormatBreakdown); err != nil {
+		log.Printf("Failed to get download format breakdown: %v", err)
 	}
-
-	// Get quality breakdown
-	rows, err = dm.DB.Query(`
-		SELECT quality, COUNT(*) 
-		FROM downloads 
-		GROUP BY quality
-	`)
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var quality string
-			var count int64
-			if rows.Scan(&quality, &count) == nil {
-				stats.QualityBreakdown[quality] = count
-			}
-		}
+	if err := dm.countBy("quality", stats.QualityBreakdown); err != nil {
+		log.Printf("Failed to get download quality breakdown: %v", err)
 	}
 
 	return stats, nil
 }
 
+// countBy counts downloads grouped by column, which must be a trusted column
+// name, into counts. The rows are closed before it returns, so the next
+// query doesn't need a second connection.
+func (dm *DownloadManager) countBy(column string, counts map[string]int64) error {
+	rows, err := dm.DB.Query(`SELECT ` + column + `, COUNT(*) FROM downloads GROUP BY ` + column)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		var count int64
+		if rows.Scan(&value, &count) == nil {
+			counts[value] = count
+		}
+	}
+	return rows.Err()
+}
+
 func (dm *DownloadManager) CancelDownload(downloadID int) error {
 	// Check if download is active
 	if active, ok := dm.activeDownloads.Load(downloadID); ok {
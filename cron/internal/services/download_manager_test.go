@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDownloadStats_SingleConnection runs the download statistics on a pool of
+// one connection. A result set still open when the next query starts holds
+// that connection, and the next query blocks forever.
+func TestDownloadStats_SingleConnection(t *testing.T) {
+	db := testutil.NewDB(t)
+	testutil.InsertDownload(t, db, 67892, "completed", 1024)
+	testutil.InsertDownload(t, db, 67890, "failed", 0)
+	db.SetMaxOpenConns(1)
+
+	var stats *models.DownloadStats
+	var analytics *models.DownloadAnalytics
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		if stats, err = NewDownloadManager(db, models.NewJobManager()).GetDownloadStats(); err != nil {
+			done <- err
+			return
+		}
+		analytics, err = NewAnalyticsService(db, models.NewJobManager()).GetDownloadAnalytics(context.Background(), &models.AnalyticsQuery{})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("download statistics blocked waiting for a connection")
+	}
+
+	assert.EqualValues(t, 2, stats.FormatBreakdown["FLAC"])
+	assert.EqualValues(t, 2, stats.QualityBreakdown["16bit"])
+	assert.Empty(t, analytics.Warnings)
+	assert.Zero(t, db.Stats().InUse)
+}
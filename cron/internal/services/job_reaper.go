@@ -0,0 +1,53 @@
+package services
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// DefaultJobMaxRuntimeMinutes applies when the job_max_runtime_minutes config
+// key is unset
+const DefaultJobMaxRuntimeMinutes = 360
+
+// JobMaxRuntime is how long a job may run before the reaper fails it, read
+// from job_max_runtime_minutes. Zero means jobs never time out.
+func JobMaxRuntime(db *sql.DB) time.Duration {
+	minutes := GetConfigInt(db, "job_max_runtime_minutes", DefaultJobMaxRuntimeMinutes)
+	if minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// StartJobReaper fails stuck jobs every interval for the life of the process
+func (s *AdminService) StartJobReaper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.ReapStuckJobs()
+			<-ticker.C
+		}
+	}()
+}
+
+// ReapStuckJobs fails jobs running longer than job_max_runtime_minutes and
+// returns them. Their goroutine most likely died, leaving them running
+// forever.
+func (s *AdminService) ReapStuckJobs() []*models.Job {
+	maxRuntime := JobMaxRuntime(s.DB)
+	if maxRuntime == 0 {
+		return nil
+	}
+
+	reaped := s.JobManager.FailStuckJobs(maxRuntime)
+	for _, job := range reaped {
+		log.Printf("Failed stuck %s job %s: running since %s, longer than %s",
+			job.Type, job.ID, job.StartedAt.Format(time.RFC3339), maxRuntime)
+	}
+	return reaped
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// ErrMaintenanceBlocked is returned for destructive maintenance requested
+// while maintenance_locked is set or during a maintenance window
+var ErrMaintenanceBlocked = errors.New("destructive maintenance is blocked")
+
+// maintenanceWindow is a daily range of minutes since midnight. End before
+// start means the window spans midnight.
+type maintenanceWindow struct {
+	spec       string
+	start, end int
+}
+
+func (w maintenanceWindow) contains(now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	if w.start <= w.end {
+		return minute >= w.start && minute < w.end
+	}
+	return minute >= w.start || minute < w.end
+}
+
+// parseMaintenanceWindows parses the comma separated HH:MM-HH:MM ranges of
+// maintenance_windows, skipping malformed entries
+func parseMaintenanceWindows(value string) []maintenanceWindow {
+	var windows []maintenanceWindow
+	for _, spec := range strings.Split(value, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		bounds := strings.Split(spec, "-")
+		if len(bounds) != 2 {
+			log.Printf("Ignoring malformed maintenance window %q", spec)
+			continue
+		}
+		start, err1 := time.Parse("15:04", strings.TrimSpace(bounds[0]))
+		end, err2 := time.Parse("15:04", strings.TrimSpace(bounds[1]))
+		if err1 != nil || err2 != nil {
+			log.Printf("Ignoring malformed maintenance window %q", spec)
+			continue
+		}
+
+		windows = append(windows, maintenanceWindow{
+			spec:  spec,
+			start: start.Hour()*60 + start.Minute(),
+			end:   end.Hour()*60 + end.Minute(),
+		})
+	}
+	return windows
+}
+
+// GetMaintenanceStatus reports whether destructive maintenance is blocked at now
+func GetMaintenanceStatus(db *sql.DB, now time.Time) models.MaintenanceStatus {
+	status := models.MaintenanceStatus{
+		Locked:  GetConfigBool(db, "maintenance_locked", false),
+		Windows: []string{},
+	}
+
+	for _, window := range parseMaintenanceWindows(GetConfigString(db, "maintenance_windows", "")) {
+		status.Windows = append(status.Windows, window.spec)
+		if status.ActiveWindow == "" && window.contains(now) {
+			status.ActiveWindow = window.spec
+		}
+	}
+
+	switch {
+	case status.Locked:
+		status.Blocked = true
+		status.Reason = "maintenance is locked"
+	case status.ActiveWindow != "":
+		status.Blocked = true
+		status.Reason = "within maintenance window " + status.ActiveWindow
+	}
+
+	return status
+}
+
+// CheckMaintenanceAllowed returns an error wrapping ErrMaintenanceBlocked when
+// destructive maintenance may not run now
+func CheckMaintenanceAllowed(db *sql.DB) error {
+	if status := GetMaintenanceStatus(db, time.Now()); status.Blocked {
+		return fmt.Errorf("%w: %s", ErrMaintenanceBlocked, status.Reason)
+	}
+	return nil
+}
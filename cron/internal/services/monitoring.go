@@ -3,6 +3,7 @@ package services
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -14,16 +15,29 @@ import (
 type MonitoringService struct {
 	DB         *sql.DB
 	JobManager *models.JobManager
+	webhooks   *WebhookService
+
+	// catalogManager builds the catalog_manager command used to refresh an artist
+	catalogManager func(args ...string) *exec.Cmd
 }
 
 func NewMonitoringService(db *sql.DB, jobManager *models.JobManager) *MonitoringService {
 	return &MonitoringService{
-		DB:         db,
-		JobManager: jobManager,
+		DB:             db,
+		JobManager:     jobManager,
+		webhooks:       NewWebhookService(db, jobManager),
+		catalogManager: catalogManagerCommand,
 	}
 }
 
-func (s *MonitoringService) CreateMonitor(req *models.MonitorRequest) (*models.MonitorResponse, error) {
+// catalogManagerCommand runs the catalog_manager binary from the cron checkout
+func catalogManagerCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("./bin/catalog_manager", args...)
+	cmd.Dir = "/home/jmagar/code/nugs/cron"
+	return cmd
+}
+
+func (s *MonitoringService) CreateMonitor(req *models.MonitorRequest, userID int) (*models.MonitorResponse, error) {
 	// Validate artist exists
 	var artistName string
 	err := s.DB.QueryRow(`SELECT name FROM artists WHERE id = ?`, req.ArtistID).Scan(&artistName)
@@ -39,7 +53,7 @@ func (s *MonitoringService) CreateMonitor(req *models.MonitorRequest) (*models.M
 
 	// Check if monitor already exists for this user and artist
 	var existingID int
-	err = s.DB.QueryRow(`SELECT id FROM monitors WHERE user_id = 1 AND artist_id = ?`, req.ArtistID).Scan(&existingID)
+	err = s.DB.QueryRow(`SELECT id FROM monitors WHERE user_id = ? AND artist_id = ?`, userID, req.ArtistID).Scan(&existingID)
 	if err == nil {
 		return &models.MonitorResponse{
 			Success: false,
@@ -60,9 +74,9 @@ func (s *MonitoringService) CreateMonitor(req *models.MonitorRequest) (*models.M
 
 	// Create monitor
 	result, err := s.DB.Exec(`
-		INSERT INTO monitors (user_id, artist_id, status, settings, shows_found, alerts_sent, created_at, updated_at)
-		VALUES (1, ?, 'active', ?, 0, 0, datetime('now'), datetime('now'))
-	`, req.ArtistID, settings)
+		INSERT INTO monitors (user_id, artist_id, status, settings, shows_found, alerts_sent, completion_goal, created_at, updated_at)
+		VALUES (?, ?, 'active', ?, 0, 0, ?, datetime('now'), datetime('now'))
+	`, userID, req.ArtistID, settings, req.CompletionGoal)
 
 	if err != nil {
 		return &models.MonitorResponse{
@@ -80,7 +94,9 @@ func (s *MonitoringService) CreateMonitor(req *models.MonitorRequest) (*models.M
 	}, nil
 }
 
-func (s *MonitoringService) UpdateMonitor(monitorID int, req *models.MonitorUpdateRequest) error {
+// UpdateMonitor changes a monitor. When scoped, only userID's monitors can be
+// changed and anyone else's is reported as not found.
+func (s *MonitoringService) UpdateMonitor(monitorID int, req *models.MonitorUpdateRequest, userID int, scoped bool) error {
 	updates := []string{}
 	args := []interface{}{}
 
@@ -89,19 +105,25 @@ func (s *MonitoringService) UpdateMonitor(monitorID int, req *models.MonitorUpda
 		args = append(args, *req.Status)
 	}
 
+	// The check interval and notification flags live in the settings JSON
 	if req.CheckInterval != nil {
-		updates = append(updates, "check_interval = ?")
+		updates = append(updates, "settings = json_set(settings, '$.check_interval', ?)")
 		args = append(args, *req.CheckInterval)
 	}
 
 	if req.NotifyNewShows != nil {
-		updates = append(updates, "notify_new_shows = ?")
-		args = append(args, *req.NotifyNewShows)
+		updates = append(updates, "settings = json_set(settings, '$.notify_new_shows', json(?))")
+		args = append(args, strconv.FormatBool(*req.NotifyNewShows))
 	}
 
 	if req.NotifyShowUpdates != nil {
-		updates = append(updates, "notify_show_updates = ?")
-		args = append(args, *req.NotifyShowUpdates)
+		updates = append(updates, "settings = json_set(settings, '$.notify_show_updates', json(?))")
+		args = append(args, strconv.FormatBool(*req.NotifyShowUpdates))
+	}
+
+	if req.CompletionGoal != nil {
+		updates = append(updates, "completion_goal = ?")
+		args = append(args, *req.CompletionGoal)
 	}
 
 	if len(updates) == 0 {
@@ -111,7 +133,11 @@ func (s *MonitoringService) UpdateMonitor(monitorID int, req *models.MonitorUpda
 	updates = append(updates, "updated_at = datetime('now')")
 	args = append(args, monitorID)
 
-	query := fmt.Sprintf("UPDATE artist_monitors SET %s WHERE id = ?", strings.Join(updates, ", "))
+	query := fmt.Sprintf("UPDATE monitors SET %s WHERE id = ?", strings.Join(updates, ", "))
+	if scoped {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
 
 	result, err := s.DB.Exec(query, args...)
 	if err != nil {
@@ -126,8 +152,17 @@ func (s *MonitoringService) UpdateMonitor(monitorID int, req *models.MonitorUpda
 	return nil
 }
 
-func (s *MonitoringService) DeleteMonitor(monitorID int) error {
-	result, err := s.DB.Exec("DELETE FROM artist_monitors WHERE id = ?", monitorID)
+// DeleteMonitor removes a monitor. When scoped, only userID's monitors can be
+// removed and anyone else's is reported as not found.
+func (s *MonitoringService) DeleteMonitor(monitorID, userID int, scoped bool) error {
+	query := "DELETE FROM monitors WHERE id = ?"
+	args := []interface{}{monitorID}
+	if scoped {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
+
+	result, err := s.DB.Exec(query, args...)
 	if err != nil {
 		return err
 	}
@@ -137,9 +172,6 @@ func (s *MonitoringService) DeleteMonitor(monitorID int) error {
 		return fmt.Errorf("monitor not found")
 	}
 
-	// Also delete related alerts
-	s.DB.Exec("DELETE FROM monitor_alerts WHERE monitor_id = ?", monitorID)
-
 	return nil
 }
 
@@ -166,11 +198,14 @@ func (s *MonitoringService) CheckArtist(artistID int) (*models.CheckResult, erro
 
 	// Get monitor for this artist
 	var monitor models.ArtistMonitor
+	var completionGoal, lastCompletion sql.NullFloat64
 	err = s.DB.QueryRow(`
-		SELECT id, total_shows 
-		FROM artist_monitors 
+		SELECT id, completion_goal, last_completion_pct
+		FROM monitors
 		WHERE artist_id = ? AND status = 'active'
-	`, artistID).Scan(&monitor.ID, &monitor.TotalShows)
+		ORDER BY id
+		LIMIT 1
+	`, artistID).Scan(&monitor.ID, &completionGoal, &lastCompletion)
 
 	if err != nil {
 		return &models.CheckResult{
@@ -184,15 +219,14 @@ func (s *MonitoringService) CheckArtist(artistID int) (*models.CheckResult, erro
 	startTime := time.Now()
 
 	// Use catalog_manager to refresh this specific artist
-	cmd := exec.Command("./bin/catalog_manager", "artist", strconv.Itoa(artistID))
-	cmd.Dir = "/home/jmagar/code/nugs/cron"
+	cmd := s.catalogManager("artist", strconv.Itoa(artistID))
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return &models.CheckResult{
 			ArtistID:      artistID,
 			ArtistName:    artistName,
-			PreviousCount: monitor.TotalShows,
+			PreviousCount: currentCount,
 			CurrentCount:  currentCount,
 			Success:       false,
 			Error:         fmt.Sprintf("catalog_manager failed: %v", err),
@@ -203,43 +237,139 @@ func (s *MonitoringService) CheckArtist(artistID int) (*models.CheckResult, erro
 	var newCurrentCount int
 	s.DB.QueryRow(`SELECT COUNT(*) FROM shows WHERE artist_id = ?`, artistID).Scan(&newCurrentCount)
 
-	newShows := newCurrentCount - monitor.TotalShows
+	newShows := newCurrentCount - currentCount
 	if newShows < 0 {
 		newShows = 0
 	}
 
 	// Update monitor
 	s.DB.Exec(`
-		UPDATE artist_monitors 
-		SET total_shows = ?, new_shows_found = new_shows_found + ?, 
-		    last_checked = datetime('now'), updated_at = datetime('now')
+		UPDATE monitors
+		SET shows_found = shows_found + ?, last_check = datetime('now'), updated_at = datetime('now')
 		WHERE id = ?
-	`, newCurrentCount, newShows, monitor.ID)
+	`, newShows, monitor.ID)
 
 	if newShows > 0 {
-		s.DB.Exec(`
-			UPDATE artist_monitors 
-			SET last_new_show = datetime('now') 
-			WHERE id = ?
-		`, monitor.ID)
-
 		// Create alert for new shows
-		s.createAlert(monitor.ID, artistID, 0, models.AlertTypeNewShow,
+		s.createAlert(monitor.ID, artistID, models.AlertTypeNewShow,
 			fmt.Sprintf("%d new show(s) found for %s", newShows, artistName),
 			string(output))
 	}
 
+	// Compare the artist's current completion against the monitor's goal
+	if completionGoal.Valid {
+		monitor.CompletionGoal = &completionGoal.Float64
+	}
+	completion, err := s.GetArtistCompletion(artistID, monitor.CompletionGoal)
+	if err == nil {
+		if completion.MeetsGoal != nil && !*completion.MeetsGoal {
+			s.alertBelowGoal(monitor.ID, artistID, artistName, *monitor.CompletionGoal, completion)
+		}
+
+		var previous *float64
+		if lastCompletion.Valid {
+			previous = &lastCompletion.Float64
+		}
+		if payload := gapThresholdCrossed(monitor.CompletionGoal, previous, completion); payload != nil {
+			payload.Artist.ID = artistID
+			payload.Artist.Name = artistName
+			payload.Monitor.ID = monitor.ID
+			if err := s.webhooks.TriggerEvent(models.WebhookEventGapThreshold, payload); err != nil {
+				log.Printf("Failed to trigger gap_threshold webhooks for %s: %v", artistName, err)
+			}
+		}
+
+		s.DB.Exec(`UPDATE monitors SET last_completion_pct = ? WHERE id = ?`, completion.CompletionPct, monitor.ID)
+	}
+
 	return &models.CheckResult{
 		ArtistID:      artistID,
 		ArtistName:    artistName,
-		PreviousCount: monitor.TotalShows,
+		PreviousCount: currentCount,
 		CurrentCount:  newCurrentCount,
 		NewShows:      newShows,
 		CheckDuration: time.Since(startTime).String(),
 		Success:       true,
+		Completion:    completion,
 	}, nil
 }
 
+// GetArtistCompletion returns how many of the artist's shows have a completed download.
+// MeetsGoal is set when a goal (percent) is given.
+func (s *MonitoringService) GetArtistCompletion(artistID int, goal *float64) (*models.ArtistCompletion, error) {
+	completion := &models.ArtistCompletion{}
+
+	err := s.DB.QueryRow(`
+		SELECT
+			COUNT(*),
+			COUNT(CASE WHEN EXISTS (
+				SELECT 1 FROM downloads d WHERE d.show_id = s.id AND d.status = 'completed'
+			) THEN 1 END)
+		FROM shows s
+		WHERE s.artist_id = ?
+	`, artistID).Scan(&completion.TotalShows, &completion.DownloadedShows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute completion: %v", err)
+	}
+
+	if completion.TotalShows > 0 {
+		completion.CompletionPct = float64(completion.DownloadedShows) / float64(completion.TotalShows) * 100
+	}
+
+	if goal != nil {
+		meetsGoal := completion.CompletionPct >= *goal
+		completion.MeetsGoal = &meetsGoal
+	}
+
+	return completion, nil
+}
+
+// gapThresholdCrossed returns the gap_threshold payload, less the artist and
+// monitor, when completion has dropped below goal since the previous check.
+// An artist already below its goal, or never checked before, hasn't crossed
+// it, so the event fires once per drop rather than on every check.
+func gapThresholdCrossed(goal, previous *float64, completion *models.ArtistCompletion) *models.GapThresholdPayload {
+	if goal == nil || previous == nil {
+		return nil
+	}
+	if *previous < *goal || completion.CompletionPct >= *goal {
+		return nil
+	}
+
+	return &models.GapThresholdPayload{
+		Threshold:          *goal,
+		PreviousCompletion: *previous,
+		Completion:         completion.CompletionPct,
+		MissingShows:       completion.TotalShows - completion.DownloadedShows,
+		TotalShows:         completion.TotalShows,
+	}
+}
+
+// alertBelowGoal raises a below-goal alert and webhook, unless an unacknowledged one is already open
+func (s *MonitoringService) alertBelowGoal(monitorID, artistID int, artistName string, goal float64, completion *models.ArtistCompletion) {
+	var openAlerts int
+	s.DB.QueryRow(`
+		SELECT COUNT(*) FROM monitor_alerts
+		WHERE monitor_id = ? AND type = ? AND acknowledged = 0
+	`, monitorID, models.AlertTypeBelowGoal).Scan(&openAlerts)
+	if openAlerts > 0 {
+		return
+	}
+
+	message := fmt.Sprintf("%s is %.1f%% complete, below the %.1f%% goal", artistName, completion.CompletionPct, goal)
+	details := fmt.Sprintf("%d of %d shows downloaded", completion.DownloadedShows, completion.TotalShows)
+	s.createAlert(monitorID, artistID, models.AlertTypeBelowGoal, message, details)
+
+	var payload models.MonitorAlertPayload
+	payload.Alert.Type = string(models.AlertTypeBelowGoal)
+	payload.Alert.Message = message
+	payload.Alert.Details = details
+	payload.Artist.ID = artistID
+	payload.Artist.Name = artistName
+	payload.Monitor.ID = monitorID
+	s.webhooks.TriggerEvent(models.WebhookEventMonitorAlert, payload)
+}
+
 func (s *MonitoringService) runMonitoringCheck(job *models.Job) {
 	startTime := time.Now()
 
@@ -327,11 +457,21 @@ func (s *MonitoringService) runMonitoringCheck(job *models.Job) {
 	})
 }
 
-func (s *MonitoringService) createAlert(monitorID, artistID, showID int, alertType models.AlertType, message, details string) {
-	s.DB.Exec(`
-		INSERT INTO monitor_alerts (monitor_id, artist_id, show_id, alert_type, message, details, created_at)
-		VALUES (?, ?, NULLIF(?, 0), ?, ?, ?, datetime('now'))
-	`, monitorID, artistID, showID, alertType, message, details)
+// alertTitles are the short titles stored with each type of monitor alert
+var alertTitles = map[models.AlertType]string{
+	models.AlertTypeNewShow:     "New shows found",
+	models.AlertTypeShowUpdate:  "Show updated",
+	models.AlertTypeMissingShow: "Missing show",
+	models.AlertTypeBelowGoal:   "Below completion goal",
+}
+
+func (s *MonitoringService) createAlert(monitorID, artistID int, alertType models.AlertType, message, details string) {
+	if _, err := s.DB.Exec(`
+		INSERT INTO monitor_alerts (monitor_id, artist_id, type, title, message, data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, datetime('now'))
+	`, monitorID, artistID, alertType, alertTitles[alertType], message, details); err != nil {
+		log.Printf("Failed to create %s alert for monitor %d: %v", alertType, monitorID, err)
+	}
 }
 
 func (s *MonitoringService) GetMonitorStats() (*models.MonitorStats, error) {
@@ -382,7 +522,36 @@ func (s *MonitoringService) GetMonitorStats() (*models.MonitorStats, error) {
 	return stats, nil
 }
 
-func (s *MonitoringService) CreateBulkMonitors(req *models.BulkMonitorRequest) (*models.BulkMonitorResponse, error) {
+// GetRecentAlerts returns the newest alerts across all monitors
+func (s *MonitoringService) GetRecentAlerts(limit int) ([]models.MonitorAlert, error) {
+	rows, err := s.DB.Query(`
+		SELECT ma.id, ma.monitor_id, ma.artist_id, ma.type, ma.message,
+		       COALESCE(ma.data, ''), ma.acknowledged, ma.created_at, a.name
+		FROM monitor_alerts ma
+		JOIN artists a ON ma.artist_id = a.id
+		ORDER BY ma.created_at DESC, ma.id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %v", err)
+	}
+	defer rows.Close()
+
+	alerts := []models.MonitorAlert{}
+	for rows.Next() {
+		var alert models.MonitorAlert
+		err := rows.Scan(&alert.ID, &alert.MonitorID, &alert.ArtistID, &alert.AlertType,
+			&alert.Message, &alert.Details, &alert.Acknowledged, &alert.CreatedAt, &alert.ArtistName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+func (s *MonitoringService) CreateBulkMonitors(req *models.BulkMonitorRequest, userID int) (*models.BulkMonitorResponse, error) {
 	response := &models.BulkMonitorResponse{
 		ProcessedCount: len(req.ArtistIDs),
 		SuccessCount:   0,
@@ -398,7 +567,7 @@ func (s *MonitoringService) CreateBulkMonitors(req *models.BulkMonitorRequest) (
 			NotifyShowUpdates: req.NotifyShowUpdates,
 		}
 
-		result, err := s.CreateMonitor(monitorReq)
+		result, err := s.CreateMonitor(monitorReq, userID)
 		if err != nil {
 			response.FailedCount++
 			response.Errors = append(response.Errors, fmt.Sprintf("Artist ID %d: %v", artistID, err))
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckArtist_NoActiveMonitor(t *testing.T) {
+	service := NewMonitoringService(testutil.NewDB(t), models.NewJobManager())
+
+	result, err := service.CheckArtist(testutil.PhishID)
+	require.NoError(t, err)
+	assert.Equal(t, "Phish", result.ArtistName)
+	assert.False(t, result.Success)
+	assert.Equal(t, "No active monitor found for artist", result.Error)
+}
+
+func TestCheckArtist_UnknownArtist(t *testing.T) {
+	service := NewMonitoringService(testutil.NewDB(t), models.NewJobManager())
+
+	_, err := service.CheckArtist(999)
+	assert.Error(t, err)
+}
+
+func TestCheckArtist_BelowGoalAlert(t *testing.T) {
+	db := testutil.NewDB(t)
+	service := NewMonitoringService(db, models.NewJobManager())
+	service.catalogManager = func(args ...string) *exec.Cmd { return exec.Command("true") }
+
+	goal := 50.0
+	created, err := service.CreateMonitor(&models.MonitorRequest{ArtistID: testutil.PhishID, CompletionGoal: &goal}, testutil.AdminUserID)
+	require.NoError(t, err)
+	require.True(t, created.Success)
+
+	belowGoalAlerts := func() int {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM monitor_alerts WHERE monitor_id = ? AND type = 'below_goal'`,
+			created.MonitorID).Scan(&count))
+		return count
+	}
+
+	// One of Phish's two shows downloaded meets the goal
+	downloadID := testutil.InsertDownload(t, db, 67892, "completed", 900)
+	result, err := service.CheckArtist(testutil.PhishID)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	assert.Equal(t, 50.0, result.Completion.CompletionPct)
+	assert.True(t, *result.Completion.MeetsGoal)
+	assert.Equal(t, 0, belowGoalAlerts())
+
+	// Losing the download drops the artist below its goal
+	_, err = db.Exec(`UPDATE downloads SET status = 'failed' WHERE id = ?`, downloadID)
+	require.NoError(t, err)
+	result, err = service.CheckArtist(testutil.PhishID)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	assert.False(t, *result.Completion.MeetsGoal)
+	assert.Equal(t, 1, belowGoalAlerts())
+
+	// The open alert isn't repeated on the next check
+	_, err = service.CheckArtist(testutil.PhishID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, belowGoalAlerts())
+
+	var lastCheck sql.NullString
+	require.NoError(t, db.QueryRow(`SELECT last_check FROM monitors WHERE id = ?`, created.MonitorID).Scan(&lastCheck))
+	assert.True(t, lastCheck.Valid)
+}
+
+func TestCheckArtist_GapThresholdWebhook(t *testing.T) {
+	received := make(chan models.WebhookPayload, 5)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload models.WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	db := testutil.NewDB(t)
+	service := NewMonitoringService(db, models.NewJobManager())
+	service.catalogManager = func(args ...string) *exec.Cmd { return exec.Command("true") }
+
+	webhook, err := service.webhooks.CreateWebhook(&models.WebhookRequest{
+		Name:   "gaps",
+		URL:    server.URL,
+		Events: []models.WebhookEvent{models.WebhookEventGapThreshold},
+	}, testutil.AdminUserID)
+	require.NoError(t, err)
+	require.True(t, webhook.Success, webhook.Error)
+
+	goal := 50.0
+	created, err := service.CreateMonitor(&models.MonitorRequest{ArtistID: testutil.PhishID, CompletionGoal: &goal}, testutil.AdminUserID)
+	require.NoError(t, err)
+	require.True(t, created.Success)
+
+	lastCompletion := func() sql.NullFloat64 {
+		var pct sql.NullFloat64
+		require.NoError(t, db.QueryRow(`SELECT last_completion_pct FROM monitors WHERE id = ?`, created.MonitorID).Scan(&pct))
+		return pct
+	}
+
+	// The first check meets the goal and records the completion
+	downloadID := testutil.InsertDownload(t, db, 67892, "completed", 900)
+	_, err = service.CheckArtist(testutil.PhishID)
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullFloat64{Float64: 50, Valid: true}, lastCompletion())
+
+	// Dropping below the goal crosses the threshold
+	_, err = db.Exec(`UPDATE downloads SET status = 'failed' WHERE id = ?`, downloadID)
+	require.NoError(t, err)
+	_, err = service.CheckArtist(testutil.PhishID)
+	require.NoError(t, err)
+	assert.Equal(t, sql.NullFloat64{Float64: 0, Valid: true}, lastCompletion())
+
+	select {
+	case payload := <-received:
+		assert.Equal(t, models.WebhookEventGapThreshold, payload.Event)
+		data := payload.Data.(map[string]interface{})
+		assert.Equal(t, 50.0, data["threshold"])
+		assert.Equal(t, 50.0, data["previous_completion_pct"])
+		assert.Equal(t, 0.0, data["completion_pct"])
+		assert.Equal(t, float64(created.MonitorID), data["monitor"].(map[string]interface{})["id"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("gap_threshold webhook was not delivered")
+	}
+
+	// Staying below the goal doesn't fire it again
+	_, err = service.CheckArtist(testutil.PhishID)
+	require.NoError(t, err)
+	select {
+	case payload := <-received:
+		t.Fatalf("unexpected %s delivery", payload.Event)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestGapThresholdCrossed(t *testing.T) {
+	goal := 90.0
+	completion := &models.ArtistCompletion{TotalShows: 40, DownloadedShows: 34, CompletionPct: 85}
+
+	tests := []struct {
+		name     string
+		goal     *float64
+		previous float64
+		first    bool
+		crossed  bool
+	}{
+		{name: "drops below goal", goal: &goal, previous: 95, crossed: true},
+		{name: "drops from exactly the goal", goal: &goal, previous: 90, crossed: true},
+		{name: "already below goal", goal: &goal, previous: 88},
+		{name: "first check", goal: &goal, first: true},
+		{name: "no goal", previous: 95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := &tt.previous
+			if tt.first {
+				previous = nil
+			}
+
+			payload := gapThresholdCrossed(tt.goal, previous, completion)
+			if !tt.crossed {
+				assert.Nil(t, payload)
+				return
+			}
+			require.NotNil(t, payload)
+			assert.Equal(t, goal, payload.Threshold)
+			assert.Equal(t, tt.previous, payload.PreviousCompletion)
+			assert.Equal(t, 85.0, payload.Completion)
+			assert.Equal(t, 6, payload.MissingShows)
+			assert.Equal(t, 40, payload.TotalShows)
+		})
+	}
+
+	// Still meeting the goal
+	met := &models.ArtistCompletion{TotalShows: 40, DownloadedShows: 38, CompletionPct: 95}
+	previous := 97.5
+	assert.Nil(t, gapThresholdCrossed(&goal, &previous, met))
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// ErrDatabaseBusy is returned when an optimize is requested while jobs that
+// write heavily to the database are running
+var ErrDatabaseBusy = errors.New("database is busy")
+
+// OptimizeDatabase starts a job running VACUUM and then ANALYZE. VACUUM
+// rewrites the whole file and holds the write lock, so it is refused during
+// a maintenance block and while downloads or catalog refreshes are running.
+func (s *AdminService) OptimizeDatabase(runBy string, client models.ClientInfo) (*models.Job, error) {
+	if err := CheckMaintenanceAllowed(s.DB); err != nil {
+		return nil, err
+	}
+
+	for _, job := range s.JobManager.ListJobs() {
+		if job.Status != models.JobStatusRunning {
+			continue
+		}
+		if job.Type == models.JobTypeDownload || job.Type == models.JobTypeCatalogRefresh {
+			return nil, fmt.Errorf("%w: %s job %s is running", ErrDatabaseBusy, job.Type, job.ID)
+		}
+	}
+
+	job := s.JobManager.CreateJob(models.JobTypeOptimize)
+
+	go s.performOptimize(job, runBy, client)
+
+	return job, nil
+}
+
+func (s *AdminService) performOptimize(job *models.Job, runBy string, client models.ClientInfo) {
+	startTime := time.Now()
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusRunning
+		j.StartedAt = startTime
+		j.Progress = 10
+		j.Message = "Running VACUUM..."
+	})
+
+	fail := func(err error) {
+		completedAt := time.Now()
+		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+			j.Status = models.JobStatusFailed
+			j.Error = err.Error()
+			j.Message = "Database optimize failed"
+			j.CompletedAt = &completedAt
+		})
+		s.logAuditAction(0, runBy, "optimize_database", "maintenance", job.ID,
+			"Database optimize failed: "+err.Error(), client.IPAddress, client.UserAgent, false)
+	}
+
+	result := &models.DatabaseOptimizeResult{}
+
+	sizeBefore, err := s.databaseSize()
+	if err != nil {
+		fail(err)
+		return
+	}
+	result.SizeBeforeBytes = sizeBefore
+
+	vacuumStart := time.Now()
+	if _, err := s.DB.Exec("VACUUM"); err != nil {
+		fail(fmt.Errorf("VACUUM failed: %v", err))
+		return
+	}
+	result.VacuumMs = time.Since(vacuumStart).Milliseconds()
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Progress = 60
+		j.Message = "Running ANALYZE..."
+	})
+
+	analyzeStart := time.Now()
+	if _, err := s.DB.Exec("ANALYZE"); err != nil {
+		fail(fmt.Errorf("ANALYZE failed: %v", err))
+		return
+	}
+	result.AnalyzeMs = time.Since(analyzeStart).Milliseconds()
+
+	sizeAfter, err := s.databaseSize()
+	if err != nil {
+		fail(err)
+		return
+	}
+	result.SizeAfterBytes = sizeAfter
+	result.FreedBytes = sizeBefore - sizeAfter
+	result.FreedMB = float64(result.FreedBytes) / (1024 * 1024)
+	result.DurationMs = time.Since(startTime).Milliseconds()
+
+	completedAt := time.Now()
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusCompleted
+		j.Progress = 100
+		j.Message = fmt.Sprintf("Database optimized: %.2f MB freed", result.FreedMB)
+		j.Result = result
+		j.CompletedAt = &completedAt
+	})
+
+	s.logAuditAction(0, runBy, "optimize_database", "maintenance", job.ID,
+		fmt.Sprintf("Optimized database, freed %d bytes", result.FreedBytes), client.IPAddress, client.UserAgent, true)
+}
+
+// databaseSize returns the size of the main database in bytes
+func (s *AdminService) databaseSize() (int64, error) {
+	var pageCount, pageSize int64
+	if err := s.DB.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, fmt.Errorf("failed to read page count: %v", err)
+	}
+	if err := s.DB.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %v", err)
+	}
+	return pageCount * pageSize, nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestDeferredCloseNotReassigned guards against deferring the Close of a
+// variable that is then reassigned, like rows reused across queries. Each
+// deferred Close keeps its result set, and its connection, open until the
+// function returns.
+func TestDeferredCloseNotReassigned(t *testing.T) {
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pkg := range packages {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				var body *ast.BlockStmt
+				switch fn := node.(type) {
+				case *ast.FuncDecl:
+					body = fn.Body
+				case *ast.FuncLit:
+					body = fn.Body
+				}
+				if body == nil {
+					return true
+				}
+				for _, pos := range reassignedAfterDeferredClose(body) {
+					t.Errorf("%s: variable reassigned after its Close was deferred", fset.Position(pos))
+				}
+				return true
+			})
+		}
+	}
+}
+
+// reassignedAfterDeferredClose returns the assignments in body to a variable
+// whose Close body already deferred. Nested function literals are checked on
+// their own.
+func reassignedAfterDeferredClose(body *ast.BlockStmt) []token.Pos {
+	deferred := make(map[string]token.Pos)
+	var reassigned []token.Pos
+
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch stmt := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			if sel, ok := stmt.Call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Close" {
+				if ident, ok := sel.X.(*ast.Ident); ok {
+					if _, seen := deferred[ident.Name]; !seen {
+						deferred[ident.Name] = stmt.Pos()
+					}
+				}
+			}
+		case *ast.AssignStmt:
+			if stmt.Tok != token.ASSIGN {
+				return true
+			}
+			for _, lhs := range stmt.Lhs {
+				ident, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if pos, seen := deferred[ident.Name]; seen && stmt.Pos() > pos {
+					reassigned = append(reassigned, stmt.Pos())
+				}
+			}
+		}
+		return true
+	})
+
+	return reassigned
+}
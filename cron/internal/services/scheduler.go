@@ -4,14 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/version"
 )
 
 type SchedulerService struct {
@@ -20,12 +24,16 @@ type SchedulerService struct {
 	CatalogService    *CatalogRefreshService
 	MonitoringService *MonitoringService
 	AdminService      *AdminService
+	webhooks          *WebhookService
 
 	isRunning     bool
 	startTime     time.Time
 	schedules     map[int]*models.Schedule
+	runningSince  map[int]time.Time // when each schedule's current execution began
 	scheduleMutex sync.RWMutex
-	stopChan      chan bool
+	restarts      int    // panic restarts since Start
+	lastPanic     string // value of the most recent panic
+	failed        bool   // gave up restarting after schedulerMaxRestarts
 	ctx           context.Context
 	cancel        context.CancelFunc
 	ticker        *time.Ticker
@@ -35,12 +43,13 @@ func NewSchedulerService(db *sql.DB, jobManager *models.JobManager) *SchedulerSe
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &SchedulerService{
-		DB:         db,
-		JobManager: jobManager,
-		schedules:  make(map[int]*models.Schedule),
-		stopChan:   make(chan bool, 1),
-		ctx:        ctx,
-		cancel:     cancel,
+		DB:           db,
+		JobManager:   jobManager,
+		webhooks:     NewWebhookService(db, jobManager),
+		schedules:    make(map[int]*models.Schedule),
+		runningSince: make(map[int]time.Time),
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -63,17 +72,27 @@ func (s *SchedulerService) Start() error {
 		return fmt.Errorf("failed to load schedules: %v", err)
 	}
 
+	s.startLoop()
+
+	log.Println("Scheduler started successfully")
+	return nil
+}
+
+// startLoop starts the scheduler loop on a fresh ticker and context. The
+// caller holds scheduleMutex.
+func (s *SchedulerService) startLoop() {
 	s.isRunning = true
 	s.startTime = time.Now()
+	s.restarts = 0
+	s.lastPanic = ""
+	s.failed = false
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	// Create ticker for checking schedules every minute
-	s.ticker = time.NewTicker(1 * time.Minute)
+	s.ticker = time.NewTicker(schedulerTickInterval)
 
 	// Start the scheduler loop
-	go s.run()
-
-	log.Println("Scheduler started successfully")
-	return nil
+	go s.run(s.ctx, s.ticker)
 }
 
 func (s *SchedulerService) Stop() error {
@@ -88,42 +107,111 @@ func (s *SchedulerService) Stop() error {
 	s.cancel()
 	s.ticker.Stop()
 
-	select {
-	case s.stopChan <- true:
-	default:
-	}
-
 	log.Println("Scheduler stopped")
 	return nil
 }
 
-func (s *SchedulerService) run() {
+// schedulerTickInterval is how often the scheduler checks for due schedules
+var schedulerTickInterval = time.Minute
+
+// schedulerMaxRestarts is how many panics the scheduler loop restarts after
+// before it gives up and marks the scheduler failed
+const schedulerMaxRestarts = 5
+
+// schedulerRestartBackoff is the wait before the first restart after a
+// panic, doubling with each further restart up to schedulerMaxRestartBackoff
+var schedulerRestartBackoff = 5 * time.Second
+
+const schedulerMaxRestartBackoff = 5 * time.Minute
+
+// run checks schedules on every tick until ctx is canceled. A panic restarts
+// the loop on the same ticker after a backoff. The locks the loop takes are
+// released by defers as the panic unwinds, so by the time it is recovered
+// here none are held, and the restart happens on a goroutine of its own.
+func (s *SchedulerService) run(ctx context.Context, ticker *time.Ticker) {
 	defer func() {
 		if r := recover(); r != nil {
-			log.Printf("Scheduler panic recovered: %v", r)
-			// Restart scheduler after panic
-			time.Sleep(5 * time.Second)
-			s.Start()
+			log.Printf("Scheduler panic recovered: %v\n%s", r, debug.Stack())
+			go s.restartAfterPanic(ctx, ticker, r)
 		}
 	}()
 
 	for {
 		select {
-		case <-s.ctx.Done():
+		case <-ctx.Done():
 			return
-		case <-s.stopChan:
-			return
-		case <-s.ticker.C:
+		case <-ticker.C:
 			s.checkSchedules()
 		}
 	}
 }
 
+// restartAfterPanic restarts the scheduler loop after it panicked with r,
+// backing off with each restart, and marks the scheduler failed once it has
+// restarted schedulerMaxRestarts times. Both are alerted to system_alert
+// webhooks.
+func (s *SchedulerService) restartAfterPanic(ctx context.Context, ticker *time.Ticker, r any) {
+	s.scheduleMutex.Lock()
+	if ctx.Err() != nil {
+		// Stopped since the panic; a later Start has a loop of its own
+		s.scheduleMutex.Unlock()
+		return
+	}
+	s.restarts++
+	s.lastPanic = fmt.Sprint(r)
+	restarts := s.restarts
+	giveUp := restarts > schedulerMaxRestarts
+	if giveUp {
+		s.isRunning = false
+		s.failed = true
+		s.cancel()
+		ticker.Stop()
+	}
+	s.scheduleMutex.Unlock()
+
+	if giveUp {
+		log.Printf("Scheduler panicked %d times, giving up", restarts)
+		s.alertRestart("scheduler_failed", "critical",
+			fmt.Sprintf("Scheduler stopped after %d panics", restarts), r)
+		return
+	}
+
+	backoff := schedulerRestartBackoff << (restarts - 1)
+	if backoff > schedulerMaxRestartBackoff || backoff <= 0 {
+		backoff = schedulerMaxRestartBackoff
+	}
+	log.Printf("Restarting scheduler in %s (restart %d of %d)", backoff, restarts, schedulerMaxRestarts)
+	s.alertRestart("scheduler_restarted", "warning",
+		fmt.Sprintf("Scheduler restarting after a panic (restart %d of %d)", restarts, schedulerMaxRestarts), r)
+
+	// Stop during the backoff cancels the restart
+	select {
+	case <-time.After(backoff):
+		go s.run(ctx, ticker)
+	case <-ctx.Done():
+	}
+}
+
+// alertRestart notifies system_alert webhooks that the scheduler panicked
+func (s *SchedulerService) alertRestart(alertType, severity, message string, r any) {
+	var payload models.SystemAlertPayload
+	payload.Alert.Type = alertType
+	payload.Alert.Severity = severity
+	payload.Alert.Message = message
+	payload.Alert.Details = fmt.Sprint(r)
+	payload.Alert.Component = "scheduler"
+	payload.System.Version = "v" + version.Version
+	if err := s.webhooks.TriggerEvent(models.WebhookEventSystemAlert, payload); err != nil {
+		log.Printf("Failed to trigger scheduler alert webhooks: %v", err)
+	}
+}
+
 func (s *SchedulerService) checkSchedules() {
-	s.scheduleMutex.RLock()
-	defer s.scheduleMutex.RUnlock()
+	s.scheduleMutex.Lock()
+	defer s.scheduleMutex.Unlock()
 
 	now := time.Now()
+	maxRuntime := JobMaxRuntime(s.DB)
 
 	for _, schedule := range s.schedules {
 		if schedule.Status != models.ScheduleStatusActive {
@@ -134,30 +222,37 @@ func (s *SchedulerService) checkSchedules() {
 			continue
 		}
 
-		// Check if schedule is already running
+		// Check if schedule is already running. An execution running longer
+		// than jobs may is stuck, so it no longer blocks the schedule.
 		if schedule.IsRunning {
-			continue
+			since := s.runningSince[schedule.ID]
+			if maxRuntime == 0 || now.Sub(since) < maxRuntime {
+				continue
+			}
+			log.Printf("Schedule %s has been running since %s, longer than %s; running it again",
+				schedule.Name, since.Format(time.RFC3339), maxRuntime)
 		}
 
 		// Execute schedule
-		go s.executeSchedule(schedule)
+		schedule.IsRunning = true
+		s.runningSince[schedule.ID] = now
+		go s.executeSchedule(schedule, now)
 	}
 }
 
-func (s *SchedulerService) executeSchedule(schedule *models.Schedule) {
-	// Mark as running
-	s.scheduleMutex.Lock()
-	schedule.IsRunning = true
-	s.scheduleMutex.Unlock()
-
+// executeSchedule runs schedule for the execution checkSchedules started at
+// startTime
+func (s *SchedulerService) executeSchedule(schedule *models.Schedule, startTime time.Time) {
 	defer func() {
 		s.scheduleMutex.Lock()
-		schedule.IsRunning = false
+		// A stuck execution finishing late leaves the one that replaced it alone
+		if s.runningSince[schedule.ID].Equal(startTime) {
+			schedule.IsRunning = false
+			delete(s.runningSince, schedule.ID)
+		}
 		s.scheduleMutex.Unlock()
 	}()
 
-	startTime := time.Now()
-
 	// Create execution record
 	executionID, err := s.createExecution(schedule.ID, "running", "")
 	if err != nil {
@@ -166,23 +261,7 @@ func (s *SchedulerService) executeSchedule(schedule *models.Schedule) {
 	}
 
 	// Execute the scheduled task
-	var job *models.Job
-	var executeErr error
-
-	switch schedule.Type {
-	case models.ScheduleTypeCatalogRefresh:
-		job, executeErr = s.executeCatalogRefresh(schedule)
-	case models.ScheduleTypeMonitorCheck:
-		job, executeErr = s.executeMonitorCheck(schedule)
-	case models.ScheduleTypeSystemCleanup:
-		job, executeErr = s.executeSystemCleanup(schedule)
-	case models.ScheduleTypeDatabaseBackup:
-		job, executeErr = s.executeDatabaseBackup(schedule)
-	case models.ScheduleTypeHealthCheck:
-		job, executeErr = s.executeHealthCheck(schedule)
-	default:
-		executeErr = fmt.Errorf("unsupported schedule type: %s", schedule.Type)
-	}
+	job, executeErr := s.runScheduledTask(schedule)
 
 	duration := int(time.Since(startTime).Milliseconds())
 
@@ -198,7 +277,6 @@ func (s *SchedulerService) executeSchedule(schedule *models.Schedule) {
 
 	if job != nil {
 		jobID = job.ID
-		schedule.CurrentJobID = jobID
 	}
 
 	s.updateExecution(executionID, status, duration, errorMsg, jobID)
@@ -212,6 +290,33 @@ func (s *SchedulerService) executeSchedule(schedule *models.Schedule) {
 	log.Printf("Schedule %s executed: %s (duration: %dms)", schedule.Name, status, duration)
 }
 
+// runScheduledTask starts the task schedule runs. A panic fails the
+// execution instead of taking down the process, which executeSchedule's
+// goroutine would otherwise do.
+func (s *SchedulerService) runScheduledTask(schedule *models.Schedule) (job *models.Job, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Schedule %s panicked: %v\n%s", schedule.Name, r, debug.Stack())
+			job, err = nil, fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	switch schedule.Type {
+	case models.ScheduleTypeCatalogRefresh:
+		return s.executeCatalogRefresh(schedule)
+	case models.ScheduleTypeMonitorCheck:
+		return s.executeMonitorCheck(schedule)
+	case models.ScheduleTypeSystemCleanup:
+		return s.executeSystemCleanup(schedule)
+	case models.ScheduleTypeDatabaseBackup:
+		return s.executeDatabaseBackup(schedule)
+	case models.ScheduleTypeHealthCheck:
+		return s.executeHealthCheck(schedule)
+	default:
+		return nil, fmt.Errorf("unsupported schedule type: %s", schedule.Type)
+	}
+}
+
 func (s *SchedulerService) executeCatalogRefresh(schedule *models.Schedule) (*models.Job, error) {
 	if s.CatalogService == nil {
 		return nil, fmt.Errorf("catalog service not available")
@@ -229,9 +334,59 @@ func (s *SchedulerService) executeCatalogRefresh(schedule *models.Schedule) (*mo
 	}
 
 	job := s.CatalogService.StartRefresh(force)
+	go s.notifyNewShows(schedule, job)
 	return job, nil
 }
 
+// scheduleJobPollInterval is how often the scheduler checks on a job it
+// started when it acts on the job's result
+var scheduleJobPollInterval = 5 * time.Second
+
+// scheduleNewShowsSampleSize caps the shows listed in a schedule_new_shows webhook
+const scheduleNewShowsSampleSize = 10
+
+// notifyNewShows waits for a scheduled catalog refresh to finish and fires a
+// schedule_new_shows webhook if it added shows. The initial import, where
+// every show is new, is not reported.
+func (s *SchedulerService) notifyNewShows(schedule *models.Schedule, job *models.Job) {
+	ticker := time.NewTicker(scheduleJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		current, exists := s.JobManager.GetJob(job.ID)
+		if !exists {
+			return
+		}
+		switch current.Status {
+		case models.JobStatusFailed, models.JobStatusCancelled:
+			return
+		case models.JobStatusCompleted:
+			result, ok := current.Result.(*RefreshResult)
+			if !ok || result.Changes == nil || result.Changes.AddedCount == 0 || result.Changes.InitialImport {
+				return
+			}
+
+			payload := models.ScheduleNewShowsPayload{
+				JobID:               job.ID,
+				AddedCount:          result.Changes.AddedCount,
+				Sample:              result.Changes.AddedShows,
+				ArtistsWithNewShows: result.Changes.ArtistsWithNewShows,
+			}
+			payload.Schedule.ID = schedule.ID
+			payload.Schedule.Name = schedule.Name
+			if len(payload.Sample) > scheduleNewShowsSampleSize {
+				payload.Sample = payload.Sample[:scheduleNewShowsSampleSize]
+			}
+
+			if err := s.webhooks.TriggerEvent(models.WebhookEventScheduleNewShows, payload); err != nil {
+				log.Printf("Failed to trigger schedule_new_shows webhooks: %v", err)
+			}
+			return
+		}
+		<-ticker.C
+	}
+}
+
 func (s *SchedulerService) executeMonitorCheck(schedule *models.Schedule) (*models.Job, error) {
 	if s.MonitoringService == nil {
 		return nil, fmt.Errorf("monitoring service not available")
@@ -256,17 +411,23 @@ func (s *SchedulerService) executeSystemCleanup(schedule *models.Schedule) (*mod
 		OldLogs:       getBool(params, "old_logs", false),
 		OldJobs:       getBool(params, "old_jobs", false),
 		OldDeliveries: getBool(params, "old_deliveries", false),
+		OldExecutions: getBool(params, "old_executions", false),
+		OldReports:    getBool(params, "old_reports", false),
 		OldFiles:      getBool(params, "old_files", false),
 		DryRun:        getBool(params, "dry_run", false),
 	}
 
-	job, err := s.AdminService.RunCleanup(req, "scheduler")
+	job, err := s.AdminService.RunCleanup(req, "scheduler", models.ClientInfo{})
 	return job, err
 }
 
 func (s *SchedulerService) executeDatabaseBackup(schedule *models.Schedule) (*models.Job, error) {
+	if err := CheckMaintenanceAllowed(s.DB); err != nil {
+		return nil, err
+	}
+
 	// Simplified database backup (in production would create actual backup)
-	job := s.JobManager.CreateJob(models.JobTypeAnalytics)
+	job := s.JobManager.CreateJob(models.JobTypeBackup)
 
 	go func() {
 		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
@@ -295,7 +456,7 @@ func (s *SchedulerService) executeDatabaseBackup(schedule *models.Schedule) (*mo
 }
 
 func (s *SchedulerService) executeHealthCheck(schedule *models.Schedule) (*models.Job, error) {
-	job := s.JobManager.CreateJob(models.JobTypeAnalytics)
+	job := s.JobManager.CreateJob(models.JobTypeHealthCheck)
 
 	go func() {
 		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
@@ -348,7 +509,30 @@ func (s *SchedulerService) executeHealthCheck(schedule *models.Schedule) (*model
 	return job, nil
 }
 
+// ErrInvalidScheduleType rejects a schedule type the scheduler cannot run
+var ErrInvalidScheduleType = errors.New("unsupported schedule type")
+
+// ErrInvalidScheduleParameters rejects parameters a schedule type does not
+// accept
+var ErrInvalidScheduleParameters = errors.New("invalid schedule parameters")
+
 func (s *SchedulerService) CreateSchedule(req *models.ScheduleRequest, createdBy string) (*models.ScheduleResponse, error) {
+	if !req.Type.Valid() {
+		return &models.ScheduleResponse{
+			Success:    false,
+			Error:      fmt.Sprintf("%v: %s", ErrInvalidScheduleType, req.Type),
+			ValidTypes: models.ScheduleTypes,
+		}, nil
+	}
+
+	if problems := models.ValidateScheduleParameters(req.Type, req.Parameters); problems != nil {
+		return &models.ScheduleResponse{
+			Success:         false,
+			Error:           fmt.Sprintf("%v: %s", ErrInvalidScheduleParameters, strings.Join(problems, "; ")),
+			ParameterErrors: problems,
+		}, nil
+	}
+
 	// Validate cron expression
 	if !s.isValidCronExpr(req.CronExpr) {
 		return &models.ScheduleResponse{
@@ -408,6 +592,22 @@ func (s *SchedulerService) UpdateSchedule(scheduleID int, req *models.ScheduleUp
 		args = append(args, *req.Description)
 	}
 
+	if req.Type != nil {
+		if !req.Type.Valid() {
+			return fmt.Errorf("%w: %s", ErrInvalidScheduleType, *req.Type)
+		}
+		updates = append(updates, "type = ?")
+		args = append(args, *req.Type)
+	}
+
+	// New parameters, or the parameters kept under a new type, must suit the
+	// type the schedule ends up with
+	if req.Type != nil || req.Parameters != nil {
+		if err := s.validateUpdatedParameters(scheduleID, req); err != nil {
+			return err
+		}
+	}
+
 	if req.CronExpr != nil {
 		if !s.isValidCronExpr(*req.CronExpr) {
 			return fmt.Errorf("invalid cron expression")
@@ -424,6 +624,24 @@ func (s *SchedulerService) UpdateSchedule(scheduleID int, req *models.ScheduleUp
 	if req.Status != nil {
 		updates = append(updates, "status = ?")
 		args = append(args, *req.Status)
+
+		// Resuming runs the schedule from now on. The next_run it kept while
+		// paused has likely passed and would fire it at once.
+		if *req.Status == models.ScheduleStatusActive && req.CronExpr == nil {
+			var current models.ScheduleStatus
+			var cronExpr string
+			err := s.DB.QueryRow("SELECT status, cron_expr FROM schedules WHERE id = ?", scheduleID).Scan(&current, &cronExpr)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("schedule not found")
+			}
+			if err != nil {
+				return err
+			}
+			if current != models.ScheduleStatusActive {
+				updates = append(updates, "next_run = ?")
+				args = append(args, s.parseNextRun(cronExpr))
+			}
+		}
 	}
 
 	if req.Parameters != nil {
@@ -457,6 +675,37 @@ func (s *SchedulerService) UpdateSchedule(scheduleID int, req *models.ScheduleUp
 	return nil
 }
 
+// validateUpdatedParameters checks the parameters schedule scheduleID will
+// have after req against the type it will have
+func (s *SchedulerService) validateUpdatedParameters(scheduleID int, req *models.ScheduleUpdateRequest) error {
+	var scheduleType models.ScheduleType
+	var stored sql.NullString
+	err := s.DB.QueryRow("SELECT type, parameters FROM schedules WHERE id = ?", scheduleID).Scan(&scheduleType, &stored)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("schedule not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if req.Type != nil {
+		scheduleType = *req.Type
+	}
+	var params map[string]interface{}
+	if req.Parameters != nil {
+		params = *req.Parameters
+	} else if stored.Valid && stored.String != "" {
+		if err := json.Unmarshal([]byte(stored.String), &params); err != nil {
+			return fmt.Errorf("%w: stored parameters are not a JSON object", ErrInvalidScheduleParameters)
+		}
+	}
+
+	if problems := models.ValidateScheduleParameters(scheduleType, params); problems != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidScheduleParameters, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
 func (s *SchedulerService) DeleteSchedule(scheduleID int) error {
 	result, err := s.DB.Exec("DELETE FROM schedules WHERE id = ?", scheduleID)
 	if err != nil {
@@ -468,9 +717,6 @@ func (s *SchedulerService) DeleteSchedule(scheduleID int) error {
 		return fmt.Errorf("schedule not found")
 	}
 
-	// Delete related executions
-	s.DB.Exec("DELETE FROM schedule_executions WHERE schedule_id = ?", scheduleID)
-
 	// Remove from memory
 	s.scheduleMutex.Lock()
 	delete(s.schedules, scheduleID)
@@ -480,11 +726,16 @@ func (s *SchedulerService) DeleteSchedule(scheduleID int) error {
 }
 
 func (s *SchedulerService) GetStatus() (*models.SchedulerStatus, error) {
+	s.scheduleMutex.RLock()
 	status := &models.SchedulerStatus{
 		IsRunning: s.isRunning,
 		StartTime: s.startTime,
 		Uptime:    time.Since(s.startTime).String(),
+		Restarts:  s.restarts,
+		LastPanic: s.lastPanic,
+		Failed:    s.failed,
 	}
+	s.scheduleMutex.RUnlock()
 
 	if !s.isRunning {
 		return status, nil
@@ -602,6 +853,55 @@ func (s *SchedulerService) GetStats() (*models.SchedulerStats, error) {
 	return stats, nil
 }
 
+// UpcomingRuns returns the next count runs across all active schedules,
+// soonest first. Each schedule's runs come from stepping its cron expression
+// forward from now, the same calculation that sets its next_run.
+func (s *SchedulerService) UpcomingRuns(count int) ([]models.UpcomingRun, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, name, type, cron_expr
+		FROM schedules
+		WHERE status = 'active'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	runs := []models.UpcomingRun{}
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := rows.Scan(&schedule.ID, &schedule.Name, &schedule.Type, &schedule.CronExpr); err != nil {
+			continue
+		}
+
+		// No schedule contributes more than count runs to the merged list
+		next := now
+		for i := 0; i < count; i++ {
+			next = s.nextRunAfter(schedule.CronExpr, next)
+			runs = append(runs, models.UpcomingRun{
+				ScheduleID:   schedule.ID,
+				ScheduleName: schedule.Name,
+				Type:         schedule.Type,
+				CronExpr:     schedule.CronExpr,
+				RunAt:        next,
+			})
+		}
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].RunAt.Equal(runs[j].RunAt) {
+			return runs[i].RunAt.Before(runs[j].RunAt)
+		}
+		return runs[i].ScheduleID < runs[j].ScheduleID
+	})
+	if len(runs) > count {
+		runs = runs[:count]
+	}
+
+	return runs, nil
+}
+
 // Helper functions
 func (s *SchedulerService) loadSchedules() error {
 	rows, err := s.DB.Query(`
@@ -636,13 +936,13 @@ func (s *SchedulerService) loadSchedules() error {
 		}
 
 		if nextRun.Valid {
-			if t, err := time.Parse("2006-01-02 15:04:05", nextRun.String); err == nil {
+			if t, ok := parseScheduleTime(nextRun.String); ok {
 				schedule.NextRun = &t
 			}
 		}
 
 		if lastRun.Valid {
-			if t, err := time.Parse("2006-01-02 15:04:05", lastRun.String); err == nil {
+			if t, ok := parseScheduleTime(lastRun.String); ok {
 				schedule.LastRun = &t
 			}
 		}
@@ -670,6 +970,17 @@ func (s *SchedulerService) loadSchedules() error {
 	return nil
 }
 
+// parseScheduleTime reads a schedule timestamp, written either by SQLite's
+// datetime() or as a time.Time the driver scans back in RFC 3339
+func parseScheduleTime(value string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
 func (s *SchedulerService) createExecution(scheduleID int, status, jobID string) (int64, error) {
 	result, err := s.DB.Exec(`
 		INSERT INTO schedule_executions (schedule_id, job_id, status, started_at)
@@ -710,7 +1021,12 @@ func (s *SchedulerService) updateScheduleAfterExecution(schedule *models.Schedul
 		`, now, jobID, schedule.ID)
 	}
 
-	// Update in memory
+	// Update in memory, under the lock checkSchedules reads it with
+	s.scheduleMutex.Lock()
+	defer s.scheduleMutex.Unlock()
+	if jobID != "" {
+		schedule.CurrentJobID = jobID
+	}
 	schedule.LastRun = &now
 	schedule.LastJobID = jobID
 	schedule.RunCount++
@@ -729,12 +1045,20 @@ func (s *SchedulerService) calculateNextRun(schedule *models.Schedule) {
 	nextRun := s.parseNextRun(schedule.CronExpr)
 
 	s.DB.Exec("UPDATE schedules SET next_run = ? WHERE id = ?", nextRun, schedule.ID)
+
+	s.scheduleMutex.Lock()
 	schedule.NextRun = &nextRun
+	s.scheduleMutex.Unlock()
 }
 
 func (s *SchedulerService) parseNextRun(cronExpr string) time.Time {
+	return s.nextRunAfter(cronExpr, time.Now())
+}
+
+// nextRunAfter returns the first run of cronExpr after now, so applying it to
+// its own result steps through the schedule's future runs
+func (s *SchedulerService) nextRunAfter(cronExpr string, now time.Time) time.Time {
 	// Simplified cron parsing - in production use a proper cron library
-	now := time.Now()
 
 	parts := strings.Fields(cronExpr)
 	if len(parts) != 5 {
@@ -759,7 +1083,7 @@ func (s *SchedulerService) parseNextRun(cronExpr string) time.Time {
 		// Daily at specific hour
 		if h, err := strconv.Atoi(hour); err == nil {
 			next := time.Date(now.Year(), now.Month(), now.Day(), h, 0, 0, 0, now.Location())
-			if next.Before(now) {
+			if !next.After(now) {
 				next = next.Add(24 * time.Hour)
 			}
 			return next
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastScheduler makes the scheduler tick and restart in milliseconds for the
+// rest of the test
+func fastScheduler(t *testing.T) {
+	tick, backoff := schedulerTickInterval, schedulerRestartBackoff
+	schedulerTickInterval, schedulerRestartBackoff = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { schedulerTickInterval, schedulerRestartBackoff = tick, backoff })
+}
+
+// startScheduler starts s on the schedules it already holds
+func startScheduler(s *SchedulerService) {
+	s.scheduleMutex.Lock()
+	s.startLoop()
+	s.scheduleMutex.Unlock()
+}
+
+func TestSchedulerService_GivesUpAfterRepeatedPanics(t *testing.T) {
+	fastScheduler(t)
+	s := NewSchedulerService(testutil.NewDB(t), models.NewJobManager())
+
+	// A nil schedule panics every check
+	s.schedules[0] = nil
+	startScheduler(s)
+
+	require.Eventually(t, func() bool {
+		status, _ := s.GetStatus()
+		return status.Failed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status, _ := s.GetStatus()
+	assert.False(t, status.IsRunning)
+	assert.Equal(t, schedulerMaxRestarts+1, status.Restarts)
+	assert.Contains(t, status.LastPanic, "nil pointer")
+
+	// Starting again clears the failure
+	s.scheduleMutex.Lock()
+	delete(s.schedules, 0)
+	s.scheduleMutex.Unlock()
+	startScheduler(s)
+	defer s.Stop()
+
+	status, _ = s.GetStatus()
+	assert.True(t, status.IsRunning)
+	assert.False(t, status.Failed)
+	assert.Zero(t, status.Restarts)
+}
+
+func TestSchedulerService_RecoversFromExecutionPanic(t *testing.T) {
+	fastScheduler(t)
+	db := testutil.NewDB(t)
+	result, err := db.Exec(`INSERT INTO schedules (name, cron, job_type) VALUES ('Hourly check', '0 * * * *', 'monitor_check')`)
+	require.NoError(t, err)
+	id, err := result.LastInsertId()
+	require.NoError(t, err)
+
+	s := NewSchedulerService(db, models.NewJobManager())
+	// A monitoring service without a job manager panics as the check starts
+	s.MonitoringService = &MonitoringService{}
+
+	due := time.Now().Add(-time.Minute)
+	schedule := &models.Schedule{
+		ID:       int(id),
+		Name:     "Hourly check",
+		Type:     models.ScheduleTypeMonitorCheck,
+		CronExpr: "0 * * * *",
+		Status:   models.ScheduleStatusActive,
+		NextRun:  &due,
+	}
+	s.schedules[schedule.ID] = schedule
+	startScheduler(s)
+
+	// The execution fails and the schedule moves on to its next run
+	require.Eventually(t, func() bool {
+		s.scheduleMutex.RLock()
+		defer s.scheduleMutex.RUnlock()
+		return schedule.LastStatus == "failed" && !schedule.IsRunning
+	}, 5*time.Second, 10*time.Millisecond)
+
+	s.scheduleMutex.RLock()
+	assert.True(t, schedule.NextRun.After(time.Now()))
+	assert.EqualValues(t, 1, schedule.FailCount)
+	s.scheduleMutex.RUnlock()
+
+	// The loop kept running without restarting, and no lock was left held
+	status, err := s.GetStatus()
+	require.NotNil(t, status)
+	assert.True(t, status.IsRunning)
+	assert.Zero(t, status.Restarts)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop() }()
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop deadlocked after the panic")
+	}
+}
+
+func TestSchedulerService_RestartsLoopWithoutDeadlock(t *testing.T) {
+	fastScheduler(t)
+	s := NewSchedulerService(testutil.NewDB(t), models.NewJobManager())
+
+	// A nil schedule panics the check while it holds scheduleMutex
+	s.schedules[0] = nil
+	startScheduler(s)
+
+	require.Eventually(t, func() bool {
+		status, _ := s.GetStatus()
+		return status.Restarts >= 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Fixing the schedules lets the restarted loop carry on
+	s.scheduleMutex.Lock()
+	delete(s.schedules, 0)
+	restarts := s.restarts
+	s.scheduleMutex.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+	status, _ := s.GetStatus()
+	assert.True(t, status.IsRunning)
+	assert.LessOrEqual(t, status.Restarts, restarts+1)
+	assert.NoError(t, s.Stop())
+}
+
+// scheduleDB returns a test database whose schedule tables match the columns
+// the scheduler reads and writes, which migration 001 predates
+func scheduleDB(t *testing.T) *sql.DB {
+	db := testutil.NewDB(t)
+	_, err := db.Exec(`DROP TABLE schedule_executions; DROP TABLE schedules`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE schedules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL, description TEXT, type TEXT NOT NULL,
+			cron_expr TEXT NOT NULL, status TEXT NOT NULL DEFAULT 'active',
+			parameters TEXT, next_run TIMESTAMP, last_run TIMESTAMP,
+			last_job_id TEXT, last_status TEXT,
+			run_count INTEGER DEFAULT 0, fail_count INTEGER DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			created_by TEXT NOT NULL
+		);
+		CREATE TABLE schedule_executions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			schedule_id INTEGER NOT NULL, job_id TEXT, status TEXT NOT NULL,
+			started_at TIMESTAMP NOT NULL, completed_at TIMESTAMP,
+			duration_ms INTEGER, error TEXT, result TEXT
+		)`)
+	require.NoError(t, err)
+	return db
+}
+
+func TestSchedulerService_ResumeDoesNotFireImmediately(t *testing.T) {
+	db := scheduleDB(t)
+	s := NewSchedulerService(db, models.NewJobManager())
+
+	// Paused an hour ago, so the next run it kept has passed
+	result, err := db.Exec(`
+		INSERT INTO schedules (name, description, type, cron_expr, status, next_run, created_by)
+		VALUES ('Hourly check', '', 'monitor_check', '0 * * * *', 'paused', datetime('now', '-1 hour'), 'admin')`)
+	require.NoError(t, err)
+	id, err := result.LastInsertId()
+	require.NoError(t, err)
+
+	active := models.ScheduleStatusActive
+	require.NoError(t, s.UpdateSchedule(int(id), &models.ScheduleUpdateRequest{Status: &active}))
+
+	s.scheduleMutex.RLock()
+	schedule := s.schedules[int(id)]
+	require.NotNil(t, schedule)
+	require.NotNil(t, schedule.NextRun)
+	assert.Equal(t, models.ScheduleStatusActive, schedule.Status)
+	assert.True(t, schedule.NextRun.After(time.Now()), "next run %s is not in the future", schedule.NextRun)
+	s.scheduleMutex.RUnlock()
+
+	// The first check after resuming leaves it to its next run
+	s.checkSchedules()
+	s.scheduleMutex.RLock()
+	assert.False(t, schedule.IsRunning)
+	s.scheduleMutex.RUnlock()
+
+	var executions int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schedule_executions`).Scan(&executions))
+	assert.Zero(t, executions)
+
+	// Resuming a schedule that is already active keeps its next run
+	nextRun := *schedule.NextRun
+	require.NoError(t, s.UpdateSchedule(int(id), &models.ScheduleUpdateRequest{Status: &active}))
+	s.scheduleMutex.RLock()
+	assert.True(t, nextRun.Equal(*s.schedules[int(id)].NextRun))
+	s.scheduleMutex.RUnlock()
+}
+
+func TestSchedulerService_ScheduleTypeValidation(t *testing.T) {
+	s := NewSchedulerService(scheduleDB(t), models.NewJobManager())
+
+	response, err := s.CreateSchedule(&models.ScheduleRequest{
+		Name: "Typo", Type: "helth_check", CronExpr: "0 * * * *",
+	}, "admin")
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, models.ScheduleTypes, response.ValidTypes)
+
+	response, err = s.CreateSchedule(&models.ScheduleRequest{
+		Name: "Nightly", Type: models.ScheduleTypeHealthCheck, CronExpr: "0 3 * * *",
+	}, "admin")
+	require.NoError(t, err)
+	require.True(t, response.Success, response.Error)
+
+	invalid := models.ScheduleType("backup")
+	err = s.UpdateSchedule(response.ScheduleID, &models.ScheduleUpdateRequest{Type: &invalid})
+	assert.ErrorIs(t, err, ErrInvalidScheduleType)
+
+	backup := models.ScheduleTypeDatabaseBackup
+	require.NoError(t, s.UpdateSchedule(response.ScheduleID, &models.ScheduleUpdateRequest{Type: &backup}))
+	s.scheduleMutex.RLock()
+	assert.Equal(t, backup, s.schedules[response.ScheduleID].Type)
+	s.scheduleMutex.RUnlock()
+}
+
+func TestSchedulerService_ScheduleParameterValidation(t *testing.T) {
+	s := NewSchedulerService(scheduleDB(t), models.NewJobManager())
+
+	response, err := s.CreateSchedule(&models.ScheduleRequest{
+		Name: "Refresh", Type: models.ScheduleTypeCatalogRefresh, CronExpr: "0 3 * * *",
+		Parameters: map[string]interface{}{"forced": true},
+	}, "admin")
+	require.NoError(t, err)
+	assert.False(t, response.Success)
+	assert.Equal(t, []string{`unknown parameter "forced"`}, response.ParameterErrors)
+
+	response, err = s.CreateSchedule(&models.ScheduleRequest{
+		Name: "Cleanup", Type: models.ScheduleTypeSystemCleanup, CronExpr: "0 2 * * 0",
+		Parameters: map[string]interface{}{"old_jobs": true},
+	}, "admin")
+	require.NoError(t, err)
+	require.True(t, response.Success, response.Error)
+	id := response.ScheduleID
+
+	err = s.UpdateSchedule(id, &models.ScheduleUpdateRequest{
+		Parameters: &map[string]interface{}{"old_jobs": "yes"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidScheduleParameters)
+
+	// The stored old_jobs means nothing to a health check
+	healthCheck := models.ScheduleTypeHealthCheck
+	err = s.UpdateSchedule(id, &models.ScheduleUpdateRequest{Type: &healthCheck})
+	assert.ErrorIs(t, err, ErrInvalidScheduleParameters)
+
+	require.NoError(t, s.UpdateSchedule(id, &models.ScheduleUpdateRequest{
+		Type: &healthCheck, Parameters: &map[string]interface{}{},
+	}))
+}
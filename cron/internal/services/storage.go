@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// storageTrendDays is the window of daily download sizes the storage
+// forecast averages, the same window as the download analytics trends
+const storageTrendDays = 30
+
+// storageMaxForecastDays bounds the projected full date
+const storageMaxForecastDays = 100 * 365
+
+// statStorage measures the filesystem holding default_download_path
+func statStorage(db *sql.DB) (*models.StorageStatus, error) {
+	status := &models.StorageStatus{
+		Path: GetConfigString(db, "default_download_path", "/downloads"),
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(status.Path, &stat); err != nil {
+		return nil, err
+	}
+
+	status.TotalGB = float64(stat.Blocks*uint64(stat.Bsize)) / (1024 * 1024 * 1024)
+	status.FreeGB = float64(stat.Bavail*uint64(stat.Bsize)) / (1024 * 1024 * 1024)
+	status.UsedGB = status.TotalGB - status.FreeGB
+	if status.TotalGB > 0 {
+		status.UsagePercent = (status.UsedGB / status.TotalGB) * 100
+	}
+
+	return status, nil
+}
+
+// downloadSizeTrend returns the downloads of shows at location created per
+// day over the last storageTrendDays days, or within dates when set, with the
+// size of those that completed
+func downloadSizeTrend(ctx context.Context, db *sql.DB, location locationScope, dates dateRange) ([]models.TrendPoint, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT date(created_at) as date,
+		       COUNT(*) as count,
+		       COALESCE(SUM(CASE WHEN status = 'completed' THEN size_mb ELSE 0 END), 0) / 1024.0 as size_gb
+		FROM downloads
+		WHERE `+dates.orSince("created_at", fmt.Sprintf("-%d days", storageTrendDays))+` AND `+location.in("show_id", "id")+`
+		GROUP BY date(created_at)
+		ORDER BY date
+	`, location.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trends []models.TrendPoint
+	for rows.Next() {
+		var trend models.TrendPoint
+		if err := rows.Scan(&trend.Date, &trend.Count, &trend.SizeGB); err != nil {
+			return nil, err
+		}
+		trends = append(trends, trend)
+	}
+	return trends, rows.Err()
+}
+
+// GetStorageForecast projects when the download filesystem fills up from the
+// average daily size of downloads over the trend window. Without growth there
+// is no projection.
+func GetStorageForecast(db *sql.DB) (*models.StorageForecast, error) {
+	storage, err := statStorage(db)
+	if err != nil {
+		return nil, err
+	}
+
+	trends, err := downloadSizeTrend(context.Background(), db, locationScope{}, dateRange{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download trends: %v", err)
+	}
+
+	forecast := &models.StorageForecast{
+		Path:         storage.Path,
+		TotalGB:      storage.TotalGB,
+		FreeGB:       storage.FreeGB,
+		UsagePercent: storage.UsagePercent,
+		WindowDays:   storageTrendDays,
+	}
+
+	var totalGB float64
+	for _, trend := range trends {
+		totalGB += trend.SizeGB
+	}
+	forecast.GrowthGBPerDay = totalGB / storageTrendDays
+
+	if forecast.GrowthGBPerDay > 0 {
+		days := forecast.FreeGB / forecast.GrowthGBPerDay
+		forecast.DaysToFull = &days
+		// Far-off dates would overflow a time.Duration and mean nothing anyway
+		if days < storageMaxForecastDays {
+			fullAt := time.Now().Add(time.Duration(days * float64(24*time.Hour))).UTC()
+			forecast.FullAt = &fullAt
+		}
+	}
+
+	return forecast, nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/version"
+)
+
+// Storage alert levels, in increasing order of severity
+const (
+	StorageLevelOK       = "ok"
+	StorageLevelWarning  = "warning"
+	StorageLevelCritical = "critical"
+)
+
+// storageAlertState remembers the last level seen so alerts fire on
+// transitions rather than on every check. It is shared by every AdminService
+// in the process.
+var storageAlertState struct {
+	mu    sync.Mutex
+	level string
+}
+
+// storageThresholds returns the warning and critical usage percentages
+func (s *AdminService) storageThresholds() (warning, critical float64) {
+	return float64(GetConfigInt(s.DB, "storage_warning_percent", 75)),
+		float64(GetConfigInt(s.DB, "storage_critical_percent", 90))
+}
+
+// storageLevel classifies a usage percentage against the thresholds
+func storageLevel(usagePercent, warning, critical float64) string {
+	switch {
+	case usagePercent >= critical:
+		return StorageLevelCritical
+	case usagePercent >= warning:
+		return StorageLevelWarning
+	default:
+		return StorageLevelOK
+	}
+}
+
+// StartStorageAlerts checks storage every interval for the life of the process
+func (s *AdminService) StartStorageAlerts(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.CheckStorageAlerts(); err != nil {
+				log.Printf("Storage alert check failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// CheckStorageAlerts fires a system_alert webhook when storage usage moves
+// between the ok, warning and critical levels set by storage_warning_percent
+// and storage_critical_percent
+func (s *AdminService) CheckStorageAlerts() error {
+	if !GetConfigBool(s.DB, "storage_alerts_enabled", true) {
+		return nil
+	}
+
+	forecast, err := GetStorageForecast(s.DB)
+	if err != nil {
+		return err
+	}
+
+	warning, critical := s.storageThresholds()
+	level := storageLevel(forecast.UsagePercent, warning, critical)
+
+	storageAlertState.mu.Lock()
+	previous := storageAlertState.level
+	if previous == "" {
+		previous = StorageLevelOK
+	}
+	storageAlertState.level = level
+	storageAlertState.mu.Unlock()
+
+	if level == previous {
+		return nil
+	}
+
+	alert := &models.StorageAlertPayload{
+		Level:           level,
+		PreviousLevel:   previous,
+		Path:            forecast.Path,
+		UsagePercent:    forecast.UsagePercent,
+		FreeGB:          forecast.FreeGB,
+		TotalGB:         forecast.TotalGB,
+		WarningPercent:  warning,
+		CriticalPercent: critical,
+		GrowthGBPerDay:  forecast.GrowthGBPerDay,
+		DaysToFull:      forecast.DaysToFull,
+	}
+
+	s.alertStorage(alert)
+	return nil
+}
+
+// alertStorage notifies system_alert webhooks of a storage level change
+func (s *AdminService) alertStorage(alert *models.StorageAlertPayload) {
+	var payload models.SystemAlertPayload
+	payload.Alert.Type = "storage_" + alert.Level
+	payload.Alert.Component = "storage"
+	payload.System.Version = "v" + version.Version
+	payload.Storage = alert
+
+	switch alert.Level {
+	case StorageLevelCritical:
+		payload.Alert.Severity = "critical"
+		payload.Alert.Message = fmt.Sprintf("Storage usage is critical: %.1f%% used, %.1f GB free", alert.UsagePercent, alert.FreeGB)
+	case StorageLevelWarning:
+		payload.Alert.Severity = "warning"
+		payload.Alert.Message = fmt.Sprintf("Storage usage is high: %.1f%% used, %.1f GB free", alert.UsagePercent, alert.FreeGB)
+	default:
+		payload.Alert.Severity = "info"
+		payload.Alert.Message = fmt.Sprintf("Storage usage is back to normal: %.1f%% used, %.1f GB free", alert.UsagePercent, alert.FreeGB)
+	}
+	if alert.DaysToFull != nil {
+		payload.Alert.Details = fmt.Sprintf("At %.2f GB/day storage is full in about %.0f days", alert.GrowthGBPerDay, *alert.DaysToFull)
+	}
+
+	s.webhooks.TriggerEvent(models.WebhookEventSystemAlert, payload)
+}
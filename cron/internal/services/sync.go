@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/detection"
+	"github.com/jmagar/nugs/cron/internal/fetch"
+	"github.com/jmagar/nugs/cron/internal/folders"
+	"github.com/jmagar/nugs/cron/internal/lockfile"
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/paths"
+	"github.com/jmagar/nugs/cron/internal/showsdata"
+)
+
+// ErrPipelineBusy is returned when a scan or fetch-new job is requested while
+// another one is still running
+var ErrPipelineBusy = errors.New("show pipeline is busy")
+
+// SyncResult summarizes one sync pass
+type SyncResult struct {
+	Artists           int           `json:"artists"`
+	NewShows          int           `json:"new_shows"`
+	RecordedDownloads int           `json:"recorded_downloads"`
+	Duration          time.Duration `json:"duration"`
+}
+
+// SyncService runs the detector's analysis inside the API process, keeping
+// shows.json and the downloads table in step with the show folders on tootie
+type SyncService struct {
+	DB         *sql.DB
+	JobManager *models.JobManager
+	webhooks   *WebhookService
+	catalog    *catalog.CatalogManager
+	list       detection.Lister
+
+	mu      sync.Mutex
+	lastRun time.Time
+}
+
+func NewSyncService(db *sql.DB, jobManager *models.JobManager) *SyncService {
+	return &SyncService{
+		DB:         db,
+		JobManager: jobManager,
+		webhooks:   NewWebhookService(db, jobManager),
+		catalog:    catalog.NewCatalogManager(),
+		list:       detection.SSHLister,
+	}
+}
+
+// StartSync checks every pollInterval whether a sync is due, for the life of
+// the process. Syncs run every sync_interval_minutes while sync_enabled is
+// set. Both are read on each check, so the worker can be turned on and off
+// without restarting the API.
+func (s *SyncService) StartSync(pollInterval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			if s.syncDue() {
+				if result, err := s.Sync(); err != nil {
+					log.Printf("Sync failed: %v", err)
+				} else {
+					log.Printf("Sync complete: %d artists, %d new shows, %d downloads recorded in %s",
+						result.Artists, result.NewShows, result.RecordedDownloads, result.Duration)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// syncDue reports whether the worker is enabled and the interval has passed
+func (s *SyncService) syncDue() bool {
+	if !GetConfigBool(s.DB, "sync_enabled", false) {
+		return false
+	}
+	interval := time.Duration(GetConfigInt(s.DB, "sync_interval_minutes", 60)) * time.Minute
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastRun) >= interval
+}
+
+// Sync rescans every monitored artist's folder, saves shows.json, records
+// downloads found on disk that the downloads table doesn't know about and
+// fires a new_show webhook for each show added to the catalog since the last
+// scan. It holds the detector and monitor locks, so it never overlaps the
+// CLI tools writing shows.json.
+func (s *SyncService) Sync() (*SyncResult, error) {
+	start := time.Now()
+	s.mu.Lock()
+	s.lastRun = start
+	s.mu.Unlock()
+
+	for _, name := range []string{"detector.lock", "monitor.lock"} {
+		lock, err := lockfile.Acquire(paths.Data(name))
+		if err != nil {
+			return nil, fmt.Errorf("skipping sync: %w", err)
+		}
+		defer lock.Release()
+	}
+
+	monitorConfig, err := detection.LoadMonitorConfig(paths.Config("monitor_config.json"))
+	if err != nil {
+		return nil, fmt.Errorf("loading monitor config: %w", err)
+	}
+	if _, err := folders.NewMatcher(monitorConfig.FolderPatterns, ""); err != nil {
+		return nil, fmt.Errorf("monitor config folder_patterns: %w", err)
+	}
+
+	// A corrupt shows.json stops the sync rather than being replaced
+	showsData, err := showsdata.Load(paths.Data("shows.json"), false)
+	if err != nil {
+		return nil, fmt.Errorf("loading shows data: %w", err)
+	}
+	if showsData.Artists == nil {
+		showsData.Artists = make(map[string]models.ArtistShowData)
+	}
+
+	result := &SyncResult{}
+	var newShows []models.NewShowPayload
+	for _, artist := range monitorConfig.Artists {
+		if !artist.Monitor {
+			continue
+		}
+
+		previous, known := showsData.Artists[artist.Artist]
+		data, err := detection.Artist(s.catalog, artist, monitorConfig.FolderPatterns, previous, s.list)
+		if err != nil {
+			log.Printf("Sync: %v", err)
+			continue
+		}
+		showsData.Artists[artist.Artist] = data
+		result.Artists++
+
+		// An artist's first scan finds every show new, so only later scans notify
+		if known {
+			for _, containerID := range addedShows(previous.Available, data.Available) {
+				newShows = append(newShows, s.newShowPayload(artist, containerID))
+			}
+		}
+
+		result.RecordedDownloads += s.recordDownloads(artist, data)
+	}
+
+	if catalogStats, err := s.catalog.GetCatalogStats(); err == nil {
+		showsData.LastCatalogUpdate = catalogStats.LastUpdate
+		showsData.CatalogTotalShows = catalogStats.TotalShows
+		showsData.CatalogTotalArtists = catalogStats.TotalArtists
+	}
+	showsData.LastAnalysisTime = time.Now().Format(time.RFC3339)
+
+	if err := showsdata.Save(paths.Data("shows.json"), showsData); err != nil {
+		return nil, fmt.Errorf("saving shows data: %w", err)
+	}
+
+	for _, payload := range newShows {
+		if err := s.webhooks.TriggerEvent(models.WebhookEventNewShow, payload); err != nil {
+			log.Printf("Failed to trigger new_show webhooks: %v", err)
+			break
+		}
+	}
+	result.NewShows = len(newShows)
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// checkPipelineIdle refuses a new scan or fetch-new job while one is pending
+// or running. The lock files still guard against the CLI tools.
+func (s *SyncService) checkPipelineIdle() error {
+	for _, job := range s.JobManager.ListJobs() {
+		if job.Type != models.JobTypeShowScan && job.Type != models.JobTypeFetchNew {
+			continue
+		}
+		if job.Status == models.JobStatusPending || job.Status == models.JobStatusRunning {
+			return fmt.Errorf("%w: %s job %s is running", ErrPipelineBusy, job.Type, job.ID)
+		}
+	}
+	return nil
+}
+
+// StartScan starts a job running Sync, the detector's missing show analysis
+func (s *SyncService) StartScan() (*models.Job, error) {
+	if err := s.checkPipelineIdle(); err != nil {
+		return nil, err
+	}
+
+	job := s.JobManager.CreateJob(models.JobTypeShowScan)
+	go s.performScan(job)
+	return job, nil
+}
+
+func (s *SyncService) performScan(job *models.Job) {
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusRunning
+		j.StartedAt = time.Now()
+		j.Progress = 10
+		j.Message = "Scanning monitored artists for missing shows..."
+	})
+
+	result, err := s.Sync()
+	completedAt := time.Now()
+	if err != nil {
+		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+			j.Status = models.JobStatusFailed
+			j.Error = err.Error()
+			j.Message = "Show scan failed"
+			j.CompletedAt = &completedAt
+		})
+		return
+	}
+
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusCompleted
+		j.Progress = 100
+		j.Message = fmt.Sprintf("Scanned %d artists, %d new shows", result.Artists, result.NewShows)
+		j.Result = result
+		j.CompletedAt = &completedAt
+	})
+}
+
+// StartFetchNew starts a job running the monitor's download of new shows,
+// downloading at most limit shows, or all of them if limit is 0. Cancelling
+// the job aborts the running download and saves the progress so far.
+func (s *SyncService) StartFetchNew(limit int) (*models.Job, error) {
+	if err := s.checkPipelineIdle(); err != nil {
+		return nil, err
+	}
+
+	job := s.JobManager.CreateJob(models.JobTypeFetchNew)
+	go s.performFetchNew(job, limit)
+	return job, nil
+}
+
+func (s *SyncService) performFetchNew(job *models.Job, limit int) {
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		j.Status = models.JobStatusRunning
+		j.StartedAt = time.Now()
+		j.Progress = 10
+		j.Message = "Downloading new shows..."
+	})
+
+	fail := func(err error) {
+		completedAt := time.Now()
+		s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+			j.Status = models.JobStatusFailed
+			j.Error = err.Error()
+			j.Message = "Fetching new shows failed"
+			j.CompletedAt = &completedAt
+		})
+	}
+
+	// Shares the monitor CLI's lock so the two never download at once
+	lock, err := lockfile.Acquire(paths.Data("monitor.lock"))
+	if err != nil {
+		fail(fmt.Errorf("skipping fetch: %w", err))
+		return
+	}
+	defer lock.Release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-job.Cancel:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	result, err := fetch.Run(ctx, fetch.Options{Limit: limit})
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	completedAt := time.Now()
+	s.JobManager.UpdateJob(job.ID, func(j *models.Job) {
+		if result.Interrupted {
+			j.Status = models.JobStatusCancelled
+			j.Message = "Cancelled, progress saved to shows.json"
+		} else {
+			j.Status = models.JobStatusCompleted
+			j.Progress = 100
+			j.Message = fmt.Sprintf("Downloaded %d new shows, upgraded %d", result.Downloaded, result.Upgraded)
+		}
+		j.Result = result
+		j.CompletedAt = &completedAt
+	})
+}
+
+// addedShows returns the container IDs in current that aren't in previous
+func addedShows(previous, current []int) []int {
+	known := make(map[int]bool, len(previous))
+	for _, id := range previous {
+		known[id] = true
+	}
+
+	var added []int
+	for _, id := range current {
+		if !known[id] {
+			added = append(added, id)
+		}
+	}
+	return added
+}
+
+// newShowPayload describes a show newly found in the artist's catalog
+func (s *SyncService) newShowPayload(artist models.Artist, containerID int) models.NewShowPayload {
+	var payload models.NewShowPayload
+	payload.Artist.ID = artist.ID
+	payload.Artist.Name = artist.Artist
+	payload.Show.ContainerID = containerID
+
+	// The show's DB ID is only known once a catalog refresh has imported it
+	s.DB.QueryRow(`SELECT id FROM shows WHERE container_id = ?`, containerID).Scan(&payload.Show.ID)
+
+	if show, err := s.catalog.GetShowByID(containerID); err == nil {
+		payload.Show.Title = artist.Artist + " - " + show.VenueName + " - " + show.PerformanceDate
+		payload.Show.VenueName = show.VenueName
+		payload.Show.VenueCity = show.VenueCity
+		payload.Show.VenueState = show.VenueState
+		payload.Show.PerformanceDate = show.PerformanceDate
+	}
+	return payload
+}
+
+// syncUserID returns the user that downloads found on disk are recorded
+// against: the first active admin
+func (s *SyncService) syncUserID() (int, error) {
+	var userID int
+	err := s.DB.QueryRow(`SELECT id FROM users WHERE role = 'admin' AND active = 1 ORDER BY id LIMIT 1`).Scan(&userID)
+	return userID, err
+}
+
+// downloadFormat maps a format recorded in shows.json to the downloads
+// table's formats. Lossless formats other than ALAC, and shows whose format
+// was never recorded, are stored as FLAC. The recorded name is kept as the
+// download's quality.
+func downloadFormat(format string) string {
+	switch strings.ToLower(format) {
+	case "alac":
+		return "ALAC"
+	case "aac":
+		return "MP3"
+	default:
+		return "FLAC"
+	}
+}
+
+// recordDownloads adds a completed download for each of the artist's shows
+// on disk that has no completed download yet, and returns how many it added.
+// Shows a catalog refresh hasn't imported into the shows table are skipped.
+func (s *SyncService) recordDownloads(artist models.Artist, data models.ArtistShowData) int {
+	userID, err := s.syncUserID()
+	if err != nil {
+		log.Printf("Sync: no active admin to record downloads against: %v", err)
+		return 0
+	}
+
+	recorded := 0
+	for _, containerID := range data.Downloaded {
+		var completed int
+		s.DB.QueryRow(`SELECT COUNT(*) FROM downloads WHERE container_id = ? AND status = 'completed'`, containerID).Scan(&completed)
+		if completed > 0 {
+			continue
+		}
+
+		var showID int
+		var showDate, venue string
+		err := s.DB.QueryRow(`SELECT id, date, venue FROM shows WHERE container_id = ?`, containerID).
+			Scan(&showID, &showDate, &venue)
+		if err != nil {
+			continue
+		}
+
+		quality := data.Formats[containerID]
+		if quality == "" {
+			quality = "unknown"
+		}
+
+		_, err = s.DB.Exec(`
+			INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue,
+				format, quality, status, progress, download_path, completed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'completed', 100, ?, CURRENT_TIMESTAMP)
+		`, userID, showID, containerID, artist.Artist, showDate, venue,
+			downloadFormat(data.Formats[containerID]), quality, artist.ArtistFolder)
+		if err != nil {
+			log.Printf("Sync: failed to record download of show %d: %v", containerID, err)
+			continue
+		}
+		recorded++
+	}
+	return recorded
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"database/sql"
@@ -9,11 +10,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/version"
 )
 
 type WebhookService struct {
@@ -32,7 +35,7 @@ func NewWebhookService(db *sql.DB, jobManager *models.JobManager) *WebhookServic
 	}
 }
 
-func (s *WebhookService) CreateWebhook(req *models.WebhookRequest) (*models.WebhookResponse, error) {
+func (s *WebhookService) CreateWebhook(req *models.WebhookRequest, userID int) (*models.WebhookResponse, error) {
 	// Set defaults
 	if req.Timeout == 0 {
 		req.Timeout = 10
@@ -50,6 +53,18 @@ func (s *WebhookService) CreateWebhook(req *models.WebhookRequest) (*models.Webh
 			}, nil
 		}
 	}
+	if req.DigestWindowMinutes < 0 || req.DigestMaxEvents < 0 {
+		return &models.WebhookResponse{
+			Success: false,
+			Error:   "digest_window_minutes and digest_max_events must be 0 or more",
+		}, nil
+	}
+	if err := s.validatePayloadTemplate(req.PayloadTemplate, req.Events); err != nil {
+		return &models.WebhookResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
 
 	// Serialize events and headers
 	eventsJSON, _ := json.Marshal(req.Events)
@@ -59,12 +74,24 @@ func (s *WebhookService) CreateWebhook(req *models.WebhookRequest) (*models.Webh
 		headersJSON = string(headersData)
 	}
 
+	// Optionally ping the endpoint so a bad URL is rejected now rather than
+	// discovered on the first real event
+	if req.VerifyOnCreate {
+		if err := s.verifyEndpoint(req, headersJSON); err != nil {
+			return &models.WebhookResponse{
+				Success: false,
+				Error:   "Endpoint verification failed: " + err.Error(),
+			}, nil
+		}
+	}
+
 	// Insert webhook
 	result, err := s.DB.Exec(`
-		INSERT INTO webhooks (name, url, events, status, secret, headers, timeout, retries, 
-		                     failure_count, created_at, updated_at)
-		VALUES (?, ?, ?, 'active', ?, ?, ?, ?, 0, datetime('now'), datetime('now'))
-	`, req.Name, req.URL, string(eventsJSON), req.Secret, headersJSON, req.Timeout, req.Retries)
+		INSERT INTO webhooks (user_id, name, url, events, active, secret, headers, timeout_seconds, retry_count,
+		                     digest_window_minutes, digest_max_events, payload_template, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+	`, userID, req.Name, req.URL, string(eventsJSON), req.Secret, headersJSON, req.Timeout, req.Retries,
+		req.DigestWindowMinutes, req.DigestMaxEvents, req.PayloadTemplate)
 
 	if err != nil {
 		return &models.WebhookResponse{
@@ -82,6 +109,36 @@ func (s *WebhookService) CreateWebhook(req *models.WebhookRequest) (*models.Webh
 	}, nil
 }
 
+// verifyEndpoint sends a ping to a webhook that hasn't been saved yet and
+// returns an error unless the endpoint answers with a 2xx status
+func (s *WebhookService) verifyEndpoint(req *models.WebhookRequest, headersJSON string) error {
+	webhook := &models.Webhook{
+		Name:    req.Name,
+		URL:     req.URL,
+		Secret:  req.Secret,
+		Headers: headersJSON,
+		Timeout: req.Timeout,
+
+		PayloadTemplate: req.PayloadTemplate,
+	}
+
+	result, err := s.sendTestDelivery(webhook, models.WebhookEventPing, map[string]interface{}{
+		"test":    true,
+		"message": "Verifying webhook endpoint",
+	})
+	if err != nil {
+		return err
+	}
+	if result.err != nil {
+		return result.err
+	}
+	if !result.success {
+		return fmt.Errorf("endpoint returned status %d", result.statusCode)
+	}
+
+	return nil
+}
+
 func (s *WebhookService) UpdateWebhook(webhookID int, req *models.WebhookUpdateRequest) error {
 	updates := []string{}
 	args := []interface{}{}
@@ -108,9 +165,10 @@ func (s *WebhookService) UpdateWebhook(webhookID int, req *models.WebhookUpdateR
 		args = append(args, string(eventsJSON))
 	}
 
+	// The schema only stores whether a webhook is active
 	if req.Status != nil {
-		updates = append(updates, "status = ?")
-		args = append(args, *req.Status)
+		updates = append(updates, "active = ?")
+		args = append(args, *req.Status == models.WebhookStatusActive)
 	}
 
 	if req.Secret != nil {
@@ -125,15 +183,54 @@ func (s *WebhookService) UpdateWebhook(webhookID int, req *models.WebhookUpdateR
 	}
 
 	if req.Timeout != nil {
-		updates = append(updates, "timeout = ?")
+		updates = append(updates, "timeout_seconds = ?")
 		args = append(args, *req.Timeout)
 	}
 
 	if req.Retries != nil {
-		updates = append(updates, "retries = ?")
+		updates = append(updates, "retry_count = ?")
 		args = append(args, *req.Retries)
 	}
 
+	if req.DigestWindowMinutes != nil {
+		if *req.DigestWindowMinutes < 0 {
+			return fmt.Errorf("digest_window_minutes must be 0 or more")
+		}
+		updates = append(updates, "digest_window_minutes = ?")
+		args = append(args, *req.DigestWindowMinutes)
+	}
+
+	if req.DigestMaxEvents != nil {
+		if *req.DigestMaxEvents < 0 {
+			return fmt.Errorf("digest_max_events must be 0 or more")
+		}
+		updates = append(updates, "digest_max_events = ?")
+		args = append(args, *req.DigestMaxEvents)
+	}
+
+	if req.PayloadTemplate != nil {
+		// Check the template against the events the webhook will have
+		var events []models.WebhookEvent
+		if req.Events != nil {
+			events = *req.Events
+		} else {
+			var eventsJSON string
+			err := s.DB.QueryRow("SELECT events FROM webhooks WHERE id = ?", webhookID).Scan(&eventsJSON)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("webhook not found")
+			}
+			if err != nil {
+				return err
+			}
+			json.Unmarshal([]byte(eventsJSON), &events)
+		}
+		if err := s.validatePayloadTemplate(*req.PayloadTemplate, events); err != nil {
+			return err
+		}
+		updates = append(updates, "payload_template = ?")
+		args = append(args, *req.PayloadTemplate)
+	}
+
 	if len(updates) == 0 {
 		return fmt.Errorf("no fields to update")
 	}
@@ -167,31 +264,31 @@ func (s *WebhookService) DeleteWebhook(webhookID int) error {
 		return fmt.Errorf("webhook not found")
 	}
 
-	// Delete related deliveries
-	s.DB.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", webhookID)
-
 	return nil
 }
 
 func (s *WebhookService) TriggerEvent(event models.WebhookEvent, data interface{}) error {
 	// Get all webhooks that listen for this event
 	rows, err := s.DB.Query(`
-		SELECT id, name, url, events, secret, headers, timeout, retries
+		SELECT id, name, url, events, COALESCE(secret, ''), COALESCE(headers, ''), timeout_seconds, retry_count,
+		       digest_window_minutes, digest_max_events, payload_template
 		FROM webhooks
-		WHERE status = 'active' AND events LIKE ?
+		WHERE active = 1 AND events LIKE ?
 	`, "%\""+string(event)+"\"%")
 
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
+	// Read them all first; digests write to the database
+	var webhooks []models.Webhook
 	for rows.Next() {
 		var webhook models.Webhook
 		var eventsJSON, headersJSON string
 
 		err := rows.Scan(&webhook.ID, &webhook.Name, &webhook.URL, &eventsJSON,
-			&webhook.Secret, &headersJSON, &webhook.Timeout, &webhook.Retries)
+			&webhook.Secret, &headersJSON, &webhook.Timeout, &webhook.Retries,
+			&webhook.DigestWindowMinutes, &webhook.DigestMaxEvents, &webhook.PayloadTemplate)
 		if err != nil {
 			continue
 		}
@@ -208,20 +305,52 @@ func (s *WebhookService) TriggerEvent(event models.WebhookEvent, data interface{
 			}
 
 			if shouldTrigger {
-				// Parse headers
-				var headers map[string]string
-				json.Unmarshal([]byte(headersJSON), &headers)
 				webhook.Headers = headersJSON
+				webhooks = append(webhooks, webhook)
+			}
+		}
+	}
+	rows.Close()
 
-				// Trigger webhook asynchronously
-				go s.deliverWebhook(&webhook, event, data, 1)
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if digested(webhook, event) {
+			if err := s.collectDigest(webhook, event, data); err != nil {
+				log.Printf("Failed to add %s event to webhook %d digest: %v", event, webhook.ID, err)
 			}
+			continue
 		}
+
+		// Queue delivery; at most the configured number run at once
+		s.queueDelivery(webhook, event, data, 1)
 	}
 
 	return nil
 }
 
+// queueDelivery hands a delivery to the shared queue, which bounds how many
+// webhook requests are in flight across the process. A delivery the full
+// queue rejects is logged and recorded as failed, and not retried.
+func (s *WebhookService) queueDelivery(webhook *models.Webhook, event models.WebhookEvent, data interface{}, attempt int) {
+	err := webhookDeliveries.submit(func() {
+		s.deliverWebhook(webhook, event, data, attempt)
+	})
+	if err != nil {
+		log.Printf("Dropping %s delivery to webhook %d: %v", event, webhook.ID, err)
+		s.recordDelivery(webhook.ID, event, webhook.URL, "", "", 0, "", err.Error(), 0, attempt, false)
+		s.recordOutcome(webhook.ID, false)
+	}
+}
+
+// retryDelivery requeues a failed delivery after an exponential backoff. The
+// wait happens outside the queue so it doesn't hold a delivery slot.
+func (s *WebhookService) retryDelivery(webhook *models.Webhook, event models.WebhookEvent, data interface{}, attempt int) {
+	backoff := time.Duration(attempt*attempt) * time.Second
+	time.AfterFunc(backoff, func() {
+		s.queueDelivery(webhook, event, data, attempt+1)
+	})
+}
+
 func (s *WebhookService) deliverWebhook(webhook *models.Webhook, event models.WebhookEvent, data interface{}, attempt int) {
 	startTime := time.Now()
 
@@ -229,16 +358,16 @@ func (s *WebhookService) deliverWebhook(webhook *models.Webhook, event models.We
 	payload := models.WebhookPayload{
 		Event:     event,
 		Timestamp: time.Now(),
-		Source:    "nugs-api/v1.0.0",
+		Source:    "nugs-api/v" + version.Version,
 		Data:      data,
 	}
 
-	// Add signature if secret is provided
-	payloadBytes, _ := json.Marshal(payload)
-	if webhook.Secret != "" {
-		signature := s.generateSignature(webhook.Secret, payloadBytes)
-		payload.Signature = signature
-		payloadBytes, _ = json.Marshal(payload) // Re-marshal with signature
+	// Render and sign the body. A template that doesn't render won't on a
+	// retry either, so the delivery fails outright.
+	payloadBytes, signature, err := s.payloadBody(webhook, payload)
+	if err != nil {
+		s.recordDelivery(webhook.ID, event, webhook.URL, "", "", 0, "", err.Error(), int(time.Since(startTime).Milliseconds()), attempt, false)
+		return
 	}
 
 	// Prepare request
@@ -250,7 +379,7 @@ func (s *WebhookService) deliverWebhook(webhook *models.Webhook, event models.We
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "nugs-api-webhook/1.0")
+	req.Header.Set("User-Agent", version.UserAgent("nugs-api-webhook"))
 	req.Header.Set("X-Webhook-Event", string(event))
 	req.Header.Set("X-Webhook-Delivery", fmt.Sprintf("%d", time.Now().Unix()))
 
@@ -265,8 +394,8 @@ func (s *WebhookService) deliverWebhook(webhook *models.Webhook, event models.We
 	}
 
 	// Set signature header
-	if webhook.Secret != "" && payload.Signature != "" {
-		req.Header.Set("X-Hub-Signature-256", "sha256="+payload.Signature)
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature-256", "sha256="+signature)
 	}
 
 	// Set timeout
@@ -284,12 +413,11 @@ func (s *WebhookService) deliverWebhook(webhook *models.Webhook, event models.We
 
 		if attempt < webhook.Retries {
 			// Retry with exponential backoff
-			backoff := time.Duration(attempt*attempt) * time.Second
-			time.Sleep(backoff)
-			go s.deliverWebhook(webhook, event, data, attempt+1)
+			s.retryDelivery(webhook, event, data, attempt)
 		} else {
-			// Mark webhook as failed after max retries
-			s.DB.Exec("UPDATE webhooks SET status = 'failed', failure_count = failure_count + 1 WHERE id = ?", webhook.ID)
+			// Deactivate the webhook once the endpoint stays unreachable
+			s.recordOutcome(webhook.ID, false)
+			s.DB.Exec("UPDATE webhooks SET active = 0 WHERE id = ?", webhook.ID)
 		}
 		return
 	}
@@ -310,29 +438,31 @@ func (s *WebhookService) deliverWebhook(webhook *models.Webhook, event models.We
 		string(headersJSON), resp.StatusCode, responseStr, "", duration, attempt, success)
 
 	if success {
-		// Update webhook success stats
-		s.DB.Exec(`
-			UPDATE webhooks 
-			SET last_fired = datetime('now'), last_status = ?, failure_count = 0
-			WHERE id = ?
-		`, resp.StatusCode, webhook.ID)
+		s.recordOutcome(webhook.ID, true)
 	} else {
 		// Retry if needed
 		if attempt < webhook.Retries {
-			backoff := time.Duration(attempt*attempt) * time.Second
-			time.Sleep(backoff)
-			go s.deliverWebhook(webhook, event, data, attempt+1)
+			s.retryDelivery(webhook, event, data, attempt)
 		} else {
-			// Mark as failed
-			s.DB.Exec(`
-				UPDATE webhooks 
-				SET last_status = ?, failure_count = failure_count + 1
-				WHERE id = ?
-			`, resp.StatusCode, webhook.ID)
+			s.recordOutcome(webhook.ID, false)
 		}
 	}
 }
 
+// recordOutcome counts a delivery that succeeded or ran out of retries
+// against the webhook's totals
+func (s *WebhookService) recordOutcome(webhookID int, success bool) {
+	column := "failed_deliveries"
+	if success {
+		column = "successful_deliveries"
+	}
+	s.DB.Exec(fmt.Sprintf(`
+		UPDATE webhooks
+		SET last_triggered = datetime('now'), total_deliveries = total_deliveries + 1, %s = %s + 1
+		WHERE id = ?
+	`, column, column), webhookID)
+}
+
 func (s *WebhookService) recordDelivery(webhookID int, event models.WebhookEvent, url, payload, headers string, statusCode int, response, errorMsg string, duration, attempt int, success bool) {
 	s.DB.Exec(`
 		INSERT INTO webhook_deliveries (webhook_id, event, url, payload, headers, status_code, 
@@ -355,6 +485,8 @@ func (s *WebhookService) isValidEvent(event models.WebhookEvent) bool {
 		models.WebhookEventCatalogRefresh,
 		models.WebhookEventMonitorAlert,
 		models.WebhookEventSystemAlert,
+		models.WebhookEventScheduleNewShows,
+		models.WebhookEventGapThreshold,
 	}
 
 	for _, validEvent := range validEvents {
@@ -370,10 +502,10 @@ func (s *WebhookService) TestWebhook(webhookID int, req *models.WebhookTestReque
 	var webhook models.Webhook
 	var eventsJSON, headersJSON string
 	err := s.DB.QueryRow(`
-		SELECT id, name, url, events, secret, headers, timeout, retries
+		SELECT id, name, url, events, COALESCE(secret, ''), COALESCE(headers, ''), timeout_seconds, retry_count, payload_template
 		FROM webhooks WHERE id = ?
 	`, webhookID).Scan(&webhook.ID, &webhook.Name, &webhook.URL, &eventsJSON,
-		&webhook.Secret, &headersJSON, &webhook.Timeout, &webhook.Retries)
+		&webhook.Secret, &headersJSON, &webhook.Timeout, &webhook.Retries, &webhook.PayloadTemplate)
 
 	if err == sql.ErrNoRows {
 		return &models.WebhookTestResponse{
@@ -399,40 +531,88 @@ func (s *WebhookService) TestWebhook(webhookID int, req *models.WebhookTestReque
 	}
 
 	// Deliver webhook synchronously for testing
+	result, err := s.sendTestDelivery(&webhook, req.Event, testData)
+	if err != nil {
+		return &models.WebhookTestResponse{
+			Success: false,
+			Error:   "Failed to create request: " + err.Error(),
+		}, nil
+	}
+
+	if result.err != nil {
+		// Record failed test delivery
+		s.recordDelivery(webhook.ID, req.Event, webhook.URL, string(result.payload),
+			string(result.headers), 0, "", "Test delivery failed: "+result.err.Error(), result.duration, 1, false)
+
+		return &models.WebhookTestResponse{
+			Success:  false,
+			Error:    result.err.Error(),
+			Duration: result.duration,
+		}, nil
+	}
+
+	// Record test delivery
+	insert, _ := s.DB.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event, url, payload, headers, status_code, 
+		                               response, error, duration_ms, attempt, success, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 'Test delivery', ?, 1, ?, datetime('now'))
+	`, webhook.ID, req.Event, webhook.URL, string(result.payload), string(result.headers),
+		result.statusCode, result.response, result.duration, result.success)
+
+	deliveryID, _ := insert.LastInsertId()
+
+	return &models.WebhookTestResponse{
+		Success:    result.success,
+		StatusCode: result.statusCode,
+		Response:   result.response,
+		Duration:   result.duration,
+		DeliveryID: int(deliveryID),
+	}, nil
+}
+
+// testDeliveryResult is the outcome of a synchronous test delivery
+type testDeliveryResult struct {
+	payload    []byte
+	headers    []byte
+	statusCode int
+	response   string
+	duration   int
+	success    bool
+	err        error // Transport error, the endpoint was not reached
+}
+
+// sendTestDelivery posts a test payload to the webhook and waits for the
+// response. It returns an error only if the request could not be built.
+func (s *WebhookService) sendTestDelivery(webhook *models.Webhook, event models.WebhookEvent, data interface{}) (*testDeliveryResult, error) {
 	startTime := time.Now()
 
 	// Create payload
 	payload := models.WebhookPayload{
-		Event:     req.Event,
+		Event:     event,
 		Timestamp: time.Now(),
-		Source:    "nugs-api/v1.0.0-test",
-		Data:      testData,
+		Source:    "nugs-api/v" + version.Version + "-test",
+		Data:      data,
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
-	if webhook.Secret != "" {
-		signature := s.generateSignature(webhook.Secret, payloadBytes)
-		payload.Signature = signature
-		payloadBytes, _ = json.Marshal(payload)
+	payloadBytes, signature, err := s.payloadBody(webhook, payload)
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare request
 	httpReq, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(payloadBytes))
 	if err != nil {
-		return &models.WebhookTestResponse{
-			Success: false,
-			Error:   "Failed to create request: " + err.Error(),
-		}, nil
+		return nil, err
 	}
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("User-Agent", "nugs-api-webhook/1.0-test")
-	httpReq.Header.Set("X-Webhook-Event", string(req.Event))
+	httpReq.Header.Set("User-Agent", version.UserAgent("nugs-api-webhook"))
+	httpReq.Header.Set("X-Webhook-Event", string(event))
 	httpReq.Header.Set("X-Webhook-Test", "true")
 
-	if webhook.Secret != "" && payload.Signature != "" {
-		httpReq.Header.Set("X-Hub-Signature-256", "sha256="+payload.Signature)
+	if signature != "" {
+		httpReq.Header.Set("X-Hub-Signature-256", "sha256="+signature)
 	}
 
 	// Custom headers
@@ -445,51 +625,29 @@ func (s *WebhookService) TestWebhook(webhookID int, req *models.WebhookTestReque
 		}
 	}
 
+	result := &testDeliveryResult{payload: payloadBytes}
+	result.headers, _ = json.Marshal(httpReq.Header)
+
 	// Make request
 	client := &http.Client{
 		Timeout: time.Duration(webhook.Timeout) * time.Second,
 	}
 
 	resp, err := client.Do(httpReq)
-	duration := int(time.Since(startTime).Milliseconds())
-
+	result.duration = int(time.Since(startTime).Milliseconds())
 	if err != nil {
-		// Record failed test delivery
-		headersJSON, _ := json.Marshal(httpReq.Header)
-		s.recordDelivery(webhook.ID, req.Event, webhook.URL, string(payloadBytes),
-			string(headersJSON), 0, "", "Test delivery failed: "+err.Error(), duration, 1, false)
-
-		return &models.WebhookTestResponse{
-			Success:  false,
-			Error:    err.Error(),
-			Duration: duration,
-		}, nil
+		result.err = err
+		return result, nil
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	responseBody, _ := io.ReadAll(resp.Body)
-	responseStr := string(responseBody)
-	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	result.statusCode = resp.StatusCode
+	result.response = string(responseBody)
+	result.success = resp.StatusCode >= 200 && resp.StatusCode < 300
 
-	// Record test delivery
-	headersBytes, _ := json.Marshal(httpReq.Header)
-	result, _ := s.DB.Exec(`
-		INSERT INTO webhook_deliveries (webhook_id, event, url, payload, headers, status_code, 
-		                               response, error, duration_ms, attempt, success, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, 'Test delivery', ?, 1, ?, datetime('now'))
-	`, webhook.ID, req.Event, webhook.URL, string(payloadBytes), string(headersBytes),
-		resp.StatusCode, responseStr, duration, success)
-
-	deliveryID, _ := result.LastInsertId()
-
-	return &models.WebhookTestResponse{
-		Success:    success,
-		StatusCode: resp.StatusCode,
-		Response:   responseStr,
-		Duration:   duration,
-		DeliveryID: int(deliveryID),
-	}, nil
+	return result, nil
 }
 
 func (s *WebhookService) generateSampleData(event models.WebhookEvent) interface{} {
@@ -546,6 +704,18 @@ func (s *WebhookService) generateSampleData(event models.WebhookEvent) interface
 			},
 		}
 
+	case models.WebhookEventGapThreshold:
+		var payload models.GapThresholdPayload
+		payload.Artist.ID = 1
+		payload.Artist.Name = "Sample Artist"
+		payload.Monitor.ID = 1
+		payload.Threshold = 90
+		payload.PreviousCompletion = 92.5
+		payload.Completion = 88.1
+		payload.MissingShows = 5
+		payload.TotalShows = 42
+		return payload
+
 	default:
 		return map[string]interface{}{
 			"sample": true,
@@ -564,9 +734,9 @@ func (s *WebhookService) GetWebhookStats() (*models.WebhookStats, error) {
 	err := s.DB.QueryRow(`
 		SELECT 
 			COUNT(*) as total,
-			COUNT(CASE WHEN status = 'active' THEN 1 END) as active,
-			COUNT(CASE WHEN status = 'disabled' THEN 1 END) as disabled,
-			COUNT(CASE WHEN status = 'failed' THEN 1 END) as failed
+			COUNT(CASE WHEN active THEN 1 END) as active,
+			COUNT(CASE WHEN NOT active AND failed_deliveries = 0 THEN 1 END) as disabled,
+			COUNT(CASE WHEN NOT active AND failed_deliveries > 0 THEN 1 END) as failed
 		FROM webhooks
 	`).Scan(&stats.TotalWebhooks, &stats.ActiveWebhooks, &stats.DisabledWebhooks, &stats.FailedWebhooks)
 
@@ -584,6 +754,10 @@ func (s *WebhookService) GetWebhookStats() (*models.WebhookStats, error) {
 		FROM webhook_deliveries
 	`).Scan(&stats.TotalDeliveries, &stats.SuccessfulDeliveries, &stats.FailedDeliveries, &stats.AverageResponseTime)
 
+	active, queued := webhookDeliveries.stats()
+	stats.ActiveDeliveries = int64(active)
+	stats.QueuedDeliveries = int64(queued)
+
 	// Calculate success rate
 	if stats.TotalDeliveries > 0 {
 		stats.DeliverySuccessRate = float64(stats.SuccessfulDeliveries) / float64(stats.TotalDeliveries) * 100
@@ -608,3 +782,44 @@ func (s *WebhookService) GetWebhookStats() (*models.WebhookStats, error) {
 
 	return stats, nil
 }
+
+// GetDeliveryTrend counts the deliveries of all webhooks over timeframe, per
+// hour, day or month as the analytics time series are, with each bucket's
+// success rate. Buckets without deliveries are left out.
+func (s *WebhookService) GetDeliveryTrend(ctx context.Context, timeframe models.AnalyticsTimeframe) (*models.WebhookDeliveryTrend, error) {
+	bucket, period := timeframeBucket(timeframe, "created_at")
+	trend := &models.WebhookDeliveryTrend{
+		Timeframe: timeframe,
+		Bucket:    bucket,
+		Points:    []models.WebhookDeliveryPoint{},
+	}
+
+	rows, err := s.DB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s as period,
+			COUNT(*),
+			COUNT(CASE WHEN success = 1 THEN 1 END),
+			COALESCE(AVG(duration_ms), 0)
+		FROM webhook_deliveries
+		WHERE created_at >= datetime('now', '-%s')
+		GROUP BY period
+		ORDER BY period
+	`, period, timeframeDuration(timeframe)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var point models.WebhookDeliveryPoint
+		if err := rows.Scan(&point.Period, &point.Deliveries, &point.Successful, &point.AverageResponseTime); err != nil {
+			return nil, err
+		}
+		point.Failed = point.Deliveries - point.Successful
+		if point.Deliveries > 0 {
+			point.SuccessRate = float64(point.Successful) / float64(point.Deliveries) * 100
+		}
+		trend.Points = append(trend.Points, point)
+	}
+
+	return trend, rows.Err()
+}
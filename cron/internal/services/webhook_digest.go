@@ -0,0 +1,157 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// digestMu serializes digest flushes across every WebhookService, so two
+// flushes never read and deliver the same waiting events
+var digestMu sync.Mutex
+
+// digested reports whether event goes into the webhook's digest rather than
+// being delivered straight away. System alerts are always sent immediately.
+func digested(webhook *models.Webhook, event models.WebhookEvent) bool {
+	return webhook.DigestWindowMinutes > 0 && event != models.WebhookEventSystemAlert
+}
+
+// collectDigest stores an event for the webhook's next digest, and flushes the
+// digest at once when digest_max_events are waiting
+func (s *WebhookService) collectDigest(webhook *models.Webhook, event models.WebhookEvent, data interface{}) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.Exec(`INSERT INTO webhook_digest_events (webhook_id, event, data) VALUES (?, ?, ?)`,
+		webhook.ID, event, string(dataJSON))
+	if err != nil {
+		return err
+	}
+
+	if webhook.DigestMaxEvents == 0 {
+		return nil
+	}
+
+	var waiting int
+	err = s.DB.QueryRow(`SELECT COUNT(*) FROM webhook_digest_events WHERE webhook_id = ?`, webhook.ID).Scan(&waiting)
+	if err != nil {
+		return err
+	}
+	if waiting < webhook.DigestMaxEvents {
+		return nil
+	}
+	return s.flushDigest(webhook)
+}
+
+// flushDigest removes the webhook's waiting events and queues them as a single
+// digest delivery. A webhook with nothing waiting is left alone.
+func (s *WebhookService) flushDigest(webhook *models.Webhook) error {
+	digestMu.Lock()
+	defer digestMu.Unlock()
+
+	rows, err := s.DB.Query(`
+		SELECT id, event, data, created_at
+		FROM webhook_digest_events
+		WHERE webhook_id = ?
+		ORDER BY id
+	`, webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	payload := models.WebhookDigestPayload{Counts: make(map[models.WebhookEvent]int)}
+	lastID := 0
+	for rows.Next() {
+		var event models.WebhookDigestEvent
+		var data string
+		if err := rows.Scan(&lastID, &event.Event, &data, &event.Timestamp); err != nil {
+			rows.Close()
+			return err
+		}
+		event.Data = json.RawMessage(data)
+
+		if payload.Count == 0 {
+			payload.Since = event.Timestamp
+		}
+		payload.Count++
+		payload.Counts[event.Event]++
+		payload.Events = append(payload.Events, event)
+	}
+	rows.Close()
+
+	if payload.Count == 0 {
+		return nil
+	}
+
+	// Events collected while this digest is delivered wait for the next one
+	_, err = s.DB.Exec(`DELETE FROM webhook_digest_events WHERE webhook_id = ? AND id <= ?`, webhook.ID, lastID)
+	if err != nil {
+		return err
+	}
+
+	s.queueDelivery(webhook, models.WebhookEventDigest, payload, 1)
+	return nil
+}
+
+// FlushDueDigests delivers the digest of every active webhook whose oldest
+// waiting event has been held for its digest window. Events left waiting by a
+// webhook that has since turned digests off go out on the next call.
+func (s *WebhookService) FlushDueDigests() error {
+	rows, err := s.DB.Query(`
+		SELECT w.id, w.name, w.url, COALESCE(w.secret, ''), COALESCE(w.headers, ''), w.timeout_seconds, w.retry_count,
+		       w.digest_window_minutes, w.digest_max_events, w.payload_template
+		FROM webhooks w
+		JOIN (
+			SELECT webhook_id, MIN(created_at) AS oldest
+			FROM webhook_digest_events
+			GROUP BY webhook_id
+		) d ON d.webhook_id = w.id
+		WHERE w.active = 1
+		  AND d.oldest <= datetime('now', '-' || w.digest_window_minutes || ' minutes')
+	`)
+	if err != nil {
+		return err
+	}
+
+	var due []models.Webhook
+	for rows.Next() {
+		var webhook models.Webhook
+		err := rows.Scan(&webhook.ID, &webhook.Name, &webhook.URL, &webhook.Secret, &webhook.Headers,
+			&webhook.Timeout, &webhook.Retries, &webhook.DigestWindowMinutes, &webhook.DigestMaxEvents,
+			&webhook.PayloadTemplate)
+		if err != nil {
+			log.Printf("Failed to read digest webhook: %v", err)
+			continue
+		}
+		due = append(due, webhook)
+	}
+	rows.Close()
+
+	for i := range due {
+		if err := s.flushDigest(&due[i]); err != nil {
+			log.Printf("Failed to flush digest for webhook %d: %v", due[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// StartDigests delivers due webhook digests every interval, for the life of
+// the process
+func (s *WebhookService) StartDigests(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.FlushDueDigests(); err != nil {
+				log.Printf("Webhook digest flush failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
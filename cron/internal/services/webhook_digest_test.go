@@ -0,0 +1,132 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// digestServer records the payloads posted to it
+func digestServer(t *testing.T) (*httptest.Server, chan models.WebhookPayload) {
+	t.Helper()
+
+	received := make(chan models.WebhookPayload, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload models.WebhookPayload
+		json.Unmarshal(body, &payload)
+		received <- payload
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// createDigestWebhook saves a new_show webhook with the given digest settings
+func createDigestWebhook(t *testing.T, service *WebhookService, url string, windowMinutes, maxEvents int) int {
+	t.Helper()
+
+	response, err := service.CreateWebhook(&models.WebhookRequest{
+		Name:                "digest",
+		URL:                 url,
+		Events:              []models.WebhookEvent{models.WebhookEventNewShow},
+		DigestWindowMinutes: windowMinutes,
+		DigestMaxEvents:     maxEvents,
+	}, testutil.AdminUserID)
+	require.NoError(t, err)
+	require.True(t, response.Success, response.Error)
+	return response.WebhookID
+}
+
+func waitingDigestEvents(t *testing.T, db *sql.DB, webhookID int) int {
+	t.Helper()
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM webhook_digest_events WHERE webhook_id = ?`, webhookID).Scan(&count))
+	return count
+}
+
+func receiveDigest(t *testing.T, received chan models.WebhookPayload) models.WebhookDigestPayload {
+	t.Helper()
+
+	select {
+	case payload := <-received:
+		require.Equal(t, models.WebhookEventDigest, payload.Event)
+		data, _ := json.Marshal(payload.Data)
+		var digest models.WebhookDigestPayload
+		require.NoError(t, json.Unmarshal(data, &digest))
+		return digest
+	case <-time.After(5 * time.Second):
+		t.Fatal("digest was not delivered")
+	}
+	return models.WebhookDigestPayload{}
+}
+
+func TestFlushDueDigests(t *testing.T) {
+	server, received := digestServer(t)
+	db := testutil.NewDB(t)
+	service := NewWebhookService(db, models.NewJobManager())
+
+	webhookID := createDigestWebhook(t, service, server.URL, 10, 0)
+
+	for _, containerID := range []int{67895, 67896} {
+		require.NoError(t, service.TriggerEvent(models.WebhookEventNewShow, map[string]int{"container_id": containerID}))
+	}
+	assert.Equal(t, 2, waitingDigestEvents(t, db, webhookID))
+
+	// Nothing is due inside the window
+	require.NoError(t, service.FlushDueDigests())
+	assert.Equal(t, 2, waitingDigestEvents(t, db, webhookID))
+
+	_, err := db.Exec(`UPDATE webhook_digest_events SET created_at = datetime('now', '-11 minutes')`)
+	require.NoError(t, err)
+	require.NoError(t, service.FlushDueDigests())
+
+	digest := receiveDigest(t, received)
+	assert.Equal(t, 2, digest.Count)
+	assert.Equal(t, 2, digest.Counts[models.WebhookEventNewShow])
+	assert.Equal(t, 0, waitingDigestEvents(t, db, webhookID))
+}
+
+func TestFlushDueDigests_SkipsInactiveWebhooks(t *testing.T) {
+	server, received := digestServer(t)
+	db := testutil.NewDB(t)
+	service := NewWebhookService(db, models.NewJobManager())
+
+	webhookID := createDigestWebhook(t, service, server.URL, 10, 0)
+	require.NoError(t, service.TriggerEvent(models.WebhookEventNewShow, map[string]int{"container_id": 67895}))
+
+	disabled := models.WebhookStatusDisabled
+	require.NoError(t, service.UpdateWebhook(webhookID, &models.WebhookUpdateRequest{Status: &disabled}))
+	_, err := db.Exec(`UPDATE webhook_digest_events SET created_at = datetime('now', '-11 minutes')`)
+	require.NoError(t, err)
+
+	require.NoError(t, service.FlushDueDigests())
+	assert.Equal(t, 1, waitingDigestEvents(t, db, webhookID))
+	assert.Empty(t, received)
+}
+
+func TestCollectDigest_FlushesAtMaxEvents(t *testing.T) {
+	server, received := digestServer(t)
+	db := testutil.NewDB(t)
+	service := NewWebhookService(db, models.NewJobManager())
+
+	webhookID := createDigestWebhook(t, service, server.URL, 60, 2)
+
+	require.NoError(t, service.TriggerEvent(models.WebhookEventNewShow, map[string]int{"container_id": 67895}))
+	assert.Equal(t, 1, waitingDigestEvents(t, db, webhookID))
+
+	require.NoError(t, service.TriggerEvent(models.WebhookEventNewShow, map[string]int{"container_id": 67896}))
+	digest := receiveDigest(t, received)
+	assert.Equal(t, 2, digest.Count)
+	assert.Equal(t, 0, waitingDigestEvents(t, db, webhookID))
+}
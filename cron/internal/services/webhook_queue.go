@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"sync"
+)
+
+// DefaultWebhookConcurrency is the number of webhook deliveries allowed in
+// flight at once when no limit is configured
+const DefaultWebhookConcurrency = 8
+
+// DefaultWebhookQueueSize is the number of webhook deliveries allowed to wait
+// for a slot when no size is configured
+const DefaultWebhookQueueSize = 1000
+
+// ErrWebhookQueueFull is returned when a delivery is rejected because the
+// queue already holds as many waiting deliveries as it may
+var ErrWebhookQueueFull = errors.New("webhook delivery queue is full")
+
+// webhookDeliveries is shared by every WebhookService so the limit applies to
+// the whole process rather than to each handler's service instance
+var webhookDeliveries = newDeliveryQueue(DefaultWebhookConcurrency, DefaultWebhookQueueSize)
+
+// SetWebhookConcurrency changes the maximum number of concurrent webhook
+// deliveries. Values below 1 restore the default.
+func SetWebhookConcurrency(limit int) {
+	webhookDeliveries.setLimit(limit)
+}
+
+// SetWebhookQueueSize changes how many webhook deliveries may wait for a
+// slot. Values below 1 restore the default.
+func SetWebhookQueueSize(size int) {
+	webhookDeliveries.setSize(size)
+}
+
+// deliveryQueue runs delivery functions with bounded concurrency. Deliveries
+// beyond the limit wait in FIFO order, so a burst of events is paced out
+// rather than opening hundreds of connections at once. At most size
+// deliveries wait; further ones are rejected so a long burst can't grow
+// memory without bound.
+//
+// The queue only paces deliveries within the running process. Waiting
+// deliveries are held in memory and are lost if the API exits before a slot
+// frees up; they are not persisted or replayed on the next start.
+type deliveryQueue struct {
+	mu      sync.Mutex
+	limit   int
+	size    int
+	active  int
+	pending []func()
+}
+
+func newDeliveryQueue(limit, size int) *deliveryQueue {
+	q := &deliveryQueue{}
+	q.setLimit(limit)
+	q.setSize(size)
+	return q
+}
+
+// submit runs fn as soon as a delivery slot is free. It returns
+// ErrWebhookQueueFull, without running fn, when every slot is busy and the
+// queue is full.
+func (q *deliveryQueue) submit(fn func()) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active >= q.limit && len(q.pending) >= q.size {
+		return ErrWebhookQueueFull
+	}
+
+	q.pending = append(q.pending, fn)
+	q.startWorkers()
+	return nil
+}
+
+// stats returns the number of running and waiting deliveries
+func (q *deliveryQueue) stats() (active, queued int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.active, len(q.pending)
+}
+
+func (q *deliveryQueue) setLimit(limit int) {
+	if limit < 1 {
+		limit = DefaultWebhookConcurrency
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.limit = limit
+	q.startWorkers()
+}
+
+func (q *deliveryQueue) setSize(size int) {
+	if size < 1 {
+		size = DefaultWebhookQueueSize
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.size = size
+}
+
+// startWorkers starts a worker per free slot while deliveries are waiting.
+// Callers must hold q.mu.
+func (q *deliveryQueue) startWorkers() {
+	for q.active < q.limit && len(q.pending) > 0 {
+		fn := q.next()
+		q.active++
+		go q.work(fn)
+	}
+}
+
+// work runs fn, then keeps taking waiting deliveries until the queue is empty
+// or the limit has been lowered below the number of running workers
+func (q *deliveryQueue) work(fn func()) {
+	for fn != nil {
+		fn()
+
+		q.mu.Lock()
+		if len(q.pending) == 0 || q.active > q.limit {
+			q.active--
+			fn = nil
+		} else {
+			fn = q.next()
+		}
+		q.mu.Unlock()
+	}
+}
+
+// next pops the oldest waiting delivery. Callers must hold q.mu.
+func (q *deliveryQueue) next() func() {
+	fn := q.pending[0]
+	q.pending[0] = nil
+	q.pending = q.pending[1:]
+	return fn
+}
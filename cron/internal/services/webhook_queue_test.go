@@ -0,0 +1,124 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryQueue_ConcurrencyBound(t *testing.T) {
+	q := newDeliveryQueue(2, 100)
+	release := make(chan struct{})
+
+	var running, peak int32
+	var done sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		done.Add(1)
+		q.submit(func() {
+			defer done.Done()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			<-release
+			atomic.AddInt32(&running, -1)
+		})
+	}
+
+	// Two deliveries run, the rest wait for a slot
+	require.Eventually(t, func() bool {
+		active, queued := q.stats()
+		return active == 2 && queued == 8
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	done.Wait()
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&peak))
+	require.Eventually(t, func() bool {
+		active, queued := q.stats()
+		return active == 0 && queued == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestDeliveryQueue_DeliversInOrder(t *testing.T) {
+	q := newDeliveryQueue(1, 100)
+	release := make(chan struct{})
+	q.submit(func() { <-release })
+
+	var mu sync.Mutex
+	var order []int
+	var done sync.WaitGroup
+	for i := 1; i <= 5; i++ {
+		i := i
+		done.Add(1)
+		q.submit(func() {
+			defer done.Done()
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+	}
+
+	close(release)
+	done.Wait()
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, order)
+}
+
+func TestDeliveryQueue_SetLimit(t *testing.T) {
+	q := newDeliveryQueue(1, 100)
+	release := make(chan struct{})
+	defer close(release)
+	for i := 0; i < 3; i++ {
+		q.submit(func() { <-release })
+	}
+
+	active, queued := q.stats()
+	assert.Equal(t, 1, active)
+	assert.Equal(t, 2, queued)
+
+	// Raising the limit starts the waiting deliveries straight away
+	q.setLimit(3)
+	active, queued = q.stats()
+	assert.Equal(t, 3, active)
+	assert.Equal(t, 0, queued)
+
+	// Values below 1 restore the default
+	q.setLimit(0)
+	q.mu.Lock()
+	assert.Equal(t, DefaultWebhookConcurrency, q.limit)
+	q.mu.Unlock()
+}
+
+func TestDeliveryQueue_RejectsWhenFull(t *testing.T) {
+	q := newDeliveryQueue(1, 2)
+	release := make(chan struct{})
+	var ran int32
+	deliver := func() {
+		<-release
+		atomic.AddInt32(&ran, 1)
+	}
+
+	// One delivery runs and two wait, filling the queue
+	for i := 0; i < 3; i++ {
+		require.NoError(t, q.submit(deliver))
+	}
+	assert.ErrorIs(t, q.submit(deliver), ErrWebhookQueueFull)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		active, queued := q.stats()
+		return active == 0 && queued == 0
+	}, time.Second, time.Millisecond)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&ran))
+
+	// Once drained, deliveries are accepted again
+	assert.NoError(t, q.submit(func() {}))
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/version"
+)
+
+// payloadTemplateFuncs are available to payload templates besides the
+// text/template builtins. json quotes a value for the body, and renders a
+// missing field as null.
+var payloadTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func parsePayloadTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(payloadTemplateFuncs).Parse(text)
+}
+
+// renderPayload renders a webhook's payload template. The template sees the
+// payload as it would be sent, so fields go by their JSON names, like
+// {{.event}} or {{.data.show.venue_name}}. The result must be valid JSON.
+func renderPayload(text string, payload models.WebhookPayload) ([]byte, error) {
+	tmpl, err := parsePayloadTemplate(text)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, fields); err != nil {
+		return nil, err
+	}
+	if !json.Valid(body.Bytes()) {
+		return nil, fmt.Errorf("rendered body is not valid JSON")
+	}
+	return body.Bytes(), nil
+}
+
+// validatePayloadTemplate checks a payload template renders for each of the
+// events, using their sample data. An empty template is always valid.
+func (s *WebhookService) validatePayloadTemplate(text string, events []models.WebhookEvent) error {
+	if text == "" {
+		return nil
+	}
+	if _, err := parsePayloadTemplate(text); err != nil {
+		return fmt.Errorf("invalid payload_template: %v", err)
+	}
+
+	for _, event := range events {
+		payload := models.WebhookPayload{
+			Event:     event,
+			Timestamp: time.Now(),
+			Source:    "nugs-api/v" + version.Version,
+			Data:      s.generateSampleData(event),
+		}
+		if _, err := renderPayload(text, payload); err != nil {
+			return fmt.Errorf("invalid payload_template for %s: %v", event, err)
+		}
+	}
+	return nil
+}
+
+// payloadBody returns the request body for a delivery and the signature to
+// send with it. A templated body is signed as rendered; otherwise the
+// signature covers the payload without it and is embedded in the body too.
+func (s *WebhookService) payloadBody(webhook *models.Webhook, payload models.WebhookPayload) ([]byte, string, error) {
+	if webhook.PayloadTemplate != "" {
+		body, err := renderPayload(webhook.PayloadTemplate, payload)
+		if err != nil {
+			return nil, "", fmt.Errorf("rendering payload template: %v", err)
+		}
+		if webhook.Secret == "" {
+			return body, "", nil
+		}
+		return body, s.generateSignature(webhook.Secret, body), nil
+	}
+
+	body, _ := json.Marshal(payload)
+	if webhook.Secret == "" {
+		return body, "", nil
+	}
+	payload.Signature = s.generateSignature(webhook.Secret, body)
+	body, _ = json.Marshal(payload) // Re-marshal with signature
+	return body, payload.Signature, nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+	"github.com/jmagar/nugs/cron/internal/testutil"
+	"github.com/jmagar/nugs/cron/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliverWebhook(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service := NewWebhookService(testutil.NewDB(t), models.NewJobManager())
+	webhook := &models.Webhook{
+		ID:      1,
+		URL:     server.URL,
+		Secret:  "s3cret",
+		Headers: `{"X-Team": "tapers"}`,
+		Timeout: 5,
+	}
+	service.deliverWebhook(webhook, models.WebhookEventNewShow, map[string]int{"container_id": 67895}, 1)
+
+	require.NotNil(t, received)
+	assert.Equal(t, version.UserAgent("nugs-api-webhook"), received.Header.Get("User-Agent"))
+	assert.Equal(t, string(models.WebhookEventNewShow), received.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, "tapers", received.Header.Get("X-Team"))
+
+	var payload models.WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, models.WebhookEventNewShow, payload.Event)
+	assert.Equal(t, "nugs-api/v"+version.Version, payload.Source)
+	assert.Equal(t, "sha256="+payload.Signature, received.Header.Get("X-Hub-Signature-256"))
+
+	// The signature covers the payload as sent without it
+	signature := payload.Signature
+	payload.Signature = ""
+	unsigned, err := json.Marshal(payload)
+	require.NoError(t, err)
+	assert.Equal(t, service.generateSignature("s3cret", unsigned), signature)
+}
+
+func TestTriggerEvent_ActiveWebhooks(t *testing.T) {
+	received := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	db := testutil.NewDB(t)
+	service := NewWebhookService(db, models.NewJobManager())
+
+	create := func(path string) int {
+		response, err := service.CreateWebhook(&models.WebhookRequest{
+			Name:   path,
+			URL:    server.URL + path,
+			Events: []models.WebhookEvent{models.WebhookEventNewShow},
+		}, testutil.AdminUserID)
+		require.NoError(t, err)
+		require.True(t, response.Success, response.Error)
+		return response.WebhookID
+	}
+	activeID := create("/active")
+	disabledID := create("/disabled")
+
+	disabled := models.WebhookStatusDisabled
+	timeout := 5
+	require.NoError(t, service.UpdateWebhook(disabledID, &models.WebhookUpdateRequest{Status: &disabled}))
+	require.NoError(t, service.UpdateWebhook(activeID, &models.WebhookUpdateRequest{Timeout: &timeout}))
+
+	require.NoError(t, service.TriggerEvent(models.WebhookEventNewShow, map[string]int{"container_id": 67895}))
+
+	select {
+	case path := <-received:
+		assert.Equal(t, "/active", path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("active webhook was not delivered")
+	}
+
+	// The delivery is counted against the webhook once the response is read
+	assert.Eventually(t, func() bool {
+		var total, successful int
+		db.QueryRow(`SELECT total_deliveries, successful_deliveries FROM webhooks WHERE id = ?`, activeID).Scan(&total, &successful)
+		return total == 1 && successful == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var timeoutSeconds int
+	require.NoError(t, db.QueryRow(`SELECT timeout_seconds FROM webhooks WHERE id = ?`, activeID).Scan(&timeoutSeconds))
+	assert.Equal(t, 5, timeoutSeconds)
+
+	stats, err := service.GetWebhookStats()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, stats.TotalWebhooks)
+	assert.EqualValues(t, 1, stats.ActiveWebhooks)
+	assert.EqualValues(t, 1, stats.DisabledWebhooks)
+
+	select {
+	case path := <-received:
+		t.Fatalf("disabled webhook was delivered to %s", path)
+	default:
+	}
+}
+
+func TestGetDeliveryTrend(t *testing.T) {
+	db := testutil.NewDB(t)
+	// Migration 001 predates the columns the service records deliveries
+	// with, so recreate the table as the service writes it
+	_, err := db.Exec(`DROP TABLE webhook_deliveries`)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER, event TEXT, url TEXT, headers TEXT,
+			status_code INTEGER, response TEXT, error TEXT,
+			duration_ms INTEGER, attempt INTEGER, success BOOLEAN,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`)
+	require.NoError(t, err)
+
+	for _, d := range []struct {
+		age      string
+		success  bool
+		duration int
+	}{
+		{"-54 hours", true, 100},
+		{"-54 hours", false, 300},
+		{"-30 hours", true, 50},
+		{"-30 hours", true, 150},
+		{"-30 hours", true, 100},
+		{"-30 hours", false, 200},
+		{"-20 days", true, 100}, // outside the week
+	} {
+		_, err := db.Exec(`
+			INSERT INTO webhook_deliveries (webhook_id, event, url, duration_ms, attempt, success, created_at)
+			VALUES (1, 'new_show', 'http://example.com', ?, 1, ?, datetime('now', ?))`,
+			d.duration, d.success, d.age)
+		require.NoError(t, err)
+	}
+
+	service := NewWebhookService(db, models.NewJobManager())
+	trend, err := service.GetDeliveryTrend(context.Background(), models.TimeframeWeek)
+	require.NoError(t, err)
+
+	assert.Equal(t, "day", trend.Bucket)
+	require.Len(t, trend.Points, 2)
+
+	older, newer := trend.Points[0], trend.Points[1]
+	assert.EqualValues(t, 2, older.Deliveries)
+	assert.EqualValues(t, 1, older.Successful)
+	assert.EqualValues(t, 1, older.Failed)
+	assert.InDelta(t, 50.0, older.SuccessRate, 0.01)
+	assert.InDelta(t, 200.0, older.AverageResponseTime, 0.01)
+
+	assert.EqualValues(t, 4, newer.Deliveries)
+	assert.EqualValues(t, 3, newer.Successful)
+	assert.InDelta(t, 75.0, newer.SuccessRate, 0.01)
+
+	// A day is bucketed by hour and only sees the last 24 hours
+	trend, err = service.GetDeliveryTrend(context.Background(), models.TimeframeDay)
+	require.NoError(t, err)
+	assert.Equal(t, "hour", trend.Bucket)
+	assert.Empty(t, trend.Points)
+}
+
+func TestDeliverWebhook_PayloadTemplate(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get("X-Hub-Signature-256")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service := NewWebhookService(testutil.NewDB(t), models.NewJobManager())
+	webhook := &models.Webhook{
+		ID:              1,
+		URL:             server.URL,
+		Secret:          "s3cret",
+		Timeout:         5,
+		PayloadTemplate: `{"text": {{json (printf "New show at %s" .data.show.venue_name)}}, "kind": {{json .event}}, "missing": {{json .data.nope}}}`,
+	}
+	service.deliverWebhook(webhook, models.WebhookEventNewShow, service.generateSampleData(models.WebhookEventNewShow), 1)
+
+	assert.JSONEq(t, `{"text": "New show at Sample Venue", "kind": "new_show", "missing": null}`, string(body))
+	// A templated body is signed as sent
+	assert.Equal(t, "sha256="+service.generateSignature("s3cret", body), signature)
+}
+
+func TestValidatePayloadTemplate(t *testing.T) {
+	service := NewWebhookService(testutil.NewDB(t), models.NewJobManager())
+	events := []models.WebhookEvent{models.WebhookEventNewShow, models.WebhookEventMonitorAlert}
+
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{"empty", "", ""},
+		{"valid", `{"event": {{json .event}}, "venue": {{json .data.show.venue_name}}}`, ""},
+		{"syntax", `{"event": {{json .event}`, "invalid payload_template"},
+		{"unknown function", `{"event": {{upper .event}}}`, "invalid payload_template"},
+		{"not json", `event={{.event}}`, "not valid JSON"},
+		// Unquoted, a field only new_show has renders as <no value> for monitor_alert
+		{"event specific", `{"venue": "{{.data.show.venue_name}}", "id": {{.data.show.id}}}`, "for monitor_alert"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.validatePayloadTemplate(tt.template, events)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package showsdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+// ErrCorrupt is returned by Load when the shows file exists but cannot be parsed
+var ErrCorrupt = errors.New("shows data is corrupt")
+
+// Load reads the shows tracking file. A missing file yields an empty struct.
+// A corrupt or truncated file is copied to a timestamped backup and an error
+// wrapping ErrCorrupt is returned, unless allowEmpty is set, in which case
+// loading continues with an empty struct so the caller can rebuild it.
+func Load(path string, allowEmpty bool) (*models.ShowsData, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return newShowsData(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var shows models.ShowsData
+	if err := json.Unmarshal(data, &shows); err != nil || len(bytes.TrimSpace(data)) == 0 {
+		if err == nil {
+			err = errors.New("file is empty")
+		}
+
+		backupPath, backupErr := backup(path, data)
+		if backupErr != nil {
+			return nil, fmt.Errorf("%w: %s: %v (backup failed: %v)", ErrCorrupt, path, err, backupErr)
+		}
+
+		if !allowEmpty {
+			return nil, fmt.Errorf("%w: %s: %v (backed up to %s)", ErrCorrupt, path, err, backupPath)
+		}
+
+		log.Printf("Warning: %s is corrupt (%v), backed up to %s; continuing with empty shows data",
+			path, err, backupPath)
+		return newShowsData(), nil
+	}
+
+	if shows.Artists == nil {
+		shows.Artists = make(map[string]models.ArtistShowData)
+	}
+
+	// Initialize metadata fields if they don't exist
+	if shows.LastCatalogUpdate == "" {
+		shows.LastCatalogUpdate = "unknown"
+	}
+	if shows.LastAnalysisTime == "" {
+		shows.LastAnalysisTime = "unknown"
+	}
+
+	return &shows, nil
+}
+
+// Save writes the shows tracking file atomically: the data is written to a
+// temp file in the same directory, read back and verified, then renamed over
+// the original so a crash never leaves a partially written file behind.
+func Save(path string, shows *models.ShowsData) error {
+	data, err := json.MarshalIndent(shows, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal shows data: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %v", err)
+	}
+
+	// Read back before replacing the original so a bad write can't clobber good data
+	written, err := ioutil.ReadFile(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to read back temp file: %v", err)
+	}
+	var verify models.ShowsData
+	if !bytes.Equal(written, data) || json.Unmarshal(written, &verify) != nil {
+		return fmt.Errorf("verification of %s failed, original left untouched", tmpPath)
+	}
+
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return fmt.Errorf("failed to set permissions: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// backup copies an unreadable shows file aside so it can be inspected or restored
+func backup(path string, data []byte) (string, error) {
+	backupPath := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+	if err := ioutil.WriteFile(backupPath, data, 0644); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+func newShowsData() *models.ShowsData {
+	return &models.ShowsData{
+		LastCatalogUpdate: "unknown",
+		LastAnalysisTime:  "unknown",
+		Artists:           make(map[string]models.ArtistShowData),
+	}
+}
@@ -0,0 +1,82 @@
+package showsdata
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jmagar/nugs/cron/internal/models"
+)
+
+func TestLoad_MissingFile(t *testing.T) {
+	shows, err := Load(filepath.Join(t.TempDir(), "shows.json"), false)
+	require.NoError(t, err)
+	assert.NotNil(t, shows.Artists)
+	assert.Equal(t, "unknown", shows.LastAnalysisTime)
+}
+
+func TestLoad_TruncatedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shows.json")
+	truncated := []byte(`{"last_catalog_update": "2025-08-22", "artists": {"Phish": {"downloaded": [1, 2`)
+	require.NoError(t, os.WriteFile(path, truncated, 0644))
+
+	shows, err := Load(path, false)
+	assert.Nil(t, shows)
+	assert.True(t, errors.Is(err, ErrCorrupt))
+
+	// The original is left in place and a backup is written alongside it
+	original, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, truncated, original)
+
+	backups, err := filepath.Glob(path + ".corrupt-*")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	backup, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Equal(t, truncated, backup)
+}
+
+func TestLoad_TruncatedFileAllowEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shows.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"artists": {`), 0644))
+
+	shows, err := Load(path, true)
+	require.NoError(t, err)
+	assert.Empty(t, shows.Artists)
+}
+
+func TestLoad_EmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shows.json")
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+
+	_, err := Load(path, false)
+	assert.True(t, errors.Is(err, ErrCorrupt))
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shows.json")
+	shows := &models.ShowsData{
+		LastCatalogUpdate: "2025-08-22T22:56:36-04:00",
+		LastAnalysisTime:  "2025-08-22T23:00:00-04:00",
+		Artists: map[string]models.ArtistShowData{
+			"Phish": {ArtistID: 62, Downloaded: []int{1, 2}, Available: []int{1, 2, 3}},
+		},
+	}
+
+	require.NoError(t, Save(path, shows))
+
+	loaded, err := Load(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, shows, loaded)
+
+	// No temp files are left behind
+	leftovers, err := filepath.Glob(path + ".tmp-*")
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
@@ -0,0 +1,108 @@
+// Package testutil provides fixtures for tests that exercise the services and
+// handlers end to end: a migrated in-memory database with the demo data
+// migration 001 seeds, and a catalog source serving the matching shows.
+package testutil
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jmagar/nugs/cron/internal/catalog"
+	"github.com/jmagar/nugs/cron/internal/database"
+)
+
+// Seeded rows from migration 001
+const (
+	AdminUserID = 1
+
+	GratefulDeadID = 1
+	PhishID        = 2
+	DeadAndCoID    = 3
+
+	SeededArtists = 5
+	SeededShows   = 5
+)
+
+// dbSeq names each test database so tests never share one
+var dbSeq int64
+
+// NewDB returns a fresh in-memory database with every migration applied. The
+// database is shared by all of the pool's connections and closed when the
+// test ends.
+func NewDB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	name := fmt.Sprintf("file:testutil_%d?mode=memory&cache=shared", atomic.AddInt64(&dbSeq, 1))
+	db, err := database.Initialize(name)
+	if err != nil {
+		t.Fatalf("initializing test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// CatalogShows returns the fixture catalog. It lists the shows migration 001
+// seeds, plus shows released since that the database doesn't have yet.
+func CatalogShows() []catalog.ShowContainer {
+	return []catalog.ShowContainer{
+		{ContainerID: 67890, ArtistName: "Grateful Dead", VenueName: "Barton Hall, Cornell University", VenueCity: "Ithaca", VenueState: "NY", PerformanceDate: "5/8/1977"},
+		{ContainerID: 67891, ArtistName: "Grateful Dead", VenueName: "JFK Stadium", VenueCity: "Philadelphia", VenueState: "PA", PerformanceDate: "7/7/1989"},
+		{ContainerID: 67892, ArtistName: "Phish", VenueName: "Madison Square Garden", VenueCity: "New York", VenueState: "NY", PerformanceDate: "12/31/1997"},
+		{ContainerID: 67893, ArtistName: "Phish", VenueName: "Merriweather Post Pavilion", VenueCity: "Columbia", VenueState: "MD", PerformanceDate: "7/14/2023"},
+		{ContainerID: 67895, ArtistName: "Phish", VenueName: "Dick's Sporting Goods Park", VenueCity: "Commerce City", VenueState: "CO", PerformanceDate: "8/30/2024"},
+		{ContainerID: 67894, ArtistName: "Dead & Company", VenueName: "Wrigley Field", VenueCity: "Chicago", VenueState: "IL", PerformanceDate: "7/1/2023"},
+	}
+}
+
+// Catalog returns a catalog source serving CatalogShows
+func Catalog() catalog.CatalogSource {
+	return catalog.NewStaticSource(CatalogShows())
+}
+
+// InsertArtist adds an artist and returns its ID
+func InsertArtist(t testing.TB, db *sql.DB, name, slug string) int {
+	t.Helper()
+
+	result, err := db.Exec(`INSERT INTO artists (name, slug) VALUES (?, ?)`, name, slug)
+	if err != nil {
+		t.Fatalf("inserting artist %s: %v", name, err)
+	}
+	id, _ := result.LastInsertId()
+	return int(id)
+}
+
+// InsertShow adds a show for artistID and returns its ID
+func InsertShow(t testing.TB, db *sql.DB, artistID, containerID int, date, venue string) int64 {
+	t.Helper()
+
+	result, err := db.Exec(`INSERT INTO shows (artist_id, date, venue, container_id) VALUES (?, ?, ?, ?)`,
+		artistID, date, venue, containerID)
+	if err != nil {
+		t.Fatalf("inserting show %d: %v", containerID, err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+// InsertDownload adds a download of a seeded or inserted show for the admin
+// user and returns its ID
+func InsertDownload(t testing.TB, db *sql.DB, containerID int, status string, sizeMB float64) int64 {
+	t.Helper()
+
+	result, err := db.Exec(`
+		INSERT INTO downloads (user_id, show_id, container_id, artist_name, show_date, venue, format, quality, size_mb, status)
+		SELECT ?, s.id, s.container_id, a.name, s.date, s.venue, 'FLAC', '16bit', ?, ?
+		FROM shows s JOIN artists a ON a.id = s.artist_id
+		WHERE s.container_id = ?`,
+		AdminUserID, sizeMB, status, containerID)
+	if err != nil {
+		t.Fatalf("inserting download of %d: %v", containerID, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		t.Fatalf("inserting download of %d: no such show", containerID)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
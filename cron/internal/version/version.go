@@ -0,0 +1,11 @@
+// Package version holds the release version shared by the API, its webhooks
+// and the nugs.net API client.
+package version
+
+// Version is the current release. Update it here only.
+const Version = "1.0.0"
+
+// UserAgent returns the User-Agent for the named client, e.g. "nugs-api-webhook/1.0.0"
+func UserAgent(client string) string {
+	return client + "/" + Version
+}